@@ -0,0 +1,79 @@
+// Command check-documents checks the files of the document library against the database, with the
+// storage backend configured by the same environment variables as the application:
+//
+//	go run ./cmd/check-documents [-delete-orphans] [-min-age 24h]
+//
+// It reports the orphan files, which no document, version, thumbnail or quarantined file
+// references (e.g., left behind by an upload whose record failed), and the records whose file is
+// missing from the store. Orphan files are only deleted with -delete-orphans. The command exits
+// with status 1 while inconsistencies remain, so that it can be run by a monitoring job.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/JneiraS/BaseSasS/internal/database"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/JneiraS/BaseSasS/internal/storage"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	deleteOrphans := flag.Bool("delete-orphans", false, "supprimer les fichiers orphelins")
+	minAge := flag.Duration("min-age", 24*time.Hour, "âge minimal d'un fichier non référencé pour être considéré orphelin")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Avertissement: Impossible de charger .env: %v", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+	store, err := storage.Open(cfg.DocumentStorageBackend, cfg)
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir le stockage des documents: %v", err)
+	}
+	db, err := database.InitDatabase()
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir la base de données: %v", err)
+	}
+	documentService := services.NewDocumentService(repositories.NewGormDocumentRepository(db), store, nil, nil, cfg)
+
+	ctx := context.Background()
+	log.Printf("Vérification de %s.", store.Name())
+	check, err := documentService.CheckStorage(ctx, *minAge)
+	if err != nil {
+		log.Fatalf("Échec de la vérification: %v", err)
+	}
+	for _, file := range check.Orphans {
+		log.Printf("ORPHELIN: %s (%s, %s)", file.Key, services.FormatFileSize(file.Size), file.ModTime.Format("02/01/2006 15:04"))
+	}
+	for _, reference := range check.Missing {
+		state := ""
+		if reference.Deleted {
+			state = ", supprimé"
+		}
+		log.Printf("MANQUANT: %s (%s %d de l'association %d, « %s »%s)", reference.Key, reference.Kind, reference.ID, reference.UserID, reference.Name, state)
+	}
+	log.Printf("Terminé: %d fichier(s) stocké(s), %d référence(s), %d orphelin(s) (%s), %d manquant(s), %d récent(s) non référencé(s) ignoré(s).",
+		check.Files, check.References, len(check.Orphans), services.FormatFileSize(check.OrphanSize()), len(check.Missing), check.Recent)
+
+	if *deleteOrphans && len(check.Orphans) > 0 {
+		count, err := documentService.DeleteOrphans(ctx, check)
+		log.Printf("%d fichier(s) orphelin(s) supprimé(s).", count)
+		if err != nil {
+			log.Fatalf("Échec de la suppression des fichiers orphelins: %v", err)
+		}
+		check.Orphans = nil
+	}
+	if !check.Consistent() {
+		os.Exit(1)
+	}
+}
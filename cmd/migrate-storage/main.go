@@ -0,0 +1,117 @@
+// Command migrate-storage moves the files of the document library from one storage backend to
+// another, e.g., from the local file system to an S3-compatible bucket before switching
+// DOCUMENT_STORAGE_BACKEND. Both backends are configured by the same environment variables as the
+// application:
+//
+//	go run ./cmd/migrate-storage -from local -to s3 [-delete] [-dry-run]
+//
+// Files already present in the destination with the same size are skipped, so that an interrupted
+// migration can be resumed.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/JneiraS/BaseSasS/internal/database"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/storage"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	from := flag.String("from", storage.BackendLocal, "stockage d'origine (local ou s3)")
+	to := flag.String("to", "", "stockage de destination (local ou s3)")
+	deleteSource := flag.Bool("delete", false, "supprimer les fichiers du stockage d'origine une fois copiés")
+	dryRun := flag.Bool("dry-run", false, "afficher les fichiers à copier sans les copier")
+	flag.Parse()
+
+	if *to == "" || *to == *from {
+		log.Fatalf("Le stockage de destination (-to) doit être indiqué et différent de l'origine (-from)")
+	}
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Avertissement: Impossible de charger .env: %v", err)
+	}
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Erreur de configuration: %v", err)
+	}
+	source, err := storage.Open(*from, cfg)
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir le stockage d'origine: %v", err)
+	}
+	destination, err := storage.Open(*to, cfg)
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir le stockage de destination: %v", err)
+	}
+	db, err := database.InitDatabase()
+	if err != nil {
+		log.Fatalf("Impossible d'ouvrir la base de données: %v", err)
+	}
+
+	// The files recorded by path before the storage was pluggable are designated by their key.
+	if count, err := database.MigrateDocumentStorageKeys(db); err != nil {
+		log.Fatalf("Impossible de convertir les chemins des fichiers: %v", err)
+	} else if count > 0 {
+		log.Printf("%d chemin(s) de fichier converti(s) en clé de stockage.", count)
+	}
+	keys, err := repositories.NewGormDocumentRepository(db).FindStorageKeys()
+	if err != nil {
+		log.Fatalf("Impossible de lister les fichiers des documents: %v", err)
+	}
+
+	log.Printf("Migration de %d fichier(s) de %s vers %s.", len(keys), source.Name(), destination.Name())
+	ctx := context.Background()
+	var copied, skipped, missing, failed int
+	for _, key := range keys {
+		size, err := source.Stat(ctx, key)
+		if err == storage.ErrNotFound {
+			// The file may have been moved by a previous run that deleted the originals.
+			if _, err := destination.Stat(ctx, key); err == nil {
+				skipped++
+			} else {
+				log.Printf("MANQUANT: %s est absent du stockage d'origine", key)
+				missing++
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("ERREUR: %s: %v", key, err)
+			failed++
+			continue
+		}
+		if existing, err := destination.Stat(ctx, key); err == nil && existing == size {
+			skipped++
+		} else if *dryRun {
+			log.Printf("À copier: %s (%d octets)", key, size)
+			copied++
+			continue
+		} else if err := storage.Copy(ctx, source, destination, key); err != nil {
+			log.Printf("ERREUR: copie de %s: %v", key, err)
+			failed++
+			continue
+		} else {
+			copied++
+		}
+		if *deleteSource && !*dryRun {
+			if err := source.Delete(ctx, key); err != nil {
+				log.Printf("ERREUR: suppression de %s du stockage d'origine: %v", key, err)
+				failed++
+			}
+		}
+	}
+
+	verb := "copié(s)"
+	if *dryRun {
+		verb = "à copier"
+	}
+	log.Printf("Terminé: %d fichier(s) %s, %d déjà présent(s), %d manquant(s), %d erreur(s).", copied, verb, skipped, missing, failed)
+	if failed > 0 {
+		log.Fatalf("La migration est incomplète: relancez la commande après avoir corrigé les erreurs.")
+	}
+	if !*dryRun {
+		log.Printf("Positionnez DOCUMENT_STORAGE_BACKEND=%s pour utiliser le nouveau stockage.", *to)
+	}
+}
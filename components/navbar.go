@@ -63,6 +63,7 @@ func containerButton() gom.Node {
 		gomh.Class("dropdown"),
 		gomh.Ul(
 			gomh.A(gom.Text("Mon profil"), gom.Attr("href", "/profile")),
+			gomh.A(gom.Text("Paramètres"), gom.Attr("href", "/settings")),
 			gomh.A(gom.Text("Mes membres"), gom.Attr("href", "/members")),
 			gomh.A(gom.Text("Mes événements"), gom.Attr("href", "/events")),
 			gomh.A(gom.Text("Communication"), gom.Attr("href", "/communication/email")),
@@ -70,6 +71,7 @@ func containerButton() gom.Node {
 			gomh.A(gom.Text("Documents"), gom.Attr("href", "/documents")),
 			gomh.A(gom.Text("Tableau de Bord"), gom.Attr("href", "/dashboard")),
 			gomh.A(gom.Text("Sondages"), gom.Attr("href", "/polls")),
+			gomh.A(gom.Text("Assemblées"), gom.Attr("href", "/assemblies")),
 			gomh.A(gom.Text("Mes favoris"), gom.Attr("href", "/favoris")),
 			gomh.A(gom.Text("Mes commandes"), gom.Attr("href", "/commandes")),
 		),
@@ -5,7 +5,9 @@ go 1.23.3
 require (
 	github.com/coreos/go-oidc/v3 v3.14.1
 	github.com/gin-gonic/gin v1.10.1
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/utrack/gin-csrf v0.0.0-20190424104817-40fb8d2c8fca
+	golang.org/x/crypto v0.37.0
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.30.0
 )
@@ -30,7 +32,6 @@ require (
 	github.com/klauspost/cpuid/v2 v2.2.10 // indirect
 	github.com/leodido/go-urn v1.4.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pelletier/go-toml/v2 v2.2.3 // indirect
@@ -48,7 +49,6 @@ require (
 	github.com/gin-contrib/sessions v1.0.4
 	github.com/go-jose/go-jose/v4 v4.0.5 // indirect
 	github.com/joho/godotenv v1.5.1
-	golang.org/x/crypto v0.37.0 // indirect
 	golang.org/x/oauth2 v0.28.0
 	maragu.dev/gomponents v1.1.0
 )
@@ -7,13 +7,17 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/JneiraS/BaseSasS/internal/adapters/middleware"
+	"github.com/JneiraS/BaseSasS/internal/antivirus"
 	"github.com/JneiraS/BaseSasS/internal/config"
 	"github.com/JneiraS/BaseSasS/internal/database"
 	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/payments"
 	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/JneiraS/BaseSasS/internal/storage"
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
@@ -34,19 +38,46 @@ type App struct {
 	eventService          *services.EventService
 	emailService          *services.EmailService
 	financeService        *services.FinanceService
+	bankImportService     *services.BankImportService
+	ledgerService         *services.LedgerService
+	settingsService       *services.SettingsService
+	invoiceService        *services.InvoiceService
+	recurringService      *services.RecurringService
+	approvalService       *services.ApprovalService
+	claimService          *services.ExpenseClaimService
+	periodService         *services.FiscalPeriodService
+	paymentService        *services.PaymentService
+	debitService          *services.DirectDebitService
+	exportService         *services.ExportService
 	documentService       *services.DocumentService
+	libraryService        *services.LibraryService
+	signatureService      *services.SignatureService
 	pollService           *services.PollService
+	assemblyService       *services.AssemblyService
 	memberHandlers        *MemberHandlers
 	eventHandlers         *EventHandlers
 	communicationHandlers *CommunicationHandlers
 	financeHandlers       *FinanceHandlers
+	bankImportHandlers    *BankImportHandlers
+	ledgerHandlers        *LedgerHandlers
+	settingsHandlers      *SettingsHandlers
+	invoiceHandlers       *InvoiceHandlers
+	recurringHandlers     *RecurringHandlers
+	approvalHandlers      *ApprovalHandlers
+	claimHandlers         *ExpenseClaimHandlers
+	periodHandlers        *FiscalPeriodHandlers
+	paymentHandlers       *PaymentHandlers
+	debitHandlers         *DirectDebitHandlers
+	exportHandlers        *ExportHandlers
 	documentHandlers      *DocumentHandlers
 	statisticsHandlers    *StatisticsHandlers
 	pollHandlers          *PollHandlers // Ajout des handlers de sondages
+	assemblyHandlers      *AssemblyHandlers
 	db                    *gorm.DB
 	router                *gin.Engine
 	cfg                   *config.Config
 	userRepo              repositories.UserRepository
+	paymentProvider       payments.PaymentProvider // nil when online payment is disabled
 }
 
 // NewApp creates and initializes a new instance of the application.
@@ -75,18 +106,67 @@ func NewApp() (*App, error) {
 	memberRepo := repositories.NewGormMemberRepository(app.db)
 	eventRepo := repositories.NewGormEventRepository(app.db)
 	transactionRepo := repositories.NewGormTransactionRepository(app.db)
+	ledgerRepo := repositories.NewGormLedgerRepository(app.db)
+	settingsRepo := repositories.NewGormSettingsRepository(app.db)
+	invoiceRepo := repositories.NewGormInvoiceRepository(app.db)
+	recurringRepo := repositories.NewGormRecurringRepository(app.db)
+	approvalRepo := repositories.NewGormApprovalRepository(app.db)
+	claimRepo := repositories.NewGormExpenseClaimRepository(app.db)
+	periodRepo := repositories.NewGormFiscalPeriodRepository(app.db)
+	paymentRepo := repositories.NewGormOnlinePaymentRepository(app.db)
+	debitRepo := repositories.NewGormDirectDebitRepository(app.db)
 	documentRepo := repositories.NewGormDocumentRepository(app.db)
 	pollRepo := repositories.NewGormPollRepository(app.db)
 	voteRepo := repositories.NewGormVoteRepository(app.db)
+	assemblyRepo := repositories.NewGormAssemblyRepository(app.db)
+
+	// Open the storage backend of the document files (local file system or S3-compatible store).
+	documentStore, err := storage.Open(app.cfg.DocumentStorageBackend, app.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document storage: %w", err)
+	}
+	log.Printf("Document storage: %s.", documentStore.Name())
+
+	// Initialize the antivirus scanning uploaded documents. Uploads are not scanned if no
+	// antivirus is configured.
+	var documentScanner antivirus.Scanner
+	if app.cfg.ClamAVAddress != "" {
+		clamd, err := antivirus.NewClamdScanner(app.cfg.ClamAVAddress, time.Duration(app.cfg.ClamAVTimeout)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure the antivirus: %w", err)
+		}
+		documentScanner = clamd
+		log.Printf("Uploaded documents scanned by %s.", clamd.Name())
+	}
 
 	// Initialize services (business logic layer).
 	app.profileService = services.NewProfileService(app.userRepo)
 	app.memberService = services.NewMemberService(memberRepo)
 	app.eventService = services.NewEventService(eventRepo)
 	app.emailService = services.NewEmailService(app.cfg)
-	app.financeService = services.NewFinanceService(transactionRepo)
-	app.documentService = services.NewDocumentService(documentRepo, app.cfg)
-	app.pollService = services.NewPollService(pollRepo, voteRepo)
+	app.settingsService = services.NewSettingsService(settingsRepo, transactionRepo)
+	app.periodService = services.NewFiscalPeriodService(periodRepo, transactionRepo, app.settingsService)
+	app.ledgerService = services.NewLedgerService(ledgerRepo, app.periodService)
+	app.financeService = services.NewFinanceService(transactionRepo, app.ledgerService, app.settingsService, app.periodService)
+	app.bankImportService = services.NewBankImportService(transactionRepo, app.financeService, app.periodService)
+	app.invoiceService = services.NewInvoiceService(invoiceRepo, transactionRepo, memberRepo, app.settingsService, app.periodService)
+	app.recurringService = services.NewRecurringService(recurringRepo, transactionRepo, app.financeService, app.periodService)
+	app.approvalService = services.NewApprovalService(approvalRepo, transactionRepo, app.userRepo, app.financeService, app.settingsService, app.periodService)
+	app.exportService = services.NewExportService(transactionRepo, app.ledgerService, app.settingsService)
+	app.documentService = services.NewDocumentService(documentRepo, documentStore, documentScanner, app.periodService, app.cfg)
+	app.libraryService = services.NewLibraryService(app.documentService, memberRepo, app.settingsService)
+	app.signatureService = services.NewSignatureService(documentRepo, app.documentService, memberRepo, app.emailService, app.settingsService, app.cfg)
+	app.claimService = services.NewExpenseClaimService(claimRepo, memberRepo, app.financeService, app.documentService, app.approvalService, app.settingsService)
+	app.debitService = services.NewDirectDebitService(debitRepo, app.memberService, app.financeService, app.settingsService)
+	app.assemblyService = services.NewAssemblyService(assemblyRepo, pollRepo, voteRepo, memberRepo, app.settingsService)
+	app.pollService = services.NewPollService(pollRepo, voteRepo, app.assemblyService)
+
+	// Initialize the online payment provider. Online payment stays unavailable
+	// if no provider is configured.
+	if err := app.initPaymentProvider(); err != nil {
+		log.Printf("WARNING: Online payment unavailable: %v", err)
+	}
+	app.paymentService = services.NewPaymentService(paymentRepo, app.paymentProvider, app.financeService, app.memberService, app.eventService, app.cfg)
 
 	// Initialize OIDC provider for authentication. This is optional;
 	// the server can start without it if OIDC configuration is missing.
@@ -95,31 +175,99 @@ func NewApp() (*App, error) {
 	}
 
 	// Auto-migrate database schemas for all models.
-	if err := app.db.AutoMigrate(&repositories.UserDB{}, &repositories.MemberDB{}, &repositories.EventDB{}, &repositories.TransactionDB{}, &repositories.DocumentDB{}, &repositories.PollDB{}, &repositories.OptionDB{}, &repositories.VoteDB{}); err != nil {
+	if err := app.db.AutoMigrate(&repositories.UserDB{}, &repositories.MemberDB{}, &repositories.EventDB{}, &repositories.TransactionDB{}, &repositories.AccountDB{}, &repositories.JournalEntryDB{}, &repositories.JournalLineDB{}, &repositories.AssociationSettingsDB{}, &repositories.InvoiceDB{}, &repositories.InvoiceLineDB{}, &repositories.RecurringTransactionDB{}, &repositories.TransactionApprovalDB{}, &repositories.ExpenseClaimDB{}, &repositories.ExpenseClaimLineDB{}, &repositories.FiscalPeriodClosureDB{}, &repositories.FiscalPeriodEventDB{}, &repositories.OnlinePaymentDB{}, &repositories.PaymentWebhookEventDB{}, &repositories.SEPAMandateDB{}, &repositories.DirectDebitBatchDB{}, &repositories.DirectDebitItemDB{}, &repositories.DocumentDB{}, &repositories.DocumentVersionDB{}, &repositories.DocumentFolderDB{}, &repositories.DocumentTagDB{}, &repositories.QuarantinedFileDB{}, &repositories.DocumentShareLinkDB{}, &repositories.DocumentShareDownloadDB{}, &repositories.DocumentSignatureRequestDB{}, &repositories.DocumentSignerDB{}, &repositories.PollDB{}, &repositories.OptionDB{}, &repositories.VoteDB{}, &repositories.PollParticipationDB{}, &repositories.SecretBallotDB{}, &repositories.AssemblyDB{}, &repositories.AssemblyAttendanceDB{}); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 	log.Println("Database migration completed.")
 
+	// Convert the amounts stored as floats before exact money handling was introduced.
+	if count, err := database.MigrateTransactionAmounts(app.db); err != nil {
+		return nil, fmt.Errorf("failed to migrate transaction amounts: %w", err)
+	} else if count > 0 {
+		log.Printf("Transaction amounts migrated to exact minor units: %d transaction(s).", count)
+	}
+
+	// Generate the journal entries of transactions recorded before the double-entry journal existed.
+	if count, err := app.financeService.BackfillJournal(); err != nil {
+		log.Printf("WARNING: Journal backfill failed: %v", err)
+	} else if count > 0 {
+		log.Printf("Journal backfill completed: %d transaction(s) journalized.", count)
+	}
+
+	// Record the file of the documents stored before documents had versions as their first version.
+	if count, err := database.MigrateDocumentVersions(app.db); err != nil {
+		return nil, fmt.Errorf("failed to migrate document versions: %w", err)
+	} else if count > 0 {
+		log.Printf("Document versions migrated: %d document(s).", count)
+	}
+
+	// Turn the file paths recorded before documents had pluggable storage into storage keys.
+	if count, err := database.MigrateDocumentStorageKeys(app.db); err != nil {
+		return nil, fmt.Errorf("failed to migrate document storage keys: %w", err)
+	} else if count > 0 {
+		log.Printf("Document storage keys migrated: %d file reference(s).", count)
+	}
+
+	// Rebuild the secret ballots table without rowid, so that it keeps no order of the ballots.
+	if count, err := database.MigrateSecretBallots(app.db); err != nil {
+		return nil, fmt.Errorf("failed to migrate secret ballots: %w", err)
+	} else if count > 0 {
+		log.Printf("Secret ballots migrated: %d ballot(s).", count)
+	}
+
+	// Set up the full-text search of documents, then extract the text of the documents stored
+	// before it existed.
+	if err := database.InitDocumentSearch(app.db); err != nil {
+		log.Printf("WARNING: Full-text document search unavailable, using simple matching: %v", err)
+	} else {
+		documentRepo.EnableFullTextSearch()
+	}
+	if count, err := app.documentService.IndexDocuments(); err != nil {
+		log.Printf("WARNING: Document text extraction failed: %v", err)
+	} else if count > 0 {
+		log.Printf("Document text extraction completed: %d document(s) indexed.", count)
+	}
+
 	// Initialize handlers (API/UI layer), injecting their respective services.
 	app.authHandlers = NewAuthHandlers(app.authService, app.cfg)
 	app.memberHandlers = NewMemberHandlers(app.memberService)
 	app.eventHandlers = NewEventHandlers(app.eventService)
 	app.communicationHandlers = NewCommunicationHandlers(app.emailService, app.memberService)
-	app.financeHandlers = NewFinanceHandlers(app.financeService)
-	app.documentHandlers = NewDocumentHandlers(app.documentService)
+	app.financeHandlers = NewFinanceHandlers(app.financeService, app.documentService, app.memberService, app.invoiceService, app.recurringService)
+	app.bankImportHandlers = NewBankImportHandlers(app.bankImportService)
+	app.ledgerHandlers = NewLedgerHandlers(app.ledgerService)
+	app.settingsHandlers = NewSettingsHandlers(app.settingsService)
+	app.invoiceHandlers = NewInvoiceHandlers(app.invoiceService, app.financeService, app.memberService)
+	app.recurringHandlers = NewRecurringHandlers(app.recurringService, app.financeService)
+	app.approvalHandlers = NewApprovalHandlers(app.approvalService)
+	app.claimHandlers = NewExpenseClaimHandlers(app.claimService, app.financeService, app.memberService)
+	app.periodHandlers = NewFiscalPeriodHandlers(app.periodService)
+	app.paymentHandlers = NewPaymentHandlers(app.paymentService, app.memberService, app.eventService, app.paymentProvider)
+	app.debitHandlers = NewDirectDebitHandlers(app.debitService, app.memberService, app.settingsService)
+	app.exportHandlers = NewExportHandlers(app.exportService)
+	app.documentHandlers = NewDocumentHandlers(app.documentService, app.libraryService, app.signatureService)
 	app.statisticsHandlers = NewStatisticsHandlers(app.memberService, app.financeService, app.eventService, app.documentService)
 	app.pollHandlers = NewPollHandlers(app.pollService)
+	app.assemblyHandlers = NewAssemblyHandlers(app.assemblyService, app.pollService)
 
 	// Set up the Gin server and define all application routes.
-	router := app.setupServer()
+	router, err := app.setupServer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the trusted proxies: %w", err)
+	}
 	app.setupRoutes(router)
 	app.router = router
 
 	return app, nil
 }
 
-// Run starts the application's HTTP server.
+// Run starts the background jobs (recurring transactions, document previews and purge of the
+// document trash) and the application's HTTP server.
 func (app *App) Run() {
+	go app.recurringService.RunScheduler(context.Background(), time.Hour)
+	go app.documentService.RunPreviewWorker(context.Background(), 5*time.Minute)
+	go app.documentService.RunTrashPurge(context.Background(), 24*time.Hour)
+
 	log.Println("🚀 Server started on :3000")
 	if err := app.router.Run(":3000"); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
@@ -150,11 +298,38 @@ func (app *App) initOIDCProvider() error {
 	return nil
 }
 
+// initPaymentProvider configures the online payment provider selected by PAYMENT_PROVIDER.
+// It leaves the provider unset when online payment is disabled.
+func (app *App) initPaymentProvider() error {
+	switch app.cfg.PaymentProvider {
+	case "":
+		return nil
+	case "stripe":
+		if app.cfg.StripeSecretKey == "" || app.cfg.StripeWebhookSecret == "" {
+			return fmt.Errorf("STRIPE_SECRET_KEY ou STRIPE_WEBHOOK_SECRET manquant")
+		}
+		app.paymentProvider = payments.NewStripeProvider(app.cfg.StripeSecretKey, app.cfg.StripeWebhookSecret, app.cfg.StripeAPIURL)
+	case "fake":
+		app.paymentProvider = payments.NewFakeProvider(strings.TrimRight(app.cfg.AppURL, "/") + "/payments/fake/")
+		log.Println("WARNING: Online payments are simulated by the local fake provider.")
+	default:
+		return fmt.Errorf("prestataire de paiement inconnu: %q", app.cfg.PaymentProvider)
+	}
+	return nil
+}
+
 // setupServer configures and returns a Gin engine instance with global middleware.
 // This includes security headers, session management, CSRF protection, and context injection.
-func (app *App) setupServer() *gin.Engine {
+// The client IP is read from the X-Forwarded-For header of the trusted reverse proxies only.
+func (app *App) setupServer() (*gin.Engine, error) {
 	r := gin.Default()
 
+	// Without trusted proxies, the client IP is the one of the connection, so that it cannot be
+	// spoofed with an X-Forwarded-For header.
+	if err := r.SetTrustedProxies(app.cfg.TrustedProxyList()); err != nil {
+		return nil, err
+	}
+
 	// Apply security headers to all responses.
 	r.Use(middleware.SecurityHeaders(app.cfg))
 
@@ -170,6 +345,10 @@ func (app *App) setupServer() *gin.Engine {
 	})
 	r.Use(sessions.Sessions(app.cfg.CookieName, store))
 
+	// Bound the size of the uploads before the CSRF protection reads their form; the forms of the
+	// transactions and of the expense claims accept several files.
+	r.Use(middleware.UploadLimit(app.cfg, "/finance/transactions/new", "/finance/transactions/edit/:id", "/finance/transactions/attachments/:id", "/finance/claims/new"))
+
 	// Apply CSRF protection middleware.
 	r.Use(middleware.CSRFProtection(app.cfg))
 
@@ -190,9 +369,12 @@ func (app *App) setupServer() *gin.Engine {
 				return template.HTML(fmt.Sprint(v))
 			}
 		},
-		"add": func(a, b int64) int64 { return a + b },
-		"mul": func(a, b float64) float64 { return a * b },
-		"div": func(a, b float64) float64 { return a / b },
+		"add":      func(a, b int64) int64 { return a + b },
+		"neg":      func(a int64) int64 { return -a },
+		"cents":    services.FormatCents,
+		"filesize": services.FormatFileSize,
+		"mul":      func(a, b float64) float64 { return a * b },
+		"div":      func(a, b float64) float64 { return a / b },
 		"float": func(a interface{}) float64 {
 			switch v := a.(type) {
 			case int:
@@ -237,7 +419,7 @@ func (app *App) setupServer() *gin.Engine {
 	r.LoadHTMLGlob("templates/*")
 	// Serve static files from the "static" directory.
 	r.Static("/static", "./static")
-	return r
+	return r, nil
 }
 
 // setupRoutes defines all application routes and assigns them to their respective handlers.
@@ -252,6 +434,10 @@ func (app *App) setupRoutes(r *gin.Engine) {
 	r.GET("/profile", app.authRequired(), app.ProfileHandler)
 	r.POST("/profile/update", app.authRequired(), app.UpdateProfileHandler)
 
+	// Association settings routes (authentication required)
+	r.GET("/settings", app.authRequired(), app.settingsHandlers.ShowSettings)
+	r.POST("/settings", app.authRequired(), app.settingsHandlers.UpdateSettings)
+
 	// Member management routes (authentication required)
 	r.GET("/members", app.authRequired(), app.memberHandlers.ListMembers)
 	r.GET("/members/new", app.authRequired(), app.memberHandlers.ShowCreateMemberForm)
@@ -280,6 +466,94 @@ func (app *App) setupRoutes(r *gin.Engine) {
 	r.GET("/finance/transactions/edit/:id", app.authRequired(), app.financeHandlers.ShowEditTransactionForm)
 	r.POST("/finance/transactions/edit/:id", app.authRequired(), app.financeHandlers.UpdateTransaction)
 	r.POST("/finance/transactions/delete/:id", app.authRequired(), app.financeHandlers.DeleteTransaction)
+	r.GET("/finance/transactions/attachments/:id", app.authRequired(), app.financeHandlers.ShowAttachments)
+	r.POST("/finance/transactions/attachments/:id", app.authRequired(), app.financeHandlers.UploadAttachments)
+	r.POST("/finance/transactions/attachments/:id/detach/:documentId", app.authRequired(), app.financeHandlers.DetachAttachment)
+
+	// Bank statement import and reconciliation routes (authentication required)
+	r.GET("/finance/import", app.authRequired(), app.bankImportHandlers.ShowImportForm)
+	r.POST("/finance/import", app.authRequired(), app.bankImportHandlers.ImportStatement)
+	r.GET("/finance/reconciliation", app.authRequired(), app.bankImportHandlers.ShowReconciliation)
+	r.POST("/finance/reconciliation/confirm/:id", app.authRequired(), app.bankImportHandlers.ConfirmMatch)
+	r.POST("/finance/reconciliation/accept/:id", app.authRequired(), app.bankImportHandlers.AcceptDraft)
+	r.POST("/finance/reconciliation/discard/:id", app.authRequired(), app.bankImportHandlers.DiscardDraft)
+
+	// Accounting routes: double-entry journal, chart of accounts and reports (authentication required)
+	r.GET("/finance/journal", app.authRequired(), app.ledgerHandlers.ShowJournal)
+	r.GET("/finance/journal/new", app.authRequired(), app.ledgerHandlers.ShowJournalEntryForm)
+	r.POST("/finance/journal/new", app.authRequired(), app.ledgerHandlers.CreateJournalEntry)
+	r.GET("/finance/ledger", app.authRequired(), app.ledgerHandlers.ShowGeneralLedger)
+	r.GET("/finance/trial-balance", app.authRequired(), app.ledgerHandlers.ShowTrialBalance)
+	r.GET("/finance/statements", app.authRequired(), app.ledgerHandlers.ShowFinancialStatements)
+	r.GET("/finance/accounts", app.authRequired(), app.ledgerHandlers.ShowAccounts)
+	r.POST("/finance/accounts", app.authRequired(), app.ledgerHandlers.CreateAccount)
+
+	// Invoice and donation receipt routes (authentication required)
+	r.GET("/finance/invoices", app.authRequired(), app.invoiceHandlers.ListInvoices)
+	r.GET("/finance/invoices/pdf", app.authRequired(), app.invoiceHandlers.DownloadInvoices)
+	r.GET("/finance/invoices/new", app.authRequired(), app.invoiceHandlers.ShowCreateInvoiceForm)
+	r.POST("/finance/invoices/new", app.authRequired(), app.invoiceHandlers.CreateInvoice)
+	r.POST("/finance/invoices/donation-receipts", app.authRequired(), app.invoiceHandlers.IssueDonationReceipts)
+	r.GET("/finance/invoices/:id/pdf", app.authRequired(), app.invoiceHandlers.DownloadInvoice)
+	r.POST("/finance/invoices/:id/cancel", app.authRequired(), app.invoiceHandlers.CancelInvoice)
+
+	// Recurring transaction routes (authentication required)
+	r.GET("/finance/recurring", app.authRequired(), app.recurringHandlers.ListRecurring)
+	r.GET("/finance/recurring/new", app.authRequired(), app.recurringHandlers.ShowCreateRecurringForm)
+	r.POST("/finance/recurring/new", app.authRequired(), app.recurringHandlers.CreateRecurring)
+	r.GET("/finance/recurring/edit/:id", app.authRequired(), app.recurringHandlers.ShowEditRecurringForm)
+	r.POST("/finance/recurring/edit/:id", app.authRequired(), app.recurringHandlers.UpdateRecurring)
+	r.POST("/finance/recurring/delete/:id", app.authRequired(), app.recurringHandlers.DeleteRecurring)
+
+	// Expense approval routes (authentication required)
+	r.GET("/finance/approvals", app.authRequired(), app.approvalHandlers.ListApprovals)
+	r.POST("/finance/approvals/:id/approve", app.authRequired(), app.approvalHandlers.ApproveTransaction)
+	r.POST("/finance/approvals/:id/reject", app.authRequired(), app.approvalHandlers.RejectTransaction)
+
+	// Expense claim routes (authentication required)
+	r.GET("/finance/claims", app.authRequired(), app.claimHandlers.ListClaims)
+	r.GET("/finance/claims/new", app.authRequired(), app.claimHandlers.ShowCreateClaimForm)
+	r.POST("/finance/claims/new", app.authRequired(), app.claimHandlers.CreateClaim)
+	r.GET("/finance/claims/:id", app.authRequired(), app.claimHandlers.ShowClaim)
+	r.GET("/finance/claims/:id/receipts/:line", app.authRequired(), app.claimHandlers.DownloadReceipt)
+	r.POST("/finance/claims/:id/approve", app.authRequired(), app.claimHandlers.ApproveClaim)
+	r.POST("/finance/claims/:id/reject", app.authRequired(), app.claimHandlers.RejectClaim)
+	r.POST("/finance/claims/:id/pay", app.authRequired(), app.claimHandlers.MarkClaimPaid)
+	r.POST("/finance/claims/:id/delete", app.authRequired(), app.claimHandlers.DeleteClaim)
+
+	// Fiscal year closing routes (authentication required)
+	r.GET("/finance/periods", app.authRequired(), app.periodHandlers.ListFiscalYears)
+	r.POST("/finance/periods/:year/close", app.authRequired(), app.periodHandlers.CloseFiscalYear)
+	r.POST("/finance/periods/:year/reopen", app.authRequired(), app.periodHandlers.ReopenFiscalYear)
+
+	// Online payment routes (authentication required)
+	r.GET("/finance/payments", app.authRequired(), app.paymentHandlers.ListPayments)
+	r.POST("/finance/payments/new", app.authRequired(), app.paymentHandlers.CreatePayment)
+	r.POST("/finance/payments/:id/refund", app.authRequired(), app.paymentHandlers.RefundPayment)
+	r.POST("/finance/payments/:id/cancel", app.authRequired(), app.paymentHandlers.CancelPayment)
+
+	// SEPA direct debit routes (authentication required)
+	r.GET("/finance/direct-debits", app.authRequired(), app.debitHandlers.ListDirectDebits)
+	r.POST("/finance/direct-debits/mandates", app.authRequired(), app.debitHandlers.CreateMandate)
+	r.POST("/finance/direct-debits/mandates/:id/revoke", app.authRequired(), app.debitHandlers.RevokeMandate)
+	r.POST("/finance/direct-debits/batches", app.authRequired(), app.debitHandlers.CreateBatch)
+	r.GET("/finance/direct-debits/batches/:id", app.authRequired(), app.debitHandlers.ShowBatch)
+	r.GET("/finance/direct-debits/batches/:id/xml", app.authRequired(), app.debitHandlers.DownloadBatch)
+	r.POST("/finance/direct-debits/batches/:id/settle", app.authRequired(), app.debitHandlers.SettleBatch)
+	r.POST("/finance/direct-debits/batches/:id/items/:item/reject", app.authRequired(), app.debitHandlers.RejectItem)
+
+	// Public payment routes, reached through the link sent to the member and by the provider
+	r.GET("/pay/:token", app.paymentHandlers.ShowPaymentPage)
+	r.POST("/pay/:token", app.paymentHandlers.StartCheckout)
+	r.POST(middleware.WebhookPathPrefix+"payments", app.paymentHandlers.HandleWebhook)
+	if _, ok := app.paymentProvider.(*payments.FakeProvider); ok {
+		r.GET("/payments/fake/:session", app.paymentHandlers.ShowFakeCheckout)
+		r.POST("/payments/fake/:session", app.paymentHandlers.CompleteFakeCheckout)
+	}
+
+	// Accounting export routes (authentication required)
+	r.GET("/finance/export", app.authRequired(), app.exportHandlers.ShowExportForm)
+	r.GET("/finance/export/download", app.authRequired(), app.exportHandlers.DownloadExport)
 
 	// Document management routes (authentication required)
 	r.GET("/documents", app.authRequired(), app.documentHandlers.ListDocuments)
@@ -287,6 +561,47 @@ func (app *App) setupRoutes(r *gin.Engine) {
 	r.POST("/documents/upload", app.authRequired(), app.documentHandlers.UploadDocument)
 	r.GET("/documents/download/:id", app.authRequired(), app.documentHandlers.DownloadDocument)
 	r.POST("/documents/delete/:id", app.authRequired(), app.documentHandlers.DeleteDocument)
+	r.GET("/documents/versions/:id", app.authRequired(), app.documentHandlers.ShowVersions)
+	r.POST("/documents/versions/:id", app.authRequired(), app.documentHandlers.UploadVersion)
+	r.GET("/documents/versions/:id/download/:version", app.authRequired(), app.documentHandlers.DownloadVersion)
+	r.POST("/documents/versions/:id/restore/:version", app.authRequired(), app.documentHandlers.RestoreVersion)
+	r.GET("/documents/organize/:id", app.authRequired(), app.documentHandlers.ShowOrganizeForm)
+	r.POST("/documents/organize/:id", app.authRequired(), app.documentHandlers.OrganizeDocument)
+	r.POST("/documents/folders", app.authRequired(), app.documentHandlers.CreateFolder)
+	r.POST("/documents/folders/rename/:id", app.authRequired(), app.documentHandlers.RenameFolder)
+	r.POST("/documents/folders/delete/:id", app.authRequired(), app.documentHandlers.DeleteFolder)
+	r.GET("/documents/preview/:id", app.authRequired(), app.documentHandlers.GetPreview)
+	r.GET("/documents/preview/:id/thumbnail", app.authRequired(), app.documentHandlers.GetThumbnail)
+	r.GET("/documents/quarantine", app.authRequired(), app.documentHandlers.ShowQuarantine)
+	r.POST("/documents/quarantine/delete/:id", app.authRequired(), app.documentHandlers.DeleteQuarantinedFile)
+	r.GET("/documents/trash", app.authRequired(), app.documentHandlers.ShowTrash)
+	r.POST("/documents/trash/restore/:id", app.authRequired(), app.documentHandlers.RestoreDocument)
+	r.POST("/documents/trash/delete/:id", app.authRequired(), app.documentHandlers.PurgeDocument)
+	r.POST("/documents/trash/empty", app.authRequired(), app.documentHandlers.EmptyTrash)
+	r.GET("/documents/shares/:id", app.authRequired(), app.documentHandlers.ShowShareLinks)
+	r.POST("/documents/shares/:id", app.authRequired(), app.documentHandlers.CreateShareLink)
+	r.POST("/documents/shares/:id/revoke/:link", app.authRequired(), app.documentHandlers.RevokeShareLink)
+	r.GET("/documents/signatures/:id", app.authRequired(), app.documentHandlers.ShowSignatureRequests)
+	r.POST("/documents/signatures/:id", app.authRequired(), app.documentHandlers.CreateSignatureRequest)
+	r.POST("/documents/signatures/:id/remind/:request", app.authRequired(), app.documentHandlers.RemindSigners)
+	r.POST("/documents/signatures/:id/cancel/:request", app.authRequired(), app.documentHandlers.CancelSignatureRequest)
+	r.GET("/documents/signatures/:id/download/:request", app.authRequired(), app.documentHandlers.DownloadSignedDocument)
+
+	// Public share routes, reached through the link sent to the recipients of a document
+	r.GET("/share/:token", app.documentHandlers.ShowSharedDocument)
+	r.POST("/share/:token", app.documentHandlers.DownloadSharedDocument)
+
+	// Public signing routes, reached through the personal link emailed to each signer of a document
+	r.GET("/sign/:token", app.documentHandlers.ShowSigning)
+	r.GET("/sign/:token/document", app.documentHandlers.DownloadSigningDocument)
+	r.POST("/sign/:token", app.documentHandlers.SignDocument)
+	r.POST("/sign/:token/decline", app.documentHandlers.DeclineSignature)
+
+	// Document library of the members. The library of an association and its downloads are public,
+	// the documents shown depending on the visitor.
+	r.GET("/library", app.authRequired(), app.documentHandlers.ShowLibraries)
+	r.GET("/library/:id", app.documentHandlers.ShowLibrary)
+	r.GET("/library/download/:id", app.documentHandlers.DownloadLibraryDocument)
 
 	// Poll management routes (authentication required)
 	r.GET("/polls", app.authRequired(), app.pollHandlers.ListPolls)
@@ -294,8 +609,24 @@ func (app *App) setupRoutes(r *gin.Engine) {
 	r.POST("/polls/new", app.authRequired(), app.pollHandlers.CreatePoll)
 	r.GET("/polls/:id", app.authRequired(), app.pollHandlers.ShowPollDetails)
 	r.POST("/polls/:id/vote", app.authRequired(), app.pollHandlers.VoteOnPoll)
+	r.POST("/polls/:id/verify", app.authRequired(), app.pollHandlers.VerifyReceipt)
+	r.GET("/polls/edit/:id", app.authRequired(), app.pollHandlers.ShowEditPollForm)
+	r.POST("/polls/edit/:id", app.authRequired(), app.pollHandlers.UpdatePoll)
+	r.POST("/polls/:id/open", app.authRequired(), app.pollHandlers.OpenPoll)
+	r.POST("/polls/:id/close", app.authRequired(), app.pollHandlers.ClosePoll)
 	r.POST("/polls/delete/:id", app.authRequired(), app.pollHandlers.DeletePoll)
 
+	// General assembly routes (authentication required); the resolutions are voted on as polls
+	r.GET("/assemblies", app.authRequired(), app.assemblyHandlers.ListAssemblies)
+	r.POST("/assemblies/new", app.authRequired(), app.assemblyHandlers.CreateAssembly)
+	r.GET("/assemblies/:id", app.authRequired(), app.assemblyHandlers.ShowAssembly)
+	r.POST("/assemblies/:id/attendance", app.authRequired(), app.assemblyHandlers.RecordAttendance)
+	r.POST("/assemblies/:id/resolutions", app.authRequired(), app.assemblyHandlers.AddResolution)
+	r.POST("/assemblies/:id/open", app.authRequired(), app.assemblyHandlers.OpenAssembly)
+	r.POST("/assemblies/:id/close", app.authRequired(), app.assemblyHandlers.CloseAssembly)
+	r.GET("/assemblies/:id/minutes", app.authRequired(), app.assemblyHandlers.DownloadMinutes)
+	r.POST("/assemblies/delete/:id", app.authRequired(), app.assemblyHandlers.DeleteAssembly)
+
 	// Statistics API routes (authentication required)
 	r.GET("/api/stats/members", app.authRequired(), app.statisticsHandlers.GetMemberStats)
 	r.GET("/api/stats/finance", app.authRequired(), app.statisticsHandlers.GetFinanceStats)
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ApprovalHandlers encapsulates the dependencies for the expense approval HTTP handlers.
+// It holds a reference to the ApprovalService, which manages the queue of the treasurer and the decisions.
+type ApprovalHandlers struct {
+	approvalService *services.ApprovalService
+}
+
+// NewApprovalHandlers creates a new instance of ApprovalHandlers.
+// It takes an ApprovalService as a dependency, adhering to the dependency inversion principle.
+func NewApprovalHandlers(approvalService *services.ApprovalService) *ApprovalHandlers {
+	return &ApprovalHandlers{approvalService: approvalService}
+}
+
+// ListApprovals displays the expenses awaiting the decision of the authenticated user and the
+// history of the decisions taken on the expenses of their association.
+func (h *ApprovalHandlers) ListApprovals(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	queue, err := h.approvalService.GetReviewQueue(user)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des dépenses à approuver: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des dépenses à approuver."})
+		return
+	}
+	decisions, err := h.approvalService.GetDecisions(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des décisions: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des décisions."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "approvals.tmpl", gin.H{
+		"title":      "Approbation des dépenses",
+		"navbar":     navbar,
+		"user":       user,
+		"queue":      queue,
+		"decisions":  decisions,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListApprovals: %v", err)
+	}
+}
+
+// ApproveTransaction handles the approval of a submitted expense by the treasurer.
+func (h *ApprovalHandlers) ApproveTransaction(c *gin.Context) {
+	h.decide(c, "Dépense approuvée.", h.approvalService.Approve)
+}
+
+// RejectTransaction handles the rejection of a submitted expense by the treasurer.
+func (h *ApprovalHandlers) RejectTransaction(c *gin.Context) {
+	h.decide(c, "Dépense rejetée.", h.approvalService.Reject)
+}
+
+// decide applies a decision to the expense identified in the URL, with the comment of the form,
+// and redirects to the approval queue with the outcome.
+func (h *ApprovalHandlers) decide(c *gin.Context, success string, decision func(actor models.User, transactionID uint, comment string) error) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de transaction invalide"})
+		return
+	}
+	if err := decision(user, uint(transactionID), c.PostForm("comment")); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/approvals")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", success, "/finance/approvals")
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *ApprovalHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultResolutionOptions are the options of a resolution created without options of its own.
+var defaultResolutionOptions = []string{"Pour", "Contre", "Abstention"}
+
+// AssemblyHandlers encapsulates the dependencies for the HTTP handlers of the general assemblies.
+// It holds the AssemblyService and the PollService, the resolutions being polls.
+type AssemblyHandlers struct {
+	assemblyService *services.AssemblyService
+	pollService     *services.PollService
+}
+
+// NewAssemblyHandlers creates a new instance of AssemblyHandlers.
+// It takes the AssemblyService and the PollService as dependencies, adhering to the dependency
+// inversion principle.
+func NewAssemblyHandlers(assemblyService *services.AssemblyService, pollService *services.PollService) *AssemblyHandlers {
+	return &AssemblyHandlers{assemblyService: assemblyService, pollService: pollService}
+}
+
+// ListAssemblies displays the general assemblies of the user's association, with the form to
+// convene a new one.
+func (h *AssemblyHandlers) ListAssemblies(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblies, err := h.assemblyService.GetAssemblies(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des assemblées: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des assemblées."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "assemblies.tmpl", gin.H{
+		"title":      "Assemblées générales",
+		"navbar":     navbar,
+		"user":       user,
+		"assemblies": assemblies,
+		"planned":    models.AssemblyPlanned,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any (e.g., flash messages).
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListAssemblies: %v", err)
+	}
+}
+
+// CreateAssembly handles the submission of the form convening a general assembly.
+func (h *AssemblyHandlers) CreateAssembly(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var assembly models.Assembly
+	if err := c.ShouldBind(&assembly); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données de l'assemblée invalides: "+err.Error(), "/assemblies")
+		return
+	}
+	date, err := parsePollTime(c.PostForm("date"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Date de l'assemblée invalide.", "/assemblies")
+		return
+	}
+	if date != nil {
+		assembly.Date = *date
+	}
+	assembly.UserID = user.ID
+
+	if err := h.assemblyService.CreateAssembly(&assembly); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de la création de l'assemblée: "+err.Error(), "/assemblies")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Assemblée convoquée. Ajoutez ses résolutions et tenez la feuille de présence.", fmt.Sprintf("/assemblies/%d", assembly.ID))
+}
+
+// ShowAssembly displays a general assembly: its quorum, its attendance sheet and its resolutions
+// with their results.
+func (h *AssemblyHandlers) ShowAssembly(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID d'assemblée invalide"})
+		return
+	}
+	assembly, err := h.assemblyService.GetAssembly(user.ID, uint(assemblyID))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Assemblée non trouvée"})
+		return
+	}
+	quorum, err := h.assemblyService.GetQuorum(assembly)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors du calcul du quorum: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors du calcul du quorum."})
+		return
+	}
+	attendance, err := h.assemblyService.GetAttendanceSheet(assembly)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la feuille de présence: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération de la feuille de présence."})
+		return
+	}
+	resolutions, err := h.assemblyService.GetResolutions(assembly)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des résolutions: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des résolutions."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "assembly_details.tmpl", gin.H{
+		"title":        assembly.Title,
+		"navbar":       navbar,
+		"user":         user,
+		"assembly":     assembly,
+		"quorum":       quorum,
+		"attendance":   attendance,
+		"resolutions":  resolutions,
+		"statuses":     models.AttendanceStatuses(),
+		"types":        models.PollTypes(),
+		"visibilities": models.ResultsVisibilities(),
+		"planned":      models.AssemblyPlanned,
+		"in_session":   models.AssemblyOpen,
+		"closed":       models.AssemblyClosed,
+		"present":      models.AttendancePresent,
+		"represented":  models.AttendanceRepresented,
+		"poll_draft":   models.PollDraft,
+		"poll_open":    models.PollOpen,
+		"csrf_token":   csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowAssembly: %v", err)
+	}
+}
+
+// RecordAttendance handles the submission of the attendance sheet of a general assembly. Each
+// member listed sends its attendance in a "status_<member ID>" field, with the member holding its
+// proxy in "proxy_<member ID>" and its votes in "weight_<member ID>".
+func (h *AssemblyHandlers) RecordAttendance(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID d'assemblée invalide.", "/assemblies")
+		return
+	}
+	location := fmt.Sprintf("/assemblies/%d", assemblyID)
+
+	var attendances []models.AssemblyAttendance
+	for _, value := range c.PostFormArray("member_ids") {
+		memberID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			h.redirectWithFlash(c, session, "error", "Membre invalide.", location)
+			return
+		}
+		attendance := models.AssemblyAttendance{
+			MemberID: uint(memberID),
+			Status:   models.AttendanceStatus(c.PostForm(fmt.Sprintf("status_%d", memberID))),
+		}
+		if value := strings.TrimSpace(c.PostForm(fmt.Sprintf("proxy_%d", memberID))); value != "" {
+			holderID, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				h.redirectWithFlash(c, session, "error", "Mandataire invalide.", location)
+				return
+			}
+			proxyHolderID := uint(holderID)
+			attendance.ProxyHolderID = &proxyHolderID
+		}
+		if attendance.Weight, err = parseChoiceCount(c.PostForm(fmt.Sprintf("weight_%d", memberID))); err != nil {
+			h.redirectWithFlash(c, session, "error", "Nombre de voix invalide.", location)
+			return
+		}
+		attendances = append(attendances, attendance)
+	}
+
+	if err := h.assemblyService.RecordAttendance(user.ID, uint(assemblyID), attendances); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'enregistrement des présences: "+err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Feuille de présence enregistrée.", location)
+}
+
+// AddResolution handles the submission of a new resolution of a general assembly. The resolution
+// is saved as a draft poll, opened to votes during the session; it is voted for, against or in
+// abstention unless other options are given.
+func (h *AssemblyHandlers) AddResolution(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID d'assemblée invalide.", "/assemblies")
+		return
+	}
+	location := fmt.Sprintf("/assemblies/%d", assemblyID)
+
+	id := uint(assemblyID)
+	poll := models.Poll{
+		UserID:            user.ID,
+		Question:          c.PostForm("question"),
+		Type:              models.PollType(c.PostForm("type")),
+		ResultsVisibility: models.ResultsVisibility(c.PostForm("results_visibility")),
+		Anonymous:         c.PostForm("anonymous") == "true",
+		AssemblyID:        &id,
+	}
+	for _, optText := range c.PostFormArray("options") {
+		if strings.TrimSpace(optText) != "" {
+			poll.Options = append(poll.Options, models.Option{Text: optText})
+		}
+	}
+	if len(poll.Options) == 0 {
+		for _, optText := range defaultResolutionOptions {
+			poll.Options = append(poll.Options, models.Option{Text: optText})
+		}
+	}
+
+	if err := h.pollService.CreatePoll(&poll, false); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'ajout de la résolution: "+err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Résolution ajoutée à l'ordre du jour.", location)
+}
+
+// OpenAssembly opens the session of a general assembly.
+func (h *AssemblyHandlers) OpenAssembly(c *gin.Context) {
+	h.changeSession(c, h.assemblyService.OpenAssembly, "Séance ouverte. Les résolutions peuvent être mises au vote une fois le quorum atteint.")
+}
+
+// CloseAssembly closes the session of a general assembly and the votes still open.
+func (h *AssemblyHandlers) CloseAssembly(c *gin.Context) {
+	h.changeSession(c, h.assemblyService.CloseAssembly, "Séance levée. Le procès-verbal est définitif.")
+}
+
+// changeSession opens or closes the session of a general assembly of the user with the given
+// service method.
+func (h *AssemblyHandlers) changeSession(c *gin.Context, change func(userID, id uint) error, success string) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID d'assemblée invalide.", "/assemblies")
+		return
+	}
+	location := fmt.Sprintf("/assemblies/%d", assemblyID)
+	if err := change(user.ID, uint(assemblyID)); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur: "+err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", success, location)
+}
+
+// DownloadMinutes sends the minutes of a general assembly as a PDF attachment.
+func (h *AssemblyHandlers) DownloadMinutes(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID d'assemblée invalide"})
+		return
+	}
+	content, filename, err := h.assemblyService.GenerateMinutesPDF(user.ID, uint(assemblyID))
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la génération du procès-verbal: %v", err)
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Assemblée non trouvée"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// DeleteAssembly deletes a general assembly which has not been held yet, with its resolutions.
+func (h *AssemblyHandlers) DeleteAssembly(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	assemblyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID d'assemblée invalide.", "/assemblies")
+		return
+	}
+	if err := h.assemblyService.DeleteAssembly(user.ID, uint(assemblyID)); err != nil {
+		h.redirectWithFlash(c, session, "error", "Échec de la suppression de l'assemblée: "+err.Error(), "/assemblies")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Assemblée supprimée avec succès !", "/assemblies")
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *AssemblyHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
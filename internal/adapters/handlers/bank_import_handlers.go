@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// BankImportHandlers encapsulates the dependencies for bank statement import and reconciliation HTTP handlers.
+// It holds a reference to the BankImportService, which contains the import and matching logic.
+type BankImportHandlers struct {
+	bankImportService *services.BankImportService
+}
+
+// NewBankImportHandlers creates a new instance of BankImportHandlers.
+// It takes a BankImportService as a dependency, adhering to the dependency inversion principle.
+func NewBankImportHandlers(bankImportService *services.BankImportService) *BankImportHandlers {
+	return &BankImportHandlers{bankImportService: bankImportService}
+}
+
+// ShowImportForm displays the bank statement upload form with a default CSV column mapping.
+func (h *BankImportHandlers) ShowImportForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	// Render the import form with the column layout used by most French bank exports.
+	c.HTML(http.StatusOK, "bank_import_form.tmpl", gin.H{
+		"title":      "Importer un relevé bancaire",
+		"navbar":     navbar,
+		"user":       user,
+		"csrf_token": csrfToken,
+		"mapping": models.CSVColumnMapping{
+			Separator:         ";",
+			DateColumn:        1,
+			DateFormat:        "02/01/2006",
+			DescriptionColumn: 2,
+			AmountColumn:      3,
+			DecimalComma:      true,
+			HasHeader:         true,
+		},
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowImportForm: %v", err)
+	}
+}
+
+// ImportStatement handles the submission of the bank statement upload form.
+// It creates draft transactions from the file and redirects to the reconciliation screen.
+func (h *BankImportHandlers) ImportStatement(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var mapping models.CSVColumnMapping
+	// Bind the CSV column mapping; it is ignored for OFX and CAMT.053 files.
+	if err := c.ShouldBind(&mapping); err != nil {
+		h.redirectWithFlash(c, session, "error", "Paramètres d'import invalides: "+err.Error(), "/finance/import")
+		return
+	}
+
+	// Retrieve the uploaded statement from the form.
+	file, err := c.FormFile("statement")
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de la récupération du fichier: "+err.Error(), "/finance/import")
+		return
+	}
+	src, err := file.Open()
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Impossible d'ouvrir le fichier: "+err.Error(), "/finance/import")
+		return
+	}
+	defer src.Close()
+
+	// Call the service to parse the statement and create the draft transactions.
+	result, err := h.bankImportService.ImportStatement(user.ID, models.BankStatementFormat(c.PostForm("format")), src, mapping)
+	if err != nil {
+		log.Printf("ERREUR: Échec de l'import du relevé: %v", err)
+		h.redirectWithFlash(c, session, "error", "Échec de l'import du relevé: "+err.Error(), "/finance/import")
+		return
+	}
+
+	message := fmt.Sprintf("%d opération(s) importée(s), dont %d rapprochée(s) automatiquement. %d doublon(s) ignoré(s).", result.Imported, result.Matched, result.Duplicates)
+	if result.Closed > 0 {
+		message += fmt.Sprintf(" %d opération(s) d'un exercice clôturé ignorée(s).", result.Closed)
+	}
+	h.redirectWithFlash(c, session, "success", message, "/finance/reconciliation")
+}
+
+// ShowReconciliation displays the imported draft transactions with their proposed matches,
+// along with the recorded transactions that no bank movement has confirmed yet.
+func (h *BankImportHandlers) ShowReconciliation(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	items, err := h.bankImportService.GetReconciliationItems(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des opérations à rapprocher: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des opérations à rapprocher."})
+		return
+	}
+	unreconciled, err := h.bankImportService.GetUnreconciledTransactions(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des transactions non rapprochées: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des transactions non rapprochées."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	// Render the reconciliation page.
+	c.HTML(http.StatusOK, "reconciliation.tmpl", gin.H{
+		"title":        "Rapprochement bancaire",
+		"navbar":       navbar,
+		"user":         user,
+		"items":        items,
+		"unreconciled": unreconciled,
+		"csrf_token":   csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowReconciliation: %v", err)
+	}
+}
+
+// ConfirmMatch reconciles an imported draft with the existing transaction chosen in the form.
+func (h *BankImportHandlers) ConfirmMatch(c *gin.Context) {
+	h.handleDraftAction(c, func(userID, draftID uint) (string, error) {
+		transactionID, err := strconv.ParseUint(c.PostForm("transaction_id"), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("veuillez choisir une transaction à rapprocher")
+		}
+		return "Opération rapprochée avec succès !", h.bankImportService.ConfirmMatch(userID, draftID, uint(transactionID))
+	})
+}
+
+// AcceptDraft records an imported draft as a new transaction, or submits it to the treasurer when
+// it is an expense requiring approval.
+func (h *BankImportHandlers) AcceptDraft(c *gin.Context) {
+	h.handleDraftAction(c, func(userID, draftID uint) (string, error) {
+		status, err := h.bankImportService.AcceptAsNew(userID, draftID)
+		if status == models.TransactionSubmitted {
+			return "Opération enregistrée comme nouvelle dépense, soumise à l'approbation du trésorier.", err
+		}
+		return "Opération enregistrée comme nouvelle transaction !", err
+	})
+}
+
+// DiscardDraft deletes an imported draft.
+func (h *BankImportHandlers) DiscardDraft(c *gin.Context) {
+	h.handleDraftAction(c, func(userID, draftID uint) (string, error) {
+		return "Opération importée ignorée.", h.bankImportService.DiscardDraft(userID, draftID)
+	})
+}
+
+// handleDraftAction centralizes the session, ID parsing and flash handling shared by the
+// reconciliation actions, then redirects back to the reconciliation screen with the success
+// message returned by the action.
+func (h *BankImportHandlers) handleDraftAction(c *gin.Context, action func(userID, draftID uint) (string, error)) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Parse the draft transaction ID from the URL parameter.
+	draftID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID d'opération invalide.", "/finance/reconciliation")
+		return
+	}
+
+	successMessage, err := action(user.ID, uint(draftID))
+	if err != nil {
+		log.Printf("ERREUR: Échec du rapprochement: %v", err)
+		h.redirectWithFlash(c, session, "error", "Échec du rapprochement: "+err.Error(), "/finance/reconciliation")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", successMessage, "/finance/reconciliation")
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *BankImportHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
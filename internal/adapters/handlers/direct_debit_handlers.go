@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCollectionDelay is the delay, in days, proposed between the generation of a batch and the
+// collection date, leaving time to upload the file to the bank.
+const defaultCollectionDelay = 7
+
+// DirectDebitHandlers encapsulates the dependencies for the SEPA direct debit HTTP handlers.
+// It holds references to the DirectDebitService, the MemberService (used to name the debtors) and
+// the SettingsService (used to check the creditor details).
+type DirectDebitHandlers struct {
+	debitService    *services.DirectDebitService
+	memberService   *services.MemberService
+	settingsService *services.SettingsService
+}
+
+// NewDirectDebitHandlers creates a new instance of DirectDebitHandlers.
+// It takes a DirectDebitService, a MemberService and a SettingsService as dependencies,
+// adhering to the dependency inversion principle.
+func NewDirectDebitHandlers(debitService *services.DirectDebitService, memberService *services.MemberService, settingsService *services.SettingsService) *DirectDebitHandlers {
+	return &DirectDebitHandlers{debitService: debitService, memberService: memberService, settingsService: settingsService}
+}
+
+// ListDirectDebits displays the SEPA mandates and batches of the association, with the members
+// whose dues can be collected on the chosen date.
+func (h *DirectDebitHandlers) ListDirectDebits(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	collectionDate := time.Now().AddDate(0, 0, defaultCollectionDelay)
+	if value := c.Query("date"); value != "" {
+		if parsed, err := time.Parse("2006-01-02", value); err == nil {
+			collectionDate = parsed
+		}
+	}
+
+	settings, err := h.settingsService.GetSettings(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des paramètres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des paramètres."})
+		return
+	}
+	mandates, err := h.debitService.GetMandates(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des mandats SEPA: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des mandats SEPA."})
+		return
+	}
+	batches, err := h.debitService.GetBatches(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des lots de prélèvements: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des lots de prélèvements."})
+		return
+	}
+	candidates, err := h.debitService.GetDueMembers(user.ID, collectionDate)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la recherche des cotisations à prélever: %v", err)
+	}
+	members, err := h.memberService.GetMembersByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des membres."})
+		return
+	}
+	memberNames := map[uint]string{}
+	for _, member := range members {
+		memberNames[member.ID] = member.FirstName + " " + member.LastName
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "direct_debits.tmpl", gin.H{
+		"title":          "Prélèvements SEPA",
+		"navbar":         navbar,
+		"user":           user,
+		"configured":     settings.SEPACreditorID != "" && settings.SEPAIBAN != "",
+		"euro":           settings.BaseCurrency == models.SEPACurrency,
+		"mandates":       mandates,
+		"batches":        batches,
+		"candidates":     candidates,
+		"members":        members,
+		"memberNames":    memberNames,
+		"selected":       c.Query("member_id"),
+		"collectionDate": collectionDate.Format("2006-01-02"),
+		"today":          time.Now().Format("2006-01-02"),
+		"csrf_token":     csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListDirectDebits: %v", err)
+	}
+}
+
+// CreateMandate handles the recording of a SEPA mandate signed by a member.
+func (h *DirectDebitHandlers) CreateMandate(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var mandate models.SEPAMandate
+	if err := c.ShouldBind(&mandate); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données du mandat invalides: "+err.Error(), "/finance/direct-debits")
+		return
+	}
+	mandate.UserID = user.ID
+	if err := h.debitService.CreateMandate(&mandate); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/direct-debits")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Mandat "+mandate.Reference+" enregistré.", "/finance/direct-debits")
+}
+
+// RevokeMandate handles the revocation of a SEPA mandate.
+func (h *DirectDebitHandlers) RevokeMandate(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	mandateID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de mandat invalide"})
+		return
+	}
+	if err := h.debitService.RevokeMandate(user.ID, uint(mandateID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/direct-debits")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Mandat révoqué.", "/finance/direct-debits")
+}
+
+// CreateBatch handles the generation of a direct debit batch for the selected members.
+func (h *DirectDebitHandlers) CreateBatch(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	collectionDate, err := time.Parse("2006-01-02", c.PostForm("collection_date"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Date de prélèvement invalide.", "/finance/direct-debits")
+		return
+	}
+	location := "/finance/direct-debits?date=" + collectionDate.Format("2006-01-02")
+	amount, err := services.ParseMoney(c.PostForm("amount"), models.SEPACurrency)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Montant invalide: "+err.Error(), location)
+		return
+	}
+	var memberIDs []uint
+	for _, value := range c.PostFormArray("member_ids") {
+		memberID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			h.redirectWithFlash(c, session, "error", "Membre invalide.", location)
+			return
+		}
+		memberIDs = append(memberIDs, uint(memberID))
+	}
+
+	batch, err := h.debitService.GenerateBatch(user.ID, memberIDs, amount, collectionDate, c.PostForm("description"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	message := fmt.Sprintf("Lot %s généré (%d prélèvements) : téléchargez le fichier et déposez-le auprès de la banque.", batch.MessageID, len(batch.Items))
+	h.redirectWithFlash(c, session, "success", message, fmt.Sprintf("/finance/direct-debits/batches/%d", batch.ID))
+}
+
+// ShowBatch displays a direct debit batch with its debits.
+func (h *DirectDebitHandlers) ShowBatch(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	batch, ok := h.batch(c, user.ID)
+	if !ok {
+		return
+	}
+	members, err := h.memberService.GetMembersByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+	}
+	memberNames := map[uint]string{}
+	for _, member := range members {
+		memberNames[member.ID] = member.FirstName + " " + member.LastName
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "direct_debit_batch.tmpl", gin.H{
+		"title":       "Lot de prélèvements " + batch.MessageID,
+		"navbar":      navbar,
+		"user":        user,
+		"batch":       batch,
+		"open":        batch.Status == models.DebitBatchGenerated,
+		"due":         !time.Now().Before(batch.CollectionDate),
+		"memberNames": memberNames,
+		"csrf_token":  csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowBatch: %v", err)
+	}
+}
+
+// DownloadBatch serves the pain.008 file of a direct debit batch, to be uploaded to the bank.
+func (h *DirectDebitHandlers) DownloadBatch(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	batch, ok := h.batch(c, user.ID)
+	if !ok {
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", batch.MessageID+".xml"))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", batch.XML)
+}
+
+// SettleBatch handles the confirmation that the bank collected the debits of a batch.
+func (h *DirectDebitHandlers) SettleBatch(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de lot invalide"})
+		return
+	}
+	location := fmt.Sprintf("/finance/direct-debits/batches/%d", batchID)
+	if err := h.debitService.SettleBatch(user.ID, uint(batchID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Lot encaissé : les recettes sont comptabilisées et les cotisations enregistrées.", location)
+}
+
+// RejectItem handles a debit returned by the bank.
+func (h *DirectDebitHandlers) RejectItem(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de lot invalide"})
+		return
+	}
+	itemID, err := strconv.ParseUint(c.Param("item"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de prélèvement invalide"})
+		return
+	}
+	location := fmt.Sprintf("/finance/direct-debits/batches/%d", batchID)
+	if err := h.debitService.RejectItem(user.ID, uint(batchID), uint(itemID), c.PostForm("reason")); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Prélèvement rejeté : la recette en attente a été annulée.", location)
+}
+
+// batch retrieves the direct debit batch named in the URL, rendering an error page when it cannot.
+func (h *DirectDebitHandlers) batch(c *gin.Context, userID uint) (*models.DirectDebitBatch, bool) {
+	batchID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de lot invalide"})
+		return nil, false
+	}
+	batch, err := h.debitService.GetBatch(userID, uint(batchID))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return batch, true
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *DirectDebitHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
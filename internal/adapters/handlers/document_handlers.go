@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/JneiraS/BaseSasS/components"
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
@@ -13,18 +19,24 @@ import (
 )
 
 // DocumentHandlers encapsulates the dependencies for document-related HTTP handlers.
-// It holds a reference to the DocumentService, which contains the business logic for documents.
+// It holds a reference to the DocumentService, which contains the business logic for documents,
+// and to the LibraryService, which decides which documents the members may see.
 type DocumentHandlers struct {
-	documentService *services.DocumentService
+	documentService  *services.DocumentService
+	libraryService   *services.LibraryService
+	signatureService *services.SignatureService
 }
 
 // NewDocumentHandlers creates a new instance of DocumentHandlers.
-// It takes a DocumentService as a dependency, adhering to the dependency inversion principle.
-func NewDocumentHandlers(documentService *services.DocumentService) *DocumentHandlers {
-	return &DocumentHandlers{documentService: documentService}
+// It takes a DocumentService, a LibraryService and a SignatureService as dependencies, adhering to
+// the dependency inversion principle.
+func NewDocumentHandlers(documentService *services.DocumentService, libraryService *services.LibraryService, signatureService *services.SignatureService) *DocumentHandlers {
+	return &DocumentHandlers{documentService: documentService, libraryService: libraryService, signatureService: signatureService}
 }
 
-// ListDocuments displays a list of documents for the authenticated user.
+// ListDocuments displays the documents of a folder for the authenticated user, with its
+// subfolders, or the documents matching a search or a tag across all folders.
+// Documents are sorted by date, name, size or type, as chosen on the page.
 // It retrieves documents from the DocumentService and renders them using the "documents.tmpl" template.
 func (h *DocumentHandlers) ListDocuments(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
@@ -35,13 +47,103 @@ func (h *DocumentHandlers) ListDocuments(c *gin.Context) {
 		return
 	}
 
+	folders, err := h.documentService.GetFolders(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des dossiers: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des dossiers."})
+		return
+	}
+	folderID, err := parseFolderID(c.Query("folder"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Dossier invalide"})
+		return
+	}
+	var current *models.DocumentFolder
+	if folderID != nil {
+		if current, err = h.documentService.GetFolder(user.ID, *folderID); err != nil {
+			c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Documents are listed by date, most recent first, unless another order is chosen.
+	query := models.DocumentQuery{
+		FolderID:   folderID,
+		Tag:        c.Query("tag"),
+		Search:     c.Query("q"),
+		Sort:       c.DefaultQuery("sort", models.DocumentSortDate),
+		Descending: c.DefaultQuery("order", "desc") == "desc",
+	}
+	searching := query.Tag != "" || query.Search != ""
+	query.AllFolders = searching && folderID == nil
+
 	// Retrieve documents associated with the current user.
-	documents, err := h.documentService.GetDocumentsByUserID(user.ID)
+	documents, err := h.documentService.SearchDocuments(user.ID, query)
 	if err != nil {
 		log.Printf("ERREUR: Erreur lors de la récupération des documents: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des documents."})
 		return
 	}
+	tags, err := h.documentService.GetTags(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des étiquettes: %v", err)
+	}
+	usage, err := h.documentService.GetStorageUsage(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors du calcul de l'espace de stockage utilisé: %v", err)
+	}
+	quarantined, err := h.documentService.GetQuarantinedFiles(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des fichiers en quarantaine: %v", err)
+	}
+	trash, err := h.documentService.GetTrash(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la corbeille: %v", err)
+	}
+
+	var subfolders []models.DocumentFolder
+	if !searching {
+		for _, folder := range folders {
+			if (folder.ParentID == nil && folderID == nil) || (folder.ParentID != nil && folderID != nil && *folder.ParentID == *folderID) {
+				subfolders = append(subfolders, folder)
+			}
+		}
+	}
+	// Search results come from several folders: name the folder of each document.
+	folderPaths := map[uint]string{}
+	for _, label := range services.FolderLabels(folders) {
+		folderPaths[label.ID] = label.Path
+	}
+	documentFolders := map[uint]string{}
+	for _, document := range documents {
+		documentFolders[document.ID] = "Racine"
+		if document.FolderID != nil {
+			documentFolders[document.ID] = folderPaths[*document.FolderID]
+		}
+	}
+
+	// The links of the column headers sort by that column, reversing the order of the current one.
+	sortLinks := map[string]string{}
+	for _, column := range []string{models.DocumentSortName, models.DocumentSortSize, models.DocumentSortType, models.DocumentSortDate} {
+		order := "asc"
+		if column == models.DocumentSortDate || column == models.DocumentSortSize {
+			order = "desc"
+		}
+		if column == query.Sort {
+			order = map[bool]string{true: "asc", false: "desc"}[query.Descending]
+		}
+		values := url.Values{"sort": {column}, "order": {order}}
+		if folderID != nil {
+			values.Set("folder", strconv.FormatUint(uint64(*folderID), 10))
+		}
+		if query.Search != "" {
+			values.Set("q", query.Search)
+		}
+		if query.Tag != "" {
+			values.Set("tag", query.Tag)
+		}
+		sortLinks[column] = "/documents?" + values.Encode()
+	}
 
 	// Retrieve CSRF token for the navigation bar.
 	csrfToken := c.MustGet("csrf_token").(string)
@@ -49,11 +151,22 @@ func (h *DocumentHandlers) ListDocuments(c *gin.Context) {
 
 	// Render the documents list page.
 	c.HTML(http.StatusOK, "documents.tmpl", gin.H{
-		"title":      "Mes Documents",
-		"navbar":     navbar,
-		"user":       user,
-		"documents":  documents,
-		"csrf_token": csrfToken,
+		"title":           "Mes Documents",
+		"usage":           usage,
+		"quarantined":     len(quarantined),
+		"trash":           len(trash),
+		"navbar":          navbar,
+		"user":            user,
+		"documents":       documents,
+		"folder":          current,
+		"breadcrumb":      folderBreadcrumb(folders, folderID),
+		"subfolders":      subfolders,
+		"documentFolders": documentFolders,
+		"tags":            tags,
+		"query":           query,
+		"searching":       searching,
+		"sortLinks":       sortLinks,
+		"csrf_token":      csrfToken,
 	})
 	// Save session changes if any (e.g., flash messages).
 	if err := session.Save(); err != nil {
@@ -76,11 +189,20 @@ func (h *DocumentHandlers) ShowUploadForm(c *gin.Context) {
 	csrfToken := c.MustGet("csrf_token").(string)
 	navbar := components.NavBar(user, csrfToken, session)
 
+	folders, err := h.documentService.GetFolders(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des dossiers: %v", err)
+	}
+
 	// Render the document upload form page.
 	c.HTML(http.StatusOK, "document_upload_form.tmpl", gin.H{
 		"title":      "Télécharger un document",
 		"navbar":     navbar,
 		"user":       user,
+		"folders":    services.FolderLabels(folders),
+		"selected":   c.Query("folder"),
+		"accept":     strings.Join(h.documentService.AcceptedExtensions(), ","),
+		"maxSize":    h.documentService.MaxFileSize(),
 		"csrf_token": csrfToken,
 	})
 	// Save session changes if any.
@@ -115,9 +237,28 @@ func (h *DocumentHandlers) UploadDocument(c *gin.Context) {
 		documentName = file.Filename
 	}
 
+	folderID, err := parseFolderID(c.PostForm("folder_id"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Dossier invalide"})
+		return
+	}
+	tags, err := services.ParseTags(c.PostForm("tags"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+
 	// Call the service to handle the file upload and database record creation.
-	if err := h.documentService.UploadDocument(user.ID, documentName, file); err != nil {
+	if err := h.documentService.UploadDocument(user, documentName, folderID, tags, file); err != nil {
 		log.Printf("ERREUR: Échec du téléchargement du document: %v", err)
+		if errors.Is(err, services.ErrUploadRejected) {
+			location := "/documents/upload"
+			if folderID != nil {
+				location += fmt.Sprintf("?folder=%d", *folderID)
+			}
+			h.redirectWithFlash(c, session, "error", err.Error(), location)
+			return
+		}
 		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Échec du téléchargement du document: " + err.Error()})
 		return
 	}
@@ -127,7 +268,7 @@ func (h *DocumentHandlers) UploadDocument(c *gin.Context) {
 	if err := session.Save(); err != nil {
 		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
 	}
-	c.Redirect(http.StatusFound, "/documents")
+	c.Redirect(http.StatusFound, folderLocation(folderID))
 }
 
 // DownloadDocument handles the download of a specific document.
@@ -155,17 +296,23 @@ func (h *DocumentHandlers) DownloadDocument(c *gin.Context) {
 		return
 	}
 
-	// Verify that the document belongs to the authenticated user for security.
+	// Verify that the document belongs to the authenticated user, or is published to them.
 	if document.UserID != user.ID {
-		c.HTML(http.StatusForbidden, "error.tmpl", gin.H{"error": "Accès non autorisé"})
-		return
+		allowed, err := h.libraryService.CanView(&user, document)
+		if err != nil {
+			log.Printf("ERREUR: Impossible de vérifier l'accès au document %d: %v", document.ID, err)
+		}
+		if !allowed {
+			c.HTML(http.StatusForbidden, "error.tmpl", gin.H{"error": "Accès non autorisé"})
+			return
+		}
 	}
 
-	// Serve the file to the client.
-	c.FileAttachment(document.FilePath, document.Name)
+	// Stream the file to the client.
+	h.serveFile(c, document.FilePath, document.FileSize, document.Name, document.MimeType)
 }
 
-// DeleteDocument handles the deletion of a document.
+// DeleteDocument handles the deletion of a document, which is moved to the trash.
 // It retrieves the document by ID, ensures it belongs to the authenticated user, and calls the service to delete it.
 func (h *DocumentHandlers) DeleteDocument(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
@@ -197,6 +344,10 @@ func (h *DocumentHandlers) DeleteDocument(c *gin.Context) {
 
 	// Call the service to delete the document. Handle any errors during deletion.
 	if err := h.documentService.DeleteDocument(uint(documentID)); err != nil {
+		if errors.Is(err, services.ErrDocumentAttached) {
+			h.redirectWithFlash(c, session, "error", err.Error(), "/documents")
+			return
+		}
 		log.Printf("ERREUR: Échec de la suppression du document: %v", err)
 		session.AddFlash("Échec de la suppression du document: "+err.Error(), "error")
 		if err := session.Save(); err != nil {
@@ -207,9 +358,486 @@ func (h *DocumentHandlers) DeleteDocument(c *gin.Context) {
 	}
 
 	// Add a success flash message and redirect to the documents list page.
-	session.AddFlash("Document supprimé avec succès !", "success")
+	session.AddFlash("Document placé dans la corbeille.", "success")
 	if err := session.Save(); err != nil {
 		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
 	}
 	c.Redirect(http.StatusFound, "/documents")
 }
+
+// ShowOrganizeForm displays the form to move a document to another folder and edit its tags.
+func (h *DocumentHandlers) ShowOrganizeForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.documentService.GetDocumentByID(uint(documentID))
+	if err != nil || document.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	folders, err := h.documentService.GetFolders(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des dossiers: %v", err)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	selected := ""
+	if document.FolderID != nil {
+		selected = strconv.FormatUint(uint64(*document.FolderID), 10)
+	}
+
+	c.HTML(http.StatusOK, "document_organize_form.tmpl", gin.H{
+		"title":        "Classer le document",
+		"navbar":       navbar,
+		"user":         user,
+		"document":     document,
+		"folders":      services.FolderLabels(folders),
+		"selected":     selected,
+		"tags":         strings.Join(document.Tags, ", "),
+		"visibilities": models.DocumentVisibilities(),
+		"csrf_token":   csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowOrganizeForm: %v", err)
+	}
+}
+
+// OrganizeDocument handles the submission of the form moving a document and editing its tags.
+func (h *DocumentHandlers) OrganizeDocument(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/organize/%d", documentID)
+	folderID, err := parseFolderID(c.PostForm("folder_id"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Dossier invalide.", location)
+		return
+	}
+	tags, err := services.ParseTags(c.PostForm("tags"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	visibility := models.DocumentVisibility(c.PostForm("visibility"))
+	if err := h.documentService.OrganizeDocument(user.ID, uint(documentID), folderID, tags, visibility); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Document classé.", folderLocation(folderID))
+}
+
+// CreateFolder handles the creation of a folder of the document library.
+func (h *DocumentHandlers) CreateFolder(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	parentID, err := parseFolderID(c.PostForm("parent_id"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Dossier parent invalide.", "/documents")
+		return
+	}
+	folder := models.DocumentFolder{UserID: user.ID, ParentID: parentID, Name: c.PostForm("name")}
+	if err := h.documentService.CreateFolder(&folder); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), folderLocation(parentID))
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Dossier « "+folder.Name+" » créé.", folderLocation(&folder.ID))
+}
+
+// RenameFolder handles the renaming of a folder of the document library.
+func (h *DocumentHandlers) RenameFolder(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de dossier invalide"})
+		return
+	}
+	id := uint(folderID)
+	if err := h.documentService.RenameFolder(user.ID, id, c.PostForm("name")); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), folderLocation(&id))
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Dossier renommé.", folderLocation(&id))
+}
+
+// DeleteFolder handles the deletion of an empty folder of the document library.
+func (h *DocumentHandlers) DeleteFolder(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	folderID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de dossier invalide"})
+		return
+	}
+	id := uint(folderID)
+	folder, err := h.documentService.GetFolder(user.ID, id)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/documents")
+		return
+	}
+	if err := h.documentService.DeleteFolder(user.ID, id); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), folderLocation(&id))
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Dossier supprimé.", folderLocation(folder.ParentID))
+}
+
+// ShowVersions displays the version history of a document, with the form to upload a new version.
+func (h *DocumentHandlers) ShowVersions(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.documentService.GetDocumentByID(uint(documentID))
+	if err != nil || document.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	versions, err := h.documentService.GetVersions(user.ID, document.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des versions: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des versions."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_versions.tmpl", gin.H{
+		"title":      "Versions du document",
+		"navbar":     navbar,
+		"user":       user,
+		"document":   document,
+		"versions":   versions,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowVersions: %v", err)
+	}
+}
+
+// UploadVersion handles the upload of a new version of a document.
+func (h *DocumentHandlers) UploadVersion(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/versions/%d", documentID)
+	file, err := c.FormFile("document")
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de la récupération du fichier: "+err.Error(), location)
+		return
+	}
+	version, err := h.documentService.UploadVersion(user, uint(documentID), c.PostForm("comment"), file)
+	if err != nil {
+		log.Printf("ERREUR: Échec du téléchargement de la nouvelle version: %v", err)
+		h.redirectWithFlash(c, session, "error", "Échec du téléchargement de la nouvelle version: "+err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("Version %d enregistrée.", version.Number), location)
+}
+
+// DownloadVersion serves the file of a version of a document.
+func (h *DocumentHandlers) DownloadVersion(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Numéro de version invalide"})
+		return
+	}
+	version, err := h.documentService.GetVersion(user.ID, uint(documentID), number)
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+
+	// Stream the file to the client.
+	h.serveFile(c, version.FilePath, version.FileSize, version.FileName, version.MimeType)
+}
+
+// RestoreVersion handles the restoration of a previous version of a document.
+func (h *DocumentHandlers) RestoreVersion(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/versions/%d", documentID)
+	number, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Numéro de version invalide.", location)
+		return
+	}
+	version, err := h.documentService.RestoreVersion(user, uint(documentID), number)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("Version %d restaurée : elle devient la version %d.", number, version.Number), location)
+}
+
+// GetPreview returns the preview of a document in JSON format, for the preview window of the
+// document library: the address of its thumbnail and the beginning of its text, if any.
+func (h *DocumentHandlers) GetPreview(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non authentifié"})
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, preview, err := h.documentService.GetPreview(user.ID, uint(documentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	thumbnail := ""
+	if preview.Thumbnail {
+		thumbnail = fmt.Sprintf("/documents/preview/%d/thumbnail?v=%d", document.ID, document.Version)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"name":      document.Name,
+		"mime_type": document.MimeType,
+		"status":    preview.Status,
+		"pending":   preview.Status == models.PreviewPending,
+		"thumbnail": thumbnail,
+		"excerpt":   preview.Excerpt,
+		"download":  fmt.Sprintf("/documents/download/%d", document.ID),
+	})
+}
+
+// GetThumbnail streams the thumbnail of a document, to be shown inline.
+func (h *DocumentHandlers) GetThumbnail(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	file, err := h.documentService.OpenThumbnail(c.Request.Context(), user.ID, uint(documentID))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	// The address of the thumbnail includes the version of the document, whose file never changes.
+	c.DataFromReader(http.StatusOK, -1, "image/jpeg", file, map[string]string{"Cache-Control": "private, max-age=86400"})
+}
+
+// ShowQuarantine displays the uploaded files in which the antivirus found malware.
+func (h *DocumentHandlers) ShowQuarantine(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	files, err := h.documentService.GetQuarantinedFiles(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des fichiers en quarantaine: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des fichiers en quarantaine."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_quarantine.tmpl", gin.H{
+		"title":      "Fichiers en quarantaine",
+		"navbar":     navbar,
+		"user":       user,
+		"files":      files,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowQuarantine: %v", err)
+	}
+}
+
+// DeleteQuarantinedFile handles the deletion of a quarantined file.
+func (h *DocumentHandlers) DeleteQuarantinedFile(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de fichier invalide"})
+		return
+	}
+	if err := h.documentService.DeleteQuarantinedFile(user.ID, uint(fileID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/documents/quarantine")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Fichier supprimé.", "/documents/quarantine")
+}
+
+// parseFolderID reads the ID of a folder from a form or query value; an empty value designates the
+// root of the document library.
+func parseFolderID(value string) (*uint, error) {
+	if value == "" {
+		return nil, nil
+	}
+	folderID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	id := uint(folderID)
+	return &id, nil
+}
+
+// folderLocation returns the address of the document page showing a folder.
+func folderLocation(folderID *uint) string {
+	if folderID == nil {
+		return "/documents"
+	}
+	return fmt.Sprintf("/documents?folder=%d", *folderID)
+}
+
+// folderBreadcrumb returns the folders leading to the current folder, for the navigation.
+func folderBreadcrumb(folders []models.DocumentFolder, folderID *uint) []models.DocumentFolder {
+	if folderID == nil {
+		return nil
+	}
+	return services.FolderPath(folders, *folderID)
+}
+
+// serveFile streams a stored file of the document library to the client as an attachment.
+func (h *DocumentHandlers) serveFile(c *gin.Context, key string, size int64, name, mimeType string) {
+	file, err := h.documentService.OpenFile(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("ERREUR: Impossible d'ouvrir le fichier %s: %v", key, err)
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+	sendFile(c, file, size, name, mimeType)
+}
+
+// sendFile streams an opened file to the client as an attachment named name, then closes it. The
+// file is copied as it is read, so that large files are not held in memory.
+func sendFile(c *gin.Context, file io.ReadCloser, size int64, name, mimeType string) {
+	defer file.Close()
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	if size <= 0 {
+		size = -1 // Unknown size: the file is sent without Content-Length.
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.DataFromReader(http.StatusOK, size, mimeType, file, map[string]string{"Content-Disposition": disposition})
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *DocumentHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
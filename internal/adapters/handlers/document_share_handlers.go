@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// shareLinkFlash is the flash key under which the URL of a new share link is passed to the page
+// of the share links, where it is shown once.
+const shareLinkFlash = "share_link"
+
+// defaultShareDays is the validity of a share link proposed by the creation form.
+const defaultShareDays = 7
+
+// ShowShareLinks displays the share links of a document, with the form to create one and the log
+// of the downloads made through them.
+func (h *DocumentHandlers) ShowShareLinks(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.documentService.GetDocumentByID(uint(documentID))
+	if err != nil || document.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	links, err := h.documentService.GetShareLinks(user.ID, document.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des liens de partage: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des liens de partage."})
+		return
+	}
+	downloads, err := h.documentService.GetShareDownloads(user.ID, document.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération du journal des téléchargements: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération du journal des téléchargements."})
+		return
+	}
+
+	// The links are listed by state, the active ones first; the log names the link of each download.
+	now := time.Now()
+	var active, inactive []models.DocumentShareLink
+	labels := map[uint]string{}
+	for _, link := range links {
+		if link.Status(now) == models.ShareActive {
+			active = append(active, link)
+		} else {
+			inactive = append(inactive, link)
+		}
+		labels[link.ID] = link.Label
+		if labels[link.ID] == "" {
+			labels[link.ID] = fmt.Sprintf("Lien n°%d", link.ID)
+		}
+	}
+
+	// The URL of a link just created is only available now.
+	createdURL := ""
+	if flashes := session.Flashes(shareLinkFlash); len(flashes) > 0 {
+		createdURL, _ = flashes[0].(string)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_shares.tmpl", gin.H{
+		"title":         "Partage du document",
+		"navbar":        navbar,
+		"user":          user,
+		"document":      document,
+		"active":        active,
+		"inactive":      inactive,
+		"downloads":     downloads,
+		"labels":        labels,
+		"now":           now,
+		"created_url":   createdURL,
+		"default_until": now.AddDate(0, 0, defaultShareDays).Format("2006-01-02"),
+		"min_date":      now.Format("2006-01-02"),
+		"max_date":      now.AddDate(1, 0, 0).Format("2006-01-02"),
+		"csrf_token":    csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowShareLinks: %v", err)
+	}
+}
+
+// CreateShareLink handles the creation of a share link for a document. The URL of the link is
+// shown once, on the page of the share links.
+func (h *DocumentHandlers) CreateShareLink(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/shares/%d", documentID)
+
+	// The link is valid until the end of the chosen day.
+	expiryDate, err := time.ParseInLocation("2006-01-02", c.PostForm("expires_at"), time.Local)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Date d'expiration invalide.", location)
+		return
+	}
+	link := &models.DocumentShareLink{
+		Label:     c.PostForm("label"),
+		ExpiresAt: expiryDate.AddDate(0, 0, 1).Add(-time.Second),
+	}
+	if value := strings.TrimSpace(c.PostForm("max_downloads")); value != "" {
+		if link.MaxDownloads, err = strconv.Atoi(value); err != nil {
+			h.redirectWithFlash(c, session, "error", "Nombre maximal de téléchargements invalide.", location)
+			return
+		}
+	}
+
+	shareURL, err := h.documentService.CreateShareLink(user, uint(documentID), link, c.PostForm("password"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Échec de la création du lien de partage: "+err.Error(), location)
+		return
+	}
+	session.AddFlash(shareURL, shareLinkFlash)
+	h.redirectWithFlash(c, session, "success", "Lien de partage créé. Copiez-le maintenant : il ne sera plus affiché.", location)
+}
+
+// RevokeShareLink handles the revocation of a share link of a document.
+func (h *DocumentHandlers) RevokeShareLink(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/shares/%d", documentID)
+	linkID, err := strconv.ParseUint(c.Param("link"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Lien de partage invalide.", location)
+		return
+	}
+	if err := h.documentService.RevokeShareLink(user.ID, uint(documentID), uint(linkID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Lien de partage révoqué.", location)
+}
+
+// ShowSharedDocument displays the public page of a share link, from which the document is
+// downloaded. It is reached through the secret link, without authentication. The download is a
+// POST, so that link previews and mail scanners fetching the page do not use up downloads.
+func (h *DocumentHandlers) ShowSharedDocument(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	setShareHeaders(c)
+	link, document, err := h.documentService.GetSharedDocument(c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de partage est invalide ou a expiré."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar and the download form.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(session.Get("user"), csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_share.tmpl", gin.H{
+		"title":      "Document partagé",
+		"navbar":     navbar,
+		"link":       link,
+		"document":   document,
+		"token":      c.Param("token"),
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowSharedDocument: %v", err)
+	}
+}
+
+// DownloadSharedDocument checks the password of a share link and streams the shared document.
+func (h *DocumentHandlers) DownloadSharedDocument(c *gin.Context) {
+	setShareHeaders(c)
+	token := c.Param("token")
+	document, file, err := h.documentService.OpenSharedDocument(c.Request.Context(), token, c.PostForm("password"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSharePassword):
+			session := c.MustGet("session").(sessions.Session)
+			h.redirectWithFlash(c, session, "error", "Mot de passe incorrect.", "/share/"+token)
+		case errors.Is(err, services.ErrShareUnavailable):
+			c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de partage est invalide ou a expiré."})
+		default:
+			log.Printf("ERREUR: Échec du téléchargement d'un document partagé: %v", err)
+			c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Stream the file to the client.
+	sendFile(c, file, document.FileSize, document.Name, document.MimeType)
+}
+
+// setShareHeaders keeps the token of a share link out of the Referer header of the requests made
+// from its page, and the page out of search engines.
+func setShareHeaders(c *gin.Context) {
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ShowSignatureRequests displays the signature requests of a document, with their signers, and
+// the form to ask members to sign it.
+func (h *DocumentHandlers) ShowSignatureRequests(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.documentService.GetDocumentByID(uint(documentID))
+	if err != nil || document.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	requests, err := h.signatureService.GetSignatureRequests(user.ID, document.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des demandes de signature: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des demandes de signature."})
+		return
+	}
+	members, err := h.signatureService.GetSignerCandidates(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des membres."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_signatures.tmpl", gin.H{
+		"title":      "Signature du document",
+		"navbar":     navbar,
+		"user":       user,
+		"document":   document,
+		"requests":   requests,
+		"members":    members,
+		"pending":    models.SignaturePending,
+		"completed":  models.SignatureCompleted,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowSignatureRequests: %v", err)
+	}
+}
+
+// CreateSignatureRequest handles the request of the signature of a document by members, who are
+// emailed their signing link.
+func (h *DocumentHandlers) CreateSignatureRequest(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/signatures/%d", documentID)
+
+	var memberIDs []uint
+	for _, value := range c.PostFormArray("member_id") {
+		memberID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			h.redirectWithFlash(c, session, "error", "Signataire invalide.", location)
+			return
+		}
+		memberIDs = append(memberIDs, uint(memberID))
+	}
+
+	request, failed, err := h.signatureService.CreateSignatureRequest(c.Request.Context(), user, uint(documentID), memberIDs, c.PostForm("message"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Échec de la demande de signature: "+err.Error(), location)
+		return
+	}
+	if len(failed) > 0 {
+		h.redirectWithFlash(c, session, "warning", fmt.Sprintf("Demande de signature créée, mais l'email n'a pas pu être envoyé à : %s. Utilisez « Relancer » pour réessayer.", strings.Join(failed, ", ")), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("Demande de signature envoyée à %d signataire(s).", len(request.Signers)), location)
+}
+
+// RemindSigners handles the reminder of the signers of a signature request who have not answered
+// yet, who are emailed a new signing link.
+func (h *DocumentHandlers) RemindSigners(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/signatures/%d", documentID)
+	requestID, err := strconv.ParseUint(c.Param("request"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Demande de signature invalide.", location)
+		return
+	}
+
+	sent, failed, err := h.signatureService.RemindSigners(user.ID, uint(documentID), uint(requestID))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Échec de la relance: "+err.Error(), location)
+		return
+	}
+	if len(failed) > 0 {
+		h.redirectWithFlash(c, session, "warning", fmt.Sprintf("%d relance(s) envoyée(s). L'email n'a pas pu être envoyé à : %s.", sent, strings.Join(failed, ", ")), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("%d relance(s) envoyée(s).", sent), location)
+}
+
+// CancelSignatureRequest handles the cancellation of a pending signature request.
+func (h *DocumentHandlers) CancelSignatureRequest(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/signatures/%d", documentID)
+	requestID, err := strconv.ParseUint(c.Param("request"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Demande de signature invalide.", location)
+		return
+	}
+	if err := h.signatureService.CancelSignatureRequest(user.ID, uint(documentID), uint(requestID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Demande de signature annulée.", location)
+}
+
+// DownloadSignedDocument streams the signed PDF of a completed signature request: the document
+// followed by its audit certificate.
+func (h *DocumentHandlers) DownloadSignedDocument(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	location := fmt.Sprintf("/documents/signatures/%d", documentID)
+	requestID, err := strconv.ParseUint(c.Param("request"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Demande de signature invalide.", location)
+		return
+	}
+	request, file, err := h.signatureService.OpenSignedDocument(c.Request.Context(), user.ID, uint(documentID), uint(requestID))
+	if err != nil {
+		log.Printf("ERREUR: Échec du téléchargement d'un document signé: %v", err)
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+
+	// Stream the file to the client.
+	sendFile(c, file, 0, services.SignedFileName(request), "application/pdf")
+}
+
+// ShowSigning displays the public page of a signing link, where the signer reads the document and
+// signs it, typing its name and optionally drawing its signature, or refuses to sign it. It is
+// reached through the personal link emailed to the signer, without authentication.
+func (h *DocumentHandlers) ShowSigning(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	setShareHeaders(c)
+	request, signer, err := h.signatureService.GetSigning(c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de signature est invalide ou n'est plus valable."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar and the forms.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(session.Get("user"), csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_sign.tmpl", gin.H{
+		"title":      "Signature de document",
+		"navbar":     navbar,
+		"request":    request,
+		"signer":     signer,
+		"can_sign":   request.Status == models.SignaturePending && signer.Status == models.SignerPending,
+		"completed":  request.Status == models.SignatureCompleted,
+		"signed":     signer.Status == models.SignerSigned,
+		"declined":   signer.Status == models.SignerDeclined,
+		"pad_width":  models.SignaturePadWidth,
+		"pad_height": models.SignaturePadHeight,
+		"token":      c.Param("token"),
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowSigning: %v", err)
+	}
+}
+
+// DownloadSigningDocument streams, to the signer of a signing link, the document to sign, or the
+// signed PDF once everyone signed.
+func (h *DocumentHandlers) DownloadSigningDocument(c *gin.Context) {
+	setShareHeaders(c)
+	name, mimeType, size, file, err := h.signatureService.OpenSigningDocument(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		if errors.Is(err, services.ErrSignatureUnavailable) {
+			c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de signature est invalide ou n'est plus valable."})
+			return
+		}
+		log.Printf("ERREUR: Échec du téléchargement d'un document à signer: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+
+	// Stream the file to the client.
+	sendFile(c, file, size, name, mimeType)
+}
+
+// SignDocument records the signature of the signer of a signing link.
+func (h *DocumentHandlers) SignDocument(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	setShareHeaders(c)
+	token := c.Param("token")
+	location := "/sign/" + token
+
+	request, err := h.signatureService.Sign(c.Request.Context(), token, c.PostForm("name"), c.PostForm("strokes"), c.PostForm("consent") == "on", c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if errors.Is(err, services.ErrSignatureUnavailable) {
+			c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de signature est invalide ou n'est plus valable."})
+			return
+		}
+		h.redirectWithFlash(c, session, "error", "Échec de la signature: "+err.Error(), location)
+		return
+	}
+	if request.Status == models.SignatureCompleted {
+		h.redirectWithFlash(c, session, "success", "Document signé. Tous les signataires ont signé : le document signé est disponible.", location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Document signé. Merci !", location)
+}
+
+// DeclineSignature records the refusal of the signer of a signing link to sign the document, which
+// ends the signature request.
+func (h *DocumentHandlers) DeclineSignature(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	setShareHeaders(c)
+	token := c.Param("token")
+	location := "/sign/" + token
+
+	if err := h.signatureService.Decline(token, c.PostForm("reason"), c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if errors.Is(err, services.ErrSignatureUnavailable) {
+			c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Ce lien de signature est invalide ou n'est plus valable."})
+			return
+		}
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Votre refus de signer a été enregistré.", location)
+}
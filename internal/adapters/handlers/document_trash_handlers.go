@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ShowTrash displays the deleted documents of the authenticated user, which can be restored or
+// permanently deleted until they are purged.
+func (h *DocumentHandlers) ShowTrash(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documents, err := h.documentService.GetTrash(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la corbeille: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération de la corbeille."})
+		return
+	}
+	purgeDates := map[uint]string{}
+	for _, document := range documents {
+		if date := h.documentService.PurgeDate(document); !date.IsZero() {
+			purgeDates[document.ID] = date.Format("02/01/2006")
+		}
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "document_trash.tmpl", gin.H{
+		"title":      "Corbeille",
+		"navbar":     navbar,
+		"user":       user,
+		"documents":  documents,
+		"purgeDates": purgeDates,
+		"trashDays":  h.documentService.TrashDays(),
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowTrash: %v", err)
+	}
+}
+
+// RestoreDocument handles the restoration of a document from the trash.
+func (h *DocumentHandlers) RestoreDocument(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.documentService.RestoreDocument(user.ID, uint(documentID))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/documents/trash")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("Document « %s » restauré.", document.Name), "/documents/trash")
+}
+
+// PurgeDocument handles the permanent deletion of a document of the trash, with its files.
+func (h *DocumentHandlers) PurgeDocument(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	if err := h.documentService.PurgeDocument(c.Request.Context(), user.ID, uint(documentID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/documents/trash")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Document supprimé définitivement.", "/documents/trash")
+}
+
+// EmptyTrash handles the permanent deletion of all the documents of the trash.
+func (h *DocumentHandlers) EmptyTrash(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	count, err := h.documentService.EmptyTrash(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Échec du vidage de la corbeille: %v", err)
+		h.redirectWithFlash(c, session, "error", fmt.Sprintf("Échec du vidage de la corbeille après %d document(s): %v", count, err), "/documents/trash")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("Corbeille vidée : %d document(s) supprimé(s) définitivement.", count), "/documents/trash")
+}
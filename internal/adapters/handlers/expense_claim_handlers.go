@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// claimFormLines is the number of expense rows shown in an empty claim form; more can be added in the page.
+const claimFormLines = 3
+
+// ExpenseClaimHandlers encapsulates the dependencies for the expense claim HTTP handlers.
+// It holds references to the ExpenseClaimService, which manages the claims and their review,
+// to the FinanceService, which provides the expense categories and the base currency,
+// and to the MemberService, which lists the members who can claim expenses.
+type ExpenseClaimHandlers struct {
+	claimService   *services.ExpenseClaimService
+	financeService *services.FinanceService
+	memberService  *services.MemberService
+}
+
+// NewExpenseClaimHandlers creates a new instance of ExpenseClaimHandlers.
+// It takes an ExpenseClaimService, a FinanceService and a MemberService as dependencies,
+// adhering to the dependency inversion principle.
+func NewExpenseClaimHandlers(claimService *services.ExpenseClaimService, financeService *services.FinanceService, memberService *services.MemberService) *ExpenseClaimHandlers {
+	return &ExpenseClaimHandlers{claimService: claimService, financeService: financeService, memberService: memberService}
+}
+
+// ListClaims displays the expense claims of the association, optionally for one member, the
+// reimbursements owed to each member, and the claims of other associations awaiting the review
+// of the authenticated user.
+func (h *ExpenseClaimHandlers) ListClaims(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	memberID, _ := strconv.ParseUint(c.Query("member_id"), 10, 64)
+	claims, err := h.claimService.GetClaimsByUserID(user.ID, uint(memberID))
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des notes de frais: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des notes de frais."})
+		return
+	}
+	reimbursements, err := h.claimService.GetReimbursements(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors du calcul des remboursements: %v", err)
+	}
+	queue, err := h.claimService.GetReviewQueue(user)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des notes de frais à examiner: %v", err)
+	}
+	members := h.members(user.ID)
+	names := make(map[uint]string, len(members))
+	for _, member := range members {
+		names[member.ID] = strings.TrimSpace(member.FirstName + " " + member.LastName)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "expense_claims.tmpl", gin.H{
+		"title":          "Notes de frais",
+		"navbar":         navbar,
+		"user":           user,
+		"claims":         claims,
+		"member_names":   names,
+		"member_id":      uint(memberID),
+		"members":        members,
+		"reimbursements": reimbursements,
+		"queue":          queue,
+		"base_currency":  h.baseCurrency(user.ID),
+		"csrf_token":     csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListClaims: %v", err)
+	}
+}
+
+// ShowCreateClaimForm displays the form to submit an expense claim on behalf of a member.
+func (h *ExpenseClaimHandlers) ShowCreateClaimForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	accounts, err := h.financeService.GetCategoryAccounts(user.ID, models.TypeExpense)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des catégories: %v", err)
+	}
+	memberID, _ := strconv.ParseUint(c.Query("member_id"), 10, 64)
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "expense_claim_form.tmpl", gin.H{
+		"title":         "Nouvelle note de frais",
+		"navbar":        navbar,
+		"user":          user,
+		"members":       h.members(user.ID),
+		"member_id":     uint(memberID),
+		"accounts":      accounts,
+		"lines":         make([]int, claimFormLines),
+		"today":         time.Now(),
+		"base_currency": h.baseCurrency(user.ID),
+		"csrf_token":    csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowCreateClaimForm: %v", err)
+	}
+}
+
+// CreateClaim handles the submission of an expense claim with its lines and receipts.
+func (h *ExpenseClaimHandlers) CreateClaim(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var claim models.ExpenseClaim
+	if err := c.ShouldBind(&claim); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données de la note de frais invalides: "+err.Error(), "/finance/claims/new")
+		return
+	}
+	claim.UserID = user.ID
+	memberID, err := strconv.ParseUint(c.PostForm("member_id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Veuillez choisir le membre qui a avancé les frais.", "/finance/claims/new")
+		return
+	}
+	claim.MemberID = uint(memberID)
+
+	lines, receipts, err := h.bindLines(c, user.ID)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/claims/new")
+		return
+	}
+	claim.Lines = lines
+	if err := h.claimService.SubmitClaim(&claim, receipts); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'enregistrement de la note de frais: "+err.Error(), "/finance/claims/new")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Note de frais soumise au trésorier.", fmt.Sprintf("/finance/claims/%d", claim.ID))
+}
+
+// ShowClaim displays an expense claim with its lines, receipts, review and reimbursement.
+func (h *ExpenseClaimHandlers) ShowClaim(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de note de frais invalide"})
+		return
+	}
+	claim, err := h.claimService.GetClaim(user, uint(id))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+	canReview, err := h.claimService.CanReview(user, claim)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la vérification du trésorier: %v", err)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "expense_claim.tmpl", gin.H{
+		"title":       fmt.Sprintf("Note de frais n°%d", claim.ID),
+		"navbar":      navbar,
+		"user":        user,
+		"claim":       claim,
+		"total":       claim.Total(),
+		"member_name": h.claimService.MemberName(claim.MemberID),
+		"can_review":  canReview,
+		"is_owner":    claim.UserID == user.ID,
+		"today":       time.Now(),
+		"csrf_token":  csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowClaim: %v", err)
+	}
+}
+
+// DownloadReceipt sends the receipt of a claim line to the owner of the association or its treasurer.
+func (h *ExpenseClaimHandlers) DownloadReceipt(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	claimID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de note de frais invalide"})
+		return
+	}
+	lineID, err := strconv.ParseUint(c.Param("line"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de ligne invalide"})
+		return
+	}
+	document, file, err := h.claimService.OpenReceipt(c.Request.Context(), user, uint(claimID), uint(lineID))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+	sendFile(c, file, document.FileSize, document.Name, document.MimeType)
+}
+
+// ApproveClaim handles the approval of an expense claim by the treasurer.
+func (h *ExpenseClaimHandlers) ApproveClaim(c *gin.Context) {
+	h.decide(c, "Note de frais approuvée : les dépenses ont été enregistrées.", func(user models.User, id uint) error {
+		return h.claimService.Approve(user, id, c.PostForm("comment"))
+	})
+}
+
+// RejectClaim handles the rejection of an expense claim by the treasurer.
+func (h *ExpenseClaimHandlers) RejectClaim(c *gin.Context) {
+	h.decide(c, "Note de frais rejetée.", func(user models.User, id uint) error {
+		return h.claimService.Reject(user, id, c.PostForm("comment"))
+	})
+}
+
+// MarkClaimPaid handles the recording of the reimbursement of a claim to the member.
+func (h *ExpenseClaimHandlers) MarkClaimPaid(c *gin.Context) {
+	h.decide(c, "Remboursement enregistré.", func(user models.User, id uint) error {
+		paidAt, err := time.Parse("2006-01-02", c.PostForm("paid_at"))
+		if err != nil {
+			return fmt.Errorf("date de remboursement invalide")
+		}
+		return h.claimService.MarkPaid(user, id, paidAt, c.PostForm("payment_ref"))
+	})
+}
+
+// DeleteClaim handles the deletion of a claim that has not been approved.
+func (h *ExpenseClaimHandlers) DeleteClaim(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de note de frais invalide"})
+		return
+	}
+	if err := h.claimService.DeleteClaim(user.ID, uint(id)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/claims")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Note de frais supprimée.", "/finance/claims")
+}
+
+// decide applies an action of the treasurer to the claim identified in the URL and redirects to
+// the claim with the outcome.
+func (h *ExpenseClaimHandlers) decide(c *gin.Context, success string, action func(user models.User, id uint) error) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de note de frais invalide"})
+		return
+	}
+	location := fmt.Sprintf("/finance/claims/%d", id)
+	if err := action(user, uint(id)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", success, location)
+}
+
+// bindLines builds the lines of a claim from the parallel form arrays, skipping empty rows, and
+// returns the receipt uploaded for each kept line in the "receipt_<row>" file fields.
+func (h *ExpenseClaimHandlers) bindLines(c *gin.Context, userID uint) ([]models.ExpenseClaimLine, []*multipart.FileHeader, error) {
+	var files map[string][]*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File
+	} else if !errors.Is(err, http.ErrNotMultipart) {
+		return nil, nil, err
+	}
+	decimals, _ := models.CurrencyDecimals(h.baseCurrency(userID))
+
+	dates, descriptions := c.PostFormArray("line_date"), c.PostFormArray("line_description")
+	categories, amounts := c.PostFormArray("line_category"), c.PostFormArray("line_amount")
+	var lines []models.ExpenseClaimLine
+	var receipts []*multipart.FileHeader
+	for i := range descriptions {
+		receipt := files[fmt.Sprintf("receipt_%d", i)]
+		if strings.TrimSpace(descriptions[i]) == "" && strings.TrimSpace(formValueAt(amounts, i)) == "" && len(receipt) == 0 {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", formValueAt(dates, i))
+		if err != nil {
+			return nil, nil, fmt.Errorf("ligne %d: date invalide", i+1)
+		}
+		amount, err := services.ParseMinorUnits(formValueAt(amounts, i), decimals)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ligne %d: montant invalide: %w", i+1, err)
+		}
+		lines = append(lines, models.ExpenseClaimLine{
+			Date:        date,
+			Description: descriptions[i],
+			AccountCode: formValueAt(categories, i),
+			Amount:      amount,
+		})
+		if len(receipt) > 0 {
+			receipts = append(receipts, receipt[0])
+		} else {
+			receipts = append(receipts, nil)
+		}
+	}
+	return lines, receipts, nil
+}
+
+// members returns the members who can claim expenses.
+// Errors are logged and yield an empty list.
+func (h *ExpenseClaimHandlers) members(userID uint) []models.Member {
+	members, err := h.memberService.GetMembersByUserID(userID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+	}
+	return members
+}
+
+// baseCurrency returns the base currency of the association, in which claims are expressed.
+// Errors are logged and yield the default currency.
+func (h *ExpenseClaimHandlers) baseCurrency(userID uint) string {
+	currency, err := h.financeService.GetBaseCurrency(userID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la devise de référence: %v", err)
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *ExpenseClaimHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandlers encapsulates the dependencies for the accounting export HTTP handlers.
+// It holds a reference to the ExportService, which generates and checks the exported files.
+type ExportHandlers struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandlers creates a new instance of ExportHandlers.
+// It takes an ExportService as a dependency, adhering to the dependency inversion principle.
+func NewExportHandlers(exportService *services.ExportService) *ExportHandlers {
+	return &ExportHandlers{exportService: exportService}
+}
+
+// ShowExportForm displays the form selecting the format and the period of an accounting export.
+func (h *ExportHandlers) ShowExportForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	currentYear, err := h.exportService.CurrentFiscalYear(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de l'exercice en cours: %v", err)
+		currentYear = time.Now().Year()
+	}
+	now := time.Now()
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "export.tmpl", gin.H{
+		"title":      "Export comptable",
+		"navbar":     navbar,
+		"user":       user,
+		"formats":    models.ExportFormats,
+		"year":       currentYear - 1, // The accountant usually asks for the year just closed.
+		"from":       time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		"to":         now,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowExportForm: %v", err)
+	}
+}
+
+// DownloadExport generates the requested export and sends it as a file. The period is either a
+// fiscal year ("period=year") or a date range whose end date is included.
+func (h *ExportHandlers) DownloadExport(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	from, to, err := h.exportPeriod(c, user.ID)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/export")
+		return
+	}
+	file, err := h.exportService.Export(user.ID, models.ExportFormat(c.Query("format")), from, to)
+	if err != nil {
+		log.Printf("ERREUR: Échec de l'export comptable: %v", err)
+		h.redirectWithFlash(c, session, "error", "Export impossible: "+err.Error(), "/finance/export")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.Data(http.StatusOK, file.ContentType, file.Content)
+}
+
+// exportPeriod reads the period of an export from the query: a fiscal year, or the "from" and "to"
+// dates (YYYY-MM-DD). It returns the first day of the period and the day following its end.
+func (h *ExportHandlers) exportPeriod(c *gin.Context, userID uint) (time.Time, time.Time, error) {
+	if c.Query("period") == "year" {
+		year, err := strconv.Atoi(c.Query("year"))
+		if err != nil || year < 1900 || year > 9999 {
+			return time.Time{}, time.Time{}, fmt.Errorf("exercice invalide")
+		}
+		return h.exportService.FiscalYearPeriod(userID, year)
+	}
+	from, err := time.Parse("2006-01-02", c.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("date de début invalide")
+	}
+	to, err := time.Parse("2006-01-02", c.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("date de fin invalide")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *ExportHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,16 +15,30 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// Horizons of the upcoming obligations preview and of the cash-flow forecast on the transactions page.
+const (
+	upcomingDays   = 30
+	forecastMonths = 6
+)
+
 // FinanceHandlers encapsulates the dependencies for financial HTTP handlers.
-// It holds a reference to the FinanceService, which contains the business logic for financial operations.
+// It holds a reference to the FinanceService, which contains the business logic for financial operations,
+// to the DocumentService, which stores the receipts attached to transactions, to the MemberService,
+// which provides the members an income can come from, to the InvoiceService, which tells which
+// transactions have been invoiced, and to the RecurringService, which forecasts upcoming obligations.
 type FinanceHandlers struct {
-	financeService *services.FinanceService
+	financeService   *services.FinanceService
+	documentService  *services.DocumentService
+	memberService    *services.MemberService
+	invoiceService   *services.InvoiceService
+	recurringService *services.RecurringService
 }
 
 // NewFinanceHandlers creates a new instance of FinanceHandlers.
-// It takes a FinanceService as a dependency, adhering to the dependency inversion principle.
-func NewFinanceHandlers(financeService *services.FinanceService) *FinanceHandlers {
-	return &FinanceHandlers{financeService: financeService}
+// It takes a FinanceService, a DocumentService, a MemberService, an InvoiceService and a RecurringService
+// as dependencies, adhering to the dependency inversion principle.
+func NewFinanceHandlers(financeService *services.FinanceService, documentService *services.DocumentService, memberService *services.MemberService, invoiceService *services.InvoiceService, recurringService *services.RecurringService) *FinanceHandlers {
+	return &FinanceHandlers{financeService: financeService, documentService: documentService, memberService: memberService, invoiceService: invoiceService, recurringService: recurringService}
 }
 
 // ListTransactions displays a list of financial transactions for the authenticated user.
@@ -43,6 +60,36 @@ func (h *FinanceHandlers) ListTransactions(c *gin.Context) {
 		return
 	}
 
+	// Count the attached documents to show attachment indicators and flag expenses lacking a receipt.
+	attachmentCounts, err := h.documentService.GetAttachmentCounts(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors du comptage des pièces jointes: %v", err)
+		attachmentCounts = map[uint]int64{}
+	}
+	missingReceipts, err := h.financeService.FindMissingReceipts(user.ID, transactions, attachmentCounts)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la recherche des justificatifs manquants: %v", err)
+	}
+	invoiced, err := h.invoiceService.GetInvoicedTransactions(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des factures: %v", err)
+	}
+	// Transactions of closed fiscal years can no longer be edited or deleted.
+	closed, err := h.financeService.FindClosedTransactions(user.ID, transactions)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la vérification des exercices clôturés: %v", err)
+	}
+
+	// Preview the recurring obligations of the coming weeks and project the cash flow.
+	upcoming, err := h.recurringService.GetUpcoming(user.ID, time.Now().AddDate(0, 0, upcomingDays))
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des échéances à venir: %v", err)
+	}
+	forecast, err := h.recurringService.GetForecast(user.ID, forecastMonths)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors du calcul de la prévision de trésorerie: %v", err)
+	}
+
 	// Retrieve CSRF token for the navigation bar.
 	csrfToken := c.MustGet("csrf_token").(string)
 	navbar := components.NavBar(user, csrfToken, session)
@@ -54,6 +101,14 @@ func (h *FinanceHandlers) ListTransactions(c *gin.Context) {
 		"user":         user,
 		"transactions": transactions,
 		"csrf_token":   csrfToken,
+
+		"attachment_counts": attachmentCounts,
+		"missing_receipts":  missingReceipts,
+		"invoiced":          invoiced,
+		"closed":            closed,
+		"upcoming":          upcoming,
+		"upcoming_days":     upcomingDays,
+		"forecast":          forecast,
 	})
 	// Save session changes if any (e.g., flash messages).
 	if err := session.Save(); err != nil {
@@ -79,11 +134,17 @@ func (h *FinanceHandlers) ShowCreateTransactionForm(c *gin.Context) {
 
 	// Render the transaction creation form.
 	c.HTML(http.StatusOK, "transaction_form.tmpl", gin.H{
-		"title":       "Ajouter une nouvelle transaction",
-		"navbar":      navbar,
-		"user":        user,
-		"csrf_token":  csrfToken,
-		"transaction": models.Transaction{Date: time.Now()}, // Default values
+		"title":            "Ajouter une nouvelle transaction",
+		"navbar":           navbar,
+		"user":             user,
+		"csrf_token":       csrfToken,
+		"transaction":      models.Transaction{Date: time.Now()}, // Default values
+		"income_accounts":  h.categoryAccounts(user.ID, models.TypeIncome),
+		"expense_accounts": h.categoryAccounts(user.ID, models.TypeExpense),
+		"base_currency":    h.baseCurrency(user.ID),
+		"currencies":       models.SupportedCurrencies(),
+		"members":          h.members(user.ID),
+		"member_id":        uint(0),
 	})
 	// Save session changes if any.
 	if err := session.Save(); err != nil {
@@ -112,12 +173,33 @@ func (h *FinanceHandlers) CreateTransaction(c *gin.Context) {
 
 	newTransaction.UserID = user.ID // Assign the current user's ID to the new transaction.
 
+	// Parse the amount exactly, in the selected currency.
+	amount, err := h.bindAmount(c, user.ID)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Montant invalide: " + err.Error()})
+		return
+	}
+	newTransaction.Amount = amount
+	if newTransaction.MemberID, err = h.bindMember(c, user.ID); err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+
 	// Call the service to create the transaction. Handle any errors during creation.
 	if err := h.financeService.CreateTransaction(&newTransaction); err != nil {
 		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la création de la transaction: " + err.Error()})
 		return
 	}
 
+	// Attach the receipts uploaded along with the transaction, if any.
+	if err := h.attachUploadedFiles(c, user.ID, newTransaction.ID); err != nil {
+		log.Printf("ERREUR: Échec de l'ajout des pièces jointes: %v", err)
+		session.AddFlash("Transaction créée, mais l'ajout des pièces jointes a échoué: "+err.Error(), "error")
+		if err := session.Save(); err != nil {
+			log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+		}
+	}
+
 	// Redirect to the transactions list page upon successful creation.
 	c.Redirect(http.StatusFound, "/finance/transactions")
 }
@@ -153,17 +235,29 @@ func (h *FinanceHandlers) ShowEditTransactionForm(c *gin.Context) {
 		return
 	}
 
+	// Preselect the member the transaction is linked to, if any.
+	var memberID uint
+	if transaction.MemberID != nil {
+		memberID = *transaction.MemberID
+	}
+
 	// Retrieve CSRF token for the navigation bar.
 	csrfToken := c.MustGet("csrf_token").(string)
 	navbar := components.NavBar(user, csrfToken, session)
 
 	// Render the transaction edit form.
 	c.HTML(http.StatusOK, "transaction_form.tmpl", gin.H{
-		"title":       "Modifier la transaction",
-		"navbar":      navbar,
-		"user":        user,
-		"csrf_token":  csrfToken,
-		"transaction": transaction,
+		"title":            "Modifier la transaction",
+		"navbar":           navbar,
+		"user":             user,
+		"csrf_token":       csrfToken,
+		"transaction":      transaction,
+		"income_accounts":  h.categoryAccounts(user.ID, models.TypeIncome),
+		"expense_accounts": h.categoryAccounts(user.ID, models.TypeExpense),
+		"base_currency":    h.baseCurrency(user.ID),
+		"currencies":       models.SupportedCurrencies(),
+		"members":          h.members(user.ID),
+		"member_id":        memberID,
 	})
 	// Save session changes if any.
 	if err := session.Save(); err != nil {
@@ -209,11 +303,27 @@ func (h *FinanceHandlers) UpdateTransaction(c *gin.Context) {
 		return
 	}
 
+	// Parse the amount exactly, in the selected currency.
+	updatedTransaction.Amount, err = h.bindAmount(c, user.ID)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Montant invalide: " + err.Error()})
+		return
+	}
+
+	memberID, err := h.bindMember(c, user.ID)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": err.Error()})
+		return
+	}
+
 	// Update the fields of the existing transaction with the new data from the form.
 	existingTransaction.Amount = updatedTransaction.Amount
+	existingTransaction.ExchangeRate = updatedTransaction.ExchangeRate
 	existingTransaction.Type = updatedTransaction.Type
 	existingTransaction.Description = updatedTransaction.Description
 	existingTransaction.Date = updatedTransaction.Date
+	existingTransaction.AccountCode = updatedTransaction.AccountCode
+	existingTransaction.MemberID = memberID
 
 	// Call the service to update the transaction. Handle any errors during update.
 	if err := h.financeService.UpdateTransaction(existingTransaction); err != nil {
@@ -260,7 +370,210 @@ func (h *FinanceHandlers) DeleteTransaction(c *gin.Context) {
 		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la suppression de la transaction: " + err.Error()})
 		return
 	}
+	// Attached receipts are kept in the document library, unlinked from the deleted transaction.
+	if err := h.documentService.DetachTransactionDocuments(uint(transactionID)); err != nil {
+		log.Printf("ERREUR: Erreur lors du détachement des pièces jointes de la transaction %d: %v", transactionID, err)
+	}
 
 	// Redirect to the transactions list page upon successful deletion.
 	c.Redirect(http.StatusFound, "/finance/transactions")
 }
+
+// categoryAccounts returns the accounts offered as categories in the transaction form.
+// Errors are logged and yield an empty list, the transaction then being booked on the default account.
+func (h *FinanceHandlers) categoryAccounts(userID uint, transactionType models.TransactionType) []models.Account {
+	accounts, err := h.financeService.GetCategoryAccounts(userID, transactionType)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des catégories: %v", err)
+	}
+	return accounts
+}
+
+// bindAmount parses the "amount" and "currency" form fields into an exact amount.
+// The base currency of the association is used when no currency is selected.
+func (h *FinanceHandlers) bindAmount(c *gin.Context, userID uint) (models.Money, error) {
+	currency := c.PostForm("currency")
+	if currency == "" {
+		var err error
+		if currency, err = h.financeService.GetBaseCurrency(userID); err != nil {
+			return models.Money{}, err
+		}
+	}
+	return services.ParseMoney(c.PostForm("amount"), currency)
+}
+
+// bindMember parses the optional "member_id" form field and ensures the member belongs to the user.
+func (h *FinanceHandlers) bindMember(c *gin.Context, userID uint) (*uint, error) {
+	value := c.PostForm("member_id")
+	if value == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("membre invalide")
+	}
+	member, err := h.memberService.GetMemberByID(uint(id))
+	if err != nil || member.UserID != userID {
+		return nil, fmt.Errorf("membre non trouvé")
+	}
+	return &member.ID, nil
+}
+
+// members returns the members offered in the transaction form.
+// Errors are logged and yield an empty list.
+func (h *FinanceHandlers) members(userID uint) []models.Member {
+	members, err := h.memberService.GetMembersByUserID(userID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+	}
+	return members
+}
+
+// baseCurrency returns the base currency of the association for the transaction form.
+// Errors are logged and yield the default currency.
+func (h *FinanceHandlers) baseCurrency(userID uint) string {
+	currency, err := h.financeService.GetBaseCurrency(userID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la devise de référence: %v", err)
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+// ShowAttachments displays the documents attached to a transaction and the form to attach more.
+func (h *FinanceHandlers) ShowAttachments(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transaction, ok := h.ownedTransaction(c, user.ID)
+	if !ok {
+		return
+	}
+
+	documents, err := h.documentService.GetTransactionDocuments(transaction.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des pièces jointes: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des pièces jointes."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "transaction_attachments.tmpl", gin.H{
+		"title":       "Pièces justificatives",
+		"navbar":      navbar,
+		"user":        user,
+		"transaction": transaction,
+		"documents":   documents,
+		"csrf_token":  csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowAttachments: %v", err)
+	}
+}
+
+// UploadAttachments handles the submission of files to attach to a transaction.
+func (h *FinanceHandlers) UploadAttachments(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transaction, ok := h.ownedTransaction(c, user.ID)
+	if !ok {
+		return
+	}
+
+	location := fmt.Sprintf("/finance/transactions/attachments/%d", transaction.ID)
+	if err := h.attachUploadedFiles(c, user.ID, transaction.ID); err != nil {
+		log.Printf("ERREUR: Échec de l'ajout des pièces jointes: %v", err)
+		session.AddFlash("Échec de l'ajout des pièces jointes: "+err.Error(), "error")
+	} else {
+		session.AddFlash("Pièces jointes ajoutées avec succès !", "success")
+	}
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// DetachAttachment unlinks a document from its transaction. The document stays in the document library.
+func (h *FinanceHandlers) DetachAttachment(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transaction, ok := h.ownedTransaction(c, user.ID)
+	if !ok {
+		return
+	}
+	documentID, err := strconv.ParseUint(c.Param("documentId"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+
+	location := fmt.Sprintf("/finance/transactions/attachments/%d", transaction.ID)
+	if err := h.documentService.DetachDocument(user.ID, transaction, uint(documentID)); err != nil {
+		session.AddFlash("Erreur lors du retrait de la pièce jointe: "+err.Error(), "error")
+	} else {
+		session.AddFlash("Pièce jointe retirée de la transaction.", "success")
+	}
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// ownedTransaction loads the transaction identified by the "id" URL parameter and ensures it belongs
+// to the given user. It renders an error page and returns false otherwise.
+func (h *FinanceHandlers) ownedTransaction(c *gin.Context, userID uint) (*models.Transaction, bool) {
+	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de transaction invalide"})
+		return nil, false
+	}
+	transaction, err := h.financeService.GetTransactionByID(uint(transactionID))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Transaction non trouvée"})
+		return nil, false
+	}
+	if transaction.UserID != userID {
+		c.HTML(http.StatusForbidden, "error.tmpl", gin.H{"error": "Accès non autorisé"})
+		return nil, false
+	}
+	return transaction, true
+}
+
+// attachUploadedFiles stores the files of the "attachments" multipart field as documents attached
+// to the transaction. Requests without files are accepted.
+func (h *FinanceHandlers) attachUploadedFiles(c *gin.Context, userID, transactionID uint) error {
+	form, err := c.MultipartForm()
+	if err != nil {
+		if errors.Is(err, http.ErrNotMultipart) {
+			return nil
+		}
+		return err
+	}
+	for _, file := range form.File["attachments"] {
+		if _, err := h.documentService.AttachToTransaction(userID, transactionID, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// FiscalPeriodHandlers encapsulates the dependencies for the fiscal year closing HTTP handlers.
+// It holds a reference to the FiscalPeriodService, which closes and reopens fiscal years.
+type FiscalPeriodHandlers struct {
+	periodService *services.FiscalPeriodService
+}
+
+// NewFiscalPeriodHandlers creates a new instance of FiscalPeriodHandlers.
+// It takes a FiscalPeriodService as a dependency, adhering to the dependency inversion principle.
+func NewFiscalPeriodHandlers(periodService *services.FiscalPeriodService) *FiscalPeriodHandlers {
+	return &FiscalPeriodHandlers{periodService: periodService}
+}
+
+// ListFiscalYears displays the fiscal years of the association with their closing status and the
+// log of the closings and reopenings.
+func (h *FiscalPeriodHandlers) ListFiscalYears(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	years, err := h.periodService.GetFiscalYears(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des exercices: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des exercices."})
+		return
+	}
+	events, err := h.periodService.GetEvents(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération du journal des clôtures: %v", err)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "fiscal_periods.tmpl", gin.H{
+		"title":      "Clôture des exercices",
+		"navbar":     navbar,
+		"user":       user,
+		"years":      years,
+		"events":     events,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListFiscalYears: %v", err)
+	}
+}
+
+// CloseFiscalYear handles the closing of a fiscal year by the owner of the association.
+func (h *FiscalPeriodHandlers) CloseFiscalYear(c *gin.Context) {
+	h.apply(c, "Exercice clôturé : ses opérations ne peuvent plus être modifiées.", h.periodService.CloseFiscalYear)
+}
+
+// ReopenFiscalYear handles the reopening of a closed fiscal year by the owner of the association.
+func (h *FiscalPeriodHandlers) ReopenFiscalYear(c *gin.Context) {
+	h.apply(c, "Exercice rouvert.", h.periodService.ReopenFiscalYear)
+}
+
+// apply performs a closing action on the fiscal year identified in the URL, with the reason given
+// in the form, and redirects to the list of fiscal years with the outcome.
+func (h *FiscalPeriodHandlers) apply(c *gin.Context, success string, action func(actor models.User, ownerID uint, year int, reason string) error) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 1900 || year > 9999 {
+		h.redirectWithFlash(c, session, "error", "Exercice invalide.", "/finance/periods")
+		return
+	}
+	if err := action(user, user.ID, year, c.PostForm("reason")); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/periods")
+		return
+	}
+	log.Printf("Exercice %d de l'association %d: %s (par l'utilisateur %d)", year, user.ID, success, user.ID)
+	h.redirectWithFlash(c, session, "success", success, "/finance/periods")
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *FiscalPeriodHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
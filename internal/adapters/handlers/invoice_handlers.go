@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// InvoiceHandlers encapsulates the dependencies for the invoice and donation receipt HTTP handlers.
+// It holds references to the InvoiceService, which issues and renders the documents, to the
+// FinanceService, which provides the invoiced transactions, and to the MemberService, which
+// provides the recipients.
+type InvoiceHandlers struct {
+	invoiceService *services.InvoiceService
+	financeService *services.FinanceService
+	memberService  *services.MemberService
+}
+
+// NewInvoiceHandlers creates a new instance of InvoiceHandlers.
+// It takes an InvoiceService, a FinanceService and a MemberService as dependencies,
+// adhering to the dependency inversion principle.
+func NewInvoiceHandlers(invoiceService *services.InvoiceService, financeService *services.FinanceService, memberService *services.MemberService) *InvoiceHandlers {
+	return &InvoiceHandlers{invoiceService: invoiceService, financeService: financeService, memberService: memberService}
+}
+
+// ListInvoices displays the invoices and donation receipts of the authenticated user, filtered by
+// kind and fiscal year, along with the form generating the annual donation receipts.
+func (h *InvoiceHandlers) ListInvoices(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	kind := models.InvoiceKind(c.Query("kind"))
+	fiscalYear, _ := strconv.Atoi(c.Query("year"))
+	invoices, err := h.invoiceService.GetInvoices(user.ID, kind, fiscalYear)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des factures: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des factures."})
+		return
+	}
+	currentYear, err := h.invoiceService.CurrentFiscalYear(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de l'exercice en cours: %v", err)
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "invoices.tmpl", gin.H{
+		"title":      "Factures et reçus fiscaux",
+		"navbar":     navbar,
+		"user":       user,
+		"invoices":   invoices,
+		"kind":       kind,
+		"year":       fiscalYear,
+		"kinds":      []models.InvoiceKind{models.KindInvoice, models.KindDonationReceipt},
+		"batch_year": currentYear - 1, // Receipts are usually issued for the year just closed.
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListInvoices: %v", err)
+	}
+}
+
+// ShowCreateInvoiceForm displays the form issuing an invoice or a donation receipt for a transaction.
+func (h *InvoiceHandlers) ShowCreateInvoiceForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transactionID, err := strconv.ParseUint(c.Query("transaction_id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de transaction invalide"})
+		return
+	}
+	transaction, err := h.financeService.GetTransactionByID(uint(transactionID))
+	if err != nil || transaction.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Transaction non trouvée"})
+		return
+	}
+	if transaction.Type != models.TypeIncome {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Seule une recette peut faire l'objet d'une facture ou d'un reçu."})
+		return
+	}
+	members, err := h.memberService.GetMembersByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+	}
+
+	// Preselect the member the income is linked to, if any.
+	var memberID uint
+	if transaction.MemberID != nil {
+		memberID = *transaction.MemberID
+	}
+	// Donations get a tax receipt by default, other incomes an invoice.
+	kind := models.KindInvoice
+	if transaction.AccountCode == models.DonationAccountCode {
+		kind = models.KindDonationReceipt
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "invoice_form.tmpl", gin.H{
+		"title":           "Émettre une facture ou un reçu",
+		"navbar":          navbar,
+		"user":            user,
+		"transaction":     transaction,
+		"members":         members,
+		"member_id":       memberID,
+		"kind":            kind,
+		"kinds":           []models.InvoiceKind{models.KindInvoice, models.KindDonationReceipt},
+		"payment_methods": models.PaymentMethods,
+		"csrf_token":      csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowCreateInvoiceForm: %v", err)
+	}
+}
+
+// CreateInvoice handles the submission of the invoice form. The number is assigned at issue.
+func (h *InvoiceHandlers) CreateInvoice(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	transactionID, err := strconv.ParseUint(c.PostForm("transaction_id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID de transaction invalide.", "/finance/transactions")
+		return
+	}
+	memberID, err := strconv.ParseUint(c.PostForm("member_id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Veuillez choisir le membre destinataire.", fmt.Sprintf("/finance/invoices/new?transaction_id=%d", transactionID))
+		return
+	}
+
+	invoice, err := h.invoiceService.IssueInvoice(user.ID, models.InvoiceKind(c.PostForm("kind")), uint(transactionID), uint(memberID), c.PostForm("payment_method"), c.PostForm("description"))
+	if err != nil && invoice == nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'émission du document: "+err.Error(), fmt.Sprintf("/finance/invoices/new?transaction_id=%d", transactionID))
+		return
+	}
+	if err != nil {
+		log.Printf("ERREUR: %v", err)
+	}
+	h.redirectWithFlash(c, session, "success", fmt.Sprintf("%s %s émis(e) avec succès !", invoice.Kind, invoice.Number), "/finance/invoices")
+}
+
+// IssueDonationReceipts generates the donation receipts of a fiscal year for all donors.
+func (h *InvoiceHandlers) IssueDonationReceipts(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	fiscalYear, err := strconv.Atoi(c.PostForm("year"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Exercice invalide.", "/finance/invoices")
+		return
+	}
+
+	receipts, skipped, err := h.invoiceService.IssueAnnualDonationReceipts(user.ID, fiscalYear)
+	location := "/finance/invoices?" + url.Values{"kind": {string(models.KindDonationReceipt)}}.Encode()
+	if err != nil {
+		log.Printf("ERREUR: Échec de l'émission des reçus fiscaux: %v", err)
+		h.redirectWithFlash(c, session, "error", fmt.Sprintf("Émission interrompue après %d reçu(s): %v", len(receipts), err), location)
+		return
+	}
+	message := fmt.Sprintf("%d reçu(s) fiscal(aux) émis.", len(receipts))
+	if skipped > 0 {
+		message += fmt.Sprintf(" %d don(s) sans membre rattaché ont été ignorés.", skipped)
+	}
+	h.redirectWithFlash(c, session, "success", message, location)
+}
+
+// DownloadInvoice sends an invoice or a donation receipt as a PDF file.
+func (h *InvoiceHandlers) DownloadInvoice(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	invoice, err := h.invoiceService.GetInvoice(user.ID, uint(invoiceID))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	h.sendPDF(c, user.ID, []models.Invoice{*invoice}, invoice.Number+".pdf")
+}
+
+// DownloadInvoices sends the invoices matching the list filters as a single PDF file,
+// for instance to print all the donation receipts of a year at once.
+func (h *InvoiceHandlers) DownloadInvoices(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	fiscalYear, _ := strconv.Atoi(c.Query("year"))
+	invoices, err := h.invoiceService.GetInvoices(user.ID, models.InvoiceKind(c.Query("kind")), fiscalYear)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des factures: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des factures."})
+		return
+	}
+	if len(invoices) == 0 {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Aucun document à exporter."})
+		return
+	}
+	h.sendPDF(c, user.ID, invoices, "factures-recus.pdf")
+}
+
+// CancelInvoice cancels an issued invoice; its number stays used.
+func (h *InvoiceHandlers) CancelInvoice(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	invoiceID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID de document invalide.", "/finance/invoices")
+		return
+	}
+	if err := h.invoiceService.CancelInvoice(user.ID, uint(invoiceID)); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'annulation: "+err.Error(), "/finance/invoices")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Document annulé. Son numéro reste attribué.", "/finance/invoices")
+}
+
+// sendPDF renders invoices and sends them as a PDF attachment.
+func (h *InvoiceHandlers) sendPDF(c *gin.Context, userID uint, invoices []models.Invoice, filename string) {
+	content, err := h.invoiceService.RenderPDF(userID, invoices)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la génération du PDF: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la génération du PDF."})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *InvoiceHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// LedgerHandlers encapsulates the dependencies for the accounting HTTP handlers
+// (chart of accounts, journal and accounting reports).
+// It holds a reference to the LedgerService, which contains the double-entry bookkeeping logic.
+type LedgerHandlers struct {
+	ledgerService *services.LedgerService
+}
+
+// NewLedgerHandlers creates a new instance of LedgerHandlers.
+// It takes a LedgerService as a dependency, adhering to the dependency inversion principle.
+func NewLedgerHandlers(ledgerService *services.LedgerService) *LedgerHandlers {
+	return &LedgerHandlers{ledgerService: ledgerService}
+}
+
+// ShowJournal displays the journal entries of the authenticated user for the selected period.
+func (h *LedgerHandlers) ShowJournal(c *gin.Context) {
+	h.renderReport(c, "journal.tmpl", "Journal comptable", func(userID uint, from, to time.Time) (gin.H, error) {
+		entries, err := h.ledgerService.GetJournal(userID, from, to)
+		return gin.H{"entries": entries}, err
+	})
+}
+
+// ShowGeneralLedger displays the general ledger (lines per account with running balance) for the selected period.
+func (h *LedgerHandlers) ShowGeneralLedger(c *gin.Context) {
+	h.renderReport(c, "general_ledger.tmpl", "Grand livre", func(userID uint, from, to time.Time) (gin.H, error) {
+		ledger, err := h.ledgerService.GetGeneralLedger(userID, from, to)
+		return gin.H{"ledger": ledger}, err
+	})
+}
+
+// ShowTrialBalance displays the trial balance (debit and credit totals per account) for the selected period.
+func (h *LedgerHandlers) ShowTrialBalance(c *gin.Context) {
+	h.renderReport(c, "trial_balance.tmpl", "Balance générale", func(userID uint, from, to time.Time) (gin.H, error) {
+		balance, err := h.ledgerService.GetTrialBalance(userID, from, to)
+		return gin.H{"balance": balance}, err
+	})
+}
+
+// ShowFinancialStatements displays the balance sheet at the end of the selected period
+// and the income statement of the period.
+func (h *LedgerHandlers) ShowFinancialStatements(c *gin.Context) {
+	h.renderReport(c, "financial_statements.tmpl", "Bilan et compte de résultat", func(userID uint, from, to time.Time) (gin.H, error) {
+		sheet, err := h.ledgerService.GetBalanceSheet(userID, to)
+		if err != nil {
+			return nil, err
+		}
+		statement, err := h.ledgerService.GetIncomeStatement(userID, from, to)
+		return gin.H{"balance_sheet": sheet, "income_statement": statement}, err
+	})
+}
+
+// ShowAccounts displays the chart of accounts of the authenticated user and the form to add an account.
+func (h *LedgerHandlers) ShowAccounts(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	accounts, err := h.ledgerService.GetAccounts(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération du plan comptable: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération du plan comptable."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "accounts.tmpl", gin.H{
+		"title":      "Plan comptable",
+		"navbar":     navbar,
+		"user":       user,
+		"accounts":   accounts,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowAccounts: %v", err)
+	}
+}
+
+// CreateAccount handles the submission of the new account form.
+func (h *LedgerHandlers) CreateAccount(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var account models.Account
+	if err := c.ShouldBind(&account); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données de compte invalides: "+err.Error(), "/finance/accounts")
+		return
+	}
+	account.UserID = user.ID
+
+	if err := h.ledgerService.CreateAccount(&account); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de la création du compte: "+err.Error(), "/finance/accounts")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Compte créé avec succès !", "/finance/accounts")
+}
+
+// ShowJournalEntryForm displays the form for entering a manual journal entry.
+func (h *LedgerHandlers) ShowJournalEntryForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	accounts, err := h.ledgerService.GetAccounts(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération du plan comptable: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération du plan comptable."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "journal_entry_form.tmpl", gin.H{
+		"title":      "Nouvelle écriture comptable",
+		"navbar":     navbar,
+		"user":       user,
+		"accounts":   accounts,
+		"entry":      models.JournalEntry{Date: time.Now()},
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowJournalEntryForm: %v", err)
+	}
+}
+
+// CreateJournalEntry handles the submission of the manual journal entry form.
+// Lines are sent as parallel arrays (account_code, label, debit, credit).
+func (h *LedgerHandlers) CreateJournalEntry(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var entry models.JournalEntry
+	if err := c.ShouldBind(&entry); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données d'écriture invalides: "+err.Error(), "/finance/journal/new")
+		return
+	}
+	entry.UserID = user.ID
+
+	lines, err := parseJournalLines(c.PostFormArray("account_code"), c.PostFormArray("label"), c.PostFormArray("debit"), c.PostFormArray("credit"))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/journal/new")
+		return
+	}
+	entry.Lines = lines
+
+	if err := h.ledgerService.CreateJournalEntry(&entry); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'enregistrement de l'écriture: "+err.Error(), "/finance/journal/new")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Écriture enregistrée avec succès !", "/finance/journal")
+}
+
+// parseJournalLines builds journal lines from the parallel form arrays, skipping empty rows.
+func parseJournalLines(accountCodes, labels, debits, credits []string) ([]models.JournalLine, error) {
+	var lines []models.JournalLine
+	for i, code := range accountCodes {
+		debit, err := services.ParseCents(formValueAt(debits, i))
+		if err != nil {
+			return nil, fmt.Errorf("ligne %d: débit invalide: %w", i+1, err)
+		}
+		credit, err := services.ParseCents(formValueAt(credits, i))
+		if err != nil {
+			return nil, fmt.Errorf("ligne %d: crédit invalide: %w", i+1, err)
+		}
+		if code == "" && debit == 0 && credit == 0 {
+			continue
+		}
+		lines = append(lines, models.JournalLine{
+			AccountCode: code,
+			Label:       formValueAt(labels, i),
+			Debit:       debit,
+			Credit:      credit,
+		})
+	}
+	return lines, nil
+}
+
+// formValueAt returns the i-th value of a form array, or an empty string if it is missing.
+func formValueAt(values []string, i int) string {
+	if i < len(values) {
+		return values[i]
+	}
+	return ""
+}
+
+// renderReport centralizes the session handling and period parsing shared by the accounting
+// reports, then renders the given template with the data produced by load.
+func (h *LedgerHandlers) renderReport(c *gin.Context, templateName, title string, load func(userID uint, from, to time.Time) (gin.H, error)) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	from, to := parsePeriod(c)
+	data, err := load(user.ID, from, to)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la génération du rapport %s: %v", templateName, err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la génération du rapport comptable."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	data["title"] = title
+	data["navbar"] = navbar
+	data["user"] = user
+	data["from"] = from
+	data["to"] = to
+	data["csrf_token"] = csrfToken
+	c.HTML(http.StatusOK, templateName, data)
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans renderReport: %v", err)
+	}
+}
+
+// parsePeriod reads the "from" and "to" query parameters (YYYY-MM-DD).
+// It defaults to the current calendar year; "to" covers the whole of its day.
+func parsePeriod(c *gin.Context) (time.Time, time.Time) {
+	now := time.Now()
+	from := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+	if value, err := time.Parse("2006-01-02", c.Query("from")); err == nil {
+		from = value
+	}
+	if value, err := time.Parse("2006-01-02", c.Query("to")); err == nil {
+		to = value
+	}
+	return from, to.Add(24*time.Hour - time.Second)
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *LedgerHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
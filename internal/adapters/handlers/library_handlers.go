@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// ShowLibraries lists the associations whose document library the authenticated user may browse:
+// its own and those it is an active member of.
+func (h *DocumentHandlers) ShowLibraries(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	memberships, err := h.libraryService.GetMemberships(user)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des associations de %s: %v", user.Email, err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des bibliothèques."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "libraries.tmpl", gin.H{
+		"title":       "Bibliothèques des membres",
+		"navbar":      navbar,
+		"user":        user,
+		"memberships": memberships,
+		"csrf_token":  csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowLibraries: %v", err)
+	}
+}
+
+// ShowLibrary displays the documents of an association the visitor may see: the public ones, and,
+// for its members, those published to the members or to the board. It does not require to be
+// logged in, so that the public documents can be linked to.
+func (h *DocumentHandlers) ShowLibrary(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	var viewer *models.User
+	if user, ok := session.Get("user").(models.User); ok {
+		viewer = &user
+	}
+
+	associationID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID d'association invalide"})
+		return
+	}
+	search := c.Query("q")
+	name, membership, documents, err := h.libraryService.GetLibrary(viewer, uint(associationID), search)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la bibliothèque %d: %v", associationID, err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des documents."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(session.Get("user"), csrfToken, session)
+
+	c.HTML(http.StatusOK, "library.tmpl", gin.H{
+		"title":         "Documents de " + name,
+		"navbar":        navbar,
+		"associationID": associationID,
+		"membership":    membership,
+		"loggedIn":      viewer != nil,
+		"documents":     documents,
+		"search":        search,
+		"csrf_token":    csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowLibrary: %v", err)
+	}
+}
+
+// DownloadLibraryDocument streams a document of a library to a visitor allowed to see it. Public
+// documents are downloaded without logging in.
+func (h *DocumentHandlers) DownloadLibraryDocument(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	var viewer *models.User
+	if user, ok := session.Get("user").(models.User); ok {
+		viewer = &user
+	}
+
+	documentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de document invalide"})
+		return
+	}
+	document, err := h.libraryService.GetDocument(viewer, uint(documentID))
+	if err != nil {
+		// Visitors may have to log in to see a document published to the members.
+		if viewer == nil {
+			c.Redirect(http.StatusFound, "/login")
+			return
+		}
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Document non trouvé"})
+		return
+	}
+	h.serveFile(c, document.FilePath, document.FileSize, document.Name, document.MimeType)
+}
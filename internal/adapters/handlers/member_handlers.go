@@ -209,6 +209,7 @@ func (h *MemberHandlers) UpdateMember(c *gin.Context) {
 	existingMember.FirstName = formMember.FirstName
 	existingMember.LastName = formMember.LastName
 	existingMember.Email = formMember.Email
+	existingMember.BoardRole = formMember.BoardRole
 	existingMember.MembershipStatus = formMember.MembershipStatus
 	existingMember.JoinDate = formMember.JoinDate
 	existingMember.EndDate = formMember.EndDate
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/payments"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookSize is the largest webhook delivery accepted, in bytes.
+const maxWebhookSize = 1 << 20
+
+// PaymentHandlers encapsulates the dependencies for the online payment HTTP handlers.
+// It holds references to the PaymentService, the MemberService and the EventService (used to fill
+// the payment request form), and to the FakeProvider when payments are simulated locally.
+type PaymentHandlers struct {
+	paymentService *services.PaymentService
+	memberService  *services.MemberService
+	eventService   *services.EventService
+	fakeProvider   *payments.FakeProvider
+}
+
+// NewPaymentHandlers creates a new instance of PaymentHandlers.
+// It takes a PaymentService, a MemberService, an EventService and the configured PaymentProvider as
+// dependencies, adhering to the dependency inversion principle.
+func NewPaymentHandlers(paymentService *services.PaymentService, memberService *services.MemberService, eventService *services.EventService, provider payments.PaymentProvider) *PaymentHandlers {
+	fakeProvider, _ := provider.(*payments.FakeProvider)
+	return &PaymentHandlers{paymentService: paymentService, memberService: memberService, eventService: eventService, fakeProvider: fakeProvider}
+}
+
+// ListPayments displays the online payment requests of the association with the form to issue a new one.
+func (h *PaymentHandlers) ListPayments(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	onlinePayments, err := h.paymentService.GetPaymentsByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des paiements en ligne: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des paiements en ligne."})
+		return
+	}
+	members, err := h.memberService.GetMembersByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des membres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des membres."})
+		return
+	}
+	events, err := h.eventService.GetEventsByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des événements: %v", err)
+	}
+
+	memberNames := map[uint]string{}
+	for _, member := range members {
+		memberNames[member.ID] = member.FirstName + " " + member.LastName
+	}
+	links := map[uint]string{}
+	for _, payment := range onlinePayments {
+		if payment.Status == models.OnlinePaymentPending || payment.Status == models.OnlinePaymentFailed {
+			links[payment.ID] = h.paymentService.PaymentURL(payment)
+		}
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "payments.tmpl", gin.H{
+		"title":       "Paiements en ligne",
+		"navbar":      navbar,
+		"user":        user,
+		"enabled":     h.paymentService.Enabled(),
+		"payments":    onlinePayments,
+		"links":       links,
+		"members":     members,
+		"memberNames": memberNames,
+		"events":      events,
+		"selected":    c.Query("member_id"),
+		"purposes":    []models.PaymentPurpose{models.PaymentDues, models.PaymentEventFee},
+		"csrf_token":  csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListPayments: %v", err)
+	}
+}
+
+// CreatePayment handles the issuing of an online payment request for a member.
+func (h *PaymentHandlers) CreatePayment(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var payment models.OnlinePayment
+	if err := c.ShouldBind(&payment); err != nil {
+		h.redirectWithFlash(c, session, "error", "Données de paiement invalides: "+err.Error(), "/finance/payments")
+		return
+	}
+	payment.UserID = user.ID
+	if value := c.PostForm("event_id"); value != "" && payment.Purpose == models.PaymentEventFee {
+		eventID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			h.redirectWithFlash(c, session, "error", "Événement invalide.", "/finance/payments")
+			return
+		}
+		id := uint(eventID)
+		payment.EventID = &id
+	}
+	currency, err := h.paymentService.BaseCurrency(user.ID)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/payments")
+		return
+	}
+	if payment.Amount, err = services.ParseMoney(c.PostForm("amount"), currency); err != nil {
+		h.redirectWithFlash(c, session, "error", "Montant invalide: "+err.Error(), "/finance/payments")
+		return
+	}
+
+	if err := h.paymentService.CreatePaymentRequest(&payment); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/payments")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Demande de paiement créée : transmettez le lien au membre.", "/finance/payments")
+}
+
+// RefundPayment handles the refund of a paid online payment.
+func (h *PaymentHandlers) RefundPayment(c *gin.Context) {
+	h.apply(c, "Paiement remboursé.", func(userID, paymentID uint) error {
+		return h.paymentService.Refund(c.Request.Context(), userID, paymentID)
+	})
+}
+
+// CancelPayment handles the withdrawal of an unpaid online payment request.
+func (h *PaymentHandlers) CancelPayment(c *gin.Context) {
+	h.apply(c, "Demande de paiement annulée.", h.paymentService.CancelPayment)
+}
+
+// apply performs an action on the online payment identified in the URL and redirects to the list
+// of payments with the outcome.
+func (h *PaymentHandlers) apply(c *gin.Context, success string, action func(userID, paymentID uint) error) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de paiement invalide"})
+		return
+	}
+	if err := action(user.ID, uint(paymentID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), "/finance/payments")
+		return
+	}
+	h.redirectWithFlash(c, session, "success", success, "/finance/payments")
+}
+
+// ShowPaymentPage displays the public page where a member pays an online payment request.
+// It is reached through the secret link sent to the member, without authentication.
+func (h *PaymentHandlers) ShowPaymentPage(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	payment, err := h.paymentService.GetPaymentByToken(c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Lien de paiement invalide."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar and the payment form.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(session.Get("user"), csrfToken, session)
+
+	c.HTML(http.StatusOK, "payment_page.tmpl", gin.H{
+		"title":      "Paiement en ligne",
+		"navbar":     navbar,
+		"payment":    payment,
+		"payable":    payment.Status == models.OnlinePaymentPending || payment.Status == models.OnlinePaymentFailed,
+		"paid":       payment.Status == models.OnlinePaymentPaid,
+		"returned":   c.Query("retour"),
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowPaymentPage: %v", err)
+	}
+}
+
+// StartCheckout opens a checkout session with the provider and redirects the member to it.
+func (h *PaymentHandlers) StartCheckout(c *gin.Context) {
+	token := c.Param("token")
+	checkoutURL, err := h.paymentService.StartCheckout(c.Request.Context(), token)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de l'ouverture du paiement en ligne: %v", err)
+		session := c.MustGet("session").(sessions.Session)
+		h.redirectWithFlash(c, session, "error", err.Error(), "/pay/"+token)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, checkoutURL)
+}
+
+// HandleWebhook receives the notifications of the payment provider. It answers 400 to deliveries
+// that are not signed by the provider and 500 when processing failed, so that the provider retries.
+func (h *PaymentHandlers) HandleWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookSize))
+	if err != nil {
+		c.String(http.StatusBadRequest, "requête illisible")
+		return
+	}
+	if err := h.paymentService.HandleWebhook(payload, c.Request.Header); err != nil {
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			log.Printf("ERREUR: Webhook de paiement refusé: %v", err)
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("ERREUR: Erreur lors du traitement du webhook de paiement: %v", err)
+		c.String(http.StatusInternalServerError, "erreur de traitement")
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+// ShowFakeCheckout displays the checkout page of the local FakeProvider.
+func (h *PaymentHandlers) ShowFakeCheckout(c *gin.Context) {
+	session := c.MustGet("session").(sessions.Session)
+	checkout, ok := h.fakeCheckout(c)
+	if !ok {
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar and the checkout form.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(session.Get("user"), csrfToken, session)
+
+	c.HTML(http.StatusOK, "fake_checkout.tmpl", gin.H{
+		"title":      "Paiement simulé",
+		"navbar":     navbar,
+		"checkout":   checkout,
+		"amount":     models.Money{Amount: checkout.Amount, Currency: checkout.Currency},
+		"csrf_token": csrfToken,
+	})
+}
+
+// CompleteFakeCheckout simulates the outcome of a checkout of the local FakeProvider: the signed
+// webhook it produces is processed like a real delivery, then the payer returns to the payment page.
+func (h *PaymentHandlers) CompleteFakeCheckout(c *gin.Context) {
+	checkout, ok := h.fakeCheckout(c)
+	if !ok {
+		return
+	}
+	complete, returnURL := h.fakeProvider.Complete, checkout.SuccessURL
+	if c.PostForm("action") != "pay" {
+		complete, returnURL = h.fakeProvider.Cancel, checkout.CancelURL
+	}
+	payload, header, err := complete(checkout.ID)
+	if err == nil {
+		err = h.paymentService.HandleWebhook(payload, header)
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors du paiement simulé: " + err.Error()})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, returnURL)
+}
+
+// fakeCheckout retrieves the FakeProvider session identified in the URL, answering 404 if payments
+// are not simulated or the session does not exist.
+func (h *PaymentHandlers) fakeCheckout(c *gin.Context) (payments.FakeSession, bool) {
+	if h.fakeProvider == nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Page introuvable."})
+		return payments.FakeSession{}, false
+	}
+	checkout, ok := h.fakeProvider.Session(c.Param("session"))
+	if !ok {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Session de paiement inconnue."})
+		return payments.FakeSession{}, false
+	}
+	return checkout, true
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *PaymentHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
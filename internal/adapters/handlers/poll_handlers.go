@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/JneiraS/BaseSasS/components"
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
@@ -14,6 +16,10 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// pollReceiptFlash is the flash key under which the receipt code of a secret ballot just cast is
+// passed to the page of the poll, where it is shown once.
+const pollReceiptFlash = "poll_receipt"
+
 // PollHandlers encapsulates the dependencies for poll-related HTTP handlers.
 // It holds a reference to the PollService, which contains the business logic for polls.
 type PollHandlers struct {
@@ -37,8 +43,8 @@ func (h *PollHandlers) ListPolls(c *gin.Context) {
 		return
 	}
 
-	// Retrieve all polls from the service.
-	polls, err := h.pollService.GetAllPolls()
+	// Retrieve the polls the user may see from the service.
+	polls, err := h.pollService.GetVisiblePolls(user.ID)
 	if err != nil {
 		log.Printf("ERREUR: Erreur lors de la récupération des sondages: %v", err)
 		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des sondages."})
@@ -55,6 +61,8 @@ func (h *PollHandlers) ListPolls(c *gin.Context) {
 		"navbar":     navbar,
 		"user":       user,
 		"polls":      polls,
+		"now":        time.Now(),
+		"closed":     models.PollClosed,
 		"csrf_token": csrfToken,
 	})
 	// Save session changes if any (e.g., flash messages).
@@ -64,7 +72,8 @@ func (h *PollHandlers) ListPolls(c *gin.Context) {
 }
 
 // ShowCreatePollForm displays the form for creating a new poll.
-// It provides an empty poll model for the form.
+// It provides an empty poll model for the form, a single choice poll whose results are visible
+// live by default.
 func (h *PollHandlers) ShowCreatePollForm(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
 	session := c.MustGet("session").(sessions.Session)
@@ -80,11 +89,16 @@ func (h *PollHandlers) ShowCreatePollForm(c *gin.Context) {
 
 	// Render the poll creation form.
 	c.HTML(http.StatusOK, "poll_form.tmpl", gin.H{
-		"title":      "Créer un nouveau sondage",
-		"navbar":     navbar,
-		"user":       user,
-		"csrf_token": csrfToken,
-		"poll":       models.Poll{}, // Empty poll for the form
+		"title":        "Créer un nouveau sondage",
+		"navbar":       navbar,
+		"user":         user,
+		"csrf_token":   csrfToken,
+		"poll":         models.Poll{Type: models.PollSingle, ResultsVisibility: models.ResultsLive}, // Empty poll for the form
+		"action":       "/polls/new",
+		"editable":     true,
+		"types":        models.PollTypes(),
+		"multiple":     models.PollMultiple,
+		"visibilities": models.ResultsVisibilities(),
 	})
 	// Save session changes if any.
 	if err := session.Save(); err != nil {
@@ -93,7 +107,8 @@ func (h *PollHandlers) ShowCreatePollForm(c *gin.Context) {
 }
 
 // CreatePoll handles the submission of the new poll creation form.
-// It binds the form data, extracts options, sets the UserID, and calls the service to create the poll.
+// It binds the form data, extracts options and the schedule, sets the UserID, and calls the service
+// to create the poll, opened at once or saved as a draft as chosen.
 func (h *PollHandlers) CreatePoll(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
 	session := c.MustGet("session").(sessions.Session)
@@ -117,6 +132,16 @@ func (h *PollHandlers) CreatePoll(c *gin.Context) {
 
 	newPoll.UserID = user.ID // Assign the current user's ID to the new poll.
 
+	// Retrieve the optional opening and closing times of the poll.
+	if err := bindPollSchedule(c, &newPoll); err != nil {
+		session.AddFlash(err.Error(), "error")
+		if err := session.Save(); err != nil {
+			log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+		}
+		c.Redirect(http.StatusFound, "/polls/new")
+		return
+	}
+
 	// Retrieve poll options from the form (sent as separate fields).
 	options := c.PostFormArray("options")
 	for _, optText := range options {
@@ -126,7 +151,7 @@ func (h *PollHandlers) CreatePoll(c *gin.Context) {
 	}
 
 	// Call the service to create the poll. Handle any errors during creation.
-	if err := h.pollService.CreatePoll(&newPoll); err != nil {
+	if err := h.pollService.CreatePoll(&newPoll, c.PostForm("publish") == "open"); err != nil {
 		log.Printf("ERREUR: Erreur lors de la création du sondage: %v", err)
 		session.AddFlash("Erreur lors de la création du sondage: "+err.Error(), "error")
 		if err := session.Save(); err != nil {
@@ -145,7 +170,8 @@ func (h *PollHandlers) CreatePoll(c *gin.Context) {
 }
 
 // ShowPollDetails displays the details of a specific poll and allows users to vote.
-// It retrieves the poll, checks if the user has voted, and fetches poll results.
+// It retrieves the poll, checks if the user has voted, and counts the ballots by the methods
+// suited to the type of the poll.
 func (h *PollHandlers) ShowPollDetails(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
 	session := c.MustGet("session").(sessions.Session)
@@ -162,8 +188,8 @@ func (h *PollHandlers) ShowPollDetails(c *gin.Context) {
 		return
 	}
 
-	// Retrieve the poll from the service.
-	poll, err := h.pollService.GetPollByID(uint(pollID))
+	// Retrieve the poll from the service; the drafts of the other users are not shown.
+	poll, err := h.pollService.GetVisiblePoll(user.ID, uint(pollID))
 	if err != nil {
 		log.Printf("ERREUR: Sondage non trouvé: %v", err)
 		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Sondage non trouvé"})
@@ -178,12 +204,35 @@ func (h *PollHandlers) ShowPollDetails(c *gin.Context) {
 		return
 	}
 
-	// Retrieve the poll results.
-	results, err := h.pollService.GetPollResults(uint(pollID))
-	if err != nil {
-		log.Printf("ERREUR: Erreur lors de la récupération des résultats du sondage: %v", err)
-		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des résultats du sondage."})
-		return
+	// Count the ballots of the poll, if the user may see the results yet.
+	now := time.Now()
+	showResults := poll.ResultsVisibleTo(user.ID, hasVoted, now)
+	tally := services.TallyPoll(poll, nil)
+	if showResults {
+		tally, err = h.pollService.GetPollTally(poll)
+		if err != nil {
+			log.Printf("ERREUR: Erreur lors de la récupération des résultats du sondage: %v", err)
+			c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des résultats du sondage."})
+			return
+		}
+	}
+	// The receipt code of a secret ballot just cast is only available now.
+	receipt := ""
+	if flashes := session.Flashes(pollReceiptFlash); len(flashes) > 0 {
+		receipt, _ = flashes[0].(string)
+	}
+	// The resolution of a general assembly is voted on by the members present, each ballot
+	// carrying the votes of the member and of the members it represents.
+	voteWeight, voteError := 1, ""
+	if poll.AssemblyID != nil && !hasVoted && poll.State(now) == models.PollOpen {
+		if voteWeight, err = h.pollService.VotingWeight(user, poll); err != nil {
+			voteError = err.Error()
+		}
+	}
+	minChoices, maxChoices := poll.ChoiceLimits()
+	ranks := make([]int, len(poll.Options))
+	for i := range ranks {
+		ranks[i] = i + 1
 	}
 
 	// Retrieve CSRF token for the navigation bar.
@@ -192,13 +241,32 @@ func (h *PollHandlers) ShowPollDetails(c *gin.Context) {
 
 	// Render the poll details page.
 	c.HTML(http.StatusOK, "poll_details.tmpl", gin.H{
-		"title":      poll.Question,
-		"navbar":     navbar,
-		"user":       user,
-		"poll":       poll,
-		"has_voted":  hasVoted,
-		"results":    results,
-		"csrf_token": csrfToken,
+		"title":        poll.Question,
+		"navbar":       navbar,
+		"user":         user,
+		"poll":         poll,
+		"has_voted":    hasVoted,
+		"tally":        tally,
+		"receipt":      receipt,
+		"vote_weight":  voteWeight,
+		"vote_error":   voteError,
+		"results":      tally.Counts(),
+		"show_results": showResults,
+		"min_choices":  minChoices,
+		"max_choices":  maxChoices,
+		"ranks":        ranks,
+		"single":       models.PollSingle,
+		"multiple":     models.PollMultiple,
+		"approval":     models.PollApproval,
+		"ranked":       models.PollRanked,
+		"state":        poll.State(now),
+		"scheduled":    poll.Scheduled(now),
+		"is_creator":   poll.UserID == user.ID,
+		"draft":        models.PollDraft,
+		"open":         models.PollOpen,
+		"closed":       models.PollClosed,
+		"after_vote":   models.ResultsAfterVote,
+		"csrf_token":   csrfToken,
 	})
 	// Save session changes if any.
 	if err := session.Save(); err != nil {
@@ -206,9 +274,9 @@ func (h *PollHandlers) ShowPollDetails(c *gin.Context) {
 	}
 }
 
-// VoteOnPoll handles the submission of a user's vote for a poll option.
-// It validates the poll and option IDs, checks if the user has already voted,
-// and records the vote via the PollService.
+// VoteOnPoll handles the submission of a user's ballot for a poll.
+// It validates the poll ID and the options chosen, checked, or ranked for a ranked poll, and
+// records the ballot via the PollService.
 func (h *PollHandlers) VoteOnPoll(c *gin.Context) {
 	// Retrieve the authenticated user from the session.
 	session := c.MustGet("session").(sessions.Session)
@@ -229,10 +297,10 @@ func (h *PollHandlers) VoteOnPoll(c *gin.Context) {
 		return
 	}
 
-	// Parse the selected option ID from the form data.
-	optionID, err := strconv.ParseUint(c.PostForm("option_id"), 10, 64)
+	// Parse the options of the ballot from the form data.
+	choices, err := parseBallot(c)
 	if err != nil {
-		session.AddFlash("Option de vote invalide.", "error")
+		session.AddFlash("Échec du vote: "+err.Error(), "error")
 		if err := session.Save(); err != nil {
 			log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
 		}
@@ -241,7 +309,8 @@ func (h *PollHandlers) VoteOnPoll(c *gin.Context) {
 	}
 
 	// Call the service to record the vote. Handle any errors (e.g., already voted, invalid option).
-	if err := h.pollService.Vote(uint(optionID), user.ID, uint(pollID)); err != nil {
+	receipt, err := h.pollService.Vote(user, uint(pollID), choices)
+	if err != nil {
 		log.Printf("ERREUR: Échec du vote: %v", err)
 		session.AddFlash("Échec du vote: "+err.Error(), "error")
 		if err := session.Save(); err != nil {
@@ -251,8 +320,14 @@ func (h *PollHandlers) VoteOnPoll(c *gin.Context) {
 		return
 	}
 
-	// Add a success flash message and redirect to the poll details page.
-	session.AddFlash("Votre vote a été enregistré avec succès !", "success")
+	// Add a success flash message and redirect to the poll details page, with the receipt code
+	// of a secret ballot.
+	if receipt != "" {
+		session.AddFlash(receipt, pollReceiptFlash)
+		session.AddFlash("Votre vote a été enregistré avec succès ! Notez votre code de reçu : il ne sera plus affiché.", "success")
+	} else {
+		session.AddFlash("Votre vote a été enregistré avec succès !", "success")
+	}
 	if err := session.Save(); err != nil {
 		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
 	}
@@ -319,3 +394,268 @@ func (h *PollHandlers) DeletePoll(c *gin.Context) {
 	}
 	c.Redirect(http.StatusFound, "/polls")
 }
+
+// ShowEditPollForm displays the form for editing a poll of the user. The question and options of
+// a poll can only be changed while it is a draft.
+func (h *PollHandlers) ShowEditPollForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Parse the poll ID from the URL parameter.
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de sondage invalide"})
+		return
+	}
+	poll, err := h.pollService.GetPollByID(uint(pollID))
+	if err != nil || poll.UserID != user.ID {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Sondage non trouvé"})
+		return
+	}
+	state := poll.State(time.Now())
+	if state == models.PollClosed {
+		session.AddFlash("Un sondage clôturé ne peut plus être modifié.", "error")
+		if err := session.Save(); err != nil {
+			log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+		}
+		c.Redirect(http.StatusFound, fmt.Sprintf("/polls/%d", poll.ID))
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	// Render the poll form with the poll to edit.
+	c.HTML(http.StatusOK, "poll_form.tmpl", gin.H{
+		"title":        "Modifier le sondage",
+		"navbar":       navbar,
+		"user":         user,
+		"csrf_token":   csrfToken,
+		"poll":         poll,
+		"action":       fmt.Sprintf("/polls/edit/%d", poll.ID),
+		"editable":     state == models.PollDraft,
+		"types":        models.PollTypes(),
+		"multiple":     models.PollMultiple,
+		"visibilities": models.ResultsVisibilities(),
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowEditPollForm: %v", err)
+	}
+}
+
+// UpdatePoll handles the submission of the poll edit form.
+func (h *PollHandlers) UpdatePoll(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Parse the poll ID from the URL parameter.
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de sondage invalide"})
+		return
+	}
+	location := fmt.Sprintf("/polls/edit/%d", pollID)
+
+	poll := models.Poll{
+		Question:          c.PostForm("question"),
+		ResultsVisibility: models.ResultsVisibility(c.PostForm("results_visibility")),
+		Type:              models.PollType(c.PostForm("type")),
+		Anonymous:         c.PostForm("anonymous") == "true",
+	}
+	poll.ID = uint(pollID)
+	if poll.MinChoices, err = parseChoiceCount(c.PostForm("min_choices")); err != nil {
+		h.redirectWithFlash(c, session, "error", "Nombre minimum de choix invalide.", location)
+		return
+	}
+	if poll.MaxChoices, err = parseChoiceCount(c.PostForm("max_choices")); err != nil {
+		h.redirectWithFlash(c, session, "error", "Nombre maximum de choix invalide.", location)
+		return
+	}
+	for _, optText := range c.PostFormArray("options") {
+		if strings.TrimSpace(optText) != "" {
+			poll.Options = append(poll.Options, models.Option{Text: optText})
+		}
+	}
+	if err := bindPollSchedule(c, &poll); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+
+	if err := h.pollService.UpdatePoll(user.ID, &poll); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de la modification du sondage: "+err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Sondage modifié avec succès !", fmt.Sprintf("/polls/%d", pollID))
+}
+
+// VerifyReceipt handles the check by a voter that the ballot given a receipt code is counted in
+// a secret poll.
+func (h *PollHandlers) VerifyReceipt(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Parse the poll ID from the URL parameter.
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID de sondage invalide.", "/polls")
+		return
+	}
+	poll, err := h.pollService.GetVisiblePoll(user.ID, uint(pollID))
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "Sondage non trouvé.", "/polls")
+		return
+	}
+	location := fmt.Sprintf("/polls/%d", poll.ID)
+
+	counted, err := h.pollService.VerifyReceipt(poll, c.PostForm("receipt"))
+	switch {
+	case err != nil:
+		h.redirectWithFlash(c, session, "error", "Vérification impossible: "+err.Error(), location)
+	case counted:
+		h.redirectWithFlash(c, session, "success", "Votre bulletin figure parmi les bulletins comptés.", location)
+	default:
+		h.redirectWithFlash(c, session, "error", "Aucun bulletin ne correspond à ce code de reçu.", location)
+	}
+}
+
+// OpenPoll handles the opening of a draft to votes by its creator.
+func (h *PollHandlers) OpenPoll(c *gin.Context) {
+	h.changePollState(c, h.pollService.OpenPoll, "Le sondage est ouvert au vote.")
+}
+
+// ClosePoll handles the closing of an open poll by its creator.
+func (h *PollHandlers) ClosePoll(c *gin.Context) {
+	h.changePollState(c, h.pollService.ClosePoll, "Le sondage est clôturé.")
+}
+
+// changePollState applies a change of state to the poll of the URL, on behalf of the
+// authenticated user, and redirects to the poll with the outcome.
+func (h *PollHandlers) changePollState(c *gin.Context, change func(userID, pollID uint) error, success string) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	// Parse the poll ID from the URL parameter.
+	pollID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.redirectWithFlash(c, session, "error", "ID de sondage invalide.", "/polls")
+		return
+	}
+	location := fmt.Sprintf("/polls/%d", pollID)
+	if err := change(user.ID, uint(pollID)); err != nil {
+		h.redirectWithFlash(c, session, "error", err.Error(), location)
+		return
+	}
+	h.redirectWithFlash(c, session, "success", success, location)
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *PollHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
+
+// bindPollSchedule sets the opening and closing times of a poll from the form, both optional.
+func bindPollSchedule(c *gin.Context, poll *models.Poll) error {
+	var err error
+	if poll.OpensAt, err = parsePollTime(c.PostForm("opens_at")); err != nil {
+		return fmt.Errorf("date d'ouverture invalide")
+	}
+	if poll.ClosesAt, err = parsePollTime(c.PostForm("closes_at")); err != nil {
+		return fmt.Errorf("date de clôture invalide")
+	}
+	return nil
+}
+
+// parsePollTime parses a date and time entered in a datetime-local field, in local time. An empty
+// value gives no time.
+func parsePollTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseChoiceCount parses a number of options entered in the poll form. An empty value gives 0,
+// which lets the service apply its default.
+func parseChoiceCount(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// parseBallot returns the options of the ballot submitted, in order of preference for a ranked
+// poll. The options chosen are sent as "option_id" fields; a ranked ballot sends instead the rank
+// given to each option in a "rank_<option ID>" field, empty for the options left unranked.
+func parseBallot(c *gin.Context) ([]uint, error) {
+	var choices []uint
+	for _, value := range c.PostFormArray("option_id") {
+		optionID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("option de vote invalide")
+		}
+		choices = append(choices, uint(optionID))
+	}
+	if len(choices) > 0 {
+		return choices, nil
+	}
+
+	byRank := make(map[int]uint)
+	for key, values := range c.Request.PostForm {
+		if !strings.HasPrefix(key, "rank_") || len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		optionID, err := strconv.ParseUint(strings.TrimPrefix(key, "rank_"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("option de vote invalide")
+		}
+		rank, err := strconv.Atoi(strings.TrimSpace(values[0]))
+		if err != nil || rank < 1 {
+			return nil, fmt.Errorf("rang invalide")
+		}
+		if _, taken := byRank[rank]; taken {
+			return nil, fmt.Errorf("chaque rang ne peut être attribué qu'une fois")
+		}
+		byRank[rank] = uint(optionID)
+	}
+	ranks := make([]int, 0, len(byRank))
+	for rank := range byRank {
+		ranks = append(ranks, rank)
+	}
+	sort.Ints(ranks)
+	for _, rank := range ranks {
+		choices = append(choices, byRank[rank])
+	}
+	return choices, nil
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// RecurringHandlers encapsulates the dependencies for the recurring transaction HTTP handlers.
+// It holds references to the RecurringService, which manages the templates and their schedule,
+// and to the FinanceService, which provides the categories and the base currency.
+type RecurringHandlers struct {
+	recurringService *services.RecurringService
+	financeService   *services.FinanceService
+}
+
+// NewRecurringHandlers creates a new instance of RecurringHandlers.
+// It takes a RecurringService and a FinanceService as dependencies,
+// adhering to the dependency inversion principle.
+func NewRecurringHandlers(recurringService *services.RecurringService, financeService *services.FinanceService) *RecurringHandlers {
+	return &RecurringHandlers{recurringService: recurringService, financeService: financeService}
+}
+
+// ListRecurring displays the recurring transaction templates of the authenticated user.
+func (h *RecurringHandlers) ListRecurring(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	templates, err := h.recurringService.GetRecurringByUserID(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des transactions récurrentes: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des transactions récurrentes."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "recurring.tmpl", gin.H{
+		"title":      "Transactions récurrentes",
+		"navbar":     navbar,
+		"user":       user,
+		"templates":  templates,
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ListRecurring: %v", err)
+	}
+}
+
+// ShowCreateRecurringForm displays the form for creating a recurring transaction.
+func (h *RecurringHandlers) ShowCreateRecurringForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	h.renderForm(c, session, user, "Nouvelle transaction récurrente", &models.RecurringTransaction{
+		Type:      models.TypeExpense,
+		Frequency: models.FrequencyMonthly,
+		StartDate: time.Now(),
+		Active:    true,
+	})
+}
+
+// CreateRecurring handles the submission of the recurring transaction creation form.
+func (h *RecurringHandlers) CreateRecurring(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	var recurring models.RecurringTransaction
+	if err := h.bindRecurring(c, user.ID, &recurring); err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+	recurring.UserID = user.ID
+
+	if err := h.recurringService.CreateRecurring(&recurring); err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Erreur lors de la création de la transaction récurrente: " + err.Error()})
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Transaction récurrente créée. Ses échéances seront enregistrées automatiquement.", "/finance/recurring")
+}
+
+// ShowEditRecurringForm displays the form for editing a recurring transaction.
+func (h *RecurringHandlers) ShowEditRecurringForm(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	recurring, ok := h.ownedRecurring(c, user.ID)
+	if !ok {
+		return
+	}
+	h.renderForm(c, session, user, "Modifier la transaction récurrente", recurring)
+}
+
+// UpdateRecurring handles the submission of the recurring transaction modification form.
+// Occurrences already recorded are left untouched.
+func (h *RecurringHandlers) UpdateRecurring(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	existing, ok := h.ownedRecurring(c, user.ID)
+	if !ok {
+		return
+	}
+	var updated models.RecurringTransaction
+	if err := h.bindRecurring(c, user.ID, &updated); err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Données invalides: " + err.Error()})
+		return
+	}
+
+	existing.Amount = updated.Amount
+	existing.ExchangeRate = updated.ExchangeRate
+	existing.Type = updated.Type
+	existing.AccountCode = updated.AccountCode
+	existing.Description = updated.Description
+	existing.Frequency = updated.Frequency
+	existing.StartDate = updated.StartDate
+	existing.EndDate = updated.EndDate
+	existing.Active = updated.Active
+
+	if err := h.recurringService.UpdateRecurring(existing); err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "Erreur lors de la mise à jour de la transaction récurrente: " + err.Error()})
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Transaction récurrente mise à jour.", "/finance/recurring")
+}
+
+// DeleteRecurring handles the deletion of a recurring transaction. The transactions already
+// recorded from it are kept.
+func (h *RecurringHandlers) DeleteRecurring(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	recurring, ok := h.ownedRecurring(c, user.ID)
+	if !ok {
+		return
+	}
+	if err := h.recurringService.DeleteRecurring(recurring.ID); err != nil {
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la suppression de la transaction récurrente: " + err.Error()})
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Transaction récurrente supprimée. Les transactions déjà enregistrées sont conservées.", "/finance/recurring")
+}
+
+// renderForm renders the recurring transaction form for creation or modification.
+func (h *RecurringHandlers) renderForm(c *gin.Context, session sessions.Session, user models.User, title string, recurring *models.RecurringTransaction) {
+	incomeAccounts, err := h.financeService.GetCategoryAccounts(user.ID, models.TypeIncome)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des catégories: %v", err)
+	}
+	expenseAccounts, err := h.financeService.GetCategoryAccounts(user.ID, models.TypeExpense)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des catégories: %v", err)
+	}
+	baseCurrency, err := h.financeService.GetBaseCurrency(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération de la devise de référence: %v", err)
+		baseCurrency = models.DefaultCurrency
+	}
+	var endDate string
+	if recurring.EndDate != nil {
+		endDate = recurring.EndDate.Format("2006-01-02")
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "recurring_form.tmpl", gin.H{
+		"title":            title,
+		"navbar":           navbar,
+		"user":             user,
+		"csrf_token":       csrfToken,
+		"recurring":        recurring,
+		"end_date":         endDate,
+		"frequencies":      models.Frequencies,
+		"income_accounts":  incomeAccounts,
+		"expense_accounts": expenseAccounts,
+		"base_currency":    baseCurrency,
+		"currencies":       models.SupportedCurrencies(),
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans le formulaire de transaction récurrente: %v", err)
+	}
+}
+
+// bindRecurring binds the recurring transaction form, parsing the exact amount and the optional end date.
+func (h *RecurringHandlers) bindRecurring(c *gin.Context, userID uint, recurring *models.RecurringTransaction) error {
+	if err := c.ShouldBind(recurring); err != nil {
+		return err
+	}
+	currency := c.PostForm("currency")
+	if currency == "" {
+		var err error
+		if currency, err = h.financeService.GetBaseCurrency(userID); err != nil {
+			return err
+		}
+	}
+	amount, err := services.ParseMoney(c.PostForm("amount"), currency)
+	if err != nil {
+		return fmt.Errorf("montant invalide: %w", err)
+	}
+	recurring.Amount = amount
+	recurring.EndDate = nil
+	if value := c.PostForm("end_date"); value != "" {
+		endDate, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("date de fin invalide")
+		}
+		recurring.EndDate = &endDate
+	}
+	return nil
+}
+
+// ownedRecurring retrieves the template identified by the "id" URL parameter and ensures it belongs
+// to the user. It renders the error page and returns false otherwise.
+func (h *RecurringHandlers) ownedRecurring(c *gin.Context, userID uint) (*models.RecurringTransaction, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "error.tmpl", gin.H{"error": "ID de transaction récurrente invalide"})
+		return nil, false
+	}
+	recurring, err := h.recurringService.GetRecurringByID(uint(id))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "error.tmpl", gin.H{"error": "Transaction récurrente non trouvée"})
+		return nil, false
+	}
+	if recurring.UserID != userID {
+		c.HTML(http.StatusForbidden, "error.tmpl", gin.H{"error": "Accès non autorisé"})
+		return nil, false
+	}
+	return recurring, true
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the given location.
+func (h *RecurringHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message, location string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, location)
+}
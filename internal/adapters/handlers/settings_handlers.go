@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/components"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandlers encapsulates the dependencies for the association settings HTTP handlers.
+// It holds a reference to the SettingsService, which contains the business logic for settings.
+type SettingsHandlers struct {
+	settingsService *services.SettingsService
+}
+
+// NewSettingsHandlers creates a new instance of SettingsHandlers.
+// It takes a SettingsService as a dependency, adhering to the dependency inversion principle.
+func NewSettingsHandlers(settingsService *services.SettingsService) *SettingsHandlers {
+	return &SettingsHandlers{settingsService: settingsService}
+}
+
+// ShowSettings displays the settings form of the authenticated user's association.
+func (h *SettingsHandlers) ShowSettings(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des paramètres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des paramètres."})
+		return
+	}
+
+	// Retrieve CSRF token for the navigation bar.
+	csrfToken := c.MustGet("csrf_token").(string)
+	navbar := components.NavBar(user, csrfToken, session)
+
+	c.HTML(http.StatusOK, "settings.tmpl", gin.H{
+		"title":      "Paramètres de l'association",
+		"navbar":     navbar,
+		"user":       user,
+		"settings":   settings,
+		"currencies": models.SupportedCurrencies(),
+		"months":     monthOptions(),
+		"csrf_token": csrfToken,
+	})
+	// Save session changes if any.
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de session dans ShowSettings: %v", err)
+	}
+}
+
+// UpdateSettings handles the submission of the settings form.
+// Only the fields present in the form are changed; the others keep their current value.
+func (h *SettingsHandlers) UpdateSettings(c *gin.Context) {
+	// Retrieve the authenticated user from the session.
+	session := c.MustGet("session").(sessions.Session)
+	user, ok := session.Get("user").(models.User)
+	if !ok {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(user.ID)
+	if err != nil {
+		log.Printf("ERREUR: Erreur lors de la récupération des paramètres: %v", err)
+		c.HTML(http.StatusInternalServerError, "error.tmpl", gin.H{"error": "Erreur lors de la récupération des paramètres."})
+		return
+	}
+	if err := c.ShouldBind(settings); err != nil {
+		h.redirectWithFlash(c, session, "error", "Paramètres invalides: "+err.Error())
+		return
+	}
+	// Amounts are typed as decimals and stored in minor units of the base currency.
+	if value, ok := c.GetPostForm("receipt_threshold"); ok {
+		if settings.ReceiptThreshold, err = services.ParseCents(value); err != nil {
+			h.redirectWithFlash(c, session, "error", "Seuil de justificatif invalide: "+err.Error())
+			return
+		}
+	}
+	// An empty approval threshold disables the approval of expenses.
+	if value, ok := c.GetPostForm("approval_threshold"); ok {
+		settings.ApprovalEnabled = strings.TrimSpace(value) != ""
+		if settings.ApprovalThreshold, err = services.ParseCents(value); err != nil {
+			h.redirectWithFlash(c, session, "error", "Seuil d'approbation invalide: "+err.Error())
+			return
+		}
+	}
+	settings.UserID = user.ID
+
+	if err := h.settingsService.UpdateSettings(settings); err != nil {
+		h.redirectWithFlash(c, session, "error", "Erreur lors de l'enregistrement des paramètres: "+err.Error())
+		return
+	}
+	h.redirectWithFlash(c, session, "success", "Paramètres enregistrés avec succès !")
+}
+
+// redirectWithFlash adds a flash message to the session and redirects to the settings page.
+func (h *SettingsHandlers) redirectWithFlash(c *gin.Context, session sessions.Session, kind, message string) {
+	session.AddFlash(message, kind)
+	if err := session.Save(); err != nil {
+		log.Printf("ERREUR: Erreur lors de la sauvegarde de la session: %v", err)
+	}
+	c.Redirect(http.StatusFound, "/settings")
+}
+
+// monthOption is a month offered in the fiscal year start select.
+type monthOption struct {
+	Number int
+	Name   string
+}
+
+// monthOptions returns the months of the year with their French names.
+func monthOptions() []monthOption {
+	names := []string{"Janvier", "Février", "Mars", "Avril", "Mai", "Juin", "Juillet", "Août", "Septembre", "Octobre", "Novembre", "Décembre"}
+	options := make([]monthOption, len(names))
+	for i, name := range names {
+		options[i] = monthOption{Number: i + 1, Name: name}
+	}
+	return options
+}
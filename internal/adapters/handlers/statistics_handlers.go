@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 	"time"
 
@@ -113,11 +114,14 @@ func (h *StatisticsHandlers) GetFinanceStats(c *gin.Context) {
 		return
 	}
 
-	// Return financial statistics as JSON.
+	// Return financial statistics as JSON. Amounts are written as exact decimal numbers
+	// in the base currency rather than as rounded floats.
+	netBalance := models.Money{Amount: totalIncome.Amount - totalExpenses.Amount, Currency: totalIncome.Currency}
 	c.JSON(http.StatusOK, gin.H{
-		"total_income":   totalIncome,
-		"total_expenses": totalExpenses,
-		"net_balance":    totalIncome - totalExpenses,
+		"total_income":   json.Number(totalIncome.Decimal()),
+		"total_expenses": json.Number(totalExpenses.Decimal()),
+		"net_balance":    json.Number(netBalance.Decimal()),
+		"currency":       totalIncome.Currency,
 	})
 }
 
@@ -145,7 +149,8 @@ func (h *StatisticsHandlers) GetEventStats(c *gin.Context) {
 }
 
 // GetDocumentStats returns statistics related to documents in JSON format.
-// It fetches the total number of documents for the authenticated user.
+// It fetches the total number of documents for the authenticated user and the storage space they
+// use, with their quota (0 for no limit).
 func (h *StatisticsHandlers) GetDocumentStats(c *gin.Context) {
 	session := c.MustGet("session").(sessions.Session)
 	user, ok := session.Get("user").(models.User)
@@ -154,15 +159,18 @@ func (h *StatisticsHandlers) GetDocumentStats(c *gin.Context) {
 		return
 	}
 
-	// Fetch total documents count.
-	totalDocuments, err := h.documentService.GetTotalDocumentsCount(user.ID)
+	// Fetch total documents count and storage usage.
+	usage, err := h.documentService.GetStorageUsage(user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération du nombre total de documents"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erreur lors de la récupération des statistiques des documents"})
 		return
 	}
 
 	// Return document statistics as JSON.
 	c.JSON(http.StatusOK, gin.H{
-		"total_documents": totalDocuments,
+		"total_documents": usage.Documents,
+		"storage_used":    usage.Used,
+		"storage_quota":   usage.Quota,
+		"storage_percent": usage.Percent(),
 	})
 }
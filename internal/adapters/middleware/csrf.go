@@ -3,16 +3,22 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/JneiraS/BaseSasS/internal/config"
 	"github.com/gin-gonic/gin"
 	csrf "github.com/utrack/gin-csrf"
 )
 
+// WebhookPathPrefix is the path prefix of the endpoints called by external services, which
+// authenticate their requests with a signature instead of a CSRF token.
+const WebhookPathPrefix = "/webhooks/"
+
 // CSRFProtection applies Cross-Site Request Forgery (CSRF) protection to the application.
-// It uses the gin-csrf middleware to validate CSRF tokens on incoming requests.
+// It uses the gin-csrf middleware to validate CSRF tokens on incoming requests,
+// except on webhook endpoints.
 func CSRFProtection(cfg *config.Config) gin.HandlerFunc {
-	return csrf.Middleware(csrf.Options{
+	options := csrf.Options{
 		Secret: cfg.CSRFSecret, // The secret key used to sign and verify CSRF tokens.
 		// ErrorFunc is a custom function to handle CSRF validation failures.
 		// It logs the error and returns a 400 Bad Request response to the client.
@@ -21,5 +27,19 @@ func CSRFProtection(cfg *config.Config) gin.HandlerFunc {
 			c.String(http.StatusBadRequest, "CSRF token mismatch")
 			c.Abort() // Abort the request chain on CSRF validation failure.
 		},
-	})
+	}
+	protect := csrf.Middleware(options)
+
+	// Webhooks still go through the middleware, which makes the token available to the
+	// following handlers, but no method is validated.
+	options.IgnoreMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost}
+	skip := csrf.Middleware(options)
+
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, WebhookPathPrefix) {
+			skip(c)
+			return
+		}
+		protect(c)
+	}
 }
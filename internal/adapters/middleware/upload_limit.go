@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadOverhead is the room left in the body of an upload request, beyond the files themselves,
+// for the multipart framing and the other fields of the form.
+const uploadOverhead = 1 << 20
+
+// maxUploadFiles is the number of files of the maximum size a form accepting several files may
+// send at once.
+const maxUploadFiles = 10
+
+// UploadLimit bounds the body of every multipart POST request by the maximum size of a document,
+// so that a file too large is refused as soon as it is read instead of being spooled to disk
+// whole. The requests to the given routes, whose forms accept several files, are bounded by
+// maxUploadFiles times that size. The form is parsed here, since the CSRF protection reads it
+// next: it must therefore be applied before CSRFProtection. Requests exceeding the limit are
+// refused with 413 Request Entity Too Large.
+func UploadLimit(cfg *config.Config, multiple ...string) gin.HandlerFunc {
+	maxSize := int64(cfg.DocumentMaxSizeMB) << 20
+	several := make(map[string]bool, len(multiple))
+	for _, route := range multiple {
+		several[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if maxSize <= 0 || c.Request.Method != http.MethodPost || c.ContentType() != "multipart/form-data" {
+			c.Next()
+			return
+		}
+		limit, message := maxSize, fmt.Sprintf("Le fichier dépasse la taille maximale de %d Mo.", cfg.DocumentMaxSizeMB)
+		if several[c.FullPath()] {
+			limit = maxUploadFiles * maxSize
+			message = fmt.Sprintf("Les fichiers dépassent la taille maximale de %d Mo par envoi.", maxUploadFiles*cfg.DocumentMaxSizeMB)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+uploadOverhead)
+		if _, err := c.MultipartForm(); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				log.Printf("ERREUR: Téléchargement refusé, requête de plus de %d octets: %s", tooLarge.Limit, c.Request.URL.Path)
+				c.HTML(http.StatusRequestEntityTooLarge, "error.tmpl", gin.H{"error": message})
+				c.Abort()
+				return
+			}
+			// Other errors are left to the handler, which fails to read the form in turn.
+		}
+		c.Next()
+	}
+}
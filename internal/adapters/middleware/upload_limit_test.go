@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadRequest returns a request posting files of the given sizes to path.
+func uploadRequest(t *testing.T, path string, sizes ...int) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("_csrf", "token")
+	for _, size := range sizes {
+		file, err := form.CreateFormFile("document", "statuts.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(bytes.Repeat([]byte("x"), size))
+	}
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestUploadLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		maxSizeMB int
+		path      string
+		sizes     []int
+		want      int
+		message   string
+	}{
+		{name: "within the limit", maxSizeMB: 1, path: "/documents/upload", sizes: []int{1 << 20}, want: http.StatusOK},
+		{name: "within the overhead", maxSizeMB: 1, path: "/documents/upload", sizes: []int{1<<20 + 1000}, want: http.StatusOK},
+		{name: "too large", maxSizeMB: 1, path: "/documents/upload", sizes: []int{3 << 20}, want: http.StatusRequestEntityTooLarge, message: "taille maximale de 1 Mo."},
+		{name: "too large with a route parameter", maxSizeMB: 1, path: "/documents/versions/7", sizes: []int{3 << 20}, want: http.StatusRequestEntityTooLarge, message: "taille maximale de 1 Mo."},
+		{name: "too large on another route", maxSizeMB: 1, path: "/finance/import", sizes: []int{3 << 20}, want: http.StatusRequestEntityTooLarge, message: "taille maximale de 1 Mo."},
+		{name: "several files", maxSizeMB: 1, path: "/finance/transactions/attachments/7", sizes: []int{1 << 20, 1 << 20, 1 << 20}, want: http.StatusOK},
+		{name: "several files too large", maxSizeMB: 1, path: "/finance/transactions/attachments/7", sizes: []int{6 << 20, 6 << 20}, want: http.StatusRequestEntityTooLarge, message: "taille maximale de 10 Mo par envoi."},
+		{name: "several files on a single file route", maxSizeMB: 1, path: "/documents/upload", sizes: []int{1 << 20, 1 << 20}, want: http.StatusRequestEntityTooLarge, message: "taille maximale de 1 Mo."},
+		{name: "no limit", maxSizeMB: 0, path: "/documents/upload", sizes: []int{3 << 20}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.SetHTMLTemplate(template.Must(template.New("error.tmpl").Parse("{{.error}}")))
+			r.Use(UploadLimit(&config.Config{DocumentMaxSizeMB: tt.maxSizeMB}, "/finance/transactions/attachments/:id"))
+			handler := func(c *gin.Context) {
+				if c.PostForm("_csrf") != "token" {
+					t.Error("form fields not available to the handler")
+				}
+				form, err := c.MultipartForm()
+				if err != nil {
+					c.String(http.StatusBadRequest, err.Error())
+					return
+				}
+				files := form.File["document"]
+				if len(files) != len(tt.sizes) {
+					t.Fatalf("%d files, want %d", len(files), len(tt.sizes))
+				}
+				for i, file := range files {
+					if file.Size != int64(tt.sizes[i]) {
+						t.Errorf("file of %d bytes, want %d", file.Size, tt.sizes[i])
+					}
+				}
+				c.Status(http.StatusOK)
+			}
+			r.POST("/documents/upload", handler)
+			r.POST("/documents/versions/:id", handler)
+			r.POST("/finance/import", handler)
+			r.POST("/finance/transactions/attachments/:id", handler)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, uploadRequest(t, tt.path, tt.sizes...))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.message) {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package antivirus
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks a file is streamed to clamd in.
+const clamdChunkSize = 64 << 10
+
+// ClamdScanner scans files with a ClamAV daemon (clamd), streaming them with the INSTREAM command
+// so that the daemon does not need access to the files.
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a scanner for the daemon listening at address: "tcp://host:port",
+// "host:port" or "unix:///path/to/clamd.sock". timeout bounds each scan.
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("adresse de l'antivirus manquante")
+	}
+	return &ClamdScanner{network: network, address: address, timeout: timeout}, nil
+}
+
+// Name returns the name of the engine.
+func (s *ClamdScanner) Name() string {
+	return "clamd (" + s.network + ":" + s.address + ")"
+}
+
+// Scan streams the file to clamd and reads its verdict.
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("antivirus injoignable: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The "z" prefix delimits the command and the reply with NUL characters.
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("envoi à l'antivirus impossible: %w", err)
+	}
+	// The file is sent as chunks prefixed by their length, then a chunk of length zero. clamd
+	// may close the connection early, e.g., when the file exceeds its size limit: its reply
+	// then tells why.
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				break
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			conn.Write([]byte{0, 0, 0, 0})
+			break
+		}
+		if readErr != nil {
+			return Result{}, fmt.Errorf("lecture du fichier à analyser impossible: %w", readErr)
+		}
+	}
+
+	reply, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil && len(reply) == 0 {
+		return Result{}, fmt.Errorf("réponse de l'antivirus illisible: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply interprets the reply to an INSTREAM command: "stream: OK",
+// "stream: <signature> FOUND" or "<message> ERROR".
+func parseClamdReply(reply string) (Result, error) {
+	switch {
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(reply, " FOUND")
+		signature = strings.TrimSpace(strings.TrimPrefix(signature, "stream:"))
+		return Result{Infected: true, Signature: signature}, nil
+	case strings.HasSuffix(reply, ": OK"):
+		return Result{}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return Result{}, fmt.Errorf("l'antivirus n'a pas pu analyser le fichier: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return Result{}, fmt.Errorf("réponse inattendue de l'antivirus: %q", reply)
+	}
+}
@@ -0,0 +1,181 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// eicar is the EICAR anti-malware test file, detected by every antivirus as Eicar-Test-Signature.
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd is a ClamAV daemon answering the INSTREAM command: it finds the EICAR test file, and
+// refuses the files larger than its size limit.
+type fakeClamd struct {
+	listener net.Listener
+	maxSize  int
+	received chan []byte // The files streamed to the daemon.
+}
+
+// newFakeClamd starts a fake daemon listening on a local TCP port.
+func newFakeClamd(t *testing.T, maxSize int) *fakeClamd {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	clamd := &fakeClamd{listener: listener, maxSize: maxSize, received: make(chan []byte, 1)}
+	go clamd.serve(t)
+	return clamd
+}
+
+func (c *fakeClamd) serve(t *testing.T) {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		c.handle(t, conn)
+	}
+}
+
+// handle reads an INSTREAM command and the chunks of the file, then replies with the verdict. The
+// whole file is read before replying, even when it is too large, so that the reply is not lost to
+// a reset of the connection.
+func (c *fakeClamd) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		t.Errorf("command = %q, %v; want zINSTREAM", command, err)
+		return
+	}
+	var file []byte
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			t.Errorf("chunk length: %v", err)
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if size > clamdChunkSize {
+			t.Errorf("chunk of %d bytes, larger than %d", size, clamdChunkSize)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			t.Errorf("chunk: %v", err)
+			return
+		}
+		file = append(file, chunk...)
+	}
+	if c.maxSize > 0 && len(file) > c.maxSize {
+		conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+		return
+	}
+	c.received <- file
+
+	reply := "stream: OK\x00"
+	if bytes.Contains(file, []byte(eicar)) {
+		reply = "stream: Eicar-Test-Signature FOUND\x00"
+	}
+	conn.Write([]byte(reply))
+}
+
+func TestClamdScanner(t *testing.T) {
+	large := strings.Repeat("Procès-verbal de l'assemblée générale. ", 5000) // Several chunks.
+	tests := []struct {
+		name    string
+		content string
+		want    Result
+	}{
+		{name: "clean", content: "Statuts de l'association", want: Result{}},
+		{name: "clean in several chunks", content: large, want: Result{}},
+		{name: "empty", content: "", want: Result{}},
+		{name: "infected", content: eicar, want: Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+		{name: "infected after the first chunk", content: large + eicar, want: Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+	}
+	clamd := newFakeClamd(t, 0)
+	for _, address := range []string{clamd.listener.Addr().String(), "tcp://" + clamd.listener.Addr().String()} {
+		scanner, err := NewClamdScanner(address, 5*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range tests {
+			t.Run(address+"/"+tt.name, func(t *testing.T) {
+				got, err := scanner.Scan(context.Background(), strings.NewReader(tt.content))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("Scan = %+v, want %+v", got, tt.want)
+				}
+				if received := <-clamd.received; string(received) != tt.content {
+					t.Errorf("clamd received %d bytes, want %d", len(received), len(tt.content))
+				}
+			})
+		}
+	}
+}
+
+func TestClamdScannerSizeLimit(t *testing.T) {
+	clamd := newFakeClamd(t, clamdChunkSize)
+	scanner, err := NewClamdScanner(clamd.listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = scanner.Scan(context.Background(), bytes.NewReader(make([]byte, 4*clamdChunkSize)))
+	if err == nil || !strings.Contains(err.Error(), "INSTREAM size limit exceeded") {
+		t.Errorf("Scan = %v, want the size limit error of clamd", err)
+	}
+}
+
+func TestClamdScannerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewClamdScanner(address, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scanner.Scan(context.Background(), strings.NewReader(eicar)); err == nil || !strings.Contains(err.Error(), "antivirus injoignable") {
+		t.Errorf("Scan = %v, want an unreachable error", err)
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	tests := []struct {
+		reply   string
+		want    Result
+		wantErr bool
+	}{
+		{reply: "stream: OK", want: Result{}},
+		{reply: "stream: Eicar-Test-Signature FOUND", want: Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+		{reply: "stream: Win.Test.EICAR_HDB-1 FOUND", want: Result{Infected: true, Signature: "Win.Test.EICAR_HDB-1"}},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+		{reply: "UNKNOWN COMMAND", wantErr: true},
+		{reply: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			got, err := parseClamdReply(tt.reply)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClamdReply(%q) error = %v, want error %v", tt.reply, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseClamdReply(%q) = %+v, want %+v", tt.reply, got, tt.want)
+			}
+		})
+	}
+}
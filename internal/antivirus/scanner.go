@@ -0,0 +1,23 @@
+// Package antivirus scans uploaded files for malware before they are stored. Each engine
+// implements Scanner; ClamdScanner talks to a ClamAV daemon over its network protocol.
+package antivirus
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the verdict of a scan.
+type Result struct {
+	Infected  bool   // Whether malware was found.
+	Signature string // Name of the malware found, e.g., "Eicar-Test-Signature".
+}
+
+// Scanner is implemented by the antivirus engines.
+type Scanner interface {
+	// Name returns the name of the engine, for logs.
+	Name() string
+	// Scan reads the content of a file from r and reports whether it is infected. An error means
+	// that the file could not be scanned, not that it is infected.
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
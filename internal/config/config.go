@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds all application-wide configuration settings.
@@ -31,6 +32,9 @@ type Config struct {
 	// Security Headers Configuration
 	ContentSecurityPolicy string // Value for the Content-Security-Policy HTTP header
 
+	// Reverse Proxy Configuration
+	TrustedProxies string // Comma-separated IPs or CIDRs of the reverse proxies whose X-Forwarded-For header is trusted; empty to trust none
+
 	// SMTP (Simple Mail Transfer Protocol) Configuration for sending emails
 	SMTPHost     string // SMTP server host
 	SMTPPort     int    // SMTP server port
@@ -39,7 +43,27 @@ type Config struct {
 	EmailSender  string // Email address used as the sender
 
 	// Document Storage Configuration
-	DocumentStoragePath string // File system path where uploaded documents are stored
+	DocumentStorageBackend string // Storage backend of the documents: "local" (file system) or "s3" (S3-compatible object store)
+	DocumentStoragePath    string // File system path where uploaded documents are stored by the "local" backend
+	S3Endpoint             string // Base URL of the S3-compatible service, e.g., "https://s3.fr-par.scw.cloud"
+	S3Region               string // Region of the bucket, used to sign requests
+	S3Bucket               string // Bucket holding the documents
+	S3AccessKeyID          string // Access key of the S3 account
+	S3SecretAccessKey      string // Secret key of the S3 account
+
+	// Document Upload Configuration
+	DocumentMaxSizeMB    int    // Largest file that can be uploaded, in megabytes
+	DocumentQuotaMB      int    // Storage space of each association, in megabytes; 0 for no limit
+	DocumentAllowedTypes string // Comma-separated MIME types that can be uploaded; empty for the built-in list
+	ClamAVAddress        string // Address of the ClamAV daemon scanning uploads ("tcp://host:3310" or "unix:///path"); empty to disable scanning
+	ClamAVTimeout        int    // Timeout of a scan, in seconds
+	DocumentTrashDays    int    // Days after which deleted documents are purged from the trash; 0 to keep them until the trash is emptied
+
+	// Online Payment Configuration
+	PaymentProvider     string // Online payment provider: "stripe", "fake" (local simulation) or empty to disable online payment
+	StripeSecretKey     string // Secret API key of the Stripe account
+	StripeWebhookSecret string // Signing secret of the Stripe webhook endpoint
+	StripeAPIURL        string // Base URL of the Stripe-compatible API
 }
 
 // LoadConfig loads application configuration from environment variables.
@@ -59,6 +83,7 @@ func LoadConfig() (*Config, error) {
 		CookieName:            getEnv("COOKIE_NAME", "mysession"),
 		CSRFSecret:            os.Getenv("CSRF_SECRET"),
 		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'self'; script-src 'self' https://cdn.jsdelivr.net 'sha256-nhU1dNZtRMH0wGMdWus+C2+OLS90BrB/ybY9vr8XxvA='; style-src 'self' https://fonts.googleapis.com https://fonts.gstatic.com 'unsafe-inline'; font-src 'self' https://fonts.gstatic.com; object-src 'none';"),
+		TrustedProxies:        os.Getenv("TRUSTED_PROXIES"),
 
 		SMTPHost:     os.Getenv("SMTP_HOST"),
 		SMTPPort:     getEnvAsInt("SMTP_PORT", 587), // Default SMTP port
@@ -66,7 +91,25 @@ func LoadConfig() (*Config, error) {
 		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 		EmailSender:  getEnv("EMAIL_SENDER", "no-reply@assoss.com"),
 
-		DocumentStoragePath: getEnv("DOCUMENT_STORAGE_PATH", "./data/documents"),
+		DocumentStorageBackend: getEnv("DOCUMENT_STORAGE_BACKEND", "local"),
+		DocumentStoragePath:    getEnv("DOCUMENT_STORAGE_PATH", "./data/documents"),
+		S3Endpoint:             os.Getenv("S3_ENDPOINT"),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:               os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:          os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey:      os.Getenv("S3_SECRET_ACCESS_KEY"),
+
+		DocumentMaxSizeMB:    getEnvAsInt("DOCUMENT_MAX_SIZE_MB", 25),
+		DocumentQuotaMB:      getEnvAsInt("DOCUMENT_QUOTA_MB", 1024),
+		DocumentAllowedTypes: os.Getenv("DOCUMENT_ALLOWED_TYPES"),
+		ClamAVAddress:        os.Getenv("CLAMAV_ADDRESS"),
+		ClamAVTimeout:        getEnvAsInt("CLAMAV_TIMEOUT", 60),
+		DocumentTrashDays:    getEnvAsInt("DOCUMENT_TRASH_DAYS", 30),
+
+		PaymentProvider:     os.Getenv("PAYMENT_PROVIDER"),
+		StripeSecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		StripeWebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+		StripeAPIURL:        getEnv("STRIPE_API_URL", "https://api.stripe.com"),
 	}
 
 	// Basic validation for essential OIDC configuration.
@@ -103,6 +146,17 @@ func getEnvAsBool(name string, defaultVal bool) bool {
 	return defaultVal
 }
 
+// TrustedProxyList returns the IPs or CIDRs of the trusted reverse proxies, or nil if none is.
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(c.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 // SessionSameSiteMode converts the string representation of SameSite policy to http.SameSite enum.
 func (c *Config) SessionSameSiteMode() http.SameSite {
 	switch c.SessionSameSite {
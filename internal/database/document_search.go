@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// documentSearchTriggers keep the FTS5 index of the documents up to date. The index uses the
+// documents table as its external content, so that the text is not stored twice.
+var documentSearchTriggers = map[string]string{
+	"documents_fts_insert": `CREATE TRIGGER IF NOT EXISTS documents_fts_insert AFTER INSERT ON documents BEGIN
+		INSERT INTO documents_fts(rowid, name, text_content) VALUES (new.id, new.name, new.text_content);
+	END`,
+	"documents_fts_delete": `CREATE TRIGGER IF NOT EXISTS documents_fts_delete AFTER DELETE ON documents BEGIN
+		INSERT INTO documents_fts(documents_fts, rowid, name, text_content) VALUES ('delete', old.id, old.name, old.text_content);
+	END`,
+	"documents_fts_update": `CREATE TRIGGER IF NOT EXISTS documents_fts_update AFTER UPDATE ON documents BEGIN
+		INSERT INTO documents_fts(documents_fts, rowid, name, text_content) VALUES ('delete', old.id, old.name, old.text_content);
+		INSERT INTO documents_fts(rowid, name, text_content) VALUES (new.id, new.name, new.text_content);
+	END`,
+}
+
+// InitDocumentSearch sets up the full-text search of the documents: an SQLite FTS5 table indexing
+// their name and text content, accent- and case-insensitive, maintained by triggers and rebuilt at
+// startup. It must run after the schema migration of the documents table.
+//
+// FTS5 is only available when the SQLite driver is built with the sqlite_fts5 tag. Without it, the
+// triggers are removed so that writing documents keeps working, and an error is returned: the
+// caller then falls back to a simple search.
+func InitDocumentSearch(db *gorm.DB) error {
+	// Probe the FTS5 module on a temporary table, which also detects a database indexed by a
+	// previous build with FTS5 now opened without it.
+	if err := db.Exec("CREATE VIRTUAL TABLE temp.fts5_probe USING fts5(content)").Error; err != nil {
+		for name := range documentSearchTriggers {
+			if dropErr := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; dropErr != nil {
+				return fmt.Errorf("failed to drop the trigger %s: %w", name, dropErr)
+			}
+		}
+		return fmt.Errorf("SQLite FTS5 module unavailable (build with -tags sqlite_fts5): %w", err)
+	}
+	if err := db.Exec("DROP TABLE temp.fts5_probe").Error; err != nil {
+		return err
+	}
+
+	if err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS documents_fts USING fts5(
+		name, text_content, content='documents', content_rowid='id', tokenize='unicode61 remove_diacritics 2')`).Error; err != nil {
+		return fmt.Errorf("failed to create the document search table: %w", err)
+	}
+	for name, statement := range documentSearchTriggers {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to create the trigger %s: %w", name, err)
+		}
+	}
+	// Rebuild the index, which may be stale if the application ran without FTS5 in between.
+	if err := db.Exec("INSERT INTO documents_fts(documents_fts) VALUES ('rebuild')").Error; err != nil {
+		return fmt.Errorf("failed to rebuild the document search index: %w", err)
+	}
+	return nil
+}
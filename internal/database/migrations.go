@@ -0,0 +1,162 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// MigrateTransactionAmounts converts the legacy floating-point "amount" column of the transactions
+// table into exact minor units in the default currency, then drops the legacy column.
+// It must run after the schema migration has created the new money columns, and does nothing
+// once the legacy column is gone. It returns the number of transactions converted.
+func MigrateTransactionAmounts(db *gorm.DB) (int64, error) {
+	if !db.Migrator().HasColumn("transactions", "amount") {
+		return 0, nil
+	}
+
+	var converted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Legacy amounts were entered with at most two decimals, so rounding amount*100 to the
+		// nearest integer recovers the intended number of cents.
+		result := tx.Exec(`UPDATE transactions
+			SET amount_minor = CAST(ROUND(amount * 100) AS INTEGER),
+				base_amount = CAST(ROUND(amount * 100) AS INTEGER),
+				currency = ?,
+				exchange_rate = '1'
+			WHERE amount IS NOT NULL AND (currency IS NULL OR currency = '')`, models.DefaultCurrency)
+		if result.Error != nil {
+			return fmt.Errorf("failed to convert transaction amounts: %w", result.Error)
+		}
+		converted = result.RowsAffected
+
+		if err := tx.Exec("ALTER TABLE transactions DROP COLUMN amount").Error; err != nil {
+			return fmt.Errorf("failed to drop the legacy amount column: %w", err)
+		}
+		return nil
+	})
+	return converted, err
+}
+
+// MigrateDocumentVersions records the file of each document stored before documents had versions
+// as its first version. It must run after the schema migration has created the versions table, and
+// does nothing once every document has a version. It returns the number of documents migrated.
+func MigrateDocumentVersions(db *gorm.DB) (int64, error) {
+	var migrated int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO document_versions
+			(created_at, updated_at, document_id, number, file_name, file_path, file_size, mime_type, upload_date, uploaded_by_id, uploaded_by, comment)
+			SELECT created_at, updated_at, id, 1, name, file_path, file_size, mime_type, upload_date, user_id, '', ''
+			FROM documents WHERE version IS NULL OR version = 0`).Error; err != nil {
+			return fmt.Errorf("failed to create the first document versions: %w", err)
+		}
+		result := tx.Exec("UPDATE documents SET version = 1 WHERE version IS NULL OR version = 0")
+		if result.Error != nil {
+			return fmt.Errorf("failed to number the document versions: %w", result.Error)
+		}
+		migrated = result.RowsAffected
+		return nil
+	})
+	return migrated, err
+}
+
+// MigrateDocumentStorageKeys turns the file paths recorded for documents and their versions before
+// the storage of files was pluggable into storage keys: the name of the file within the storage
+// directory. It does nothing once every reference is a key. It returns the number of references
+// migrated.
+func MigrateDocumentStorageKeys(db *gorm.DB) (int64, error) {
+	var migrated int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"documents", "document_versions"} {
+			var rows []struct {
+				ID       uint
+				FilePath string
+			}
+			if err := tx.Raw(`SELECT id, file_path FROM ` + table + ` WHERE file_path LIKE '%/%' OR file_path LIKE '%\%'`).Scan(&rows).Error; err != nil {
+				return fmt.Errorf("failed to read the file paths of %s: %w", table, err)
+			}
+			for _, row := range rows {
+				key := row.FilePath[strings.LastIndexAny(row.FilePath, `/\`)+1:]
+				if err := tx.Exec(`UPDATE `+table+` SET file_path = ? WHERE id = ?`, key, row.ID).Error; err != nil {
+					return fmt.Errorf("failed to migrate the file path of %s %d: %w", table, row.ID, err)
+				}
+				migrated++
+			}
+		}
+		return nil
+	})
+	return migrated, err
+}
+
+// MigrateSecretBallots rebuilds the table of the secret ballots without rowid, so that the hidden
+// sequence SQLite gives the rows of an ordinary table does not keep the order in which ballots
+// were cast, which the participations of the voters also keep. A ballot recorded before secret
+// ballots carried a single vote is split into as many ballots as its weight, the others keyed by
+// random hashes. It does nothing once the table is without rowid. It returns the number of
+// ballots migrated.
+func MigrateSecretBallots(db *gorm.DB) (int64, error) {
+	var schema string
+	if err := db.Raw(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'secret_ballots'`).Scan(&schema).Error; err != nil {
+		return 0, fmt.Errorf("failed to read the schema of secret_ballots: %w", err)
+	}
+	if schema == "" || strings.Contains(strings.ToUpper(schema), "WITHOUT ROWID") {
+		return 0, nil
+	}
+
+	var migrated int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`CREATE TABLE secret_ballots_new (
+			receipt_hash TEXT NOT NULL PRIMARY KEY,
+			poll_id INTEGER,
+			choices TEXT
+		) WITHOUT ROWID`).Error; err != nil {
+			return fmt.Errorf("failed to create the secret ballots table: %w", err)
+		}
+		result := tx.Exec(`INSERT INTO secret_ballots_new (receipt_hash, poll_id, choices)
+			SELECT receipt_hash, poll_id, choices FROM secret_ballots`)
+		if result.Error != nil {
+			return fmt.Errorf("failed to copy the secret ballots: %w", result.Error)
+		}
+		migrated = result.RowsAffected
+
+		if tx.Migrator().HasColumn("secret_ballots", "weight") {
+			var weighted []struct {
+				PollID  uint
+				Choices string
+				Weight  int
+			}
+			if err := tx.Raw(`SELECT poll_id, choices, weight FROM secret_ballots WHERE weight > 1`).Scan(&weighted).Error; err != nil {
+				return fmt.Errorf("failed to read the weighted secret ballots: %w", err)
+			}
+			for _, ballot := range weighted {
+				for i := 1; i < ballot.Weight; i++ {
+					key := make([]byte, sha256.Size)
+					if _, err := rand.Read(key); err != nil {
+						return fmt.Errorf("failed to generate a secret ballot key: %w", err)
+					}
+					if err := tx.Exec(`INSERT INTO secret_ballots_new (receipt_hash, poll_id, choices) VALUES (?, ?, ?)`,
+						hex.EncodeToString(key), ballot.PollID, ballot.Choices).Error; err != nil {
+						return fmt.Errorf("failed to split a weighted secret ballot: %w", err)
+					}
+				}
+			}
+		}
+
+		for _, statement := range []string{
+			`DROP TABLE secret_ballots`,
+			`ALTER TABLE secret_ballots_new RENAME TO secret_ballots`,
+			`CREATE INDEX idx_secret_ballots_poll_id ON secret_ballots(poll_id)`,
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to replace the secret ballots table: %w", err)
+			}
+		}
+		return nil
+	})
+	return migrated, err
+}
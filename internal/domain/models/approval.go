@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ApprovalDecision defines the outcome of the review of a submitted expense.
+type ApprovalDecision string
+
+// Constants defining the possible decisions of the treasurer.
+const (
+	DecisionApproved ApprovalDecision = "Approuvée" // The expense is posted and counts in the totals.
+	DecisionRejected ApprovalDecision = "Rejetée"   // The expense is refused until it is edited and resubmitted.
+)
+
+// TransactionApproval records a decision taken on a submitted expense, with its author and time.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type TransactionApproval struct {
+	gorm.Model
+	TransactionID uint             `json:"transaction_id"` // The expense the decision applies to.
+	UserID        uint             `json:"user_id"`        // The owner of the association the expense belongs to.
+	ActorID       uint             `json:"actor_id"`       // The user who took the decision.
+	ActorName     string           `json:"actor_name"`     // The name of the user at the time of the decision.
+	Decision      ApprovalDecision `json:"decision"`       // Approved or rejected.
+	Comment       string           `json:"comment"`        // The justification given by the treasurer.
+	DecidedAt     time.Time        `json:"decided_at"`     // When the decision was taken.
+	Description   string           `json:"description"`    // The description of the expense when it was reviewed.
+	Amount        Money            `json:"amount"`         // The amount of the expense when it was reviewed.
+}
+
+// ApprovalRequest is an expense awaiting the decision of a treasurer, with the association it
+// belongs to.
+type ApprovalRequest struct {
+	Transaction Transaction
+	Association string
+}
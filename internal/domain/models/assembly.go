@@ -0,0 +1,142 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AssemblyStatus represents the state of a general assembly.
+type AssemblyStatus string
+
+// Constants for the states of a general assembly.
+const (
+	AssemblyPlanned AssemblyStatus = "Convoquée" // Being prepared: resolutions and attendance sheet.
+	AssemblyOpen    AssemblyStatus = "En séance" // In session: the resolutions can be opened to votes.
+	AssemblyClosed  AssemblyStatus = "Clôturée"  // Over: the minutes are final.
+)
+
+// AttendanceStatus represents how a member takes part in a general assembly.
+type AttendanceStatus string
+
+// Constants for the attendance of a member at a general assembly.
+const (
+	AttendanceAbsent      AttendanceStatus = "Absent"     // Neither present nor represented.
+	AttendancePresent     AttendanceStatus = "Présent"    // Present, votes for itself and for the members it represents.
+	AttendanceRepresented AttendanceStatus = "Représenté" // Absent, has given its proxy to a present member.
+)
+
+// AttendanceStatuses returns the attendances a member can be recorded with.
+func AttendanceStatuses() []AttendanceStatus {
+	return []AttendanceStatus{AttendanceAbsent, AttendancePresent, AttendanceRepresented}
+}
+
+// Valid reports whether the attendance is one of the AttendanceStatus constants.
+func (s AttendanceStatus) Valid() bool {
+	for _, status := range AttendanceStatuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Assembly represents a general assembly of an association: a session whose resolutions are polls
+// voted on by the members present, for themselves and for the members they hold the proxies of.
+// The resolutions can only be opened to votes once the quorum is reached.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type Assembly struct {
+	gorm.Model
+	UserID        uint                 `json:"user_id"`                              // The ID of the application user who owns the association.
+	Title         string               `json:"title" form:"title"`                   // E.g. "Assemblée générale ordinaire 2026".
+	Date          time.Time            `json:"date" form:"-"`                        // When the assembly is held.
+	Location      string               `json:"location" form:"location"`             // Where the assembly is held.
+	QuorumPercent int                  `json:"quorum_percent" form:"quorum_percent"` // The share of the active members who must be present or represented, per the statutes.
+	MaxProxies    int                  `json:"max_proxies" form:"max_proxies"`       // The most proxies a member may hold; 0 forbids proxies.
+	Status        AssemblyStatus       `json:"status"`
+	OpenedAt      *time.Time           `json:"opened_at"` // When the session was opened.
+	ClosedAt      *time.Time           `json:"closed_at"` // When the session was closed.
+	Attendances   []AssemblyAttendance `json:"attendances"`
+}
+
+// AssemblyAttendance records how a member takes part in a general assembly. The members without
+// a record are absent.
+type AssemblyAttendance struct {
+	ID            uint             `json:"id"`
+	AssemblyID    uint             `json:"assembly_id"`
+	MemberID      uint             `json:"member_id"`
+	Status        AttendanceStatus `json:"status"`
+	ProxyHolderID *uint            `json:"proxy_holder_id"` // The present member a represented member has given its proxy to.
+	Weight        int              `json:"weight"`          // The votes of the member, 1 unless the statutes weigh the votes.
+}
+
+// Attendance returns the attendance recorded for a member, or nil if the member is absent.
+func (a *Assembly) Attendance(memberID uint) *AssemblyAttendance {
+	for i := range a.Attendances {
+		if a.Attendances[i].MemberID == memberID {
+			return &a.Attendances[i]
+		}
+	}
+	return nil
+}
+
+// Votes returns the votes a present member casts: its own and those of the members it holds the
+// proxies of. A member who is not present casts none.
+func (a *Assembly) Votes(memberID uint) int {
+	attendance := a.Attendance(memberID)
+	if attendance == nil || attendance.Status != AttendancePresent {
+		return 0
+	}
+	votes := attendance.Weight
+	for _, other := range a.Attendances {
+		if other.Status == AttendanceRepresented && other.ProxyHolderID != nil && *other.ProxyHolderID == memberID {
+			votes += other.Weight
+		}
+	}
+	return votes
+}
+
+// AttendanceLine is a line of the attendance sheet of a general assembly: an active member of the
+// association, with its attendance and, for a present member, the proxies it holds.
+type AttendanceLine struct {
+	Member      Member           `json:"member"`
+	Status      AttendanceStatus `json:"status"`
+	ProxyHolder *Member          `json:"proxy_holder,omitempty"` // The member holding the proxy of a represented member.
+	Weight      int              `json:"weight"`
+	Proxies     []Member         `json:"proxies,omitempty"` // The members represented by a present member.
+	Votes       int              `json:"votes"`             // The votes a present member casts: its own and those of the members it represents.
+}
+
+// Quorum is the count of the members present or represented at a general assembly, against the
+// active members of the association.
+type Quorum struct {
+	ActiveMembers int64 `json:"active_members"`
+	Present       int   `json:"present"`
+	Represented   int   `json:"represented"`
+	Percent       int   `json:"percent"`  // The share of the active members required by the statutes.
+	Required      int   `json:"required"` // The members who must be present or represented.
+	Votes         int   `json:"votes"`    // The votes of the members present or represented.
+	Reached       bool  `json:"reached"`
+}
+
+// Attending returns the number of members present or represented.
+func (q *Quorum) Attending() int {
+	return q.Present + q.Represented
+}
+
+// ResolutionResult is a resolution of a general assembly with the count of its ballots.
+type ResolutionResult struct {
+	Poll    Poll       `json:"poll"`
+	State   PollStatus `json:"state"`
+	Tally   *PollTally `json:"tally"`
+	Outcome string     `json:"outcome"` // The winning options, or the options tied.
+}
+
+// AssemblyMinutes gathers what the minutes of a general assembly record.
+type AssemblyMinutes struct {
+	Assembly    Assembly           `json:"assembly"`
+	Association string             `json:"association"`
+	Quorum      Quorum             `json:"quorum"`
+	Attendance  []AttendanceLine   `json:"attendance"`
+	Resolutions []ResolutionResult `json:"resolutions"`
+}
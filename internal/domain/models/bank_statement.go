@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// BankStatementFormat identifies the file format of an imported bank statement.
+type BankStatementFormat string
+
+// Constants defining the supported bank statement formats.
+const (
+	FormatCSV     BankStatementFormat = "csv"     // Comma/semicolon separated values with a configurable column mapping.
+	FormatOFX     BankStatementFormat = "ofx"     // Open Financial Exchange (SGML 1.x or XML 2.x).
+	FormatCAMT053 BankStatementFormat = "camt053" // ISO 20022 Bank to Customer Statement (camt.053).
+)
+
+// BankStatementLine represents a single movement read from a bank statement.
+// Amount is signed: positive values are credits (income), negative values are debits (expenses).
+// Its currency is empty when the statement does not state it.
+type BankStatementLine struct {
+	Date        time.Time // The booking date of the movement.
+	Amount      Money     // The signed amount of the movement.
+	Description string    // The label provided by the bank.
+	Reference   string    // The bank's unique reference for the movement, if any.
+}
+
+// CSVColumnMapping describes how the columns of a CSV bank export map to statement fields.
+// Column indexes are 1-based so that they match what users see in a spreadsheet; 0 means "unused".
+type CSVColumnMapping struct {
+	Separator         string `form:"separator"`          // Field separator (defaults to ";").
+	DateColumn        int    `form:"date_column"`        // Column holding the booking date.
+	DateFormat        string `form:"date_format"`        // Go layout of the date column (defaults to "02/01/2006").
+	AmountColumn      int    `form:"amount_column"`      // Column holding a signed amount. Ignored when debit/credit columns are set.
+	DebitColumn       int    `form:"debit_column"`       // Column holding debit amounts, for exports with separate debit/credit columns.
+	CreditColumn      int    `form:"credit_column"`      // Column holding credit amounts, for exports with separate debit/credit columns.
+	DescriptionColumn int    `form:"description_column"` // Column holding the movement label.
+	ReferenceColumn   int    `form:"reference_column"`   // Optional column holding a unique movement reference.
+	DecimalComma      bool   `form:"decimal_comma"`      // True when amounts use a comma as decimal separator (e.g., "1 234,56").
+	HasHeader         bool   `form:"has_header"`         // True when the first row contains column titles.
+}
+
+// ImportResult summarizes the outcome of a bank statement import.
+type ImportResult struct {
+	Imported   int // Number of draft transactions created.
+	Duplicates int // Number of lines skipped because they were already imported.
+	Closed     int // Number of lines skipped because they fall within a closed fiscal year.
+	Matched    int // Number of draft transactions automatically matched to an existing transaction.
+}
+
+// ReconciliationItem pairs a draft transaction imported from a bank statement with
+// the existing transaction it was matched to, if any.
+type ReconciliationItem struct {
+	Draft   Transaction   // The imported draft transaction.
+	Match   *Transaction  // The proposed existing transaction, or nil if none was found.
+	Options []Transaction // Unreconciled transactions of the same type the user may pick instead.
+}
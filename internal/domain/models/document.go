@@ -11,7 +11,7 @@ import (
 type Document struct {
 	gorm.Model
 	Name       string    `json:"name" form:"name"`       // The original name of the uploaded document.
-	FilePath   string    `json:"file_path"`             // The relative or absolute path to the file on the server's file system.
+	FilePath   string    `json:"file_path"`             // The key of the file in the document store (local file system or S3-compatible bucket).
 	FileSize   int64     `json:"file_size"`             // The size of the file in bytes.
 	MimeType   string    `json:"mime_type"`             // The MIME type of the file (e.g., "application/pdf", "image/png").
 	UploadDate time.Time `json:"upload_date"`           // The timestamp when the document was uploaded.
@@ -19,4 +19,70 @@ type Document struct {
 	// UserID is the ID of the application user who uploaded this document.
 	// This establishes a relationship between the document and its owner.
 	UserID uint `json:"user_id"`
+
+	// TransactionID links the document to the financial transaction it justifies (receipt, invoice),
+	// if any. A transaction may have several attached documents.
+	TransactionID *uint `json:"transaction_id,omitempty" form:"-"`
+
+	FolderID *uint    `json:"folder_id,omitempty" form:"-"` // The folder containing the document; nil at the root of the library.
+	Tags     []string `json:"tags" form:"-"`                // The tags of the document, in lower case.
+
+	// TextContent is the text extracted from the file (plain text, PDF text layer) for the search.
+	TextContent string `json:"-" form:"-"`
+	// ContentIndexed tells whether the text of the file has been extracted, even if it has none.
+	ContentIndexed bool `json:"-" form:"-"`
+
+	// Version is the number of the current version of the document, whose file is described by
+	// FilePath, FileSize and MimeType. The previous files are kept as DocumentVersion.
+	Version int `json:"version" form:"-"`
+
+	// PreviewStatus tells whether the preview of the current file has been generated by the
+	// background worker. PreviewKey is the key of its thumbnail in the document store, if any.
+	PreviewStatus DocumentPreviewStatus `json:"preview_status" form:"-"`
+	PreviewKey    string                `json:"-" form:"-"`
+
+	// Visibility tells who may see and download the document besides the association owning it:
+	// the board, all the active members, or anyone. Documents are private by default.
+	Visibility DocumentVisibility `json:"visibility" form:"-"`
+}
+
+// DocumentPreviewStatus defines the state of the preview of a document.
+type DocumentPreviewStatus string
+
+// Constants defining the possible states of the preview of a document.
+const (
+	PreviewPending DocumentPreviewStatus = "En attente" // The preview has not been generated yet.
+	PreviewReady   DocumentPreviewStatus = "Prête"      // A thumbnail or a text excerpt is available.
+	PreviewNone    DocumentPreviewStatus = "Aucune"     // The file has no preview (type not supported, no text).
+	PreviewFailed  DocumentPreviewStatus = "Échec"      // The file could not be read (corrupted image...).
+)
+
+// DocumentPreview is what is shown of a document without downloading it: a thumbnail for
+// images, an excerpt of the text for PDF and text files.
+type DocumentPreview struct {
+	Status    DocumentPreviewStatus `json:"status"`
+	Thumbnail bool                  `json:"thumbnail"` // Whether a thumbnail of the document is available.
+	Excerpt   string                `json:"excerpt"`   // The beginning of the text of the document, if any.
+}
+
+// HasThumbnail reports whether a thumbnail of the document can be shown.
+func (d *Document) HasThumbnail() bool {
+	return d.PreviewStatus == PreviewReady && d.PreviewKey != ""
+}
+
+// DocumentVersion is a file uploaded for a document. A document has one version per upload or
+// restoration; the files of all versions are kept.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DocumentVersion struct {
+	gorm.Model
+	DocumentID   uint      `json:"document_id"`
+	Number       int       `json:"number"`      // The version number, starting at 1.
+	FileName     string    `json:"file_name"`   // The original name of the uploaded file.
+	FilePath     string    `json:"file_path"`   // The key of the file in the document store; restored versions share the file of the original.
+	FileSize     int64     `json:"file_size"`   // The size of the file in bytes.
+	MimeType     string    `json:"mime_type"`   // The MIME type of the file.
+	UploadDate   time.Time `json:"upload_date"` // The timestamp when the version was uploaded or restored.
+	UploadedByID uint      `json:"uploaded_by_id"`
+	UploadedBy   string    `json:"uploaded_by"`           // The name of the user who uploaded the version, if known.
+	Comment      string    `json:"comment" form:"comment"` // What changed in this version, e.g., "Révision adoptée en AG".
 }
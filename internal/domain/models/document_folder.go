@@ -0,0 +1,41 @@
+package models
+
+import "gorm.io/gorm"
+
+// DocumentFolder is a folder of the document library. Folders can be nested.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DocumentFolder struct {
+	gorm.Model
+	UserID   uint   `json:"user_id"`
+	ParentID *uint  `json:"parent_id,omitempty" form:"-"` // The parent folder; nil for a folder at the root of the library.
+	Name     string `json:"name" form:"name"`
+}
+
+// Sort orders of the document library.
+const (
+	DocumentSortDate = "date"
+	DocumentSortName = "name"
+	DocumentSortSize = "size"
+	DocumentSortType = "type"
+)
+
+// DocumentQuery describes the documents to list in the document library.
+type DocumentQuery struct {
+	// FolderID restricts the list to the documents of a folder, or to the documents at the root of
+	// the library when nil. It is ignored when AllFolders is set.
+	FolderID   *uint
+	AllFolders bool
+	Tag        string // Restricts the list to the documents with this tag, when set.
+	Search     string // Words searched in the name and the text content of the documents, when set.
+	Sort       string // One of the DocumentSort constants; by date when empty.
+	Descending bool
+
+	// Visibilities restricts the list to the documents with one of these visibilities, when set.
+	Visibilities []DocumentVisibility
+}
+
+// DocumentFolderLabel is a folder of the document library with its full path, for selection lists.
+type DocumentFolderLabel struct {
+	ID   uint
+	Path string
+}
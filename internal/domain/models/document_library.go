@@ -0,0 +1,63 @@
+package models
+
+// DocumentVisibility defines who may see a document besides the association owning it.
+type DocumentVisibility string
+
+// Constants defining the possible visibilities of a document, from the narrowest to the widest.
+const (
+	VisibilityPrivate DocumentVisibility = "Privé"   // Only the association owning the document.
+	VisibilityBoard   DocumentVisibility = "Bureau"  // The members of the board of the association.
+	VisibilityMembers DocumentVisibility = "Membres" // All the active members of the association.
+	VisibilityPublic  DocumentVisibility = "Public"  // Anyone, without logging in.
+)
+
+// DocumentVisibilities returns the visibilities a document can be given, from the narrowest to
+// the widest.
+func DocumentVisibilities() []DocumentVisibility {
+	return []DocumentVisibility{VisibilityPrivate, VisibilityBoard, VisibilityMembers, VisibilityPublic}
+}
+
+// Valid reports whether the visibility is one of the DocumentVisibility constants.
+func (v DocumentVisibility) Valid() bool {
+	for _, visibility := range DocumentVisibilities() {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// Description describes who may see a document with the visibility.
+func (v DocumentVisibility) Description() string {
+	switch v {
+	case VisibilityBoard:
+		return "membres du bureau"
+	case VisibilityMembers:
+		return "tous les membres actifs"
+	case VisibilityPublic:
+		return "tout le monde, sans connexion"
+	default:
+		return "association seulement"
+	}
+}
+
+// LibraryMembership is an association whose document library a user may browse, with the
+// standing of the user in it.
+type LibraryMembership struct {
+	AssociationID uint   `json:"association_id"` // The ID of the application user owning the association.
+	Name          string `json:"name"`           // The name of the association.
+	Owner         bool   `json:"owner"`          // Whether the user manages the association.
+	BoardRole     string `json:"board_role"`     // The role of the user on the board, if any.
+}
+
+// Audience returns the visibilities of the documents of the association the user may see.
+func (m *LibraryMembership) Audience() []DocumentVisibility {
+	switch {
+	case m.Owner:
+		return DocumentVisibilities()
+	case m.BoardRole != "":
+		return []DocumentVisibility{VisibilityBoard, VisibilityMembers, VisibilityPublic}
+	default:
+		return []DocumentVisibility{VisibilityMembers, VisibilityPublic}
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DocumentShareStatus represents the state of a share link.
+type DocumentShareStatus string
+
+// Constants for the states of a share link.
+const (
+	ShareActive    DocumentShareStatus = "Actif"
+	ShareExpired   DocumentShareStatus = "Expiré"
+	ShareExhausted DocumentShareStatus = "Épuisé" // The maximum number of downloads was reached.
+	ShareLocked    DocumentShareStatus = "Bloqué" // Too many wrong passwords were entered.
+	ShareRevoked   DocumentShareStatus = "Révoqué"
+)
+
+// MaxSharePasswordFailures is the number of wrong passwords after which a share link is locked.
+const MaxSharePasswordFailures = 10
+
+// DocumentShareLink is a secret link giving access to a document to people without an account,
+// e.g., external auditors. Only a hash of its token is stored: the link itself is shown once, when
+// it is created.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DocumentShareLink struct {
+	gorm.Model
+	UserID         uint       `json:"user_id"`     // The association the document belongs to.
+	DocumentID     uint       `json:"document_id"` // The shared document.
+	Label          string     `json:"label"`       // Who the link is for, e.g., "Commissaire aux comptes".
+	TokenHash      string     `json:"-"`           // SHA-256 hash of the token of the link.
+	PasswordHash   string     `json:"-"`           // bcrypt hash of the password; empty without password.
+	ExpiresAt      time.Time  `json:"expires_at"`
+	MaxDownloads   int        `json:"max_downloads"` // The number of downloads allowed; 0 for no limit.
+	DownloadCount  int        `json:"download_count"`
+	FailedAttempts int        `json:"failed_attempts"` // The number of wrong passwords entered.
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedByID    uint       `json:"created_by_id"`
+	CreatedBy      string     `json:"created_by"` // The name of the user who created the link, if known.
+}
+
+// HasPassword reports whether the link is protected by a password.
+func (l *DocumentShareLink) HasPassword() bool {
+	return l.PasswordHash != ""
+}
+
+// Status returns the state of the link at the given time.
+func (l *DocumentShareLink) Status(now time.Time) DocumentShareStatus {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareRevoked
+	case !now.Before(l.ExpiresAt):
+		return ShareExpired
+	case l.MaxDownloads > 0 && l.DownloadCount >= l.MaxDownloads:
+		return ShareExhausted
+	case l.FailedAttempts >= MaxSharePasswordFailures:
+		return ShareLocked
+	}
+	return ShareActive
+}
+
+// Active reports whether the link can still be used.
+func (l *DocumentShareLink) Active() bool {
+	return l.Status(time.Now()) == ShareActive
+}
+
+// RemainingDownloads returns the number of downloads left, or -1 without limit.
+func (l *DocumentShareLink) RemainingDownloads() int {
+	if l.MaxDownloads == 0 {
+		return -1
+	}
+	if l.DownloadCount >= l.MaxDownloads {
+		return 0
+	}
+	return l.MaxDownloads - l.DownloadCount
+}
+
+// DocumentShareDownload is an entry of the log of the downloads made through a share link.
+type DocumentShareDownload struct {
+	ID           uint      `json:"id"`
+	LinkID       uint      `json:"link_id"`
+	DocumentID   uint      `json:"document_id"`
+	Version      int       `json:"version"` // The version of the document that was downloaded.
+	DownloadedAt time.Time `json:"downloaded_at"`
+	IPAddress    string    `json:"ip_address"`
+	UserAgent    string    `json:"user_agent"`
+}
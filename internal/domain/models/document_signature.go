@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SignatureRequestStatus represents the state of a signature request.
+type SignatureRequestStatus string
+
+// Constants for the states of a signature request.
+const (
+	SignaturePending   SignatureRequestStatus = "En cours"
+	SignatureCompleted SignatureRequestStatus = "Signée"  // Every signer signed.
+	SignatureDeclined  SignatureRequestStatus = "Refusée" // A signer refused to sign.
+	SignatureCancelled SignatureRequestStatus = "Annulée" // The association withdrew the request.
+)
+
+// SignerStatus represents the state of a signer of a signature request.
+type SignerStatus string
+
+// Constants for the states of a signer.
+const (
+	SignerPending  SignerStatus = "En attente"
+	SignerSigned   SignerStatus = "Signé"
+	SignerDeclined SignerStatus = "Refusé"
+)
+
+// SignatureMethod tells how a signer signed.
+type SignatureMethod string
+
+// Constants for the ways of signing.
+const (
+	SignatureTyped SignatureMethod = "Saisie"     // The signer typed its name.
+	SignatureDrawn SignatureMethod = "Manuscrite" // The signer drew its signature.
+)
+
+// Size of the pad the signatures are drawn on, in the units of their strokes.
+const (
+	SignaturePadWidth  = 500
+	SignaturePadHeight = 150
+)
+
+// DocumentSignatureRequest asks members of the association to sign a version of a document. The
+// signed file is identified by its SHA-256 hash, checked again at every signature. Once every
+// signer signed, a signed PDF is produced: the document followed by an audit certificate.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DocumentSignatureRequest struct {
+	gorm.Model
+	UserID       uint                   `json:"user_id"`     // The association the document belongs to.
+	DocumentID   uint                   `json:"document_id"` // The document to sign.
+	DocumentName string                 `json:"document_name"`
+	Version      int                    `json:"version"`       // The version of the document to sign.
+	FilePath     string                 `json:"-"`             // The storage key of the file of that version.
+	FileSize     int64                  `json:"file_size"`     // The size of the file in bytes.
+	MimeType     string                 `json:"mime_type"`     // The MIME type of the file.
+	DocumentHash string                 `json:"document_hash"` // The SHA-256 hash of the file, in hexadecimal.
+	Message      string                 `json:"message"`       // The message sent to the signers.
+	Status       SignatureRequestStatus `json:"status"`
+	CreatedByID  uint                   `json:"created_by_id"`
+	CreatedBy    string                 `json:"created_by"`   // The name of the user who sent the request.
+	CompletedAt  *time.Time             `json:"completed_at"` // When the request was signed, refused or cancelled.
+	SignedKey    string                 `json:"-"`            // The storage key of the signed PDF, once produced.
+	SignedHash   string                 `json:"signed_hash"`  // The SHA-256 hash of the signed PDF.
+	Signers      []DocumentSigner       `json:"signers"`
+}
+
+// SignedCount returns the number of signers who signed.
+func (r *DocumentSignatureRequest) SignedCount() int {
+	count := 0
+	for _, signer := range r.Signers {
+		if signer.Status == SignerSigned {
+			count++
+		}
+	}
+	return count
+}
+
+// HasSignedFile reports whether the signed PDF of the request was produced.
+func (r *DocumentSignatureRequest) HasSignedFile() bool {
+	return r.SignedKey != ""
+}
+
+// DocumentSigner is a member asked to sign a document. The signer opens the request through a
+// secret link sent by email, of which only a hash is stored. The signature is recorded with its
+// time, the address and browser of the signer and the hash of the file it signed.
+type DocumentSigner struct {
+	ID            uint            `json:"id"`
+	RequestID     uint            `json:"request_id"`
+	MemberID      uint            `json:"member_id"`
+	Name          string          `json:"name"`
+	Email         string          `json:"email"`
+	TokenHash     string          `json:"-"` // SHA-256 hash of the token of the signing link.
+	Status        SignerStatus    `json:"status"`
+	EmailSentAt   *time.Time      `json:"email_sent_at"` // When the signing link was last sent.
+	ViewedAt      *time.Time      `json:"viewed_at"`     // When the signing link was first opened.
+	SignedAt      *time.Time      `json:"signed_at"`     // When the signer signed or refused.
+	Method        SignatureMethod `json:"method"`
+	SignatureText string          `json:"signature_text"` // The name typed by the signer.
+	// SignatureStrokes is the signature drawn by the signer, as a JSON array of strokes, each a flat
+	// array of x, y coordinates on a pad of SignaturePadWidth by SignaturePadHeight.
+	SignatureStrokes string `json:"-"`
+	DocumentHash     string `json:"document_hash"` // The hash of the file when it was signed.
+	IPAddress        string `json:"ip_address"`
+	UserAgent        string `json:"user_agent"`
+	DeclineReason    string `json:"decline_reason"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Kinds of the records referencing a file of the document store.
+const (
+	StorageRefDocument   = "document"   // The current file of a document.
+	StorageRefVersion    = "version"    // The file of a version of a document.
+	StorageRefPreview    = "preview"    // The thumbnail of a document.
+	StorageRefQuarantine = "quarantine" // A quarantined file.
+	StorageRefSignature  = "signature"  // The signed PDF of a signature request.
+)
+
+// StorageReference is a record referencing a file of the document store.
+type StorageReference struct {
+	Kind       string `json:"kind"`        // One of the StorageRef constants.
+	ID         uint   `json:"id"`          // The ID of the document, version, quarantined file or signature request.
+	DocumentID uint   `json:"document_id"` // The document of a version; the document itself otherwise.
+	UserID     uint   `json:"user_id"`
+	Name       string `json:"name"` // The name of the document or file.
+	Key        string `json:"key"`  // The storage key of the file.
+	Deleted    bool   `json:"deleted"`
+}
+
+// StoredFile is a file of the document store.
+type StoredFile struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// StorageCheck is the result of the consistency check of the document store against the records
+// of the database.
+type StorageCheck struct {
+	Files      int                // The number of files in the store.
+	References int                // The number of records referencing a file.
+	Orphans    []StoredFile       // Files no record references.
+	Missing    []StorageReference // Records whose file is not in the store.
+	Recent     int                // Unreferenced files too recent to be considered orphans.
+}
+
+// OrphanSize returns the storage space used by the orphan files, in bytes.
+func (c *StorageCheck) OrphanSize() int64 {
+	var size int64
+	for _, file := range c.Orphans {
+		size += file.Size
+	}
+	return size
+}
+
+// Consistent reports whether the store and the records match.
+func (c *StorageCheck) Consistent() bool {
+	return len(c.Orphans) == 0 && len(c.Missing) == 0
+}
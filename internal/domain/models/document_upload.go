@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuarantinedFile is an uploaded file in which the antivirus found malware. The file is kept aside
+// in the document store, out of the reach of downloads, until it is deleted.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type QuarantinedFile struct {
+	gorm.Model
+	UserID       uint      `json:"user_id"`   // The association the file was uploaded to.
+	FileName     string    `json:"file_name"` // The original name of the uploaded file.
+	StorageKey   string    `json:"-"`         // The key of the file in the document store.
+	FileSize     int64     `json:"file_size"` // The size of the file in bytes.
+	MimeType     string    `json:"mime_type"` // The type detected from the content of the file.
+	Signature    string    `json:"signature"` // The name of the malware found, e.g., "Eicar-Test-Signature".
+	UploadedByID uint      `json:"uploaded_by_id"`
+	UploadedBy   string    `json:"uploaded_by"` // The name of the user who uploaded the file, if known.
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
+// StorageUsage is the storage space used by the documents of an association.
+type StorageUsage struct {
+	Documents int64 `json:"documents"` // The number of documents.
+	Used      int64 `json:"used"`      // The size of the files of all versions, trash included, in bytes.
+	Quota     int64 `json:"quota"`     // The storage space of the association in bytes; 0 for no limit.
+}
+
+// Percent returns the share of the quota used, from 0 to 100 (and beyond when the quota was
+// lowered); 0 without quota.
+func (u StorageUsage) Percent() float64 {
+	if u.Quota <= 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Quota) * 100
+}
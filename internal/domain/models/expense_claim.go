@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ExpenseClaimStatus defines the review state of an expense claim.
+type ExpenseClaimStatus string
+
+// Constants defining the possible expense claim statuses.
+const (
+	ClaimSubmitted ExpenseClaimStatus = "Soumise"   // Awaiting the decision of the treasurer.
+	ClaimApproved  ExpenseClaimStatus = "Approuvée" // Approved: its expenses are recorded and the member is owed the total.
+	ClaimRejected  ExpenseClaimStatus = "Rejetée"   // Refused by the treasurer; nothing is recorded.
+)
+
+// ReimbursementStatus defines whether the member has been paid back for an approved claim.
+type ReimbursementStatus string
+
+// Constants defining the possible reimbursement statuses.
+const (
+	ReimbursementPending ReimbursementStatus = "À rembourser" // The association owes the total to the member.
+	ReimbursementPaid    ReimbursementStatus = "Remboursée"   // The member has been paid back.
+)
+
+// ExpenseClaim represents a note de frais: expenses a member advanced on behalf of the association
+// and claims back. Approving the claim records one expense transaction per line.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type ExpenseClaim struct {
+	gorm.Model
+	UserID   uint               `json:"user_id"`                // The owner of the association the claim is addressed to.
+	MemberID uint               `json:"member_id" form:"-"`     // The member who advanced the money.
+	Title    string             `json:"title" form:"title"`     // A short title (event, trip...).
+	Currency string             `json:"currency"`               // The base currency of the association, used by every line.
+	Status   ExpenseClaimStatus `json:"status"`                 // The review state of the claim.
+	Lines    []ExpenseClaimLine `json:"lines"`                  // The expenses claimed.
+	Comment  string             `json:"comment" form:"comment"` // An optional note of the member.
+
+	ReviewerName  string     `json:"reviewer_name,omitempty"`  // The treasurer who approved or rejected the claim.
+	ReviewComment string     `json:"review_comment,omitempty"` // The justification given by the treasurer.
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`    // When the claim was approved or rejected.
+
+	Reimbursement ReimbursementStatus `json:"reimbursement,omitempty"` // Set once the claim is approved.
+	PaidAt        *time.Time          `json:"paid_at,omitempty"`       // When the member was paid back.
+	PaymentRef    string              `json:"payment_ref,omitempty"`   // The reference of the reimbursement (transfer, cheque number).
+}
+
+// Total returns the sum of the lines of the claim, in minor units of its currency.
+func (c *ExpenseClaim) Total() Money {
+	total := Money{Currency: c.Currency}
+	for _, line := range c.Lines {
+		total.Amount += line.Amount
+	}
+	return total
+}
+
+// ExpenseClaimLine represents one expense of a claim, justified by an uploaded receipt.
+type ExpenseClaimLine struct {
+	gorm.Model
+	ClaimID           uint      `json:"claim_id"`                      // The claim this line belongs to.
+	Date              time.Time `json:"date"`                          // The date of the expense.
+	Description       string    `json:"description"`                   // What was paid for.
+	AccountCode       string    `json:"account_code"`                  // The expense category (chart of accounts code).
+	Amount            int64     `json:"amount"`                        // The amount in minor units of the claim currency.
+	ReceiptDocumentID *uint     `json:"receipt_document_id,omitempty"` // The receipt stored in the document library, if any.
+	TransactionID     *uint     `json:"transaction_id,omitempty"`      // The expense transaction recorded when the claim was approved.
+}
+
+// MemberReimbursement sums the approved claims of a member by reimbursement status.
+type MemberReimbursement struct {
+	MemberID   uint
+	MemberName string
+	Pending    int64 // Total owed to the member, in minor units of the base currency.
+	Paid       int64 // Total already paid back to the member.
+	Submitted  int64 // Total of the claims awaiting review.
+}
@@ -0,0 +1,24 @@
+package models
+
+// ExportFormat defines the file format of an accounting export.
+type ExportFormat string
+
+// Constants defining the available export formats.
+const (
+	ExportFECPipe ExportFormat = "FEC (séparateur |)" // Fichier des Écritures Comptables, pipe-delimited.
+	ExportFECTab  ExportFormat = "FEC (tabulation)"   // Fichier des Écritures Comptables, tab-delimited.
+	ExportCSV     ExportFormat = "Journal CSV"        // Generic journal, one line per debit or credit, semicolon-delimited.
+	ExportQIF     ExportFormat = "QIF"                // Quicken Interchange Format, one record per bank movement.
+	ExportOFX     ExportFormat = "OFX"                // Open Financial Exchange 2 bank statement.
+)
+
+// ExportFormats lists the formats offered on the export page.
+var ExportFormats = []ExportFormat{ExportFECPipe, ExportFECTab, ExportCSV, ExportQIF, ExportOFX}
+
+// ExportFile is a generated export, ready to be downloaded.
+type ExportFile struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	Records     int // The number of journal lines or bank movements exported.
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FiscalPeriodAction defines an action taken on the closing of a fiscal year.
+type FiscalPeriodAction string
+
+// Constants defining the actions recorded in the closing log.
+const (
+	PeriodClosed   FiscalPeriodAction = "Clôture"     // The fiscal year was closed.
+	PeriodReopened FiscalPeriodAction = "Réouverture" // The fiscal year was reopened.
+)
+
+// FiscalPeriodClosure marks a fiscal year as closed: the transactions and journal entries dated
+// within it can no longer be created, edited or deleted. Corrections are made with adjusting
+// entries dated in an open period.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type FiscalPeriodClosure struct {
+	gorm.Model
+	UserID       uint      `json:"user_id"`        // The owner of the association.
+	FiscalYear   int       `json:"fiscal_year"`    // The fiscal year, identified by the calendar year it starts in.
+	Label        string    `json:"label"`          // The display label of the fiscal year ("2025-2026").
+	StartDate    time.Time `json:"start_date"`     // The first day of the closed period.
+	EndDate      time.Time `json:"end_date"`       // The first day after the closed period.
+	ClosedAt     time.Time `json:"closed_at"`      // When the period was closed.
+	ClosedByName string    `json:"closed_by_name"` // The name of the user who closed the period.
+}
+
+// Covers reports whether a date falls within the closed period.
+func (c *FiscalPeriodClosure) Covers(date time.Time) bool {
+	return !date.Before(c.StartDate) && date.Before(c.EndDate)
+}
+
+// FiscalPeriodEvent records a closing or a reopening of a fiscal year, with its author and reason.
+type FiscalPeriodEvent struct {
+	gorm.Model
+	UserID     uint               `json:"user_id"`     // The owner of the association.
+	FiscalYear int                `json:"fiscal_year"` // The fiscal year concerned.
+	Label      string             `json:"label"`       // The display label of the fiscal year.
+	Action     FiscalPeriodAction `json:"action"`      // Closing or reopening.
+	ActorID    uint               `json:"actor_id"`    // The user who took the action.
+	ActorName  string             `json:"actor_name"`  // The name of the user at the time of the action.
+	Reason     string             `json:"reason"`      // Why the period was reopened (required) or closed.
+	At         time.Time          `json:"at"`          // When the action was taken.
+}
+
+// FiscalYearStatus describes a fiscal year on the closing page.
+type FiscalYearStatus struct {
+	Year      int
+	Label     string
+	StartDate time.Time
+	LastDay   time.Time            // The last day of the fiscal year.
+	Ended     bool                 // Whether the fiscal year is over and can be closed.
+	Closure   *FiscalPeriodClosure // Set when the fiscal year is closed.
+}
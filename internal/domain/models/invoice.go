@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvoiceKind defines the type of document issued for an income.
+type InvoiceKind string
+
+// Constants defining the possible invoice kinds. Each kind has its own numbering sequence.
+const (
+	KindInvoice         InvoiceKind = "Facture"     // Invoice for a service or a sale.
+	KindDonationReceipt InvoiceKind = "Reçu fiscal" // Tax receipt for a donation (Cerfa 11580).
+)
+
+// InvoiceStatus defines the lifecycle state of an invoice.
+type InvoiceStatus string
+
+// Constants defining the possible invoice statuses.
+const (
+	InvoiceIssued    InvoiceStatus = "Émise"   // The invoice has been issued and is valid.
+	InvoiceCancelled InvoiceStatus = "Annulée" // The invoice has been cancelled; its number stays used.
+)
+
+// DonationAccountCode is the income account donations are booked on. The annual donation
+// receipt batch covers the posted transactions booked on this account.
+const DonationAccountCode = "754"
+
+// PaymentMethods lists the payment methods printed on donation receipts, as named by the Cerfa form.
+var PaymentMethods = []string{"Virement, prélèvement, carte bancaire", "Chèque", "Espèces"}
+
+// Invoice represents an invoice or a donation receipt issued for one or more income transactions.
+// Numbers are sequential and gap-free per association, kind and fiscal year: an invoice is never
+// deleted once issued, only cancelled.
+type Invoice struct {
+	gorm.Model
+	UserID     uint          `json:"user_id"`     // The ID of the application user who issued the invoice.
+	Kind       InvoiceKind   `json:"kind"`        // Invoice or donation receipt.
+	FiscalYear int           `json:"fiscal_year"` // The fiscal year the invoice is numbered in.
+	Sequence   int           `json:"sequence"`    // The position of the invoice in its numbering sequence.
+	Number     string        `json:"number"`      // The full invoice number (e.g., "FA-2026-0001").
+	Status     InvoiceStatus `json:"status"`      // Issued or cancelled.
+	IssueDate  time.Time     `json:"issue_date"`  // The date the invoice was issued.
+
+	// The member the invoice is addressed to, with the name and address at the time of issue.
+	MemberID         uint   `json:"member_id"`
+	RecipientName    string `json:"recipient_name"`
+	RecipientAddress string `json:"recipient_address"`
+
+	Description   string        `json:"description"`    // A free description printed on the invoice.
+	Amount        Money         `json:"amount"`         // The total amount, in the base currency.
+	PaymentMethod string        `json:"payment_method"` // How the income was paid (one of PaymentMethods).
+	Lines         []InvoiceLine `json:"lines"`          // The transactions covered by the invoice.
+
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"` // The date the invoice was cancelled, if it was.
+}
+
+// InvoiceLine represents an income transaction covered by an invoice.
+type InvoiceLine struct {
+	gorm.Model
+	InvoiceID     uint      `json:"invoice_id"`     // The ID of the invoice this line belongs to.
+	TransactionID uint      `json:"transaction_id"` // The income transaction the line is for.
+	Date          time.Time `json:"date"`           // The date of the transaction.
+	Description   string    `json:"description"`    // The description of the transaction.
+	Amount        int64     `json:"amount"`         // The amount in minor units of the base currency.
+}
+
+// InvoiceNumber formats the number of an invoice from its kind, fiscal year and sequence.
+func InvoiceNumber(kind InvoiceKind, fiscalYear, sequence int) string {
+	prefix := "FA"
+	if kind == KindDonationReceipt {
+		prefix = "RF"
+	}
+	return fmt.Sprintf("%s-%d-%04d", prefix, fiscalYear, sequence)
+}
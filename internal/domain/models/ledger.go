@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AccountType defines the class of an account in the chart of accounts.
+type AccountType string
+
+// Constants defining the possible account types.
+const (
+	AccountAsset     AccountType = "Actif"    // Resources held by the association (bank, cash, receivables).
+	AccountLiability AccountType = "Passif"   // Amounts owed by the association (suppliers, debts).
+	AccountEquity    AccountType = "Capitaux" // Association funds and retained results.
+	AccountIncome    AccountType = "Produit"  // Revenue accounts (class 7 of the French chart of accounts).
+	AccountExpense   AccountType = "Charge"   // Expense accounts (class 6 of the French chart of accounts).
+)
+
+// DefaultBankAccountCode is the account used as counterpart for the entries generated
+// from the simple transaction form.
+const DefaultBankAccountCode = "512"
+
+// Account represents an account of the chart of accounts of an association.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type Account struct {
+	gorm.Model
+	Code   string      `json:"code" form:"code"` // The account number (e.g., "512" for the bank account).
+	Name   string      `json:"name" form:"name"` // The human-readable label of the account.
+	Type   AccountType `json:"type" form:"type"` // The class of the account, which drives its place in the reports.
+	UserID uint        `json:"user_id"`          // The ID of the application user who owns this chart of accounts.
+}
+
+// JournalEntry represents a balanced accounting entry made of several debit and credit lines.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type JournalEntry struct {
+	gorm.Model
+	Date        time.Time     `json:"date" form:"date" time_format:"2006-01-02"` // The accounting date of the entry.
+	Description string        `json:"description" form:"description"`            // The label of the entry.
+	Reference   string        `json:"reference" form:"reference"`                // An optional piece reference (invoice number, receipt...).
+	UserID      uint          `json:"user_id"`                                   // The ID of the application user who owns this entry.
+	Lines       []JournalLine `json:"lines" gorm:"foreignKey:JournalEntryID"`    // The debit and credit lines of the entry.
+
+	// TransactionID links the entry to the simple transaction it was generated from, if any.
+	// Entries entered directly in the journal have no transaction.
+	TransactionID *uint `json:"transaction_id,omitempty"`
+}
+
+// JournalLine represents one side of a journal entry against a single account.
+// Amounts are stored in cents so that debit and credit totals can be compared exactly.
+type JournalLine struct {
+	gorm.Model
+	JournalEntryID uint   `json:"journal_entry_id"` // The ID of the entry this line belongs to.
+	AccountCode    string `json:"account_code"`     // The code of the account debited or credited.
+	Label          string `json:"label"`            // An optional line-level label.
+	Debit          int64  `json:"debit"`            // The debit amount in cents (zero for a credit line).
+	Credit         int64  `json:"credit"`           // The credit amount in cents (zero for a debit line).
+}
+
+// LedgerLine is a line of the general ledger for a single account, with its running balance.
+type LedgerLine struct {
+	Date        time.Time
+	Description string
+	Reference   string
+	Debit       int64
+	Credit      int64
+	Balance     int64 // Running balance (debit minus credit) after this line.
+}
+
+// LedgerAccount groups the general ledger lines of a single account.
+type LedgerAccount struct {
+	Account     Account
+	Lines       []LedgerLine
+	TotalDebit  int64
+	TotalCredit int64
+	Balance     int64 // Debit minus credit.
+}
+
+// TrialBalanceRow holds the debit and credit totals of an account over a period.
+type TrialBalanceRow struct {
+	Account Account
+	Debit   int64
+	Credit  int64
+	Balance int64 // Debit minus credit.
+}
+
+// TrialBalance lists the totals of every account over a period.
+// TotalDebit and TotalCredit are always equal when every entry is balanced.
+type TrialBalance struct {
+	Rows        []TrialBalanceRow
+	TotalDebit  int64
+	TotalCredit int64
+}
+
+// StatementRow is a line of a balance sheet or income statement.
+type StatementRow struct {
+	Account Account
+	Amount  int64 // The amount in cents, with the natural sign of the section.
+}
+
+// BalanceSheet presents the financial position of an association at a given date.
+type BalanceSheet struct {
+	Date             time.Time
+	Assets           []StatementRow
+	Liabilities      []StatementRow
+	Equity           []StatementRow
+	NetResult        int64 // Result of the period not yet allocated to equity.
+	TotalAssets      int64
+	TotalLiabilities int64 // Liabilities plus equity plus the net result.
+}
+
+// IncomeStatement presents the revenue and expenses of an association over a period.
+type IncomeStatement struct {
+	From          time.Time
+	To            time.Time
+	Income        []StatementRow
+	Expenses      []StatementRow
+	TotalIncome   int64
+	TotalExpenses int64
+	NetResult     int64
+}
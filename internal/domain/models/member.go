@@ -24,6 +24,12 @@ type Member struct {
 	FirstName string `json:"first_name" form:"first_name"` // First name of the member.
 	LastName  string `json:"last_name" form:"last_name"`   // Last name of the member.
 	Email     string `json:"email" form:"email"`         // Email address of the member.
+	Address   string `json:"address" form:"address"`     // Postal address of the member, printed on invoices and donation receipts.
+
+	// BoardRole is the role of the member on the board of the association (e.g., "Présidente",
+	// "Trésorier"), empty for members outside the board. Board members see the documents
+	// published to the board.
+	BoardRole string `json:"board_role" form:"board_role"`
 
 	// UserID is the ID of the application user who manages this member record.
 	// This links the member to a specific association or user account.
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultCurrency is the base currency of associations that have not configured one.
+const DefaultCurrency = "EUR"
+
+// currencyDecimals lists the supported ISO 4217 currencies with their number of minor-unit digits.
+var currencyDecimals = map[string]int{
+	"AUD": 2, "CAD": 2, "CHF": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HUF": 2,
+	"JPY": 0, "MAD": 2, "NOK": 2, "PLN": 2, "RON": 2, "SEK": 2, "TND": 3, "USD": 2,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// CurrencyDecimals returns the number of minor-unit digits of an ISO 4217 currency code,
+// and false if the currency is not supported.
+func CurrencyDecimals(currency string) (int, bool) {
+	decimals, ok := currencyDecimals[currency]
+	return decimals, ok
+}
+
+// SupportedCurrencies returns the supported ISO 4217 currency codes in alphabetical order.
+func SupportedCurrencies() []string {
+	currencies := make([]string, 0, len(currencyDecimals))
+	for currency := range currencyDecimals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// Money represents an exact monetary amount as an integer number of minor units
+// (e.g., cents) of an ISO 4217 currency, so that sums never drift.
+type Money struct {
+	Amount   int64  `json:"amount"`   // The amount in minor units of the currency.
+	Currency string `json:"currency"` // The ISO 4217 currency code (e.g., "EUR").
+}
+
+// Decimal returns the amount as a plain decimal number with a dot separator (e.g., "1234.50"),
+// suitable for form inputs and exports.
+func (m Money) Decimal() string {
+	decimals, _ := CurrencyDecimals(m.Currency)
+	return FormatMinorUnits(m.Amount, decimals, ".")
+}
+
+// String returns the amount formatted for display with its currency (e.g., "1234,50 EUR").
+func (m Money) String() string {
+	decimals, _ := CurrencyDecimals(m.Currency)
+	return strings.TrimSpace(FormatMinorUnits(m.Amount, decimals, ",") + " " + m.Currency)
+}
+
+// FormatMinorUnits formats an amount expressed in minor units with the given number of
+// decimals and decimal separator (e.g., 123450, 2, "," gives "1234,50").
+func FormatMinorUnits(amount int64, decimals int, separator string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if decimals == 0 {
+		return fmt.Sprintf("%s%d", sign, amount)
+	}
+	scale := int64(1)
+	for i := 0; i < decimals; i++ {
+		scale *= 10
+	}
+	return fmt.Sprintf("%s%d%s%0*d", sign, amount/scale, separator, decimals, amount%scale)
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentPurpose defines what an online payment is collected for.
+type PaymentPurpose string
+
+// Constants defining the purposes of online payments.
+const (
+	PaymentDues     PaymentPurpose = "Cotisation"              // Membership dues; the member's last payment date is updated once paid.
+	PaymentEventFee PaymentPurpose = "Participation événement" // Fee for taking part in an event of the association.
+)
+
+// OnlinePaymentStatus defines the lifecycle state of an online payment.
+type OnlinePaymentStatus string
+
+// Constants defining the possible states of an online payment.
+const (
+	OnlinePaymentPending   OnlinePaymentStatus = "En attente" // The payment link has been issued and not paid yet.
+	OnlinePaymentPaid      OnlinePaymentStatus = "Payé"       // The provider confirmed the payment; the income is recorded.
+	OnlinePaymentFailed    OnlinePaymentStatus = "Échoué"     // The checkout expired or the payment was declined; a new attempt is possible.
+	OnlinePaymentRefunded  OnlinePaymentStatus = "Remboursé"  // The payment was refunded to the member.
+	OnlinePaymentCancelled OnlinePaymentStatus = "Annulé"     // The request was withdrawn by the association before being paid.
+)
+
+// OnlinePayment is a request for a member to pay dues or an event fee online. The member pays
+// through the public link identified by Token; the provider then reports the outcome by webhook.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type OnlinePayment struct {
+	gorm.Model
+	UserID      uint                `json:"user_id"`                     // The owner of the association collecting the payment.
+	MemberID    uint                `json:"member_id" form:"member_id"`  // The member who pays.
+	EventID     *uint               `json:"event_id,omitempty" form:"-"` // The event the fee is for, if any.
+	Purpose     PaymentPurpose      `json:"purpose" form:"purpose"`
+	Description string              `json:"description" form:"description"` // Label shown to the member and used for the income transaction.
+	Amount      Money               `json:"amount" form:"-"`
+	Status      OnlinePaymentStatus `json:"status" form:"-"`
+	Token       string              `json:"-" form:"-"` // Secret identifying the public payment page.
+
+	Provider   string `json:"provider" form:"-"`    // Name of the provider that handled the checkout.
+	SessionID  string `json:"session_id" form:"-"`  // Provider identifier of the last checkout session.
+	Attempts   int    `json:"attempts" form:"-"`    // Number of checkout sessions opened, a new one after each failure.
+	PaymentRef string `json:"payment_ref" form:"-"` // Provider identifier of the captured payment, used for refunds.
+
+	TransactionID       *uint      `json:"transaction_id,omitempty" form:"-"` // The income recorded when the payment succeeded.
+	PaidAt              *time.Time `json:"paid_at,omitempty" form:"-"`
+	RefundTransactionID *uint      `json:"refund_transaction_id,omitempty" form:"-"` // The expense recorded when the payment was refunded.
+	RefundedAt          *time.Time `json:"refunded_at,omitempty" form:"-"`
+}
+
+// AccountCode returns the income account an online payment is booked against.
+func (p *OnlinePayment) AccountCode() string {
+	if p.Purpose == PaymentDues {
+		return "756"
+	}
+	return "706"
+}
@@ -1,16 +1,31 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // Poll represents a poll created by a user.
+// A poll is prepared as a draft, is open to votes between its opening and closing times, then
+// closed; its results are shown to the voters as chosen by its creator. In a secret poll, who
+// voted is recorded apart from the ballots, so that nobody can tell who voted for what.
+// A poll can be a resolution of a general assembly, voted on by the members present there.
 // It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
 type Poll struct {
 	gorm.Model
-	Question string   `json:"question" form:"question"` // The question posed in the poll.
-	UserID   uint     `json:"user_id"`                   // The ID of the user who created the poll.
-	Options  []Option `gorm:"foreignKey:PollID"`         // A slice of Option models associated with this poll (one-to-many relationship).
+	Question          string            `json:"question" form:"question"` // The question posed in the poll.
+	UserID            uint              `json:"user_id"`                  // The ID of the user who created the poll.
+	Options           []Option          `gorm:"foreignKey:PollID"`        // A slice of Option models associated with this poll (one-to-many relationship).
+	Status            PollStatus        `json:"status"`                   // The state set by the creator; see State for the state at a given time.
+	OpensAt           *time.Time        `json:"opens_at"`                 // When a draft opens by itself, or when the poll was opened.
+	ClosesAt          *time.Time        `json:"closes_at"`                // When the poll closes by itself, or when it was closed.
+	ResultsVisibility ResultsVisibility `json:"results_visibility" form:"results_visibility"`
+	Type              PollType          `json:"type" form:"type"`               // How the voters choose among the options.
+	MinChoices        int               `json:"min_choices" form:"min_choices"` // The fewest options a ballot of a multiple choice poll chooses.
+	MaxChoices        int               `json:"max_choices" form:"max_choices"` // The most options a ballot of a multiple choice poll chooses, e.g. the seats to fill.
+	Anonymous         bool              `json:"anonymous" form:"anonymous"`     // Whether the poll is a secret ballot, whose ballots are not linked to their voters.
+	AssemblyID        *uint             `json:"assembly_id" form:"-"`           // The general assembly the poll is a resolution of, if any.
 }
 
 // Option represents a voting option for a poll.
@@ -28,4 +43,6 @@ type Vote struct {
 	gorm.Model
 	OptionID uint `json:"option_id"` // The ID of the option for which the user voted (foreign key).
 	UserID   uint `json:"user_id"`   // The ID of the user who cast the vote (foreign key).
+	Rank     int  `json:"rank"`      // The rank given to the option in a ranked poll, from 1; 0 in the other polls.
+	Weight   int  `json:"weight"`    // The votes the ballot carries, with the proxies of the voter; 0 for the votes cast before votes were weighted, which carry one.
 }
@@ -0,0 +1,86 @@
+package models
+
+import "time"
+
+// PollStatus represents the state of a poll.
+type PollStatus string
+
+// Constants for the states of a poll.
+const (
+	PollDraft  PollStatus = "Brouillon" // Being prepared, seen by its creator only.
+	PollOpen   PollStatus = "Ouvert"    // Open to votes.
+	PollClosed PollStatus = "Clôturé"   // Votes are over.
+)
+
+// ResultsVisibility tells when the voters may see the results of a poll. Its creator always
+// sees them.
+type ResultsVisibility string
+
+// Constants for the visibilities of the results of a poll.
+const (
+	ResultsLive       ResultsVisibility = "En direct"        // Anyone, as the votes come in.
+	ResultsAfterVote  ResultsVisibility = "Après le vote"    // The users who voted.
+	ResultsAfterClose ResultsVisibility = "Après la clôture" // Anyone, once the poll is closed.
+)
+
+// ResultsVisibilities returns the visibilities the results of a poll can be given.
+func ResultsVisibilities() []ResultsVisibility {
+	return []ResultsVisibility{ResultsLive, ResultsAfterVote, ResultsAfterClose}
+}
+
+// Valid reports whether the visibility is one of the ResultsVisibility constants.
+func (v ResultsVisibility) Valid() bool {
+	for _, visibility := range ResultsVisibilities() {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
+// Description describes who may see the results of a poll with the visibility.
+func (v ResultsVisibility) Description() string {
+	switch v {
+	case ResultsAfterVote:
+		return "visibles par chacun après avoir voté"
+	case ResultsAfterClose:
+		return "visibles par tous après la clôture"
+	default:
+		return "visibles par tous pendant le vote"
+	}
+}
+
+// State returns the state of the poll at the given time: a draft opens at its opening time, if
+// scheduled, and an open poll closes at its closing time, if scheduled. The polls created before
+// polls had states are open.
+func (p *Poll) State(now time.Time) PollStatus {
+	switch {
+	case p.Status == PollClosed || (p.ClosesAt != nil && !now.Before(*p.ClosesAt)):
+		return PollClosed
+	case p.Status == PollDraft && (p.OpensAt == nil || now.Before(*p.OpensAt)):
+		return PollDraft
+	default:
+		return PollOpen
+	}
+}
+
+// Scheduled reports whether the poll is a draft that will open by itself.
+func (p *Poll) Scheduled(now time.Time) bool {
+	return p.State(now) == PollDraft && p.OpensAt != nil
+}
+
+// ResultsVisibleTo reports whether a user sees the results of the poll at the given time, having
+// voted or not. The polls created before the results had a visibility show them live.
+func (p *Poll) ResultsVisibleTo(userID uint, hasVoted bool, now time.Time) bool {
+	if userID == p.UserID {
+		return true
+	}
+	switch p.ResultsVisibility {
+	case ResultsAfterClose:
+		return p.State(now) == PollClosed
+	case ResultsAfterVote:
+		return hasVoted || p.State(now) == PollClosed
+	default:
+		return p.State(now) != PollDraft
+	}
+}
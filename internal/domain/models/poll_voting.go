@@ -0,0 +1,135 @@
+package models
+
+// PollType tells how the voters of a poll choose among its options, and how its results are
+// counted.
+type PollType string
+
+// Constants for the types of poll.
+const (
+	PollSingle   PollType = "Choix unique"   // One option per voter.
+	PollMultiple PollType = "Choix multiple" // Between a minimum and a maximum number of options, e.g. one per seat to fill.
+	PollApproval PollType = "Approbation"    // Any number of options the voter approves of.
+	PollRanked   PollType = "Classement"     // The options ranked by preference, counted by instant-runoff and by the Schulze method.
+)
+
+// PollTypes returns the types a poll can be given.
+func PollTypes() []PollType {
+	return []PollType{PollSingle, PollMultiple, PollApproval, PollRanked}
+}
+
+// Valid reports whether the type is one of the PollType constants.
+func (t PollType) Valid() bool {
+	for _, pollType := range PollTypes() {
+		if t == pollType {
+			return true
+		}
+	}
+	return false
+}
+
+// Description describes how the voters of a poll of the type vote.
+func (t PollType) Description() string {
+	switch t {
+	case PollMultiple:
+		return "plusieurs options, entre un minimum et un maximum"
+	case PollApproval:
+		return "toutes les options que le votant approuve"
+	case PollRanked:
+		return "les options classées par ordre de préférence"
+	default:
+		return "une seule option"
+	}
+}
+
+// ChoiceLimits returns the fewest and the most options a ballot of the poll chooses, or ranks
+// for a ranked poll.
+func (p *Poll) ChoiceLimits() (min, max int) {
+	switch p.Type {
+	case PollMultiple:
+		return p.MinChoices, p.MaxChoices
+	case PollApproval, PollRanked:
+		return 1, len(p.Options)
+	default:
+		return 1, 1
+	}
+}
+
+// Ballot is a ballot to count: the options it chooses, in order of preference for a ranked poll,
+// and the votes it carries.
+type Ballot struct {
+	Choices []uint
+	Weight  int // The votes of the voter and of the members it holds the proxies of, at least 1.
+}
+
+// OptionTally is the count of the ballots choosing an option of a poll.
+type OptionTally struct {
+	OptionID uint    `json:"option_id"`
+	Text     string  `json:"text"`
+	Votes    int     `json:"votes"`   // The votes of the ballots choosing the option (first choices for a ranked poll).
+	Percent  float64 `json:"percent"` // The share of the votes cast choosing the option.
+	Leading  bool    `json:"leading"` // Whether the option is among the options leading the poll; unset for a ranked poll, whose winners come from its runoff and Schulze counts.
+}
+
+// RunoffRound is a round of an instant-runoff count: the ballots are counted for the first of
+// their options still in the running, then the option with the fewest votes is eliminated.
+type RunoffRound struct {
+	Number     int          `json:"number"`     // The number of the round, from 1.
+	Votes      map[uint]int `json:"votes"`      // Option ID -> votes counted for it; the options eliminated are absent.
+	Exhausted  int          `json:"exhausted"`  // The votes of the ballots ranking none of the options still in the running.
+	Eliminated []uint       `json:"eliminated"` // The options eliminated at the end of the round.
+}
+
+// InstantRunoffResult is the outcome of an instant-runoff count.
+type InstantRunoffResult struct {
+	Rounds  []RunoffRound `json:"rounds"`
+	Winners []uint        `json:"winners"` // The winning option, or the options tied at the end.
+}
+
+// SchulzeResult is the outcome of a count by the Schulze method (Condorcet): the options are
+// compared by pairs, then ranked by the strength of their strongest paths of victories.
+type SchulzeResult struct {
+	Preferences [][]int  `json:"preferences"` // [i][j] = the votes preferring the i-th option to the j-th one.
+	Strengths   [][]int  `json:"strengths"`   // [i][j] = the strength of the strongest path from the i-th option to the j-th one.
+	Ranking     [][]uint `json:"ranking"`     // The options from the first place to the last, tied options sharing a place.
+	Winners     []uint   `json:"winners"`     // The options in the first place.
+}
+
+// PollTally is the count of the ballots of a poll.
+type PollTally struct {
+	Type          PollType             `json:"type"`
+	Ballots       int                  `json:"ballots"` // The number of ballots cast.
+	Votes         int                  `json:"votes"`   // The votes carried by the ballots, more than the ballots when votes are weighted.
+	Options       []OptionTally        `json:"options"` // In the order of the options of the poll.
+	InstantRunoff *InstantRunoffResult `json:"instant_runoff,omitempty"`
+	Schulze       *SchulzeResult       `json:"schulze,omitempty"`
+}
+
+// Counts returns the votes choosing each option, by option ID.
+func (t *PollTally) Counts() map[uint]int {
+	counts := make(map[uint]int, len(t.Options))
+	for _, option := range t.Options {
+		counts[option.OptionID] = option.Votes
+	}
+	return counts
+}
+
+// OptionText returns the text of an option of the poll.
+func (t *PollTally) OptionText(optionID uint) string {
+	for _, option := range t.Options {
+		if option.OptionID == optionID {
+			return option.Text
+		}
+	}
+	return ""
+}
+
+// SecretBallot is a vote cast in a secret poll. It holds the options chosen but not the voter,
+// and is identified by the hash of the receipt code given to the voter, with which the voter can
+// check that the ballot is counted. A ballot carrying several votes, those of a proxy holder at a
+// general assembly, is recorded as as many secret ballots, the others identified by random
+// hashes, so that its weight does not single it out.
+type SecretBallot struct {
+	PollID      uint   `json:"poll_id"`
+	ReceiptHash string `json:"-"`
+	Choices     []uint `json:"choices"` // The options chosen, in order of preference for a ranked poll.
+}
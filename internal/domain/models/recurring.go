@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Frequency defines how often a recurring transaction falls due.
+type Frequency string
+
+// Constants defining the possible frequencies of a recurring transaction.
+const (
+	FrequencyWeekly    Frequency = "Hebdomadaire"  // Every week.
+	FrequencyMonthly   Frequency = "Mensuelle"     // Every month.
+	FrequencyQuarterly Frequency = "Trimestrielle" // Every three months.
+	FrequencyYearly    Frequency = "Annuelle"      // Every year.
+)
+
+// Frequencies lists the frequencies offered in the recurring transaction form.
+var Frequencies = []Frequency{FrequencyWeekly, FrequencyMonthly, FrequencyQuarterly, FrequencyYearly}
+
+// Occurrence returns the due date of the n-th occurrence (starting at 0) of a schedule starting
+// on start. Dates are computed from the start date rather than from the previous occurrence, and
+// the day is clamped to the end of shorter months, so that a schedule starting on January 31st
+// falls due on February 28th, then March 31st.
+func (f Frequency) Occurrence(start time.Time, n int) time.Time {
+	months := 0
+	switch f {
+	case FrequencyWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case FrequencyMonthly:
+		months = n
+	case FrequencyQuarterly:
+		months = 3 * n
+	case FrequencyYearly:
+		months = 12 * n
+	}
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(months), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	return firstOfMonth.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
+
+// RecurringTransaction represents a template from which a transaction is recorded on every due date
+// (rent, insurance, subscriptions...).
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type RecurringTransaction struct {
+	gorm.Model
+	UserID       uint            `json:"user_id"`                                               // The ID of the application user who owns the template.
+	Amount       Money           `json:"amount" form:"-"`                                       // The exact amount of every occurrence, in its own currency.
+	ExchangeRate string          `json:"exchange_rate" form:"exchange_rate"`                    // The rate to the base currency used for foreign-currency occurrences.
+	Type         TransactionType `json:"type" form:"type"`                                      // Income or expense.
+	AccountCode  string          `json:"account_code" form:"account_code"`                      // The income or expense account (category) occurrences are booked against.
+	Description  string          `json:"description" form:"description"`                        // The description copied to every occurrence.
+	Frequency    Frequency       `json:"frequency" form:"frequency"`                            // How often the transaction falls due.
+	StartDate    time.Time       `json:"start_date" form:"start_date" time_format:"2006-01-02"` // The due date of the first occurrence.
+	EndDate      *time.Time      `json:"end_date,omitempty" form:"-"`                           // The last date an occurrence may fall due on, if any.
+	Active       bool            `json:"active" form:"active"`                                  // Paused templates are neither recorded nor forecast.
+
+	// Occurrences is the number of occurrences already recorded; NextDueDate is the due date of the
+	// next one. Both are maintained by the scheduler.
+	Occurrences int       `json:"occurrences" form:"-"`
+	NextDueDate time.Time `json:"next_due_date" form:"-"`
+}
+
+// Finished reports whether every occurrence of the template has been recorded.
+func (r *RecurringTransaction) Finished() bool {
+	return r.EndDate != nil && r.NextDueDate.After(*r.EndDate)
+}
+
+// UpcomingOccurrence is a future occurrence of a recurring transaction, as shown in the preview
+// of upcoming obligations.
+type UpcomingOccurrence struct {
+	RecurringID uint
+	Date        time.Time
+	Description string
+	Type        TransactionType
+	Amount      Money
+	BaseAmount  int64 // The amount converted to the base currency, in minor units.
+}
+
+// ForecastMonth is a month of the cash-flow forecast, in minor units of the base currency.
+type ForecastMonth struct {
+	Month    time.Time // The first day of the month.
+	Income   int64     // The recurring incomes falling due during the month.
+	Expenses int64     // The recurring expenses falling due during the month.
+	Balance  int64     // The projected balance at the end of the month.
+}
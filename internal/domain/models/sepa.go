@@ -0,0 +1,126 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SEPACurrency is the only currency SEPA direct debits can be collected in.
+const SEPACurrency = "EUR"
+
+// SEPASequenceType defines the position of a direct debit in the series collected under a mandate.
+type SEPASequenceType string
+
+// Constants defining the sequence types of SEPA direct debits.
+const (
+	SequenceFirst     SEPASequenceType = "FRST" // First collection under a mandate.
+	SequenceRecurrent SEPASequenceType = "RCUR" // Subsequent collection under a mandate.
+)
+
+// SEPAMandate is the authorization given by a member to debit their account for the dues of the
+// association. It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type SEPAMandate struct {
+	gorm.Model
+	UserID        uint      `json:"user_id"`                                                       // The owner of the association collecting the dues.
+	MemberID      uint      `json:"member_id" form:"member_id"`                                    // The member whose account is debited.
+	Reference     string    `json:"reference" form:"reference"`                                    // The unique mandate reference (RUM), quoted in every debit.
+	IBAN          string    `json:"iban" form:"iban"`                                              // The account of the member.
+	BIC           string    `json:"bic" form:"bic"`                                                // The bank of the member; optional within the SEPA area.
+	SignatureDate time.Time `json:"signature_date" form:"signature_date" time_format:"2006-01-02"` // The date the member signed the mandate.
+
+	// LastCollectionDate is the date of the last debit sent under the mandate; the next debit is a
+	// first collection as long as it is nil.
+	LastCollectionDate *time.Time `json:"last_collection_date,omitempty" form:"-"`
+	RevokedAt          *time.Time `json:"revoked_at,omitempty" form:"-"` // Set when the member or the association ends the mandate.
+}
+
+// Active reports whether debits can still be collected under the mandate.
+func (m *SEPAMandate) Active() bool {
+	return m.RevokedAt == nil
+}
+
+// SequenceType returns the sequence type of the next debit collected under the mandate.
+func (m *SEPAMandate) SequenceType() SEPASequenceType {
+	if m.LastCollectionDate == nil {
+		return SequenceFirst
+	}
+	return SequenceRecurrent
+}
+
+// MaskedIBAN returns the IBAN with all but its country, check digits and last four characters hidden.
+func (m *SEPAMandate) MaskedIBAN() string {
+	if len(m.IBAN) <= 8 {
+		return m.IBAN
+	}
+	masked := []byte(m.IBAN)
+	for i := 4; i < len(masked)-4; i++ {
+		masked[i] = '*'
+	}
+	return string(masked)
+}
+
+// DirectDebitBatchStatus defines the lifecycle state of a direct debit batch.
+type DirectDebitBatchStatus string
+
+// Constants defining the possible states of a direct debit batch.
+const (
+	DebitBatchGenerated DirectDebitBatchStatus = "Générée"   // The file is ready to be uploaded; the debits are pending.
+	DebitBatchSettled   DirectDebitBatchStatus = "Encaissée" // The bank collected the debits; the incomes are posted.
+)
+
+// DirectDebitItemStatus defines the state of a single debit of a batch.
+type DirectDebitItemStatus string
+
+// Constants defining the possible states of a debit.
+const (
+	DebitItemPending  DirectDebitItemStatus = "En attente" // Sent to the bank, awaiting collection.
+	DebitItemSettled  DirectDebitItemStatus = "Encaissé"   // Collected; the income is posted and the member's dues are paid.
+	DebitItemRejected DirectDebitItemStatus = "Rejeté"     // Returned by the bank (insufficient funds, closed account...); the income is cancelled.
+)
+
+// DirectDebitBatch is a pain.008 file collecting the dues of members by SEPA direct debit.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DirectDebitBatch struct {
+	gorm.Model
+	UserID         uint                   `json:"user_id"`
+	MessageID      string                 `json:"message_id"`      // The unique identifier of the file for the bank.
+	Description    string                 `json:"description"`     // The remittance information shown on the members' statements.
+	CollectionDate time.Time              `json:"collection_date"` // The date the accounts are debited.
+	Status         DirectDebitBatchStatus `json:"status"`
+	SettledAt      *time.Time             `json:"settled_at,omitempty"`
+	XML            []byte                 `json:"-"` // The generated file, kept so that it can be downloaded again unchanged.
+	Items          []DirectDebitItem      `json:"items"`
+}
+
+// Total returns the amount of the debits of the batch that have not been rejected.
+func (b *DirectDebitBatch) Total() Money {
+	total := Money{Currency: SEPACurrency}
+	for _, item := range b.Items {
+		if item.Status != DebitItemRejected {
+			total.Amount += item.Amount.Amount
+		}
+	}
+	return total
+}
+
+// DirectDebitItem is the debit of a member's account within a batch.
+// It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type DirectDebitItem struct {
+	gorm.Model
+	BatchID       uint                  `json:"batch_id"`
+	MandateID     uint                  `json:"mandate_id"`
+	MemberID      uint                  `json:"member_id"`
+	EndToEndID    string                `json:"end_to_end_id"` // The identifier of the debit, returned by the bank with any rejection.
+	SequenceType  SEPASequenceType      `json:"sequence_type"`
+	Amount        Money                 `json:"amount"`
+	TransactionID *uint                 `json:"transaction_id,omitempty"` // The pending income recorded for the debit.
+	Status        DirectDebitItemStatus `json:"status"`
+	RejectReason  string                `json:"reject_reason,omitempty"`
+}
+
+// DirectDebitCandidate is a member whose dues can be collected by direct debit, with their mandate.
+type DirectDebitCandidate struct {
+	Member  Member
+	Mandate SEPAMandate
+}
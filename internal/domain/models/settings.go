@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AssociationSettings holds the configuration of an association, owned by the application user
+// who manages it. A default value is used for every setting until the association saves its own.
+type AssociationSettings struct {
+	gorm.Model
+	UserID       uint   `json:"user_id"`                            // The ID of the application user who owns these settings.
+	BaseCurrency string `json:"base_currency" form:"base_currency"` // The ISO 4217 currency the accounts and reports are kept in.
+
+	// ReceiptThreshold is the amount, in minor units of the base currency, above which a posted
+	// expense must have a receipt attached. Zero means every expense needs one.
+	ReceiptThreshold int64 `json:"receipt_threshold" form:"-"`
+
+	// When ApprovalEnabled is set, expenses entered above ApprovalThreshold (in minor units of the
+	// base currency) are submitted for approval and only count once approved. The treasurer
+	// reviewing them is the application user with TreasurerEmail, or the owner when it is empty.
+	ApprovalEnabled   bool   `json:"approval_enabled" form:"-"`
+	ApprovalThreshold int64  `json:"approval_threshold" form:"-"`
+	TreasurerEmail    string `json:"treasurer_email" form:"treasurer_email"`
+
+	// FiscalYearStartMonth is the month (1 to 12) the fiscal year of the association starts in.
+	// Invoice and receipt numbering restarts at every fiscal year.
+	FiscalYearStartMonth int `json:"fiscal_year_start_month" form:"fiscal_year_start_month"`
+
+	// Legal identity of the association, printed on invoices and donation receipts.
+	LegalName           string `json:"legal_name" form:"legal_name"`                     // The registered name of the association.
+	Address             string `json:"address" form:"address"`                           // The postal address of the registered office.
+	Purpose             string `json:"purpose" form:"purpose"`                           // The purpose (objet) of the association, as stated in its bylaws.
+	RegistrationNumber  string `json:"registration_number" form:"registration_number"`   // The RNA or SIREN number.
+	DonationEligibility string `json:"donation_eligibility" form:"donation_eligibility"` // The category of general-interest organization entitling donors to a tax reduction.
+	SignatoryName       string `json:"signatory_name" form:"signatory_name"`             // The name and role of the person signing the receipts.
+
+	// SEPA creditor details of the association, used to collect dues by direct debit.
+	SEPACreditorID string `json:"sepa_creditor_id" form:"sepa_creditor_id"` // The SEPA creditor identifier (ICS) granted by the bank.
+	SEPAIBAN       string `json:"sepa_iban" form:"sepa_iban"`               // The account the dues are collected on.
+	SEPABIC        string `json:"sepa_bic" form:"sepa_bic"`                 // The bank holding that account.
+}
+
+// RequiresApproval reports whether an expense of the given amount, in minor units of the base
+// currency, must be approved by the treasurer before it counts.
+func (s *AssociationSettings) RequiresApproval(baseAmount int64) bool {
+	return s.ApprovalEnabled && baseAmount > s.ApprovalThreshold
+}
+
+// FiscalYear returns the fiscal year a date belongs to, identified by the calendar year it starts in.
+func (s *AssociationSettings) FiscalYear(date time.Time) int {
+	if s.FiscalYearStartMonth > 1 && int(date.Month()) < s.FiscalYearStartMonth {
+		return date.Year() - 1
+	}
+	return date.Year()
+}
+
+// FiscalYearBounds returns the first day of a fiscal year and the first day of the next one.
+func (s *AssociationSettings) FiscalYearBounds(year int) (time.Time, time.Time) {
+	month := time.Month(max(s.FiscalYearStartMonth, 1))
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+// FiscalYearLabel returns the display label of a fiscal year ("2026", or "2025-2026" when the
+// fiscal year does not follow the calendar year).
+func (s *AssociationSettings) FiscalYearLabel(year int) string {
+	if s.FiscalYearStartMonth > 1 {
+		return fmt.Sprintf("%d-%d", year, year+1)
+	}
+	return fmt.Sprintf("%d", year)
+}
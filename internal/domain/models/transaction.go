@@ -15,16 +15,52 @@ const (
 	TypeExpense TransactionType = "Dépense" // Represents an expense transaction.
 )
 
+// TransactionStatus defines the lifecycle state of a financial transaction.
+type TransactionStatus string
+
+// Constants defining the possible transaction statuses.
+const (
+	TransactionDraft  TransactionStatus = "Brouillon" // Imported from a bank statement and awaiting reconciliation; not counted in totals.
+	TransactionPosted TransactionStatus = "Validée"   // Confirmed transaction, counted in totals.
+	TransactionSubmitted TransactionStatus = "Soumise" // Expense above the approval threshold awaiting the treasurer; not counted in totals.
+	TransactionRejected  TransactionStatus = "Rejetée" // Expense refused by the treasurer; not counted in totals until resubmitted.
+	TransactionPending   TransactionStatus = "En attente" // Direct debit sent to the bank and awaiting collection; not counted in totals.
+)
+
 // Transaction represents a financial transaction (either an income or an expense).
 // It embeds gorm.Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
 type Transaction struct {
 	gorm.Model
-	Amount      float64         `json:"amount" form:"amount"`         // The monetary amount of the transaction.
+	Amount      Money           `json:"amount" form:"-"`              // The exact amount of the transaction in its own currency.
 	Type        TransactionType `json:"type" form:"type"`             // The type of transaction (Income or Expense).
 	Description string          `json:"description" form:"description"` // A brief description of the transaction.
 	Date        time.Time       `json:"date" form:"date" time_format:"2006-01-02"` // The date when the transaction occurred.
+	AccountCode string          `json:"account_code" form:"account_code"` // The income or expense account (category) the transaction is booked against.
+
+	// ExchangeRate is the number of base-currency units for one unit of the transaction currency,
+	// stored as an exact decimal string ("1" when the transaction is in the base currency).
+	// BaseAmount is the amount converted to the base currency of the association, in minor units;
+	// it is what totals and journal entries are computed from.
+	ExchangeRate string `json:"exchange_rate" form:"exchange_rate"`
+	BaseAmount   int64  `json:"base_amount" form:"-"`
 
 	// UserID is the ID of the application user who recorded this transaction.
 	// This establishes a relationship between the transaction and its owner.
 	UserID uint `json:"user_id"`
+
+	Status        TransactionStatus `json:"status" form:"-"`                   // The lifecycle state of the transaction (draft or posted).
+	BankReference string            `json:"bank_reference,omitempty" form:"-"` // The bank's unique reference for the movement (e.g., OFX FITID), used to skip duplicate imports.
+	Reconciled    bool              `json:"reconciled" form:"-"`               // True once the transaction has been matched against a bank statement line.
+
+	// MatchedTransactionID is set on draft transactions when the import found an existing
+	// posted transaction that likely corresponds to the same bank movement.
+	MatchedTransactionID *uint `json:"matched_transaction_id,omitempty" form:"-"`
+
+	// MemberID identifies the member an income comes from (donor, customer) or the member an expense
+	// claim is reimbursed to, if any.
+	// It is used to address invoices and donation receipts.
+	MemberID *uint `json:"member_id,omitempty" form:"-"`
+
+	// RecurringID identifies the recurring transaction this transaction was recorded from, if any.
+	RecurringID *uint `json:"recurring_id,omitempty" form:"-"`
 }
@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// TransactionApprovalDB represents the database model for an approval decision, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type TransactionApprovalDB struct {
+	gorm.Model
+	TransactionID uint                    `gorm:"index"` // The expense the decision applies to.
+	UserID        uint                    `gorm:"index"` // Owner of the association the expense belongs to.
+	ActorID       uint                    // The user who took the decision.
+	ActorName     string                  // Name of the user at the time of the decision.
+	Decision      models.ApprovalDecision // Approved or rejected.
+	Comment       string                  // Justification given by the treasurer.
+	DecidedAt     time.Time               // When the decision was taken.
+	Description   string                  // Description of the expense when it was reviewed.
+	AmountMinor   int64                   // Amount of the expense in minor units of Currency.
+	Currency      string                  `gorm:"size:3"` // ISO 4217 currency of the amount.
+}
+
+// TableName specifies the table name for the TransactionApprovalDB model.
+func (TransactionApprovalDB) TableName() string {
+	return "transaction_approvals"
+}
+
+// ApprovalRepository defines the interface for approval decision persistence operations.
+// It abstracts the underlying database implementation.
+type ApprovalRepository interface {
+	CreateApproval(approval *models.TransactionApproval) error
+	FindApprovalsByUserID(userID uint) ([]models.TransactionApproval, error)
+}
+
+// GormApprovalRepository is an implementation of ApprovalRepository that uses GORM
+// for interacting with a relational database.
+type GormApprovalRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormApprovalRepository creates a new instance of GormApprovalRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormApprovalRepository(db *gorm.DB) *GormApprovalRepository {
+	return &GormApprovalRepository{db: db}
+}
+
+// CreateApproval persists a new approval decision.
+func (r *GormApprovalRepository) CreateApproval(approval *models.TransactionApproval) error {
+	approvalDB := toTransactionApprovalDB(approval)
+	if err := r.db.Create(approvalDB).Error; err != nil {
+		return err
+	}
+	*approval = *toTransactionApproval(approvalDB) // Update the original decision with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindApprovalsByUserID retrieves the decisions taken on the expenses of an association, most recent first.
+func (r *GormApprovalRepository) FindApprovalsByUserID(userID uint) ([]models.TransactionApproval, error) {
+	var approvalsDB []TransactionApprovalDB
+	if err := r.db.Where("user_id = ?", userID).Order("decided_at desc, id desc").Find(&approvalsDB).Error; err != nil {
+		return nil, err
+	}
+	approvals := make([]models.TransactionApproval, len(approvalsDB))
+	for i, approvalDB := range approvalsDB {
+		approvals[i] = *toTransactionApproval(&approvalDB)
+	}
+	return approvals, nil
+}
+
+// toTransactionApprovalDB converts a models.TransactionApproval to a TransactionApprovalDB.
+func toTransactionApprovalDB(a *models.TransactionApproval) *TransactionApprovalDB {
+	return &TransactionApprovalDB{
+		Model:         a.Model,
+		TransactionID: a.TransactionID,
+		UserID:        a.UserID,
+		ActorID:       a.ActorID,
+		ActorName:     a.ActorName,
+		Decision:      a.Decision,
+		Comment:       a.Comment,
+		DecidedAt:     a.DecidedAt,
+		Description:   a.Description,
+		AmountMinor:   a.Amount.Amount,
+		Currency:      a.Amount.Currency,
+	}
+}
+
+// toTransactionApproval converts a TransactionApprovalDB to a models.TransactionApproval.
+func toTransactionApproval(adb *TransactionApprovalDB) *models.TransactionApproval {
+	return &models.TransactionApproval{
+		Model:         adb.Model,
+		TransactionID: adb.TransactionID,
+		UserID:        adb.UserID,
+		ActorID:       adb.ActorID,
+		ActorName:     adb.ActorName,
+		Decision:      adb.Decision,
+		Comment:       adb.Comment,
+		DecidedAt:     adb.DecidedAt,
+		Description:   adb.Description,
+		Amount:        models.Money{Amount: adb.AmountMinor, Currency: adb.Currency},
+	}
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// AssemblyDB represents the database model for a general assembly, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type AssemblyDB struct {
+	gorm.Model
+	UserID        uint                   `gorm:"index"` // Owner of the association.
+	Title         string                 // Title of the assembly.
+	Date          time.Time              // When the assembly is held.
+	Location      string                 // Where the assembly is held.
+	QuorumPercent int                    // Share of the active members who must be present or represented.
+	MaxProxies    int                    // Most proxies a member may hold.
+	Status        string                 // Convened, in session or closed.
+	OpenedAt      *time.Time             // When the session was opened.
+	ClosedAt      *time.Time             // When the session was closed.
+	Attendances   []AssemblyAttendanceDB `gorm:"foreignKey:AssemblyID"` // Attendance sheet.
+}
+
+// AssemblyAttendanceDB represents the database model for the attendance of a member at a general
+// assembly. A member has at most one attendance per assembly.
+type AssemblyAttendanceDB struct {
+	ID            uint   `gorm:"primarykey"`
+	AssemblyID    uint   `gorm:"uniqueIndex:idx_assembly_attendance"`
+	MemberID      uint   `gorm:"uniqueIndex:idx_assembly_attendance"`
+	Status        string // Present or represented.
+	ProxyHolderID *uint  // Member holding the proxy of a represented member.
+	Weight        int    // Votes of the member.
+}
+
+// TableName specifies the table name for the AssemblyDB model.
+func (AssemblyDB) TableName() string {
+	return "assemblies"
+}
+
+// TableName specifies the table name for the AssemblyAttendanceDB model.
+func (AssemblyAttendanceDB) TableName() string {
+	return "assembly_attendances"
+}
+
+// AssemblyRepository defines the interface for general assembly persistence operations.
+// It abstracts the underlying database implementation.
+type AssemblyRepository interface {
+	CreateAssembly(assembly *models.Assembly) error
+	FindAssemblyByID(id uint) (*models.Assembly, error)
+	FindAssembliesByUserID(userID uint) ([]models.Assembly, error)
+	UpdateAssembly(assembly *models.Assembly) error
+	ReplaceAttendances(assemblyID uint, attendances []models.AssemblyAttendance) error
+	DeleteAssembly(id uint) error
+}
+
+// GormAssemblyRepository is an implementation of AssemblyRepository that uses GORM
+// for interacting with a relational database.
+type GormAssemblyRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormAssemblyRepository creates a new instance of GormAssemblyRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormAssemblyRepository(db *gorm.DB) *GormAssemblyRepository {
+	return &GormAssemblyRepository{db: db}
+}
+
+// CreateAssembly persists a new general assembly.
+func (r *GormAssemblyRepository) CreateAssembly(assembly *models.Assembly) error {
+	assemblyDB := toAssemblyDB(assembly)
+	if err := r.db.Omit("Attendances").Create(assemblyDB).Error; err != nil {
+		return err
+	}
+	assembly.Model = assemblyDB.Model // Update the assembly with DB-generated fields.
+	return nil
+}
+
+// FindAssemblyByID retrieves a general assembly by its ID, eagerly loading its attendance sheet.
+func (r *GormAssemblyRepository) FindAssemblyByID(id uint) (*models.Assembly, error) {
+	var assemblyDB AssemblyDB
+	if err := r.db.Preload("Attendances").First(&assemblyDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toAssembly(&assemblyDB), nil
+}
+
+// FindAssembliesByUserID retrieves the general assemblies of an association, the latest first.
+func (r *GormAssemblyRepository) FindAssembliesByUserID(userID uint) ([]models.Assembly, error) {
+	var assembliesDB []AssemblyDB
+	if err := r.db.Where("user_id = ?", userID).Order("date DESC").Find(&assembliesDB).Error; err != nil {
+		return nil, err
+	}
+	var assemblies []models.Assembly
+	for _, adb := range assembliesDB {
+		assemblies = append(assemblies, *toAssembly(&adb))
+	}
+	return assemblies, nil
+}
+
+// UpdateAssembly updates an existing general assembly, but not its attendance sheet.
+func (r *GormAssemblyRepository) UpdateAssembly(assembly *models.Assembly) error {
+	return r.db.Omit("Attendances").Save(toAssemblyDB(assembly)).Error
+}
+
+// ReplaceAttendances replaces the attendance sheet of a general assembly, in a transaction.
+func (r *GormAssemblyRepository) ReplaceAttendances(assemblyID uint, attendances []models.AssemblyAttendance) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assembly_id = ?", assemblyID).Delete(&AssemblyAttendanceDB{}).Error; err != nil {
+			return err
+		}
+		for _, attendance := range attendances {
+			attendanceDB := toAssemblyAttendanceDB(&attendance)
+			attendanceDB.ID = 0
+			attendanceDB.AssemblyID = assemblyID
+			if err := tx.Create(attendanceDB).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteAssembly deletes a general assembly by its ID, with its attendance sheet.
+func (r *GormAssemblyRepository) DeleteAssembly(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assembly_id = ?", id).Delete(&AssemblyAttendanceDB{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&AssemblyDB{}, id).Error
+	})
+}
+
+// toAssemblyDB converts a models.Assembly to an AssemblyDB.
+func toAssemblyDB(a *models.Assembly) *AssemblyDB {
+	assemblyDB := &AssemblyDB{
+		Model:         a.Model,
+		UserID:        a.UserID,
+		Title:         a.Title,
+		Date:          a.Date,
+		Location:      a.Location,
+		QuorumPercent: a.QuorumPercent,
+		MaxProxies:    a.MaxProxies,
+		Status:        string(a.Status),
+		OpenedAt:      a.OpenedAt,
+		ClosedAt:      a.ClosedAt,
+	}
+	for _, attendance := range a.Attendances {
+		assemblyDB.Attendances = append(assemblyDB.Attendances, *toAssemblyAttendanceDB(&attendance))
+	}
+	return assemblyDB
+}
+
+// toAssembly converts an AssemblyDB to a models.Assembly.
+func toAssembly(adb *AssemblyDB) *models.Assembly {
+	assembly := &models.Assembly{
+		Model:         adb.Model,
+		UserID:        adb.UserID,
+		Title:         adb.Title,
+		Date:          adb.Date,
+		Location:      adb.Location,
+		QuorumPercent: adb.QuorumPercent,
+		MaxProxies:    adb.MaxProxies,
+		Status:        models.AssemblyStatus(adb.Status),
+		OpenedAt:      adb.OpenedAt,
+		ClosedAt:      adb.ClosedAt,
+	}
+	for _, attendanceDB := range adb.Attendances {
+		assembly.Attendances = append(assembly.Attendances, *toAssemblyAttendance(&attendanceDB))
+	}
+	return assembly
+}
+
+// toAssemblyAttendanceDB converts a models.AssemblyAttendance to an AssemblyAttendanceDB.
+func toAssemblyAttendanceDB(a *models.AssemblyAttendance) *AssemblyAttendanceDB {
+	return &AssemblyAttendanceDB{
+		ID:            a.ID,
+		AssemblyID:    a.AssemblyID,
+		MemberID:      a.MemberID,
+		Status:        string(a.Status),
+		ProxyHolderID: a.ProxyHolderID,
+		Weight:        a.Weight,
+	}
+}
+
+// toAssemblyAttendance converts an AssemblyAttendanceDB to a models.AssemblyAttendance.
+func toAssemblyAttendance(adb *AssemblyAttendanceDB) *models.AssemblyAttendance {
+	return &models.AssemblyAttendance{
+		ID:            adb.ID,
+		AssemblyID:    adb.AssemblyID,
+		MemberID:      adb.MemberID,
+		Status:        models.AttendanceStatus(adb.Status),
+		ProxyHolderID: adb.ProxyHolderID,
+		Weight:        adb.Weight,
+	}
+}
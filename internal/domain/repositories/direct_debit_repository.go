@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// SEPAMandateDB represents the database model for a SEPA direct debit mandate, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type SEPAMandateDB struct {
+	gorm.Model
+	UserID             uint   `gorm:"index;uniqueIndex:idx_sepa_mandate_reference"` // Owner of the association collecting the dues.
+	MemberID           uint   `gorm:"index"`                                        // Member whose account is debited.
+	Reference          string `gorm:"uniqueIndex:idx_sepa_mandate_reference"`       // Unique mandate reference (RUM).
+	IBAN               string // Account of the member.
+	BIC                string // Bank of the member, if known.
+	SignatureDate      time.Time
+	LastCollectionDate *time.Time // Date of the last debit sent under the mandate.
+	RevokedAt          *time.Time // When the mandate was ended.
+}
+
+// TableName specifies the table name for the SEPAMandateDB model.
+func (SEPAMandateDB) TableName() string {
+	return "sepa_mandates"
+}
+
+// DirectDebitBatchDB represents the database model for a direct debit batch.
+type DirectDebitBatchDB struct {
+	gorm.Model
+	UserID         uint                          `gorm:"index"`
+	MessageID      string                        `gorm:"uniqueIndex"` // Identifier of the pain.008 file.
+	Description    string                        // Remittance information.
+	CollectionDate time.Time                     // Date the accounts are debited.
+	Status         models.DirectDebitBatchStatus // Generated or settled.
+	SettledAt      *time.Time
+	XML            []byte              // Generated pain.008 file.
+	Items          []DirectDebitItemDB `gorm:"foreignKey:BatchID"`
+}
+
+// DirectDebitItemDB represents the database model for a debit of a batch.
+type DirectDebitItemDB struct {
+	gorm.Model
+	BatchID       uint `gorm:"index"`
+	MandateID     uint `gorm:"index"`
+	MemberID      uint `gorm:"index"`
+	EndToEndID    string
+	SequenceType  models.SEPASequenceType
+	AmountMinor   int64  // Amount in minor units of Currency.
+	Currency      string `gorm:"size:3"`
+	TransactionID *uint  // Pending income recorded for the debit.
+	Status        models.DirectDebitItemStatus
+	RejectReason  string
+}
+
+// TableName specifies the table name for the DirectDebitBatchDB model.
+func (DirectDebitBatchDB) TableName() string {
+	return "direct_debit_batches"
+}
+
+// TableName specifies the table name for the DirectDebitItemDB model.
+func (DirectDebitItemDB) TableName() string {
+	return "direct_debit_items"
+}
+
+// DirectDebitRepository defines the interface for SEPA mandate and direct debit batch persistence operations.
+// It abstracts the underlying database implementation.
+type DirectDebitRepository interface {
+	CreateMandate(mandate *models.SEPAMandate) error
+	FindMandateByID(id uint) (*models.SEPAMandate, error)
+	FindMandatesByUserID(userID uint) ([]models.SEPAMandate, error)
+	UpdateMandate(mandate *models.SEPAMandate) error
+	CreateBatch(batch *models.DirectDebitBatch, mandates []models.SEPAMandate) error
+	FindBatchByID(id uint) (*models.DirectDebitBatch, error)
+	FindBatchesByUserID(userID uint) ([]models.DirectDebitBatch, error)
+	UpdateBatch(batch *models.DirectDebitBatch) error
+}
+
+// GormDirectDebitRepository is an implementation of DirectDebitRepository that uses GORM
+// for interacting with a relational database.
+type GormDirectDebitRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormDirectDebitRepository creates a new instance of GormDirectDebitRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormDirectDebitRepository(db *gorm.DB) *GormDirectDebitRepository {
+	return &GormDirectDebitRepository{db: db}
+}
+
+// CreateMandate persists a new SEPA mandate.
+func (r *GormDirectDebitRepository) CreateMandate(mandate *models.SEPAMandate) error {
+	mandateDB := toSEPAMandateDB(mandate)
+	if err := r.db.Create(mandateDB).Error; err != nil {
+		return err
+	}
+	*mandate = *toSEPAMandate(mandateDB) // Update the original mandate with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindMandateByID retrieves a SEPA mandate by its ID.
+func (r *GormDirectDebitRepository) FindMandateByID(id uint) (*models.SEPAMandate, error) {
+	var mandateDB SEPAMandateDB
+	if err := r.db.First(&mandateDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toSEPAMandate(&mandateDB), nil
+}
+
+// FindMandatesByUserID retrieves the SEPA mandates of an association, most recently signed first.
+func (r *GormDirectDebitRepository) FindMandatesByUserID(userID uint) ([]models.SEPAMandate, error) {
+	var mandatesDB []SEPAMandateDB
+	if err := r.db.Where("user_id = ?", userID).Order("signature_date DESC, id DESC").Find(&mandatesDB).Error; err != nil {
+		return nil, err
+	}
+	mandates := make([]models.SEPAMandate, len(mandatesDB))
+	for i, mandateDB := range mandatesDB {
+		mandates[i] = *toSEPAMandate(&mandateDB)
+	}
+	return mandates, nil
+}
+
+// UpdateMandate saves the changes made to a SEPA mandate.
+func (r *GormDirectDebitRepository) UpdateMandate(mandate *models.SEPAMandate) error {
+	return r.db.Save(toSEPAMandateDB(mandate)).Error
+}
+
+// CreateBatch persists a new direct debit batch with its items and saves the mandates it collects
+// under, whose sequence advances, in a single database transaction.
+func (r *GormDirectDebitRepository) CreateBatch(batch *models.DirectDebitBatch, mandates []models.SEPAMandate) error {
+	batchDB := toDirectDebitBatchDB(batch)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batchDB).Error; err != nil {
+			return err
+		}
+		for i := range mandates {
+			if err := tx.Save(toSEPAMandateDB(&mandates[i])).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*batch = *toDirectDebitBatch(batchDB) // Update the original batch with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindBatchByID retrieves a direct debit batch and its items by its ID.
+func (r *GormDirectDebitRepository) FindBatchByID(id uint) (*models.DirectDebitBatch, error) {
+	var batchDB DirectDebitBatchDB
+	if err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&batchDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toDirectDebitBatch(&batchDB), nil
+}
+
+// FindBatchesByUserID retrieves the direct debit batches of an association with their items,
+// most recent first. The generated files are not loaded.
+func (r *GormDirectDebitRepository) FindBatchesByUserID(userID uint) ([]models.DirectDebitBatch, error) {
+	var batchesDB []DirectDebitBatchDB
+	if err := r.db.Omit("XML").Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&batchesDB).Error; err != nil {
+		return nil, err
+	}
+	batches := make([]models.DirectDebitBatch, len(batchesDB))
+	for i, batchDB := range batchesDB {
+		batches[i] = *toDirectDebitBatch(&batchDB)
+	}
+	return batches, nil
+}
+
+// UpdateBatch saves the status of a batch and of its items in a single database transaction.
+// The generated file is left unchanged.
+func (r *GormDirectDebitRepository) UpdateBatch(batch *models.DirectDebitBatch) error {
+	batchDB := toDirectDebitBatchDB(batch)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&DirectDebitBatchDB{}).Where("id = ?", batchDB.ID).Updates(map[string]any{"status": batchDB.Status, "settled_at": batchDB.SettledAt}).Error; err != nil {
+			return err
+		}
+		for _, item := range batchDB.Items {
+			if err := tx.Model(&DirectDebitItemDB{}).Where("id = ?", item.ID).Updates(map[string]any{"status": item.Status, "reject_reason": item.RejectReason, "transaction_id": item.TransactionID}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// toSEPAMandateDB converts a domain SEPAMandate model to a database-specific SEPAMandateDB model.
+func toSEPAMandateDB(m *models.SEPAMandate) *SEPAMandateDB {
+	return &SEPAMandateDB{
+		Model:              m.Model,
+		UserID:             m.UserID,
+		MemberID:           m.MemberID,
+		Reference:          m.Reference,
+		IBAN:               m.IBAN,
+		BIC:                m.BIC,
+		SignatureDate:      m.SignatureDate,
+		LastCollectionDate: m.LastCollectionDate,
+		RevokedAt:          m.RevokedAt,
+	}
+}
+
+// toSEPAMandate converts a database-specific SEPAMandateDB model back to a domain SEPAMandate model.
+func toSEPAMandate(mdb *SEPAMandateDB) *models.SEPAMandate {
+	return &models.SEPAMandate{
+		Model:              mdb.Model,
+		UserID:             mdb.UserID,
+		MemberID:           mdb.MemberID,
+		Reference:          mdb.Reference,
+		IBAN:               mdb.IBAN,
+		BIC:                mdb.BIC,
+		SignatureDate:      mdb.SignatureDate,
+		LastCollectionDate: mdb.LastCollectionDate,
+		RevokedAt:          mdb.RevokedAt,
+	}
+}
+
+// toDirectDebitBatchDB converts a domain DirectDebitBatch model to a database-specific DirectDebitBatchDB model.
+func toDirectDebitBatchDB(b *models.DirectDebitBatch) *DirectDebitBatchDB {
+	items := make([]DirectDebitItemDB, len(b.Items))
+	for n, item := range b.Items {
+		items[n] = DirectDebitItemDB{
+			Model:         item.Model,
+			BatchID:       item.BatchID,
+			MandateID:     item.MandateID,
+			MemberID:      item.MemberID,
+			EndToEndID:    item.EndToEndID,
+			SequenceType:  item.SequenceType,
+			AmountMinor:   item.Amount.Amount,
+			Currency:      item.Amount.Currency,
+			TransactionID: item.TransactionID,
+			Status:        item.Status,
+			RejectReason:  item.RejectReason,
+		}
+	}
+	return &DirectDebitBatchDB{
+		Model:          b.Model,
+		UserID:         b.UserID,
+		MessageID:      b.MessageID,
+		Description:    b.Description,
+		CollectionDate: b.CollectionDate,
+		Status:         b.Status,
+		SettledAt:      b.SettledAt,
+		XML:            b.XML,
+		Items:          items,
+	}
+}
+
+// toDirectDebitBatch converts a database-specific DirectDebitBatchDB model back to a domain DirectDebitBatch model.
+func toDirectDebitBatch(bdb *DirectDebitBatchDB) *models.DirectDebitBatch {
+	items := make([]models.DirectDebitItem, len(bdb.Items))
+	for n, item := range bdb.Items {
+		items[n] = models.DirectDebitItem{
+			Model:         item.Model,
+			BatchID:       item.BatchID,
+			MandateID:     item.MandateID,
+			MemberID:      item.MemberID,
+			EndToEndID:    item.EndToEndID,
+			SequenceType:  item.SequenceType,
+			Amount:        models.Money{Amount: item.AmountMinor, Currency: item.Currency},
+			TransactionID: item.TransactionID,
+			Status:        item.Status,
+			RejectReason:  item.RejectReason,
+		}
+	}
+	return &models.DirectDebitBatch{
+		Model:          bdb.Model,
+		UserID:         bdb.UserID,
+		MessageID:      bdb.MessageID,
+		Description:    bdb.Description,
+		CollectionDate: bdb.CollectionDate,
+		Status:         bdb.Status,
+		SettledAt:      bdb.SettledAt,
+		XML:            bdb.XML,
+		Items:          items,
+	}
+}
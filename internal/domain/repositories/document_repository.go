@@ -1,6 +1,10 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
@@ -12,11 +16,21 @@ import (
 type DocumentDB struct {
 	gorm.Model
 	Name       string    // Original name of the document file.
-	FilePath   string    // Path to the stored file on the server.
+	FilePath   string    // Storage key of the file in the document store.
 	FileSize   int64     // Size of the file in bytes.
 	MimeType   string    // MIME type of the file (e.g., "application/pdf").
 	UploadDate time.Time // Date and time when the document was uploaded.
 	UserID     uint      // Foreign key linking to the User who uploaded this document.
+
+	TransactionID *uint `gorm:"index"` // The transaction this document is attached to, if any.
+
+	FolderID       *uint  `gorm:"index"` // The folder containing the document; nil at the root of the library.
+	TextContent    string // Text extracted from the file, indexed for the search.
+	ContentIndexed bool   // Whether the text of the file has been extracted.
+	Version        int    // Number of the current version.
+	PreviewStatus  string `gorm:"index"` // State of the preview of the current file.
+	PreviewKey     string // Storage key of the thumbnail of the current file, if any.
+	Visibility     string `gorm:"index"` // Who may see the document besides its owner.
 }
 
 // TableName specifies the table name for the DocumentDB model in the database.
@@ -25,20 +39,241 @@ func (DocumentDB) TableName() string {
 	return "documents"
 }
 
+// DocumentVersionDB represents the database model for a version of a document.
+type DocumentVersionDB struct {
+	gorm.Model
+	DocumentID   uint `gorm:"uniqueIndex:idx_document_version"`
+	Number       int  `gorm:"uniqueIndex:idx_document_version"`
+	FileName     string
+	FilePath     string
+	FileSize     int64
+	MimeType     string
+	UploadDate   time.Time
+	UploadedByID uint
+	UploadedBy   string
+	Comment      string
+}
+
+// TableName specifies the table name for the DocumentVersionDB model in the database.
+func (DocumentVersionDB) TableName() string {
+	return "document_versions"
+}
+
+// DocumentFolderDB represents the database model for a folder of the document library.
+type DocumentFolderDB struct {
+	gorm.Model
+	UserID   uint  `gorm:"index"`
+	ParentID *uint `gorm:"index"` // The parent folder; nil for a folder at the root of the library.
+	Name     string
+}
+
+// TableName specifies the table name for the DocumentFolderDB model in the database.
+func (DocumentFolderDB) TableName() string {
+	return "document_folders"
+}
+
+// DocumentTagDB represents the database model for a tag given to a document.
+type DocumentTagDB struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"index"`
+	DocumentID uint   `gorm:"uniqueIndex:idx_document_tag"`
+	Name       string `gorm:"uniqueIndex:idx_document_tag;index"`
+}
+
+// TableName specifies the table name for the DocumentTagDB model in the database.
+func (DocumentTagDB) TableName() string {
+	return "document_tags"
+}
+
+// QuarantinedFileDB represents the database model for an uploaded file in which malware was found.
+type QuarantinedFileDB struct {
+	gorm.Model
+	UserID       uint `gorm:"index"`
+	FileName     string
+	StorageKey   string
+	FileSize     int64
+	MimeType     string
+	Signature    string
+	UploadedByID uint
+	UploadedBy   string
+	DetectedAt   time.Time
+}
+
+// TableName specifies the table name for the QuarantinedFileDB model in the database.
+func (QuarantinedFileDB) TableName() string {
+	return "quarantined_files"
+}
+
+// DocumentShareLinkDB represents the database model for a share link of a document.
+type DocumentShareLinkDB struct {
+	gorm.Model
+	UserID         uint `gorm:"index"`
+	DocumentID     uint `gorm:"index"`
+	Label          string
+	TokenHash      string `gorm:"uniqueIndex"`
+	PasswordHash   string
+	ExpiresAt      time.Time
+	MaxDownloads   int
+	DownloadCount  int
+	FailedAttempts int
+	RevokedAt      *time.Time
+	CreatedByID    uint
+	CreatedBy      string
+}
+
+// TableName specifies the table name for the DocumentShareLinkDB model in the database.
+func (DocumentShareLinkDB) TableName() string {
+	return "document_share_links"
+}
+
+// DocumentShareDownloadDB represents the database model for a download made through a share link.
+type DocumentShareDownloadDB struct {
+	ID           uint `gorm:"primaryKey"`
+	LinkID       uint `gorm:"index"`
+	DocumentID   uint `gorm:"index"`
+	Version      int
+	DownloadedAt time.Time
+	IPAddress    string
+	UserAgent    string
+}
+
+// TableName specifies the table name for the DocumentShareDownloadDB model in the database.
+func (DocumentShareDownloadDB) TableName() string {
+	return "document_share_downloads"
+}
+
+// DocumentSignatureRequestDB represents the database model for a request to sign a document.
+type DocumentSignatureRequestDB struct {
+	gorm.Model
+	UserID       uint `gorm:"index"`
+	DocumentID   uint `gorm:"index"`
+	DocumentName string
+	Version      int
+	FilePath     string
+	FileSize     int64
+	MimeType     string
+	DocumentHash string
+	Message      string
+	Status       string `gorm:"index"`
+	CreatedByID  uint
+	CreatedBy    string
+	CompletedAt  *time.Time
+	SignedKey    string
+	SignedHash   string
+}
+
+// TableName specifies the table name for the DocumentSignatureRequestDB model in the database.
+func (DocumentSignatureRequestDB) TableName() string {
+	return "document_signature_requests"
+}
+
+// DocumentSignerDB represents the database model for a signer of a signature request.
+type DocumentSignerDB struct {
+	ID               uint `gorm:"primaryKey"`
+	RequestID        uint `gorm:"index"`
+	MemberID         uint
+	Name             string
+	Email            string
+	TokenHash        string `gorm:"uniqueIndex"`
+	Status           string
+	EmailSentAt      *time.Time
+	ViewedAt         *time.Time
+	SignedAt         *time.Time
+	Method           string
+	SignatureText    string
+	SignatureStrokes string
+	DocumentHash     string
+	IPAddress        string
+	UserAgent        string
+	DeclineReason    string
+}
+
+// TableName specifies the table name for the DocumentSignerDB model in the database.
+func (DocumentSignerDB) TableName() string {
+	return "document_signers"
+}
+
+// DocumentSearchTable is the SQLite FTS5 table indexing the name and the text content of the
+// documents, kept up to date by triggers on the documents table.
+const DocumentSearchTable = "documents_fts"
+
+// ErrQuotaExceeded is returned when recording a file would exceed the storage quota of the
+// association.
+var ErrQuotaExceeded = errors.New("espace de stockage insuffisant")
+
+// documentSortColumns maps the sort orders of the document library to their column.
+var documentSortColumns = map[string]string{
+	models.DocumentSortDate: "upload_date",
+	models.DocumentSortName: "name COLLATE NOCASE",
+	models.DocumentSortSize: "file_size",
+	models.DocumentSortType: "mime_type",
+}
+
 // DocumentRepository defines the interface for document persistence operations.
 // It abstracts the underlying database implementation.
 type DocumentRepository interface {
-	CreateDocument(document *models.Document) error
+	CreateDocument(document *models.Document, version *models.DocumentVersion, quota int64) error
+	AddDocumentVersion(document *models.Document, version *models.DocumentVersion, quota int64) error
+	FindDocumentVersions(documentID uint) ([]models.DocumentVersion, error)
+	FindDocumentVersion(documentID uint, number int) (*models.DocumentVersion, error)
 	FindDocumentByID(id uint) (*models.Document, error)
 	FindDocumentsByUserID(userID uint) ([]models.Document, error)
+	UpdateDocument(document *models.Document) error
 	DeleteDocument(id uint) error
 	GetTotalDocumentsCount(userID uint) (int64, error)
+	FindDocumentsByTransactionID(transactionID uint) ([]models.Document, error)
+	CountDocumentsByTransaction(userID uint) (map[uint]int64, error)
+	DetachDocumentsFromTransaction(transactionID uint) error
+	FindDocuments(userID uint, query models.DocumentQuery) ([]models.Document, error)
+	FindDocumentsToIndex(limit int) ([]models.Document, error)
+	FindStorageKeys() ([]string, error)
+	FindStorageReferences() ([]models.StorageReference, error)
+	FindDeletedDocuments(userID uint) ([]models.Document, error)
+	FindDeletedDocumentByID(id uint) (*models.Document, error)
+	FindDocumentsDeletedBefore(before time.Time, limit int) ([]models.Document, error)
+	RestoreDocument(document *models.Document) error
+	PurgeDocument(id uint) ([]string, error)
+	IsDocumentAttached(id uint) (bool, error)
+	FindDocumentsPendingPreview(limit int) ([]models.Document, error)
+	UpdateDocumentPreview(document *models.Document, status models.DocumentPreviewStatus, key string) error
+	SetDocumentTags(document *models.Document, tags []string) error
+	FindTagsByUserID(userID uint) ([]string, error)
+	CountDocumentsInFolder(folderID uint) (int64, error)
+	CreateFolder(folder *models.DocumentFolder) error
+	FindFolderByID(id uint) (*models.DocumentFolder, error)
+	FindFoldersByUserID(userID uint) ([]models.DocumentFolder, error)
+	UpdateFolder(folder *models.DocumentFolder) error
+	DeleteFolder(id uint) error
+	GetStorageUsed(userID uint) (int64, error)
+	CreateQuarantinedFile(file *models.QuarantinedFile) error
+	FindQuarantinedFiles(userID uint) ([]models.QuarantinedFile, error)
+	FindQuarantinedFileByID(id uint) (*models.QuarantinedFile, error)
+	DeleteQuarantinedFile(id uint) error
+	CreateShareLink(link *models.DocumentShareLink) error
+	FindShareLinkByID(id uint) (*models.DocumentShareLink, error)
+	FindShareLinkByTokenHash(tokenHash string) (*models.DocumentShareLink, error)
+	FindShareLinksByDocumentID(documentID uint) ([]models.DocumentShareLink, error)
+	RevokeShareLink(id uint, revokedAt time.Time) error
+	RecordShareFailure(id uint) error
+	RecordShareDownload(link *models.DocumentShareLink, download *models.DocumentShareDownload) (bool, error)
+	FindShareDownloads(documentID uint) ([]models.DocumentShareDownload, error)
+	CreateSignatureRequest(request *models.DocumentSignatureRequest) error
+	FindSignatureRequestByID(id uint) (*models.DocumentSignatureRequest, error)
+	FindSignatureRequestsByDocumentID(documentID uint) ([]models.DocumentSignatureRequest, error)
+	FindSignerByTokenHash(tokenHash string) (*models.DocumentSigner, error)
+	UpdateSignerToken(signerID uint, tokenHash string, sentAt *time.Time) error
+	RecordSignerView(signerID uint, viewedAt time.Time) error
+	RecordSignature(signer *models.DocumentSigner) (bool, error)
+	RecordSignatureRefusal(signer *models.DocumentSigner) (bool, error)
+	CancelSignatureRequest(id uint, cancelledAt time.Time) (bool, error)
+	SetSignedFile(requestID uint, key, hash string) error
 }
 
 // GormDocumentRepository is an implementation of DocumentRepository that uses GORM
 // for interacting with a relational database.
 type GormDocumentRepository struct {
-	db *gorm.DB // GORM database client
+	db       *gorm.DB // GORM database client
+	fullText bool     // Whether the SQLite FTS5 search table is available.
 }
 
 // NewGormDocumentRepository creates a new instance of GormDocumentRepository.
@@ -47,18 +282,95 @@ func NewGormDocumentRepository(db *gorm.DB) *GormDocumentRepository {
 	return &GormDocumentRepository{db: db}
 }
 
-// CreateDocument persists a new document to the database.
+// EnableFullTextSearch makes the search use the FTS5 table created by database.InitDocumentSearch
+// instead of matching the words one by one.
+func (r *GormDocumentRepository) EnableFullTextSearch() {
+	r.fullText = true
+}
+
+// CreateDocument persists a new document to the database, with its first version.
 // It converts the domain model Document to a database-specific DocumentDB model
-// before saving and then updates the domain model with the generated ID.
-func (r *GormDocumentRepository) CreateDocument(document *models.Document) error {
+// before saving and then updates the domain model with the generated ID. It returns
+// ErrQuotaExceeded if the files of the association then exceed the quota, in bytes (0 for no
+// limit).
+func (r *GormDocumentRepository) CreateDocument(document *models.Document, version *models.DocumentVersion, quota int64) error {
+	document.Version = 1
 	documentDB := toDocumentDB(document)
-	if err := r.db.Create(&documentDB).Error; err != nil {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&documentDB).Error; err != nil {
+			return err
+		}
+		version.DocumentID = documentDB.ID
+		version.Number = 1
+		versionDB := toDocumentVersionDB(version)
+		if err := tx.Create(versionDB).Error; err != nil {
+			return err
+		}
+		*version = *toDocumentVersion(versionDB)
+		return checkStorageQuota(tx, document.UserID, quota)
+	})
+	if err != nil {
 		return err
 	}
+	tags := document.Tags
 	*document = *toDocument(documentDB) // Update the original document with DB-generated fields (e.g., ID)
+	if len(tags) > 0 {
+		return r.SetDocumentTags(document, tags)
+	}
 	return nil
 }
 
+// AddDocumentVersion records a new version of a document, numbered after the last one, and makes
+// its file the current file of the document. It returns ErrQuotaExceeded if the files of the
+// association then exceed the quota, in bytes (0 for no limit).
+func (r *GormDocumentRepository) AddDocumentVersion(document *models.Document, version *models.DocumentVersion, quota int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&DocumentVersionDB{}).Where("document_id = ?", document.ID).Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		version.DocumentID = document.ID
+		version.Number = last + 1
+		versionDB := toDocumentVersionDB(version)
+		if err := tx.Create(versionDB).Error; err != nil {
+			return err
+		}
+		*version = *toDocumentVersion(versionDB)
+
+		document.Version = version.Number
+		document.FilePath = version.FilePath
+		document.FileSize = version.FileSize
+		document.MimeType = version.MimeType
+		document.UploadDate = version.UploadDate
+		if err := tx.Save(toDocumentDB(document)).Error; err != nil {
+			return err
+		}
+		return checkStorageQuota(tx, document.UserID, quota)
+	})
+}
+
+// FindDocumentVersions retrieves the versions of a document, most recent first.
+func (r *GormDocumentRepository) FindDocumentVersions(documentID uint) ([]models.DocumentVersion, error) {
+	var versionsDB []DocumentVersionDB
+	if err := r.db.Where("document_id = ?", documentID).Order("number DESC").Find(&versionsDB).Error; err != nil {
+		return nil, err
+	}
+	versions := make([]models.DocumentVersion, len(versionsDB))
+	for i := range versionsDB {
+		versions[i] = *toDocumentVersion(&versionsDB[i])
+	}
+	return versions, nil
+}
+
+// FindDocumentVersion retrieves a version of a document by its number.
+func (r *GormDocumentRepository) FindDocumentVersion(documentID uint, number int) (*models.DocumentVersion, error) {
+	var versionDB DocumentVersionDB
+	if err := r.db.Where("document_id = ? AND number = ?", documentID, number).First(&versionDB).Error; err != nil {
+		return nil, err
+	}
+	return toDocumentVersion(&versionDB), nil
+}
+
 // FindDocumentByID retrieves a document from the database by its ID.
 // It returns the document as a domain model or an error if not found.
 func (r *GormDocumentRepository) FindDocumentByID(id uint) (*models.Document, error) {
@@ -67,7 +379,11 @@ func (r *GormDocumentRepository) FindDocumentByID(id uint) (*models.Document, er
 	if result.Error != nil {
 		return nil, result.Error
 	}
-	return toDocument(&documentDB), nil
+	document := toDocument(&documentDB)
+	if err := r.loadTags([]*models.Document{document}); err != nil {
+		return nil, err
+	}
+	return document, nil
 }
 
 // FindDocumentsByUserID retrieves all documents associated with a specific user ID.
@@ -84,13 +400,347 @@ func (r *GormDocumentRepository) FindDocumentsByUserID(userID uint) ([]models.Do
 	return documents, nil
 }
 
+// FindDocuments retrieves the documents of a user matching a query of the document library, with
+// their tags.
+func (r *GormDocumentRepository) FindDocuments(userID uint, query models.DocumentQuery) ([]models.Document, error) {
+	db := r.db.Where("user_id = ?", userID)
+	if !query.AllFolders {
+		if query.FolderID != nil {
+			db = db.Where("folder_id = ?", *query.FolderID)
+		} else {
+			db = db.Where("folder_id IS NULL")
+		}
+	}
+	if query.Tag != "" {
+		db = db.Where("id IN (?)", r.db.Model(&DocumentTagDB{}).Select("document_id").Where("user_id = ? AND name = ?", userID, query.Tag))
+	}
+	if len(query.Visibilities) > 0 {
+		db = db.Where("visibility IN ?", query.Visibilities)
+	}
+	if words := strings.Fields(query.Search); len(words) > 0 {
+		if r.fullText {
+			db = db.Where(fmt.Sprintf("id IN (SELECT rowid FROM %s WHERE %s MATCH ?)", DocumentSearchTable, DocumentSearchTable), matchExpression(words))
+		} else {
+			for _, word := range words {
+				pattern := "%" + word + "%"
+				db = db.Where("(name LIKE ? OR text_content LIKE ?)", pattern, pattern)
+			}
+		}
+	}
+	column, ok := documentSortColumns[query.Sort]
+	if !ok {
+		column = documentSortColumns[models.DocumentSortDate]
+	}
+	if query.Descending {
+		column += " DESC"
+	}
+
+	var documentsDB []DocumentDB
+	if err := db.Order(column).Order("id").Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	documents := make([]models.Document, len(documentsDB))
+	pointers := make([]*models.Document, len(documentsDB))
+	for i := range documentsDB {
+		documents[i] = *toDocument(&documentsDB[i])
+		pointers[i] = &documents[i]
+	}
+	if err := r.loadTags(pointers); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// matchExpression builds an FTS5 query matching the documents containing all the words, each word
+// being quoted so that the FTS5 syntax characters typed by the user are searched literally, and
+// matched as a prefix.
+func matchExpression(words []string) string {
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// FindDocumentsToIndex retrieves documents whose text has not been extracted yet, oldest first.
+func (r *GormDocumentRepository) FindDocumentsToIndex(limit int) ([]models.Document, error) {
+	var documentsDB []DocumentDB
+	if err := r.db.Where("content_indexed = ?", false).Order("id").Limit(limit).Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	var documents []models.Document
+	for _, ddb := range documentsDB {
+		documents = append(documents, *toDocument(&ddb))
+	}
+	return documents, nil
+}
+
+// FindDocumentsPendingPreview retrieves documents whose preview has not been generated yet,
+// including those uploaded before previews existed.
+func (r *GormDocumentRepository) FindDocumentsPendingPreview(limit int) ([]models.Document, error) {
+	var documentsDB []DocumentDB
+	if err := r.db.Where("preview_status = ? OR preview_status = '' OR preview_status IS NULL", string(models.PreviewPending)).
+		Order("id").Limit(limit).Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	var documents []models.Document
+	for _, ddb := range documentsDB {
+		documents = append(documents, *toDocument(&ddb))
+	}
+	return documents, nil
+}
+
+// UpdateDocumentPreview records the state of the preview of a document and the key of its
+// thumbnail, unless the current file of the document changed since it was read: the preview of
+// the new file is then generated in turn.
+func (r *GormDocumentRepository) UpdateDocumentPreview(document *models.Document, status models.DocumentPreviewStatus, key string) error {
+	return r.db.Model(&DocumentDB{}).Where("id = ? AND file_path = ?", document.ID, document.FilePath).
+		Updates(map[string]interface{}{"preview_status": string(status), "preview_key": key}).Error
+}
+
+// FindStorageKeys retrieves the storage keys of all the files referenced by documents, their
+// versions, previews and signed copies, and quarantined files, including deleted ones, each once.
+func (r *GormDocumentRepository) FindStorageKeys() ([]string, error) {
+	var keys []string
+	err := r.db.Raw(`SELECT file_path AS storage_key FROM documents WHERE file_path <> ''
+		UNION SELECT file_path FROM document_versions WHERE file_path <> ''
+		UNION SELECT preview_key FROM documents WHERE preview_key <> ''
+		UNION SELECT storage_key FROM quarantined_files WHERE storage_key <> ''
+		UNION SELECT signed_key FROM document_signature_requests WHERE signed_key <> ''
+		ORDER BY storage_key`).Scan(&keys).Error
+	return keys, err
+}
+
+// FindStorageReferences retrieves the records referencing a file of the document store: the
+// documents, their versions and thumbnails, the quarantined files and the signed PDFs of the
+// signature requests, including deleted ones.
+func (r *GormDocumentRepository) FindStorageReferences() ([]models.StorageReference, error) {
+	var references []models.StorageReference
+	err := r.db.Raw(`SELECT ? AS kind, id, id AS document_id, user_id, name, file_path AS key, deleted_at IS NOT NULL AS deleted
+			FROM documents WHERE file_path <> ''
+		UNION ALL SELECT ?, v.id, v.document_id, d.user_id, v.file_name, v.file_path, v.deleted_at IS NOT NULL OR d.deleted_at IS NOT NULL
+			FROM document_versions v JOIN documents d ON d.id = v.document_id WHERE v.file_path <> ''
+		UNION ALL SELECT ?, id, id, user_id, name, preview_key, deleted_at IS NOT NULL
+			FROM documents WHERE preview_key <> ''
+		UNION ALL SELECT ?, id, 0, user_id, file_name, storage_key, deleted_at IS NOT NULL
+			FROM quarantined_files WHERE storage_key <> ''
+		UNION ALL SELECT ?, s.id, s.document_id, s.user_id, s.document_name, s.signed_key, s.deleted_at IS NOT NULL OR d.deleted_at IS NOT NULL
+			FROM document_signature_requests s JOIN documents d ON d.id = s.document_id WHERE s.signed_key <> ''
+		ORDER BY key`,
+		models.StorageRefDocument, models.StorageRefVersion, models.StorageRefPreview, models.StorageRefQuarantine, models.StorageRefSignature).
+		Scan(&references).Error
+	return references, err
+}
+
+// FindDeletedDocuments retrieves the documents of a user in the trash, most recently deleted first.
+func (r *GormDocumentRepository) FindDeletedDocuments(userID uint) ([]models.Document, error) {
+	var documentsDB []DocumentDB
+	if err := r.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC").Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	var documents []models.Document
+	for _, ddb := range documentsDB {
+		documents = append(documents, *toDocument(&ddb))
+	}
+	return documents, nil
+}
+
+// FindDeletedDocumentByID retrieves a document in the trash by its ID.
+func (r *GormDocumentRepository) FindDeletedDocumentByID(id uint) (*models.Document, error) {
+	var documentDB DocumentDB
+	if err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&documentDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toDocument(&documentDB), nil
+}
+
+// FindDocumentsDeletedBefore retrieves, for all users, documents put in the trash before the given
+// date, at most limit of them. The supporting documents of the accounts, which are never purged,
+// are left out.
+func (r *GormDocumentRepository) FindDocumentsDeletedBefore(before time.Time, limit int) ([]models.Document, error) {
+	var documentsDB []DocumentDB
+	receipts := r.db.Model(&ExpenseClaimLineDB{}).Select("receipt_document_id").Where("receipt_document_id IS NOT NULL")
+	if err := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("transaction_id IS NULL AND id NOT IN (?)", receipts).
+		Order("deleted_at").Limit(limit).Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	var documents []models.Document
+	for _, ddb := range documentsDB {
+		documents = append(documents, *toDocument(&ddb))
+	}
+	return documents, nil
+}
+
+// RestoreDocument takes a document out of the trash, in the folder set on the document.
+func (r *GormDocumentRepository) RestoreDocument(document *models.Document) error {
+	return r.db.Unscoped().Model(&DocumentDB{}).Where("id = ?", document.ID).
+		Updates(map[string]interface{}{"deleted_at": nil, "folder_id": document.FolderID}).Error
+}
+
+// PurgeDocument permanently deletes a document with its versions, tags, share links and signature
+// requests. It returns
+// the storage keys of the files the document referenced, which the caller deletes once no other
+// record references them.
+func (r *GormDocumentRepository) PurgeDocument(id uint) ([]string, error) {
+	var keys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`SELECT file_path FROM documents WHERE id = ? AND file_path <> ''
+			UNION SELECT preview_key FROM documents WHERE id = ? AND preview_key <> ''
+			UNION SELECT file_path FROM document_versions WHERE document_id = ? AND file_path <> ''
+			UNION SELECT signed_key FROM document_signature_requests WHERE document_id = ? AND signed_key <> ''`,
+			id, id, id, id).Scan(&keys).Error; err != nil {
+			return err
+		}
+		requests := tx.Model(&DocumentSignatureRequestDB{}).Unscoped().Select("id").Where("document_id = ?", id)
+		if err := tx.Where("request_id IN (?)", requests).Delete(&DocumentSignerDB{}).Error; err != nil {
+			return err
+		}
+		for _, table := range []interface{}{&DocumentTagDB{}, &DocumentShareDownloadDB{}, &DocumentShareLinkDB{}, &DocumentSignatureRequestDB{}, &DocumentVersionDB{}} {
+			if err := tx.Unscoped().Where("document_id = ?", id).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&DocumentDB{}, id).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// IsDocumentAttached reports whether a document, in the trash or not, is a supporting document of
+// the accounts: attached to a transaction, or the receipt of an expense claim line.
+func (r *GormDocumentRepository) IsDocumentAttached(id uint) (bool, error) {
+	var attached bool
+	err := r.db.Raw(`SELECT EXISTS (SELECT 1 FROM documents WHERE id = ? AND transaction_id IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM expense_claim_lines WHERE receipt_document_id = ? AND deleted_at IS NULL)`,
+		id, id).Scan(&attached).Error
+	return attached, err
+}
+
+// SetDocumentTags replaces the tags of a document.
+func (r *GormDocumentRepository) SetDocumentTags(document *models.Document, tags []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", document.ID).Delete(&DocumentTagDB{}).Error; err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := tx.Create(&DocumentTagDB{UserID: document.UserID, DocumentID: document.ID, Name: tag}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	document.Tags = tags
+	return nil
+}
+
+// FindTagsByUserID returns the tags used by the documents of a user, in alphabetical order.
+func (r *GormDocumentRepository) FindTagsByUserID(userID uint) ([]string, error) {
+	var tags []string
+	err := r.db.Model(&DocumentTagDB{}).
+		Distinct("document_tags.name").
+		Joins("JOIN documents ON documents.id = document_tags.document_id AND documents.deleted_at IS NULL").
+		Where("document_tags.user_id = ?", userID).
+		Order("document_tags.name").
+		Pluck("document_tags.name", &tags).Error
+	return tags, err
+}
+
+// loadTags fills the tags of the given documents.
+func (r *GormDocumentRepository) loadTags(documents []*models.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+	}
+	var tagsDB []DocumentTagDB
+	if err := r.db.Where("document_id IN ?", ids).Order("name").Find(&tagsDB).Error; err != nil {
+		return err
+	}
+	tags := map[uint][]string{}
+	for _, tag := range tagsDB {
+		tags[tag.DocumentID] = append(tags[tag.DocumentID], tag.Name)
+	}
+	for _, document := range documents {
+		document.Tags = tags[document.ID]
+	}
+	return nil
+}
+
+// CountDocumentsInFolder returns the number of documents directly contained in a folder.
+func (r *GormDocumentRepository) CountDocumentsInFolder(folderID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&DocumentDB{}).Where("folder_id = ?", folderID).Count(&count).Error
+	return count, err
+}
+
+// CreateFolder persists a new folder of the document library.
+func (r *GormDocumentRepository) CreateFolder(folder *models.DocumentFolder) error {
+	folderDB := toDocumentFolderDB(folder)
+	if err := r.db.Create(folderDB).Error; err != nil {
+		return err
+	}
+	*folder = *toDocumentFolder(folderDB)
+	return nil
+}
+
+// FindFolderByID retrieves a folder of the document library by its ID.
+func (r *GormDocumentRepository) FindFolderByID(id uint) (*models.DocumentFolder, error) {
+	var folderDB DocumentFolderDB
+	if err := r.db.First(&folderDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toDocumentFolder(&folderDB), nil
+}
+
+// FindFoldersByUserID retrieves all the folders of the document library of a user, by name.
+func (r *GormDocumentRepository) FindFoldersByUserID(userID uint) ([]models.DocumentFolder, error) {
+	var foldersDB []DocumentFolderDB
+	if err := r.db.Where("user_id = ?", userID).Find(&foldersDB).Error; err != nil {
+		return nil, err
+	}
+	folders := make([]models.DocumentFolder, len(foldersDB))
+	for i := range foldersDB {
+		folders[i] = *toDocumentFolder(&foldersDB[i])
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		return strings.ToLower(folders[i].Name) < strings.ToLower(folders[j].Name)
+	})
+	return folders, nil
+}
+
+// UpdateFolder saves the changes made to a folder.
+func (r *GormDocumentRepository) UpdateFolder(folder *models.DocumentFolder) error {
+	return r.db.Save(toDocumentFolderDB(folder)).Error
+}
+
+// DeleteFolder deletes a folder of the document library by its ID.
+func (r *GormDocumentRepository) DeleteFolder(id uint) error {
+	return r.db.Delete(&DocumentFolderDB{}, id).Error
+}
+
+// UpdateDocument saves the changes made to an existing document.
+func (r *GormDocumentRepository) UpdateDocument(document *models.Document) error {
+	// The preview is left to UpdateDocumentPreview, so that the background worker generating it
+	// does not race with the other updates of the document.
+	return r.db.Omit("preview_status", "preview_key").Save(toDocumentDB(document)).Error
+}
+
 // DeleteDocument deletes a document from the database by its ID.
 func (r *GormDocumentRepository) DeleteDocument(id uint) error {
 	return r.db.Delete(&DocumentDB{}, id).Error
 }
 
 // GetTotalDocumentsCount returns the total number of documents for a given user ID.
-// It performs a count query on the documents table, filtered by user_id.
+// It performs a count query on the documents table, filtered by user_id, so that a document
+// counts once whatever its number of versions.
 func (r *GormDocumentRepository) GetTotalDocumentsCount(userID uint) (int64, error) {
 	var count int64
 	if err := r.db.Model(&DocumentDB{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
@@ -99,6 +749,417 @@ func (r *GormDocumentRepository) GetTotalDocumentsCount(userID uint) (int64, err
 	return count, nil
 }
 
+// GetStorageUsed returns the storage space used by a user, in bytes: the size of the files of all
+// the versions of their documents, including the documents in the trash whose files are kept until
+// they are purged, counting once the files shared by restored versions, and of their quarantined
+// files.
+func (r *GormDocumentRepository) GetStorageUsed(userID uint) (int64, error) {
+	return storageUsed(r.db, userID)
+}
+
+// checkStorageQuota returns ErrQuotaExceeded if the files of a user exceed the quota, in bytes
+// (0 for no limit). It is called within the transaction recording a file, after the file, so that
+// concurrent uploads cannot exceed the quota together.
+func checkStorageQuota(tx *gorm.DB, userID uint, quota int64) error {
+	if quota <= 0 {
+		return nil
+	}
+	used, err := storageUsed(tx, userID)
+	if err != nil {
+		return err
+	}
+	if used > quota {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// storageUsed returns the storage space used by the files of a user, in bytes.
+func storageUsed(db *gorm.DB, userID uint) (int64, error) {
+	var used struct{ Documents, Quarantine int64 }
+	err := db.Raw(`SELECT
+		(SELECT COALESCE(SUM(file_size), 0) FROM (SELECT DISTINCT v.file_path, v.file_size FROM document_versions v
+			JOIN documents d ON d.id = v.document_id
+			WHERE d.user_id = ? AND v.deleted_at IS NULL)) AS documents,
+		(SELECT COALESCE(SUM(file_size), 0) FROM quarantined_files WHERE user_id = ? AND deleted_at IS NULL) AS quarantine`,
+		userID, userID).Scan(&used).Error
+	return used.Documents + used.Quarantine, err
+}
+
+// CreateQuarantinedFile records a file put in quarantine.
+func (r *GormDocumentRepository) CreateQuarantinedFile(file *models.QuarantinedFile) error {
+	fileDB := toQuarantinedFileDB(file)
+	if err := r.db.Create(fileDB).Error; err != nil {
+		return err
+	}
+	file.ID = fileDB.ID
+	file.CreatedAt = fileDB.CreatedAt
+	file.UpdatedAt = fileDB.UpdatedAt
+	return nil
+}
+
+// FindQuarantinedFiles retrieves the quarantined files of a user, most recent first.
+func (r *GormDocumentRepository) FindQuarantinedFiles(userID uint) ([]models.QuarantinedFile, error) {
+	var filesDB []QuarantinedFileDB
+	if err := r.db.Where("user_id = ?", userID).Order("detected_at DESC").Find(&filesDB).Error; err != nil {
+		return nil, err
+	}
+	var files []models.QuarantinedFile
+	for _, fdb := range filesDB {
+		files = append(files, *toQuarantinedFile(&fdb))
+	}
+	return files, nil
+}
+
+// FindQuarantinedFileByID retrieves a quarantined file by its ID.
+func (r *GormDocumentRepository) FindQuarantinedFileByID(id uint) (*models.QuarantinedFile, error) {
+	var fileDB QuarantinedFileDB
+	if err := r.db.First(&fileDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toQuarantinedFile(&fileDB), nil
+}
+
+// DeleteQuarantinedFile deletes the record of a quarantined file.
+func (r *GormDocumentRepository) DeleteQuarantinedFile(id uint) error {
+	return r.db.Delete(&QuarantinedFileDB{}, id).Error
+}
+
+// CreateShareLink records a new share link.
+func (r *GormDocumentRepository) CreateShareLink(link *models.DocumentShareLink) error {
+	linkDB := toDocumentShareLinkDB(link)
+	if err := r.db.Create(linkDB).Error; err != nil {
+		return err
+	}
+	*link = *toDocumentShareLink(linkDB)
+	return nil
+}
+
+// FindShareLinkByID retrieves a share link by its ID.
+func (r *GormDocumentRepository) FindShareLinkByID(id uint) (*models.DocumentShareLink, error) {
+	var linkDB DocumentShareLinkDB
+	if err := r.db.First(&linkDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toDocumentShareLink(&linkDB), nil
+}
+
+// FindShareLinkByTokenHash retrieves the share link whose token has the given hash.
+func (r *GormDocumentRepository) FindShareLinkByTokenHash(tokenHash string) (*models.DocumentShareLink, error) {
+	var linkDB DocumentShareLinkDB
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&linkDB).Error; err != nil {
+		return nil, err
+	}
+	return toDocumentShareLink(&linkDB), nil
+}
+
+// FindShareLinksByDocumentID retrieves the share links of a document, most recent first.
+func (r *GormDocumentRepository) FindShareLinksByDocumentID(documentID uint) ([]models.DocumentShareLink, error) {
+	var linksDB []DocumentShareLinkDB
+	if err := r.db.Where("document_id = ?", documentID).Order("created_at DESC, id DESC").Find(&linksDB).Error; err != nil {
+		return nil, err
+	}
+	var links []models.DocumentShareLink
+	for _, ldb := range linksDB {
+		links = append(links, *toDocumentShareLink(&ldb))
+	}
+	return links, nil
+}
+
+// RevokeShareLink marks a share link as revoked, unless it already was.
+func (r *GormDocumentRepository) RevokeShareLink(id uint, revokedAt time.Time) error {
+	return r.db.Model(&DocumentShareLinkDB{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", revokedAt).Error
+}
+
+// RecordShareFailure counts a wrong password entered on a share link.
+func (r *GormDocumentRepository) RecordShareFailure(id uint) error {
+	return r.db.Model(&DocumentShareLinkDB{}).Where("id = ?", id).
+		Update("failed_attempts", gorm.Expr("failed_attempts + 1")).Error
+}
+
+// RecordShareDownload counts a download made through a share link and adds it to the download
+// log, provided the link is still usable when the download is counted. The check and the count
+// are made by a single statement, so that concurrent downloads cannot exceed the maximum number
+// of downloads. It reports whether the download was recorded.
+func (r *GormDocumentRepository) RecordShareDownload(link *models.DocumentShareLink, download *models.DocumentShareDownload) (bool, error) {
+	recorded := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&DocumentShareLinkDB{}).
+			Where("id = ? AND deleted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", link.ID, download.DownloadedAt).
+			Where("max_downloads = 0 OR download_count < max_downloads").
+			Where("failed_attempts < ?", models.MaxSharePasswordFailures).
+			Update("download_count", gorm.Expr("download_count + 1"))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		downloadDB := toDocumentShareDownloadDB(download)
+		if err := tx.Create(downloadDB).Error; err != nil {
+			return err
+		}
+		download.ID = downloadDB.ID
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if recorded {
+		link.DownloadCount++
+	}
+	return recorded, nil
+}
+
+// FindShareDownloads retrieves the downloads made through the share links of a document, most
+// recent first.
+func (r *GormDocumentRepository) FindShareDownloads(documentID uint) ([]models.DocumentShareDownload, error) {
+	var downloadsDB []DocumentShareDownloadDB
+	if err := r.db.Where("document_id = ?", documentID).Order("downloaded_at DESC, id DESC").Find(&downloadsDB).Error; err != nil {
+		return nil, err
+	}
+	var downloads []models.DocumentShareDownload
+	for _, ddb := range downloadsDB {
+		downloads = append(downloads, *toDocumentShareDownload(&ddb))
+	}
+	return downloads, nil
+}
+
+// CreateSignatureRequest persists a new signature request with its signers.
+func (r *GormDocumentRepository) CreateSignatureRequest(request *models.DocumentSignatureRequest) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		requestDB := toDocumentSignatureRequestDB(request)
+		if err := tx.Create(requestDB).Error; err != nil {
+			return err
+		}
+		request.ID = requestDB.ID
+		request.CreatedAt = requestDB.CreatedAt
+		request.UpdatedAt = requestDB.UpdatedAt
+		for i := range request.Signers {
+			request.Signers[i].RequestID = request.ID
+			signerDB := toDocumentSignerDB(&request.Signers[i])
+			if err := tx.Create(signerDB).Error; err != nil {
+				return err
+			}
+			request.Signers[i].ID = signerDB.ID
+		}
+		return nil
+	})
+}
+
+// FindSignatureRequestByID retrieves a signature request by its ID, with its signers.
+func (r *GormDocumentRepository) FindSignatureRequestByID(id uint) (*models.DocumentSignatureRequest, error) {
+	var requestDB DocumentSignatureRequestDB
+	if err := r.db.First(&requestDB, id).Error; err != nil {
+		return nil, err
+	}
+	request := toDocumentSignatureRequest(&requestDB)
+	if err := r.loadSigners([]*models.DocumentSignatureRequest{request}); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// FindSignatureRequestsByDocumentID retrieves the signature requests of a document with their
+// signers, most recent first.
+func (r *GormDocumentRepository) FindSignatureRequestsByDocumentID(documentID uint) ([]models.DocumentSignatureRequest, error) {
+	var requestsDB []DocumentSignatureRequestDB
+	if err := r.db.Where("document_id = ?", documentID).Order("created_at DESC, id DESC").Find(&requestsDB).Error; err != nil {
+		return nil, err
+	}
+	requests := make([]models.DocumentSignatureRequest, len(requestsDB))
+	pointers := make([]*models.DocumentSignatureRequest, len(requestsDB))
+	for i := range requestsDB {
+		requests[i] = *toDocumentSignatureRequest(&requestsDB[i])
+		pointers[i] = &requests[i]
+	}
+	if err := r.loadSigners(pointers); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// loadSigners fills the signers of signature requests, in the order they were added.
+func (r *GormDocumentRepository) loadSigners(requests []*models.DocumentSignatureRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(requests))
+	byID := make(map[uint]*models.DocumentSignatureRequest, len(requests))
+	for i, request := range requests {
+		ids[i] = request.ID
+		byID[request.ID] = request
+	}
+	var signersDB []DocumentSignerDB
+	if err := r.db.Where("request_id IN ?", ids).Order("id").Find(&signersDB).Error; err != nil {
+		return err
+	}
+	for i := range signersDB {
+		request := byID[signersDB[i].RequestID]
+		request.Signers = append(request.Signers, *toDocumentSigner(&signersDB[i]))
+	}
+	return nil
+}
+
+// FindSignerByTokenHash retrieves the signer whose signing link has the given token hash.
+func (r *GormDocumentRepository) FindSignerByTokenHash(tokenHash string) (*models.DocumentSigner, error) {
+	var signerDB DocumentSignerDB
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&signerDB).Error; err != nil {
+		return nil, err
+	}
+	return toDocumentSigner(&signerDB), nil
+}
+
+// UpdateSignerToken replaces the token of the signing link of a signer, which invalidates the
+// previous link, and records when the new one was sent (nil if it could not be).
+func (r *GormDocumentRepository) UpdateSignerToken(signerID uint, tokenHash string, sentAt *time.Time) error {
+	return r.db.Model(&DocumentSignerDB{}).Where("id = ?", signerID).
+		Updates(map[string]interface{}{"token_hash": tokenHash, "email_sent_at": sentAt}).Error
+}
+
+// RecordSignerView records when a signer first opened its signing link.
+func (r *GormDocumentRepository) RecordSignerView(signerID uint, viewedAt time.Time) error {
+	return r.db.Model(&DocumentSignerDB{}).Where("id = ? AND viewed_at IS NULL", signerID).Update("viewed_at", viewedAt).Error
+}
+
+// RecordSignature records the signature of a signer, provided the signer and its request are still
+// pending, and completes the request once every signer signed. The conditional updates make
+// concurrent submissions safe: only one is recorded. It reports whether the request was completed.
+func (r *GormDocumentRepository) RecordSignature(signer *models.DocumentSigner) (bool, error) {
+	completed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&DocumentSignerDB{}).
+			Where("id = ? AND status = ?", signer.ID, string(models.SignerPending)).
+			Where("request_id IN (?)", tx.Model(&DocumentSignatureRequestDB{}).Select("id").Where("status = ?", string(models.SignaturePending))).
+			Updates(map[string]interface{}{
+				"status":            string(models.SignerSigned),
+				"signed_at":         signer.SignedAt,
+				"method":            string(signer.Method),
+				"signature_text":    signer.SignatureText,
+				"signature_strokes": signer.SignatureStrokes,
+				"document_hash":     signer.DocumentHash,
+				"ip_address":        signer.IPAddress,
+				"user_agent":        signer.UserAgent,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var pending int64
+		if err := tx.Model(&DocumentSignerDB{}).Where("request_id = ? AND status <> ?", signer.RequestID, string(models.SignerSigned)).Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+		result = tx.Model(&DocumentSignatureRequestDB{}).Where("id = ? AND status = ?", signer.RequestID, string(models.SignaturePending)).
+			Updates(map[string]interface{}{"status": string(models.SignatureCompleted), "completed_at": signer.SignedAt})
+		completed = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return false, err
+	}
+	signer.Status = models.SignerSigned
+	return completed, nil
+}
+
+// RecordSignatureRefusal records that a pending signer refused to sign, which ends its request.
+// It reports whether the refusal was recorded, i.e. whether the signer and its request were still
+// pending.
+func (r *GormDocumentRepository) RecordSignatureRefusal(signer *models.DocumentSigner) (bool, error) {
+	recorded := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&DocumentSignatureRequestDB{}).Where("id = ? AND status = ?", signer.RequestID, string(models.SignaturePending)).
+			Updates(map[string]interface{}{"status": string(models.SignatureDeclined), "completed_at": signer.SignedAt})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		result = tx.Model(&DocumentSignerDB{}).Where("id = ? AND status = ?", signer.ID, string(models.SignerPending)).
+			Updates(map[string]interface{}{
+				"status":         string(models.SignerDeclined),
+				"signed_at":      signer.SignedAt,
+				"decline_reason": signer.DeclineReason,
+				"ip_address":     signer.IPAddress,
+				"user_agent":     signer.UserAgent,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// The signer answered meanwhile: the request is left as it was.
+			return gorm.ErrRecordNotFound
+		}
+		recorded = true
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if recorded {
+		signer.Status = models.SignerDeclined
+	}
+	return recorded, nil
+}
+
+// CancelSignatureRequest cancels a pending signature request. It reports whether the request was
+// still pending.
+func (r *GormDocumentRepository) CancelSignatureRequest(id uint, cancelledAt time.Time) (bool, error) {
+	result := r.db.Model(&DocumentSignatureRequestDB{}).Where("id = ? AND status = ?", id, string(models.SignaturePending)).
+		Updates(map[string]interface{}{"status": string(models.SignatureCancelled), "completed_at": cancelledAt})
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetSignedFile records the signed PDF of a signature request.
+func (r *GormDocumentRepository) SetSignedFile(requestID uint, key, hash string) error {
+	return r.db.Model(&DocumentSignatureRequestDB{}).Where("id = ?", requestID).
+		Updates(map[string]interface{}{"signed_key": key, "signed_hash": hash}).Error
+}
+
+// FindDocumentsByTransactionID retrieves the documents attached to a transaction.
+func (r *GormDocumentRepository) FindDocumentsByTransactionID(transactionID uint) ([]models.Document, error) {
+	var documentsDB []DocumentDB
+	if err := r.db.Where("transaction_id = ?", transactionID).Order("upload_date").Find(&documentsDB).Error; err != nil {
+		return nil, err
+	}
+	var documents []models.Document
+	for _, ddb := range documentsDB {
+		documents = append(documents, *toDocument(&ddb))
+	}
+	return documents, nil
+}
+
+// CountDocumentsByTransaction returns, for each transaction of a user having attachments,
+// the number of documents attached to it.
+func (r *GormDocumentRepository) CountDocumentsByTransaction(userID uint) (map[uint]int64, error) {
+	var rows []struct {
+		TransactionID uint
+		Count         int64
+	}
+	err := r.db.Model(&DocumentDB{}).
+		Select("transaction_id, count(*) AS count").
+		Where("user_id = ? AND transaction_id IS NOT NULL", userID).
+		Group("transaction_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TransactionID] = row.Count
+	}
+	return counts, nil
+}
+
+// DetachDocumentsFromTransaction unlinks the documents attached to a transaction, e.g., when the
+// transaction is deleted. The documents themselves are kept.
+func (r *GormDocumentRepository) DetachDocumentsFromTransaction(transactionID uint) error {
+	return r.db.Model(&DocumentDB{}).Where("transaction_id = ?", transactionID).Update("transaction_id", nil).Error
+}
+
 // toDocumentDB converts a domain Document model to a database-specific DocumentDB model.
 // This is used before persisting the document to the database.
 func toDocumentDB(d *models.Document) *DocumentDB {
@@ -110,12 +1171,27 @@ func toDocumentDB(d *models.Document) *DocumentDB {
 		MimeType:   d.MimeType,
 		UploadDate: d.UploadDate,
 		UserID:     d.UserID,
+
+		TransactionID: d.TransactionID,
+
+		FolderID:       d.FolderID,
+		TextContent:    d.TextContent,
+		ContentIndexed: d.ContentIndexed,
+		Version:        d.Version,
+		PreviewStatus:  string(d.PreviewStatus),
+		PreviewKey:     d.PreviewKey,
+		Visibility:     string(d.Visibility),
 	}
 }
 
 // toDocument converts a database-specific DocumentDB model back to a domain Document model.
 // This is used after retrieving data from the database.
 func toDocument(ddb *DocumentDB) *models.Document {
+	// Documents stored before they had a visibility are private.
+	visibility := models.DocumentVisibility(ddb.Visibility)
+	if visibility == "" {
+		visibility = models.VisibilityPrivate
+	}
 	return &models.Document{
 		Model:      gorm.Model{ID: ddb.ID, CreatedAt: ddb.CreatedAt, UpdatedAt: ddb.UpdatedAt, DeletedAt: ddb.DeletedAt},
 		Name:       ddb.Name,
@@ -124,5 +1200,255 @@ func toDocument(ddb *DocumentDB) *models.Document {
 		MimeType:   ddb.MimeType,
 		UploadDate: ddb.UploadDate,
 		UserID:     ddb.UserID,
+
+		TransactionID: ddb.TransactionID,
+
+		FolderID:       ddb.FolderID,
+		TextContent:    ddb.TextContent,
+		ContentIndexed: ddb.ContentIndexed,
+		Version:        ddb.Version,
+		PreviewStatus:  models.DocumentPreviewStatus(ddb.PreviewStatus),
+		PreviewKey:     ddb.PreviewKey,
+		Visibility:     visibility,
+	}
+}
+
+// toDocumentVersionDB converts a domain DocumentVersion model to a database-specific DocumentVersionDB model.
+func toDocumentVersionDB(v *models.DocumentVersion) *DocumentVersionDB {
+	return &DocumentVersionDB{
+		Model:        gorm.Model{ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt, DeletedAt: v.DeletedAt},
+		DocumentID:   v.DocumentID,
+		Number:       v.Number,
+		FileName:     v.FileName,
+		FilePath:     v.FilePath,
+		FileSize:     v.FileSize,
+		MimeType:     v.MimeType,
+		UploadDate:   v.UploadDate,
+		UploadedByID: v.UploadedByID,
+		UploadedBy:   v.UploadedBy,
+		Comment:      v.Comment,
+	}
+}
+
+// toDocumentVersion converts a database-specific DocumentVersionDB model back to a domain DocumentVersion model.
+func toDocumentVersion(vdb *DocumentVersionDB) *models.DocumentVersion {
+	return &models.DocumentVersion{
+		Model:        gorm.Model{ID: vdb.ID, CreatedAt: vdb.CreatedAt, UpdatedAt: vdb.UpdatedAt, DeletedAt: vdb.DeletedAt},
+		DocumentID:   vdb.DocumentID,
+		Number:       vdb.Number,
+		FileName:     vdb.FileName,
+		FilePath:     vdb.FilePath,
+		FileSize:     vdb.FileSize,
+		MimeType:     vdb.MimeType,
+		UploadDate:   vdb.UploadDate,
+		UploadedByID: vdb.UploadedByID,
+		UploadedBy:   vdb.UploadedBy,
+		Comment:      vdb.Comment,
+	}
+}
+
+// toDocumentFolderDB converts a domain DocumentFolder model to a database-specific DocumentFolderDB model.
+func toDocumentFolderDB(f *models.DocumentFolder) *DocumentFolderDB {
+	return &DocumentFolderDB{
+		Model:    gorm.Model{ID: f.ID, CreatedAt: f.CreatedAt, UpdatedAt: f.UpdatedAt, DeletedAt: f.DeletedAt},
+		UserID:   f.UserID,
+		ParentID: f.ParentID,
+		Name:     f.Name,
+	}
+}
+
+// toDocumentFolder converts a database-specific DocumentFolderDB model back to a domain DocumentFolder model.
+func toDocumentFolder(fdb *DocumentFolderDB) *models.DocumentFolder {
+	return &models.DocumentFolder{
+		Model:    gorm.Model{ID: fdb.ID, CreatedAt: fdb.CreatedAt, UpdatedAt: fdb.UpdatedAt, DeletedAt: fdb.DeletedAt},
+		UserID:   fdb.UserID,
+		ParentID: fdb.ParentID,
+		Name:     fdb.Name,
+	}
+}
+
+// toQuarantinedFileDB converts a domain QuarantinedFile model to a database-specific QuarantinedFileDB model.
+func toQuarantinedFileDB(f *models.QuarantinedFile) *QuarantinedFileDB {
+	return &QuarantinedFileDB{
+		Model:        gorm.Model{ID: f.ID, CreatedAt: f.CreatedAt, UpdatedAt: f.UpdatedAt, DeletedAt: f.DeletedAt},
+		UserID:       f.UserID,
+		FileName:     f.FileName,
+		StorageKey:   f.StorageKey,
+		FileSize:     f.FileSize,
+		MimeType:     f.MimeType,
+		Signature:    f.Signature,
+		UploadedByID: f.UploadedByID,
+		UploadedBy:   f.UploadedBy,
+		DetectedAt:   f.DetectedAt,
+	}
+}
+
+// toQuarantinedFile converts a database-specific QuarantinedFileDB model back to a domain QuarantinedFile model.
+func toQuarantinedFile(fdb *QuarantinedFileDB) *models.QuarantinedFile {
+	return &models.QuarantinedFile{
+		Model:        gorm.Model{ID: fdb.ID, CreatedAt: fdb.CreatedAt, UpdatedAt: fdb.UpdatedAt, DeletedAt: fdb.DeletedAt},
+		UserID:       fdb.UserID,
+		FileName:     fdb.FileName,
+		StorageKey:   fdb.StorageKey,
+		FileSize:     fdb.FileSize,
+		MimeType:     fdb.MimeType,
+		Signature:    fdb.Signature,
+		UploadedByID: fdb.UploadedByID,
+		UploadedBy:   fdb.UploadedBy,
+		DetectedAt:   fdb.DetectedAt,
+	}
+}
+
+// toDocumentShareLinkDB converts a domain DocumentShareLink model to a database-specific DocumentShareLinkDB model.
+func toDocumentShareLinkDB(l *models.DocumentShareLink) *DocumentShareLinkDB {
+	return &DocumentShareLinkDB{
+		Model:          gorm.Model{ID: l.ID, CreatedAt: l.CreatedAt, UpdatedAt: l.UpdatedAt, DeletedAt: l.DeletedAt},
+		UserID:         l.UserID,
+		DocumentID:     l.DocumentID,
+		Label:          l.Label,
+		TokenHash:      l.TokenHash,
+		PasswordHash:   l.PasswordHash,
+		ExpiresAt:      l.ExpiresAt,
+		MaxDownloads:   l.MaxDownloads,
+		DownloadCount:  l.DownloadCount,
+		FailedAttempts: l.FailedAttempts,
+		RevokedAt:      l.RevokedAt,
+		CreatedByID:    l.CreatedByID,
+		CreatedBy:      l.CreatedBy,
+	}
+}
+
+// toDocumentShareLink converts a database-specific DocumentShareLinkDB model back to a domain DocumentShareLink model.
+func toDocumentShareLink(ldb *DocumentShareLinkDB) *models.DocumentShareLink {
+	return &models.DocumentShareLink{
+		Model:          gorm.Model{ID: ldb.ID, CreatedAt: ldb.CreatedAt, UpdatedAt: ldb.UpdatedAt, DeletedAt: ldb.DeletedAt},
+		UserID:         ldb.UserID,
+		DocumentID:     ldb.DocumentID,
+		Label:          ldb.Label,
+		TokenHash:      ldb.TokenHash,
+		PasswordHash:   ldb.PasswordHash,
+		ExpiresAt:      ldb.ExpiresAt,
+		MaxDownloads:   ldb.MaxDownloads,
+		DownloadCount:  ldb.DownloadCount,
+		FailedAttempts: ldb.FailedAttempts,
+		RevokedAt:      ldb.RevokedAt,
+		CreatedByID:    ldb.CreatedByID,
+		CreatedBy:      ldb.CreatedBy,
+	}
+}
+
+// toDocumentShareDownloadDB converts a domain DocumentShareDownload model to a database-specific DocumentShareDownloadDB model.
+func toDocumentShareDownloadDB(d *models.DocumentShareDownload) *DocumentShareDownloadDB {
+	return &DocumentShareDownloadDB{
+		ID:           d.ID,
+		LinkID:       d.LinkID,
+		DocumentID:   d.DocumentID,
+		Version:      d.Version,
+		DownloadedAt: d.DownloadedAt,
+		IPAddress:    d.IPAddress,
+		UserAgent:    d.UserAgent,
+	}
+}
+
+// toDocumentShareDownload converts a database-specific DocumentShareDownloadDB model back to a domain DocumentShareDownload model.
+func toDocumentShareDownload(ddb *DocumentShareDownloadDB) *models.DocumentShareDownload {
+	return &models.DocumentShareDownload{
+		ID:           ddb.ID,
+		LinkID:       ddb.LinkID,
+		DocumentID:   ddb.DocumentID,
+		Version:      ddb.Version,
+		DownloadedAt: ddb.DownloadedAt,
+		IPAddress:    ddb.IPAddress,
+		UserAgent:    ddb.UserAgent,
+	}
+}
+
+// toDocumentSignatureRequestDB converts a domain DocumentSignatureRequest model to a database-specific DocumentSignatureRequestDB model.
+func toDocumentSignatureRequestDB(r *models.DocumentSignatureRequest) *DocumentSignatureRequestDB {
+	return &DocumentSignatureRequestDB{
+		Model:        gorm.Model{ID: r.ID, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt, DeletedAt: r.DeletedAt},
+		UserID:       r.UserID,
+		DocumentID:   r.DocumentID,
+		DocumentName: r.DocumentName,
+		Version:      r.Version,
+		FilePath:     r.FilePath,
+		FileSize:     r.FileSize,
+		MimeType:     r.MimeType,
+		DocumentHash: r.DocumentHash,
+		Message:      r.Message,
+		Status:       string(r.Status),
+		CreatedByID:  r.CreatedByID,
+		CreatedBy:    r.CreatedBy,
+		CompletedAt:  r.CompletedAt,
+		SignedKey:    r.SignedKey,
+		SignedHash:   r.SignedHash,
+	}
+}
+
+// toDocumentSignatureRequest converts a database-specific DocumentSignatureRequestDB model back to a domain DocumentSignatureRequest model.
+func toDocumentSignatureRequest(rdb *DocumentSignatureRequestDB) *models.DocumentSignatureRequest {
+	return &models.DocumentSignatureRequest{
+		Model:        gorm.Model{ID: rdb.ID, CreatedAt: rdb.CreatedAt, UpdatedAt: rdb.UpdatedAt, DeletedAt: rdb.DeletedAt},
+		UserID:       rdb.UserID,
+		DocumentID:   rdb.DocumentID,
+		DocumentName: rdb.DocumentName,
+		Version:      rdb.Version,
+		FilePath:     rdb.FilePath,
+		FileSize:     rdb.FileSize,
+		MimeType:     rdb.MimeType,
+		DocumentHash: rdb.DocumentHash,
+		Message:      rdb.Message,
+		Status:       models.SignatureRequestStatus(rdb.Status),
+		CreatedByID:  rdb.CreatedByID,
+		CreatedBy:    rdb.CreatedBy,
+		CompletedAt:  rdb.CompletedAt,
+		SignedKey:    rdb.SignedKey,
+		SignedHash:   rdb.SignedHash,
+	}
+}
+
+// toDocumentSignerDB converts a domain DocumentSigner model to a database-specific DocumentSignerDB model.
+func toDocumentSignerDB(s *models.DocumentSigner) *DocumentSignerDB {
+	return &DocumentSignerDB{
+		ID:               s.ID,
+		RequestID:        s.RequestID,
+		MemberID:         s.MemberID,
+		Name:             s.Name,
+		Email:            s.Email,
+		TokenHash:        s.TokenHash,
+		Status:           string(s.Status),
+		EmailSentAt:      s.EmailSentAt,
+		ViewedAt:         s.ViewedAt,
+		SignedAt:         s.SignedAt,
+		Method:           string(s.Method),
+		SignatureText:    s.SignatureText,
+		SignatureStrokes: s.SignatureStrokes,
+		DocumentHash:     s.DocumentHash,
+		IPAddress:        s.IPAddress,
+		UserAgent:        s.UserAgent,
+		DeclineReason:    s.DeclineReason,
+	}
+}
+
+// toDocumentSigner converts a database-specific DocumentSignerDB model back to a domain DocumentSigner model.
+func toDocumentSigner(sdb *DocumentSignerDB) *models.DocumentSigner {
+	return &models.DocumentSigner{
+		ID:               sdb.ID,
+		RequestID:        sdb.RequestID,
+		MemberID:         sdb.MemberID,
+		Name:             sdb.Name,
+		Email:            sdb.Email,
+		TokenHash:        sdb.TokenHash,
+		Status:           models.SignerStatus(sdb.Status),
+		EmailSentAt:      sdb.EmailSentAt,
+		ViewedAt:         sdb.ViewedAt,
+		SignedAt:         sdb.SignedAt,
+		Method:           models.SignatureMethod(sdb.Method),
+		SignatureText:    sdb.SignatureText,
+		SignatureStrokes: sdb.SignatureStrokes,
+		DocumentHash:     sdb.DocumentHash,
+		IPAddress:        sdb.IPAddress,
+		UserAgent:        sdb.UserAgent,
+		DeclineReason:    sdb.DeclineReason,
 	}
 }
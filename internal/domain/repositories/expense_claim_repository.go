@@ -0,0 +1,207 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// ExpenseClaimDB represents the database model for an expense claim, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type ExpenseClaimDB struct {
+	gorm.Model
+	UserID   uint                      `gorm:"index"` // Owner of the association the claim is addressed to.
+	MemberID uint                      `gorm:"index"` // Member who advanced the money.
+	Title    string                    // Short title of the claim.
+	Currency string                    `gorm:"size:3"`             // Base currency of the association.
+	Status   models.ExpenseClaimStatus `gorm:"index"`              // Review state.
+	Lines    []ExpenseClaimLineDB      `gorm:"foreignKey:ClaimID"` // Expenses claimed.
+	Comment  string                    // Note of the member.
+
+	ReviewerName  string     // Treasurer who approved or rejected the claim.
+	ReviewComment string     // Justification given by the treasurer.
+	ReviewedAt    *time.Time // When the claim was reviewed.
+
+	Reimbursement models.ReimbursementStatus // Pending or paid, once approved.
+	PaidAt        *time.Time                 // When the member was paid back.
+	PaymentRef    string                     // Reference of the reimbursement.
+}
+
+// ExpenseClaimLineDB represents the database model for one expense of a claim.
+type ExpenseClaimLineDB struct {
+	gorm.Model
+	ClaimID           uint      `gorm:"index"` // The claim this line belongs to.
+	Date              time.Time // Date of the expense.
+	Description       string    // What was paid for.
+	AccountCode       string    // Expense category.
+	Amount            int64     // Amount in minor units of the claim currency.
+	ReceiptDocumentID *uint     // Receipt stored in the document library, if any.
+	TransactionID     *uint     `gorm:"index"` // Expense transaction recorded on approval.
+}
+
+// TableName specifies the table name for the ExpenseClaimDB model.
+func (ExpenseClaimDB) TableName() string {
+	return "expense_claims"
+}
+
+// TableName specifies the table name for the ExpenseClaimLineDB model.
+func (ExpenseClaimLineDB) TableName() string {
+	return "expense_claim_lines"
+}
+
+// ExpenseClaimRepository defines the interface for expense claim persistence operations.
+// It abstracts the underlying database implementation.
+type ExpenseClaimRepository interface {
+	CreateClaim(claim *models.ExpenseClaim) error
+	FindClaimByID(id uint) (*models.ExpenseClaim, error)
+	FindClaimsByUserID(userID, memberID uint) ([]models.ExpenseClaim, error)
+	UpdateClaim(claim *models.ExpenseClaim) error
+	DeleteClaim(id uint) error
+}
+
+// GormExpenseClaimRepository is an implementation of ExpenseClaimRepository that uses GORM
+// for interacting with a relational database.
+type GormExpenseClaimRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormExpenseClaimRepository creates a new instance of GormExpenseClaimRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormExpenseClaimRepository(db *gorm.DB) *GormExpenseClaimRepository {
+	return &GormExpenseClaimRepository{db: db}
+}
+
+// CreateClaim persists a new expense claim with its lines.
+func (r *GormExpenseClaimRepository) CreateClaim(claim *models.ExpenseClaim) error {
+	claimDB := toExpenseClaimDB(claim)
+	if err := r.db.Create(claimDB).Error; err != nil {
+		return err
+	}
+	*claim = *toExpenseClaim(claimDB) // Update the original claim with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindClaimByID retrieves an expense claim and its lines by its ID.
+func (r *GormExpenseClaimRepository) FindClaimByID(id uint) (*models.ExpenseClaim, error) {
+	var claimDB ExpenseClaimDB
+	if err := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).First(&claimDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toExpenseClaim(&claimDB), nil
+}
+
+// FindClaimsByUserID retrieves the expense claims addressed to an association with their lines,
+// most recent first. A zero member ID does not filter on the member.
+func (r *GormExpenseClaimRepository) FindClaimsByUserID(userID, memberID uint) ([]models.ExpenseClaim, error) {
+	var claimsDB []ExpenseClaimDB
+	query := r.db.Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).Where("user_id = ?", userID)
+	if memberID != 0 {
+		query = query.Where("member_id = ?", memberID)
+	}
+	if err := query.Order("created_at DESC, id DESC").Find(&claimsDB).Error; err != nil {
+		return nil, err
+	}
+	claims := make([]models.ExpenseClaim, len(claimsDB))
+	for i, claimDB := range claimsDB {
+		claims[i] = *toExpenseClaim(&claimDB)
+	}
+	return claims, nil
+}
+
+// UpdateClaim saves the review and reimbursement fields of a claim and the transactions linked
+// to its lines, in a single database transaction.
+func (r *GormExpenseClaimRepository) UpdateClaim(claim *models.ExpenseClaim) error {
+	claimDB := toExpenseClaimDB(claim)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Lines").Save(claimDB).Error; err != nil {
+			return err
+		}
+		for _, line := range claimDB.Lines {
+			if err := tx.Model(&ExpenseClaimLineDB{}).Where("id = ?", line.ID).Update("transaction_id", line.TransactionID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteClaim deletes an expense claim and its lines.
+func (r *GormExpenseClaimRepository) DeleteClaim(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("claim_id = ?", id).Delete(&ExpenseClaimLineDB{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&ExpenseClaimDB{}, id).Error
+	})
+}
+
+// toExpenseClaimDB converts a domain ExpenseClaim model to a database-specific ExpenseClaimDB model.
+func toExpenseClaimDB(c *models.ExpenseClaim) *ExpenseClaimDB {
+	lines := make([]ExpenseClaimLineDB, len(c.Lines))
+	for n, line := range c.Lines {
+		lines[n] = ExpenseClaimLineDB{
+			Model:             line.Model,
+			ClaimID:           line.ClaimID,
+			Date:              line.Date,
+			Description:       line.Description,
+			AccountCode:       line.AccountCode,
+			Amount:            line.Amount,
+			ReceiptDocumentID: line.ReceiptDocumentID,
+			TransactionID:     line.TransactionID,
+		}
+	}
+	return &ExpenseClaimDB{
+		Model:    c.Model,
+		UserID:   c.UserID,
+		MemberID: c.MemberID,
+		Title:    c.Title,
+		Currency: c.Currency,
+		Status:   c.Status,
+		Lines:    lines,
+		Comment:  c.Comment,
+
+		ReviewerName:  c.ReviewerName,
+		ReviewComment: c.ReviewComment,
+		ReviewedAt:    c.ReviewedAt,
+
+		Reimbursement: c.Reimbursement,
+		PaidAt:        c.PaidAt,
+		PaymentRef:    c.PaymentRef,
+	}
+}
+
+// toExpenseClaim converts a database-specific ExpenseClaimDB model back to a domain ExpenseClaim model.
+func toExpenseClaim(cdb *ExpenseClaimDB) *models.ExpenseClaim {
+	lines := make([]models.ExpenseClaimLine, len(cdb.Lines))
+	for n, line := range cdb.Lines {
+		lines[n] = models.ExpenseClaimLine{
+			Model:             line.Model,
+			ClaimID:           line.ClaimID,
+			Date:              line.Date,
+			Description:       line.Description,
+			AccountCode:       line.AccountCode,
+			Amount:            line.Amount,
+			ReceiptDocumentID: line.ReceiptDocumentID,
+			TransactionID:     line.TransactionID,
+		}
+	}
+	return &models.ExpenseClaim{
+		Model:    cdb.Model,
+		UserID:   cdb.UserID,
+		MemberID: cdb.MemberID,
+		Title:    cdb.Title,
+		Currency: cdb.Currency,
+		Status:   cdb.Status,
+		Lines:    lines,
+		Comment:  cdb.Comment,
+
+		ReviewerName:  cdb.ReviewerName,
+		ReviewComment: cdb.ReviewComment,
+		ReviewedAt:    cdb.ReviewedAt,
+
+		Reimbursement: cdb.Reimbursement,
+		PaidAt:        cdb.PaidAt,
+		PaymentRef:    cdb.PaymentRef,
+	}
+}
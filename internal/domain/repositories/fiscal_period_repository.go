@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// FiscalPeriodClosureDB represents the database model for a closed fiscal year, used for GORM persistence.
+// The unique index on (user, fiscal year) prevents closing a fiscal year twice.
+type FiscalPeriodClosureDB struct {
+	gorm.Model
+	UserID       uint      `gorm:"uniqueIndex:idx_fiscal_period_closure"` // Owner of the association.
+	FiscalYear   int       `gorm:"uniqueIndex:idx_fiscal_period_closure"` // Calendar year the fiscal year starts in.
+	Label        string    // Display label of the fiscal year.
+	StartDate    time.Time // First day of the closed period.
+	EndDate      time.Time // First day after the closed period.
+	ClosedAt     time.Time // When the period was closed.
+	ClosedByName string    // Name of the user who closed the period.
+}
+
+// FiscalPeriodEventDB represents the database model for an entry of the closing log.
+type FiscalPeriodEventDB struct {
+	gorm.Model
+	UserID     uint                      `gorm:"index"` // Owner of the association.
+	FiscalYear int                       // Fiscal year concerned.
+	Label      string                    // Display label of the fiscal year.
+	Action     models.FiscalPeriodAction // Closing or reopening.
+	ActorID    uint                      // User who took the action.
+	ActorName  string                    // Name of the user at the time of the action.
+	Reason     string                    // Reason given for the action.
+	At         time.Time                 // When the action was taken.
+}
+
+// TableName specifies the table name for the FiscalPeriodClosureDB model.
+func (FiscalPeriodClosureDB) TableName() string {
+	return "fiscal_period_closures"
+}
+
+// TableName specifies the table name for the FiscalPeriodEventDB model.
+func (FiscalPeriodEventDB) TableName() string {
+	return "fiscal_period_events"
+}
+
+// FiscalPeriodRepository defines the interface for fiscal period closing persistence operations.
+// It abstracts the underlying database implementation.
+type FiscalPeriodRepository interface {
+	CloseFiscalYear(closure *models.FiscalPeriodClosure, event *models.FiscalPeriodEvent) error
+	ReopenFiscalYear(closureID uint, event *models.FiscalPeriodEvent) error
+	FindClosuresByUserID(userID uint) ([]models.FiscalPeriodClosure, error)
+	FindClosureCovering(userID uint, date time.Time) (*models.FiscalPeriodClosure, error)
+	FindEventsByUserID(userID uint) ([]models.FiscalPeriodEvent, error)
+}
+
+// GormFiscalPeriodRepository is an implementation of FiscalPeriodRepository that uses GORM
+// for interacting with a relational database.
+type GormFiscalPeriodRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormFiscalPeriodRepository creates a new instance of GormFiscalPeriodRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormFiscalPeriodRepository(db *gorm.DB) *GormFiscalPeriodRepository {
+	return &GormFiscalPeriodRepository{db: db}
+}
+
+// CloseFiscalYear persists the closing of a fiscal year and its log entry in a single database transaction.
+func (r *GormFiscalPeriodRepository) CloseFiscalYear(closure *models.FiscalPeriodClosure, event *models.FiscalPeriodEvent) error {
+	closureDB := toFiscalPeriodClosureDB(closure)
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(closureDB).Error; err != nil {
+			return err
+		}
+		return tx.Create(toFiscalPeriodEventDB(event)).Error
+	}); err != nil {
+		return err
+	}
+	*closure = *toFiscalPeriodClosure(closureDB) // Update the original closure with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// ReopenFiscalYear removes the closing of a fiscal year and records the reopening in the log, in a
+// single database transaction. The closure is deleted permanently so that the year can be closed again.
+func (r *GormFiscalPeriodRepository) ReopenFiscalYear(closureID uint, event *models.FiscalPeriodEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&FiscalPeriodClosureDB{}, closureID).Error; err != nil {
+			return err
+		}
+		return tx.Create(toFiscalPeriodEventDB(event)).Error
+	})
+}
+
+// FindClosuresByUserID retrieves the closed fiscal years of an association, most recent first.
+func (r *GormFiscalPeriodRepository) FindClosuresByUserID(userID uint) ([]models.FiscalPeriodClosure, error) {
+	var closuresDB []FiscalPeriodClosureDB
+	if err := r.db.Where("user_id = ?", userID).Order("start_date DESC").Find(&closuresDB).Error; err != nil {
+		return nil, err
+	}
+	closures := make([]models.FiscalPeriodClosure, len(closuresDB))
+	for i, closureDB := range closuresDB {
+		closures[i] = *toFiscalPeriodClosure(&closureDB)
+	}
+	return closures, nil
+}
+
+// FindClosureCovering retrieves the closed period a date falls within, or nil if the date is in an open period.
+func (r *GormFiscalPeriodRepository) FindClosureCovering(userID uint, date time.Time) (*models.FiscalPeriodClosure, error) {
+	var closureDB FiscalPeriodClosureDB
+	err := r.db.Where("user_id = ? AND start_date <= ? AND end_date > ?", userID, date, date).First(&closureDB).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toFiscalPeriodClosure(&closureDB), nil
+}
+
+// FindEventsByUserID retrieves the closing log of an association, most recent first.
+func (r *GormFiscalPeriodRepository) FindEventsByUserID(userID uint) ([]models.FiscalPeriodEvent, error) {
+	var eventsDB []FiscalPeriodEventDB
+	if err := r.db.Where("user_id = ?", userID).Order("at DESC, id DESC").Find(&eventsDB).Error; err != nil {
+		return nil, err
+	}
+	events := make([]models.FiscalPeriodEvent, len(eventsDB))
+	for i, eventDB := range eventsDB {
+		events[i] = models.FiscalPeriodEvent{
+			Model:      eventDB.Model,
+			UserID:     eventDB.UserID,
+			FiscalYear: eventDB.FiscalYear,
+			Label:      eventDB.Label,
+			Action:     eventDB.Action,
+			ActorID:    eventDB.ActorID,
+			ActorName:  eventDB.ActorName,
+			Reason:     eventDB.Reason,
+			At:         eventDB.At,
+		}
+	}
+	return events, nil
+}
+
+// toFiscalPeriodClosureDB converts a models.FiscalPeriodClosure to a FiscalPeriodClosureDB.
+func toFiscalPeriodClosureDB(c *models.FiscalPeriodClosure) *FiscalPeriodClosureDB {
+	return &FiscalPeriodClosureDB{
+		Model:        c.Model,
+		UserID:       c.UserID,
+		FiscalYear:   c.FiscalYear,
+		Label:        c.Label,
+		StartDate:    c.StartDate,
+		EndDate:      c.EndDate,
+		ClosedAt:     c.ClosedAt,
+		ClosedByName: c.ClosedByName,
+	}
+}
+
+// toFiscalPeriodClosure converts a FiscalPeriodClosureDB to a models.FiscalPeriodClosure.
+func toFiscalPeriodClosure(cdb *FiscalPeriodClosureDB) *models.FiscalPeriodClosure {
+	return &models.FiscalPeriodClosure{
+		Model:        cdb.Model,
+		UserID:       cdb.UserID,
+		FiscalYear:   cdb.FiscalYear,
+		Label:        cdb.Label,
+		StartDate:    cdb.StartDate,
+		EndDate:      cdb.EndDate,
+		ClosedAt:     cdb.ClosedAt,
+		ClosedByName: cdb.ClosedByName,
+	}
+}
+
+// toFiscalPeriodEventDB converts a models.FiscalPeriodEvent to a FiscalPeriodEventDB.
+func toFiscalPeriodEventDB(e *models.FiscalPeriodEvent) *FiscalPeriodEventDB {
+	return &FiscalPeriodEventDB{
+		Model:      e.Model,
+		UserID:     e.UserID,
+		FiscalYear: e.FiscalYear,
+		Label:      e.Label,
+		Action:     e.Action,
+		ActorID:    e.ActorID,
+		ActorName:  e.ActorName,
+		Reason:     e.Reason,
+		At:         e.At,
+	}
+}
@@ -0,0 +1,245 @@
+package repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// InvoiceDB represents the database model for an invoice or a donation receipt, used for GORM persistence.
+// The unique index on (user, kind, fiscal year, sequence) guarantees that a number is never issued twice.
+type InvoiceDB struct {
+	gorm.Model
+	UserID     uint                 `gorm:"uniqueIndex:idx_invoice_number"` // Foreign key linking to the User who issued the invoice.
+	Kind       models.InvoiceKind   `gorm:"uniqueIndex:idx_invoice_number"` // Invoice or donation receipt.
+	FiscalYear int                  `gorm:"uniqueIndex:idx_invoice_number"` // Fiscal year the invoice is numbered in.
+	Sequence   int                  `gorm:"uniqueIndex:idx_invoice_number"` // Position in the numbering sequence.
+	Number     string               // Full invoice number.
+	Status     models.InvoiceStatus `gorm:"index"` // Issued or cancelled.
+	IssueDate  time.Time            // Date of issue.
+
+	MemberID         uint   `gorm:"index"` // Member the invoice is addressed to.
+	RecipientName    string // Name of the member at the time of issue.
+	RecipientAddress string // Address of the member at the time of issue.
+
+	Description   string          // Free description.
+	AmountMinor   int64           // Total amount in minor units of Currency.
+	Currency      string          `gorm:"size:3"` // Base currency of the association.
+	PaymentMethod string          // How the income was paid.
+	Lines         []InvoiceLineDB `gorm:"foreignKey:InvoiceID"` // Transactions covered by the invoice.
+
+	CancelledAt *time.Time // Date of cancellation, if any.
+}
+
+// InvoiceLineDB represents the database model for a transaction covered by an invoice.
+type InvoiceLineDB struct {
+	gorm.Model
+	InvoiceID     uint      `gorm:"index"` // The invoice this line belongs to.
+	TransactionID uint      `gorm:"index"` // The income transaction covered.
+	Date          time.Time // Date of the transaction.
+	Description   string    // Description of the transaction.
+	Amount        int64     // Amount in minor units of the base currency.
+}
+
+// TableName specifies the table name for the InvoiceDB model.
+func (InvoiceDB) TableName() string {
+	return "invoices"
+}
+
+// TableName specifies the table name for the InvoiceLineDB model.
+func (InvoiceLineDB) TableName() string {
+	return "invoice_lines"
+}
+
+// InvoiceRepository defines the interface for invoice persistence operations.
+// It abstracts the underlying database implementation.
+type InvoiceRepository interface {
+	CreateInvoice(invoice *models.Invoice) error
+	FindInvoiceByID(id uint) (*models.Invoice, error)
+	FindInvoicesByUserID(userID uint, kind models.InvoiceKind, fiscalYear int) ([]models.Invoice, error)
+	FindInvoicedTransactions(userID uint) (map[uint]string, error)
+	CancelInvoice(id uint, at time.Time) error
+}
+
+// GormInvoiceRepository is an implementation of InvoiceRepository that uses GORM
+// for interacting with a relational database.
+type GormInvoiceRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormInvoiceRepository creates a new instance of GormInvoiceRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormInvoiceRepository(db *gorm.DB) *GormInvoiceRepository {
+	return &GormInvoiceRepository{db: db}
+}
+
+// CreateInvoice numbers and persists a new invoice with its lines in a single database transaction.
+// The sequence is the next one of the invoice's kind and fiscal year, so that numbers have no gap;
+// the invoice is rejected if one of its transactions is already covered by an issued invoice.
+func (r *GormInvoiceRepository) CreateInvoice(invoice *models.Invoice) error {
+	invoiceDB := toInvoiceDB(invoice)
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		transactionIDs := make([]uint, len(invoiceDB.Lines))
+		for i, line := range invoiceDB.Lines {
+			transactionIDs[i] = line.TransactionID
+		}
+		var numbers []string
+		if err := tx.Model(&InvoiceLineDB{}).
+			Joins("JOIN invoices ON invoices.id = invoice_lines.invoice_id").
+			Where("invoices.user_id = ? AND invoices.status = ? AND invoices.deleted_at IS NULL AND invoice_lines.transaction_id IN ?", invoiceDB.UserID, models.InvoiceIssued, transactionIDs).
+			Limit(1).Pluck("invoices.number", &numbers).Error; err != nil {
+			return err
+		}
+		if len(numbers) > 0 {
+			return fmt.Errorf("une transaction est déjà couverte par le document %s", numbers[0])
+		}
+
+		var last int
+		if err := tx.Model(&InvoiceDB{}).Unscoped().
+			Where("user_id = ? AND kind = ? AND fiscal_year = ?", invoiceDB.UserID, invoiceDB.Kind, invoiceDB.FiscalYear).
+			Select("coalesce(max(sequence), 0)").Row().Scan(&last); err != nil {
+			return err
+		}
+		invoiceDB.Sequence = last + 1
+		invoiceDB.Number = models.InvoiceNumber(invoiceDB.Kind, invoiceDB.FiscalYear, invoiceDB.Sequence)
+		return tx.Create(invoiceDB).Error
+	}); err != nil {
+		return err
+	}
+	*invoice = *toInvoice(invoiceDB) // Update the original invoice with DB-generated fields (e.g., ID, number)
+	return nil
+}
+
+// FindInvoiceByID retrieves an invoice and its lines by its ID.
+func (r *GormInvoiceRepository) FindInvoiceByID(id uint) (*models.Invoice, error) {
+	var invoiceDB InvoiceDB
+	if err := r.db.Preload("Lines").First(&invoiceDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toInvoice(&invoiceDB), nil
+}
+
+// FindInvoicesByUserID retrieves the invoices of a user with their lines, in numbering order.
+// An empty kind or a zero fiscal year does not filter on that field.
+func (r *GormInvoiceRepository) FindInvoicesByUserID(userID uint, kind models.InvoiceKind, fiscalYear int) ([]models.Invoice, error) {
+	var invoicesDB []InvoiceDB
+	query := r.db.Preload("Lines").Where("user_id = ?", userID)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if fiscalYear != 0 {
+		query = query.Where("fiscal_year = ?", fiscalYear)
+	}
+	if err := query.Order("fiscal_year DESC, kind, sequence").Find(&invoicesDB).Error; err != nil {
+		return nil, err
+	}
+	var invoices []models.Invoice
+	for _, idb := range invoicesDB {
+		invoices = append(invoices, *toInvoice(&idb))
+	}
+	return invoices, nil
+}
+
+// FindInvoicedTransactions returns the number of the issued invoice covering each invoiced
+// transaction of a user, keyed by transaction ID. Cancelled invoices are ignored.
+func (r *GormInvoiceRepository) FindInvoicedTransactions(userID uint) (map[uint]string, error) {
+	var rows []struct {
+		TransactionID uint
+		Number        string
+	}
+	if err := r.db.Model(&InvoiceLineDB{}).
+		Select("invoice_lines.transaction_id, invoices.number").
+		Joins("JOIN invoices ON invoices.id = invoice_lines.invoice_id").
+		Where("invoices.user_id = ? AND invoices.status = ? AND invoices.deleted_at IS NULL", userID, models.InvoiceIssued).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	invoiced := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		invoiced[row.TransactionID] = row.Number
+	}
+	return invoiced, nil
+}
+
+// CancelInvoice marks an invoice as cancelled. The invoice and its number are kept.
+func (r *GormInvoiceRepository) CancelInvoice(id uint, at time.Time) error {
+	return r.db.Model(&InvoiceDB{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       models.InvoiceCancelled,
+		"cancelled_at": at,
+	}).Error
+}
+
+// toInvoiceDB converts a domain Invoice model to a database-specific InvoiceDB model.
+func toInvoiceDB(i *models.Invoice) *InvoiceDB {
+	lines := make([]InvoiceLineDB, len(i.Lines))
+	for n, line := range i.Lines {
+		lines[n] = InvoiceLineDB{
+			Model:         gorm.Model{ID: line.ID, CreatedAt: line.CreatedAt, UpdatedAt: line.UpdatedAt, DeletedAt: line.DeletedAt},
+			InvoiceID:     line.InvoiceID,
+			TransactionID: line.TransactionID,
+			Date:          line.Date,
+			Description:   line.Description,
+			Amount:        line.Amount,
+		}
+	}
+	return &InvoiceDB{
+		Model:      gorm.Model{ID: i.ID, CreatedAt: i.CreatedAt, UpdatedAt: i.UpdatedAt, DeletedAt: i.DeletedAt},
+		UserID:     i.UserID,
+		Kind:       i.Kind,
+		FiscalYear: i.FiscalYear,
+		Sequence:   i.Sequence,
+		Number:     i.Number,
+		Status:     i.Status,
+		IssueDate:  i.IssueDate,
+
+		MemberID:         i.MemberID,
+		RecipientName:    i.RecipientName,
+		RecipientAddress: i.RecipientAddress,
+
+		Description:   i.Description,
+		AmountMinor:   i.Amount.Amount,
+		Currency:      i.Amount.Currency,
+		PaymentMethod: i.PaymentMethod,
+		Lines:         lines,
+
+		CancelledAt: i.CancelledAt,
+	}
+}
+
+// toInvoice converts a database-specific InvoiceDB model back to a domain Invoice model.
+func toInvoice(idb *InvoiceDB) *models.Invoice {
+	lines := make([]models.InvoiceLine, len(idb.Lines))
+	for n, line := range idb.Lines {
+		lines[n] = models.InvoiceLine{
+			Model:         gorm.Model{ID: line.ID, CreatedAt: line.CreatedAt, UpdatedAt: line.UpdatedAt, DeletedAt: line.DeletedAt},
+			InvoiceID:     line.InvoiceID,
+			TransactionID: line.TransactionID,
+			Date:          line.Date,
+			Description:   line.Description,
+			Amount:        line.Amount,
+		}
+	}
+	return &models.Invoice{
+		Model:      gorm.Model{ID: idb.ID, CreatedAt: idb.CreatedAt, UpdatedAt: idb.UpdatedAt, DeletedAt: idb.DeletedAt},
+		UserID:     idb.UserID,
+		Kind:       idb.Kind,
+		FiscalYear: idb.FiscalYear,
+		Sequence:   idb.Sequence,
+		Number:     idb.Number,
+		Status:     idb.Status,
+		IssueDate:  idb.IssueDate,
+
+		MemberID:         idb.MemberID,
+		RecipientName:    idb.RecipientName,
+		RecipientAddress: idb.RecipientAddress,
+
+		Description:   idb.Description,
+		Amount:        models.Money{Amount: idb.AmountMinor, Currency: idb.Currency},
+		PaymentMethod: idb.PaymentMethod,
+		Lines:         lines,
+
+		CancelledAt: idb.CancelledAt,
+	}
+}
@@ -0,0 +1,243 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// AccountDB represents the database model for an account of the chart of accounts, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type AccountDB struct {
+	gorm.Model
+	Code   string             `gorm:"index"` // The account number.
+	Name   string             // The label of the account.
+	Type   models.AccountType // The class of the account.
+	UserID uint               `gorm:"index"` // Foreign key linking to the User who owns this chart of accounts.
+}
+
+// JournalEntryDB represents the database model for a journal entry, used for GORM persistence.
+// It has a one-to-many relationship with JournalLineDB.
+type JournalEntryDB struct {
+	gorm.Model
+	Date          time.Time       `gorm:"index"` // The accounting date of the entry.
+	Description   string          // The label of the entry.
+	Reference     string          // An optional piece reference.
+	UserID        uint            `gorm:"index"`                     // Foreign key linking to the User who owns this entry.
+	TransactionID *uint           `gorm:"index"`                     // The simple transaction this entry was generated from, if any.
+	Lines         []JournalLineDB `gorm:"foreignKey:JournalEntryID"` // The debit and credit lines of the entry.
+}
+
+// JournalLineDB represents the database model for a journal line, used for GORM persistence.
+type JournalLineDB struct {
+	gorm.Model
+	JournalEntryID uint   `gorm:"index"` // The entry this line belongs to.
+	AccountCode    string `gorm:"index"` // The code of the account debited or credited.
+	Label          string // An optional line-level label.
+	Debit          int64  // The debit amount in cents.
+	Credit         int64  // The credit amount in cents.
+}
+
+// TableName specifies the table name for the AccountDB model.
+func (AccountDB) TableName() string {
+	return "accounts"
+}
+
+// TableName specifies the table name for the JournalEntryDB model.
+func (JournalEntryDB) TableName() string {
+	return "journal_entries"
+}
+
+// TableName specifies the table name for the JournalLineDB model.
+func (JournalLineDB) TableName() string {
+	return "journal_lines"
+}
+
+// AccountTotals holds the debit and credit totals of an account, as computed by the database.
+type AccountTotals struct {
+	AccountCode string
+	Debit       int64
+	Credit      int64
+}
+
+// LedgerRepository defines the interface for chart of accounts and journal persistence operations.
+// It abstracts the underlying database implementation.
+type LedgerRepository interface {
+	CreateAccount(account *models.Account) error
+	FindAccountsByUserID(userID uint) ([]models.Account, error)
+	CreateJournalEntry(entry *models.JournalEntry) error
+	FindJournalEntries(userID uint, from, to time.Time) ([]models.JournalEntry, error)
+	DeleteJournalEntriesByTransactionID(transactionID uint) error
+	GetAccountTotals(userID uint, from, to time.Time) ([]AccountTotals, error)
+	FindTransactionIDsWithoutEntry() ([]uint, error)
+}
+
+// GormLedgerRepository is an implementation of LedgerRepository that uses GORM
+// for interacting with a relational database.
+type GormLedgerRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormLedgerRepository creates a new instance of GormLedgerRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormLedgerRepository(db *gorm.DB) *GormLedgerRepository {
+	return &GormLedgerRepository{db: db}
+}
+
+// CreateAccount persists a new account to the database.
+func (r *GormLedgerRepository) CreateAccount(account *models.Account) error {
+	accountDB := toAccountDB(account)
+	if err := r.db.Create(&accountDB).Error; err != nil {
+		return err
+	}
+	*account = *toAccount(accountDB) // Update the original account with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindAccountsByUserID retrieves the chart of accounts of a user, ordered by account code.
+func (r *GormLedgerRepository) FindAccountsByUserID(userID uint) ([]models.Account, error) {
+	var accountsDB []AccountDB
+	if err := r.db.Where("user_id = ?", userID).Order("code").Find(&accountsDB).Error; err != nil {
+		return nil, err
+	}
+	var accounts []models.Account
+	for _, adb := range accountsDB {
+		accounts = append(accounts, *toAccount(&adb))
+	}
+	return accounts, nil
+}
+
+// CreateJournalEntry persists a journal entry and its lines in a single database transaction,
+// so that an entry is never stored partially.
+func (r *GormLedgerRepository) CreateJournalEntry(entry *models.JournalEntry) error {
+	entryDB := toJournalEntryDB(entry)
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&entryDB).Error
+	}); err != nil {
+		return err
+	}
+	*entry = *toJournalEntry(entryDB) // Update the original entry with DB-generated fields (e.g., IDs)
+	return nil
+}
+
+// FindJournalEntries retrieves the entries of a user dated within [from, to], with their lines,
+// in chronological order.
+func (r *GormLedgerRepository) FindJournalEntries(userID uint, from, to time.Time) ([]models.JournalEntry, error) {
+	var entriesDB []JournalEntryDB
+	if err := r.db.Preload("Lines").Where("user_id = ? AND date >= ? AND date <= ?", userID, from, to).Order("date, id").Find(&entriesDB).Error; err != nil {
+		return nil, err
+	}
+	var entries []models.JournalEntry
+	for _, edb := range entriesDB {
+		entries = append(entries, *toJournalEntry(&edb))
+	}
+	return entries, nil
+}
+
+// DeleteJournalEntriesByTransactionID deletes the entries (and their lines) generated from a transaction.
+func (r *GormLedgerRepository) DeleteJournalEntriesByTransactionID(transactionID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("journal_entry_id IN (SELECT id FROM journal_entries WHERE transaction_id = ?)", transactionID).Delete(&JournalLineDB{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("transaction_id = ?", transactionID).Delete(&JournalEntryDB{}).Error
+	})
+}
+
+// GetAccountTotals returns the debit and credit totals per account for the entries of a user
+// dated within [from, to]. Summing integer cents in SQL keeps the totals exact.
+func (r *GormLedgerRepository) GetAccountTotals(userID uint, from, to time.Time) ([]AccountTotals, error) {
+	var totals []AccountTotals
+	err := r.db.Model(&JournalLineDB{}).
+		Select("journal_lines.account_code AS account_code, coalesce(sum(journal_lines.debit), 0) AS debit, coalesce(sum(journal_lines.credit), 0) AS credit").
+		Joins("JOIN journal_entries ON journal_entries.id = journal_lines.journal_entry_id AND journal_entries.deleted_at IS NULL").
+		Where("journal_entries.user_id = ? AND journal_entries.date >= ? AND journal_entries.date <= ?", userID, from, to).
+		Group("journal_lines.account_code").
+		Order("journal_lines.account_code").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// FindTransactionIDsWithoutEntry returns the IDs of posted transactions that have no journal entry yet,
+// e.g., transactions recorded before the journal was introduced.
+func (r *GormLedgerRepository) FindTransactionIDsWithoutEntry() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&TransactionDB{}).
+		Where("status = ? AND id NOT IN (SELECT transaction_id FROM journal_entries WHERE transaction_id IS NOT NULL AND deleted_at IS NULL)", models.TransactionPosted).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// toAccountDB converts a domain Account model to a database-specific AccountDB model.
+func toAccountDB(a *models.Account) *AccountDB {
+	return &AccountDB{
+		Model:  gorm.Model{ID: a.ID, CreatedAt: a.CreatedAt, UpdatedAt: a.UpdatedAt, DeletedAt: a.DeletedAt},
+		Code:   a.Code,
+		Name:   a.Name,
+		Type:   a.Type,
+		UserID: a.UserID,
+	}
+}
+
+// toAccount converts a database-specific AccountDB model back to a domain Account model.
+func toAccount(adb *AccountDB) *models.Account {
+	return &models.Account{
+		Model:  gorm.Model{ID: adb.ID, CreatedAt: adb.CreatedAt, UpdatedAt: adb.UpdatedAt, DeletedAt: adb.DeletedAt},
+		Code:   adb.Code,
+		Name:   adb.Name,
+		Type:   adb.Type,
+		UserID: adb.UserID,
+	}
+}
+
+// toJournalEntryDB converts a domain JournalEntry model to a database-specific JournalEntryDB model,
+// including its lines.
+func toJournalEntryDB(e *models.JournalEntry) *JournalEntryDB {
+	entryDB := &JournalEntryDB{
+		Model:         gorm.Model{ID: e.ID, CreatedAt: e.CreatedAt, UpdatedAt: e.UpdatedAt, DeletedAt: e.DeletedAt},
+		Date:          e.Date,
+		Description:   e.Description,
+		Reference:     e.Reference,
+		UserID:        e.UserID,
+		TransactionID: e.TransactionID,
+	}
+	for _, line := range e.Lines {
+		entryDB.Lines = append(entryDB.Lines, JournalLineDB{
+			Model:          gorm.Model{ID: line.ID, CreatedAt: line.CreatedAt, UpdatedAt: line.UpdatedAt, DeletedAt: line.DeletedAt},
+			JournalEntryID: line.JournalEntryID,
+			AccountCode:    line.AccountCode,
+			Label:          line.Label,
+			Debit:          line.Debit,
+			Credit:         line.Credit,
+		})
+	}
+	return entryDB
+}
+
+// toJournalEntry converts a database-specific JournalEntryDB model back to a domain JournalEntry model,
+// including its lines.
+func toJournalEntry(edb *JournalEntryDB) *models.JournalEntry {
+	entry := &models.JournalEntry{
+		Model:         gorm.Model{ID: edb.ID, CreatedAt: edb.CreatedAt, UpdatedAt: edb.UpdatedAt, DeletedAt: edb.DeletedAt},
+		Date:          edb.Date,
+		Description:   edb.Description,
+		Reference:     edb.Reference,
+		UserID:        edb.UserID,
+		TransactionID: edb.TransactionID,
+	}
+	for _, ldb := range edb.Lines {
+		entry.Lines = append(entry.Lines, models.JournalLine{
+			Model:          gorm.Model{ID: ldb.ID, CreatedAt: ldb.CreatedAt, UpdatedAt: ldb.UpdatedAt, DeletedAt: ldb.DeletedAt},
+			JournalEntryID: ldb.JournalEntryID,
+			AccountCode:    ldb.AccountCode,
+			Label:          ldb.Label,
+			Debit:          ldb.Debit,
+			Credit:         ldb.Credit,
+		})
+	}
+	return entry
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"strings"
 	"time"
 
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
@@ -14,6 +15,8 @@ type MemberDB struct {
 	FirstName        string                // First name of the member
 	LastName         string                // Last name of the member
 	Email            string                // Email address of the member
+	Address          string                // Postal address of the member
+	BoardRole        string                // Role of the member on the board, empty outside the board
 	UserID           uint                  // Foreign key linking to the User who owns this member record
 	MembershipStatus models.MembershipStatus // Current status of the member's membership
 	JoinDate         time.Time             // Date when the member joined
@@ -34,6 +37,7 @@ type MemberRepository interface {
 	CreateMember(member *models.Member) error
 	FindMemberByID(id uint) (*models.Member, error)
 	FindMembersByUserID(userID uint) ([]models.Member, error)
+	FindMembersByEmail(email string) ([]models.Member, error)
 	UpdateMember(member *models.Member) error
 	DeleteMember(id uint) error
 	UpdateLastPaymentDate(memberID uint, date time.Time) error
@@ -90,6 +94,20 @@ func (r *GormMemberRepository) FindMembersByUserID(userID uint) ([]models.Member
 	return members, nil
 }
 
+// FindMembersByEmail retrieves the member records with the given email address, in all the
+// associations, whatever their status. The email is compared case-insensitively.
+func (r *GormMemberRepository) FindMembersByEmail(email string) ([]models.Member, error) {
+	var membersDB []MemberDB
+	if err := r.db.Where("LOWER(email) = ?", strings.ToLower(email)).Order("user_id").Find(&membersDB).Error; err != nil {
+		return nil, err
+	}
+	members := make([]models.Member, len(membersDB))
+	for i := range membersDB {
+		members[i] = *toMember(&membersDB[i])
+	}
+	return members, nil
+}
+
 // UpdateMember updates an existing member in the database.
 // It converts the domain model to a database model and saves the changes.
 func (r *GormMemberRepository) UpdateMember(member *models.Member) error {
@@ -155,6 +173,8 @@ func toMemberDB(m *models.Member) *MemberDB {
 		FirstName:        m.FirstName,
 		LastName:         m.LastName,
 		Email:            m.Email,
+		Address:          m.Address,
+		BoardRole:        m.BoardRole,
 		UserID:           m.UserID,
 		MembershipStatus: m.MembershipStatus,
 		JoinDate:         m.JoinDate,
@@ -171,6 +191,8 @@ func toMember(mdb *MemberDB) *models.Member {
 		FirstName:        mdb.FirstName,
 		LastName:         mdb.LastName,
 		Email:            mdb.Email,
+		Address:          mdb.Address,
+		BoardRole:        mdb.BoardRole,
 		UserID:           mdb.UserID,
 		MembershipStatus: mdb.MembershipStatus,
 		JoinDate:         mdb.JoinDate,
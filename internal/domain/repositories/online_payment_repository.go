@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OnlinePaymentDB represents the database model for an online payment, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type OnlinePaymentDB struct {
+	gorm.Model
+	UserID              uint                       `gorm:"index"`
+	MemberID            uint                       `gorm:"index"`
+	EventID             *uint                      // The event the fee is for, if any.
+	Purpose             models.PaymentPurpose      // Dues or event fee.
+	Description         string                     // Label shown to the member.
+	AmountMinor         int64                      // Amount in minor units of Currency.
+	Currency            string                     `gorm:"size:3"` // ISO 4217 currency of the amount.
+	Status              models.OnlinePaymentStatus `gorm:"index"`
+	Token               string                     `gorm:"uniqueIndex"` // Secret identifying the public payment page.
+	Provider            string                     // Name of the provider that handled the checkout.
+	SessionID           string                     `gorm:"index"` // Provider identifier of the last checkout session.
+	Attempts            int                        // Number of checkout sessions opened.
+	PaymentRef          string                     // Provider identifier of the captured payment.
+	TransactionID       *uint                      // Income recorded when the payment succeeded.
+	PaidAt              *time.Time
+	RefundTransactionID *uint // Expense recorded when the payment was refunded.
+	RefundedAt          *time.Time
+}
+
+// TableName specifies the table name for the OnlinePaymentDB model.
+func (OnlinePaymentDB) TableName() string {
+	return "online_payments"
+}
+
+// PaymentWebhookEventDB records a webhook event already processed, so that the deliveries a
+// provider retries are acknowledged without being processed twice.
+type PaymentWebhookEventDB struct {
+	ID         uint   `gorm:"primarykey"`
+	Provider   string `gorm:"uniqueIndex:idx_payment_webhook_event"`
+	EventID    string `gorm:"uniqueIndex:idx_payment_webhook_event"`
+	Type       string
+	ReceivedAt time.Time
+}
+
+// TableName specifies the table name for the PaymentWebhookEventDB model.
+func (PaymentWebhookEventDB) TableName() string {
+	return "payment_webhook_events"
+}
+
+// OnlinePaymentRepository defines the interface for online payment persistence operations.
+// It abstracts the underlying database implementation.
+type OnlinePaymentRepository interface {
+	CreatePayment(payment *models.OnlinePayment) error
+	FindPaymentByID(id uint) (*models.OnlinePayment, error)
+	FindPaymentByToken(token string) (*models.OnlinePayment, error)
+	FindPaymentBySessionID(sessionID string) (*models.OnlinePayment, error)
+	FindPaymentsByUserID(userID uint) ([]models.OnlinePayment, error)
+	UpdatePayment(payment *models.OnlinePayment) error
+	TransitionPayment(id uint, from, to models.OnlinePaymentStatus) (bool, error)
+	IsWebhookEventProcessed(provider, eventID string) (bool, error)
+	RecordWebhookEvent(provider, eventID, eventType string) error
+}
+
+// GormOnlinePaymentRepository is an implementation of OnlinePaymentRepository that uses GORM
+// for interacting with a relational database.
+type GormOnlinePaymentRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormOnlinePaymentRepository creates a new instance of GormOnlinePaymentRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormOnlinePaymentRepository(db *gorm.DB) *GormOnlinePaymentRepository {
+	return &GormOnlinePaymentRepository{db: db}
+}
+
+// CreatePayment persists a new online payment.
+func (r *GormOnlinePaymentRepository) CreatePayment(payment *models.OnlinePayment) error {
+	paymentDB := toOnlinePaymentDB(payment)
+	if err := r.db.Create(paymentDB).Error; err != nil {
+		return err
+	}
+	*payment = *toOnlinePayment(paymentDB) // Update the original payment with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindPaymentByID retrieves an online payment by its ID.
+func (r *GormOnlinePaymentRepository) FindPaymentByID(id uint) (*models.OnlinePayment, error) {
+	return r.findPayment("id = ?", id)
+}
+
+// FindPaymentByToken retrieves an online payment by the token of its public page.
+func (r *GormOnlinePaymentRepository) FindPaymentByToken(token string) (*models.OnlinePayment, error) {
+	return r.findPayment("token = ?", token)
+}
+
+// FindPaymentBySessionID retrieves an online payment by the provider identifier of its checkout session.
+func (r *GormOnlinePaymentRepository) FindPaymentBySessionID(sessionID string) (*models.OnlinePayment, error) {
+	return r.findPayment("session_id = ?", sessionID)
+}
+
+// FindPaymentsByUserID retrieves the online payments of an association, most recent first.
+func (r *GormOnlinePaymentRepository) FindPaymentsByUserID(userID uint) ([]models.OnlinePayment, error) {
+	var paymentsDB []OnlinePaymentDB
+	if err := r.db.Where("user_id = ?", userID).Order("created_at desc, id desc").Find(&paymentsDB).Error; err != nil {
+		return nil, err
+	}
+	payments := make([]models.OnlinePayment, len(paymentsDB))
+	for i, paymentDB := range paymentsDB {
+		payments[i] = *toOnlinePayment(&paymentDB)
+	}
+	return payments, nil
+}
+
+// UpdatePayment saves the changes made to an online payment.
+func (r *GormOnlinePaymentRepository) UpdatePayment(payment *models.OnlinePayment) error {
+	return r.db.Save(toOnlinePaymentDB(payment)).Error
+}
+
+// TransitionPayment moves a payment from one status to another only if it is still in the first
+// one, and reports whether it did. Concurrent webhook deliveries thus cannot both process a payment.
+func (r *GormOnlinePaymentRepository) TransitionPayment(id uint, from, to models.OnlinePaymentStatus) (bool, error) {
+	result := r.db.Model(&OnlinePaymentDB{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	return result.RowsAffected == 1, result.Error
+}
+
+// IsWebhookEventProcessed reports whether a webhook event of a provider was already processed.
+func (r *GormOnlinePaymentRepository) IsWebhookEventProcessed(provider, eventID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&PaymentWebhookEventDB{}).Where("provider = ? AND event_id = ?", provider, eventID).Count(&count).Error
+	return count > 0, err
+}
+
+// RecordWebhookEvent marks a webhook event of a provider as processed. Recording it twice is harmless.
+func (r *GormOnlinePaymentRepository) RecordWebhookEvent(provider, eventID, eventType string) error {
+	event := PaymentWebhookEventDB{Provider: provider, EventID: eventID, Type: eventType, ReceivedAt: time.Now()}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&event).Error
+}
+
+// findPayment retrieves the online payment matching a condition.
+func (r *GormOnlinePaymentRepository) findPayment(query string, arg any) (*models.OnlinePayment, error) {
+	var paymentDB OnlinePaymentDB
+	if err := r.db.Where(query, arg).First(&paymentDB).Error; err != nil {
+		return nil, err
+	}
+	return toOnlinePayment(&paymentDB), nil
+}
+
+// toOnlinePaymentDB converts a models.OnlinePayment to an OnlinePaymentDB.
+func toOnlinePaymentDB(p *models.OnlinePayment) *OnlinePaymentDB {
+	return &OnlinePaymentDB{
+		Model:               p.Model,
+		UserID:              p.UserID,
+		MemberID:            p.MemberID,
+		EventID:             p.EventID,
+		Purpose:             p.Purpose,
+		Description:         p.Description,
+		AmountMinor:         p.Amount.Amount,
+		Currency:            p.Amount.Currency,
+		Status:              p.Status,
+		Token:               p.Token,
+		Provider:            p.Provider,
+		SessionID:           p.SessionID,
+		Attempts:            p.Attempts,
+		PaymentRef:          p.PaymentRef,
+		TransactionID:       p.TransactionID,
+		PaidAt:              p.PaidAt,
+		RefundTransactionID: p.RefundTransactionID,
+		RefundedAt:          p.RefundedAt,
+	}
+}
+
+// toOnlinePayment converts an OnlinePaymentDB to a models.OnlinePayment.
+func toOnlinePayment(p *OnlinePaymentDB) *models.OnlinePayment {
+	return &models.OnlinePayment{
+		Model:               p.Model,
+		UserID:              p.UserID,
+		MemberID:            p.MemberID,
+		EventID:             p.EventID,
+		Purpose:             p.Purpose,
+		Description:         p.Description,
+		Amount:              models.Money{Amount: p.AmountMinor, Currency: p.Currency},
+		Status:              p.Status,
+		Token:               p.Token,
+		Provider:            p.Provider,
+		SessionID:           p.SessionID,
+		Attempts:            p.Attempts,
+		PaymentRef:          p.PaymentRef,
+		TransactionID:       p.TransactionID,
+		PaidAt:              p.PaidAt,
+		RefundTransactionID: p.RefundTransactionID,
+		RefundedAt:          p.RefundedAt,
+	}
+}
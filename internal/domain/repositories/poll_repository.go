@@ -1,17 +1,31 @@
 package repositories
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // PollDB represents the database model for a poll, used for GORM persistence.
 // It includes GORM's Model for common fields and has a one-to-many relationship with OptionDB.
 type PollDB struct {
 	gorm.Model
-	Question string     // The question of the poll.
-	UserID   uint       // The ID of the user who created the poll.
-	Options  []OptionDB `gorm:"foreignKey:PollID"` // Associated options for this poll.
+	Question          string     // The question of the poll.
+	UserID            uint       // The ID of the user who created the poll.
+	Options           []OptionDB `gorm:"foreignKey:PollID"` // Associated options for this poll.
+	Status            string     // The state set by the creator (draft, open or closed).
+	OpensAt           *time.Time // When a draft opens by itself, or when the poll was opened.
+	ClosesAt          *time.Time // When the poll closes by itself, or when it was closed.
+	ResultsVisibility string     // When the voters may see the results.
+	Type              string     // How the voters choose among the options.
+	MinChoices        int        // The fewest options a ballot of a multiple choice poll chooses.
+	MaxChoices        int        // The most options a ballot of a multiple choice poll chooses.
+	Anonymous         bool       // Whether the poll is a secret ballot.
+	AssemblyID        *uint      `gorm:"index"` // The general assembly the poll is a resolution of, if any.
 }
 
 // OptionDB represents the database model for a poll option, used for GORM persistence.
@@ -29,6 +43,30 @@ type VoteDB struct {
 	gorm.Model
 	OptionID uint // The ID of the option that was voted for.
 	UserID   uint // The ID of the user who cast the vote.
+	Rank     int  // The rank given to the option in a ranked poll; 0 in the other polls.
+	Weight   int  // The votes the ballot carries; 0 for the votes cast before votes were weighted.
+}
+
+// PollParticipationDB records that a user voted in a poll, apart from the ballot cast: for a
+// secret poll, nothing links a participation to its ballot. Its unique index keeps a user from
+// voting twice in a poll, even through concurrent requests.
+type PollParticipationDB struct {
+	ID      uint      `gorm:"primarykey"`
+	PollID  uint      `gorm:"uniqueIndex:idx_poll_participation"`
+	UserID  uint      `gorm:"uniqueIndex:idx_poll_participation"`
+	VotedAt time.Time // When the user voted.
+}
+
+// SecretBallotDB represents the database model for a vote of a secret poll. It has neither voter
+// nor timestamp, and is keyed by the hash of its random receipt code rather than by a sequence,
+// so that it cannot be matched with the participation of its voter. The table is stored without
+// rowid (see database.MigrateSecretBallots), so that the order of insertion is not kept either,
+// and each row carries a single vote, so that the weight of a voter does not single out its
+// ballot.
+type SecretBallotDB struct {
+	ReceiptHash string `gorm:"primaryKey"`
+	PollID      uint   `gorm:"index"`
+	Choices     string // The IDs of the options chosen, in order of preference, separated by commas.
 }
 
 // TableName specifies the table name for the PollDB model.
@@ -46,6 +84,16 @@ func (VoteDB) TableName() string {
 	return "votes"
 }
 
+// TableName specifies the table name for the PollParticipationDB model.
+func (PollParticipationDB) TableName() string {
+	return "poll_participations"
+}
+
+// TableName specifies the table name for the SecretBallotDB model.
+func (SecretBallotDB) TableName() string {
+	return "secret_ballots"
+}
+
 // PollRepository defines the interface for poll persistence operations.
 // It abstracts the underlying database implementation for polls and their options.
 type PollRepository interface {
@@ -53,6 +101,7 @@ type PollRepository interface {
 	FindPollByID(id uint) (*models.Poll, error)
 	FindPollsByUserID(userID uint) ([]models.Poll, error)
 	FindAllPolls() ([]models.Poll, error)
+	FindPollsByAssemblyID(assemblyID uint) ([]models.Poll, error)
 	UpdatePoll(poll *models.Poll) error
 	DeletePoll(id uint) error
 	GetPollResults(pollID uint) (map[uint]int64, error) // Returns OptionID -> Count of votes.
@@ -117,19 +166,61 @@ func (r *GormPollRepository) FindAllPolls() ([]models.Poll, error) {
 	return polls, nil
 }
 
-// UpdatePoll updates an existing poll and its options.
-// Note: GORM's Save method does not automatically update nested associations.
-// For complex updates involving options, manual handling or transactions might be needed.
+// FindPollsByAssemblyID retrieves the resolutions of a general assembly, in the order they were
+// created, eagerly loading their options.
+func (r *GormPollRepository) FindPollsByAssemblyID(assemblyID uint) ([]models.Poll, error) {
+	var pollsDB []PollDB
+	if err := r.db.Preload("Options").Where("assembly_id = ?", assemblyID).Order("id").Find(&pollsDB).Error; err != nil {
+		return nil, err
+	}
+	var polls []models.Poll
+	for _, pdb := range pollsDB {
+		polls = append(polls, *toPoll(&pdb))
+	}
+	return polls, nil
+}
+
+// UpdatePoll updates an existing poll and its options, in a transaction: the options kept are
+// updated, the new ones created and the ones removed deleted with their votes.
 func (r *GormPollRepository) UpdatePoll(poll *models.Poll) error {
 	pollDB := toPollDB(poll)
-	// GORM does not update nested relations by default with Save.
-	// Options need to be managed separately or within a transaction.
-	return r.db.Save(&pollDB).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// GORM does not update nested relations by default with Save: options are saved one by one.
+		if err := tx.Omit("Options").Save(pollDB).Error; err != nil {
+			return err
+		}
+		kept := []uint{0}
+		for i := range pollDB.Options {
+			option := &pollDB.Options[i]
+			option.PollID = pollDB.ID
+			if err := tx.Save(option).Error; err != nil {
+				return err
+			}
+			kept = append(kept, option.ID)
+		}
+		removed := tx.Model(&OptionDB{}).Select("id").Where("poll_id = ? AND id NOT IN ?", pollDB.ID, kept)
+		if err := tx.Where("option_id IN (?)", removed).Delete(&VoteDB{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("poll_id = ? AND id NOT IN ?", pollDB.ID, kept).Delete(&OptionDB{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	*poll = *toPoll(pollDB) // Update the original poll with the IDs of the new options.
+	return nil
 }
 
-// DeletePoll deletes a poll by its ID, including its associated options and votes.
-// It performs cascading deletes for related records.
+// DeletePoll deletes a poll by its ID, including its associated options, votes, secret ballots
+// and participations. It performs cascading deletes for related records.
 func (r *GormPollRepository) DeletePoll(id uint) error {
+	// Delete the ballots of the poll and the record of its voters.
+	if err := r.db.Where("poll_id = ?", id).Delete(&SecretBallotDB{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("poll_id = ?", id).Delete(&PollParticipationDB{}).Error; err != nil {
+		return err
+	}
 	// Delete votes associated with the poll's options.
 	if err := r.db.Where("option_id IN (SELECT id FROM options WHERE poll_id = ?)", id).Delete(&VoteDB{}).Error; err != nil {
 		return err
@@ -167,9 +258,18 @@ func (r *GormPollRepository) GetPollResults(pollID uint) (map[uint]int64, error)
 // It also converts associated Option models to OptionDB models.
 func toPollDB(p *models.Poll) *PollDB {
 	pollDB := &PollDB{
-		Model:    gorm.Model{ID: p.ID, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, DeletedAt: p.DeletedAt},
-		Question: p.Question,
-		UserID:   p.UserID,
+		Model:             gorm.Model{ID: p.ID, CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, DeletedAt: p.DeletedAt},
+		Question:          p.Question,
+		UserID:            p.UserID,
+		Status:            string(p.Status),
+		OpensAt:           p.OpensAt,
+		ClosesAt:          p.ClosesAt,
+		ResultsVisibility: string(p.ResultsVisibility),
+		Type:              string(p.Type),
+		MinChoices:        p.MinChoices,
+		MaxChoices:        p.MaxChoices,
+		Anonymous:         p.Anonymous,
+		AssemblyID:        p.AssemblyID,
 	}
 	for _, opt := range p.Options {
 		pollDB.Options = append(pollDB.Options, *toOptionDB(&opt))
@@ -181,9 +281,18 @@ func toPollDB(p *models.Poll) *PollDB {
 // It also converts associated OptionDB models to Option models.
 func toPoll(pdb *PollDB) *models.Poll {
 	poll := &models.Poll{
-		Model:    gorm.Model{ID: pdb.ID, CreatedAt: pdb.CreatedAt, UpdatedAt: pdb.UpdatedAt, DeletedAt: pdb.DeletedAt},
-		Question: pdb.Question,
-		UserID:   pdb.UserID,
+		Model:             gorm.Model{ID: pdb.ID, CreatedAt: pdb.CreatedAt, UpdatedAt: pdb.UpdatedAt, DeletedAt: pdb.DeletedAt},
+		Question:          pdb.Question,
+		UserID:            pdb.UserID,
+		Status:            models.PollStatus(pdb.Status),
+		OpensAt:           pdb.OpensAt,
+		ClosesAt:          pdb.ClosesAt,
+		ResultsVisibility: models.ResultsVisibility(pdb.ResultsVisibility),
+		Type:              models.PollType(pdb.Type),
+		MinChoices:        pdb.MinChoices,
+		MaxChoices:        pdb.MaxChoices,
+		Anonymous:         pdb.Anonymous,
+		AssemblyID:        pdb.AssemblyID,
 	}
 	for _, optdb := range pdb.Options {
 		poll.Options = append(poll.Options, *toOption(&optdb))
@@ -213,8 +322,14 @@ func toOption(odb *OptionDB) *models.Option {
 // It abstracts the underlying database implementation for votes.
 type VoteRepository interface {
 	CreateVote(vote *models.Vote) error
+	CastBallot(pollID, userID uint, votes []models.Vote) (bool, error)
+	CastSecretBallot(pollID, userID uint, ballots []models.SecretBallot) (bool, error)
 	HasUserVoted(userID, pollID uint) (bool, error)
 	GetVotesByOptionID(optionID uint) ([]models.Vote, error)
+	FindVotesByPollID(pollID uint) ([]models.Vote, error)
+	FindSecretBallotsByPollID(pollID uint) ([]models.SecretBallot, error)
+	CountParticipations(pollID uint) (int64, error)
+	SecretBallotExists(pollID uint, receiptHash string) (bool, error)
 }
 
 // GormVoteRepository is an implementation of VoteRepository that uses GORM.
@@ -237,10 +352,68 @@ func (r *GormVoteRepository) CreateVote(vote *models.Vote) error {
 	return nil
 }
 
+// CastBallot records in a transaction that a user voted in a poll, and the votes of the ballot,
+// so that a ballot is recorded entirely or not at all. It reports false, recording nothing, if
+// the user already voted, were it through a concurrent request.
+func (r *GormVoteRepository) CastBallot(pollID, userID uint, votes []models.Vote) (bool, error) {
+	cast := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if cast, err = participate(tx, pollID, userID); err != nil || !cast {
+			return err
+		}
+		for i := range votes {
+			voteDB := toVoteDB(&votes[i])
+			if err := tx.Create(voteDB).Error; err != nil {
+				return err
+			}
+			votes[i] = *toVote(voteDB) // Update the vote with DB-generated fields.
+		}
+		return nil
+	})
+	return cast && err == nil, err
+}
+
+// CastSecretBallot records in a transaction that a user voted in a secret poll, and the ballot,
+// one row per vote it carries, which keeps no trace of the user. It reports false, recording
+// nothing, if the user already voted, were it through a concurrent request.
+func (r *GormVoteRepository) CastSecretBallot(pollID, userID uint, ballots []models.SecretBallot) (bool, error) {
+	cast := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if cast, err = participate(tx, pollID, userID); err != nil || !cast {
+			return err
+		}
+		for _, ballot := range ballots {
+			if err := tx.Create(toSecretBallotDB(&ballot)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return cast && err == nil, err
+}
+
+// participate records that a user voted in a poll, within a transaction. It reports false if the
+// user already had, the unique index of the participations settling concurrent requests.
+func participate(tx *gorm.DB, pollID, userID uint) (bool, error) {
+	participation := PollParticipationDB{PollID: pollID, UserID: userID, VotedAt: time.Now()}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&participation)
+	return result.RowsAffected == 1, result.Error
+}
+
 // HasUserVoted checks if a user has already voted in a given poll.
-// It queries for votes cast by the user for any option belonging to the specified poll.
+// It queries for the participation of the user in the poll, then, for the votes cast before
+// participations were recorded, for votes cast by the user for any option belonging to the
+// specified poll.
 func (r *GormVoteRepository) HasUserVoted(userID, pollID uint) (bool, error) {
 	var count int64
+	if err := r.db.Model(&PollParticipationDB{}).Where("poll_id = ? AND user_id = ?", pollID, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
 	// Count votes by the user for options within the specified poll.
 	if err := r.db.Model(&VoteDB{}).Where("user_id = ? AND option_id IN (SELECT id FROM options WHERE poll_id = ?)", userID, pollID).Count(&count).Error; err != nil {
 		return false, err
@@ -261,12 +434,60 @@ func (r *GormVoteRepository) GetVotesByOptionID(optionID uint) ([]models.Vote, e
 	return votes, nil
 }
 
+// FindVotesByPollID retrieves all votes cast in a poll, ordered by voter then by rank, so that
+// the votes of a ballot follow each other.
+func (r *GormVoteRepository) FindVotesByPollID(pollID uint) ([]models.Vote, error) {
+	var votesDB []VoteDB
+	if err := r.db.Where("option_id IN (SELECT id FROM options WHERE poll_id = ?)", pollID).Order("user_id, rank, id").Find(&votesDB).Error; err != nil {
+		return nil, err
+	}
+	var votes []models.Vote
+	for _, vdb := range votesDB {
+		votes = append(votes, *toVote(&vdb))
+	}
+	return votes, nil
+}
+
+// FindSecretBallotsByPollID retrieves all ballots cast in a secret poll, ordered by the hash of
+// their receipt codes, which tells nothing of the order in which they were cast.
+func (r *GormVoteRepository) FindSecretBallotsByPollID(pollID uint) ([]models.SecretBallot, error) {
+	var ballotsDB []SecretBallotDB
+	if err := r.db.Where("poll_id = ?", pollID).Order("receipt_hash").Find(&ballotsDB).Error; err != nil {
+		return nil, err
+	}
+	var ballots []models.SecretBallot
+	for _, bdb := range ballotsDB {
+		ballots = append(ballots, *toSecretBallot(&bdb))
+	}
+	return ballots, nil
+}
+
+// CountParticipations returns the number of users who voted in a poll.
+func (r *GormVoteRepository) CountParticipations(pollID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&PollParticipationDB{}).Where("poll_id = ?", pollID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SecretBallotExists checks if a ballot with the given receipt code hash was cast in a secret poll.
+func (r *GormVoteRepository) SecretBallotExists(pollID uint, receiptHash string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&SecretBallotDB{}).Where("poll_id = ? AND receipt_hash = ?", pollID, receiptHash).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // toVoteDB converts a domain Vote model to a database-specific VoteDB model.
 func toVoteDB(v *models.Vote) *VoteDB {
 	return &VoteDB{
 		Model:    gorm.Model{ID: v.ID, CreatedAt: v.CreatedAt, UpdatedAt: v.UpdatedAt, DeletedAt: v.DeletedAt},
 		OptionID: v.OptionID,
 		UserID:   v.UserID,
+		Rank:     v.Rank,
+		Weight:   v.Weight,
 	}
 }
 
@@ -276,5 +497,31 @@ func toVote(vdb *VoteDB) *models.Vote {
 		Model:    gorm.Model{ID: vdb.ID, CreatedAt: vdb.CreatedAt, UpdatedAt: vdb.UpdatedAt, DeletedAt: vdb.DeletedAt},
 		OptionID: vdb.OptionID,
 		UserID:   vdb.UserID,
+		Rank:     vdb.Rank,
+		Weight:   vdb.Weight,
+	}
+}
+
+// toSecretBallotDB converts a domain SecretBallot model to a database-specific SecretBallotDB model.
+func toSecretBallotDB(b *models.SecretBallot) *SecretBallotDB {
+	choices := make([]string, len(b.Choices))
+	for i, optionID := range b.Choices {
+		choices[i] = strconv.FormatUint(uint64(optionID), 10)
+	}
+	return &SecretBallotDB{
+		ReceiptHash: b.ReceiptHash,
+		PollID:      b.PollID,
+		Choices:     strings.Join(choices, ","),
+	}
+}
+
+// toSecretBallot converts a database-specific SecretBallotDB model back to a domain SecretBallot model.
+func toSecretBallot(bdb *SecretBallotDB) *models.SecretBallot {
+	ballot := &models.SecretBallot{ReceiptHash: bdb.ReceiptHash, PollID: bdb.PollID}
+	for _, value := range strings.Split(bdb.Choices, ",") {
+		if optionID, err := strconv.ParseUint(value, 10, 64); err == nil {
+			ballot.Choices = append(ballot.Choices, uint(optionID))
+		}
 	}
+	return ballot
 }
@@ -0,0 +1,150 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// RecurringTransactionDB represents the database model for a recurring transaction template, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type RecurringTransactionDB struct {
+	gorm.Model
+	UserID       uint                   `gorm:"index"` // Foreign key linking to the User who owns the template.
+	AmountMinor  int64                  // The amount in minor units of Currency.
+	Currency     string                 `gorm:"size:3"` // The ISO 4217 currency of the amount.
+	ExchangeRate string                 // Base-currency units for one unit of Currency.
+	Type         models.TransactionType // Income or expense.
+	AccountCode  string                 // The account occurrences are booked against.
+	Description  string                 // The description copied to every occurrence.
+	Frequency    models.Frequency       // How often the transaction falls due.
+	StartDate    time.Time              // Due date of the first occurrence.
+	EndDate      *time.Time             // Last possible due date, if any.
+	Active       bool                   // Paused templates are skipped.
+	Occurrences  int                    // Number of occurrences already recorded.
+	NextDueDate  time.Time              `gorm:"index"` // Due date of the next occurrence.
+}
+
+// TableName specifies the table name for the RecurringTransactionDB model.
+func (RecurringTransactionDB) TableName() string {
+	return "recurring_transactions"
+}
+
+// RecurringRepository defines the interface for recurring transaction persistence operations.
+// It abstracts the underlying database implementation.
+type RecurringRepository interface {
+	CreateRecurring(recurring *models.RecurringTransaction) error
+	FindRecurringByID(id uint) (*models.RecurringTransaction, error)
+	FindRecurringByUserID(userID uint) ([]models.RecurringTransaction, error)
+	FindDueRecurring(asOf time.Time) ([]models.RecurringTransaction, error)
+	UpdateRecurring(recurring *models.RecurringTransaction) error
+	DeleteRecurring(id uint) error
+}
+
+// GormRecurringRepository is an implementation of RecurringRepository that uses GORM
+// for interacting with a relational database.
+type GormRecurringRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormRecurringRepository creates a new instance of GormRecurringRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormRecurringRepository(db *gorm.DB) *GormRecurringRepository {
+	return &GormRecurringRepository{db: db}
+}
+
+// CreateRecurring persists a new recurring transaction template.
+func (r *GormRecurringRepository) CreateRecurring(recurring *models.RecurringTransaction) error {
+	recurringDB := toRecurringDB(recurring)
+	if err := r.db.Create(recurringDB).Error; err != nil {
+		return err
+	}
+	*recurring = *toRecurring(recurringDB) // Update the original template with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindRecurringByID retrieves a recurring transaction template by its ID.
+func (r *GormRecurringRepository) FindRecurringByID(id uint) (*models.RecurringTransaction, error) {
+	var recurringDB RecurringTransactionDB
+	if err := r.db.First(&recurringDB, id).Error; err != nil {
+		return nil, err
+	}
+	return toRecurring(&recurringDB), nil
+}
+
+// FindRecurringByUserID retrieves the recurring transaction templates of a user, by next due date.
+func (r *GormRecurringRepository) FindRecurringByUserID(userID uint) ([]models.RecurringTransaction, error) {
+	var recurringDB []RecurringTransactionDB
+	if err := r.db.Where("user_id = ?", userID).Order("next_due_date, id").Find(&recurringDB).Error; err != nil {
+		return nil, err
+	}
+	var recurring []models.RecurringTransaction
+	for _, rdb := range recurringDB {
+		recurring = append(recurring, *toRecurring(&rdb))
+	}
+	return recurring, nil
+}
+
+// FindDueRecurring retrieves the active templates of all users whose next occurrence is due on or
+// before the given date and within their end date.
+func (r *GormRecurringRepository) FindDueRecurring(asOf time.Time) ([]models.RecurringTransaction, error) {
+	var recurringDB []RecurringTransactionDB
+	if err := r.db.Where("active = ? AND next_due_date <= ? AND (end_date IS NULL OR next_due_date <= end_date)", true, asOf).Order("next_due_date, id").Find(&recurringDB).Error; err != nil {
+		return nil, err
+	}
+	var recurring []models.RecurringTransaction
+	for _, rdb := range recurringDB {
+		recurring = append(recurring, *toRecurring(&rdb))
+	}
+	return recurring, nil
+}
+
+// UpdateRecurring saves the changes made to a recurring transaction template.
+func (r *GormRecurringRepository) UpdateRecurring(recurring *models.RecurringTransaction) error {
+	return r.db.Save(toRecurringDB(recurring)).Error
+}
+
+// DeleteRecurring deletes a recurring transaction template. Recorded occurrences are kept.
+func (r *GormRecurringRepository) DeleteRecurring(id uint) error {
+	return r.db.Delete(&RecurringTransactionDB{}, id).Error
+}
+
+// toRecurringDB converts a domain RecurringTransaction model to a database-specific RecurringTransactionDB model.
+func toRecurringDB(r *models.RecurringTransaction) *RecurringTransactionDB {
+	return &RecurringTransactionDB{
+		Model:        gorm.Model{ID: r.ID, CreatedAt: r.CreatedAt, UpdatedAt: r.UpdatedAt, DeletedAt: r.DeletedAt},
+		UserID:       r.UserID,
+		AmountMinor:  r.Amount.Amount,
+		Currency:     r.Amount.Currency,
+		ExchangeRate: r.ExchangeRate,
+		Type:         r.Type,
+		AccountCode:  r.AccountCode,
+		Description:  r.Description,
+		Frequency:    r.Frequency,
+		StartDate:    r.StartDate,
+		EndDate:      r.EndDate,
+		Active:       r.Active,
+		Occurrences:  r.Occurrences,
+		NextDueDate:  r.NextDueDate,
+	}
+}
+
+// toRecurring converts a database-specific RecurringTransactionDB model back to a domain RecurringTransaction model.
+func toRecurring(rdb *RecurringTransactionDB) *models.RecurringTransaction {
+	return &models.RecurringTransaction{
+		Model:        gorm.Model{ID: rdb.ID, CreatedAt: rdb.CreatedAt, UpdatedAt: rdb.UpdatedAt, DeletedAt: rdb.DeletedAt},
+		UserID:       rdb.UserID,
+		Amount:       models.Money{Amount: rdb.AmountMinor, Currency: rdb.Currency},
+		ExchangeRate: rdb.ExchangeRate,
+		Type:         rdb.Type,
+		AccountCode:  rdb.AccountCode,
+		Description:  rdb.Description,
+		Frequency:    rdb.Frequency,
+		StartDate:    rdb.StartDate,
+		EndDate:      rdb.EndDate,
+		Active:       rdb.Active,
+		Occurrences:  rdb.Occurrences,
+		NextDueDate:  rdb.NextDueDate,
+	}
+}
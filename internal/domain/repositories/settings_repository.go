@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// AssociationSettingsDB represents the database model for the settings of an association, used for GORM persistence.
+// It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
+type AssociationSettingsDB struct {
+	gorm.Model
+	UserID       uint   `gorm:"uniqueIndex"` // Foreign key linking to the User who owns these settings.
+	BaseCurrency string `gorm:"size:3"`      // The ISO 4217 base currency of the association.
+
+	ReceiptThreshold int64 // Expense amount (base minor units) above which a receipt is required.
+
+	ApprovalEnabled   bool   // Whether large expenses must be approved.
+	ApprovalThreshold int64  // Expense amount (base minor units) above which approval is required.
+	TreasurerEmail    string `gorm:"index"` // Email of the application user reviewing expenses.
+
+	FiscalYearStartMonth int    // Month (1-12) the fiscal year starts in.
+	LegalName            string // Registered name of the association.
+	Address              string // Postal address of the registered office.
+	Purpose              string // Purpose of the association.
+	RegistrationNumber   string // RNA or SIREN number.
+	DonationEligibility  string // Category of general-interest organization.
+	SignatoryName        string // Person signing the receipts.
+
+	SEPACreditorID string // SEPA creditor identifier (ICS).
+	SEPAIBAN       string // Account the direct debits are collected on.
+	SEPABIC        string // Bank holding that account.
+}
+
+// TableName specifies the table name for the AssociationSettingsDB model.
+func (AssociationSettingsDB) TableName() string {
+	return "association_settings"
+}
+
+// SettingsRepository defines the interface for association settings persistence operations.
+// It abstracts the underlying database implementation.
+type SettingsRepository interface {
+	FindSettingsByUserID(userID uint) (*models.AssociationSettings, error)
+	SaveSettings(settings *models.AssociationSettings) error
+	FindUserIDsByTreasurerEmail(email string) ([]uint, error)
+}
+
+// GormSettingsRepository is an implementation of SettingsRepository that uses GORM
+// for interacting with a relational database.
+type GormSettingsRepository struct {
+	db *gorm.DB // GORM database client
+}
+
+// NewGormSettingsRepository creates a new instance of GormSettingsRepository.
+// It takes a GORM DB instance as a dependency.
+func NewGormSettingsRepository(db *gorm.DB) *GormSettingsRepository {
+	return &GormSettingsRepository{db: db}
+}
+
+// FindSettingsByUserID retrieves the settings of a user's association.
+// It returns gorm.ErrRecordNotFound if the association has not saved any settings yet.
+func (r *GormSettingsRepository) FindSettingsByUserID(userID uint) (*models.AssociationSettings, error) {
+	var settingsDB AssociationSettingsDB
+	// Find is used instead of First so that the common "no settings yet" case is not logged as an error.
+	result := r.db.Where("user_id = ?", userID).Limit(1).Find(&settingsDB)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return toAssociationSettings(&settingsDB), nil
+}
+
+// SaveSettings creates or updates the settings of an association.
+func (r *GormSettingsRepository) SaveSettings(settings *models.AssociationSettings) error {
+	settingsDB := toAssociationSettingsDB(settings)
+	if err := r.db.Save(settingsDB).Error; err != nil {
+		return err
+	}
+	*settings = *toAssociationSettings(settingsDB) // Update the original settings with DB-generated fields (e.g., ID)
+	return nil
+}
+
+// FindUserIDsByTreasurerEmail returns the IDs of the users whose association designated the given
+// email as treasurer.
+func (r *GormSettingsRepository) FindUserIDsByTreasurerEmail(email string) ([]uint, error) {
+	var userIDs []uint
+	if err := r.db.Model(&AssociationSettingsDB{}).Where("treasurer_email = ?", email).Order("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// toAssociationSettingsDB converts a domain AssociationSettings model to a database-specific AssociationSettingsDB model.
+func toAssociationSettingsDB(s *models.AssociationSettings) *AssociationSettingsDB {
+	return &AssociationSettingsDB{
+		Model:        gorm.Model{ID: s.ID, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt, DeletedAt: s.DeletedAt},
+		UserID:       s.UserID,
+		BaseCurrency: s.BaseCurrency,
+
+		ReceiptThreshold: s.ReceiptThreshold,
+
+		ApprovalEnabled:   s.ApprovalEnabled,
+		ApprovalThreshold: s.ApprovalThreshold,
+		TreasurerEmail:    s.TreasurerEmail,
+
+		FiscalYearStartMonth: s.FiscalYearStartMonth,
+		LegalName:            s.LegalName,
+		Address:              s.Address,
+		Purpose:              s.Purpose,
+		RegistrationNumber:   s.RegistrationNumber,
+		DonationEligibility:  s.DonationEligibility,
+		SignatoryName:        s.SignatoryName,
+
+		SEPACreditorID: s.SEPACreditorID,
+		SEPAIBAN:       s.SEPAIBAN,
+		SEPABIC:        s.SEPABIC,
+	}
+}
+
+// toAssociationSettings converts a database-specific AssociationSettingsDB model back to a domain AssociationSettings model.
+func toAssociationSettings(sdb *AssociationSettingsDB) *models.AssociationSettings {
+	return &models.AssociationSettings{
+		Model:        gorm.Model{ID: sdb.ID, CreatedAt: sdb.CreatedAt, UpdatedAt: sdb.UpdatedAt, DeletedAt: sdb.DeletedAt},
+		UserID:       sdb.UserID,
+		BaseCurrency: sdb.BaseCurrency,
+
+		ReceiptThreshold: sdb.ReceiptThreshold,
+
+		ApprovalEnabled:   sdb.ApprovalEnabled,
+		ApprovalThreshold: sdb.ApprovalThreshold,
+		TreasurerEmail:    sdb.TreasurerEmail,
+
+		FiscalYearStartMonth: sdb.FiscalYearStartMonth,
+		LegalName:            sdb.LegalName,
+		Address:              sdb.Address,
+		Purpose:              sdb.Purpose,
+		RegistrationNumber:   sdb.RegistrationNumber,
+		DonationEligibility:  sdb.DonationEligibility,
+		SignatoryName:        sdb.SignatoryName,
+
+		SEPACreditorID: sdb.SEPACreditorID,
+		SEPAIBAN:       sdb.SEPAIBAN,
+		SEPABIC:        sdb.SEPABIC,
+	}
+}
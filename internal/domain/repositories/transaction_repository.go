@@ -11,11 +11,22 @@ import (
 // It includes GORM's Model for common fields like ID, CreatedAt, UpdatedAt, and DeletedAt.
 type TransactionDB struct {
 	gorm.Model
-	Amount      float64                 // The monetary amount of the transaction.
-	Type        models.TransactionType  // The type of transaction (Income or Expense).
-	Description string                  // A brief description of the transaction.
-	Date        time.Time               // The date when the transaction occurred.
-	UserID      uint                    // Foreign key linking to the User who recorded this transaction.
+	AmountMinor  int64                  // The amount in minor units of Currency.
+	Currency     string                 `gorm:"size:3"` // The ISO 4217 currency of the transaction.
+	ExchangeRate string                 // Base-currency units for one unit of Currency, as an exact decimal.
+	BaseAmount   int64                  // The amount converted to the base currency, in minor units.
+	Type         models.TransactionType // The type of transaction (Income or Expense).
+	Description  string                 // A brief description of the transaction.
+	Date         time.Time              // The date when the transaction occurred.
+	AccountCode  string                 // The income or expense account the transaction is booked against.
+	UserID       uint                   // Foreign key linking to the User who recorded this transaction.
+
+	Status               models.TransactionStatus `gorm:"default:Validée;index"` // Lifecycle state; existing rows default to posted.
+	BankReference        string                   `gorm:"index"`                 // Bank's unique reference for the movement, used to detect duplicate imports.
+	Reconciled           bool                     // True once matched against a bank statement line.
+	MatchedTransactionID *uint                    // Proposed match for an imported draft transaction.
+	MemberID             *uint                    `gorm:"index"` // Member the income comes from, if any.
+	RecurringID          *uint                    `gorm:"index"` // Recurring transaction this one was recorded from, if any.
 }
 
 // TableName specifies the table name for the TransactionDB model in the database.
@@ -32,8 +43,16 @@ type TransactionRepository interface {
 	FindTransactionsByUserID(userID uint) ([]models.Transaction, error)
 	UpdateTransaction(transaction *models.Transaction) error
 	DeleteTransaction(id uint) error
-	GetTotalIncome(userID uint) (float64, error)
-	GetTotalExpenses(userID uint) (float64, error)
+	GetTotalIncome(userID uint) (int64, error)
+	GetTotalExpenses(userID uint) (int64, error)
+	CountTransactions(userID uint) (int64, error)
+	FindTransactionsByStatus(userID uint, status models.TransactionStatus) ([]models.Transaction, error)
+	FindUnreconciledTransactions(userID uint, transactionType models.TransactionType) ([]models.Transaction, error)
+	BankReferenceExists(userID uint, reference string) (bool, error)
+	FindPostedTransactionsByAccount(userID uint, accountCode string, from, to time.Time) ([]models.Transaction, error)
+	FindPostedTransactionsByPeriod(userID uint, from, to time.Time) ([]models.Transaction, error)
+	RecurringOccurrenceExists(recurringID uint, date time.Time) (bool, error)
+	CountPendingTransactionsByPeriod(userID uint, from, to time.Time) (int64, error)
 }
 
 // GormTransactionRepository is an implementation of TransactionRepository that uses GORM
@@ -97,36 +116,149 @@ func (r *GormTransactionRepository) DeleteTransaction(id uint) error {
 	return r.db.Delete(&TransactionDB{}, id).Error
 }
 
-// GetTotalIncome returns the sum of all income transactions for a given user ID.
-// It queries the database for transactions of type TypeIncome and sums their amounts.
-func (r *GormTransactionRepository) GetTotalIncome(userID uint) (float64, error) {
-	var total float64
-	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND type = ?", userID, models.TypeIncome).Select("sum(amount)").Row().Scan(&total); err != nil {
+// GetTotalIncome returns the sum of all posted income transactions for a given user ID,
+// in minor units of the base currency. Summing integers in SQL keeps the total exact.
+// Draft transactions awaiting reconciliation are excluded.
+func (r *GormTransactionRepository) GetTotalIncome(userID uint) (int64, error) {
+	var total int64
+	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND type = ? AND status = ?", userID, models.TypeIncome, models.TransactionPosted).Select("coalesce(sum(base_amount), 0)").Row().Scan(&total); err != nil {
 		return 0, err
 	}
 	return total, nil
 }
 
-// GetTotalExpenses returns the sum of all expense transactions for a given user ID.
-// It queries the database for transactions of type TypeExpense and sums their amounts.
-func (r *GormTransactionRepository) GetTotalExpenses(userID uint) (float64, error) {
-	var total float64
-	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND type = ?", userID, models.TypeExpense).Select("sum(amount)").Row().Scan(&total); err != nil {
+// GetTotalExpenses returns the sum of all posted expense transactions for a given user ID,
+// in minor units of the base currency. Summing integers in SQL keeps the total exact.
+// Draft transactions awaiting reconciliation are excluded.
+func (r *GormTransactionRepository) GetTotalExpenses(userID uint) (int64, error) {
+	var total int64
+	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND type = ? AND status = ?", userID, models.TypeExpense, models.TransactionPosted).Select("coalesce(sum(base_amount), 0)").Row().Scan(&total); err != nil {
 		return 0, err
 	}
 	return total, nil
 }
 
+// CountTransactions returns the number of transactions recorded by a user, drafts included.
+func (r *GormTransactionRepository) CountTransactions(userID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&TransactionDB{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindTransactionsByStatus retrieves all transactions of a user that are in the given status,
+// ordered by date.
+func (r *GormTransactionRepository) FindTransactionsByStatus(userID uint, status models.TransactionStatus) ([]models.Transaction, error) {
+	var transactionsDB []TransactionDB
+	if err := r.db.Where("user_id = ? AND status = ?", userID, status).Order("date").Find(&transactionsDB).Error; err != nil {
+		return nil, err
+	}
+	var transactions []models.Transaction
+	for _, tdb := range transactionsDB {
+		transactions = append(transactions, *toTransaction(&tdb))
+	}
+	return transactions, nil
+}
+
+// FindUnreconciledTransactions retrieves the posted transactions of a given type that have not
+// yet been matched against a bank statement line. An empty type returns both incomes and expenses.
+func (r *GormTransactionRepository) FindUnreconciledTransactions(userID uint, transactionType models.TransactionType) ([]models.Transaction, error) {
+	var transactionsDB []TransactionDB
+	query := r.db.Where("user_id = ? AND status = ? AND reconciled = ?", userID, models.TransactionPosted, false)
+	if transactionType != "" {
+		query = query.Where("type = ?", transactionType)
+	}
+	if err := query.Order("date").Find(&transactionsDB).Error; err != nil {
+		return nil, err
+	}
+	var transactions []models.Transaction
+	for _, tdb := range transactionsDB {
+		transactions = append(transactions, *toTransaction(&tdb))
+	}
+	return transactions, nil
+}
+
+// BankReferenceExists reports whether a transaction with the given bank reference was already
+// recorded for the user, including drafts.
+func (r *GormTransactionRepository) BankReferenceExists(userID uint, reference string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND bank_reference = ?", userID, reference).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindPostedTransactionsByAccount retrieves the posted transactions of a user booked on an account
+// and dated within [from, to), ordered by date.
+func (r *GormTransactionRepository) FindPostedTransactionsByAccount(userID uint, accountCode string, from, to time.Time) ([]models.Transaction, error) {
+	var transactionsDB []TransactionDB
+	if err := r.db.Where("user_id = ? AND status = ? AND account_code = ? AND date >= ? AND date < ?", userID, models.TransactionPosted, accountCode, from, to).Order("date, id").Find(&transactionsDB).Error; err != nil {
+		return nil, err
+	}
+	var transactions []models.Transaction
+	for _, tdb := range transactionsDB {
+		transactions = append(transactions, *toTransaction(&tdb))
+	}
+	return transactions, nil
+}
+
+// FindPostedTransactionsByPeriod retrieves the posted transactions of a user dated within [from, to),
+// ordered by date.
+func (r *GormTransactionRepository) FindPostedTransactionsByPeriod(userID uint, from, to time.Time) ([]models.Transaction, error) {
+	var transactionsDB []TransactionDB
+	if err := r.db.Where("user_id = ? AND status = ? AND date >= ? AND date < ?", userID, models.TransactionPosted, from, to).Order("date, id").Find(&transactionsDB).Error; err != nil {
+		return nil, err
+	}
+	var transactions []models.Transaction
+	for _, tdb := range transactionsDB {
+		transactions = append(transactions, *toTransaction(&tdb))
+	}
+	return transactions, nil
+}
+
+// RecurringOccurrenceExists reports whether the occurrence of a recurring transaction due on the
+// given date has already been recorded.
+func (r *GormTransactionRepository) RecurringOccurrenceExists(recurringID uint, date time.Time) (bool, error) {
+	var count int64
+	if err := r.db.Model(&TransactionDB{}).Where("recurring_id = ? AND date = ?", recurringID, date).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountPendingTransactionsByPeriod counts the transactions of a user dated within [from, to) that
+// are still awaiting a decision: drafts to reconcile, expenses submitted for approval and direct
+// debits awaiting collection.
+func (r *GormTransactionRepository) CountPendingTransactionsByPeriod(userID uint, from, to time.Time) (int64, error) {
+	var count int64
+	if err := r.db.Model(&TransactionDB{}).Where("user_id = ? AND status IN ? AND date >= ? AND date < ?", userID, []models.TransactionStatus{models.TransactionDraft, models.TransactionSubmitted, models.TransactionPending}, from, to).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // toTransactionDB converts a domain Transaction model to a database-specific TransactionDB model.
 // This is used before persisting the transaction to the database.
 func toTransactionDB(t *models.Transaction) *TransactionDB {
 	return &TransactionDB{
 		Model:       gorm.Model{ID: t.ID, CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt, DeletedAt: t.DeletedAt},
-		Amount:      t.Amount,
+		AmountMinor: t.Amount.Amount,
+		Currency:    t.Amount.Currency,
+		ExchangeRate: t.ExchangeRate,
+		BaseAmount:  t.BaseAmount,
 		Type:        t.Type,
 		Description: t.Description,
 		Date:        t.Date,
+		AccountCode: t.AccountCode,
 		UserID:      t.UserID,
+
+		Status:               t.Status,
+		BankReference:        t.BankReference,
+		Reconciled:           t.Reconciled,
+		MatchedTransactionID: t.MatchedTransactionID,
+		MemberID:             t.MemberID,
+		RecurringID:          t.RecurringID,
 	}
 }
 
@@ -135,10 +267,20 @@ func toTransactionDB(t *models.Transaction) *TransactionDB {
 func toTransaction(tdb *TransactionDB) *models.Transaction {
 	return &models.Transaction{
 		Model:       gorm.Model{ID: tdb.ID, CreatedAt: tdb.CreatedAt, UpdatedAt: tdb.UpdatedAt, DeletedAt: tdb.DeletedAt},
-		Amount:      tdb.Amount,
+		Amount:      models.Money{Amount: tdb.AmountMinor, Currency: tdb.Currency},
+		ExchangeRate: tdb.ExchangeRate,
+		BaseAmount:  tdb.BaseAmount,
 		Type:        tdb.Type,
 		Description: tdb.Description,
 		Date:        tdb.Date,
+		AccountCode: tdb.AccountCode,
 		UserID:      tdb.UserID,
+
+		Status:               tdb.Status,
+		BankReference:        tdb.BankReference,
+		Reconciled:           tdb.Reconciled,
+		MatchedTransactionID: tdb.MatchedTransactionID,
+		MemberID:             tdb.MemberID,
+		RecurringID:          tdb.RecurringID,
 	}
 }
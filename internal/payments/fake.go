@@ -0,0 +1,153 @@
+package payments
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeSession is a checkout session opened with the FakeProvider.
+type FakeSession struct {
+	CheckoutRequest
+	ID         string
+	PaymentRef string // Set once the payment is completed.
+	Refunded   int64  // Amount refunded so far, in minor units.
+}
+
+// FakeProvider is a PaymentProvider that runs inside the application, for development and tests.
+// Its checkout page is served by the application itself; completing it produces a webhook signed
+// like a Stripe one, which goes through the same processing as a real delivery.
+type FakeProvider struct {
+	checkoutURL string
+	secret      string
+	mu          sync.Mutex
+	sessions    map[string]*FakeSession
+	sequence    int
+}
+
+// NewFakeProvider creates a new instance of FakeProvider.
+// Checkout pages are served at checkoutURL followed by the session identifier.
+func NewFakeProvider(checkoutURL string) *FakeProvider {
+	return &FakeProvider{checkoutURL: checkoutURL, secret: randomHex(32), sessions: map[string]*FakeSession{}}
+}
+
+// Name returns the name of the provider.
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// CreateCheckoutSession records a session and returns the address of its local checkout page.
+func (p *FakeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("montant invalide")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sequence++
+	session := &FakeSession{CheckoutRequest: req, ID: fmt.Sprintf("cs_fake_%d_%s", p.sequence, randomHex(4))}
+	p.sessions[session.ID] = session
+	return &CheckoutSession{ID: session.ID, URL: p.checkoutURL + session.ID}, nil
+}
+
+// Session returns a copy of a checkout session, if it exists.
+func (p *FakeProvider) Session(id string) (FakeSession, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	session, ok := p.sessions[id]
+	if !ok {
+		return FakeSession{}, false
+	}
+	return *session, true
+}
+
+// Complete simulates the payer completing the checkout and returns the webhook delivery the
+// provider would send, signed with its secret. Delivering it several times simulates retries.
+func (p *FakeProvider) Complete(id string) ([]byte, http.Header, error) {
+	p.mu.Lock()
+	session, ok := p.sessions[id]
+	if ok && session.PaymentRef == "" {
+		session.PaymentRef = "pi_fake_" + randomHex(8)
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("session de paiement inconnue")
+	}
+	return p.delivery("checkout.session.completed", *session)
+}
+
+// Cancel simulates the expiry of the checkout and returns the corresponding webhook delivery.
+func (p *FakeProvider) Cancel(id string) ([]byte, http.Header, error) {
+	session, ok := p.Session(id)
+	if !ok {
+		return nil, nil, fmt.Errorf("session de paiement inconnue")
+	}
+	return p.delivery("checkout.session.expired", session)
+}
+
+// VerifyWebhook authenticates a delivery produced by Complete or Cancel.
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := verifySignature(payload, header.Get("Stripe-Signature"), p.secret, time.Now()); err != nil {
+		return nil, err
+	}
+	var event WebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("webhook illisible: %w", err)
+	}
+	return &event, nil
+}
+
+// Refund records a refund of a completed session; it cannot exceed the amount paid.
+func (p *FakeProvider) Refund(ctx context.Context, paymentRef string, amount int64, currency string) (*Refund, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, session := range p.sessions {
+		if session.PaymentRef == "" || session.PaymentRef != paymentRef {
+			continue
+		}
+		if !strings.EqualFold(session.Currency, currency) || amount <= 0 || session.Refunded+amount > session.Amount {
+			return nil, fmt.Errorf("montant du remboursement invalide")
+		}
+		session.Refunded += amount
+		return &Refund{ID: "re_fake_" + randomHex(8), Status: "succeeded"}, nil
+	}
+	return nil, fmt.Errorf("paiement inconnu du prestataire")
+}
+
+// delivery builds a signed webhook delivery for a session. The event identifier only depends on
+// the session and the event type, as a provider keeps it across retries.
+func (p *FakeProvider) delivery(eventType string, session FakeSession) ([]byte, http.Header, error) {
+	event := WebhookEvent{
+		ID:         "evt_" + eventType + "_" + session.ID,
+		Type:       EventPaymentFailed,
+		SessionID:  session.ID,
+		Reference:  session.Reference,
+		PaymentRef: session.PaymentRef,
+		Amount:     session.Amount,
+		Currency:   strings.ToUpper(session.Currency),
+	}
+	if eventType == "checkout.session.completed" {
+		event.Type = EventPaymentSucceeded
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set("Stripe-Signature", signPayload(payload, p.secret, time.Now()))
+	return payload, header, nil
+}
+
+// randomHex returns n random bytes encoded in hexadecimal.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("payments: random source unavailable: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+// Package payments connects the application to online payment providers. Each provider
+// implements PaymentProvider: it opens hosted checkout pages, authenticates the webhooks that
+// report their outcome and refunds captured payments.
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidSignature is returned when a webhook is not signed by the provider.
+var ErrInvalidSignature = errors.New("signature du webhook invalide")
+
+// EventType classifies the webhook events the application reacts to.
+type EventType string
+
+// Constants defining the webhook events understood by the application.
+const (
+	EventPaymentSucceeded EventType = "payment_succeeded" // The payer completed the checkout and the funds are captured.
+	EventPaymentFailed    EventType = "payment_failed"    // The checkout expired or the payment was declined.
+	EventIgnored          EventType = "ignored"           // Any other event, acknowledged without processing.
+)
+
+// CheckoutRequest describes a payment to collect through a hosted checkout page.
+type CheckoutRequest struct {
+	Reference   string // Application reference of the payment, echoed back in webhooks.
+	Attempt     int    // Number of the checkout attempt for the payment, starting at 1.
+	Description string // Label shown to the payer.
+	Amount      int64  // Amount in minor units of Currency.
+	Currency    string // ISO 4217 currency code (e.g., "EUR").
+	Email       string // Email address of the payer, prefilled on the checkout page when given.
+	SuccessURL  string // Page the payer returns to once the payment is completed.
+	CancelURL   string // Page the payer returns to when abandoning the checkout.
+}
+
+// CheckoutSession is a hosted checkout page opened with the provider.
+type CheckoutSession struct {
+	ID  string // Provider identifier of the session, reported in webhooks.
+	URL string // Address the payer is redirected to.
+}
+
+// WebhookEvent is an authenticated notification received from the provider.
+type WebhookEvent struct {
+	ID         string    // Provider identifier of the event, identical across delivery retries.
+	Type       EventType // What happened.
+	SessionID  string    // Checkout session the event relates to.
+	Reference  string    // Application reference given when the session was created.
+	PaymentRef string    // Provider identifier of the captured payment, used for refunds.
+	Amount     int64     // Amount paid, in minor units of Currency.
+	Currency   string    // ISO 4217 currency code, upper case.
+}
+
+// Refund is a refund issued by the provider.
+type Refund struct {
+	ID     string // Provider identifier of the refund.
+	Status string // Provider status of the refund (e.g., "succeeded", "pending").
+}
+
+// PaymentProvider is implemented by the online payment providers.
+type PaymentProvider interface {
+	// Name returns the name of the provider, recorded with each payment.
+	Name() string
+	// CreateCheckoutSession opens a hosted checkout page for the payment.
+	CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error)
+	// VerifyWebhook authenticates a webhook delivery and decodes its event.
+	// It returns ErrInvalidSignature when the delivery is not signed by the provider.
+	VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+	// Refund refunds the given amount, in minor units, of a captured payment.
+	Refund(ctx context.Context, paymentRef string, amount int64, currency string) (*Refund, error)
+}
@@ -0,0 +1,243 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStripeAPIURL is the address of the Stripe API.
+const DefaultStripeAPIURL = "https://api.stripe.com"
+
+// signatureTolerance is how old a webhook signature may be before the delivery is refused,
+// which prevents replaying captured deliveries.
+const signatureTolerance = 5 * time.Minute
+
+// StripeProvider is a PaymentProvider speaking the Stripe API: Checkout Sessions, signed webhooks
+// and Refunds. Any service exposing the same API can be used by changing the API URL.
+type StripeProvider struct {
+	apiURL        string
+	secretKey     string
+	webhookSecret string
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewStripeProvider creates a new instance of StripeProvider.
+// It takes the API secret key, the signing secret of the webhook endpoint and the API URL
+// (DefaultStripeAPIURL when empty).
+func NewStripeProvider(secretKey, webhookSecret, apiURL string) *StripeProvider {
+	if apiURL == "" {
+		apiURL = DefaultStripeAPIURL
+	}
+	return &StripeProvider{
+		apiURL:        strings.TrimRight(apiURL, "/"),
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		client:        &http.Client{Timeout: 15 * time.Second},
+		now:           time.Now,
+	}
+}
+
+// Name returns the name of the provider.
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+// CreateCheckoutSession opens a Checkout Session in payment mode for a single line item.
+// The application reference and the attempt number form the idempotency key, so that retrying a
+// request cannot open two sessions for the same attempt, while a new attempt after a failure does
+// not replay the failed session.
+func (p *StripeProvider) CreateCheckoutSession(ctx context.Context, req CheckoutRequest) (*CheckoutSession, error) {
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("success_url", req.SuccessURL)
+	form.Set("cancel_url", req.CancelURL)
+	form.Set("client_reference_id", req.Reference)
+	form.Set("metadata[reference]", req.Reference)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("line_items[0][price_data][currency]", strings.ToLower(req.Currency))
+	form.Set("line_items[0][price_data][unit_amount]", strconv.FormatInt(req.Amount, 10))
+	form.Set("line_items[0][price_data][product_data][name]", req.Description)
+	if req.Email != "" {
+		form.Set("customer_email", req.Email)
+	}
+
+	var session struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := p.post(ctx, "/v1/checkout/sessions", form, "checkout-"+req.Reference+"-"+strconv.Itoa(req.Attempt), &session); err != nil {
+		return nil, err
+	}
+	if session.ID == "" || session.URL == "" {
+		return nil, fmt.Errorf("réponse du prestataire de paiement incomplète")
+	}
+	return &CheckoutSession{ID: session.ID, URL: session.URL}, nil
+}
+
+// VerifyWebhook checks the Stripe-Signature header of a delivery (an HMAC-SHA256 of the timestamp
+// and the payload, keyed with the endpoint secret) and decodes the Checkout Session events.
+func (p *StripeProvider) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := verifySignature(payload, header.Get("Stripe-Signature"), p.webhookSecret, p.now()); err != nil {
+		return nil, err
+	}
+
+	var event struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				ID                string `json:"id"`
+				ClientReferenceID string `json:"client_reference_id"`
+				PaymentIntent     string `json:"payment_intent"`
+				PaymentStatus     string `json:"payment_status"`
+				AmountTotal       int64  `json:"amount_total"`
+				Currency          string `json:"currency"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("webhook illisible: %w", err)
+	}
+	if event.ID == "" {
+		return nil, fmt.Errorf("webhook sans identifiant d'événement")
+	}
+
+	object := event.Data.Object
+	result := &WebhookEvent{
+		ID:         event.ID,
+		Type:       EventIgnored,
+		SessionID:  object.ID,
+		Reference:  object.ClientReferenceID,
+		PaymentRef: object.PaymentIntent,
+		Amount:     object.AmountTotal,
+		Currency:   strings.ToUpper(object.Currency),
+	}
+	switch event.Type {
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
+		// Delayed payment methods complete the session before the funds are captured.
+		if object.PaymentStatus == "paid" {
+			result.Type = EventPaymentSucceeded
+		}
+	case "checkout.session.expired", "checkout.session.async_payment_failed":
+		result.Type = EventPaymentFailed
+	}
+	return result, nil
+}
+
+// Refund refunds the given amount of a captured PaymentIntent.
+func (p *StripeProvider) Refund(ctx context.Context, paymentRef string, amount int64, currency string) (*Refund, error) {
+	form := url.Values{}
+	form.Set("payment_intent", paymentRef)
+	form.Set("amount", strconv.FormatInt(amount, 10))
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := p.post(ctx, "/v1/refunds", form, "refund-"+paymentRef, &refund); err != nil {
+		return nil, err
+	}
+	if refund.Status == "failed" || refund.Status == "canceled" {
+		return nil, fmt.Errorf("le remboursement a été refusé par le prestataire de paiement")
+	}
+	return &Refund{ID: refund.ID, Status: refund.Status}, nil
+}
+
+// post sends a form-encoded request to the API and decodes the JSON response into out.
+func (p *StripeProvider) post(ctx context.Context, path string, form url.Values, idempotencyKey string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.apiURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.secretKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("prestataire de paiement injoignable: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("erreur lors de la lecture de la réponse du prestataire de paiement: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("erreur du prestataire de paiement: %s", apiErr.Error.Message)
+		}
+		return fmt.Errorf("erreur du prestataire de paiement (HTTP %d)", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("réponse du prestataire de paiement illisible: %w", err)
+	}
+	return nil
+}
+
+// signPayload returns the signature header of a payload in the Stripe format, "t=<timestamp>,v1=<hmac>".
+func signPayload(payload []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + computeSignature(payload, timestamp, secret)
+}
+
+// verifySignature checks a signature header in the Stripe format against the payload. Any of the
+// v1 signatures may match, so that the secret can be rolled.
+func verifySignature(payload []byte, header, secret string, now time.Time) error {
+	if secret == "" || header == "" {
+		return ErrInvalidSignature
+	}
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := computeSignature(payload, timestamp, secret)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// computeSignature returns the hex-encoded HMAC-SHA256 of "<timestamp>.<payload>".
+func computeSignature(payload []byte, timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,133 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"evt_1","type":"checkout.session.completed"}`)
+	now := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	earlier := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		payload string
+		header  string
+		valid   bool
+	}{
+		{name: "valid", header: signPayload(payload, secret, now), valid: true},
+		{name: "valid within the tolerance", header: signPayload(payload, secret, now.Add(-4*time.Minute)), valid: true},
+		{name: "rolled secret", header: signPayload(payload, secret, now) + ",v1=" + computeSignature(payload, "0", "whsec_old"), valid: true},
+		{name: "tampered payload", payload: `{"id":"evt_1","type":"checkout.session.expired"}`, header: signPayload(payload, secret, now)},
+		{name: "wrong secret", header: signPayload(payload, "whsec_other", now)},
+		{name: "outside the tolerance", header: signPayload(payload, secret, now.Add(-6*time.Minute))},
+		{name: "in the future", header: signPayload(payload, secret, now.Add(6*time.Minute))},
+		{name: "timestamp replaced", header: "t=" + timestamp + ",v1=" + computeSignature(payload, earlier, secret)},
+		{name: "no signature", header: "t=" + timestamp},
+		{name: "no header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := payload
+			if tt.payload != "" {
+				body = []byte(tt.payload)
+			}
+			err := verifySignature(body, tt.header, secret, now)
+			if tt.valid && err != nil {
+				t.Errorf("verifySignature = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("verifySignature = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	t.Run("no secret configured", func(t *testing.T) {
+		if err := verifySignature(payload, signPayload(payload, "", now), "", now); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("verifySignature = %v, want ErrInvalidSignature", err)
+		}
+	})
+}
+
+func TestStripeVerifyWebhook(t *testing.T) {
+	now := time.Now()
+	p := NewStripeProvider("sk_test", "whsec_test", "")
+	p.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		payload string
+		want    EventType
+	}{
+		{name: "paid", payload: `{"id":"evt_1","type":"checkout.session.completed","data":{"object":{"id":"cs_1","client_reference_id":"paiement-7","payment_intent":"pi_1","payment_status":"paid","amount_total":2500,"currency":"eur"}}}`, want: EventPaymentSucceeded},
+		{name: "awaiting funds", payload: `{"id":"evt_2","type":"checkout.session.completed","data":{"object":{"id":"cs_1","payment_status":"unpaid"}}}`, want: EventIgnored},
+		{name: "expired", payload: `{"id":"evt_3","type":"checkout.session.expired","data":{"object":{"id":"cs_1"}}}`, want: EventPaymentFailed},
+		{name: "other event", payload: `{"id":"evt_4","type":"customer.created","data":{"object":{"id":"cus_1"}}}`, want: EventIgnored},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Stripe-Signature", signPayload([]byte(tt.payload), "whsec_test", now))
+			event, err := p.VerifyWebhook([]byte(tt.payload), header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Type != tt.want {
+				t.Errorf("event type = %s, want %s", event.Type, tt.want)
+			}
+		})
+	}
+
+	t.Run("paid event fields", func(t *testing.T) {
+		payload := []byte(tests[0].payload)
+		header := http.Header{}
+		header.Set("Stripe-Signature", signPayload(payload, "whsec_test", now))
+		event, err := p.VerifyWebhook(payload, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := WebhookEvent{ID: "evt_1", Type: EventPaymentSucceeded, SessionID: "cs_1", Reference: "paiement-7", PaymentRef: "pi_1", Amount: 2500, Currency: "EUR"}
+		if *event != want {
+			t.Errorf("event = %+v, want %+v", *event, want)
+		}
+	})
+
+	t.Run("replayed delivery", func(t *testing.T) {
+		payload := []byte(tests[0].payload)
+		header := http.Header{}
+		header.Set("Stripe-Signature", signPayload(payload, "whsec_test", now.Add(-time.Hour)))
+		if _, err := p.VerifyWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyWebhook = %v, want ErrInvalidSignature", err)
+		}
+	})
+}
+
+func TestStripeCheckoutIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.Write([]byte(`{"id":"cs_1","url":"https://checkout.example/cs_1"}`))
+	}))
+	defer server.Close()
+
+	p := NewStripeProvider("sk_test", "whsec_test", server.URL)
+	for _, attempt := range []int{1, 1, 2} {
+		req := CheckoutRequest{Reference: "paiement-7", Attempt: attempt, Amount: 2500, Currency: "EUR"}
+		if _, err := p.CreateCheckoutSession(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"checkout-paiement-7-1", "checkout-paiement-7-1", "checkout-paiement-7-2"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("idempotency keys = %q, want %q", keys, want)
+	}
+}
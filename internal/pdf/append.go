@@ -0,0 +1,277 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// ErrUnsupported is returned by Append for the files whose structure it cannot update, such as
+// encrypted documents or documents whose page tree cannot be found.
+var ErrUnsupported = errors.New("structure PDF non prise en charge")
+
+var (
+	startXrefKeyword = regexp.MustCompile(`startxref\s+(\d+)`)
+	objectHeader     = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	rootEntry        = regexp.MustCompile(`/Root\s+(\d+)\s+(\d+)\s+R`)
+	infoEntry        = regexp.MustCompile(`/Info\s+\d+\s+\d+\s+R`)
+	idEntry          = regexp.MustCompile(`/ID\s*\[[^\]]*\]`)
+	sizeEntry        = regexp.MustCompile(`/Size\s+(\d+)`)
+	pagesEntry       = regexp.MustCompile(`/Pages\s+(\d+)\s+(\d+)\s+R`)
+	kidsEntry        = regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`)
+	countEntry       = regexp.MustCompile(`/Count\s+(\d+)`)
+	firstEntry       = regexp.MustCompile(`/First\s+(\d+)`)
+)
+
+// object is an object of an existing PDF file.
+type object struct {
+	generation int
+	body       []byte // The content between the "obj" and "endobj" keywords.
+	position   int    // Where the object is defined; for compressed objects, where its object stream is.
+}
+
+// Append adds the pages of a document after the pages of an existing PDF file. The file is
+// updated incrementally: its bytes are kept as they are and followed by the new pages, a new
+// version of its page tree root and a cross-reference section, so that the original content,
+// and any signature it holds, is left intact. The cross-reference section is a stream when the
+// previous one is, as a reader of cross-reference streams may not expect a classic table after
+// them.
+func Append(original []byte, d *Document) ([]byte, error) {
+	// The last startxref keyword gives the offset of the latest cross-reference section, which is
+	// followed by the trailer (or is a stream whose dictionary holds the trailer entries).
+	xrefs := startXrefKeyword.FindAllSubmatchIndex(original, -1)
+	if len(xrefs) == 0 {
+		return nil, ErrUnsupported
+	}
+	last := xrefs[len(xrefs)-1]
+	previous, err := strconv.Atoi(string(original[last[2]:last[3]]))
+	if err != nil || previous <= 0 || previous >= last[0] {
+		return nil, ErrUnsupported
+	}
+	trailer := original[previous:last[0]]
+	if bytes.Contains(trailer, []byte("/Encrypt")) {
+		return nil, ErrUnsupported
+	}
+	root := rootEntry.FindSubmatch(trailer)
+	size := sizeEntry.FindSubmatch(trailer)
+	if root == nil || size == nil {
+		return nil, ErrUnsupported
+	}
+	next, _ := strconv.Atoi(string(size[1]))
+
+	// Find the root of the page tree through the catalog.
+	objects := readObjects(original)
+	rootNumber, _ := strconv.Atoi(string(root[1]))
+	catalog, ok := objects[rootNumber]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	pagesRef := pagesEntry.FindSubmatch(catalog.body)
+	if pagesRef == nil {
+		return nil, ErrUnsupported
+	}
+	pagesNumber, _ := strconv.Atoi(string(pagesRef[1]))
+	pages, ok := objects[pagesNumber]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	kids := kidsEntry.FindSubmatchIndex(pages.body)
+	count := countEntry.FindSubmatchIndex(pages.body)
+	if kids == nil || count == nil {
+		return nil, ErrUnsupported
+	}
+	pageCount, _ := strconv.Atoi(string(pages.body[count[2]:count[3]]))
+	for _, number := range []int{rootNumber, pagesNumber} {
+		next = max(next, number+1)
+	}
+
+	// Number the new objects: the two fonts, then the page and the content stream of each page.
+	regularFont, boldFont := next, next+1
+	next += 2
+	parent := fmt.Sprintf("%d %d R", pagesNumber, pages.generation)
+	var newKids bytes.Buffer
+	for i := range d.pages {
+		fmt.Fprintf(&newKids, " %d 0 R", next+2*i)
+	}
+
+	// The page tree root is redefined with the new pages at the end of its kids.
+	var tree bytes.Buffer
+	tree.Write(pages.body[:kids[3]])
+	tree.Write(newKids.Bytes())
+	tree.Write(pages.body[kids[3]:count[2]])
+	fmt.Fprintf(&tree, "%d", pageCount+len(d.pages))
+	tree.Write(pages.body[count[3]:])
+
+	var buf bytes.Buffer
+	buf.Write(original)
+	if !bytes.HasSuffix(original, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	offsets := map[int]int{}
+	generations := map[int]int{}
+	write := func(number, generation int, body string) {
+		offsets[number] = buf.Len()
+		generations[number] = generation
+		fmt.Fprintf(&buf, "%d %d obj\n%s\nendobj\n", number, generation, body)
+	}
+	write(pagesNumber, pages.generation, string(bytes.TrimSpace(tree.Bytes())))
+	write(regularFont, 0, fontObject(false))
+	write(boldFont, 0, fontObject(true))
+	for i, page := range d.pages {
+		write(next+2*i, 0, page.object(parent, regularFont, boldFont, next+2*i+1))
+		write(next+2*i+1, 0, page.contentObject())
+	}
+	next += 2 * len(d.pages)
+
+	// The trailer entries are carried over to the new section, which points to the previous one.
+	entries := fmt.Sprintf("/Root %s %s R /Prev %d", root[1], root[2], previous)
+	if info := infoEntry.Find(trailer); info != nil {
+		entries += " " + string(info)
+	}
+	if id := idEntry.Find(trailer); id != nil {
+		entries += " " + string(id)
+	}
+
+	// The cross-reference section lists the new objects, in subsections of consecutive numbers.
+	xref := buf.Len()
+	if loc := objectHeader.FindIndex(bytes.TrimLeft(trailer, " \t\r\n")); loc != nil && loc[0] == 0 {
+		// The stream is an object of its own, listed in the section along with the new objects.
+		offsets[next], generations[next] = xref, 0
+		next++
+		var index, rows bytes.Buffer
+		for _, subsection := range subsections(offsets) {
+			fmt.Fprintf(&index, "%d %d ", subsection[0], len(subsection))
+			for _, number := range subsection {
+				// Each row is the type 1 (object in use), the offset on 4 bytes and the generation on 2.
+				offset, generation := offsets[number], generations[number]
+				rows.Write([]byte{1, byte(offset >> 24), byte(offset >> 16), byte(offset >> 8), byte(offset), byte(generation >> 8), byte(generation)})
+			}
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d %s /Index [%s] /W [1 4 2] /Length %d >>\nstream\n",
+			next-1, next, entries, bytes.TrimSpace(index.Bytes()), rows.Len())
+		buf.Write(rows.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	} else {
+		buf.WriteString("xref\n")
+		for _, subsection := range subsections(offsets) {
+			fmt.Fprintf(&buf, "%d %d\n", subsection[0], len(subsection))
+			for _, number := range subsection {
+				fmt.Fprintf(&buf, "%010d %05d n \n", offsets[number], generations[number])
+			}
+		}
+		fmt.Fprintf(&buf, "trailer\n<< /Size %d %s >>\n", next, entries)
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xref)
+	return buf.Bytes(), nil
+}
+
+// subsections groups the numbers of the objects written by an update into runs of consecutive
+// numbers, in ascending order, as listed by the subsections of a cross-reference section.
+func subsections(offsets map[int]int) [][]int {
+	numbers := make([]int, 0, len(offsets))
+	for number := range offsets {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	var runs [][]int
+	for i := 0; i < len(numbers); {
+		j := i + 1
+		for j < len(numbers) && numbers[j] == numbers[j-1]+1 {
+			j++
+		}
+		runs = append(runs, numbers[i:j])
+		i = j
+	}
+	return runs
+}
+
+// readObjects reads the objects of a PDF file, including those compressed in object streams, by
+// their number. When an object is defined several times, by incremental updates, the definition
+// appearing last in the file is kept.
+func readObjects(data []byte) map[int]object {
+	objects := map[int]object{}
+	define := func(number int, o object) {
+		if current, ok := objects[number]; !ok || current.position <= o.position {
+			objects[number] = o
+		}
+	}
+	for offset := 0; ; {
+		loc := objectHeader.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			break
+		}
+		start := offset + loc[1]
+		end := bytes.Index(data[start:], []byte("endobj"))
+		if end < 0 {
+			break
+		}
+		number, _ := strconv.Atoi(string(data[offset+loc[2] : offset+loc[3]]))
+		generation, _ := strconv.Atoi(string(data[offset+loc[4] : offset+loc[5]]))
+		o := object{generation: generation, body: data[start : start+end], position: offset + loc[0]}
+		define(number, o)
+		if bytes.Contains(o.body, []byte("/ObjStm")) {
+			for compressed, body := range streamObjects(o.body) {
+				define(compressed, object{body: body, position: o.position})
+			}
+		}
+		offset = start + end + len("endobj")
+	}
+	return objects
+}
+
+// streamObjects reads the objects compressed in an object stream, by their number.
+func streamObjects(body []byte) map[int][]byte {
+	loc := streamStart.FindIndex(body)
+	if loc == nil {
+		return nil
+	}
+	dictionary, content := body[:loc[0]], body[loc[1]:]
+	if end := bytes.LastIndex(content, []byte("endstream")); end >= 0 {
+		content = content[:end]
+	}
+	first := firstEntry.FindSubmatch(dictionary)
+	if first == nil {
+		return nil
+	}
+	if bytes.Contains(dictionary, []byte("/FlateDecode")) {
+		reader, err := zlib.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil
+		}
+		content, _ = io.ReadAll(io.LimitReader(reader, maxStreamSize))
+		reader.Close()
+	} else if bytes.Contains(dictionary, []byte("/Filter")) {
+		return nil
+	}
+	start, _ := strconv.Atoi(string(first[1]))
+	if start > len(content) {
+		return nil
+	}
+
+	// The stream starts with pairs of object numbers and offsets relative to the first object.
+	fields := bytes.Fields(content[:start])
+	type entry struct{ number, offset int }
+	var entries []entry
+	for i := 0; i+1 < len(fields); i += 2 {
+		number, err1 := strconv.Atoi(string(fields[i]))
+		offset, err2 := strconv.Atoi(string(fields[i+1]))
+		if err1 != nil || err2 != nil || start+offset > len(content) {
+			return nil
+		}
+		entries = append(entries, entry{number, start + offset})
+	}
+	objects := make(map[int][]byte, len(entries))
+	for i, e := range entries {
+		end := len(content)
+		if i+1 < len(entries) && entries[i+1].offset >= e.offset {
+			end = entries[i+1].offset
+		}
+		objects[e.number] = content[e.offset:end]
+	}
+	return objects
+}
@@ -0,0 +1,382 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	prevEntry      = regexp.MustCompile(`/Prev\s+(\d+)`)
+	lengthEntry    = regexp.MustCompile(`/Length\s+(\d+)`)
+	widthsEntry    = regexp.MustCompile(`/W\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+	indexEntry     = regexp.MustCompile(`/Index\s*\[([^\]]*)\]`)
+	referencePairs = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+)
+
+// xrefEntry locates an object of a PDF file: at an offset, or compressed in an object stream.
+type xrefEntry struct {
+	offset int // Offset of the object, or 0 when it is compressed.
+	stream int // Object stream holding the object, when it is compressed.
+}
+
+// crossReferences reads the cross-reference sections of a PDF file, from the last one back through
+// their /Prev entries, and returns where each object is defined as of the last update, with the
+// trailer dictionary of the last section.
+func crossReferences(t *testing.T, data []byte) (map[int]xrefEntry, []byte) {
+	t.Helper()
+	xrefs := startXrefKeyword.FindAllSubmatch(data, -1)
+	if len(xrefs) == 0 {
+		t.Fatal("no startxref keyword")
+	}
+	offset, _ := strconv.Atoi(string(xrefs[len(xrefs)-1][1]))
+	entries := map[int]xrefEntry{}
+	var trailer []byte
+	for seen := map[int]bool{}; ; {
+		if seen[offset] || offset <= 0 || offset >= len(data) {
+			t.Fatalf("invalid cross-reference section offset %d", offset)
+		}
+		seen[offset] = true
+		var dictionary []byte
+		if section := data[offset:]; bytes.HasPrefix(section, []byte("xref")) {
+			dictionary = readXrefTable(t, section, entries)
+		} else {
+			dictionary = readXrefStream(t, section, entries)
+		}
+		if trailer == nil {
+			trailer = dictionary
+		}
+		prev := prevEntry.FindSubmatch(dictionary)
+		if prev == nil {
+			return entries, trailer
+		}
+		offset, _ = strconv.Atoi(string(prev[1]))
+	}
+}
+
+// readXrefTable reads a classic cross-reference table, adding the objects it defines to entries
+// unless a later section defines them, and returns its trailer dictionary.
+func readXrefTable(t *testing.T, section []byte, entries map[int]xrefEntry) []byte {
+	t.Helper()
+	end := bytes.Index(section, []byte("trailer"))
+	dictionaryEnd := bytes.Index(section, []byte("startxref"))
+	if end < 0 || dictionaryEnd < end {
+		t.Fatal("cross-reference table without trailer")
+	}
+	fields := bytes.Fields(section[len("xref"):end])
+	for i := 0; i+1 < len(fields); {
+		first, err1 := strconv.Atoi(string(fields[i]))
+		count, err2 := strconv.Atoi(string(fields[i+1]))
+		if err1 != nil || err2 != nil || i+2+3*count > len(fields) {
+			t.Fatalf("invalid cross-reference subsection %q %q", fields[i], fields[i+1])
+		}
+		i += 2
+		for number := first; number < first+count; number++ {
+			offset, _ := strconv.Atoi(string(fields[i]))
+			if _, ok := entries[number]; !ok && string(fields[i+2]) == "n" {
+				entries[number] = xrefEntry{offset: offset}
+			}
+			i += 3
+		}
+	}
+	return section[end:dictionaryEnd]
+}
+
+// readXrefStream reads a cross-reference stream, adding the objects it defines to entries unless
+// a later section defines them, and returns its dictionary.
+func readXrefStream(t *testing.T, section []byte, entries map[int]xrefEntry) []byte {
+	t.Helper()
+	header := objectHeader.FindIndex(section)
+	end := bytes.Index(section, []byte("endobj"))
+	if header == nil || header[0] != 0 || end < 0 {
+		t.Fatal("the cross-reference section is neither a table nor an object")
+	}
+	body := section[header[1]:end]
+	loc := streamStart.FindIndex(body)
+	if loc == nil {
+		t.Fatal("the cross-reference object is not a stream")
+	}
+	dictionary, content := body[:loc[0]], body[loc[1]:]
+	if !bytes.Contains(dictionary, []byte("/Type /XRef")) {
+		t.Fatalf("the cross-reference object is not a cross-reference stream: %s", dictionary)
+	}
+	length := lengthEntry.FindSubmatch(dictionary)
+	widths := widthsEntry.FindSubmatch(dictionary)
+	size := sizeEntry.FindSubmatch(dictionary)
+	if length == nil || widths == nil || size == nil {
+		t.Fatalf("cross-reference stream without /Length, /W or /Size: %s", dictionary)
+	}
+	n, _ := strconv.Atoi(string(length[1]))
+	if n > len(content) {
+		t.Fatalf("/Length %d beyond the stream", n)
+	}
+	content = content[:n]
+	if bytes.Contains(dictionary, []byte("/FlateDecode")) {
+		reader, err := zlib.NewReader(bytes.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content, err = io.ReadAll(reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var w [3]int
+	for i := range w {
+		w[i], _ = strconv.Atoi(string(widths[i+1]))
+	}
+	index := []byte("0 " + string(size[1]))
+	if match := indexEntry.FindSubmatch(dictionary); match != nil {
+		index = match[1]
+	}
+	fields := bytes.Fields(index)
+	field := func(width int) int {
+		value := 0
+		for _, b := range content[:width] {
+			value = value<<8 | int(b)
+		}
+		content = content[width:]
+		return value
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		first, _ := strconv.Atoi(string(fields[i]))
+		count, _ := strconv.Atoi(string(fields[i+1]))
+		for number := first; number < first+count; number++ {
+			if len(content) < w[0]+w[1]+w[2] {
+				t.Fatalf("cross-reference stream too short for object %d", number)
+			}
+			kind := 1 // The type defaults to 1 when its field is absent.
+			if w[0] > 0 {
+				kind = field(w[0])
+			}
+			second := field(w[1])
+			field(w[2]) // The generation, or the index in the object stream.
+			if _, ok := entries[number]; ok {
+				continue
+			}
+			switch kind {
+			case 1:
+				entries[number] = xrefEntry{offset: second}
+			case 2:
+				entries[number] = xrefEntry{stream: second}
+			}
+		}
+	}
+	return dictionary
+}
+
+// resolve returns the body of an object of a PDF file, found through its cross-references.
+func resolve(t *testing.T, data []byte, entries map[int]xrefEntry, number int) []byte {
+	t.Helper()
+	entry, ok := entries[number]
+	if !ok {
+		t.Fatalf("object %d not in the cross-references", number)
+	}
+	if entry.offset == 0 {
+		body, ok := streamObjects(resolve(t, data, entries, entry.stream))[number]
+		if !ok {
+			t.Fatalf("object %d not in object stream %d", number, entry.stream)
+		}
+		return body
+	}
+	if entry.offset >= len(data) {
+		t.Fatalf("object %d at offset %d, beyond the file", number, entry.offset)
+	}
+	loc := objectHeader.FindSubmatchIndex(data[entry.offset:])
+	if loc == nil || loc[0] != 0 || string(data[entry.offset+loc[2]:entry.offset+loc[3]]) != strconv.Itoa(number) {
+		t.Fatalf("object %d not found at offset %d", number, entry.offset)
+	}
+	start := entry.offset + loc[1]
+	end := bytes.Index(data[start:], []byte("endobj"))
+	if end < 0 {
+		t.Fatalf("object %d not terminated", number)
+	}
+	return data[start : start+end]
+}
+
+// pageTree returns the bodies of the pages of a PDF file, read from the root of its page tree as
+// of the last update, and checks that its /Count matches them.
+func pageTree(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	entries, trailer := crossReferences(t, data)
+	root := rootEntry.FindSubmatch(trailer)
+	if root == nil {
+		t.Fatalf("trailer without /Root: %s", trailer)
+	}
+	rootNumber, _ := strconv.Atoi(string(root[1]))
+	pagesRef := pagesEntry.FindSubmatch(resolve(t, data, entries, rootNumber))
+	if pagesRef == nil {
+		t.Fatal("catalog without /Pages")
+	}
+	pagesNumber, _ := strconv.Atoi(string(pagesRef[1]))
+	pages := resolve(t, data, entries, pagesNumber)
+	kids := kidsEntry.FindSubmatch(pages)
+	count := countEntry.FindSubmatch(pages)
+	if kids == nil || count == nil {
+		t.Fatalf("page tree root without /Kids or /Count: %s", pages)
+	}
+	var bodies [][]byte
+	for _, kid := range referencePairs.FindAllSubmatch(kids[1], -1) {
+		number, _ := strconv.Atoi(string(kid[1]))
+		page := resolve(t, data, entries, number)
+		if !bytes.Contains(page, []byte("/Type /Page")) || bytes.Contains(page, []byte("/Type /Pages")) {
+			t.Errorf("kid %d is not a page: %s", number, page)
+		}
+		if !bytes.Contains(page, []byte(fmt.Sprintf("/Parent %d ", pagesNumber))) {
+			t.Errorf("page %d is not a child of the page tree root %d", number, pagesNumber)
+		}
+		bodies = append(bodies, page)
+	}
+	if n, _ := strconv.Atoi(string(count[1])); n != len(bodies) {
+		t.Errorf("/Count %d, want %d kids", n, len(bodies))
+	}
+	return bodies
+}
+
+// deflate compresses data with zlib, as the FlateDecode filter expects.
+func deflate(data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(data))
+	w.Close()
+	return buf.String()
+}
+
+// objectStreamPDF returns a PDF 1.5 file whose catalog and page tree root are compressed in an
+// object stream, and whose cross-references are a compressed stream, as written by most recent
+// producers.
+func objectStreamPDF() []byte {
+	var buf bytes.Buffer
+	offsets := map[int]int{}
+	write := func(number int, body string) {
+		offsets[number] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", number, body)
+	}
+	buf.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+
+	content := "BT /F1 12 Tf 72 720 Td (Article premier) Tj ET\n"
+	write(3, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595.28 841.89] /Resources << >> /Contents 5 0 R >>")
+	catalog, pages := "<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"
+	header := fmt.Sprintf("1 0 2 %d ", len(catalog)+1)
+	objects := deflate(header + catalog + " " + pages)
+	write(4, fmt.Sprintf("<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(header), len(objects), objects))
+	write(5, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+
+	// The rows are the type, the offset or object stream on 2 bytes, and the generation or index.
+	xref := buf.Len()
+	rows := []byte{0, 0, 0, 255, 2, 0, 4, 0, 2, 0, 4, 1}
+	for _, number := range []int{3, 4, 5, 6} {
+		offset := offsets[number]
+		if number == 6 {
+			offset = xref
+		}
+		rows = append(rows, 1, byte(offset>>8), byte(offset), 0)
+	}
+	compressed := deflate(string(rows))
+	fmt.Fprintf(&buf, "6 0 obj\n<< /Type /XRef /Size 7 /W [1 2 1] /Root 1 0 R /ID [<0123456789abcdef><0123456789abcdef>] /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(compressed), compressed)
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xref)
+	return buf.Bytes()
+}
+
+// certificate returns a document of two pages to append.
+func certificate() *Document {
+	d := New("Certificat")
+	d.AddPage().Text(72, 720, 14, true, "Certificat de signature électronique")
+	d.AddPage().Text(72, 720, 10, false, "Journal des événements")
+	return d
+}
+
+// statutes returns a PDF file with a classic cross-reference table, as written by New.
+func statutes() []byte {
+	d := New("Statuts")
+	d.AddPage().Text(72, 720, 12, false, "Article premier")
+	return d.Bytes()
+}
+
+func TestAppend(t *testing.T) {
+	appended, err := Append(statutes(), certificate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		original   []byte
+		pages      int  // Pages of the original file.
+		wantStream bool // Whether the new cross-reference section must be a stream.
+	}{
+		{name: "cross-reference table", original: statutes(), pages: 1},
+		{name: "cross-reference stream and object stream", original: objectStreamPDF(), pages: 1, wantStream: true},
+		{name: "file already updated", original: appended, pages: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The original file must read correctly on its own, or the test proves nothing.
+			if got := len(pageTree(t, tt.original)); got != tt.pages {
+				t.Fatalf("original file of %d pages, want %d", got, tt.pages)
+			}
+
+			got, err := Append(tt.original, certificate())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(got, tt.original) {
+				t.Fatal("the original bytes are not kept")
+			}
+			update := got[len(tt.original):]
+			if stream := bytes.Contains(update, []byte("/Type /XRef")); stream != tt.wantStream {
+				t.Errorf("cross-reference stream = %v, want %v", stream, tt.wantStream)
+			}
+			if table := bytes.Contains(update, []byte("\nxref\n")) || bytes.HasPrefix(update, []byte("xref\n")); table == tt.wantStream {
+				t.Errorf("cross-reference table = %v, want %v", table, !tt.wantStream)
+			}
+
+			pages := pageTree(t, got)
+			if len(pages) != tt.pages+2 {
+				t.Fatalf("%d pages, want %d", len(pages), tt.pages+2)
+			}
+			text := ExtractText(got)
+			for _, want := range []string{"Article premier", "Certificat de signature électronique", "Journal des événements"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("text %q does not contain %q", text, want)
+				}
+			}
+			if strings.Index(text, "Article premier") > strings.Index(text, "Certificat") {
+				t.Errorf("the appended pages come before the original ones: %q", text)
+			}
+
+			_, trailer := crossReferences(t, got)
+			if id := idEntry.Find(tt.original); id != nil && !bytes.Contains(trailer, id) {
+				t.Errorf("trailer %s without the file identifier %s", trailer, id)
+			}
+			if info := infoEntry.Find(tt.original); info != nil && !bytes.Contains(trailer, info) {
+				t.Errorf("trailer %s without the document information %s", trailer, info)
+			}
+		})
+	}
+}
+
+func TestAppendUnsupported(t *testing.T) {
+	encrypted := bytes.Replace(statutes(), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 9 0 R"), 1)
+	noPages := bytes.Replace(statutes(), []byte("/Pages 2 0 R"), []byte("/Outlines 2 0 R"), 1)
+	tests := []struct {
+		name     string
+		original []byte
+	}{
+		{name: "not a PDF file", original: []byte("Statuts de l'association")},
+		{name: "encrypted", original: encrypted},
+		{name: "without page tree", original: noPages},
+		{name: "truncated", original: statutes()[:200]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Append(tt.original, certificate()); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Append = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+}
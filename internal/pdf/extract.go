@@ -0,0 +1,283 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxStreamSize is the largest decompressed stream read when extracting text, in bytes.
+const maxStreamSize = 8 << 20
+
+// streamStart matches the keyword opening a stream, followed by its end-of-line marker.
+var streamStart = regexp.MustCompile(`stream\r?\n`)
+
+// ExtractText returns the text layer of a PDF document: the strings shown by the text operators of
+// its content streams, one line per positioning operator. Uncompressed and Flate-compressed streams
+// are read; text drawn with fonts using a custom encoding (embedded CID fonts) cannot be decoded and
+// is skipped, as are scanned pages without a text layer.
+func ExtractText(data []byte) string {
+	var text strings.Builder
+	for offset := 0; ; {
+		loc := streamStart.FindIndex(data[offset:])
+		if loc == nil {
+			break
+		}
+		start := offset + loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		dictionary := data[max(offset, offset+loc[0]-512) : offset+loc[0]]
+		if i := bytes.LastIndex(dictionary, []byte("<<")); i >= 0 {
+			dictionary = dictionary[i:]
+		}
+		offset = start + end + len("endstream")
+
+		if !isContentStream(dictionary) {
+			continue
+		}
+		content := data[start : start+end]
+		if bytes.Contains(dictionary, []byte("/FlateDecode")) {
+			reader, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			// A truncated stream still yields the text decompressed so far.
+			content, _ = io.ReadAll(io.LimitReader(reader, maxStreamSize))
+			reader.Close()
+		}
+		if line := contentText(content); line != "" {
+			text.WriteString(line)
+			text.WriteByte('\n')
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// isContentStream reports whether a stream dictionary may describe a page content stream, as opposed
+// to an image, a font program or a stream compressed with a filter other than Flate.
+func isContentStream(dictionary []byte) bool {
+	for _, key := range []string{"/Image", "/Length1", "/Length2", "/FontFile", "/XRef", "/ObjStm", "/DCTDecode", "/JPXDecode", "/CCITTFaxDecode", "/JBIG2Decode", "/LZWDecode", "/ASCII85Decode"} {
+		if bytes.Contains(dictionary, []byte(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// contentText returns the strings shown by the text operators of a content stream.
+func contentText(content []byte) string {
+	var (
+		text    strings.Builder
+		line    strings.Builder
+		operand []string // Strings of the operands read since the last operator.
+		inText  bool
+	)
+	flush := func() {
+		if value := strings.TrimSpace(line.String()); value != "" {
+			if text.Len() > 0 {
+				text.WriteByte('\n')
+			}
+			text.WriteString(value)
+		}
+		line.Reset()
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			value, next := literalString(content, i)
+			operand = append(operand, value)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return text.String()
+			}
+			operand = append(operand, hexString(content[i+1:i+end]))
+			i += end + 1
+		case c == '[' || c == ']':
+			i++
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			for i < len(content) && (content[i] == '-' || content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			// A large negative displacement in a TJ array separates words.
+			if len(operand) > 0 && content[start] == '-' && i-start >= 4 {
+				operand = append(operand, " ")
+			}
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isDelimiter(c):
+			i++
+		default:
+			start := i
+			for i < len(content) && !isDelimiter(content[i]) && content[i] != '(' && content[i] != '<' && content[i] != '[' {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			switch string(content[start:i]) {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				flush()
+			case "Td", "TD", "T*", "Tm":
+				flush()
+			case "Tj", "TJ":
+				if inText {
+					line.WriteString(strings.Join(operand, ""))
+				}
+			case "'", `"`:
+				if inText {
+					flush()
+					line.WriteString(strings.Join(operand, ""))
+				}
+			}
+			operand = operand[:0]
+		}
+	}
+	flush()
+	return text.String()
+}
+
+// isDelimiter reports whether a byte separates the tokens of a content stream.
+func isDelimiter(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0 || c == '/' || c == '{' || c == '}' || c == '>'
+}
+
+// literalString decodes the string literal starting at content[start], which is '(', and returns it
+// with the offset following its closing parenthesis.
+func literalString(content []byte, start int) (string, int) {
+	var value []byte
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				value = append(value, '\n')
+			case 'r':
+				value = append(value, '\r')
+			case 't':
+				value = append(value, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// A backslash at the end of a line continues the string on the next line.
+			default:
+				if e >= '0' && e <= '7' {
+					code := 0
+					for n := 0; n < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; n++ {
+						code = code*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					value = append(value, byte(code))
+				} else {
+					value = append(value, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				value = append(value, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodeText(value), i + 1
+			}
+			value = append(value, c)
+		default:
+			value = append(value, c)
+		}
+	}
+	return decodeText(value), i
+}
+
+// hexString decodes the digits of a hexadecimal string.
+func hexString(digits []byte) string {
+	var value []byte
+	var high byte
+	odd := false
+	for _, c := range digits {
+		var nibble byte
+		switch {
+		case c >= '0' && c <= '9':
+			nibble = c - '0'
+		case c >= 'a' && c <= 'f':
+			nibble = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			nibble = c - 'A' + 10
+		default:
+			continue
+		}
+		if odd {
+			value = append(value, high<<4|nibble)
+		} else {
+			high = nibble
+		}
+		odd = !odd
+	}
+	if odd {
+		value = append(value, high<<4)
+	}
+	return decodeText(value)
+}
+
+// winAnsiRunes maps the codes of WinAnsiEncoding outside of Latin-1 to their character.
+var winAnsiRunes = func() map[byte]rune {
+	runes := map[byte]rune{}
+	for r, code := range winAnsi {
+		if code >= 0x80 {
+			runes[code] = r
+		}
+	}
+	return runes
+}()
+
+// decodeText converts the bytes of a PDF string to UTF-8. Strings starting with a byte order mark are
+// UTF-16, the others are read as WinAnsiEncoding. Strings made of control characters, as produced by
+// fonts with a custom encoding, are dropped.
+func decodeText(value []byte) string {
+	if len(value) >= 2 && value[0] == 0xFE && value[1] == 0xFF {
+		units := make([]uint16, 0, len(value)/2)
+		for i := 2; i+1 < len(value); i += 2 {
+			units = append(units, uint16(value[i])<<8|uint16(value[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	var b strings.Builder
+	printable := 0
+	for _, c := range value {
+		r := rune(c)
+		if c >= 0x80 && c < 0xA0 {
+			r = winAnsiRunes[c]
+		}
+		if r == 0 || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+			continue
+		}
+		printable++
+		b.WriteRune(r)
+	}
+	if printable*2 < len(value) || !utf8.ValidString(b.String()) {
+		return ""
+	}
+	return b.String()
+}
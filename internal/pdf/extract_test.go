@@ -0,0 +1,71 @@
+package pdf
+
+import (
+	"fmt"
+	"testing"
+)
+
+// streamObject returns a PDF object holding a stream of the given content, with extra entries in
+// its dictionary.
+func streamObject(entries, content string) string {
+	return fmt.Sprintf("1 0 obj\n<< /Length %d%s >>\nstream\n%s\nendstream\nendobj\n", len(content), entries, content)
+}
+
+func TestExtractText(t *testing.T) {
+	d := New("Procès-verbal")
+	page := d.AddPage()
+	page.Text(72, 720, 14, true, "Procès-verbal de l'assemblée générale")
+	page.TextRight(520, 700, 10, false, "Budget : 1 234,56 €")
+	d.AddPage().Text(72, 720, 10, false, "Résolution adoptée (unanimité)")
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "generated document", data: string(d.Bytes()), want: "Procès-verbal de l'assemblée générale\nBudget : 1 234,56 €\nRésolution adoptée (unanimité)"},
+		{name: "compressed stream", data: streamObject(" /Filter /FlateDecode", deflate("BT /F1 12 Tf 72 720 Td (Statuts) Tj ET")), want: "Statuts"},
+		{name: "corrupted compressed stream", data: streamObject(" /Filter /FlateDecode", "BT (Statuts) Tj ET"), want: ""},
+		{name: "positioned text", data: streamObject("", "BT 72 720 Td (Article 1) Tj 0 -14 Td (Article 2) Tj T* (Article 3) Tj ET"), want: "Article 1\nArticle 2\nArticle 3"},
+		{name: "kerning and word spacing", data: streamObject("", "BT [(Bon)-20(jour)-300(monde)] TJ ET"), want: "Bonjour monde"},
+		{name: "quote operator", data: streamObject("", "BT (Ligne 1) Tj (Ligne 2) ' ET"), want: "Ligne 1\nLigne 2"},
+		{name: "escapes", data: streamObject("", `BT (Statuts \(version 2\)\040sign\351s) Tj ET`), want: "Statuts (version 2) signés"},
+		{name: "hexadecimal string", data: streamObject("", "BT <4173736F63696174696F6E> Tj ET"), want: "Association"},
+		{name: "UTF-16 string", data: streamObject("", "BT <FEFF00C9007400E9> Tj ET"), want: "Été"},
+		{name: "custom font encoding", data: streamObject("", "BT <01020304> Tj ET"), want: ""},
+		{name: "text outside a text object", data: streamObject("", "(Statuts) Tj"), want: ""},
+		{name: "comment", data: streamObject("", "% (Brouillon) Tj\nBT (Statuts) Tj ET"), want: "Statuts"},
+		{name: "image", data: streamObject(" /Type /XObject /Subtype /Image", "BT (Statuts) Tj ET"), want: ""},
+		{name: "font program", data: streamObject(" /Length1 18", "BT (Statuts) Tj ET"), want: ""},
+		{name: "unterminated stream", data: "1 0 obj\n<< /Length 20 >>\nstream\nBT (Statuts) Tj ET", want: ""},
+		{name: "not a PDF file", data: "Statuts de l'association", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractText([]byte(tt.data)); got != tt.want {
+				t.Errorf("ExtractText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "Assembl\xe9e g\xe9n\xe9rale", want: "Assemblée générale"},
+		{value: "1 234,56 \x80", want: "1 234,56 €"},
+		{value: "\x93C\x9cur\x94", want: "“Cœur”"},
+		{value: "\xfe\xff\x00A\x00\xe9\x20\xac", want: "Aé€"},
+		{value: "\x01\x02\x03A", want: ""},
+		{value: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := decodeText([]byte(tt.value)); got != tt.want {
+				t.Errorf("decodeText(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package pdf writes simple A4 documents made of text and lines in the PDF format.
+// It only uses the standard Helvetica fonts, so that no font file has to be embedded,
+// and encodes text in WinAnsiEncoding, which covers French accented characters.
+// It also extracts the text layer of existing PDF documents, for indexing.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dimensions of an A4 page, in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a PDF document under construction.
+type Document struct {
+	title string
+	pages []*Page
+}
+
+// Page is a page of a Document. Coordinates are in points from the bottom-left corner.
+type Page struct {
+	content bytes.Buffer
+}
+
+// New creates an empty document with the given title, stored in the document information.
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// AddPage appends a new blank page to the document and returns it.
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// PageCount returns the number of pages of the document.
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text writes a single line of text with its baseline starting at (x, y).
+func (p *Page) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(encode(text)))
+}
+
+// TextRight writes a single line of text ending at x, for right-aligned columns such as amounts.
+func (p *Page) TextRight(x, y, size float64, bold bool, text string) {
+	p.Text(x-TextWidth(text, size, bold), y, size, bold, text)
+}
+
+// Line draws a straight line between two points.
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Polyline draws connected straight lines of the given width through points given as x, y pairs,
+// with round joins and ends, e.g. to reproduce a handwritten stroke.
+func (p *Page) Polyline(width float64, points ...float64) {
+	if len(points) < 4 {
+		return
+	}
+	fmt.Fprintf(&p.content, "q %.2f w 1 J 1 j %.2f %.2f m", width, points[0], points[1])
+	for i := 2; i+1 < len(points); i += 2 {
+		fmt.Fprintf(&p.content, " %.2f %.2f l", points[i], points[i+1])
+	}
+	p.content.WriteString(" S Q\n")
+}
+
+// Rect draws the outline of a rectangle whose bottom-left corner is (x, y).
+func (p *Page) Rect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re S\n", x, y, width, height)
+}
+
+// TextWidth estimates the width of a text in points. Helvetica widths are approximated by
+// character class, which is precise enough to align amounts and wrap paragraphs.
+func TextWidth(text string, size float64, bold bool) float64 {
+	units := 0.0
+	for _, r := range text {
+		switch {
+		case r == ' ' || r == ',' || r == '.' || r == ':' || r == ';' || r == 'i' || r == 'l' || r == 'j' || r == '\'' || r == '|':
+			units += 278
+		case r == 'f' || r == 't' || r == 'I' || r == '-' || r == '(' || r == ')' || r == '/':
+			units += 333
+		case r == 'm' || r == 'M' || r == 'W':
+			units += 833
+		case r == 'w':
+			units += 722
+		case r >= 'A' && r <= 'Z', r == '€':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+	if bold {
+		units *= 1.05
+	}
+	return units * size / 1000
+}
+
+// WrapText splits a text into lines that fit within the given width. Existing line breaks are kept.
+func WrapText(text string, size, width float64, bold bool) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := strings.TrimSpace(line + " " + word)
+			if line != "" && TextWidth(candidate, size, bold) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo writes the document in the PDF format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 to 4 are the catalog, the page tree, the two fonts and the information dictionary;
+	// each page then takes two objects: the page itself and its content stream.
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object(fontObject(false))
+	object(fontObject(true))
+	object(fmt.Sprintf("<< /Title (%s) /Producer (BaseSasS) >>", escape(encode(d.title))))
+	for i, page := range pages {
+		object(page.object("2 0 R", 3, 4, 7+2*i))
+		object(page.contentObject())
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// fontObject returns the dictionary of the regular or bold font of the pages.
+func fontObject(bold bool) string {
+	font := "Helvetica"
+	if bold {
+		font = "Helvetica-Bold"
+	}
+	return fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font)
+}
+
+// object returns the dictionary of the page, child of the page tree node parent, using the fonts
+// and the content stream of the given object numbers.
+func (p *Page) object(parent string, regularFont, boldFont, contents int) string {
+	return fmt.Sprintf("<< /Type /Page /Parent %s /MediaBox [0 0 %.2f %.2f] /Rotate 0 /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+		parent, PageWidth, PageHeight, regularFont, boldFont, contents)
+}
+
+// contentObject returns the content stream of the page.
+func (p *Page) contentObject() string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String())
+}
+
+// Bytes returns the document in the PDF format.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf) // Writing to a bytes.Buffer never fails.
+	return buf.Bytes()
+}
+
+// winAnsi maps the characters of WinAnsiEncoding outside of Latin-1 to their code.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, 'Œ': 0x8C, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99, 'œ': 0x9C, 'Ÿ': 0x9F,
+	'\u2009': ' ', '\u202f': ' ', // Thin and narrow no-break spaces, used by French number formatting.
+}
+
+// encode converts a UTF-8 string to WinAnsiEncoding. Characters it cannot represent become '?'.
+func encode(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			b.WriteByte(byte(r))
+		case winAnsi[r] != 0:
+			b.WriteByte(winAnsi[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// escape escapes the characters that have a special meaning in PDF string literals.
+func escape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", "", "\n", " ").Replace(text)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// frenchUnits holds the French names of the numbers from zero to sixteen.
+var frenchUnits = []string{
+	"zéro", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf",
+	"dix", "onze", "douze", "treize", "quatorze", "quinze", "seize",
+}
+
+// frenchTens holds the French names of the tens from twenty to sixty.
+var frenchTens = map[int64]string{2: "vingt", 3: "trente", 4: "quarante", 5: "cinquante", 6: "soixante"}
+
+// SpellAmount writes an amount in French words, as required on donation receipts
+// (e.g., 12050 EUR gives "cent vingt euros et cinquante centimes").
+func SpellAmount(amount models.Money) string {
+	decimals, _ := models.CurrencyDecimals(amount.Currency)
+	scale := int64(1)
+	for i := 0; i < decimals; i++ {
+		scale *= 10
+	}
+	major, minor := abs64(amount.Amount)/scale, abs64(amount.Amount)%scale
+
+	unit, units, subunit := amount.Currency, amount.Currency, "centième"
+	if amount.Currency == "EUR" {
+		unit, units, subunit = "euro", "euros", "centime"
+	}
+
+	words := SpellNumber(major)
+	switch {
+	case major <= 1:
+		words += " " + unit
+	case strings.HasSuffix(words, "million") || strings.HasSuffix(words, "millions") ||
+		strings.HasSuffix(words, "milliard") || strings.HasSuffix(words, "milliards"):
+		words += " d'" + units // "un million d'euros"
+	default:
+		words += " " + units
+	}
+	if minor > 0 {
+		words += " et " + SpellNumber(minor) + " " + subunit
+		if minor > 1 {
+			words += "s"
+		}
+	}
+	if amount.Amount < 0 {
+		words = "moins " + words
+	}
+	return words
+}
+
+// SpellNumber writes a non-negative integer in French words, following the traditional
+// spelling rules ("quatre-vingts", "deux cents", "deux cent mille", "soixante et onze").
+func SpellNumber(n int64) string {
+	if n == 0 {
+		return frenchUnits[0]
+	}
+	var parts []string
+	for _, group := range []struct {
+		size int64
+		name string
+	}{{1_000_000_000, "milliard"}, {1_000_000, "million"}} {
+		if count := n / group.size; count > 0 {
+			name := group.name
+			if count > 1 {
+				name += "s"
+			}
+			parts = append(parts, SpellNumber(count)+" "+name)
+			n %= group.size
+		}
+	}
+	if thousands := n / 1000; thousands > 0 {
+		if thousands == 1 {
+			parts = append(parts, "mille")
+		} else {
+			// "cent" and "vingt" take no plural mark before "mille".
+			parts = append(parts, spellBelowThousand(thousands, false)+" mille")
+		}
+		n %= 1000
+	}
+	if n > 0 {
+		parts = append(parts, spellBelowThousand(n, true))
+	}
+	return strings.Join(parts, " ")
+}
+
+// spellBelowThousand writes a number from 1 to 999. final tells whether the number ends the
+// whole amount, in which case round hundreds and "quatre-vingts" take a plural mark.
+func spellBelowThousand(n int64, final bool) string {
+	hundreds, rest := n/100, n%100
+	if hundreds == 0 {
+		return spellBelowHundred(rest, final)
+	}
+	words := "cent"
+	if hundreds > 1 {
+		words = frenchUnits[hundreds] + " cent"
+		if rest == 0 && final {
+			words += "s"
+		}
+	}
+	if rest == 0 {
+		return words
+	}
+	return words + " " + spellBelowHundred(rest, final)
+}
+
+// spellBelowHundred writes a number from 1 to 99.
+func spellBelowHundred(n int64, final bool) string {
+	switch {
+	case n <= 16:
+		return frenchUnits[n]
+	case n < 20:
+		return "dix-" + frenchUnits[n-10]
+	case n < 70:
+		tens, unit := n/10, n%10
+		switch unit {
+		case 0:
+			return frenchTens[tens]
+		case 1:
+			return frenchTens[tens] + " et un"
+		default:
+			return frenchTens[tens] + "-" + frenchUnits[unit]
+		}
+	case n < 80:
+		if n == 71 {
+			return "soixante et onze"
+		}
+		return "soixante-" + spellBelowHundred(n-60, final)
+	case n == 80:
+		if final {
+			return "quatre-vingts"
+		}
+		return "quatre-vingt"
+	default:
+		return "quatre-vingt-" + spellBelowHundred(n-80, final)
+	}
+}
+
+// abs64 returns the absolute value of a 64-bit integer.
+func abs64(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// ApprovalService encapsulates the business logic for the approval of expenses: the queue of
+// the treasurer, the decisions and their history. Approved expenses are posted through the
+// FinanceService, so that they are journalized and counted like any other transaction.
+type ApprovalService struct {
+	approvalRepo    repositories.ApprovalRepository
+	transactionRepo repositories.TransactionRepository
+	userRepo        repositories.UserRepository
+	financeService  *FinanceService
+	settingsService *SettingsService
+	periodService   *FiscalPeriodService
+}
+
+// NewApprovalService creates a new instance of ApprovalService.
+// It takes an ApprovalRepository, a TransactionRepository, a UserRepository, a FinanceService,
+// a SettingsService and a FiscalPeriodService as dependencies, adhering to the dependency
+// inversion principle.
+func NewApprovalService(approvalRepo repositories.ApprovalRepository, transactionRepo repositories.TransactionRepository, userRepo repositories.UserRepository, financeService *FinanceService, settingsService *SettingsService, periodService *FiscalPeriodService) *ApprovalService {
+	return &ApprovalService{approvalRepo: approvalRepo, transactionRepo: transactionRepo, userRepo: userRepo, financeService: financeService, settingsService: settingsService, periodService: periodService}
+}
+
+// CanReview reports whether a user may approve or reject the expenses of an association: the
+// designated treasurer, or the owner of the association when no treasurer is designated.
+func (s *ApprovalService) CanReview(actor models.User, ownerID uint) (bool, error) {
+	settings, err := s.settingsService.GetSettings(ownerID)
+	if err != nil {
+		return false, err
+	}
+	if settings.TreasurerEmail == "" {
+		return actor.ID == ownerID, nil
+	}
+	return strings.EqualFold(strings.TrimSpace(actor.Email), settings.TreasurerEmail), nil
+}
+
+// GetReviewQueue returns the expenses awaiting the decision of a user, across every association
+// the user reviews, oldest first within each association.
+func (s *ApprovalService) GetReviewQueue(actor models.User) ([]models.ApprovalRequest, error) {
+	ownerIDs, err := s.settingsService.GetTreasurerAssociations(actor.Email)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := s.CanReview(actor, actor.ID); err != nil {
+		return nil, err
+	} else if ok && !containsID(ownerIDs, actor.ID) {
+		ownerIDs = append([]uint{actor.ID}, ownerIDs...)
+	}
+
+	var queue []models.ApprovalRequest
+	for _, ownerID := range ownerIDs {
+		submitted, err := s.transactionRepo.FindTransactionsByStatus(ownerID, models.TransactionSubmitted)
+		if err != nil {
+			return nil, err
+		}
+		if len(submitted) == 0 {
+			continue
+		}
+		association := s.associationName(ownerID)
+		for _, transaction := range submitted {
+			queue = append(queue, models.ApprovalRequest{Transaction: transaction, Association: association})
+		}
+	}
+	return queue, nil
+}
+
+// GetDecisions returns the decisions taken on the expenses of an association, most recent first.
+func (s *ApprovalService) GetDecisions(ownerID uint) ([]models.TransactionApproval, error) {
+	return s.approvalRepo.FindApprovalsByUserID(ownerID)
+}
+
+// Approve posts a submitted expense on behalf of the treasurer and records the decision.
+func (s *ApprovalService) Approve(actor models.User, transactionID uint, comment string) error {
+	transaction, err := s.reviewable(actor, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := s.financeService.PostTransaction(transaction); err != nil {
+		return fmt.Errorf("erreur lors de la validation de la dépense: %w", err)
+	}
+	return s.record(actor, transaction, models.DecisionApproved, comment)
+}
+
+// Reject refuses a submitted expense and records the decision. A comment explaining the refusal
+// is required; the expense can then be corrected and resubmitted by editing it. An expense of a
+// closed fiscal year cannot be rejected.
+func (s *ApprovalService) Reject(actor models.User, transactionID uint, comment string) error {
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("un commentaire est requis pour rejeter une dépense")
+	}
+	transaction, err := s.reviewable(actor, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+	transaction.Status = models.TransactionRejected
+	if err := s.transactionRepo.UpdateTransaction(transaction); err != nil {
+		return fmt.Errorf("erreur lors du rejet de la dépense: %w", err)
+	}
+	return s.record(actor, transaction, models.DecisionRejected, comment)
+}
+
+// reviewable retrieves a submitted expense and checks that the user may decide on it.
+func (s *ApprovalService) reviewable(actor models.User, transactionID uint) (*models.Transaction, error) {
+	transaction, err := s.transactionRepo.FindTransactionByID(transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("transaction non trouvée")
+	}
+	ok, err := s.CanReview(actor, transaction.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("seul le trésorier peut approuver ou rejeter cette dépense")
+	}
+	if transaction.Status != models.TransactionSubmitted {
+		return nil, fmt.Errorf("cette dépense n'est pas en attente d'approbation")
+	}
+	return transaction, nil
+}
+
+// record saves the decision taken on an expense with its author and time.
+func (s *ApprovalService) record(actor models.User, transaction *models.Transaction, decision models.ApprovalDecision, comment string) error {
+	return s.approvalRepo.CreateApproval(&models.TransactionApproval{
+		TransactionID: transaction.ID,
+		UserID:        transaction.UserID,
+		ActorID:       actor.ID,
+		ActorName:     actor.Name,
+		Decision:      decision,
+		Comment:       strings.TrimSpace(comment),
+		DecidedAt:     time.Now(),
+		Description:   transaction.Description,
+		Amount:        transaction.Amount,
+	})
+}
+
+// associationName returns the name shown for an association in the review queue: its legal name,
+// or the name of its owner when it has none.
+func (s *ApprovalService) associationName(ownerID uint) string {
+	if settings, err := s.settingsService.GetSettings(ownerID); err == nil && settings.LegalName != "" {
+		return settings.LegalName
+	}
+	if owner, err := s.userRepo.FindUserByID(ownerID); err == nil {
+		return owner.Name
+	}
+	return fmt.Sprintf("Association n°%d", ownerID)
+}
+
+// containsID reports whether an ID is in a list.
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
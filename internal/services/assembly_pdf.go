@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/pdf"
+)
+
+// assemblyTimeFormat is the format of the times of the minutes.
+const assemblyTimeFormat = "02/01/2006 à 15:04"
+
+// renderAssemblyMinutes renders the minutes of a general assembly: the session, the quorum, the
+// attendance sheet with the proxies, then each resolution with the votes for each option and its
+// outcome.
+func renderAssemblyMinutes(minutes *models.AssemblyMinutes) *pdf.Document {
+	assembly := minutes.Assembly
+	w := &pdfWriter{doc: pdf.New("Procès-verbal - " + assembly.Title)}
+	w.newPage()
+
+	w.paragraph(0, 16, true, "Procès-verbal de l'assemblée générale")
+	w.paragraph(0, 10, false, minutes.Association)
+	w.space(6)
+	w.rule()
+
+	w.paragraph(0, 12, true, assembly.Title)
+	w.field("Date :", assembly.Date.Format(assemblyTimeFormat))
+	w.field("Lieu :", assembly.Location)
+	w.field("Statut :", string(assembly.Status))
+	if assembly.OpenedAt != nil {
+		w.field("Séance ouverte le :", assembly.OpenedAt.Format(assemblyTimeFormat))
+	}
+	if assembly.ClosedAt != nil {
+		w.field("Séance levée le :", assembly.ClosedAt.Format(assemblyTimeFormat))
+	}
+	w.space(6)
+
+	quorum := minutes.Quorum
+	w.paragraph(0, 12, true, "Quorum")
+	w.field("Membres actifs :", fmt.Sprintf("%d", quorum.ActiveMembers))
+	w.field("Présents :", fmt.Sprintf("%d", quorum.Present))
+	w.field("Représentés :", fmt.Sprintf("%d", quorum.Represented))
+	w.field("Voix :", fmt.Sprintf("%d", quorum.Votes))
+	reached := "non atteint"
+	if quorum.Reached {
+		reached = "atteint"
+	}
+	w.field("Quorum requis :", fmt.Sprintf("%d %% des membres actifs, soit %d membre(s) : %s", quorum.Percent, quorum.Required, reached))
+	w.space(6)
+
+	w.paragraph(0, 12, true, "Feuille de présence")
+	absent := 0
+	for _, line := range minutes.Attendance {
+		name := line.Member.FirstName + " " + line.Member.LastName
+		switch line.Status {
+		case models.AttendancePresent:
+			text := fmt.Sprintf("%s : présent(e), %d voix", name, line.Votes)
+			if len(line.Proxies) > 0 {
+				names := make([]string, len(line.Proxies))
+				for i, proxy := range line.Proxies {
+					names[i] = proxy.FirstName + " " + proxy.LastName
+				}
+				text += " dont les procurations de " + strings.Join(names, ", ")
+			}
+			w.paragraph(10, 10, false, text)
+		case models.AttendanceRepresented:
+			holder := "un autre membre"
+			if line.ProxyHolder != nil {
+				holder = line.ProxyHolder.FirstName + " " + line.ProxyHolder.LastName
+			}
+			w.paragraph(10, 10, false, fmt.Sprintf("%s : représenté(e) par %s", name, holder))
+		default:
+			absent++
+		}
+	}
+	w.paragraph(10, 10, false, fmt.Sprintf("Membres absents : %d", absent))
+	w.space(6)
+
+	w.paragraph(0, 12, true, "Résolutions")
+	if len(minutes.Resolutions) == 0 {
+		w.paragraph(10, 10, false, "Aucune résolution.")
+	}
+	for i, resolution := range minutes.Resolutions {
+		w.ensure(120)
+		w.rule()
+		w.paragraph(0, 11, true, fmt.Sprintf("Résolution %d : %s", i+1, resolution.Poll.Question))
+		mode := string(resolution.Tally.Type)
+		if resolution.Poll.Anonymous {
+			mode += ", à bulletin secret"
+		}
+		w.field("Mode de vote :", mode)
+		if resolution.State == models.PollDraft {
+			w.field("Résultat :", "non soumise au vote")
+			continue
+		}
+		w.field("Bulletins :", fmt.Sprintf("%d bulletin(s), %d voix", resolution.Tally.Ballots, resolution.Tally.Votes))
+		if resolution.Tally.Type == models.PollRanked {
+			w.paragraph(10, 10, false, "Premiers choix :")
+		}
+		for _, option := range resolution.Tally.Options {
+			w.paragraph(10, 10, false, fmt.Sprintf("%s : %d voix (%.1f %%)", option.Text, option.Votes, option.Percent))
+		}
+		if resolution.State == models.PollOpen {
+			w.field("Résultat :", "vote en cours")
+			continue
+		}
+		w.field("Résultat :", resolution.Outcome)
+	}
+	return w.doc
+}
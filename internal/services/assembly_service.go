@@ -0,0 +1,464 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// AssemblyService encapsulates the business logic of the general assemblies of an association: a
+// session gathering resolutions, which are polls, the attendance sheet of the active members, the
+// quorum the statutes require before the resolutions can be voted on, the proxies members give to
+// each other, and the minutes recording the results.
+// The members vote with their own account: a user votes for a member when its email is the email
+// of that member, and carries the votes of the member and of the members it represents.
+type AssemblyService struct {
+	assemblyRepo    repositories.AssemblyRepository
+	pollRepo        repositories.PollRepository
+	voteRepo        repositories.VoteRepository
+	memberRepo      repositories.MemberRepository
+	settingsService *SettingsService
+}
+
+// NewAssemblyService creates a new instance of AssemblyService.
+// It takes an AssemblyRepository, the PollRepository and VoteRepository of the resolutions, a
+// MemberRepository and the SettingsService (for the name of the association) as dependencies,
+// adhering to the dependency inversion principle.
+func NewAssemblyService(assemblyRepo repositories.AssemblyRepository, pollRepo repositories.PollRepository, voteRepo repositories.VoteRepository, memberRepo repositories.MemberRepository, settingsService *SettingsService) *AssemblyService {
+	return &AssemblyService{
+		assemblyRepo:    assemblyRepo,
+		pollRepo:        pollRepo,
+		voteRepo:        voteRepo,
+		memberRepo:      memberRepo,
+		settingsService: settingsService,
+	}
+}
+
+// CreateAssembly convenes a new general assembly of the user's association.
+func (s *AssemblyService) CreateAssembly(assembly *models.Assembly) error {
+	assembly.Title = strings.TrimSpace(assembly.Title)
+	assembly.Location = strings.TrimSpace(assembly.Location)
+	if assembly.Title == "" {
+		return fmt.Errorf("le titre de l'assemblée est requis")
+	}
+	if assembly.Date.IsZero() {
+		return fmt.Errorf("la date de l'assemblée est requise")
+	}
+	if assembly.QuorumPercent < 0 || assembly.QuorumPercent > 100 {
+		return fmt.Errorf("le quorum doit être compris entre 0 et 100 %%")
+	}
+	if assembly.MaxProxies < 0 {
+		return fmt.Errorf("le nombre maximum de procurations ne peut pas être négatif")
+	}
+	assembly.Status = models.AssemblyPlanned
+	assembly.OpenedAt, assembly.ClosedAt = nil, nil
+	assembly.Attendances = nil
+	return s.assemblyRepo.CreateAssembly(assembly)
+}
+
+// GetAssemblies retrieves the general assemblies of the user's association, the latest first.
+func (s *AssemblyService) GetAssemblies(userID uint) ([]models.Assembly, error) {
+	return s.assemblyRepo.FindAssembliesByUserID(userID)
+}
+
+// GetAssembly retrieves a general assembly of the user's association, with its attendance records.
+func (s *AssemblyService) GetAssembly(userID, id uint) (*models.Assembly, error) {
+	assembly, err := s.assemblyRepo.FindAssemblyByID(id)
+	if err != nil || assembly.UserID != userID {
+		return nil, fmt.Errorf("assemblée non trouvée")
+	}
+	return assembly, nil
+}
+
+// DeleteAssembly deletes a general assembly which has not been held yet, with its resolutions.
+func (s *AssemblyService) DeleteAssembly(userID, id uint) error {
+	assembly, err := s.GetAssembly(userID, id)
+	if err != nil {
+		return err
+	}
+	if assembly.Status != models.AssemblyPlanned {
+		return fmt.Errorf("une assemblée tenue ne peut pas être supprimée")
+	}
+	polls, err := s.pollRepo.FindPollsByAssemblyID(assembly.ID)
+	if err != nil {
+		return err
+	}
+	for _, poll := range polls {
+		if err := s.pollRepo.DeletePoll(poll.ID); err != nil {
+			return err
+		}
+	}
+	return s.assemblyRepo.DeleteAssembly(assembly.ID)
+}
+
+// OpenAssembly opens the session of a convened general assembly. Its resolutions can then be
+// opened to votes, once the quorum is reached.
+func (s *AssemblyService) OpenAssembly(userID, id uint) error {
+	assembly, err := s.GetAssembly(userID, id)
+	if err != nil {
+		return err
+	}
+	if assembly.Status != models.AssemblyPlanned {
+		return fmt.Errorf("seule une assemblée convoquée peut être ouverte")
+	}
+	now := time.Now()
+	assembly.Status = models.AssemblyOpen
+	assembly.OpenedAt = &now
+	return s.assemblyRepo.UpdateAssembly(assembly)
+}
+
+// CloseAssembly closes the session of a general assembly and the votes on its resolutions still
+// open. The resolutions not put to the vote stay drafts, and the minutes are final.
+func (s *AssemblyService) CloseAssembly(userID, id uint) error {
+	assembly, err := s.GetAssembly(userID, id)
+	if err != nil {
+		return err
+	}
+	if assembly.Status != models.AssemblyOpen {
+		return fmt.Errorf("seule une assemblée en séance peut être clôturée")
+	}
+	now := time.Now()
+	polls, err := s.pollRepo.FindPollsByAssemblyID(assembly.ID)
+	if err != nil {
+		return err
+	}
+	for _, poll := range polls {
+		if poll.State(now) != models.PollOpen {
+			continue
+		}
+		poll.Status = models.PollClosed
+		poll.ClosesAt = &now
+		if err := s.pollRepo.UpdatePoll(&poll); err != nil {
+			return err
+		}
+	}
+	assembly.Status = models.AssemblyClosed
+	assembly.ClosedAt = &now
+	return s.assemblyRepo.UpdateAssembly(assembly)
+}
+
+// GetAttendanceSheet returns the attendance sheet of a general assembly: the active members of the
+// association, and the members no longer active recorded as present or represented.
+func (s *AssemblyService) GetAttendanceSheet(assembly *models.Assembly) ([]models.AttendanceLine, error) {
+	members, err := s.memberRepo.FindMembersByUserID(assembly.UserID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Member, len(members))
+	for _, member := range members {
+		byID[member.ID] = member
+	}
+
+	var lines []models.AttendanceLine
+	for _, member := range members {
+		attendance := assembly.Attendance(member.ID)
+		if attendance == nil && member.MembershipStatus != models.StatusActive {
+			continue
+		}
+		line := models.AttendanceLine{Member: member, Status: models.AttendanceAbsent, Weight: 1}
+		if attendance != nil {
+			line.Status = attendance.Status
+			line.Weight = attendance.Weight
+			if attendance.ProxyHolderID != nil {
+				if holder, ok := byID[*attendance.ProxyHolderID]; ok {
+					line.ProxyHolder = &holder
+				}
+			}
+		}
+		for _, other := range assembly.Attendances {
+			if other.Status == models.AttendanceRepresented && other.ProxyHolderID != nil && *other.ProxyHolderID == member.ID {
+				line.Proxies = append(line.Proxies, byID[other.MemberID])
+			}
+		}
+		line.Votes = assembly.Votes(member.ID)
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// RecordAttendance records the attendance sheet of a general assembly, replacing the previous one.
+// Only active members are recorded; a represented member gives its proxy to another member who is
+// present, and a member holds at most as many proxies as the assembly allows. The sheet cannot
+// change once the assembly is closed, nor while a resolution is open to votes, so that the votes
+// carried by the voters stay the same until the vote ends.
+func (s *AssemblyService) RecordAttendance(userID, assemblyID uint, attendances []models.AssemblyAttendance) error {
+	assembly, err := s.GetAssembly(userID, assemblyID)
+	if err != nil {
+		return err
+	}
+	if assembly.Status == models.AssemblyClosed {
+		return fmt.Errorf("la feuille de présence d'une assemblée clôturée ne peut plus être modifiée")
+	}
+	polls, err := s.pollRepo.FindPollsByAssemblyID(assembly.ID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, poll := range polls {
+		if poll.State(now) == models.PollOpen {
+			return fmt.Errorf("la feuille de présence ne peut pas être modifiée pendant le vote d'une résolution")
+		}
+	}
+
+	members, err := s.memberRepo.FindMembersByUserID(assembly.UserID)
+	if err != nil {
+		return err
+	}
+	eligible := make(map[uint]models.Member, len(members))
+	for _, member := range members {
+		if member.MembershipStatus == models.StatusActive {
+			eligible[member.ID] = member
+		}
+	}
+
+	var sheet []models.AssemblyAttendance
+	status := make(map[uint]models.AttendanceStatus, len(attendances))
+	for _, attendance := range attendances {
+		member, ok := eligible[attendance.MemberID]
+		if !ok {
+			return fmt.Errorf("seuls les membres actifs de l'association peuvent participer à l'assemblée")
+		}
+		if !attendance.Status.Valid() {
+			return fmt.Errorf("présence invalide pour %s %s", member.FirstName, member.LastName)
+		}
+		if _, seen := status[attendance.MemberID]; seen {
+			return fmt.Errorf("%s %s figure plusieurs fois sur la feuille de présence", member.FirstName, member.LastName)
+		}
+		status[attendance.MemberID] = attendance.Status
+		if attendance.Status == models.AttendanceAbsent {
+			continue
+		}
+		if attendance.Weight == 0 {
+			attendance.Weight = 1
+		}
+		if attendance.Weight < 1 {
+			return fmt.Errorf("le nombre de voix de %s %s doit être d'au moins 1", member.FirstName, member.LastName)
+		}
+		if attendance.Status == models.AttendancePresent {
+			attendance.ProxyHolderID = nil
+		}
+		attendance.AssemblyID = assembly.ID
+		sheet = append(sheet, attendance)
+	}
+
+	proxies := make(map[uint]int)
+	for _, attendance := range sheet {
+		if attendance.Status != models.AttendanceRepresented {
+			continue
+		}
+		member := eligible[attendance.MemberID]
+		if attendance.ProxyHolderID == nil {
+			return fmt.Errorf("veuillez choisir le mandataire de %s %s", member.FirstName, member.LastName)
+		}
+		holderID := *attendance.ProxyHolderID
+		if holderID == attendance.MemberID {
+			return fmt.Errorf("%s %s ne peut pas se donner procuration", member.FirstName, member.LastName)
+		}
+		holder, ok := eligible[holderID]
+		if !ok || status[holderID] != models.AttendancePresent {
+			return fmt.Errorf("le mandataire de %s %s doit être un membre présent", member.FirstName, member.LastName)
+		}
+		if assembly.MaxProxies == 0 {
+			return fmt.Errorf("les procurations ne sont pas autorisées pour cette assemblée")
+		}
+		proxies[holderID]++
+		if proxies[holderID] > assembly.MaxProxies {
+			return fmt.Errorf("%s %s ne peut pas détenir plus de %d procuration(s)", holder.FirstName, holder.LastName, assembly.MaxProxies)
+		}
+	}
+
+	return s.assemblyRepo.ReplaceAttendances(assembly.ID, sheet)
+}
+
+// GetQuorum counts the members present or represented at a general assembly against the active
+// members of the association. The quorum is reached when they are at least the share of the
+// active members the assembly requires, rounded up.
+func (s *AssemblyService) GetQuorum(assembly *models.Assembly) (*models.Quorum, error) {
+	counts, err := s.memberRepo.GetMembersCountByStatus(assembly.UserID)
+	if err != nil {
+		return nil, err
+	}
+	quorum := &models.Quorum{ActiveMembers: counts[models.StatusActive], Percent: assembly.QuorumPercent}
+	quorum.Required = int((quorum.ActiveMembers*int64(quorum.Percent) + 99) / 100)
+	for _, attendance := range assembly.Attendances {
+		switch attendance.Status {
+		case models.AttendancePresent:
+			quorum.Present++
+		case models.AttendanceRepresented:
+			quorum.Represented++
+		default:
+			continue
+		}
+		quorum.Votes += attendance.Weight
+	}
+	quorum.Reached = quorum.Attending() >= quorum.Required
+	return quorum, nil
+}
+
+// GetResolutions returns the resolutions of a general assembly with the count of their ballots.
+func (s *AssemblyService) GetResolutions(assembly *models.Assembly) ([]models.ResolutionResult, error) {
+	polls, err := s.pollRepo.FindPollsByAssemblyID(assembly.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var resolutions []models.ResolutionResult
+	for _, poll := range polls {
+		tally, err := tallyPollBallots(s.voteRepo, &poll)
+		if err != nil {
+			return nil, err
+		}
+		resolutions = append(resolutions, models.ResolutionResult{
+			Poll:    poll,
+			State:   poll.State(now),
+			Tally:   tally,
+			Outcome: resolutionOutcome(&poll, tally),
+		})
+	}
+	return resolutions, nil
+}
+
+// GetMinutes gathers what the minutes of a general assembly of the user's association record.
+func (s *AssemblyService) GetMinutes(userID, id uint) (*models.AssemblyMinutes, error) {
+	assembly, err := s.GetAssembly(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	minutes := &models.AssemblyMinutes{Assembly: *assembly, Association: "Votre association"}
+	if settings, err := s.settingsService.GetSettings(assembly.UserID); err == nil && settings.LegalName != "" {
+		minutes.Association = settings.LegalName
+	}
+	quorum, err := s.GetQuorum(assembly)
+	if err != nil {
+		return nil, err
+	}
+	minutes.Quorum = *quorum
+	if minutes.Attendance, err = s.GetAttendanceSheet(assembly); err != nil {
+		return nil, err
+	}
+	if minutes.Resolutions, err = s.GetResolutions(assembly); err != nil {
+		return nil, err
+	}
+	return minutes, nil
+}
+
+// GenerateMinutesPDF renders the minutes of a general assembly of the user's association as a PDF
+// and returns it with its file name.
+func (s *AssemblyService) GenerateMinutesPDF(userID, id uint) ([]byte, string, error) {
+	minutes, err := s.GetMinutes(userID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	fileName := fmt.Sprintf("proces-verbal-ag-%s.pdf", minutes.Assembly.Date.Format("2006-01-02"))
+	return renderAssemblyMinutes(minutes).Bytes(), fileName, nil
+}
+
+// VoterWeight returns the votes a user casts on the resolutions of a general assembly in session:
+// those of the member of the association it is, if that member is present, and of the members
+// that member represents.
+func (s *AssemblyService) VoterWeight(user models.User, assemblyID uint) (int, error) {
+	assembly, err := s.assemblyRepo.FindAssemblyByID(assemblyID)
+	if err != nil {
+		return 0, fmt.Errorf("assemblée non trouvée")
+	}
+	if assembly.Status != models.AssemblyOpen {
+		return 0, fmt.Errorf("l'assemblée n'est pas en séance")
+	}
+	email := strings.TrimSpace(user.Email)
+	if email == "" {
+		return 0, fmt.Errorf("seuls les membres présents à l'assemblée peuvent voter ses résolutions")
+	}
+	members, err := s.memberRepo.FindMembersByEmail(email)
+	if err != nil {
+		return 0, err
+	}
+	var representedBy string
+	for _, member := range members {
+		if member.UserID != assembly.UserID || member.MembershipStatus != models.StatusActive {
+			continue
+		}
+		attendance := assembly.Attendance(member.ID)
+		if attendance == nil {
+			continue
+		}
+		switch attendance.Status {
+		case models.AttendancePresent:
+			return assembly.Votes(member.ID), nil
+		case models.AttendanceRepresented:
+			representedBy = "un autre membre"
+			if attendance.ProxyHolderID != nil {
+				if holder, err := s.memberRepo.FindMemberByID(*attendance.ProxyHolderID); err == nil {
+					representedBy = holder.FirstName + " " + holder.LastName
+				}
+			}
+		}
+	}
+	if representedBy != "" {
+		return 0, fmt.Errorf("vous êtes représenté(e) par %s : votre mandataire vote pour vous", representedBy)
+	}
+	return 0, fmt.Errorf("seuls les membres présents à l'assemblée peuvent voter ses résolutions")
+}
+
+// checkResolution checks that a poll of the user can be a resolution of a general assembly: the
+// assembly is one of the user's association, and is not closed.
+func (s *AssemblyService) checkResolution(userID, assemblyID uint) error {
+	assembly, err := s.GetAssembly(userID, assemblyID)
+	if err != nil {
+		return err
+	}
+	if assembly.Status == models.AssemblyClosed {
+		return fmt.Errorf("une assemblée clôturée ne peut plus recevoir de résolutions")
+	}
+	return nil
+}
+
+// checkResolutionOpening checks that a resolution of a general assembly can be opened to votes:
+// the assembly is in session and the quorum is reached.
+func (s *AssemblyService) checkResolutionOpening(assemblyID uint) error {
+	assembly, err := s.assemblyRepo.FindAssemblyByID(assemblyID)
+	if err != nil {
+		return fmt.Errorf("assemblée non trouvée")
+	}
+	if assembly.Status != models.AssemblyOpen {
+		return fmt.Errorf("une résolution ne peut être ouverte au vote que pendant la séance de l'assemblée")
+	}
+	quorum, err := s.GetQuorum(assembly)
+	if err != nil {
+		return err
+	}
+	if !quorum.Reached {
+		return fmt.Errorf("le quorum n'est pas atteint (%d membre(s) présent(s) ou représenté(s) sur %d requis)", quorum.Attending(), quorum.Required)
+	}
+	return nil
+}
+
+// resolutionOutcome describes the result of a resolution: the winning options, the options tied,
+// or the lack of votes.
+func resolutionOutcome(poll *models.Poll, tally *models.PollTally) string {
+	if tally.Votes == 0 {
+		return "Aucun vote"
+	}
+	var winners []uint
+	if tally.Type == models.PollRanked {
+		if tally.InstantRunoff != nil {
+			winners = tally.InstantRunoff.Winners
+		}
+	} else {
+		for _, option := range tally.Options {
+			if option.Leading {
+				winners = append(winners, option.OptionID)
+			}
+		}
+	}
+	texts := make([]string, len(winners))
+	for i, optionID := range winners {
+		texts[i] = tally.OptionText(optionID)
+	}
+	if len(winners) > leadingCount(poll) {
+		return "Égalité : " + strings.Join(texts, ", ")
+	}
+	return strings.Join(texts, ", ")
+}
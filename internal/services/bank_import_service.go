@@ -0,0 +1,300 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// matchDateTolerance is the maximum gap between the bank booking date and the recorded date
+// for an existing transaction to be proposed as a match.
+const matchDateTolerance = 5 * 24 * time.Hour
+
+// BankImportService encapsulates the business logic for importing bank statements
+// and reconciling them with the transactions recorded by hand.
+type BankImportService struct {
+	transactionRepo repositories.TransactionRepository
+	financeService  *FinanceService
+	periodService   *FiscalPeriodService
+}
+
+// NewBankImportService creates a new instance of BankImportService.
+// It takes a TransactionRepository, the FinanceService (used to post accepted movements
+// to the journal) and a FiscalPeriodService as dependencies, adhering to the dependency
+// inversion principle.
+func NewBankImportService(transactionRepo repositories.TransactionRepository, financeService *FinanceService, periodService *FiscalPeriodService) *BankImportService {
+	return &BankImportService{transactionRepo: transactionRepo, financeService: financeService, periodService: periodService}
+}
+
+// ImportStatement parses a bank statement and creates a draft transaction for each new movement.
+// Movements already imported and movements of a closed fiscal year are skipped, and each draft is
+// automatically matched to an existing unreconciled transaction when one looks equivalent.
+func (s *BankImportService) ImportStatement(userID uint, format models.BankStatementFormat, r io.Reader, mapping models.CSVColumnMapping) (*models.ImportResult, error) {
+	lines, err := ParseBankStatement(format, r, mapping)
+	if err != nil {
+		return nil, err
+	}
+	baseCurrency, err := s.financeService.GetBaseCurrency(userID)
+	if err != nil {
+		return nil, fmt.Errorf("erreur lors de la récupération de la devise de référence: %w", err)
+	}
+	// The imported account is expected to be held in the base currency of the association.
+	for _, line := range lines {
+		if line.Amount.Currency != "" && line.Amount.Currency != baseCurrency {
+			return nil, fmt.Errorf("le relevé est en %s alors que la devise de référence est %s", line.Amount.Currency, baseCurrency)
+		}
+	}
+
+	candidates := map[models.TransactionType][]models.Transaction{}
+	for _, transactionType := range []models.TransactionType{models.TypeIncome, models.TypeExpense} {
+		candidates[transactionType], err = s.transactionRepo.FindUnreconciledTransactions(userID, transactionType)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la recherche des transactions à rapprocher: %w", err)
+		}
+	}
+	// Each existing transaction may only be proposed once per import.
+	used := map[uint]bool{}
+
+	// Identical movements of the same statement, e.g., two equal card payments on the same day,
+	// are told apart by their rank when the bank gives no reference.
+	occurrences := map[string]int{}
+
+	result := &models.ImportResult{}
+	for _, line := range lines {
+		if line.Amount.Amount == 0 {
+			continue
+		}
+		reference := line.Reference
+		if reference == "" {
+			key := movementKey(line)
+			reference = derivedReference(key, occurrences[key])
+			occurrences[key]++
+		}
+		exists, err := s.transactionRepo.BankReferenceExists(userID, reference)
+		if err != nil {
+			return nil, fmt.Errorf("erreur lors de la détection des doublons: %w", err)
+		}
+		if exists {
+			result.Duplicates++
+			continue
+		}
+		// A closed fiscal year cannot receive new transactions.
+		closed, err := s.periodService.IsClosed(userID, line.Date)
+		if err != nil {
+			return nil, err
+		}
+		if closed {
+			result.Closed++
+			continue
+		}
+
+		amount := line.Amount.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		draft := &models.Transaction{
+			Amount:        models.Money{Amount: amount, Currency: baseCurrency},
+			ExchangeRate:  "1",
+			BaseAmount:    amount,
+			Type:          models.TypeIncome,
+			Description:   strings.TrimSpace(line.Description),
+			Date:          line.Date,
+			UserID:        userID,
+			Status:        models.TransactionDraft,
+			BankReference: reference,
+		}
+		if line.Amount.Amount < 0 {
+			draft.Type = models.TypeExpense
+		}
+		if draft.Description == "" {
+			draft.Description = "Opération bancaire"
+		}
+
+		if match := findBestMatch(draft, candidates[draft.Type], used); match != nil {
+			used[match.ID] = true
+			matchID := match.ID
+			draft.MatchedTransactionID = &matchID
+			result.Matched++
+		}
+
+		if err := s.transactionRepo.CreateTransaction(draft); err != nil {
+			return nil, fmt.Errorf("erreur lors de la création de la transaction importée: %w", err)
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// GetReconciliationItems returns the pending draft transactions of a user together with their
+// proposed matches and the unreconciled transactions that could be chosen instead.
+func (s *BankImportService) GetReconciliationItems(userID uint) ([]models.ReconciliationItem, error) {
+	drafts, err := s.transactionRepo.FindTransactionsByStatus(userID, models.TransactionDraft)
+	if err != nil {
+		return nil, err
+	}
+	unreconciled, err := s.transactionRepo.FindUnreconciledTransactions(userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var items []models.ReconciliationItem
+	for _, draft := range drafts {
+		item := models.ReconciliationItem{Draft: draft}
+		for i, candidate := range unreconciled {
+			if candidate.Type != draft.Type {
+				continue
+			}
+			item.Options = append(item.Options, candidate)
+			if draft.MatchedTransactionID != nil && *draft.MatchedTransactionID == candidate.ID {
+				item.Match = &unreconciled[i]
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// GetUnreconciledTransactions returns the posted transactions of a user that no bank
+// statement line has confirmed yet.
+func (s *BankImportService) GetUnreconciledTransactions(userID uint) ([]models.Transaction, error) {
+	return s.transactionRepo.FindUnreconciledTransactions(userID, "")
+}
+
+// ConfirmMatch reconciles a draft transaction with an existing transaction.
+// The existing transaction is flagged as reconciled and inherits the bank reference,
+// and the draft, now redundant, is deleted. A transaction of a closed fiscal year cannot be
+// reconciled.
+func (s *BankImportService) ConfirmMatch(userID, draftID, transactionID uint) error {
+	draft, err := s.findDraft(userID, draftID)
+	if err != nil {
+		return err
+	}
+	transaction, err := s.transactionRepo.FindTransactionByID(transactionID)
+	if err != nil {
+		return fmt.Errorf("transaction à rapprocher non trouvée: %w", err)
+	}
+	if transaction.UserID != userID || transaction.Status != models.TransactionPosted {
+		return fmt.Errorf("transaction à rapprocher invalide")
+	}
+	if transaction.Reconciled {
+		return fmt.Errorf("cette transaction est déjà rapprochée")
+	}
+	if transaction.Type != draft.Type {
+		return fmt.Errorf("le type de la transaction ne correspond pas à l'opération bancaire")
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+
+	transaction.Reconciled = true
+	transaction.BankReference = draft.BankReference
+	if err := s.transactionRepo.UpdateTransaction(transaction); err != nil {
+		return err
+	}
+	return s.transactionRepo.DeleteTransaction(draft.ID)
+}
+
+// AcceptAsNew turns a draft transaction into a posted, reconciled transaction.
+// It is used for bank movements that were never entered by hand. An expense above the approval
+// threshold is submitted to the treasurer instead of being posted. It returns the status given to
+// the transaction.
+func (s *BankImportService) AcceptAsNew(userID, draftID uint) (models.TransactionStatus, error) {
+	draft, err := s.findDraft(userID, draftID)
+	if err != nil {
+		return "", err
+	}
+	draft.Reconciled = true
+	draft.MatchedTransactionID = nil
+	return s.financeService.AcceptDraft(draft)
+}
+
+// DiscardDraft deletes a draft transaction, e.g., an internal transfer that should not be recorded.
+func (s *BankImportService) DiscardDraft(userID, draftID uint) error {
+	draft, err := s.findDraft(userID, draftID)
+	if err != nil {
+		return err
+	}
+	return s.transactionRepo.DeleteTransaction(draft.ID)
+}
+
+// findDraft retrieves a draft transaction and ensures it belongs to the given user.
+func (s *BankImportService) findDraft(userID, draftID uint) (*models.Transaction, error) {
+	draft, err := s.transactionRepo.FindTransactionByID(draftID)
+	if err != nil {
+		return nil, fmt.Errorf("opération importée non trouvée: %w", err)
+	}
+	if draft.UserID != userID {
+		return nil, fmt.Errorf("accès non autorisé")
+	}
+	if draft.Status != models.TransactionDraft {
+		return nil, fmt.Errorf("cette opération a déjà été traitée")
+	}
+	return draft, nil
+}
+
+// findBestMatch returns the unused candidate with the same base-currency amount dated within
+// matchDateTolerance of the draft that shares the most description words with it, as the label
+// identifies the counterpart better than a few days of bank processing. Ties are broken by the
+// closest date.
+func findBestMatch(draft *models.Transaction, candidates []models.Transaction, used map[uint]bool) *models.Transaction {
+	var best *models.Transaction
+	var bestGap time.Duration
+	bestScore := -1
+	for i, candidate := range candidates {
+		if used[candidate.ID] || candidate.BaseAmount != draft.BaseAmount {
+			continue
+		}
+		gap := candidate.Date.Sub(draft.Date)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > matchDateTolerance {
+			continue
+		}
+		score := commonWords(candidate.Description, draft.Description)
+		if best == nil || score > bestScore || (score == bestScore && gap < bestGap) {
+			best, bestGap, bestScore = &candidates[i], gap, score
+		}
+	}
+	return best
+}
+
+// movementKey identifies a bank movement by its date, signed amount and label, ignoring case and
+// spacing, for the movements the bank gives no reference.
+func movementKey(line models.BankStatementLine) string {
+	description := strings.Join(strings.Fields(strings.ToLower(line.Description)), " ")
+	return line.Date.Format("2006-01-02") + "|" + strconv.FormatInt(line.Amount.Amount, 10) + "|" + description
+}
+
+// derivedReference returns the bank reference recorded for a movement without one: a hash of its
+// key and of its rank among the identical movements of the statement, so that importing the same
+// statement again, or one overlapping it, detects the duplicates.
+func derivedReference(key string, occurrence int) string {
+	sum := sha256.Sum256([]byte(key + "|" + strconv.Itoa(occurrence)))
+	return "auto-" + hex.EncodeToString(sum[:16])
+}
+
+// commonWords counts the case-insensitive words of at least three letters shared by two descriptions.
+func commonWords(a, b string) int {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(strings.ToLower(a)) {
+		if len(word) >= 3 {
+			words[word] = true
+		}
+	}
+	count := 0
+	for _, word := range strings.Fields(strings.ToLower(b)) {
+		if words[word] {
+			count++
+			delete(words, word)
+		}
+	}
+	return count
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// newTestBankImportService returns a BankImportService of association 1 backed by an in-memory
+// database.
+func newTestBankImportService(t *testing.T) (*BankImportService, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t, &repositories.TransactionDB{}, &repositories.AccountDB{}, &repositories.JournalEntryDB{}, &repositories.JournalLineDB{}, &repositories.AssociationSettingsDB{}, &repositories.FiscalPeriodClosureDB{}, &repositories.FiscalPeriodEventDB{})
+	transactionRepo := repositories.NewGormTransactionRepository(db)
+	settingsService := NewSettingsService(repositories.NewGormSettingsRepository(db), transactionRepo)
+	periodService := NewFiscalPeriodService(repositories.NewGormFiscalPeriodRepository(db), transactionRepo, settingsService)
+	financeService := NewFinanceService(transactionRepo, NewLedgerService(repositories.NewGormLedgerRepository(db), periodService), settingsService, periodService)
+	return NewBankImportService(transactionRepo, financeService, periodService), db
+}
+
+func TestImportStatement(t *testing.T) {
+	mapping := models.CSVColumnMapping{Separator: ";", DateColumn: 1, DescriptionColumn: 2, AmountColumn: 3, ReferenceColumn: 4, DecimalComma: true}
+	statement := strings.Join([]string{
+		"03/02/2025;Cotisation Dupont;25,00;",
+		"04/02/2025;CB Boulangerie;-12,50;",
+		"04/02/2025;CB  boulangerie;-12,50;", // Second identical purchase on the same day.
+		"05/02/2025;Subvention mairie;500,00;VIR-1",
+		"15/12/2024;Frais bancaires;-3,00;", // In the closed fiscal year.
+	}, "\n")
+
+	tests := []struct {
+		name   string
+		input  string
+		result models.ImportResult
+	}{
+		{name: "first import", input: statement, result: models.ImportResult{Imported: 4, Closed: 1}},
+		{name: "same statement again", input: statement, result: models.ImportResult{Duplicates: 4, Closed: 1}},
+		{name: "overlapping statement", input: "04/02/2025;CB Boulangerie;-12,50;\n04/02/2025;CB Boulangerie;-12,50;\n04/02/2025;CB Boulangerie;-12,50;\n06/02/2025;Cotisation Martin;25,00;", result: models.ImportResult{Imported: 2, Duplicates: 2}},
+	}
+
+	service, db := newTestBankImportService(t)
+	closure := repositories.FiscalPeriodClosureDB{UserID: 1, FiscalYear: 2024, Label: "2024", StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), ClosedAt: time.Now()}
+	if err := db.Create(&closure).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The imports are applied in sequence to the same association.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ImportStatement(1, models.FormatCSV, strings.NewReader(tt.input), mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *result != tt.result {
+				t.Errorf("result = %+v, want %+v", *result, tt.result)
+			}
+		})
+	}
+}
+
+func TestFindBestMatch(t *testing.T) {
+	day := time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC)
+	candidate := func(id uint, daysAfter int, amount int64, description string) models.Transaction {
+		return models.Transaction{Model: gorm.Model{ID: id}, Date: day.AddDate(0, 0, daysAfter), BaseAmount: amount, Description: description}
+	}
+	draft := &models.Transaction{Date: day, BaseAmount: 2500, Description: "VIR SEPA Cotisation Dupont"}
+
+	tests := []struct {
+		name       string
+		candidates []models.Transaction
+		used       map[uint]bool
+		want       uint
+	}{
+		{name: "more words in common", candidates: []models.Transaction{candidate(1, 0, 2500, "Don anonyme"), candidate(2, 4, 2500, "Cotisation Dupont")}, want: 2},
+		{name: "closest date on equal words", candidates: []models.Transaction{candidate(1, -3, 2500, "Cotisation Dupont"), candidate(2, 1, 2500, "Cotisation Dupont")}, want: 2},
+		{name: "different amount", candidates: []models.Transaction{candidate(1, 0, 2400, "Cotisation Dupont")}},
+		{name: "outside the tolerance", candidates: []models.Transaction{candidate(1, 6, 2500, "Cotisation Dupont")}},
+		{name: "already used", candidates: []models.Transaction{candidate(1, 0, 2500, "Cotisation Dupont"), candidate(2, 2, 2500, "Don")}, used: map[uint]bool{1: true}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uint
+			if match := findBestMatch(draft, tt.candidates, tt.used); match != nil {
+				got = match.ID
+			}
+			if got != tt.want {
+				t.Errorf("findBestMatch = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
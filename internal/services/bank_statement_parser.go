@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// ParseBankStatement reads a bank statement in the given format and returns its movements.
+// The CSV column mapping is only used for the CSV format.
+func ParseBankStatement(format models.BankStatementFormat, r io.Reader, mapping models.CSVColumnMapping) ([]models.BankStatementLine, error) {
+	switch format {
+	case models.FormatCSV:
+		return parseCSVStatement(r, mapping)
+	case models.FormatOFX:
+		return parseOFXStatement(r)
+	case models.FormatCAMT053:
+		return parseCAMT053Statement(r)
+	default:
+		return nil, fmt.Errorf("format de relevé non supporté: %s", format)
+	}
+}
+
+// parseCSVStatement reads a CSV bank export using the provided column mapping.
+func parseCSVStatement(r io.Reader, mapping models.CSVColumnMapping) ([]models.BankStatementLine, error) {
+	if mapping.Separator == "" {
+		mapping.Separator = ";"
+	}
+	if mapping.DateFormat == "" {
+		mapping.DateFormat = "02/01/2006"
+	}
+	if mapping.DateColumn <= 0 {
+		return nil, fmt.Errorf("la colonne de date est requise")
+	}
+	if mapping.AmountColumn <= 0 && (mapping.DebitColumn <= 0 || mapping.CreditColumn <= 0) {
+		return nil, fmt.Errorf("une colonne de montant, ou des colonnes débit et crédit, sont requises")
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(mapping.Separator)[0]
+	reader.FieldsPerRecord = -1 // Bank exports often have trailing or missing cells.
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("fichier CSV invalide: %w", err)
+	}
+	if mapping.HasHeader && len(records) > 0 {
+		records = records[1:]
+	}
+
+	var lines []models.BankStatementLine
+	for i, record := range records {
+		row := i + 1
+		if mapping.HasHeader {
+			row++
+		}
+		// Skip blank rows that some banks append at the end of the export.
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		date, err := time.Parse(mapping.DateFormat, csvCell(record, mapping.DateColumn))
+		if err != nil {
+			return nil, fmt.Errorf("ligne %d: date invalide: %w", row, err)
+		}
+
+		var amount models.Money
+		if mapping.DebitColumn > 0 && mapping.CreditColumn > 0 {
+			debit, err := parseStatementAmount(csvCell(record, mapping.DebitColumn), mapping.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("ligne %d: débit invalide: %w", row, err)
+			}
+			credit, err := parseStatementAmount(csvCell(record, mapping.CreditColumn), mapping.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("ligne %d: crédit invalide: %w", row, err)
+			}
+			// Some banks export debits as negative numbers, others as positive ones.
+			if debit.Amount < 0 {
+				debit.Amount = -debit.Amount
+			}
+			amount = models.Money{Amount: credit.Amount - debit.Amount}
+		} else {
+			amount, err = parseStatementAmount(csvCell(record, mapping.AmountColumn), mapping.DecimalComma)
+			if err != nil {
+				return nil, fmt.Errorf("ligne %d: montant invalide: %w", row, err)
+			}
+		}
+
+		lines = append(lines, models.BankStatementLine{
+			Date:        date,
+			Amount:      amount,
+			Description: csvCell(record, mapping.DescriptionColumn),
+			Reference:   csvCell(record, mapping.ReferenceColumn),
+		})
+	}
+	return lines, nil
+}
+
+// csvCell returns the trimmed value of a 1-based column, or an empty string if the column is unused or missing.
+func csvCell(record []string, column int) string {
+	if column <= 0 || column > len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[column-1])
+}
+
+// parseStatementAmount converts a bank formatted amount (e.g., "-1 234,56 €") to an exact amount
+// whose currency is left unknown. An empty value is treated as zero so that empty debit or credit
+// cells are accepted.
+func parseStatementAmount(value string, decimalComma bool) (models.Money, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+', r == '.', r == ',':
+			return r
+		default:
+			// Drop currency symbols, spaces and non-breaking spaces used as thousands separators.
+			return -1
+		}
+	}, value)
+	if cleaned == "" {
+		return models.Money{}, nil
+	}
+	if decimalComma {
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	} else {
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	}
+	return statementMoney(cleaned, "")
+}
+
+// statementMoney parses a decimal amount read from a statement in the given currency.
+// Amounts of statements that do not state their currency are read with two decimals.
+func statementMoney(value, currency string) (models.Money, error) {
+	decimals := 2
+	if currency != "" {
+		var ok bool
+		if decimals, ok = models.CurrencyDecimals(currency); !ok {
+			return models.Money{}, fmt.Errorf("devise non supportée: %q", currency)
+		}
+	}
+	amount, err := ParseMinorUnits(value, decimals)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return models.Money{Amount: amount, Currency: currency}, nil
+}
+
+// parseOFXStatement reads the STMTTRN blocks of an OFX file.
+// Both OFX 1.x (SGML, where leaf elements are not closed) and OFX 2.x (XML) are supported
+// by scanning tags sequentially instead of relying on an XML parser.
+func parseOFXStatement(r io.Reader) ([]models.BankStatementLine, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("impossible de lire le fichier OFX: %w", err)
+	}
+
+	var lines []models.BankStatementLine
+	var current map[string]string
+	currency := "" // Default currency of the statement (CURDEF), declared before the transaction list.
+	for _, chunk := range strings.Split(string(content), "<")[1:] {
+		end := strings.Index(chunk, ">")
+		if end < 0 {
+			continue
+		}
+		tag := strings.ToUpper(strings.TrimSpace(chunk[:end]))
+		value := strings.TrimSpace(chunk[end+1:])
+
+		switch {
+		case tag == "CURDEF":
+			currency = strings.ToUpper(value)
+		case tag == "STMTTRN":
+			current = map[string]string{}
+		case tag == "/STMTTRN":
+			if current == nil {
+				continue
+			}
+			line, err := ofxTransactionToLine(current, currency)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+			current = nil
+		case current != nil && !strings.HasPrefix(tag, "/"):
+			current[tag] = value
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("aucune opération trouvée dans le fichier OFX")
+	}
+	return lines, nil
+}
+
+// ofxTransactionToLine converts the fields of an OFX STMTTRN block into a statement line.
+func ofxTransactionToLine(fields map[string]string, currency string) (models.BankStatementLine, error) {
+	amount, err := statementMoney(fields["TRNAMT"], currency)
+	if err != nil {
+		return models.BankStatementLine{}, fmt.Errorf("montant OFX invalide %q: %w", fields["TRNAMT"], err)
+	}
+	// DTPOSTED is YYYYMMDD optionally followed by a time and a timezone; only the date matters here.
+	posted := fields["DTPOSTED"]
+	if len(posted) < 8 {
+		return models.BankStatementLine{}, fmt.Errorf("date OFX invalide %q", posted)
+	}
+	date, err := time.Parse("20060102", posted[:8])
+	if err != nil {
+		return models.BankStatementLine{}, fmt.Errorf("date OFX invalide %q: %w", posted, err)
+	}
+
+	description := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" && memo != description {
+		description = strings.TrimSpace(description + " " + memo)
+	}
+
+	return models.BankStatementLine{
+		Date:        date,
+		Amount:      amount,
+		Description: description,
+		Reference:   fields["FITID"],
+	}, nil
+}
+
+// camtDocument maps the subset of an ISO 20022 camt.053 document needed for the import.
+// Element names are matched without namespace so that every camt.053 version is accepted.
+type camtDocument struct {
+	Statements []struct {
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// camtEntry maps a single Ntry element of a camt.053 statement.
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit    string `xml:"CdtDbtInd"`
+	BookingDate    string `xml:"BookgDt>Dt"`
+	BookingTime    string `xml:"BookgDt>DtTm"`
+	ValueDate      string `xml:"ValDt>Dt"`
+	ServicerRef    string `xml:"AcctSvcrRef"`
+	EntryRef       string `xml:"NtryRef"`
+	AdditionalInfo string `xml:"AddtlNtryInf"`
+	Details        []struct {
+		EndToEndID   string   `xml:"Refs>EndToEndId"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// parseCAMT053Statement reads the entries of an ISO 20022 camt.053 statement.
+func parseCAMT053Statement(r io.Reader) ([]models.BankStatementLine, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("fichier CAMT.053 invalide: %w", err)
+	}
+
+	var lines []models.BankStatementLine
+	for _, stmt := range doc.Statements {
+		for _, entry := range stmt.Entries {
+			amount, err := statementMoney(strings.TrimSpace(entry.Amount.Value), strings.ToUpper(strings.TrimSpace(entry.Amount.Currency)))
+			if err != nil {
+				return nil, fmt.Errorf("montant CAMT.053 invalide %q: %w", entry.Amount.Value, err)
+			}
+			if entry.CreditDebit == "DBIT" {
+				amount.Amount = -amount.Amount
+			}
+
+			rawDate := entry.BookingDate
+			if rawDate == "" && len(entry.BookingTime) >= 10 {
+				rawDate = entry.BookingTime[:10]
+			}
+			if rawDate == "" {
+				rawDate = entry.ValueDate
+			}
+			date, err := time.Parse("2006-01-02", rawDate)
+			if err != nil {
+				return nil, fmt.Errorf("date CAMT.053 invalide %q: %w", rawDate, err)
+			}
+
+			var descriptionParts []string
+			reference := entry.ServicerRef
+			if reference == "" {
+				reference = entry.EntryRef
+			}
+			for _, detail := range entry.Details {
+				descriptionParts = append(descriptionParts, detail.Unstructured...)
+				if reference == "" && detail.EndToEndID != "" && detail.EndToEndID != "NOTPROVIDED" {
+					reference = detail.EndToEndID
+				}
+			}
+			if len(descriptionParts) == 0 && entry.AdditionalInfo != "" {
+				descriptionParts = append(descriptionParts, entry.AdditionalInfo)
+			}
+
+			lines = append(lines, models.BankStatementLine{
+				Date:        date,
+				Amount:      amount,
+				Description: strings.TrimSpace(strings.Join(descriptionParts, " ")),
+				Reference:   reference,
+			})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("aucune opération trouvée dans le fichier CAMT.053")
+	}
+	return lines, nil
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// DirectDebitService encapsulates the business logic for collecting dues by SEPA direct debit:
+// the mandates signed by the members, and the pain.008 batches the treasurer uploads to the bank.
+// The income of each debit is recorded as pending until the bank collects it.
+type DirectDebitService struct {
+	debitRepo       repositories.DirectDebitRepository
+	memberService   *MemberService
+	financeService  *FinanceService
+	settingsService *SettingsService
+}
+
+// NewDirectDebitService creates a new instance of DirectDebitService.
+// It takes a DirectDebitRepository, a MemberService, a FinanceService and a SettingsService as
+// dependencies, adhering to the dependency inversion principle.
+func NewDirectDebitService(debitRepo repositories.DirectDebitRepository, memberService *MemberService, financeService *FinanceService, settingsService *SettingsService) *DirectDebitService {
+	return &DirectDebitService{debitRepo: debitRepo, memberService: memberService, financeService: financeService, settingsService: settingsService}
+}
+
+// CreateMandate validates and records the SEPA mandate signed by a member. A member has at most one
+// active mandate, and mandate references are unique within the association. A reference is
+// generated when none is given.
+func (s *DirectDebitService) CreateMandate(mandate *models.SEPAMandate) error {
+	member, err := s.memberService.GetMemberByID(mandate.MemberID)
+	if err != nil || member.UserID != mandate.UserID {
+		return fmt.Errorf("membre invalide")
+	}
+	mandate.IBAN = NormalizeIBAN(mandate.IBAN)
+	mandate.BIC = NormalizeIBAN(mandate.BIC)
+	mandate.Reference = strings.TrimSpace(mandate.Reference)
+	if mandate.Reference == "" {
+		mandate.Reference = fmt.Sprintf("ADH%06d-%s", member.ID, time.Now().Format("20060102150405"))
+	}
+	if err := ValidateIBAN(mandate.IBAN); err != nil {
+		return err
+	}
+	if mandate.BIC != "" {
+		if err := ValidateBIC(mandate.BIC); err != nil {
+			return err
+		}
+	}
+	if err := ValidateMandateReference(mandate.Reference); err != nil {
+		return err
+	}
+	if mandate.SignatureDate.IsZero() {
+		return fmt.Errorf("la date de signature est requise")
+	}
+	if mandate.SignatureDate.After(time.Now()) {
+		return fmt.Errorf("la date de signature ne peut pas être dans le futur")
+	}
+
+	mandates, err := s.debitRepo.FindMandatesByUserID(mandate.UserID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range mandates {
+		if existing.Reference == mandate.Reference {
+			return fmt.Errorf("la référence de mandat %s est déjà utilisée", mandate.Reference)
+		}
+		if existing.MemberID == mandate.MemberID && existing.Active() {
+			return fmt.Errorf("ce membre a déjà un mandat actif : révoquez-le avant d'en enregistrer un nouveau")
+		}
+	}
+	mandate.LastCollectionDate = nil
+	mandate.RevokedAt = nil
+	return s.debitRepo.CreateMandate(mandate)
+}
+
+// GetMandates retrieves the SEPA mandates of an association, most recently signed first.
+func (s *DirectDebitService) GetMandates(userID uint) ([]models.SEPAMandate, error) {
+	return s.debitRepo.FindMandatesByUserID(userID)
+}
+
+// RevokeMandate ends a SEPA mandate; no further debit can be collected under it.
+func (s *DirectDebitService) RevokeMandate(userID, mandateID uint) error {
+	mandate, err := s.debitRepo.FindMandateByID(mandateID)
+	if err != nil || mandate.UserID != userID {
+		return fmt.Errorf("mandat introuvable")
+	}
+	if !mandate.Active() {
+		return fmt.Errorf("ce mandat est déjà révoqué")
+	}
+	now := time.Now()
+	mandate.RevokedAt = &now
+	return s.debitRepo.UpdateMandate(mandate)
+}
+
+// GetDueMembers returns the members whose dues can be collected on the given date: they have an
+// active mandate, are not inactive, have not paid since the start of the fiscal year of that date
+// and have no debit awaiting collection.
+func (s *DirectDebitService) GetDueMembers(userID uint, collectionDate time.Time) ([]models.DirectDebitCandidate, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	yearStart, _ := settings.FiscalYearBounds(settings.FiscalYear(collectionDate))
+
+	mandates, err := s.debitRepo.FindMandatesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	batches, err := s.debitRepo.FindBatchesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	pending := map[uint]bool{}
+	for _, batch := range batches {
+		for _, item := range batch.Items {
+			if item.Status == models.DebitItemPending {
+				pending[item.MemberID] = true
+			}
+		}
+	}
+	members, err := s.memberService.GetMembersByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	membersByID := map[uint]models.Member{}
+	for _, member := range members {
+		membersByID[member.ID] = member
+	}
+
+	var candidates []models.DirectDebitCandidate
+	for _, mandate := range mandates {
+		member, ok := membersByID[mandate.MemberID]
+		if !ok || !mandate.Active() || pending[member.ID] || member.MembershipStatus == models.StatusInactive {
+			continue
+		}
+		if member.LastPaymentDate != nil && !member.LastPaymentDate.Before(yearStart) {
+			continue
+		}
+		candidates = append(candidates, models.DirectDebitCandidate{Member: member, Mandate: mandate})
+	}
+	return candidates, nil
+}
+
+// GenerateBatch creates a direct debit batch collecting the given amount from each of the selected
+// due members on the collection date. A pending income is recorded for each debit, and the
+// pain.008 file to upload to the bank is generated and kept with the batch.
+func (s *DirectDebitService) GenerateBatch(userID uint, memberIDs []uint, amount models.Money, collectionDate time.Time, description string) (*models.DirectDebitBatch, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.SEPACreditorID == "" || settings.SEPAIBAN == "" || strings.TrimSpace(settings.LegalName) == "" {
+		return nil, fmt.Errorf("renseignez la dénomination, l'identifiant créancier SEPA et l'IBAN de l'association dans les paramètres")
+	}
+	if settings.BaseCurrency != models.SEPACurrency || amount.Currency != models.SEPACurrency {
+		return nil, fmt.Errorf("les prélèvements SEPA sont libellés en euros")
+	}
+	if amount.Amount <= 0 {
+		return nil, fmt.Errorf("le montant doit être supérieur à zéro")
+	}
+	today := time.Now().Truncate(24 * time.Hour)
+	collectionDate = time.Date(collectionDate.Year(), collectionDate.Month(), collectionDate.Day(), 0, 0, 0, 0, time.UTC)
+	if !collectionDate.After(today) {
+		return nil, fmt.Errorf("la date de prélèvement doit être postérieure à aujourd'hui")
+	}
+	if len(memberIDs) == 0 {
+		return nil, fmt.Errorf("sélectionnez au moins un membre")
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		description = fmt.Sprintf("Cotisation %s %s", settings.FiscalYearLabel(settings.FiscalYear(collectionDate)), settings.LegalName)
+	}
+
+	candidates, err := s.GetDueMembers(userID, collectionDate)
+	if err != nil {
+		return nil, err
+	}
+	dueByMember := map[uint]models.DirectDebitCandidate{}
+	for _, candidate := range candidates {
+		dueByMember[candidate.Member.ID] = candidate
+	}
+
+	now := time.Now()
+	batch := &models.DirectDebitBatch{
+		UserID:         userID,
+		MessageID:      fmt.Sprintf("PRLV-%d-%s", userID, now.Format("20060102150405")),
+		Description:    description,
+		CollectionDate: collectionDate,
+		Status:         models.DebitBatchGenerated,
+	}
+	var mandates []models.SEPAMandate
+	mandatesByID := map[uint]models.SEPAMandate{}
+	names := map[uint]string{}
+	var recorded []uint
+	rollback := func() {
+		for _, id := range recorded {
+			if err := s.financeService.DeleteTransaction(id); err != nil {
+				log.Printf("ERREUR: Impossible d'annuler la transaction %d du prélèvement: %v", id, err)
+			}
+		}
+	}
+
+	for _, memberID := range memberIDs {
+		candidate, ok := dueByMember[memberID]
+		if !ok {
+			rollback()
+			return nil, fmt.Errorf("le membre %d n'a pas de cotisation à prélever", memberID)
+		}
+		delete(dueByMember, memberID) // A member selected twice is debited once.
+		name := strings.TrimSpace(candidate.Member.FirstName + " " + candidate.Member.LastName)
+
+		member := candidate.Member.ID
+		transaction := &models.Transaction{
+			UserID:      userID,
+			Type:        models.TypeIncome,
+			Status:      models.TransactionPending,
+			Date:        collectionDate,
+			Amount:      amount,
+			Description: "Prélèvement SEPA : " + description + " (" + name + ")",
+			AccountCode: "756",
+			MemberID:    &member,
+		}
+		if err := s.financeService.CreateTransaction(transaction); err != nil {
+			rollback()
+			return nil, fmt.Errorf("erreur lors de l'enregistrement du prélèvement de %s: %w", name, err)
+		}
+		recorded = append(recorded, transaction.ID)
+
+		mandate := candidate.Mandate
+		batch.Items = append(batch.Items, models.DirectDebitItem{
+			MandateID:     mandate.ID,
+			MemberID:      member,
+			EndToEndID:    fmt.Sprintf("%s-%d", batch.MessageID, member),
+			SequenceType:  mandate.SequenceType(),
+			Amount:        amount,
+			TransactionID: &transaction.ID,
+			Status:        models.DebitItemPending,
+		})
+		mandatesByID[mandate.ID] = mandate
+		names[member] = name
+		mandate.LastCollectionDate = &collectionDate
+		mandates = append(mandates, mandate)
+	}
+
+	xmlFile, err := BuildPain008(batch, settings, mandatesByID, names, now)
+	if err != nil {
+		rollback()
+		return nil, err
+	}
+	batch.XML = xmlFile
+	if err := s.debitRepo.CreateBatch(batch, mandates); err != nil {
+		rollback()
+		return nil, err
+	}
+	return batch, nil
+}
+
+// GetBatches retrieves the direct debit batches of an association, most recent first.
+func (s *DirectDebitService) GetBatches(userID uint) ([]models.DirectDebitBatch, error) {
+	return s.debitRepo.FindBatchesByUserID(userID)
+}
+
+// GetBatch retrieves a direct debit batch and ensures it belongs to the given association.
+func (s *DirectDebitService) GetBatch(userID, batchID uint) (*models.DirectDebitBatch, error) {
+	batch, err := s.debitRepo.FindBatchByID(batchID)
+	if err != nil || batch.UserID != userID {
+		return nil, fmt.Errorf("lot de prélèvements introuvable")
+	}
+	return batch, nil
+}
+
+// RejectItem records that the bank returned a debit: its pending income is cancelled, and if it
+// was the first debit under the mandate, the next one will be a first collection again.
+func (s *DirectDebitService) RejectItem(userID, batchID, itemID uint, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return fmt.Errorf("le motif du rejet est requis")
+	}
+	batch, err := s.GetBatch(userID, batchID)
+	if err != nil {
+		return err
+	}
+	item := findDebitItem(batch, itemID)
+	if item == nil {
+		return fmt.Errorf("prélèvement introuvable")
+	}
+	if item.Status != models.DebitItemPending {
+		return fmt.Errorf("seul un prélèvement en attente peut être rejeté")
+	}
+
+	if item.TransactionID != nil {
+		if err := s.financeService.DeleteTransaction(*item.TransactionID); err != nil {
+			return fmt.Errorf("erreur lors de l'annulation de la recette: %w", err)
+		}
+		item.TransactionID = nil
+	}
+	item.Status = models.DebitItemRejected
+	item.RejectReason = reason
+	if err := s.debitRepo.UpdateBatch(batch); err != nil {
+		return err
+	}
+
+	if item.SequenceType == models.SequenceFirst {
+		mandate, err := s.debitRepo.FindMandateByID(item.MandateID)
+		if err != nil {
+			return err
+		}
+		if mandate.LastCollectionDate != nil && mandate.LastCollectionDate.Equal(batch.CollectionDate) {
+			mandate.LastCollectionDate = nil
+			return s.debitRepo.UpdateMandate(mandate)
+		}
+	}
+	return nil
+}
+
+// SettleBatch records that the bank collected the debits of a batch that were not rejected: their
+// incomes are posted and the dues of the members are marked as paid on the collection date.
+func (s *DirectDebitService) SettleBatch(userID, batchID uint) error {
+	batch, err := s.GetBatch(userID, batchID)
+	if err != nil {
+		return err
+	}
+	if batch.Status != models.DebitBatchGenerated {
+		return fmt.Errorf("ce lot est déjà encaissé")
+	}
+	if time.Now().Before(batch.CollectionDate) {
+		return fmt.Errorf("la date de prélèvement n'est pas encore atteinte")
+	}
+
+	for i := range batch.Items {
+		item := &batch.Items[i]
+		if item.Status != models.DebitItemPending {
+			continue
+		}
+		if item.TransactionID != nil {
+			transaction, err := s.financeService.GetTransactionByID(*item.TransactionID)
+			if err == nil {
+				err = s.financeService.PostTransaction(transaction)
+			}
+			if err != nil {
+				// Keep the debits already posted so that settling again resumes from here.
+				if saveErr := s.debitRepo.UpdateBatch(batch); saveErr != nil {
+					log.Printf("ERREUR: Impossible d'enregistrer l'encaissement partiel du lot %d: %v", batch.ID, saveErr)
+				}
+				return fmt.Errorf("erreur lors de la validation du prélèvement %s: %w", item.EndToEndID, err)
+			}
+		}
+		item.Status = models.DebitItemSettled
+		if err := s.memberService.MarkPaymentReceived(item.MemberID, batch.CollectionDate); err != nil {
+			log.Printf("ERREUR: Impossible d'enregistrer la cotisation du membre %d: %v", item.MemberID, err)
+		}
+	}
+
+	now := time.Now()
+	batch.Status = models.DebitBatchSettled
+	batch.SettledAt = &now
+	return s.debitRepo.UpdateBatch(batch)
+}
+
+// findDebitItem returns the item of a batch with the given ID, or nil.
+func findDebitItem(batch *models.DirectDebitBatch, itemID uint) *models.DirectDebitItem {
+	for i := range batch.Items {
+		if batch.Items[i].ID == itemID {
+			return &batch.Items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers the GIF decoder for the thumbnails.
+	"image/jpeg"
+	_ "image/png" // Registers the PNG decoder for the thumbnails.
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/storage"
+)
+
+// Settings of the document previews.
+const (
+	thumbnailSize        = 640        // Largest side of the thumbnails, in pixels.
+	thumbnailQuality     = 80         // JPEG quality of the thumbnails.
+	maxThumbnailFile     = 32 << 20   // Largest image file a thumbnail is made of, in bytes.
+	maxThumbnailPixels   = 50_000_000 // Largest image a thumbnail is made of, against decompression bombs.
+	previewExcerptLength = 2000       // Characters of text shown in the preview of PDF and text files.
+	previewBatchSize     = 20         // Documents read at once by the preview worker.
+)
+
+// previewPrefix starts the storage keys of the thumbnails, followed by the key of their file.
+const previewPrefix = "preview_"
+
+// thumbnailTypes lists the image types thumbnails are made of.
+var thumbnailTypes = map[string]bool{"image/png": true, "image/jpeg": true, "image/gif": true}
+
+// GetPreview returns the preview of a document of a user. The generation of a preview still
+// pending is requested from the worker, in case it is idle.
+func (s *DocumentService) GetPreview(userID, documentID uint) (*models.Document, models.DocumentPreview, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, models.DocumentPreview{}, fmt.Errorf("document non trouvé")
+	}
+	preview := models.DocumentPreview{Status: document.PreviewStatus, Thumbnail: document.HasThumbnail()}
+	switch document.PreviewStatus {
+	case models.PreviewReady:
+		preview.Excerpt = previewExcerpt(document.TextContent)
+	case models.PreviewNone, models.PreviewFailed:
+	default:
+		preview.Status = models.PreviewPending
+		s.requestPreviews()
+	}
+	return document, preview, nil
+}
+
+// OpenThumbnail opens the thumbnail of a document of a user to stream it. The caller must close it.
+func (s *DocumentService) OpenThumbnail(ctx context.Context, userID, documentID uint) (io.ReadCloser, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	if !document.HasThumbnail() {
+		return nil, fmt.Errorf("ce document n'a pas de miniature")
+	}
+	return s.OpenFile(ctx, document.PreviewKey)
+}
+
+// requestPreviews wakes the preview worker up after an upload. It does not block: a wake-up
+// already pending covers the new documents.
+func (s *DocumentService) requestPreviews() {
+	select {
+	case s.previewQueue <- struct{}{}:
+	default:
+	}
+}
+
+// RunPreviewWorker generates the pending previews immediately, then whenever documents are
+// uploaded and at every interval, until the context is cancelled. It is meant to be run in its
+// own goroutine.
+func (s *DocumentService) RunPreviewWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if count, err := s.GeneratePreviews(ctx); err != nil {
+			log.Printf("ERREUR: Échec de la génération des aperçus des documents: %v", err)
+		} else if count > 0 {
+			log.Printf("Aperçus de documents générés: %d", count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.previewQueue:
+		}
+	}
+}
+
+// GeneratePreviews generates the previews of the documents awaiting one. A document whose file is
+// missing or unreadable gets a failed preview; an outage of the document store or of the database
+// stops the generation, leaving the remaining documents for the next run. It returns the number
+// of documents processed.
+func (s *DocumentService) GeneratePreviews(ctx context.Context) (int, error) {
+	count := 0
+	for {
+		documents, err := s.documentRepo.FindDocumentsPendingPreview(previewBatchSize)
+		if err != nil || len(documents) == 0 {
+			return count, err
+		}
+		for i := range documents {
+			document := &documents[i]
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+			status, key, err := s.generatePreview(ctx, document)
+			if err != nil {
+				return count, err
+			}
+			if err := s.documentRepo.UpdateDocumentPreview(document, status, key); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
+// generatePreview generates the preview of the current file of a document: a thumbnail stored
+// next to the file for images, the excerpt of the extracted text for the other files. It returns
+// the state of the preview and the key of the thumbnail, or an error if the document store is
+// unavailable. A file missing from the store, or which cannot be read, fails the preview of its
+// document only.
+func (s *DocumentService) generatePreview(ctx context.Context, document *models.Document) (models.DocumentPreviewStatus, string, error) {
+	if !thumbnailTypes[document.MimeType] {
+		if strings.TrimSpace(document.TextContent) != "" {
+			return models.PreviewReady, "", nil
+		}
+		return models.PreviewNone, "", nil
+	}
+
+	// Restored versions share the file, and thus the thumbnail, of the original.
+	key := previewPrefix + document.FilePath + ".jpg"
+	if _, err := s.store.Stat(ctx, key); err == nil {
+		return models.PreviewReady, key, nil
+	}
+	file, err := s.store.Get(ctx, document.FilePath)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Printf("AVERTISSEMENT: Fichier du document %d introuvable, aperçu impossible: %s", document.ID, document.FilePath)
+		return models.PreviewFailed, "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("lecture du document %d: %w", document.ID, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxThumbnailFile+1))
+	file.Close()
+	if err != nil && ctx.Err() != nil {
+		return "", "", ctx.Err()
+	}
+	if err != nil {
+		log.Printf("AVERTISSEMENT: Impossible de lire le fichier du document %d, aperçu impossible: %v", document.ID, err)
+		return models.PreviewFailed, "", nil
+	}
+	thumbnail, err := makeThumbnail(data)
+	if err != nil {
+		log.Printf("AVERTISSEMENT: Impossible de générer la miniature du document %d: %v", document.ID, err)
+		return models.PreviewFailed, "", nil
+	}
+	if err := s.store.Put(ctx, key, bytes.NewReader(thumbnail), int64(len(thumbnail)), "image/jpeg"); err != nil {
+		return "", "", fmt.Errorf("enregistrement de la miniature du document %d: %w", document.ID, err)
+	}
+	return models.PreviewReady, key, nil
+}
+
+// makeThumbnail decodes an image and returns its JPEG thumbnail.
+func makeThumbnail(data []byte) ([]byte, error) {
+	if len(data) > maxThumbnailFile {
+		return nil, fmt.Errorf("image trop volumineuse")
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxThumbnailPixels {
+		return nil, fmt.Errorf("dimensions de l'image non prises en charge (%dx%d)", config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleDown(img, thumbnailSize), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleDown reduces an image so that its largest side is at most maxSide pixels, averaging the
+// pixels each thumbnail pixel covers. Transparent areas are laid on a white background, since
+// JPEG has no transparency.
+func scaleDown(src image.Image, maxSide int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if width > maxSide || height > maxSide {
+		if width >= height {
+			dstWidth, dstHeight = maxSide, max(1, height*maxSide/width)
+		} else {
+			dstWidth, dstHeight = max(1, width*maxSide/height), maxSide
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			// The colors are premultiplied by alpha: the white background shows through by the
+			// missing opacity.
+			white := 0xffff - a/n
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((b/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
+
+// previewExcerpt returns the beginning of the text of a document for its preview.
+func previewExcerpt(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= previewExcerptLength {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:previewExcerptLength])) + "…"
+}
@@ -1,84 +1,339 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/JneiraS/BaseSasS/internal/antivirus"
 	"github.com/JneiraS/BaseSasS/internal/config"
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
 	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/storage"
 )
 
 // DocumentService encapsulates the business logic for managing documents.
-// It interacts with the DocumentRepository for database operations and the file system for storage.
+// It interacts with the DocumentRepository for database operations and a BlobStore (local file
+// system or S3-compatible object store) for the files.
 type DocumentService struct {
-	documentRepo repositories.DocumentRepository
-	cfg          *config.Config
+	documentRepo  repositories.DocumentRepository
+	store         storage.BlobStore
+	scanner       antivirus.Scanner    // nil when uploads are not scanned.
+	periodService *FiscalPeriodService // Guards the attachments of the transactions of closed fiscal years.
+
+	maxFileSize  int64           // Largest file that can be uploaded, in bytes.
+	quota        int64           // Storage space of each association in bytes; 0 for no limit.
+	allowedTypes map[string]bool // MIME types that can be uploaded.
+	trashDays    int             // Days after which deleted documents are purged; 0 to keep them.
+
+	previewQueue chan struct{} // Wakes the preview worker up after uploads.
+
+	appURL string // Base URL of the application, for the share links.
 }
 
 // NewDocumentService creates a new instance of DocumentService.
-// It ensures that the document storage directory exists upon initialization.
-func NewDocumentService(documentRepo repositories.DocumentRepository, cfg *config.Config) *DocumentService {
-	// Ensure the document storage directory exists. Create it if it doesn't.
-	if _, err := os.Stat(cfg.DocumentStoragePath); os.IsNotExist(err) {
-		err := os.MkdirAll(cfg.DocumentStoragePath, 0755)
-		if err != nil {
-			// Log the error if directory creation fails, but continue with a warning.
-			fmt.Printf("AVERTISSEMENT: Impossible de créer le répertoire de stockage des documents: %v\n", err)
+// It takes a DocumentRepository, the BlobStore holding the files, the antivirus Scanner (nil
+// to disable scanning) and a FiscalPeriodService as dependencies, adhering to the dependency
+// inversion principle. The upload limits are read from the configuration.
+func NewDocumentService(documentRepo repositories.DocumentRepository, store storage.BlobStore, scanner antivirus.Scanner, periodService *FiscalPeriodService, cfg *config.Config) *DocumentService {
+	allowedTypes := map[string]bool{}
+	types := DefaultDocumentTypes()
+	if cfg.DocumentAllowedTypes != "" {
+		types = strings.Split(cfg.DocumentAllowedTypes, ",")
+	}
+	for _, mimeType := range types {
+		if mimeType = strings.ToLower(strings.TrimSpace(mimeType)); mimeType != "" {
+			allowedTypes[mimeType] = true
 		}
 	}
-	return &DocumentService{documentRepo: documentRepo, cfg: cfg}
+	return &DocumentService{
+		documentRepo:  documentRepo,
+		store:         store,
+		scanner:       scanner,
+		periodService: periodService,
+		maxFileSize:   int64(cfg.DocumentMaxSizeMB) << 20,
+		quota:         int64(cfg.DocumentQuotaMB) << 20,
+		allowedTypes:  allowedTypes,
+		trashDays:     cfg.DocumentTrashDays,
+		previewQueue:  make(chan struct{}, 1),
+		appURL:        strings.TrimRight(cfg.AppURL, "/"),
+	}
 }
 
+// Limits of the organization of the document library.
+const (
+	maxFolderNameLength = 100
+	maxTagLength        = 50
+	maxDocumentTags     = 20
+	maxFolderDepth      = 32
+)
+
 // UploadDocument handles the upload and storage of a document.
-// It saves the file to the configured storage path and records its metadata in the database.
-func (s *DocumentService) UploadDocument(userID uint, name string, file *multipart.FileHeader) error {
-	// Open the uploaded file.
-	src, err := file.Open()
+// It saves the file to the document store and records its metadata in the database,
+// in the given folder (nil for the root of the library) and with the given tags, as the first
+// version of the document.
+func (s *DocumentService) UploadDocument(uploader models.User, name string, folderID *uint, tags []string, file *multipart.FileHeader) error {
+	if err := s.checkFolder(uploader.ID, folderID); err != nil {
+		return err
+	}
+	_, err := s.storeDocument(&models.Document{UserID: uploader.ID, Name: name, FolderID: folderID, Tags: tags}, uploader.Name, file)
+	return err
+}
+
+// UploadVersion stores a new file for an existing document of the uploader, such as the revised
+// statutes of the year. The previous versions and their files are kept.
+func (s *DocumentService) UploadVersion(uploader models.User, documentID uint, comment string, file *multipart.FileHeader) (*models.DocumentVersion, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != uploader.ID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	key, mimeType, err := s.saveFile(uploader.ID, uploader.Name, file)
+	if err != nil {
+		return nil, err
+	}
+	version := &models.DocumentVersion{
+		FileName:     file.Filename,
+		FilePath:     key,
+		FileSize:     file.Size,
+		MimeType:     mimeType,
+		UploadDate:   time.Now(),
+		UploadedByID: uploader.ID,
+		UploadedBy:   uploader.Name,
+		Comment:      strings.TrimSpace(comment),
+	}
+	if err := s.addVersion(document, version, s.quota); err != nil {
+		s.removeFile(key)
+		return nil, err
+	}
+	return version, nil
+}
+
+// RestoreVersion makes a previous version the current file of a document. The restoration is
+// recorded as a new version sharing the file of the restored one, so that the history is kept.
+func (s *DocumentService) RestoreVersion(user models.User, documentID uint, number int) (*models.DocumentVersion, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != user.ID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	if number == document.Version {
+		return nil, fmt.Errorf("la version %d est déjà la version courante", number)
+	}
+	restored, err := s.documentRepo.FindDocumentVersion(documentID, number)
+	if err != nil {
+		return nil, fmt.Errorf("version %d introuvable", number)
+	}
+	version := &models.DocumentVersion{
+		FileName:     restored.FileName,
+		FilePath:     restored.FilePath,
+		FileSize:     restored.FileSize,
+		MimeType:     restored.MimeType,
+		UploadDate:   time.Now(),
+		UploadedByID: user.ID,
+		UploadedBy:   user.Name,
+		Comment:      fmt.Sprintf("Restauration de la version %d", number),
+	}
+	// The restored file is already stored and counted in the storage used.
+	if err := s.addVersion(document, version, 0); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// GetVersions retrieves the versions of a document of a user, most recent first.
+func (s *DocumentService) GetVersions(userID, documentID uint) ([]models.DocumentVersion, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return s.documentRepo.FindDocumentVersions(documentID)
+}
+
+// GetVersion retrieves a version of a document of a user by its number.
+func (s *DocumentService) GetVersion(userID, documentID uint, number int) (*models.DocumentVersion, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	version, err := s.documentRepo.FindDocumentVersion(documentID, number)
+	if err != nil {
+		return nil, fmt.Errorf("version %d introuvable", number)
+	}
+	return version, nil
+}
+
+// addVersion records a version as the current file of a document, re-extracting its text for the
+// search and requesting its preview. The version is refused if the files of the association then
+// exceed the quota, in bytes (0 for no limit).
+func (s *DocumentService) addVersion(document *models.Document, version *models.DocumentVersion, quota int64) error {
+	document.TextContent = s.extractText(version.FilePath, version.MimeType, document.Name)
+	document.ContentIndexed = true
+	document.PreviewStatus = models.PreviewPending
+	document.PreviewKey = ""
+	if err := s.documentRepo.AddDocumentVersion(document, version, quota); err != nil {
+		if errors.Is(err, repositories.ErrQuotaExceeded) {
+			return s.rejectOverQuota(document.UserID)
+		}
+		return fmt.Errorf("impossible d'enregistrer la version en base de données: %w", err)
+	}
+	s.requestPreviews()
+	return nil
+}
+
+// AttachToTransaction stores an uploaded file as a document attached to a transaction
+// (receipt, invoice...). The file name is used as the document name.
+func (s *DocumentService) AttachToTransaction(userID, transactionID uint, file *multipart.FileHeader) (*models.Document, error) {
+	return s.storeDocument(&models.Document{UserID: userID, Name: file.Filename, TransactionID: &transactionID}, "", file)
+}
+
+// StoreReceipt stores an uploaded receipt as a document that is not attached to a transaction yet,
+// such as the receipt of an expense claim line awaiting approval.
+func (s *DocumentService) StoreReceipt(userID uint, file *multipart.FileHeader) (*models.Document, error) {
+	return s.storeDocument(&models.Document{UserID: userID, Name: file.Filename}, "", file)
+}
+
+// LinkToTransaction attaches a stored document of a user to a transaction.
+func (s *DocumentService) LinkToTransaction(userID, documentID, transactionID uint) error {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
 	if err != nil {
-		return fmt.Errorf("impossible d'ouvrir le fichier téléchargé: %w", err)
+		return fmt.Errorf("document non trouvé: %w", err)
 	}
-	defer src.Close()
+	if document.UserID != userID {
+		return fmt.Errorf("ce document n'appartient pas à l'association")
+	}
+	document.TransactionID = &transactionID
+	return s.documentRepo.UpdateDocument(document)
+}
 
-	// Generate a unique file name to prevent collisions.
-	uniqueFileName := fmt.Sprintf("%d_%s_%s", userID, time.Now().Format("20060102150405"), filepath.Base(file.Filename))
-	// Construct the full destination path for the file.
-	destPath := filepath.Join(s.cfg.DocumentStoragePath, uniqueFileName)
+// GetTransactionDocuments retrieves the documents attached to a transaction.
+func (s *DocumentService) GetTransactionDocuments(transactionID uint) ([]models.Document, error) {
+	return s.documentRepo.FindDocumentsByTransactionID(transactionID)
+}
+
+// GetAttachmentCounts returns the number of documents attached to each transaction of a user.
+// Transactions without attachment are absent from the map.
+func (s *DocumentService) GetAttachmentCounts(userID uint) (map[uint]int64, error) {
+	return s.documentRepo.CountDocumentsByTransaction(userID)
+}
 
-	// Create the destination file on the server.
-	dst, err := os.Create(destPath)
+// DetachTransactionDocuments unlinks the documents attached to a transaction; the documents are kept
+// in the document library.
+func (s *DocumentService) DetachTransactionDocuments(transactionID uint) error {
+	return s.documentRepo.DetachDocumentsFromTransaction(transactionID)
+}
+
+// DetachDocument unlinks a document from the transaction it is attached to, after checking that
+// the document belongs to the user and is attached to that transaction. The supporting documents
+// of the transactions of a closed fiscal year cannot be detached.
+func (s *DocumentService) DetachDocument(userID uint, transaction *models.Transaction, documentID uint) error {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
 	if err != nil {
-		return fmt.Errorf("impossible de créer le fichier sur le serveur: %w", err)
+		return fmt.Errorf("document non trouvé: %w", err)
+	}
+	if document.UserID != userID || document.TransactionID == nil || *document.TransactionID != transaction.ID {
+		return fmt.Errorf("ce document n'est pas attaché à cette transaction")
 	}
-	defer dst.Close()
+	if err := s.periodService.EnsureOpen(userID, transaction.Date); err != nil {
+		return err
+	}
+	document.TransactionID = nil
+	return s.documentRepo.UpdateDocument(document)
+}
 
-	// Copy the uploaded file content to the destination file.
-	if _, err := io.Copy(dst, src); err != nil {
-		return fmt.Errorf("impossible de copier le fichier: %w", err)
+// storeDocument saves an uploaded file to the document store and records its metadata,
+// completing the given document (owner, name, and optionally transaction, folder and tags) with
+// the file details and its text content. The file is recorded as the first version of the
+// document, uploaded by the named user.
+func (s *DocumentService) storeDocument(document *models.Document, uploadedBy string, file *multipart.FileHeader) (*models.Document, error) {
+	key, mimeType, err := s.saveFile(document.UserID, uploadedBy, file)
+	if err != nil {
+		return nil, err
 	}
 
-	// Prepare document metadata for database storage.
-	document := &models.Document{
-		Name:       name,
-		FilePath:   destPath,
-		FileSize:   file.Size,
-		MimeType:   file.Header.Get("Content-Type"),
-		UploadDate: time.Now(),
-		UserID:     userID,
+	// Complete document metadata for database storage.
+	document.FilePath = key
+	document.FileSize = file.Size
+	document.MimeType = mimeType
+	document.UploadDate = time.Now()
+	document.TextContent = s.extractText(key, document.MimeType, document.Name)
+	document.ContentIndexed = true
+	document.PreviewStatus = models.PreviewPending
+	if document.Visibility == "" {
+		document.Visibility = models.VisibilityPrivate
+	}
+	version := &models.DocumentVersion{
+		FileName:     file.Filename,
+		FilePath:     key,
+		FileSize:     file.Size,
+		MimeType:     document.MimeType,
+		UploadDate:   document.UploadDate,
+		UploadedByID: document.UserID,
+		UploadedBy:   uploadedBy,
 	}
 
 	// Save document information to the database.
-	if err := s.documentRepo.CreateDocument(document); err != nil {
-		// If database record creation fails, attempt to remove the physically saved file to prevent orphans.
-		os.Remove(destPath)
-		return fmt.Errorf("impossible d'enregistrer le document en base de données: %w", err)
+	if err := s.documentRepo.CreateDocument(document, version, s.quota); err != nil {
+		// If database record creation fails, attempt to remove the stored file to prevent orphans.
+		s.removeFile(key)
+		if errors.Is(err, repositories.ErrQuotaExceeded) {
+			return nil, s.rejectOverQuota(document.UserID)
+		}
+		return nil, fmt.Errorf("impossible d'enregistrer le document en base de données: %w", err)
 	}
+	s.requestPreviews()
 
-	return nil
+	return document, nil
+}
+
+// removeFile deletes a stored file that is not referenced, logging failures: the file is then
+// merely orphaned.
+func (s *DocumentService) removeFile(key string) {
+	if err := s.store.Delete(context.Background(), key); err != nil {
+		log.Printf("AVERTISSEMENT: Impossible de supprimer le fichier %s du stockage: %v", key, err)
+	}
+}
+
+// OpenFile opens a stored file of the document library, identified by the storage key of a
+// document or a version, to stream it. The caller must close it.
+func (s *DocumentService) OpenFile(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := s.store.Get(ctx, key)
+	if err == storage.ErrNotFound {
+		return nil, fmt.Errorf("le fichier de ce document est introuvable")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("impossible de lire le fichier: %w", err)
+	}
+	return file, nil
+}
+
+// extractText extracts the text of a stored file for the search. A file whose text cannot be read
+// is still stored, without text.
+func (s *DocumentService) extractText(key, mimeType, name string) string {
+	text, err := s.readText(key, mimeType)
+	if err != nil {
+		log.Printf("AVERTISSEMENT: Impossible d'extraire le texte du document %s: %v", name, err)
+	}
+	return text
+}
+
+// readText reads the text of a stored file, which is only fetched from the store if its type has
+// a text to extract.
+func (s *DocumentService) readText(key, mimeType string) (string, error) {
+	if !hasText(key, mimeType) {
+		return "", nil
+	}
+	file, err := s.store.Get(context.Background(), key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return extractText(file, key, mimeType)
 }
 
 // GetDocumentByID retrieves a document by its unique identifier.
@@ -91,13 +346,249 @@ func (s *DocumentService) GetDocumentsByUserID(userID uint) ([]models.Document,
 	return s.documentRepo.FindDocumentsByUserID(userID)
 }
 
-// DeleteDocument deletes a document record from the database.
-// Note: This service method only handles the database record deletion.
-// The actual file deletion from the file system might be handled elsewhere or in the repository.
+// ErrDocumentAttached is returned when deleting a document which is a supporting document of the
+// accounts.
+var ErrDocumentAttached = errors.New("ce document est une pièce justificative d'une transaction ou d'une note de frais : retirez-le de la transaction avant de le supprimer")
+
+// DeleteDocument moves a document to the trash. Its record and files are kept, so that it can be
+// restored, until it is permanently deleted from the trash or purged after the retention period.
+// The documents attached to a transaction or to an expense claim line cannot be deleted.
 func (s *DocumentService) DeleteDocument(documentID uint) error {
+	attached, err := s.documentRepo.IsDocumentAttached(documentID)
+	if err != nil {
+		return err
+	}
+	if attached {
+		return ErrDocumentAttached
+	}
 	return s.documentRepo.DeleteDocument(documentID)
 }
 
+// SearchDocuments retrieves the documents of a user matching a query of the document library.
+func (s *DocumentService) SearchDocuments(userID uint, query models.DocumentQuery) ([]models.Document, error) {
+	query.Search = strings.TrimSpace(query.Search)
+	query.Tag = strings.ToLower(strings.TrimSpace(query.Tag))
+	return s.documentRepo.FindDocuments(userID, query)
+}
+
+// IndexDocuments extracts the text of the documents stored before the search of their content
+// existed. It returns the number of documents indexed.
+func (s *DocumentService) IndexDocuments() (int, error) {
+	indexed := 0
+	for {
+		documents, err := s.documentRepo.FindDocumentsToIndex(100)
+		if err != nil || len(documents) == 0 {
+			return indexed, err
+		}
+		for i := range documents {
+			document := &documents[i]
+			text, err := s.readText(document.FilePath, document.MimeType)
+			if err != nil {
+				log.Printf("AVERTISSEMENT: Impossible d'extraire le texte du document %d: %v", document.ID, err)
+			}
+			document.TextContent = text
+			document.ContentIndexed = true
+			if err := s.documentRepo.UpdateDocument(document); err != nil {
+				return indexed, err
+			}
+			indexed++
+		}
+	}
+}
+
+// OrganizeDocument moves a document of a user to a folder (nil for the root of the library),
+// replaces its tags and sets who may see it (unchanged when visibility is empty).
+func (s *DocumentService) OrganizeDocument(userID, documentID uint, folderID *uint, tags []string, visibility models.DocumentVisibility) error {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return fmt.Errorf("document non trouvé")
+	}
+	if err := s.checkFolder(userID, folderID); err != nil {
+		return err
+	}
+	if visibility == "" {
+		visibility = document.Visibility
+	} else if !visibility.Valid() {
+		return fmt.Errorf("visibilité invalide")
+	}
+	document.FolderID = folderID
+	document.Visibility = visibility
+	if err := s.documentRepo.UpdateDocument(document); err != nil {
+		return err
+	}
+	return s.documentRepo.SetDocumentTags(document, tags)
+}
+
+// GetTags returns the tags used by the documents of a user, in alphabetical order.
+func (s *DocumentService) GetTags(userID uint) ([]string, error) {
+	return s.documentRepo.FindTagsByUserID(userID)
+}
+
+// ParseTags reads a comma-separated list of tags. Tags are trimmed and put in lower case, and
+// duplicates are removed.
+func ParseTags(value string) ([]string, error) {
+	seen := map[string]bool{}
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("l'étiquette « %s » dépasse %d caractères", tag, maxTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxDocumentTags {
+		return nil, fmt.Errorf("un document ne peut pas avoir plus de %d étiquettes", maxDocumentTags)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// CreateFolder creates a folder of the document library, at the root or within a parent folder.
+// Folder names are unique within their parent.
+func (s *DocumentService) CreateFolder(folder *models.DocumentFolder) error {
+	folder.Name = strings.TrimSpace(folder.Name)
+	if err := s.checkFolder(folder.UserID, folder.ParentID); err != nil {
+		return err
+	}
+	if err := s.checkFolderName(folder); err != nil {
+		return err
+	}
+	return s.documentRepo.CreateFolder(folder)
+}
+
+// RenameFolder renames a folder of the document library.
+func (s *DocumentService) RenameFolder(userID, folderID uint, name string) error {
+	folder, err := s.GetFolder(userID, folderID)
+	if err != nil {
+		return err
+	}
+	folder.Name = strings.TrimSpace(name)
+	if err := s.checkFolderName(folder); err != nil {
+		return err
+	}
+	return s.documentRepo.UpdateFolder(folder)
+}
+
+// DeleteFolder deletes an empty folder of the document library.
+func (s *DocumentService) DeleteFolder(userID, folderID uint) error {
+	folder, err := s.GetFolder(userID, folderID)
+	if err != nil {
+		return err
+	}
+	folders, err := s.documentRepo.FindFoldersByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, other := range folders {
+		if other.ParentID != nil && *other.ParentID == folder.ID {
+			return fmt.Errorf("le dossier contient des sous-dossiers : supprimez-les d'abord")
+		}
+	}
+	count, err := s.documentRepo.CountDocumentsInFolder(folder.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("le dossier contient %d document(s) : déplacez-les ou supprimez-les d'abord", count)
+	}
+	return s.documentRepo.DeleteFolder(folder.ID)
+}
+
+// GetFolder retrieves a folder of the document library and ensures it belongs to the given user.
+func (s *DocumentService) GetFolder(userID, folderID uint) (*models.DocumentFolder, error) {
+	folder, err := s.documentRepo.FindFolderByID(folderID)
+	if err != nil || folder.UserID != userID {
+		return nil, fmt.Errorf("dossier introuvable")
+	}
+	return folder, nil
+}
+
+// GetFolders retrieves all the folders of the document library of a user, by name.
+func (s *DocumentService) GetFolders(userID uint) ([]models.DocumentFolder, error) {
+	return s.documentRepo.FindFoldersByUserID(userID)
+}
+
+// FolderPath returns the folders leading from the root of the library to the given folder,
+// included, for breadcrumbs and folder labels.
+func FolderPath(folders []models.DocumentFolder, folderID uint) []models.DocumentFolder {
+	byID := make(map[uint]models.DocumentFolder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+	var path []models.DocumentFolder
+	for id := &folderID; id != nil && len(path) < maxFolderDepth; {
+		folder, ok := byID[*id]
+		if !ok {
+			break
+		}
+		path = append([]models.DocumentFolder{folder}, path...)
+		id = folder.ParentID
+	}
+	return path
+}
+
+// FolderLabels returns the full path of each folder, such as "Comptabilité / 2026", sorted by path.
+func FolderLabels(folders []models.DocumentFolder) []models.DocumentFolderLabel {
+	labels := make([]models.DocumentFolderLabel, 0, len(folders))
+	for _, folder := range folders {
+		var names []string
+		for _, parent := range FolderPath(folders, folder.ID) {
+			names = append(names, parent.Name)
+		}
+		labels = append(labels, models.DocumentFolderLabel{ID: folder.ID, Path: strings.Join(names, " / ")})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return strings.ToLower(labels[i].Path) < strings.ToLower(labels[j].Path)
+	})
+	return labels
+}
+
+// checkFolder ensures that a folder, when set, belongs to the given user.
+func (s *DocumentService) checkFolder(userID uint, folderID *uint) error {
+	if folderID == nil {
+		return nil
+	}
+	_, err := s.GetFolder(userID, *folderID)
+	return err
+}
+
+// checkFolderName validates the name of a folder, which must be unique within its parent.
+func (s *DocumentService) checkFolderName(folder *models.DocumentFolder) error {
+	if folder.Name == "" {
+		return fmt.Errorf("le nom du dossier est requis")
+	}
+	if utf8.RuneCountInString(folder.Name) > maxFolderNameLength {
+		return fmt.Errorf("le nom du dossier ne peut pas dépasser %d caractères", maxFolderNameLength)
+	}
+	folders, err := s.documentRepo.FindFoldersByUserID(folder.UserID)
+	if err != nil {
+		return err
+	}
+	for _, other := range folders {
+		sameParent := (other.ParentID == nil && folder.ParentID == nil) ||
+			(other.ParentID != nil && folder.ParentID != nil && *other.ParentID == *folder.ParentID)
+		if other.ID != folder.ID && sameParent && strings.EqualFold(other.Name, folder.Name) {
+			return fmt.Errorf("un dossier « %s » existe déjà à cet emplacement", other.Name)
+		}
+	}
+	if len(FolderPath(folders, derefFolder(folder.ParentID))) >= maxFolderDepth-1 {
+		return fmt.Errorf("les dossiers ne peuvent pas être imbriqués sur plus de %d niveaux", maxFolderDepth-1)
+	}
+	return nil
+}
+
+// derefFolder returns the ID of a folder, or 0 for the root of the library.
+func derefFolder(folderID *uint) uint {
+	if folderID == nil {
+		return 0
+	}
+	return *folderID
+}
+
 // GetTotalDocumentsCount returns the total number of documents for a given user ID.
 func (s *DocumentService) GetTotalDocumentsCount(userID uint) (int64, error) {
 	return s.documentRepo.GetTotalDocumentsCount(userID)
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors returned to the visitors of a share link.
+var (
+	// ErrShareUnavailable is returned for unknown, expired, exhausted, locked or revoked links,
+	// which are not told apart so as not to inform on the links that exist.
+	ErrShareUnavailable = errors.New("ce lien de partage est invalide ou a expiré")
+	// ErrSharePassword is returned when the password of a link is missing or wrong.
+	ErrSharePassword = errors.New("mot de passe incorrect")
+)
+
+// Limits of the share links.
+const (
+	maxShareDuration       = 366 * 24 * time.Hour
+	maxShareLabelLength    = 100
+	minSharePasswordLength = 8
+	maxSharePasswordLength = 72 // The longest password bcrypt hashes.
+	maxUserAgentLength     = 255
+)
+
+// CreateShareLink creates a share link for a document of the user, protected by the given password
+// if it is not empty. The link completes the label, expiry date and maximum number of downloads
+// given. It returns the URL of the link, which is not stored and cannot be retrieved later.
+func (s *DocumentService) CreateShareLink(user models.User, documentID uint, link *models.DocumentShareLink, password string) (string, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != user.ID {
+		return "", fmt.Errorf("document non trouvé")
+	}
+	link.Label = strings.TrimSpace(link.Label)
+	if utf8.RuneCountInString(link.Label) > maxShareLabelLength {
+		return "", fmt.Errorf("le libellé ne peut pas dépasser %d caractères", maxShareLabelLength)
+	}
+	now := time.Now()
+	if link.ExpiresAt.IsZero() {
+		return "", fmt.Errorf("la date d'expiration est requise")
+	}
+	if !link.ExpiresAt.After(now) {
+		return "", fmt.Errorf("la date d'expiration doit être dans le futur")
+	}
+	if link.ExpiresAt.After(now.Add(maxShareDuration)) {
+		return "", fmt.Errorf("un lien de partage ne peut pas être valable plus d'un an")
+	}
+	if link.MaxDownloads < 0 {
+		return "", fmt.Errorf("le nombre maximal de téléchargements ne peut pas être négatif")
+	}
+	link.PasswordHash = ""
+	if password != "" {
+		if utf8.RuneCountInString(password) < minSharePasswordLength {
+			return "", fmt.Errorf("le mot de passe doit comporter au moins %d caractères", minSharePasswordLength)
+		}
+		if len(password) > maxSharePasswordLength {
+			return "", fmt.Errorf("le mot de passe ne peut pas dépasser %d octets", maxSharePasswordLength)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return "", fmt.Errorf("erreur lors du chiffrement du mot de passe: %w", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return "", err
+	}
+	link.UserID = user.ID
+	link.DocumentID = document.ID
+	link.TokenHash = shareTokenHash(token)
+	link.DownloadCount = 0
+	link.FailedAttempts = 0
+	link.RevokedAt = nil
+	link.CreatedByID = user.ID
+	link.CreatedBy = user.Name
+	if err := s.documentRepo.CreateShareLink(link); err != nil {
+		return "", fmt.Errorf("impossible d'enregistrer le lien de partage: %w", err)
+	}
+	return s.ShareURL(token), nil
+}
+
+// ShareURL returns the URL of the share link with the given token.
+func (s *DocumentService) ShareURL(token string) string {
+	return s.appURL + "/share/" + token
+}
+
+// GetShareLinks retrieves the share links of a document of the user, most recent first.
+func (s *DocumentService) GetShareLinks(userID, documentID uint) ([]models.DocumentShareLink, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return s.documentRepo.FindShareLinksByDocumentID(documentID)
+}
+
+// GetShareDownloads retrieves the log of the downloads made through the share links of a document
+// of the user, most recent first.
+func (s *DocumentService) GetShareDownloads(userID, documentID uint) ([]models.DocumentShareDownload, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return s.documentRepo.FindShareDownloads(documentID)
+}
+
+// RevokeShareLink revokes a share link of a document of the user. The link stops working at once;
+// it is kept, with its download log.
+func (s *DocumentService) RevokeShareLink(userID, documentID, linkID uint) error {
+	link, err := s.documentRepo.FindShareLinkByID(linkID)
+	if err != nil || link.UserID != userID || link.DocumentID != documentID {
+		return fmt.Errorf("lien de partage introuvable")
+	}
+	if link.RevokedAt != nil {
+		return fmt.Errorf("ce lien de partage est déjà révoqué")
+	}
+	return s.documentRepo.RevokeShareLink(link.ID, time.Now())
+}
+
+// GetSharedDocument retrieves an active share link from its token, with the shared document.
+func (s *DocumentService) GetSharedDocument(token string) (*models.DocumentShareLink, *models.Document, error) {
+	if token == "" {
+		return nil, nil, ErrShareUnavailable
+	}
+	link, err := s.documentRepo.FindShareLinkByTokenHash(shareTokenHash(token))
+	if err != nil || !link.Active() {
+		return nil, nil, ErrShareUnavailable
+	}
+	// The links of a deleted document stop working with it.
+	document, err := s.documentRepo.FindDocumentByID(link.DocumentID)
+	if err != nil || document.UserID != link.UserID {
+		return nil, nil, ErrShareUnavailable
+	}
+	return link, document, nil
+}
+
+// OpenSharedDocument checks the password of a share link and opens the current file of the shared
+// document to stream it. The download is counted and logged with the address and browser of the
+// visitor; a wrong password is counted too, and locks the link once there were too many. The
+// caller must close the file.
+func (s *DocumentService) OpenSharedDocument(ctx context.Context, token, password, ipAddress, userAgent string) (*models.Document, io.ReadCloser, error) {
+	link, document, err := s.GetSharedDocument(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link.HasPassword() && bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		if err := s.documentRepo.RecordShareFailure(link.ID); err != nil {
+			log.Printf("ERREUR: Impossible de compter l'échec du lien de partage %d: %v", link.ID, err)
+		}
+		return nil, nil, ErrSharePassword
+	}
+
+	file, err := s.OpenFile(ctx, document.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	download := &models.DocumentShareDownload{
+		LinkID:       link.ID,
+		DocumentID:   document.ID,
+		Version:      document.Version,
+		DownloadedAt: time.Now(),
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+	}
+	recorded, err := s.documentRepo.RecordShareDownload(link, download)
+	if err != nil || !recorded {
+		file.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("impossible d'enregistrer le téléchargement: %w", err)
+		}
+		// The link was used up or revoked meanwhile.
+		return nil, nil, ErrShareUnavailable
+	}
+	return document, file, nil
+}
+
+// newShareToken returns a random token for a share link.
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erreur lors de la génération du lien de partage: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// shareTokenHash returns the hash under which the token of a share link is stored. The tokens are
+// random, so that a fast hash is enough.
+func shareTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/JneiraS/BaseSasS/internal/pdf"
+)
+
+// Limits of the text extraction of documents, in bytes.
+const (
+	maxIndexedText  = 256 << 10 // Text kept for the search of a document.
+	maxExtractedPDF = 32 << 20  // Largest PDF file whose text is extracted.
+)
+
+// plainTextExtensions lists the extensions of files indexed as plain text whatever their MIME type.
+var plainTextExtensions = map[string]bool{".txt": true, ".md": true, ".csv": true}
+
+// hasText reports whether the text of a file of the given name and MIME type can be extracted.
+func hasText(name, mimeType string) bool {
+	return isPlainText(name, mimeType) || isPDF(name, mimeType)
+}
+
+// isPlainText reports whether a file is indexed as plain text.
+func isPlainText(name, mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(mimeType), "text/") || plainTextExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// isPDF reports whether a file is a PDF document.
+func isPDF(name, mimeType string) bool {
+	return strings.ToLower(mimeType) == "application/pdf" || strings.ToLower(filepath.Ext(name)) == ".pdf"
+}
+
+// extractText returns the text of a document read from file for the search: the content of plain
+// text files and the text layer of PDF files. Other files have no text. name is used to recognize
+// the type of the file by its extension.
+func extractText(file io.Reader, name, mimeType string) (string, error) {
+	var text string
+	switch {
+	case isPlainText(name, mimeType):
+		data, err := io.ReadAll(io.LimitReader(file, maxIndexedText))
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	case isPDF(name, mimeType):
+		data, err := io.ReadAll(io.LimitReader(file, maxExtractedPDF))
+		if err != nil {
+			return "", err
+		}
+		text = pdf.ExtractText(data)
+	default:
+		return "", nil
+	}
+
+	text = strings.ToValidUTF8(text, "")
+	if len(text) > maxIndexedText {
+		text = text[:maxIndexedText]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/storage"
+)
+
+// trashPurgeBatchSize is the number of expired documents of the trash read at once by the purge.
+const trashPurgeBatchSize = 50
+
+// GetTrash retrieves the documents of a user in the trash, most recently deleted first.
+func (s *DocumentService) GetTrash(userID uint) ([]models.Document, error) {
+	return s.documentRepo.FindDeletedDocuments(userID)
+}
+
+// TrashDays returns the number of days deleted documents stay in the trash, or 0 if they stay
+// until the trash is emptied.
+func (s *DocumentService) TrashDays() int {
+	return s.trashDays
+}
+
+// PurgeDate returns the date a document of the trash will be purged, or the zero time if documents
+// are not purged automatically.
+func (s *DocumentService) PurgeDate(document models.Document) time.Time {
+	if s.trashDays <= 0 || !document.DeletedAt.Valid {
+		return time.Time{}
+	}
+	return document.DeletedAt.Time.AddDate(0, 0, s.trashDays)
+}
+
+// RestoreDocument takes a document of a user out of the trash, with its versions and share links.
+// A document whose folder was deleted meanwhile is restored at the root of the library.
+func (s *DocumentService) RestoreDocument(userID, documentID uint) (*models.Document, error) {
+	document, err := s.documentRepo.FindDeletedDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document introuvable dans la corbeille")
+	}
+	if document.FolderID != nil {
+		if folder, err := s.documentRepo.FindFolderByID(*document.FolderID); err != nil || folder.UserID != userID {
+			document.FolderID = nil
+		}
+	}
+	if err := s.documentRepo.RestoreDocument(document); err != nil {
+		return nil, fmt.Errorf("impossible de restaurer le document: %w", err)
+	}
+	document.DeletedAt.Valid = false
+	return document, nil
+}
+
+// PurgeDocument permanently deletes a document of a user from the trash, with the files of all its
+// versions.
+func (s *DocumentService) PurgeDocument(ctx context.Context, userID, documentID uint) error {
+	document, err := s.documentRepo.FindDeletedDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return fmt.Errorf("document introuvable dans la corbeille")
+	}
+	attached, err := s.documentRepo.IsDocumentAttached(documentID)
+	if err != nil {
+		return err
+	}
+	if attached {
+		return ErrDocumentAttached
+	}
+	_, err = s.purge(ctx, []models.Document{*document})
+	return err
+}
+
+// EmptyTrash permanently deletes all the documents of a user in the trash. It returns the number
+// of documents deleted.
+func (s *DocumentService) EmptyTrash(ctx context.Context, userID uint) (int, error) {
+	documents, err := s.documentRepo.FindDeletedDocuments(userID)
+	if err != nil {
+		return 0, err
+	}
+	return s.purge(ctx, documents)
+}
+
+// PurgeTrash permanently deletes, for all users, the documents put in the trash before the given
+// date. It returns the number of documents deleted.
+func (s *DocumentService) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	count := 0
+	for {
+		documents, err := s.documentRepo.FindDocumentsDeletedBefore(before, trashPurgeBatchSize)
+		if err != nil || len(documents) == 0 {
+			return count, err
+		}
+		purged, err := s.purge(ctx, documents)
+		count += purged
+		// Documents attached meanwhile are kept, and would be read again.
+		if err != nil || purged == 0 {
+			return count, err
+		}
+	}
+}
+
+// RunTrashPurge purges the documents that stayed in the trash longer than the retention period,
+// immediately, then at every interval until the context is cancelled. It returns at once if
+// documents are not purged automatically. It is meant to be run in its own goroutine.
+func (s *DocumentService) RunTrashPurge(ctx context.Context, interval time.Duration) {
+	if s.trashDays <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if count, err := s.PurgeTrash(ctx, time.Now().AddDate(0, 0, -s.trashDays)); err != nil {
+			log.Printf("ERREUR: Échec de la purge de la corbeille des documents: %v", err)
+		} else if count > 0 {
+			log.Printf("Documents purgés de la corbeille: %d", count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge permanently deletes documents of the trash, then the files no record references anymore.
+// The documents attached to a transaction or to an expense claim line are kept. It returns the
+// number of documents deleted.
+func (s *DocumentService) purge(ctx context.Context, documents []models.Document) (int, error) {
+	var keys []string
+	count := 0
+	var err error
+	for _, document := range documents {
+		// A supporting document of the accounts put in the trash before it was attached, or before
+		// attached documents were kept out of the trash, stays there.
+		var attached bool
+		if attached, err = s.documentRepo.IsDocumentAttached(document.ID); err != nil {
+			err = fmt.Errorf("impossible de vérifier les pièces justificatives du document %d: %w", document.ID, err)
+			break
+		}
+		if attached {
+			log.Printf("AVERTISSEMENT: Document %d conservé dans la corbeille: pièce justificative d'une transaction ou d'une note de frais", document.ID)
+			continue
+		}
+		var documentKeys []string
+		if documentKeys, err = s.documentRepo.PurgeDocument(document.ID); err != nil {
+			err = fmt.Errorf("impossible de supprimer définitivement le document %d: %w", document.ID, err)
+			break
+		}
+		for _, key := range documentKeys {
+			keys = append(keys, key)
+			// The thumbnails of the previous versions are kept in case they are restored.
+			if !strings.HasPrefix(key, previewPrefix) {
+				keys = append(keys, previewPrefix+key+".jpg")
+			}
+		}
+		count++
+	}
+	// The files of the documents deleted before a failure are deleted all the same.
+	if deleteErr := s.deleteUnreferenced(ctx, keys); err == nil {
+		err = deleteErr
+	}
+	return count, err
+}
+
+// deleteUnreferenced deletes the files of the given keys that no record references. Failures are
+// logged: the files are then merely orphaned, and found by the consistency check.
+func (s *DocumentService) deleteUnreferenced(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	referenced, err := s.referencedKeys()
+	if err != nil {
+		return fmt.Errorf("impossible de vérifier les fichiers encore utilisés: %w", err)
+	}
+	for _, key := range keys {
+		if isReferenced(referenced, key) {
+			continue
+		}
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("AVERTISSEMENT: Impossible de supprimer le fichier %s du stockage: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// CheckStorage checks the document store against the records of the database: it finds the files
+// no record references (orphans) and the records whose file is missing. Unreferenced files stored
+// less than minAge ago are not reported, as they may belong to an upload still being recorded.
+func (s *DocumentService) CheckStorage(ctx context.Context, minAge time.Duration) (*models.StorageCheck, error) {
+	// The records are read before the files, so that a file stored meanwhile is at worst a recent
+	// unreferenced file rather than reported missing.
+	references, err := s.documentRepo.FindStorageReferences()
+	if err != nil {
+		return nil, fmt.Errorf("impossible de lire les fichiers référencés: %w", err)
+	}
+	referenced := make(map[string]bool, len(references))
+	for _, reference := range references {
+		referenced[reference.Key] = true
+	}
+
+	check := &models.StorageCheck{References: len(references)}
+	stored := map[string]bool{}
+	cutoff := time.Now().Add(-minAge)
+	err = s.store.List(ctx, func(file storage.FileInfo) error {
+		check.Files++
+		stored[file.Key] = true
+		switch {
+		case isReferenced(referenced, file.Key):
+		case file.ModTime.After(cutoff):
+			check.Recent++
+		default:
+			check.Orphans = append(check.Orphans, models.StoredFile{Key: file.Key, Size: file.Size, ModTime: file.ModTime})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("impossible de lister les fichiers de %s: %w", s.store.Name(), err)
+	}
+	for _, reference := range references {
+		if !stored[reference.Key] {
+			check.Missing = append(check.Missing, reference)
+		}
+	}
+	return check, nil
+}
+
+// DeleteOrphans deletes the orphan files found by a consistency check, unless a record references
+// them since. It returns the number of files deleted.
+func (s *DocumentService) DeleteOrphans(ctx context.Context, check *models.StorageCheck) (int, error) {
+	referenced, err := s.referencedKeys()
+	if err != nil {
+		return 0, fmt.Errorf("impossible de vérifier les fichiers encore utilisés: %w", err)
+	}
+	count := 0
+	for _, file := range check.Orphans {
+		if isReferenced(referenced, file.Key) {
+			continue
+		}
+		if err := s.store.Delete(ctx, file.Key); err != nil {
+			return count, fmt.Errorf("impossible de supprimer %s: %w", file.Key, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// referencedKeys returns the set of the storage keys referenced by a record.
+func (s *DocumentService) referencedKeys() (map[string]bool, error) {
+	keys, err := s.documentRepo.FindStorageKeys()
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		referenced[key] = true
+	}
+	return referenced, nil
+}
+
+// isReferenced reports whether a stored file is in use: referenced by a record, or the thumbnail
+// of a referenced file, kept for when its version is restored.
+func isReferenced(referenced map[string]bool, key string) bool {
+	if referenced[key] {
+		return true
+	}
+	source, ok := strings.CutPrefix(key, previewPrefix)
+	if !ok {
+		return false
+	}
+	source, ok = strings.CutSuffix(source, ".jpg")
+	return ok && referenced[source]
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sniffLength is the number of leading bytes the type of an uploaded file is detected from.
+const sniffLength = 512
+
+// documentTypes lists the file types that can be uploaded, with their accepted extensions.
+var documentTypes = map[string][]string{
+	"application/pdf":               {".pdf"},
+	"image/png":                     {".png"},
+	"image/jpeg":                    {".jpg", ".jpeg"},
+	"image/gif":                     {".gif"},
+	"image/webp":                    {".webp"},
+	"text/plain":                    {".txt"},
+	"text/csv":                      {".csv"},
+	"text/markdown":                 {".md"},
+	"application/msword":            {".doc"},
+	"application/vnd.ms-excel":      {".xls"},
+	"application/vnd.ms-powerpoint": {".ppt"},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   {".docx"},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         {".xlsx"},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": {".pptx"},
+	"application/vnd.oasis.opendocument.text":                                   {".odt"},
+	"application/vnd.oasis.opendocument.spreadsheet":                            {".ods"},
+	"application/vnd.oasis.opendocument.presentation":                           {".odp"},
+}
+
+// oleSignature starts the legacy Microsoft Office files (.doc, .xls, .ppt).
+var oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Types of the containers shared by several document formats, told apart by their extension.
+const (
+	zipType = "application/zip"
+	oleType = "application/x-ole-storage"
+)
+
+// containerTypes lists the document types stored in each container format.
+var containerTypes = map[string][]string{
+	zipType: {
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+	oleType:      {"application/msword", "application/vnd.ms-excel", "application/vnd.ms-powerpoint"},
+	"text/plain": {"text/csv", "text/markdown"},
+}
+
+// DefaultDocumentTypes returns the file types accepted when DOCUMENT_ALLOWED_TYPES is not set.
+func DefaultDocumentTypes() []string {
+	types := make([]string, 0, len(documentTypes))
+	for mimeType := range documentTypes {
+		types = append(types, mimeType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// detectContentType detects the type of a file from its first bytes, whatever its name and the
+// type announced by the browser. The name only tells apart the formats sharing a container, such
+// as the Office documents, which are zip archives.
+func detectContentType(head []byte, name string) string {
+	extension := strings.ToLower(filepath.Ext(name))
+	detected, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if bytes.HasPrefix(head, oleSignature) {
+		detected = oleType
+	}
+	// OpenDocument files start with an uncompressed "mimetype" entry holding their type.
+	if detected == zipType && len(head) > 38 && string(head[30:38]) == "mimetype" {
+		mimeType := head[38:]
+		if end := bytes.Index(mimeType, []byte("PK")); end >= 0 {
+			mimeType = mimeType[:end]
+		}
+		if _, ok := documentTypes[string(mimeType)]; ok {
+			return string(mimeType)
+		}
+	}
+	for _, mimeType := range containerTypes[detected] {
+		for _, accepted := range documentTypes[mimeType] {
+			if accepted == extension {
+				return mimeType
+			}
+		}
+	}
+	return detected
+}
+
+// hasExtensionOf reports whether a file name has one of the extensions of a document type. Types
+// not listed in documentTypes, allowed through DOCUMENT_ALLOWED_TYPES, accept any extension.
+func hasExtensionOf(name, mimeType string) bool {
+	extensions, ok := documentTypes[mimeType]
+	if !ok {
+		return true
+	}
+	extension := strings.ToLower(filepath.Ext(name))
+	for _, accepted := range extensions {
+		if accepted == extension {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// ErrUploadRejected is wrapped by the errors of the files refused at upload: type not allowed,
+// file too large, storage quota exceeded or malware found.
+var ErrUploadRejected = errors.New("fichier refusé")
+
+// quarantinePrefix starts the storage keys of the quarantined files, which no document references.
+const quarantinePrefix = "quarantine_"
+
+// rejectUpload returns the error of a file refused at upload.
+func rejectUpload(format string, args ...any) error {
+	return fmt.Errorf("%w : %s", ErrUploadRejected, fmt.Sprintf(format, args...))
+}
+
+// saveFile checks an uploaded file, then streams it to the document store. The type of the file
+// is detected from its content and must be allowed and match its extension; the file must fit in
+// the maximum size and in the storage quota of the association, and be found clean by the
+// antivirus, if any. An infected file is put in quarantine. saveFile returns the storage key of
+// the file and its detected type.
+func (s *DocumentService) saveFile(userID uint, uploadedBy string, file *multipart.FileHeader) (string, string, error) {
+	if s.maxFileSize > 0 && file.Size > s.maxFileSize {
+		return "", "", rejectUpload("le fichier dépasse la taille maximale de %s", FormatFileSize(s.maxFileSize))
+	}
+
+	// Open the uploaded file and detect its type from its first bytes.
+	src, err := file.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("impossible d'ouvrir le fichier téléchargé: %w", err)
+	}
+	defer src.Close()
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", "", fmt.Errorf("impossible de lire le fichier téléchargé: %w", err)
+	}
+	head = head[:n]
+	mimeType := detectContentType(head, file.Filename)
+	if !s.allowedTypes[mimeType] {
+		return "", "", rejectUpload("ce type de fichier n'est pas autorisé (%s)", mimeType)
+	}
+	if !hasExtensionOf(file.Filename, mimeType) {
+		return "", "", rejectUpload("l'extension du fichier ne correspond pas à son contenu (%s)", mimeType)
+	}
+
+	if s.quota > 0 {
+		used, err := s.documentRepo.GetStorageUsed(userID)
+		if err != nil {
+			return "", "", fmt.Errorf("impossible de calculer l'espace de stockage utilisé: %w", err)
+		}
+		if used+file.Size > s.quota {
+			return "", "", rejectUpload("espace de stockage insuffisant (%s utilisés sur %s, corbeille comprise)", FormatFileSize(used), FormatFileSize(s.quota))
+		}
+		// The quota is checked again when the file is recorded, as other uploads may be stored
+		// meanwhile.
+	}
+
+	// Generate a unique key to prevent collisions, including between several files with the same
+	// name uploaded in the same request. The original name is kept at the end of the key, so that
+	// its extension tells the type of the file.
+	key := fmt.Sprintf("%d_%s_%s", userID, time.Now().Format("20060102150405.000000000"), storageName(file.Filename))
+
+	if s.scanner != nil {
+		signature, err := s.scan(file)
+		if err != nil {
+			// A file that cannot be scanned is refused rather than stored unchecked.
+			log.Printf("ERREUR: Analyse antivirus de %s impossible: %v", file.Filename, err)
+			return "", "", fmt.Errorf("analyse antivirus impossible, le fichier n'a pas été enregistré: %w", err)
+		}
+		if signature != "" {
+			s.quarantine(userID, uploadedBy, key, mimeType, signature, file)
+			return "", "", rejectUpload("le fichier contient un logiciel malveillant (%s) et a été mis en quarantaine", signature)
+		}
+	}
+
+	// Stream the file to the store, starting with the bytes already read.
+	content := io.MultiReader(bytes.NewReader(head), src)
+	if err := s.store.Put(context.Background(), key, content, file.Size, mimeType); err != nil {
+		s.removeFile(key)
+		return "", "", fmt.Errorf("impossible d'enregistrer le fichier: %w", err)
+	}
+	return key, mimeType, nil
+}
+
+// rejectOverQuota returns the error of a file refused when recorded, because files stored
+// meanwhile filled the storage quota of the association.
+func (s *DocumentService) rejectOverQuota(userID uint) error {
+	used, err := s.documentRepo.GetStorageUsed(userID)
+	if err != nil {
+		return rejectUpload("espace de stockage insuffisant (%s, corbeille comprise)", FormatFileSize(s.quota))
+	}
+	return rejectUpload("espace de stockage insuffisant (%s utilisés sur %s, corbeille comprise)", FormatFileSize(used), FormatFileSize(s.quota))
+}
+
+// scan submits an uploaded file to the antivirus. It returns the name of the malware found, or an
+// empty string for a clean file.
+func (s *DocumentService) scan(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	result, err := s.scanner.Scan(context.Background(), src)
+	if err != nil {
+		return "", err
+	}
+	if result.Infected && result.Signature == "" {
+		return "inconnu", nil
+	}
+	return result.Signature, nil
+}
+
+// quarantine stores an infected file aside and records it, so that it can be examined before
+// being deleted. Failures are logged: the file is refused anyway.
+func (s *DocumentService) quarantine(userID uint, uploadedBy, key, mimeType, signature string, file *multipart.FileHeader) {
+	log.Printf("AVERTISSEMENT: Logiciel malveillant %s détecté dans %s, mis en quarantaine", signature, file.Filename)
+	quarantined := &models.QuarantinedFile{
+		UserID:       userID,
+		FileName:     file.Filename,
+		StorageKey:   quarantinePrefix + key,
+		FileSize:     file.Size,
+		MimeType:     mimeType,
+		Signature:    signature,
+		UploadedByID: userID,
+		UploadedBy:   uploadedBy,
+		DetectedAt:   time.Now(),
+	}
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("ERREUR: Impossible de mettre en quarantaine %s: %v", file.Filename, err)
+		return
+	}
+	defer src.Close()
+	if err := s.store.Put(context.Background(), quarantined.StorageKey, src, file.Size, "application/octet-stream"); err != nil {
+		log.Printf("ERREUR: Impossible de mettre en quarantaine %s: %v", file.Filename, err)
+		return
+	}
+	if err := s.documentRepo.CreateQuarantinedFile(quarantined); err != nil {
+		log.Printf("ERREUR: Impossible d'enregistrer la mise en quarantaine de %s: %v", file.Filename, err)
+		s.removeFile(quarantined.StorageKey)
+	}
+}
+
+// GetQuarantinedFiles retrieves the quarantined files of a user, most recent first.
+func (s *DocumentService) GetQuarantinedFiles(userID uint) ([]models.QuarantinedFile, error) {
+	return s.documentRepo.FindQuarantinedFiles(userID)
+}
+
+// DeleteQuarantinedFile deletes a quarantined file of a user and its record.
+func (s *DocumentService) DeleteQuarantinedFile(userID, fileID uint) error {
+	file, err := s.documentRepo.FindQuarantinedFileByID(fileID)
+	if err != nil || file.UserID != userID {
+		return fmt.Errorf("fichier en quarantaine non trouvé")
+	}
+	if err := s.store.Delete(context.Background(), file.StorageKey); err != nil {
+		return fmt.Errorf("impossible de supprimer le fichier: %w", err)
+	}
+	return s.documentRepo.DeleteQuarantinedFile(fileID)
+}
+
+// GetStorageUsage returns the number of documents of a user, the storage space they use and
+// their quota.
+func (s *DocumentService) GetStorageUsage(userID uint) (models.StorageUsage, error) {
+	usage := models.StorageUsage{Quota: s.quota}
+	var err error
+	if usage.Documents, err = s.documentRepo.GetTotalDocumentsCount(userID); err != nil {
+		return usage, err
+	}
+	usage.Used, err = s.documentRepo.GetStorageUsed(userID)
+	return usage, err
+}
+
+// MaxFileSize returns the largest file that can be uploaded, in bytes; 0 for no limit.
+func (s *DocumentService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+// AcceptedExtensions returns the extensions of the allowed file types, sorted, for the file
+// inputs of the upload forms. It is empty when a type accepting any extension is allowed.
+func (s *DocumentService) AcceptedExtensions() []string {
+	var extensions []string
+	for mimeType := range s.allowedTypes {
+		typeExtensions, ok := documentTypes[mimeType]
+		if !ok {
+			return nil
+		}
+		extensions = append(extensions, typeExtensions...)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// storageName returns the base name of an uploaded file, usable in a storage key.
+func storageName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" {
+		return "fichier"
+	}
+	return name
+}
+
+// FormatFileSize formats a size in bytes for display, e.g., "1,5 Mo" or "25 Mo".
+func FormatFileSize(size int64) string {
+	units := []string{"octets", "Ko", "Mo", "Go", "To"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d octets", size)
+	}
+	formatted := strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0")
+	return strings.Replace(formatted, ".", ",", 1) + " " + units[unit]
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// ExpenseClaimService encapsulates the business logic for expense claims (notes de frais): the
+// expenses members advanced for the association, their review by the treasurer and their
+// reimbursement. Approved lines are recorded as expense transactions through the FinanceService,
+// with their receipts attached, so that they are journalized like any other expense.
+type ExpenseClaimService struct {
+	claimRepo       repositories.ExpenseClaimRepository
+	memberRepo      repositories.MemberRepository
+	financeService  *FinanceService
+	documentService *DocumentService
+	approvalService *ApprovalService
+	settingsService *SettingsService
+}
+
+// NewExpenseClaimService creates a new instance of ExpenseClaimService.
+// It takes an ExpenseClaimRepository, a MemberRepository, a FinanceService, a DocumentService,
+// an ApprovalService (which designates the treasurer) and a SettingsService as dependencies,
+// adhering to the dependency inversion principle.
+func NewExpenseClaimService(claimRepo repositories.ExpenseClaimRepository, memberRepo repositories.MemberRepository, financeService *FinanceService, documentService *DocumentService, approvalService *ApprovalService, settingsService *SettingsService) *ExpenseClaimService {
+	return &ExpenseClaimService{claimRepo: claimRepo, memberRepo: memberRepo, financeService: financeService, documentService: documentService, approvalService: approvalService, settingsService: settingsService}
+}
+
+// SubmitClaim validates and records a new claim awaiting the treasurer. receipts holds the
+// receipt uploaded for each line, in the order of the lines (nil when none was uploaded); a
+// receipt is required for the lines above the receipt threshold of the association.
+func (s *ExpenseClaimService) SubmitClaim(claim *models.ExpenseClaim, receipts []*multipart.FileHeader) error {
+	if err := s.validateClaim(claim, receipts); err != nil {
+		return err
+	}
+	for i := range claim.Lines {
+		if i >= len(receipts) || receipts[i] == nil {
+			continue
+		}
+		document, err := s.documentService.StoreReceipt(claim.UserID, receipts[i])
+		if err != nil {
+			return fmt.Errorf("ligne %d: %w", i+1, err)
+		}
+		claim.Lines[i].ReceiptDocumentID = &document.ID
+	}
+	claim.Status = models.ClaimSubmitted
+	return s.claimRepo.CreateClaim(claim)
+}
+
+// GetClaim retrieves a claim, checking that the user is the owner of the association or reviews
+// its expenses.
+func (s *ExpenseClaimService) GetClaim(actor models.User, id uint) (*models.ExpenseClaim, error) {
+	claim, err := s.claimRepo.FindClaimByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("note de frais non trouvée")
+	}
+	if claim.UserID == actor.ID {
+		return claim, nil
+	}
+	if ok, err := s.approvalService.CanReview(actor, claim.UserID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("accès non autorisé")
+	}
+	return claim, nil
+}
+
+// GetReceipt returns the receipt of a claim line, for the users allowed to see the claim.
+func (s *ExpenseClaimService) GetReceipt(actor models.User, claimID, lineID uint) (*models.Document, error) {
+	claim, err := s.GetClaim(actor, claimID)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range claim.Lines {
+		if line.ID == lineID && line.ReceiptDocumentID != nil {
+			return s.documentService.GetDocumentByID(*line.ReceiptDocumentID)
+		}
+	}
+	return nil, fmt.Errorf("justificatif non trouvé")
+}
+
+// OpenReceipt returns the receipt of a claim line with its file opened for streaming, for the
+// users allowed to see the claim. The caller must close the file.
+func (s *ExpenseClaimService) OpenReceipt(ctx context.Context, actor models.User, claimID, lineID uint) (*models.Document, io.ReadCloser, error) {
+	document, err := s.GetReceipt(actor, claimID, lineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := s.documentService.OpenFile(ctx, document.FilePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return document, file, nil
+}
+
+// GetClaimsByUserID retrieves the claims addressed to an association, most recent first,
+// optionally restricted to one member.
+func (s *ExpenseClaimService) GetClaimsByUserID(userID, memberID uint) ([]models.ExpenseClaim, error) {
+	return s.claimRepo.FindClaimsByUserID(userID, memberID)
+}
+
+// GetReviewQueue returns the claims awaiting the decision of a user in the associations they
+// review, other than their own.
+func (s *ExpenseClaimService) GetReviewQueue(actor models.User) ([]models.ExpenseClaim, error) {
+	ownerIDs, err := s.settingsService.GetTreasurerAssociations(actor.Email)
+	if err != nil {
+		return nil, err
+	}
+	var queue []models.ExpenseClaim
+	for _, ownerID := range ownerIDs {
+		if ownerID == actor.ID {
+			continue
+		}
+		claims, err := s.claimRepo.FindClaimsByUserID(ownerID, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, claim := range claims {
+			if claim.Status == models.ClaimSubmitted {
+				queue = append(queue, claim)
+			}
+		}
+	}
+	return queue, nil
+}
+
+// GetReimbursements sums the claims of an association per member: the totals awaiting review,
+// owed and already paid back. Members without claims are omitted.
+func (s *ExpenseClaimService) GetReimbursements(userID uint) ([]models.MemberReimbursement, error) {
+	claims, err := s.claimRepo.FindClaimsByUserID(userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	byMember := map[uint]*models.MemberReimbursement{}
+	for _, claim := range claims {
+		summary, ok := byMember[claim.MemberID]
+		if !ok {
+			summary = &models.MemberReimbursement{MemberID: claim.MemberID, MemberName: s.MemberName(claim.MemberID)}
+			byMember[claim.MemberID] = summary
+		}
+		total := claim.Total().Amount
+		switch {
+		case claim.Status == models.ClaimSubmitted:
+			summary.Submitted += total
+		case claim.Reimbursement == models.ReimbursementPending:
+			summary.Pending += total
+		case claim.Reimbursement == models.ReimbursementPaid:
+			summary.Paid += total
+		}
+	}
+	summaries := make([]models.MemberReimbursement, 0, len(byMember))
+	for _, summary := range byMember {
+		summaries = append(summaries, *summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].MemberName < summaries[j].MemberName })
+	return summaries, nil
+}
+
+// Approve records the lines of a submitted claim as posted expense transactions linked to the
+// member, attaches their receipts, and leaves the total to be reimbursed to the member. If one of
+// the transactions cannot be recorded, those already recorded are deleted and the claim is
+// left untouched.
+func (s *ExpenseClaimService) Approve(actor models.User, id uint, comment string) error {
+	claim, err := s.reviewable(actor, id)
+	if err != nil {
+		return err
+	}
+	member := s.MemberName(claim.MemberID)
+	var recorded []uint
+	for i := range claim.Lines {
+		line := &claim.Lines[i]
+		memberID := claim.MemberID
+		transaction := &models.Transaction{
+			Amount:      models.Money{Amount: line.Amount, Currency: claim.Currency},
+			Type:        models.TypeExpense,
+			Description: fmt.Sprintf("Note de frais n°%d (%s) : %s", claim.ID, member, line.Description),
+			Date:        line.Date,
+			AccountCode: line.AccountCode,
+			UserID:      claim.UserID,
+			MemberID:    &memberID,
+			// The treasurer approved the claim: its lines do not go through the approval again.
+			Status: models.TransactionPosted,
+		}
+		if err := s.financeService.CreateTransaction(transaction); err != nil {
+			s.rollback(recorded)
+			return fmt.Errorf("ligne %d: %w", i+1, err)
+		}
+		recorded = append(recorded, transaction.ID)
+		line.TransactionID = &transaction.ID
+		if line.ReceiptDocumentID != nil {
+			if err := s.documentService.LinkToTransaction(claim.UserID, *line.ReceiptDocumentID, transaction.ID); err != nil {
+				log.Printf("ERREUR: Impossible d'attacher le justificatif de la note de frais %d à la transaction %d: %v", claim.ID, transaction.ID, err)
+			}
+		}
+	}
+
+	s.review(claim, actor, models.ClaimApproved, comment)
+	claim.Reimbursement = models.ReimbursementPending
+	if err := s.claimRepo.UpdateClaim(claim); err != nil {
+		s.rollback(recorded)
+		return err
+	}
+	return nil
+}
+
+// Reject refuses a submitted claim. A comment explaining the refusal is required.
+func (s *ExpenseClaimService) Reject(actor models.User, id uint, comment string) error {
+	if strings.TrimSpace(comment) == "" {
+		return fmt.Errorf("un commentaire est requis pour rejeter une note de frais")
+	}
+	claim, err := s.reviewable(actor, id)
+	if err != nil {
+		return err
+	}
+	s.review(claim, actor, models.ClaimRejected, comment)
+	return s.claimRepo.UpdateClaim(claim)
+}
+
+// MarkPaid records the reimbursement of an approved claim to the member.
+func (s *ExpenseClaimService) MarkPaid(actor models.User, id uint, paidAt time.Time, reference string) error {
+	claim, err := s.claimRepo.FindClaimByID(id)
+	if err != nil {
+		return fmt.Errorf("note de frais non trouvée")
+	}
+	if ok, err := s.approvalService.CanReview(actor, claim.UserID); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("seul le trésorier peut enregistrer un remboursement")
+	}
+	if claim.Reimbursement != models.ReimbursementPending {
+		return fmt.Errorf("cette note de frais n'est pas en attente de remboursement")
+	}
+	if paidAt.IsZero() {
+		return fmt.Errorf("la date de remboursement est requise")
+	}
+	claim.Reimbursement = models.ReimbursementPaid
+	claim.PaidAt = &paidAt
+	claim.PaymentRef = strings.TrimSpace(reference)
+	return s.claimRepo.UpdateClaim(claim)
+}
+
+// DeleteClaim deletes a claim that has not been approved. The uploaded receipts are kept in the
+// document library.
+func (s *ExpenseClaimService) DeleteClaim(userID, id uint) error {
+	claim, err := s.claimRepo.FindClaimByID(id)
+	if err != nil || claim.UserID != userID {
+		return fmt.Errorf("note de frais non trouvée")
+	}
+	if claim.Status == models.ClaimApproved {
+		return fmt.Errorf("une note de frais approuvée ne peut pas être supprimée")
+	}
+	return s.claimRepo.DeleteClaim(id)
+}
+
+// reviewable retrieves a submitted claim and checks that the user may decide on it.
+func (s *ExpenseClaimService) reviewable(actor models.User, id uint) (*models.ExpenseClaim, error) {
+	claim, err := s.claimRepo.FindClaimByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("note de frais non trouvée")
+	}
+	ok, err := s.approvalService.CanReview(actor, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("seul le trésorier peut approuver ou rejeter cette note de frais")
+	}
+	if claim.Status != models.ClaimSubmitted {
+		return nil, fmt.Errorf("cette note de frais n'est pas en attente d'examen")
+	}
+	return claim, nil
+}
+
+// review records the decision of the treasurer on a claim.
+func (s *ExpenseClaimService) review(claim *models.ExpenseClaim, actor models.User, status models.ExpenseClaimStatus, comment string) {
+	now := time.Now()
+	claim.Status = status
+	claim.ReviewerName = actor.Name
+	claim.ReviewComment = strings.TrimSpace(comment)
+	claim.ReviewedAt = &now
+}
+
+// rollback deletes the transactions recorded for a claim whose approval failed.
+func (s *ExpenseClaimService) rollback(transactionIDs []uint) {
+	for _, id := range transactionIDs {
+		if err := s.financeService.DeleteTransaction(id); err != nil {
+			log.Printf("ERREUR: Impossible d'annuler la transaction %d de la note de frais: %v", id, err)
+		}
+	}
+}
+
+// validateClaim performs business logic validation on a new claim: the member belongs to the
+// association, and every line has a date, a description, a positive amount, an expense category
+// and, above the receipt threshold, a receipt. Amounts are in the base currency.
+func (s *ExpenseClaimService) validateClaim(claim *models.ExpenseClaim, receipts []*multipart.FileHeader) error {
+	claim.Title = strings.TrimSpace(claim.Title)
+	claim.Comment = strings.TrimSpace(claim.Comment)
+	if claim.Title == "" {
+		return fmt.Errorf("le titre est requis")
+	}
+	member, err := s.memberRepo.FindMemberByID(claim.MemberID)
+	if err != nil || member.UserID != claim.UserID {
+		return fmt.Errorf("membre non trouvé")
+	}
+	if len(claim.Lines) == 0 {
+		return fmt.Errorf("la note de frais doit comporter au moins une dépense")
+	}
+	settings, err := s.settingsService.GetSettings(claim.UserID)
+	if err != nil {
+		return err
+	}
+	claim.Currency = settings.BaseCurrency
+	accounts, err := s.financeService.GetCategoryAccounts(claim.UserID, models.TypeExpense)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération du plan comptable: %w", err)
+	}
+	categories := map[string]bool{}
+	for _, account := range accounts {
+		categories[account.Code] = true
+	}
+
+	for i := range claim.Lines {
+		line := &claim.Lines[i]
+		line.Description = strings.TrimSpace(line.Description)
+		switch {
+		case line.Date.IsZero():
+			return fmt.Errorf("ligne %d: la date est requise", i+1)
+		case line.Description == "":
+			return fmt.Errorf("ligne %d: la description est requise", i+1)
+		case line.Amount <= 0:
+			return fmt.Errorf("ligne %d: le montant doit être supérieur à zéro", i+1)
+		case !categories[line.AccountCode]:
+			return fmt.Errorf("ligne %d: catégorie de dépense invalide", i+1)
+		}
+		if line.Amount > settings.ReceiptThreshold && (i >= len(receipts) || receipts[i] == nil) {
+			return fmt.Errorf("ligne %d: un justificatif est requis au-delà de %s %s", i+1, FormatCents(settings.ReceiptThreshold), settings.BaseCurrency)
+		}
+	}
+	return nil
+}
+
+// CanReview reports whether a user may approve, reject and reimburse a claim.
+func (s *ExpenseClaimService) CanReview(actor models.User, claim *models.ExpenseClaim) (bool, error) {
+	return s.approvalService.CanReview(actor, claim.UserID)
+}
+
+// MemberName returns the full name of a member, or a placeholder if the member was deleted.
+func (s *ExpenseClaimService) MemberName(memberID uint) string {
+	member, err := s.memberRepo.FindMemberByID(memberID)
+	if err != nil {
+		return fmt.Sprintf("Membre n°%d", memberID)
+	}
+	return memberName(member)
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// fecColumns are the 18 mandatory columns of the Fichier des Écritures Comptables,
+// in the order set by article A. 47 A-1 of the French tax procedure code.
+var fecColumns = []string{
+	"JournalCode", "JournalLib", "EcritureNum", "EcritureDate", "CompteNum", "CompteLib",
+	"CompAuxNum", "CompAuxLib", "PieceRef", "PieceDate", "EcritureLib", "Debit", "Credit",
+	"EcritureLet", "DateLet", "ValidDate", "Montantdevise", "Idevise",
+}
+
+// csvJournalColumns are the columns of the generic CSV journal export.
+var csvJournalColumns = []string{"Journal", "Numéro", "Date", "Compte", "Libellé compte", "Pièce", "Libellé", "Débit", "Crédit", "Montant devise", "Devise"}
+
+// Journals the entries are exported to: entries generated from transactions move the bank
+// account, entries typed in the journal are miscellaneous operations.
+const (
+	bankJournalCode  = "BQ"
+	bankJournalName  = "Banque"
+	otherJournalCode = "OD"
+	otherJournalName = "Opérations diverses"
+)
+
+// ExportService generates the accounting exports handed to the accountant or loaded into external
+// accounting software: the FEC and a CSV journal from the double-entry journal, QIF and OFX
+// statements from the posted transactions.
+type ExportService struct {
+	transactionRepo repositories.TransactionRepository
+	ledgerService   *LedgerService
+	settingsService *SettingsService
+}
+
+// NewExportService creates a new instance of ExportService.
+// It takes a TransactionRepository, a LedgerService and a SettingsService as dependencies,
+// adhering to the dependency inversion principle.
+func NewExportService(transactionRepo repositories.TransactionRepository, ledgerService *LedgerService, settingsService *SettingsService) *ExportService {
+	return &ExportService{transactionRepo: transactionRepo, ledgerService: ledgerService, settingsService: settingsService}
+}
+
+// FiscalYearPeriod returns the first day of a fiscal year of a user's association and the first
+// day of the next one.
+func (s *ExportService) FiscalYearPeriod(userID uint, year int) (time.Time, time.Time, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from, to := settings.FiscalYearBounds(year)
+	return from, to, nil
+}
+
+// CurrentFiscalYear returns the fiscal year of a user's association that today belongs to.
+func (s *ExportService) CurrentFiscalYear(userID uint) (int, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return 0, err
+	}
+	return settings.FiscalYear(time.Now()), nil
+}
+
+// Export generates the export of a user's accounting data dated within [from, to) in the given
+// format. The generated file is checked before being returned, so that a malformed file is never
+// handed over.
+func (s *ExportService) Export(userID uint, format models.ExportFormat, from, to time.Time) (*models.ExportFile, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("la fin de la période doit être postérieure à son début")
+	}
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var file *models.ExportFile
+	switch format {
+	case models.ExportFECPipe:
+		file, err = s.exportFEC(userID, settings, from, to, "|")
+	case models.ExportFECTab:
+		file, err = s.exportFEC(userID, settings, from, to, "\t")
+	case models.ExportCSV:
+		file, err = s.exportCSVJournal(userID, settings, from, to)
+	case models.ExportQIF:
+		file, err = s.exportQIF(userID, settings, from, to)
+	case models.ExportOFX:
+		file, err = s.exportOFX(userID, settings, from, to)
+	default:
+		return nil, fmt.Errorf("format d'export inconnu")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateExport(format, file.Content); err != nil {
+		return nil, fmt.Errorf("le fichier généré est invalide: %w", err)
+	}
+	return file, nil
+}
+
+// exportLine is a journal line with the entry data every export line repeats.
+type exportLine struct {
+	journalCode, journalName, number string
+	entry                            *models.JournalEntry
+	line                             models.JournalLine
+	account                          models.Account
+	foreign                          *models.Money // The original amount of a foreign-currency transaction.
+}
+
+// journalLines returns the journal lines dated within [from, to), numbered continuously within
+// each journal in chronological order.
+func (s *ExportService) journalLines(userID uint, settings *models.AssociationSettings, from, to time.Time) ([]exportLine, error) {
+	entries, err := s.ledgerService.GetJournal(userID, from, to.Add(-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.ledgerService.accountsByCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactionRepo.FindPostedTransactionsByPeriod(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	foreign := map[uint]*models.Money{}
+	for i := range transactions {
+		if transactions[i].Amount.Currency != settings.BaseCurrency {
+			foreign[transactions[i].ID] = &transactions[i].Amount
+		}
+	}
+
+	var lines []exportLine
+	sequences := map[string]int{}
+	for i := range entries {
+		entry := &entries[i]
+		journalCode, journalName := otherJournalCode, otherJournalName
+		var original *models.Money
+		if entry.TransactionID != nil {
+			journalCode, journalName = bankJournalCode, bankJournalName
+			original = foreign[*entry.TransactionID]
+		}
+		sequences[journalCode]++
+		number := fmt.Sprintf("%s%06d", journalCode, sequences[journalCode])
+		for _, line := range entry.Lines {
+			lines = append(lines, exportLine{
+				journalCode: journalCode,
+				journalName: journalName,
+				number:      number,
+				entry:       entry,
+				line:        line,
+				account:     accounts.lookup(line.AccountCode),
+				foreign:     original,
+			})
+		}
+	}
+	return lines, nil
+}
+
+// pieceRef returns the reference of the supporting document of an entry, which the FEC requires.
+func (l *exportLine) pieceRef() string {
+	switch {
+	case l.entry.Reference != "":
+		return l.entry.Reference
+	case l.entry.TransactionID != nil:
+		return fmt.Sprintf("TR%d", *l.entry.TransactionID)
+	default:
+		return fmt.Sprintf("EC%d", l.entry.ID)
+	}
+}
+
+// label returns the label of a line, falling back to the description of its entry.
+func (l *exportLine) label() string {
+	if label := strings.TrimSpace(l.line.Label); label != "" {
+		return label
+	}
+	if description := strings.TrimSpace(l.entry.Description); description != "" {
+		return description
+	}
+	return "Écriture " + l.number
+}
+
+// exportFEC writes the Fichier des Écritures Comptables: one line per debit or credit, dates as
+// YYYYMMDD, amounts with a decimal comma, encoded in ISO 8859-15 and named after the registration
+// number of the association and the closing date of the period.
+func (s *ExportService) exportFEC(userID uint, settings *models.AssociationSettings, from, to time.Time, separator string) (*models.ExportFile, error) {
+	lines, err := s.journalLines(userID, settings, from, to)
+	if err != nil {
+		return nil, err
+	}
+	decimals, _ := models.CurrencyDecimals(settings.BaseCurrency)
+
+	var b strings.Builder
+	b.WriteString(strings.Join(fecColumns, separator) + "\r\n")
+	for _, l := range lines {
+		validDate := l.entry.CreatedAt
+		if validDate.IsZero() {
+			validDate = l.entry.Date
+		}
+		var foreignAmount, foreignCurrency string
+		if l.foreign != nil {
+			foreignDecimals, _ := models.CurrencyDecimals(l.foreign.Currency)
+			foreignAmount = models.FormatMinorUnits(l.foreign.Amount, foreignDecimals, ",")
+			foreignCurrency = l.foreign.Currency
+		}
+		fields := []string{
+			l.journalCode, l.journalName, l.number, l.entry.Date.Format("20060102"),
+			l.account.Code, l.account.Name, "", "",
+			l.pieceRef(), l.entry.Date.Format("20060102"), l.label(),
+			models.FormatMinorUnits(l.line.Debit, decimals, ","), models.FormatMinorUnits(l.line.Credit, decimals, ","),
+			"", "", validDate.Format("20060102"), foreignAmount, foreignCurrency,
+		}
+		for i := range fields {
+			fields[i] = fecField(fields[i], separator)
+		}
+		b.WriteString(strings.Join(fields, separator) + "\r\n")
+	}
+
+	return &models.ExportFile{
+		Filename:    fmt.Sprintf("%sFEC%s.txt", fecIdentifier(settings.RegistrationNumber), to.AddDate(0, 0, -1).Format("20060102")),
+		ContentType: "text/plain; charset=iso-8859-15",
+		Content:     encodeLatin9(b.String()),
+		Records:     len(lines),
+	}, nil
+}
+
+// exportCSVJournal writes the journal as a semicolon-delimited CSV file with decimal commas,
+// as expected by spreadsheets and accounting software set up for France.
+func (s *ExportService) exportCSVJournal(userID uint, settings *models.AssociationSettings, from, to time.Time) (*models.ExportFile, error) {
+	lines, err := s.journalLines(userID, settings, from, to)
+	if err != nil {
+		return nil, err
+	}
+	decimals, _ := models.CurrencyDecimals(settings.BaseCurrency)
+
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff") // Lets spreadsheets detect UTF-8.
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+	w.UseCRLF = true
+	if err := w.Write(csvJournalColumns); err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		foreignAmount, currency := "", settings.BaseCurrency
+		if l.foreign != nil {
+			foreignDecimals, _ := models.CurrencyDecimals(l.foreign.Currency)
+			foreignAmount = models.FormatMinorUnits(l.foreign.Amount, foreignDecimals, ",")
+			currency = l.foreign.Currency
+		}
+		if err := w.Write([]string{
+			l.journalCode, l.number, l.entry.Date.Format("02/01/2006"), l.account.Code, l.account.Name,
+			l.pieceRef(), l.label(),
+			models.FormatMinorUnits(l.line.Debit, decimals, ","), models.FormatMinorUnits(l.line.Credit, decimals, ","),
+			foreignAmount, currency,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return &models.ExportFile{
+		Filename:    fmt.Sprintf("journal_%s_%s.csv", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")),
+		ContentType: "text/csv; charset=utf-8",
+		Content:     buf.Bytes(),
+		Records:     len(lines),
+	}, nil
+}
+
+// exportQIF writes the posted transactions as the movements of a QIF bank account, with amounts
+// in the base currency and the category as the account code and name.
+func (s *ExportService) exportQIF(userID uint, settings *models.AssociationSettings, from, to time.Time) (*models.ExportFile, error) {
+	transactions, err := s.transactionRepo.FindPostedTransactionsByPeriod(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := s.ledgerService.accountsByCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	decimals, _ := models.CurrencyDecimals(settings.BaseCurrency)
+
+	var b strings.Builder
+	b.WriteString("!Type:Bank\r\n")
+	for _, transaction := range transactions {
+		b.WriteString("D" + transaction.Date.Format("01/02/2006") + "\r\n")
+		b.WriteString("T" + models.FormatMinorUnits(signedBaseAmount(&transaction), decimals, ".") + "\r\n")
+		b.WriteString("P" + singleLine(transaction.Description) + "\r\n")
+		if code := categoryCode(&transaction); code != "" {
+			b.WriteString("L" + singleLine(code+" "+accounts.lookup(code).Name) + "\r\n")
+		}
+		if transaction.Amount.Currency != settings.BaseCurrency {
+			b.WriteString("MMontant d'origine " + transaction.Amount.String() + "\r\n")
+		}
+		b.WriteString(fmt.Sprintf("N%s\r\n", transactionReference(&transaction)))
+		b.WriteString("^\r\n")
+	}
+
+	return &models.ExportFile{
+		Filename:    fmt.Sprintf("transactions_%s_%s.qif", from.Format("20060102"), to.AddDate(0, 0, -1).Format("20060102")),
+		ContentType: "application/qif",
+		Content:     []byte(b.String()),
+		Records:     len(transactions),
+	}, nil
+}
+
+// exportOFX writes the posted transactions as an OFX 2.2 statement of the bank account, with the
+// balance of all posted transactions at the end of the period.
+func (s *ExportService) exportOFX(userID uint, settings *models.AssociationSettings, from, to time.Time) (*models.ExportFile, error) {
+	transactions, err := s.transactionRepo.FindPostedTransactionsByPeriod(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.transactionRepo.FindPostedTransactionsByPeriod(userID, time.Time{}, to)
+	if err != nil {
+		return nil, err
+	}
+	var balance int64
+	for i := range history {
+		balance += signedBaseAmount(&history[i])
+	}
+	decimals, _ := models.CurrencyDecimals(settings.BaseCurrency)
+	end := to.AddDate(0, 0, -1)
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n")
+	b.WriteString(`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n")
+	b.WriteString("<OFX>\n<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	b.WriteString("<DTSERVER>" + time.Now().Format("20060102150405") + "</DTSERVER><LANGUAGE>FRA</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n")
+	b.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>1</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n<STMTRS>")
+	b.WriteString("<CURDEF>" + settings.BaseCurrency + "</CURDEF>")
+	b.WriteString("<BANKACCTFROM><BANKID>0</BANKID><ACCTID>" + models.DefaultBankAccountCode + "</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n")
+	b.WriteString("<BANKTRANLIST><DTSTART>" + from.Format("20060102") + "</DTSTART><DTEND>" + end.Format("20060102") + "</DTEND>\n")
+	for _, transaction := range transactions {
+		transactionType := "CREDIT"
+		if transaction.Type == models.TypeExpense {
+			transactionType = "DEBIT"
+		}
+		b.WriteString("<STMTTRN><TRNTYPE>" + transactionType + "</TRNTYPE>")
+		b.WriteString("<DTPOSTED>" + transaction.Date.Format("20060102") + "</DTPOSTED>")
+		b.WriteString("<TRNAMT>" + models.FormatMinorUnits(signedBaseAmount(&transaction), decimals, ".") + "</TRNAMT>")
+		b.WriteString("<FITID>" + xmlText(transactionReference(&transaction)) + "</FITID>")
+		b.WriteString("<NAME>" + xmlText(truncateRunes(singleLine(transaction.Description), 32)) + "</NAME>")
+		b.WriteString("<MEMO>" + xmlText(singleLine(transaction.Description)) + "</MEMO></STMTTRN>\n")
+	}
+	b.WriteString("</BANKTRANLIST>\n")
+	b.WriteString("<LEDGERBAL><BALAMT>" + models.FormatMinorUnits(balance, decimals, ".") + "</BALAMT><DTASOF>" + end.Format("20060102") + "</DTASOF></LEDGERBAL>\n")
+	b.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n</OFX>\n")
+
+	return &models.ExportFile{
+		Filename:    fmt.Sprintf("transactions_%s_%s.ofx", from.Format("20060102"), end.Format("20060102")),
+		ContentType: "application/x-ofx",
+		Content:     []byte(b.String()),
+		Records:     len(transactions),
+	}, nil
+}
+
+// signedBaseAmount returns the base amount of a transaction as a bank movement: positive for an
+// income, negative for an expense.
+func signedBaseAmount(transaction *models.Transaction) int64 {
+	if transaction.Type == models.TypeExpense {
+		return -transaction.BaseAmount
+	}
+	return transaction.BaseAmount
+}
+
+// categoryCode returns the account a transaction is booked against, defaults included.
+func categoryCode(transaction *models.Transaction) string {
+	switch {
+	case transaction.AccountCode != "":
+		return transaction.AccountCode
+	case transaction.Type == models.TypeIncome:
+		return defaultIncomeAccountCode
+	default:
+		return defaultExpenseAccountCode
+	}
+}
+
+// transactionReference returns the identifier of a transaction in bank exports: the bank reference
+// it was imported with, so that software matching on it skips the duplicate, or its ID.
+func transactionReference(transaction *models.Transaction) string {
+	if transaction.BankReference != "" {
+		return transaction.BankReference
+	}
+	return fmt.Sprintf("TR%d", transaction.ID)
+}
+
+// fecField removes from a FEC value the characters that would break the line structure.
+func fecField(value, separator string) string {
+	return singleLine(strings.NewReplacer(separator, " ", "|", " ").Replace(value))
+}
+
+// singleLine collapses the line breaks, tabs and repeated spaces of a value into single spaces.
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// xmlText escapes a value for an XML element.
+func xmlText(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+// truncateRunes shortens a text to at most n characters.
+func truncateRunes(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n])
+}
+
+// fecIdentifier returns the registration number used to name the FEC (the SIREN), keeping only
+// its letters and digits. A placeholder is used while the association has not entered it.
+func fecIdentifier(registrationNumber string) string {
+	var b strings.Builder
+	for _, r := range registrationNumber {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "000000000"
+	}
+	return b.String()
+}
+
+// latin9Specials are the characters ISO 8859-15 encodes in place of ISO 8859-1 symbols.
+var latin9Specials = map[rune]byte{
+	'€': 0xA4, 'Š': 0xA6, 'š': 0xA8, 'Ž': 0xB4, 'ž': 0xB8, 'Œ': 0xBC, 'œ': 0xBD, 'Ÿ': 0xBE,
+}
+
+// encodeLatin9 encodes a text in ISO 8859-15, one of the encodings accepted for the FEC.
+// Characters the encoding lacks are replaced with a question mark.
+func encodeLatin9(text string) []byte {
+	replaced := map[byte]bool{}
+	for _, b := range latin9Specials {
+		replaced[b] = true
+	}
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, ok := latin9Specials[r]; ok {
+			out = append(out, b)
+		} else if r < 0x100 && !replaced[byte(r)] {
+			out = append(out, byte(r))
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// decodeLatin9 decodes an ISO 8859-15 text.
+func decodeLatin9(content []byte) string {
+	decoded := map[byte]rune{}
+	for r, b := range latin9Specials {
+		decoded[b] = r
+	}
+	var b strings.Builder
+	for _, c := range content {
+		if r, ok := decoded[c]; ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// exportAmountPattern matches the amounts of the FEC and CSV exports: digits with an optional
+// decimal comma and sign.
+var exportAmountPattern = regexp.MustCompile(`^-?[0-9]+(,[0-9]+)?$`)
+
+// ValidateExport checks that an export file is well-formed for its format: expected header and
+// columns, valid dates and amounts, and balanced entries for the journal formats.
+func ValidateExport(format models.ExportFormat, content []byte) error {
+	switch format {
+	case models.ExportFECPipe:
+		return validateFEC(content, "|")
+	case models.ExportFECTab:
+		return validateFEC(content, "\t")
+	case models.ExportCSV:
+		return validateCSVJournal(content)
+	case models.ExportQIF:
+		return validateQIF(content)
+	case models.ExportOFX:
+		return validateOFX(content)
+	}
+	return fmt.Errorf("format d'export inconnu")
+}
+
+// entryBalance accumulates the debit and credit totals of the lines of an entry.
+type entryBalance struct {
+	debit, credit int64
+	date, journal string
+}
+
+// checkBalances ensures every entry and the whole file are balanced.
+func checkBalances(entries map[string]*entryBalance, order []string) error {
+	var totalDebit, totalCredit int64
+	for _, number := range order {
+		entry := entries[number]
+		if entry.debit != entry.credit {
+			return fmt.Errorf("écriture %s déséquilibrée", number)
+		}
+		totalDebit += entry.debit
+		totalCredit += entry.credit
+	}
+	if totalDebit != totalCredit {
+		return fmt.Errorf("total des débits différent du total des crédits")
+	}
+	return nil
+}
+
+// parseExportAmount parses an amount of the FEC or CSV exports into thousandths, which is precise
+// enough for every supported currency.
+func parseExportAmount(value string) (int64, error) {
+	if !exportAmountPattern.MatchString(value) {
+		return 0, fmt.Errorf("montant invalide %q", value)
+	}
+	return ParseMinorUnits(value, 3)
+}
+
+// validateFEC checks a Fichier des Écritures Comptables: the 18 columns in order, the mandatory
+// values, YYYYMMDD dates, amounts with a decimal comma, and balanced entries sharing their date
+// and journal.
+func validateFEC(content []byte, separator string) error {
+	lines := strings.Split(strings.TrimRight(decodeLatin9(content), "\r\n"), "\n")
+	if strings.TrimRight(lines[0], "\r") != strings.Join(fecColumns, separator) {
+		return fmt.Errorf("ligne 1: en-tête FEC invalide")
+	}
+	column := map[string]int{}
+	for i, name := range fecColumns {
+		column[name] = i
+	}
+	mandatory := []string{"JournalCode", "JournalLib", "EcritureNum", "EcritureDate", "CompteNum", "CompteLib", "PieceRef", "PieceDate", "EcritureLib", "ValidDate"}
+
+	entries := map[string]*entryBalance{}
+	var order []string
+	for i, line := range lines[1:] {
+		lineNumber := i + 2
+		fields := strings.Split(strings.TrimRight(line, "\r"), separator)
+		if len(fields) != len(fecColumns) {
+			return fmt.Errorf("ligne %d: %d colonnes au lieu de %d", lineNumber, len(fields), len(fecColumns))
+		}
+		for _, name := range mandatory {
+			if strings.TrimSpace(fields[column[name]]) == "" {
+				return fmt.Errorf("ligne %d: la colonne %s est obligatoire", lineNumber, name)
+			}
+		}
+		for _, name := range []string{"EcritureDate", "PieceDate", "ValidDate", "DateLet"} {
+			if value := fields[column[name]]; value != "" {
+				if _, err := time.Parse("20060102", value); err != nil {
+					return fmt.Errorf("ligne %d: date %s invalide %q", lineNumber, name, value)
+				}
+			}
+		}
+		debit, err := parseExportAmount(fields[column["Debit"]])
+		if err != nil {
+			return fmt.Errorf("ligne %d: Debit: %w", lineNumber, err)
+		}
+		credit, err := parseExportAmount(fields[column["Credit"]])
+		if err != nil {
+			return fmt.Errorf("ligne %d: Credit: %w", lineNumber, err)
+		}
+		if currency := fields[column["Idevise"]]; currency != "" {
+			if _, ok := models.CurrencyDecimals(currency); !ok {
+				return fmt.Errorf("ligne %d: devise inconnue %q", lineNumber, currency)
+			}
+			if _, err := parseExportAmount(fields[column["Montantdevise"]]); err != nil {
+				return fmt.Errorf("ligne %d: Montantdevise: %w", lineNumber, err)
+			}
+		}
+
+		number := fields[column["EcritureNum"]]
+		entry, ok := entries[number]
+		if !ok {
+			entry = &entryBalance{date: fields[column["EcritureDate"]], journal: fields[column["JournalCode"]]}
+			entries[number] = entry
+			order = append(order, number)
+		} else if entry.date != fields[column["EcritureDate"]] || entry.journal != fields[column["JournalCode"]] {
+			return fmt.Errorf("ligne %d: les lignes de l'écriture %s n'ont pas la même date ou le même journal", lineNumber, number)
+		}
+		entry.debit += debit
+		entry.credit += credit
+	}
+	return checkBalances(entries, order)
+}
+
+// validateCSVJournal checks the CSV journal: the expected header, the same number of columns on
+// every line, dd/mm/yyyy dates, amounts with a decimal comma and balanced entries.
+func validateCSVJournal(content []byte) error {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\ufeff"))))
+	r.Comma = ';'
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("en-tête illisible: %w", err)
+	}
+	if strings.Join(header, ";") != strings.Join(csvJournalColumns, ";") {
+		return fmt.Errorf("ligne 1: en-tête invalide")
+	}
+
+	entries := map[string]*entryBalance{}
+	var order []string
+	for lineNumber := 2; ; lineNumber++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("ligne %d: %w", lineNumber, err)
+		}
+		if _, err := time.Parse("02/01/2006", record[2]); err != nil {
+			return fmt.Errorf("ligne %d: date invalide %q", lineNumber, record[2])
+		}
+		if record[0] == "" || record[1] == "" || record[3] == "" {
+			return fmt.Errorf("ligne %d: journal, numéro et compte sont obligatoires", lineNumber)
+		}
+		debit, err := parseExportAmount(record[7])
+		if err != nil {
+			return fmt.Errorf("ligne %d: débit: %w", lineNumber, err)
+		}
+		credit, err := parseExportAmount(record[8])
+		if err != nil {
+			return fmt.Errorf("ligne %d: crédit: %w", lineNumber, err)
+		}
+		key := record[0] + record[1]
+		entry, ok := entries[key]
+		if !ok {
+			entry = &entryBalance{}
+			entries[key] = entry
+			order = append(order, key)
+		}
+		entry.debit += debit
+		entry.credit += credit
+	}
+	return checkBalances(entries, order)
+}
+
+// validateQIF checks a QIF bank file: the account type header, known field codes, and a date and
+// an amount in every record, each record being closed by a caret.
+func validateQIF(content []byte) error {
+	lines := strings.Split(strings.TrimRight(string(content), "\r\n"), "\n")
+	if strings.TrimRight(lines[0], "\r") != "!Type:Bank" {
+		return fmt.Errorf("ligne 1: en-tête QIF invalide")
+	}
+	hasDate, hasAmount, open := false, false, false
+	for i, line := range lines[1:] {
+		lineNumber := i + 2
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			return fmt.Errorf("ligne %d: ligne vide", lineNumber)
+		}
+		open = true
+		switch code, value := line[0], line[1:]; code {
+		case 'D':
+			if _, err := time.Parse("01/02/2006", value); err != nil {
+				return fmt.Errorf("ligne %d: date invalide %q", lineNumber, value)
+			}
+			hasDate = true
+		case 'T':
+			if _, err := ParseMinorUnits(value, 3); err != nil || strings.Contains(value, ",") {
+				return fmt.Errorf("ligne %d: montant invalide %q", lineNumber, value)
+			}
+			hasAmount = true
+		case 'P', 'L', 'M', 'N':
+		case '^':
+			if !hasDate || !hasAmount {
+				return fmt.Errorf("ligne %d: opération sans date ou sans montant", lineNumber)
+			}
+			hasDate, hasAmount, open = false, false, false
+		default:
+			return fmt.Errorf("ligne %d: code QIF inconnu %q", lineNumber, string(code))
+		}
+	}
+	if open {
+		return fmt.Errorf("dernière opération non terminée par ^")
+	}
+	return nil
+}
+
+// validateOFX checks that an OFX 2 file is well-formed XML and that its statement can be read
+// back by the bank statement import.
+func validateOFX(content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("XML invalide: %w", err)
+		}
+	}
+	// An export of a period without movement has no STMTTRN block to read back.
+	if !bytes.Contains(content, []byte("<STMTTRN>")) {
+		return nil
+	}
+	if _, err := parseOFXStatement(bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("relevé OFX illisible: %w", err)
+	}
+	return nil
+}
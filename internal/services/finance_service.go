@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"log"
 	"strings"
 
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
@@ -9,24 +10,61 @@ import (
 )
 
 // FinanceService encapsulates the business logic for financial management.
-// It interacts with the TransactionRepository to perform CRUD operations and financial calculations.
+// It interacts with the TransactionRepository to perform CRUD operations and financial calculations,
+// with the LedgerService to keep the double-entry journal in sync with the simple transactions,
+// with the SettingsService to convert foreign-currency transactions to the base currency,
+// and with the FiscalPeriodService to keep the transactions of closed fiscal years immutable.
 type FinanceService struct {
 	transactionRepo repositories.TransactionRepository
+	ledgerService   *LedgerService
+	settingsService *SettingsService
+	periodService   *FiscalPeriodService
 }
 
 // NewFinanceService creates a new instance of FinanceService.
-// It takes a TransactionRepository as a dependency, adhering to the dependency inversion principle.
-func NewFinanceService(transactionRepo repositories.TransactionRepository) *FinanceService {
-	return &FinanceService{transactionRepo: transactionRepo}
+// It takes a TransactionRepository, a LedgerService, a SettingsService and a FiscalPeriodService
+// as dependencies, adhering to the dependency inversion principle.
+func NewFinanceService(transactionRepo repositories.TransactionRepository, ledgerService *LedgerService, settingsService *SettingsService, periodService *FiscalPeriodService) *FinanceService {
+	return &FinanceService{transactionRepo: transactionRepo, ledgerService: ledgerService, settingsService: settingsService, periodService: periodService}
 }
 
 // CreateTransaction handles the creation of a new financial transaction.
 // It performs validation on the transaction data before persisting it via the repository.
+// Transactions entered by hand are posted immediately, unless they are expenses above the
+// approval threshold of the association, which are submitted to the treasurer.
+// Transactions cannot be dated within a closed fiscal year.
 func (s *FinanceService) CreateTransaction(transaction *models.Transaction) error {
 	if err := s.validateTransaction(transaction); err != nil {
 		return err
 	}
-	return s.transactionRepo.CreateTransaction(transaction)
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+	if transaction.Status == "" {
+		status, err := s.approvalStatus(transaction)
+		if err != nil {
+			return err
+		}
+		transaction.Status = status
+	}
+	if err := s.transactionRepo.CreateTransaction(transaction); err != nil {
+		return err
+	}
+	// Write the matching journal entry; roll the transaction back if the journal rejects it
+	// so that the simple view and the ledger never disagree.
+	if err := s.ledgerService.RecordTransaction(transaction); err != nil {
+		if delErr := s.transactionRepo.DeleteTransaction(transaction.ID); delErr != nil {
+			log.Printf("ERREUR: Impossible d'annuler la transaction %d après l'échec de l'écriture comptable: %v", transaction.ID, delErr)
+		}
+		return fmt.Errorf("erreur lors de l'écriture comptable: %w", err)
+	}
+	return nil
+}
+
+// ValidateTransaction checks a transaction without recording it and computes its amount in the
+// base currency, as CreateTransaction would.
+func (s *FinanceService) ValidateTransaction(transaction *models.Transaction) error {
+	return s.validateTransaction(transaction)
 }
 
 // GetTransactionByID retrieves a financial transaction by its unique identifier.
@@ -41,36 +79,244 @@ func (s *FinanceService) GetTransactionsByUserID(userID uint) ([]models.Transact
 
 // UpdateTransaction handles the update of an existing financial transaction.
 // It performs validation on the updated transaction data before persisting the changes.
+// Editing a submitted or rejected expense submits it again, and a posted expense raised above
+// the approval threshold goes back to the treasurer. A transaction of a closed fiscal year cannot
+// be edited, nor moved into one.
 func (s *FinanceService) UpdateTransaction(transaction *models.Transaction) error {
 	if err := s.validateTransaction(transaction); err != nil {
 		return err
 	}
-	return s.transactionRepo.UpdateTransaction(transaction)
+	previous, err := s.transactionRepo.FindTransactionByID(transaction.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, previous.Date); err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+	if err := s.resubmitIfRequired(transaction, previous); err != nil {
+		return err
+	}
+	return s.saveTransaction(transaction, previous)
+}
+
+// PostTransaction confirms a draft or an approved transaction: it becomes posted, counts in the
+// totals and gets its journal entry.
+func (s *FinanceService) PostTransaction(transaction *models.Transaction) error {
+	if err := s.validateTransaction(transaction); err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+	previous, err := s.transactionRepo.FindTransactionByID(transaction.ID)
+	if err != nil {
+		return err
+	}
+	transaction.Status = models.TransactionPosted
+	return s.saveTransaction(transaction, previous)
+}
+
+// AcceptDraft records a draft transaction, e.g., an imported bank movement, as a transaction of its
+// own. It is posted, unless it is an expense above the approval threshold of the association,
+// which is submitted to the treasurer like an expense entered by hand: only its approval posts it.
+// It returns the status given to the transaction.
+func (s *FinanceService) AcceptDraft(transaction *models.Transaction) (models.TransactionStatus, error) {
+	if err := s.validateTransaction(transaction); err != nil {
+		return "", err
+	}
+	status, err := s.approvalStatus(transaction)
+	if err != nil {
+		return "", err
+	}
+	if status == models.TransactionPosted {
+		return status, s.PostTransaction(transaction)
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return "", err
+	}
+	previous, err := s.transactionRepo.FindTransactionByID(transaction.ID)
+	if err != nil {
+		return "", err
+	}
+	transaction.Status = status
+	return status, s.saveTransaction(transaction, previous)
 }
 
 // DeleteTransaction handles the deletion of a financial transaction by its unique identifier.
+// The journal entries generated from the transaction are deleted as well. Transactions of a
+// closed fiscal year cannot be deleted.
 func (s *FinanceService) DeleteTransaction(id uint) error {
-	return s.transactionRepo.DeleteTransaction(id)
+	transaction, err := s.transactionRepo.FindTransactionByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(transaction.UserID, transaction.Date); err != nil {
+		return err
+	}
+	if err := s.ledgerService.RemoveTransaction(id); err != nil {
+		return fmt.Errorf("erreur lors de la suppression de l'écriture comptable: %w", err)
+	}
+	// Write the journal entry again if the transaction cannot be deleted, so that the simple view
+	// and the ledger never disagree.
+	if err := s.transactionRepo.DeleteTransaction(id); err != nil {
+		if recErr := s.ledgerService.RecordTransaction(transaction); recErr != nil {
+			log.Printf("ERREUR: Impossible de rétablir l'écriture comptable de la transaction %d après l'échec de sa suppression: %v", id, recErr)
+		}
+		return err
+	}
+	return nil
 }
 
-// GetTotalIncome returns the total sum of all income transactions for a given user ID.
-// It delegates the call to the underlying repository.
-func (s *FinanceService) GetTotalIncome(userID uint) (float64, error) {
-	return s.transactionRepo.GetTotalIncome(userID)
+// GetCategoryAccounts returns the accounts a simple transaction of the given type can be booked against.
+func (s *FinanceService) GetCategoryAccounts(userID uint, transactionType models.TransactionType) ([]models.Account, error) {
+	if transactionType == models.TypeIncome {
+		return s.ledgerService.GetAccountsByType(userID, models.AccountIncome)
+	}
+	return s.ledgerService.GetAccountsByType(userID, models.AccountExpense)
 }
 
-// GetTotalExpenses returns the total sum of all expense transactions for a given user ID.
-// It delegates the call to the underlying repository.
-func (s *FinanceService) GetTotalExpenses(userID uint) (float64, error) {
-	return s.transactionRepo.GetTotalExpenses(userID)
+// BackfillJournal writes the journal entries of posted transactions recorded before the
+// double-entry journal existed. It returns the number of transactions journalized.
+func (s *FinanceService) BackfillJournal() (int, error) {
+	ids, err := s.ledgerService.UnjournalizedTransactionIDs()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, id := range ids {
+		transaction, err := s.transactionRepo.FindTransactionByID(id)
+		if err != nil {
+			return count, err
+		}
+		if err := s.ledgerService.RecordTransaction(transaction); err != nil {
+			return count, fmt.Errorf("transaction %d: %w", id, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// GetTotalIncome returns the total sum of all posted income transactions for a given user ID,
+// as an exact amount in the base currency.
+func (s *FinanceService) GetTotalIncome(userID uint) (models.Money, error) {
+	return s.baseTotal(userID, s.transactionRepo.GetTotalIncome)
+}
+
+// GetTotalExpenses returns the total sum of all posted expense transactions for a given user ID,
+// as an exact amount in the base currency. Expenses awaiting approval or rejected are not counted.
+func (s *FinanceService) GetTotalExpenses(userID uint) (models.Money, error) {
+	return s.baseTotal(userID, s.transactionRepo.GetTotalExpenses)
+}
+
+// GetBaseCurrency returns the currency the accounts of a user's association are kept in.
+func (s *FinanceService) GetBaseCurrency(userID uint) (string, error) {
+	return s.settingsService.GetBaseCurrency(userID)
+}
+
+// FindMissingReceipts returns the IDs of the posted expenses above the receipt threshold of the
+// association that have no document attached, given the number of attachments per transaction.
+func (s *FinanceService) FindMissingReceipts(userID uint, transactions []models.Transaction, attachmentCounts map[uint]int64) (map[uint]bool, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	missing := map[uint]bool{}
+	for _, transaction := range transactions {
+		if transaction.Type == models.TypeExpense && transaction.Status == models.TransactionPosted &&
+			transaction.BaseAmount > settings.ReceiptThreshold && attachmentCounts[transaction.ID] == 0 {
+			missing[transaction.ID] = true
+		}
+	}
+	return missing, nil
+}
+
+// saveTransaction persists a validated transaction and writes its journal entry again. previous
+// is the transaction as stored before; it is restored with its journal entry if the journal
+// rejects the transaction, so that the simple view and the ledger never disagree.
+func (s *FinanceService) saveTransaction(transaction, previous *models.Transaction) error {
+	if err := s.transactionRepo.UpdateTransaction(transaction); err != nil {
+		return err
+	}
+	if err := s.ledgerService.RecordTransaction(transaction); err != nil {
+		if restoreErr := s.transactionRepo.UpdateTransaction(previous); restoreErr != nil {
+			log.Printf("ERREUR: Impossible de rétablir la transaction %d après l'échec de l'écriture comptable: %v", previous.ID, restoreErr)
+		} else if restoreErr := s.ledgerService.RecordTransaction(previous); restoreErr != nil {
+			log.Printf("ERREUR: Impossible de rétablir l'écriture comptable de la transaction %d: %v", previous.ID, restoreErr)
+		}
+		return fmt.Errorf("erreur lors de l'écriture comptable: %w", err)
+	}
+	return nil
+}
+
+// approvalStatus returns the status a new transaction is created with: submitted for the expenses
+// requiring the approval of the treasurer, posted otherwise.
+func (s *FinanceService) approvalStatus(transaction *models.Transaction) (models.TransactionStatus, error) {
+	if transaction.Type != models.TypeExpense {
+		return models.TransactionPosted, nil
+	}
+	settings, err := s.settingsService.GetSettings(transaction.UserID)
+	if err != nil {
+		return "", fmt.Errorf("erreur lors de la récupération des paramètres: %w", err)
+	}
+	if settings.RequiresApproval(transaction.BaseAmount) {
+		return models.TransactionSubmitted, nil
+	}
+	return models.TransactionPosted, nil
+}
+
+// resubmitIfRequired updates the status of an edited transaction. Submitted and rejected
+// transactions go through the approval again; a posted expense is submitted again when its amount
+// now requires an approval it did not have, so that editing cannot bypass the treasurer.
+// previous is the transaction as stored before the edit.
+func (s *FinanceService) resubmitIfRequired(transaction, previous *models.Transaction) error {
+	switch transaction.Status {
+	case models.TransactionSubmitted, models.TransactionRejected:
+		status, err := s.approvalStatus(transaction)
+		if err != nil {
+			return err
+		}
+		transaction.Status = status
+	case models.TransactionPosted:
+		status, err := s.approvalStatus(transaction)
+		if err != nil || status != models.TransactionSubmitted {
+			return err
+		}
+		if previous.Type != models.TypeExpense || transaction.BaseAmount > previous.BaseAmount {
+			transaction.Status = models.TransactionSubmitted
+		}
+	}
+	return nil
+}
+
+// FindClosedTransactions returns the IDs of the given transactions that belong to a closed fiscal
+// year and can therefore no longer be edited or deleted.
+func (s *FinanceService) FindClosedTransactions(userID uint, transactions []models.Transaction) (map[uint]bool, error) {
+	return s.periodService.GetClosedTransactions(userID, transactions)
+}
+
+// baseTotal wraps a total computed by the repository in minor units into a Money in the base currency.
+func (s *FinanceService) baseTotal(userID uint, total func(userID uint) (int64, error)) (models.Money, error) {
+	currency, err := s.settingsService.GetBaseCurrency(userID)
+	if err != nil {
+		return models.Money{}, err
+	}
+	amount, err := total(userID)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return models.Money{Amount: amount, Currency: currency}, nil
 }
 
 // validateTransaction performs business logic validation on a Transaction model.
-// It checks for valid amount, non-empty description, and a valid date.
+// It checks for valid amount, non-empty description, and a valid date, then computes
+// the amount in the base currency.
 func (s *FinanceService) validateTransaction(transaction *models.Transaction) error {
 	transaction.Description = strings.TrimSpace(transaction.Description)
 
-	if transaction.Amount <= 0 {
+	if transaction.Amount.Amount <= 0 {
 		return fmt.Errorf("le montant doit être supérieur à zéro")
 	}
 	if transaction.Description == "" {
@@ -79,6 +325,63 @@ func (s *FinanceService) validateTransaction(transaction *models.Transaction) er
 	if transaction.Date.IsZero() {
 		return fmt.Errorf("la date est requise")
 	}
+	if transaction.Type != models.TypeIncome && transaction.Type != models.TypeExpense {
+		return fmt.Errorf("type de transaction invalide")
+	}
+	if transaction.AccountCode != "" {
+		accounts, err := s.GetCategoryAccounts(transaction.UserID, transaction.Type)
+		if err != nil {
+			return fmt.Errorf("erreur lors de la récupération du plan comptable: %w", err)
+		}
+		found := false
+		for _, account := range accounts {
+			if account.Code == transaction.AccountCode {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("la catégorie %q ne correspond pas au type de transaction", transaction.AccountCode)
+		}
+	}
+
+	return s.applyExchangeRate(transaction)
+}
+
+// applyExchangeRate computes the base-currency amount of a transaction. Transactions in the
+// base currency use a rate of 1; transactions in another currency require the rate to be given.
+func (s *FinanceService) applyExchangeRate(transaction *models.Transaction) error {
+	baseCurrency, err := s.settingsService.GetBaseCurrency(transaction.UserID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération de la devise de référence: %w", err)
+	}
+	if transaction.Amount.Currency == "" {
+		transaction.Amount.Currency = baseCurrency
+	}
+	if _, ok := models.CurrencyDecimals(transaction.Amount.Currency); !ok {
+		return fmt.Errorf("devise non supportée: %q", transaction.Amount.Currency)
+	}
 
+	if transaction.Amount.Currency == baseCurrency {
+		transaction.ExchangeRate = "1"
+		transaction.BaseAmount = transaction.Amount.Amount
+		return nil
+	}
+	if strings.TrimSpace(transaction.ExchangeRate) == "" {
+		return fmt.Errorf("le taux de change vers %s est requis pour une transaction en %s", baseCurrency, transaction.Amount.Currency)
+	}
+	normalized, rate, err := ParseExchangeRate(transaction.ExchangeRate)
+	if err != nil {
+		return err
+	}
+	baseAmount, err := ConvertMoney(transaction.Amount, rate, baseCurrency)
+	if err != nil {
+		return err
+	}
+	if baseAmount <= 0 {
+		return fmt.Errorf("le montant converti en %s est nul", baseCurrency)
+	}
+	transaction.ExchangeRate = normalized
+	transaction.BaseAmount = baseAmount
 	return nil
 }
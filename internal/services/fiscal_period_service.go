@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// FiscalPeriodService encapsulates the business logic for the closing of fiscal years. Once the
+// accounts of a fiscal year are approved, the year is closed and the transactions and journal
+// entries dated within it become immutable; only the owner of the association can reopen it, and
+// every closing and reopening is logged.
+type FiscalPeriodService struct {
+	periodRepo      repositories.FiscalPeriodRepository
+	transactionRepo repositories.TransactionRepository
+	settingsService *SettingsService
+}
+
+// NewFiscalPeriodService creates a new instance of FiscalPeriodService.
+// It takes a FiscalPeriodRepository, a TransactionRepository and a SettingsService as dependencies,
+// adhering to the dependency inversion principle.
+func NewFiscalPeriodService(periodRepo repositories.FiscalPeriodRepository, transactionRepo repositories.TransactionRepository, settingsService *SettingsService) *FiscalPeriodService {
+	return &FiscalPeriodService{periodRepo: periodRepo, transactionRepo: transactionRepo, settingsService: settingsService}
+}
+
+// EnsureOpen returns an error if the date falls within a closed fiscal year of the association.
+func (s *FiscalPeriodService) EnsureOpen(userID uint, date time.Time) error {
+	closure, err := s.periodRepo.FindClosureCovering(userID, date)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la vérification de la clôture: %w", err)
+	}
+	if closure != nil {
+		return fmt.Errorf("l'exercice %s est clôturé : les opérations du %s ne peuvent plus être créées, modifiées ou supprimées, passez une écriture de régularisation sur un exercice ouvert", closure.Label, date.Format("02/01/2006"))
+	}
+	return nil
+}
+
+// IsClosed reports whether the date falls within a closed fiscal year of the association.
+func (s *FiscalPeriodService) IsClosed(userID uint, date time.Time) (bool, error) {
+	closure, err := s.periodRepo.FindClosureCovering(userID, date)
+	if err != nil {
+		return false, fmt.Errorf("erreur lors de la vérification de la clôture: %w", err)
+	}
+	return closure != nil, nil
+}
+
+// GetClosedTransactions returns the IDs of the transactions dated within a closed fiscal year,
+// among the given ones.
+func (s *FiscalPeriodService) GetClosedTransactions(userID uint, transactions []models.Transaction) (map[uint]bool, error) {
+	closures, err := s.periodRepo.FindClosuresByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	closed := map[uint]bool{}
+	for _, transaction := range transactions {
+		for i := range closures {
+			if closures[i].Covers(transaction.Date) {
+				closed[transaction.ID] = true
+				break
+			}
+		}
+	}
+	return closed, nil
+}
+
+// GetFiscalYears returns the fiscal years of an association, most recent first: the current one,
+// those with transactions and those closed.
+func (s *FiscalPeriodService) GetFiscalYears(userID uint) ([]models.FiscalYearStatus, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := s.periodRepo.FindClosuresByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	transactions, err := s.transactionRepo.FindTransactionsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	years := map[int]bool{settings.FiscalYear(time.Now()): true}
+	for _, transaction := range transactions {
+		years[settings.FiscalYear(transaction.Date)] = true
+	}
+	closedYears := map[int]*models.FiscalPeriodClosure{}
+	for i := range closures {
+		years[closures[i].FiscalYear] = true
+		closedYears[closures[i].FiscalYear] = &closures[i]
+	}
+
+	var statuses []models.FiscalYearStatus
+	for year := range years {
+		start, end := settings.FiscalYearBounds(year)
+		status := models.FiscalYearStatus{
+			Year:      year,
+			Label:     settings.FiscalYearLabel(year),
+			StartDate: start,
+			LastDay:   end.AddDate(0, 0, -1),
+			Ended:     !time.Now().Before(end),
+			Closure:   closedYears[year],
+		}
+		if status.Closure != nil {
+			status.Label = status.Closure.Label
+			status.StartDate = status.Closure.StartDate
+			status.LastDay = status.Closure.EndDate.AddDate(0, 0, -1)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Year > statuses[j].Year })
+	return statuses, nil
+}
+
+// GetEvents returns the closing log of an association, most recent first.
+func (s *FiscalPeriodService) GetEvents(userID uint) ([]models.FiscalPeriodEvent, error) {
+	return s.periodRepo.FindEventsByUserID(userID)
+}
+
+// CloseFiscalYear closes a fiscal year of the association. The year must be over, no transaction
+// dated within it may still be awaiting reconciliation, approval or collection, and only the owner of the
+// association may close it.
+func (s *FiscalPeriodService) CloseFiscalYear(actor models.User, ownerID uint, year int, comment string) error {
+	if actor.ID != ownerID {
+		return fmt.Errorf("seul le propriétaire de l'association peut clôturer un exercice")
+	}
+	settings, err := s.settingsService.GetSettings(ownerID)
+	if err != nil {
+		return err
+	}
+	start, end := settings.FiscalYearBounds(year)
+	label := settings.FiscalYearLabel(year)
+	if time.Now().Before(end) {
+		return fmt.Errorf("l'exercice %s n'est pas terminé", label)
+	}
+	if closure, err := s.periodRepo.FindClosureCovering(ownerID, start); err != nil {
+		return err
+	} else if closure != nil {
+		return fmt.Errorf("l'exercice %s est déjà clôturé", closure.Label)
+	}
+	pending, err := s.transactionRepo.CountPendingTransactionsByPeriod(ownerID, start, end)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d transaction(s) de l'exercice %s sont encore à rapprocher, à approuver ou à encaisser", pending, label)
+	}
+
+	now := time.Now()
+	closure := &models.FiscalPeriodClosure{
+		UserID:       ownerID,
+		FiscalYear:   year,
+		Label:        label,
+		StartDate:    start,
+		EndDate:      end,
+		ClosedAt:     now,
+		ClosedByName: actor.Name,
+	}
+	return s.periodRepo.CloseFiscalYear(closure, s.event(actor, ownerID, year, label, models.PeriodClosed, comment, now))
+}
+
+// ReopenFiscalYear reopens a closed fiscal year so that its transactions can be corrected. Only the
+// owner of the association may reopen a year, and a reason is required for the log.
+func (s *FiscalPeriodService) ReopenFiscalYear(actor models.User, ownerID uint, year int, reason string) error {
+	if actor.ID != ownerID {
+		return fmt.Errorf("seul le propriétaire de l'association peut rouvrir un exercice")
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("le motif de la réouverture est requis")
+	}
+	closures, err := s.periodRepo.FindClosuresByUserID(ownerID)
+	if err != nil {
+		return err
+	}
+	for _, closure := range closures {
+		if closure.FiscalYear == year {
+			return s.periodRepo.ReopenFiscalYear(closure.ID, s.event(actor, ownerID, year, closure.Label, models.PeriodReopened, reason, time.Now()))
+		}
+	}
+	return fmt.Errorf("cet exercice n'est pas clôturé")
+}
+
+// event builds an entry of the closing log.
+func (s *FiscalPeriodService) event(actor models.User, ownerID uint, year int, label string, action models.FiscalPeriodAction, reason string, at time.Time) *models.FiscalPeriodEvent {
+	return &models.FiscalPeriodEvent{
+		UserID:     ownerID,
+		FiscalYear: year,
+		Label:      label,
+		Action:     action,
+		ActorID:    actor.ID,
+		ActorName:  actor.Name,
+		Reason:     strings.TrimSpace(reason),
+		At:         at,
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/pdf"
+)
+
+// Layout of the generated documents, in points.
+const (
+	pdfMarginLeft   = 50.0
+	pdfMarginRight  = pdf.PageWidth - 50.0
+	pdfMarginTop    = pdf.PageHeight - 50.0
+	pdfMarginBottom = 60.0
+)
+
+// pdfWriter lays out text from top to bottom, adding pages as needed.
+type pdfWriter struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+// newPage starts a new page and moves the cursor to its top.
+func (w *pdfWriter) newPage() {
+	w.page = w.doc.AddPage()
+	w.y = pdfMarginTop
+}
+
+// ensure starts a new page if less than the given height is left on the current one.
+func (w *pdfWriter) ensure(height float64) {
+	if w.y-height < pdfMarginBottom {
+		w.newPage()
+	}
+}
+
+// paragraph writes a text at the given indentation, wrapped to the page width.
+func (w *pdfWriter) paragraph(indent, size float64, bold bool, text string) {
+	for _, line := range pdf.WrapText(text, size, pdfMarginRight-pdfMarginLeft-indent, bold) {
+		w.ensure(size * 1.4)
+		w.y -= size * 1.4
+		w.page.Text(pdfMarginLeft+indent, w.y, size, bold, line)
+	}
+}
+
+// field writes a bold label followed by its value, wrapped after the label.
+func (w *pdfWriter) field(label, value string) {
+	const size = 10.0
+	if value == "" {
+		value = "-"
+	}
+	labelWidth := pdf.TextWidth(label+" ", size, true)
+	lines := pdf.WrapText(value, size, pdfMarginRight-pdfMarginLeft-labelWidth, false)
+	w.ensure(size * 1.4)
+	w.y -= size * 1.4
+	w.page.Text(pdfMarginLeft, w.y, size, true, label)
+	for i, line := range lines {
+		if i > 0 {
+			w.ensure(size * 1.4)
+			w.y -= size * 1.4
+		}
+		w.page.Text(pdfMarginLeft+labelWidth, w.y, size, false, line)
+	}
+}
+
+// space moves the cursor down.
+func (w *pdfWriter) space(height float64) {
+	w.y -= height
+}
+
+// rule draws a horizontal line across the page at the cursor.
+func (w *pdfWriter) rule() {
+	w.space(4)
+	w.page.Line(pdfMarginLeft, w.y, pdfMarginRight, w.y)
+	w.space(4)
+}
+
+// renderInvoices renders invoices and donation receipts in a single PDF document.
+func renderInvoices(title string, invoices []models.Invoice, settings *models.AssociationSettings) []byte {
+	w := &pdfWriter{doc: pdf.New(title)}
+	for i := range invoices {
+		w.newPage()
+		if invoices[i].Kind == models.KindDonationReceipt {
+			renderDonationReceipt(w, &invoices[i], settings)
+		} else {
+			renderInvoice(w, &invoices[i], settings)
+		}
+	}
+	return w.doc.Bytes()
+}
+
+// renderIssuer writes the identity of the association at the top left of the page.
+func renderIssuer(w *pdfWriter, settings *models.AssociationSettings) {
+	w.paragraph(0, 14, true, settings.LegalName)
+	w.paragraph(0, 9, false, settings.Address)
+	if settings.RegistrationNumber != "" {
+		w.paragraph(0, 9, false, "RNA / SIREN : "+settings.RegistrationNumber)
+	}
+}
+
+// renderCancelled marks a cancelled document.
+func renderCancelled(w *pdfWriter, invoice *models.Invoice) {
+	if invoice.Status != models.InvoiceCancelled {
+		return
+	}
+	label := "DOCUMENT ANNULÉ"
+	if invoice.CancelledAt != nil {
+		label += " LE " + invoice.CancelledAt.Format("02/01/2006")
+	}
+	w.space(8)
+	w.paragraph(0, 16, true, label)
+}
+
+// renderInvoice lays out an invoice: issuer, customer, covered transactions and total.
+func renderInvoice(w *pdfWriter, invoice *models.Invoice, settings *models.AssociationSettings) {
+	top := w.y
+	w.page.TextRight(pdfMarginRight, top-20, 20, true, "FACTURE")
+	w.page.TextRight(pdfMarginRight, top-38, 10, false, "N° "+invoice.Number)
+	w.page.TextRight(pdfMarginRight, top-52, 10, false, "Date : "+invoice.IssueDate.Format("02/01/2006"))
+	renderIssuer(w, settings)
+	w.y = min(w.y, top-60)
+
+	w.space(20)
+	w.paragraph(280, 10, true, "Facturé à :")
+	w.paragraph(280, 10, false, invoice.RecipientName)
+	w.paragraph(280, 10, false, invoice.RecipientAddress)
+	renderCancelled(w, invoice)
+
+	if invoice.Description != "" {
+		w.space(16)
+		w.paragraph(0, 10, false, invoice.Description)
+	}
+
+	w.space(20)
+	w.ensure(30)
+	w.space(12)
+	w.page.Text(pdfMarginLeft, w.y, 10, true, "Date")
+	w.page.Text(pdfMarginLeft+80, w.y, 10, true, "Désignation")
+	w.page.TextRight(pdfMarginRight, w.y, 10, true, "Montant ("+invoice.Amount.Currency+")")
+	w.rule()
+	for _, line := range invoice.Lines {
+		w.ensure(14)
+		w.space(10)
+		w.page.Text(pdfMarginLeft, w.y, 10, false, line.Date.Format("02/01/2006"))
+		w.page.Text(pdfMarginLeft+80, w.y, 10, false, truncate(line.Description, 330, 10))
+		w.page.TextRight(pdfMarginRight, w.y, 10, false, FormatCents(line.Amount))
+		w.space(4)
+	}
+	w.rule()
+	w.space(10)
+	w.page.Text(pdfMarginLeft+80, w.y, 11, true, "Total")
+	w.page.TextRight(pdfMarginRight, w.y, 11, true, invoice.Amount.String())
+
+	w.space(30)
+	w.paragraph(0, 9, false, "TVA non applicable, article 293 B du code général des impôts.")
+	if len(invoice.Lines) > 0 {
+		w.paragraph(0, 9, false, "Facture acquittée le "+invoice.Lines[len(invoice.Lines)-1].Date.Format("02/01/2006")+" - mode de paiement : "+invoice.PaymentMethod+".")
+	}
+}
+
+// renderDonationReceipt lays out a tax receipt for donations following the content of the
+// Cerfa 11580 form: beneficiary, donor, amount in figures and words, certification and signature.
+func renderDonationReceipt(w *pdfWriter, receipt *models.Invoice, settings *models.AssociationSettings) {
+	w.page.TextRight(pdfMarginRight, w.y-10, 9, false, "Cerfa n° 11580")
+	w.paragraph(0, 13, true, "Reçu au titre des dons à certains organismes d'intérêt général")
+	w.paragraph(0, 9, false, "Articles 200, 238 bis et 978 du code général des impôts")
+	w.space(6)
+	w.paragraph(0, 11, true, "Numéro d'ordre du reçu : "+receipt.Number)
+	renderCancelled(w, receipt)
+
+	w.space(12)
+	w.paragraph(0, 11, true, "Bénéficiaire des versements")
+	w.rule()
+	w.field("Nom ou dénomination :", settings.LegalName)
+	w.field("Adresse :", settings.Address)
+	w.field("N° RNA / SIREN :", settings.RegistrationNumber)
+	w.field("Objet :", settings.Purpose)
+	w.field("Qualité de l'organisme :", settings.DonationEligibility)
+
+	w.space(12)
+	w.paragraph(0, 11, true, "Donateur")
+	w.rule()
+	w.field("Nom, prénom :", receipt.RecipientName)
+	w.field("Adresse :", receipt.RecipientAddress)
+
+	w.space(12)
+	w.paragraph(0, 10, false, "Le bénéficiaire reconnaît avoir reçu au titre des dons et versements ouvrant droit à réduction d'impôt, la somme de :")
+	w.paragraph(0, 13, true, receipt.Amount.String())
+	w.field("Somme en toutes lettres :", SpellAmount(receipt.Amount))
+
+	if len(receipt.Lines) == 1 {
+		w.field("Date du versement ou du don :", receipt.Lines[0].Date.Format("02/01/2006"))
+	} else if len(receipt.Lines) > 1 {
+		first, last := receipt.Lines[0].Date, receipt.Lines[len(receipt.Lines)-1].Date
+		w.field("Dates des versements :", "du "+first.Format("02/01/2006")+" au "+last.Format("02/01/2006")+", détaillés ci-dessous")
+		for _, line := range receipt.Lines {
+			w.ensure(14)
+			w.space(13)
+			w.page.Text(pdfMarginLeft+20, w.y, 9, false, line.Date.Format("02/01/2006"))
+			w.page.Text(pdfMarginLeft+90, w.y, 9, false, truncate(line.Description, 280, 9))
+			w.page.TextRight(pdfMarginRight, w.y, 9, false, FormatCents(line.Amount))
+		}
+	}
+
+	w.space(12)
+	w.paragraph(0, 10, false, "Le bénéficiaire certifie sur l'honneur que les dons et versements qu'il reçoit ouvrent droit à la réduction d'impôt prévue aux articles 200 et 238 bis du code général des impôts.")
+	w.space(6)
+	w.field("Forme du don :", "Déclaration de don manuel")
+	w.field("Nature du don :", "Numéraire")
+	w.field("Mode de versement :", receipt.PaymentMethod)
+
+	w.space(20)
+	w.paragraph(280, 10, false, "Fait le "+receipt.IssueDate.Format("02/01/2006"))
+	w.paragraph(280, 10, false, "Signature : "+settings.SignatoryName)
+}
+
+// truncate shortens a text to fit within the given width, adding an ellipsis when needed.
+func truncate(text string, width, size float64) string {
+	if pdf.TextWidth(text, size, false) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.TextWidth(string(runes)+"…", size, false) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimSpace(string(runes)) + "…"
+}
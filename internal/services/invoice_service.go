@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// InvoiceService encapsulates the business logic for issuing invoices and donation receipts.
+// Numbers are assigned by the repository at issue time, sequentially per fiscal year; issued
+// documents are never deleted, only cancelled, so that the numbering has no gap.
+type InvoiceService struct {
+	invoiceRepo     repositories.InvoiceRepository
+	transactionRepo repositories.TransactionRepository
+	memberRepo      repositories.MemberRepository
+	settingsService *SettingsService
+	periodService   *FiscalPeriodService
+}
+
+// NewInvoiceService creates a new instance of InvoiceService.
+// It takes an InvoiceRepository, a TransactionRepository, a MemberRepository, a SettingsService
+// and a FiscalPeriodService as dependencies, adhering to the dependency inversion principle.
+func NewInvoiceService(invoiceRepo repositories.InvoiceRepository, transactionRepo repositories.TransactionRepository, memberRepo repositories.MemberRepository, settingsService *SettingsService, periodService *FiscalPeriodService) *InvoiceService {
+	return &InvoiceService{invoiceRepo: invoiceRepo, transactionRepo: transactionRepo, memberRepo: memberRepo, settingsService: settingsService, periodService: periodService}
+}
+
+// IssueInvoice issues an invoice or a donation receipt for a posted income transaction, addressed
+// to a member. The transaction is linked to the member if it was not yet, unless it belongs to a
+// closed fiscal year and can no longer be modified.
+func (s *InvoiceService) IssueInvoice(userID uint, kind models.InvoiceKind, transactionID, memberID uint, paymentMethod, description string) (*models.Invoice, error) {
+	if kind != models.KindInvoice && kind != models.KindDonationReceipt {
+		return nil, fmt.Errorf("type de document invalide")
+	}
+	paymentMethod, err := validatePaymentMethod(paymentMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := s.transactionRepo.FindTransactionByID(transactionID)
+	if err != nil || transaction.UserID != userID {
+		return nil, fmt.Errorf("transaction non trouvée")
+	}
+	if transaction.Type != models.TypeIncome || transaction.Status != models.TransactionPosted {
+		return nil, fmt.Errorf("seule une recette validée peut faire l'objet d'une facture ou d'un reçu")
+	}
+	if kind == models.KindDonationReceipt && transaction.AccountCode != models.DonationAccountCode {
+		return nil, fmt.Errorf("un reçu fiscal ne peut être émis que pour un don (compte %s)", models.DonationAccountCode)
+	}
+	if transaction.MemberID != nil && *transaction.MemberID != memberID {
+		return nil, fmt.Errorf("la transaction est rattachée à un autre membre")
+	}
+	member, err := s.ownedMember(userID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	issueDate := time.Now()
+	invoice := &models.Invoice{
+		UserID:           userID,
+		Kind:             kind,
+		FiscalYear:       settings.FiscalYear(issueDate),
+		Status:           models.InvoiceIssued,
+		IssueDate:        issueDate,
+		MemberID:         member.ID,
+		RecipientName:    memberName(member),
+		RecipientAddress: member.Address,
+		Description:      strings.TrimSpace(description),
+		Amount:           models.Money{Amount: transaction.BaseAmount, Currency: settings.BaseCurrency},
+		PaymentMethod:    paymentMethod,
+		Lines:            []models.InvoiceLine{invoiceLine(transaction)},
+	}
+	if err := s.invoiceRepo.CreateInvoice(invoice); err != nil {
+		return nil, err
+	}
+
+	if transaction.MemberID == nil && s.periodService.EnsureOpen(transaction.UserID, transaction.Date) == nil {
+		transaction.MemberID = &member.ID
+		if err := s.transactionRepo.UpdateTransaction(transaction); err != nil {
+			return invoice, fmt.Errorf("document émis, mais la transaction n'a pas pu être rattachée au membre: %w", err)
+		}
+	}
+	return invoice, nil
+}
+
+// IssueAnnualDonationReceipts issues one donation receipt per donor for the donations of a fiscal
+// year that are not yet covered by a receipt. Donations must be posted, booked on the donation
+// account and linked to a member; the number of donations skipped for lack of a member is returned.
+func (s *InvoiceService) IssueAnnualDonationReceipts(userID uint, fiscalYear int) ([]models.Invoice, int, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	from, to := settings.FiscalYearBounds(fiscalYear)
+	donations, err := s.transactionRepo.FindPostedTransactionsByAccount(userID, models.DonationAccountCode, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	invoiced, err := s.invoiceRepo.FindInvoicedTransactions(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byMember := map[uint][]models.Transaction{}
+	skipped := 0
+	for _, donation := range donations {
+		if _, ok := invoiced[donation.ID]; ok {
+			continue
+		}
+		if donation.MemberID == nil {
+			skipped++
+			continue
+		}
+		byMember[*donation.MemberID] = append(byMember[*donation.MemberID], donation)
+	}
+
+	// Number the receipts in alphabetical order of the donors.
+	var donors []*models.Member
+	for memberID := range byMember {
+		member, err := s.ownedMember(userID, memberID)
+		if err != nil {
+			return nil, skipped, err
+		}
+		donors = append(donors, member)
+	}
+	sort.Slice(donors, func(i, j int) bool {
+		return strings.ToLower(memberName(donors[i])) < strings.ToLower(memberName(donors[j]))
+	})
+
+	issueDate := time.Now()
+	var receipts []models.Invoice
+	for _, donor := range donors {
+		receipt := models.Invoice{
+			UserID:           userID,
+			Kind:             models.KindDonationReceipt,
+			FiscalYear:       settings.FiscalYear(issueDate),
+			Status:           models.InvoiceIssued,
+			IssueDate:        issueDate,
+			MemberID:         donor.ID,
+			RecipientName:    memberName(donor),
+			RecipientAddress: donor.Address,
+			Description:      "Dons de l'exercice " + settings.FiscalYearLabel(fiscalYear),
+			Amount:           models.Money{Currency: settings.BaseCurrency},
+			PaymentMethod:    models.PaymentMethods[0],
+		}
+		for _, donation := range byMember[donor.ID] {
+			receipt.Lines = append(receipt.Lines, invoiceLine(&donation))
+			receipt.Amount.Amount += donation.BaseAmount
+		}
+		if err := s.invoiceRepo.CreateInvoice(&receipt); err != nil {
+			return receipts, skipped, fmt.Errorf("reçu de %s: %w", receipt.RecipientName, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, skipped, nil
+}
+
+// GetInvoice retrieves an invoice of a user by its ID.
+func (s *InvoiceService) GetInvoice(userID, id uint) (*models.Invoice, error) {
+	invoice, err := s.invoiceRepo.FindInvoiceByID(id)
+	if err != nil || invoice.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return invoice, nil
+}
+
+// GetInvoices retrieves the invoices of a user, optionally filtered by kind and fiscal year.
+func (s *InvoiceService) GetInvoices(userID uint, kind models.InvoiceKind, fiscalYear int) ([]models.Invoice, error) {
+	return s.invoiceRepo.FindInvoicesByUserID(userID, kind, fiscalYear)
+}
+
+// GetInvoicedTransactions returns the number of the document covering each invoiced transaction of a user.
+func (s *InvoiceService) GetInvoicedTransactions(userID uint) (map[uint]string, error) {
+	return s.invoiceRepo.FindInvoicedTransactions(userID)
+}
+
+// CancelInvoice cancels an issued invoice. Its number stays used and the covered transactions
+// can be invoiced again.
+func (s *InvoiceService) CancelInvoice(userID, id uint) error {
+	invoice, err := s.GetInvoice(userID, id)
+	if err != nil {
+		return err
+	}
+	if invoice.Status == models.InvoiceCancelled {
+		return fmt.Errorf("le document %s est déjà annulé", invoice.Number)
+	}
+	return s.invoiceRepo.CancelInvoice(invoice.ID, time.Now())
+}
+
+// RenderPDF renders invoices and donation receipts of a user in a single PDF document,
+// each one starting on a new page.
+func (s *InvoiceService) RenderPDF(userID uint, invoices []models.Invoice) ([]byte, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+	title := "Factures et reçus"
+	if len(invoices) == 1 {
+		title = string(invoices[0].Kind) + " " + invoices[0].Number
+	}
+	return renderInvoices(title, invoices, settings), nil
+}
+
+// CurrentFiscalYear returns the fiscal year of a user's association that includes today.
+func (s *InvoiceService) CurrentFiscalYear(userID uint) (int, error) {
+	settings, err := s.settingsService.GetSettings(userID)
+	if err != nil {
+		return 0, err
+	}
+	return settings.FiscalYear(time.Now()), nil
+}
+
+// ownedMember retrieves a member and ensures it belongs to the user.
+func (s *InvoiceService) ownedMember(userID, memberID uint) (*models.Member, error) {
+	member, err := s.memberRepo.FindMemberByID(memberID)
+	if err != nil || member.UserID != userID {
+		return nil, fmt.Errorf("membre non trouvé")
+	}
+	return member, nil
+}
+
+// validatePaymentMethod checks that a payment method is one of those printed on receipts,
+// defaulting to the first one.
+func validatePaymentMethod(paymentMethod string) (string, error) {
+	if paymentMethod == "" {
+		return models.PaymentMethods[0], nil
+	}
+	for _, method := range models.PaymentMethods {
+		if method == paymentMethod {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("mode de paiement invalide: %q", paymentMethod)
+}
+
+// invoiceLine builds the invoice line covering a transaction.
+func invoiceLine(transaction *models.Transaction) models.InvoiceLine {
+	return models.InvoiceLine{
+		TransactionID: transaction.ID,
+		Date:          transaction.Date,
+		Description:   transaction.Description,
+		Amount:        transaction.BaseAmount,
+	}
+}
+
+// memberName returns the full name of a member as printed on invoices.
+func memberName(member *models.Member) string {
+	return strings.TrimSpace(member.FirstName + " " + member.LastName)
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// defaultChartOfAccounts is the simplified chart of accounts of the French associations
+// accounting standard, created for each association on first use.
+var defaultChartOfAccounts = []models.Account{
+	{Code: "102", Name: "Fonds associatif", Type: models.AccountEquity},
+	{Code: "110", Name: "Report à nouveau", Type: models.AccountEquity},
+	{Code: "401", Name: "Fournisseurs", Type: models.AccountLiability},
+	{Code: "411", Name: "Adhérents et usagers", Type: models.AccountAsset},
+	{Code: "467", Name: "Autres comptes débiteurs ou créditeurs", Type: models.AccountLiability},
+	{Code: models.DefaultBankAccountCode, Name: "Banque", Type: models.AccountAsset},
+	{Code: "530", Name: "Caisse", Type: models.AccountAsset},
+	{Code: "604", Name: "Achats de prestations de services", Type: models.AccountExpense},
+	{Code: "606", Name: "Achats non stockés de matières et fournitures", Type: models.AccountExpense},
+	{Code: "613", Name: "Locations", Type: models.AccountExpense},
+	{Code: "616", Name: "Primes d'assurance", Type: models.AccountExpense},
+	{Code: "618", Name: "Documentation et abonnements", Type: models.AccountExpense},
+	{Code: "623", Name: "Publicité, publications", Type: models.AccountExpense},
+	{Code: "625", Name: "Déplacements, missions et réceptions", Type: models.AccountExpense},
+	{Code: "626", Name: "Frais postaux et de télécommunications", Type: models.AccountExpense},
+	{Code: "627", Name: "Services bancaires", Type: models.AccountExpense},
+	{Code: "641", Name: "Rémunérations du personnel", Type: models.AccountExpense},
+	{Code: "658", Name: "Charges diverses de gestion courante", Type: models.AccountExpense},
+	{Code: "706", Name: "Prestations de services", Type: models.AccountIncome},
+	{Code: "707", Name: "Ventes de marchandises", Type: models.AccountIncome},
+	{Code: "740", Name: "Subventions d'exploitation", Type: models.AccountIncome},
+	{Code: "754", Name: "Dons manuels", Type: models.AccountIncome},
+	{Code: "756", Name: "Cotisations", Type: models.AccountIncome},
+	{Code: "758", Name: "Produits divers de gestion courante", Type: models.AccountIncome},
+}
+
+// Default accounts used when a simple transaction does not specify its category.
+const (
+	defaultIncomeAccountCode  = "758"
+	defaultExpenseAccountCode = "658"
+)
+
+// LedgerService encapsulates the double-entry bookkeeping logic: the chart of accounts,
+// the journal and the accounting reports derived from it. Manual entries cannot be dated
+// within a closed fiscal year, which the FiscalPeriodService checks.
+type LedgerService struct {
+	ledgerRepo    repositories.LedgerRepository
+	periodService *FiscalPeriodService
+}
+
+// NewLedgerService creates a new instance of LedgerService.
+// It takes a LedgerRepository and a FiscalPeriodService as dependencies, adhering to the
+// dependency inversion principle.
+func NewLedgerService(ledgerRepo repositories.LedgerRepository, periodService *FiscalPeriodService) *LedgerService {
+	return &LedgerService{ledgerRepo: ledgerRepo, periodService: periodService}
+}
+
+// GetAccounts returns the chart of accounts of a user, creating the default one on first use.
+func (s *LedgerService) GetAccounts(userID uint) ([]models.Account, error) {
+	accounts, err := s.ledgerRepo.FindAccountsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) > 0 {
+		return accounts, nil
+	}
+	for _, account := range defaultChartOfAccounts {
+		account.UserID = userID
+		if err := s.ledgerRepo.CreateAccount(&account); err != nil {
+			return nil, fmt.Errorf("erreur lors de la création du plan comptable: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// GetAccountsByType returns the accounts of a user that belong to the given class.
+func (s *LedgerService) GetAccountsByType(userID uint, accountType models.AccountType) ([]models.Account, error) {
+	accounts, err := s.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []models.Account
+	for _, account := range accounts {
+		if account.Type == accountType {
+			filtered = append(filtered, account)
+		}
+	}
+	return filtered, nil
+}
+
+// CreateAccount adds an account to the chart of accounts of a user after validating it.
+func (s *LedgerService) CreateAccount(account *models.Account) error {
+	account.Code = strings.TrimSpace(account.Code)
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Code == "" {
+		return fmt.Errorf("le numéro de compte est requis")
+	}
+	if account.Name == "" {
+		return fmt.Errorf("le libellé du compte est requis")
+	}
+	switch account.Type {
+	case models.AccountAsset, models.AccountLiability, models.AccountEquity, models.AccountIncome, models.AccountExpense:
+	default:
+		return fmt.Errorf("type de compte invalide")
+	}
+
+	accounts, err := s.GetAccounts(account.UserID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range accounts {
+		if existing.Code == account.Code {
+			return fmt.Errorf("le compte %s existe déjà", account.Code)
+		}
+	}
+	return s.ledgerRepo.CreateAccount(account)
+}
+
+// CreateJournalEntry records a manual journal entry after checking that it is balanced,
+// only uses accounts of the user's chart of accounts and is dated in an open fiscal year.
+func (s *LedgerService) CreateJournalEntry(entry *models.JournalEntry) error {
+	if err := s.validateJournalEntry(entry); err != nil {
+		return err
+	}
+	if err := s.periodService.EnsureOpen(entry.UserID, entry.Date); err != nil {
+		return err
+	}
+	return s.ledgerRepo.CreateJournalEntry(entry)
+}
+
+// RecordTransaction generates the journal entry of a posted simple transaction:
+// an income debits the bank and credits the income account, an expense debits the
+// expense account and credits the bank. Any previous entry of the transaction is replaced.
+func (s *LedgerService) RecordTransaction(transaction *models.Transaction) error {
+	if err := s.ledgerRepo.DeleteJournalEntriesByTransactionID(transaction.ID); err != nil {
+		return fmt.Errorf("erreur lors de la suppression de l'écriture précédente: %w", err)
+	}
+	if transaction.Status != models.TransactionPosted {
+		return nil
+	}
+
+	// The journal is kept in the base currency of the association.
+	amount := transaction.BaseAmount
+	accountCode := transaction.AccountCode
+	var lines []models.JournalLine
+	if transaction.Type == models.TypeIncome {
+		if accountCode == "" {
+			accountCode = defaultIncomeAccountCode
+		}
+		lines = []models.JournalLine{
+			{AccountCode: models.DefaultBankAccountCode, Debit: amount},
+			{AccountCode: accountCode, Credit: amount},
+		}
+	} else {
+		if accountCode == "" {
+			accountCode = defaultExpenseAccountCode
+		}
+		lines = []models.JournalLine{
+			{AccountCode: accountCode, Debit: amount},
+			{AccountCode: models.DefaultBankAccountCode, Credit: amount},
+		}
+	}
+
+	transactionID := transaction.ID
+	entry := &models.JournalEntry{
+		Date:          transaction.Date,
+		Description:   transaction.Description,
+		UserID:        transaction.UserID,
+		TransactionID: &transactionID,
+		Lines:         lines,
+	}
+	return s.CreateJournalEntry(entry)
+}
+
+// RemoveTransaction deletes the journal entries generated from a transaction.
+func (s *LedgerService) RemoveTransaction(transactionID uint) error {
+	return s.ledgerRepo.DeleteJournalEntriesByTransactionID(transactionID)
+}
+
+// UnjournalizedTransactionIDs returns the IDs of posted transactions that have no journal entry yet.
+func (s *LedgerService) UnjournalizedTransactionIDs() ([]uint, error) {
+	return s.ledgerRepo.FindTransactionIDsWithoutEntry()
+}
+
+// GetJournal returns the journal entries of a user within [from, to] in chronological order.
+func (s *LedgerService) GetJournal(userID uint, from, to time.Time) ([]models.JournalEntry, error) {
+	return s.ledgerRepo.FindJournalEntries(userID, from, to)
+}
+
+// GetGeneralLedger returns, for each account used within [from, to], its lines with running balance.
+func (s *LedgerService) GetGeneralLedger(userID uint, from, to time.Time) ([]models.LedgerAccount, error) {
+	accounts, err := s.accountsByCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.ledgerRepo.FindJournalEntries(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ledger := map[string]*models.LedgerAccount{}
+	for _, entry := range entries {
+		for _, line := range entry.Lines {
+			account, ok := ledger[line.AccountCode]
+			if !ok {
+				account = &models.LedgerAccount{Account: accounts.lookup(line.AccountCode)}
+				ledger[line.AccountCode] = account
+			}
+			account.TotalDebit += line.Debit
+			account.TotalCredit += line.Credit
+			account.Balance += line.Debit - line.Credit
+			description := entry.Description
+			if line.Label != "" {
+				description = line.Label
+			}
+			account.Lines = append(account.Lines, models.LedgerLine{
+				Date:        entry.Date,
+				Description: description,
+				Reference:   entry.Reference,
+				Debit:       line.Debit,
+				Credit:      line.Credit,
+				Balance:     account.Balance,
+			})
+		}
+	}
+
+	result := make([]models.LedgerAccount, 0, len(ledger))
+	for _, account := range ledger {
+		result = append(result, *account)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Account.Code < result[j].Account.Code })
+	return result, nil
+}
+
+// GetTrialBalance returns the debit and credit totals of every account used within [from, to].
+func (s *LedgerService) GetTrialBalance(userID uint, from, to time.Time) (*models.TrialBalance, error) {
+	accounts, err := s.accountsByCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.ledgerRepo.GetAccountTotals(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &models.TrialBalance{}
+	for _, total := range totals {
+		balance.Rows = append(balance.Rows, models.TrialBalanceRow{
+			Account: accounts.lookup(total.AccountCode),
+			Debit:   total.Debit,
+			Credit:  total.Credit,
+			Balance: total.Debit - total.Credit,
+		})
+		balance.TotalDebit += total.Debit
+		balance.TotalCredit += total.Credit
+	}
+	return balance, nil
+}
+
+// GetIncomeStatement returns the revenue and expenses of a user within [from, to].
+func (s *LedgerService) GetIncomeStatement(userID uint, from, to time.Time) (*models.IncomeStatement, error) {
+	trial, err := s.GetTrialBalance(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.IncomeStatement{From: from, To: to}
+	for _, row := range trial.Rows {
+		switch row.Account.Type {
+		case models.AccountIncome:
+			statement.Income = append(statement.Income, models.StatementRow{Account: row.Account, Amount: -row.Balance})
+			statement.TotalIncome -= row.Balance
+		case models.AccountExpense:
+			statement.Expenses = append(statement.Expenses, models.StatementRow{Account: row.Account, Amount: row.Balance})
+			statement.TotalExpenses += row.Balance
+		}
+	}
+	statement.NetResult = statement.TotalIncome - statement.TotalExpenses
+	return statement, nil
+}
+
+// GetBalanceSheet returns the financial position of a user at the given date, computed from
+// every entry up to that date. The result not yet allocated to equity is shown separately.
+func (s *LedgerService) GetBalanceSheet(userID uint, at time.Time) (*models.BalanceSheet, error) {
+	trial, err := s.GetTrialBalance(userID, time.Time{}, at)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &models.BalanceSheet{Date: at}
+	for _, row := range trial.Rows {
+		switch row.Account.Type {
+		case models.AccountAsset:
+			sheet.Assets = append(sheet.Assets, models.StatementRow{Account: row.Account, Amount: row.Balance})
+			sheet.TotalAssets += row.Balance
+		case models.AccountLiability:
+			sheet.Liabilities = append(sheet.Liabilities, models.StatementRow{Account: row.Account, Amount: -row.Balance})
+			sheet.TotalLiabilities -= row.Balance
+		case models.AccountEquity:
+			sheet.Equity = append(sheet.Equity, models.StatementRow{Account: row.Account, Amount: -row.Balance})
+			sheet.TotalLiabilities -= row.Balance
+		case models.AccountIncome, models.AccountExpense:
+			sheet.NetResult -= row.Balance
+		}
+	}
+	sheet.TotalLiabilities += sheet.NetResult
+	return sheet, nil
+}
+
+// validateJournalEntry checks that an entry has a date, a label, at least two lines each
+// holding either a debit or a credit on a known account, and that debits equal credits.
+func (s *LedgerService) validateJournalEntry(entry *models.JournalEntry) error {
+	entry.Description = strings.TrimSpace(entry.Description)
+	if entry.Date.IsZero() {
+		return fmt.Errorf("la date de l'écriture est requise")
+	}
+	if entry.Description == "" {
+		return fmt.Errorf("le libellé de l'écriture est requis")
+	}
+	if len(entry.Lines) < 2 {
+		return fmt.Errorf("une écriture doit comporter au moins deux lignes")
+	}
+
+	accounts, err := s.accountsByCode(entry.UserID)
+	if err != nil {
+		return err
+	}
+
+	var totalDebit, totalCredit int64
+	for i, line := range entry.Lines {
+		if _, ok := accounts[line.AccountCode]; !ok {
+			return fmt.Errorf("ligne %d: compte %q inconnu", i+1, line.AccountCode)
+		}
+		if line.Debit < 0 || line.Credit < 0 {
+			return fmt.Errorf("ligne %d: les montants doivent être positifs", i+1)
+		}
+		if (line.Debit == 0) == (line.Credit == 0) {
+			return fmt.Errorf("ligne %d: une ligne doit porter soit un débit, soit un crédit", i+1)
+		}
+		totalDebit += line.Debit
+		totalCredit += line.Credit
+	}
+	if totalDebit != totalCredit {
+		return fmt.Errorf("écriture déséquilibrée: débit %s, crédit %s", FormatCents(totalDebit), FormatCents(totalCredit))
+	}
+	return nil
+}
+
+// chartOfAccounts indexes the accounts of a user by code.
+type chartOfAccounts map[string]models.Account
+
+// lookup returns the account with the given code, or a placeholder for codes that are no
+// longer in the chart of accounts so that reports never lose amounts.
+func (c chartOfAccounts) lookup(code string) models.Account {
+	if account, ok := c[code]; ok {
+		return account
+	}
+	return models.Account{Code: code, Name: "Compte inconnu"}
+}
+
+// accountsByCode returns the chart of accounts of a user indexed by account code.
+func (s *LedgerService) accountsByCode(userID uint) (chartOfAccounts, error) {
+	accounts, err := s.GetAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	indexed := chartOfAccounts{}
+	for _, account := range accounts {
+		indexed[account.Code] = account
+	}
+	return indexed, nil
+}
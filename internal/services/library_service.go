@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// LibraryService encapsulates the business logic of the document library of the members: which
+// documents of an association a user may see, according to their visibility and to the standing
+// of the user in the association.
+// A user is a member of an association when its email is the email of an active member record of
+// the association, and a board member when that record has a board role.
+type LibraryService struct {
+	documentService *DocumentService
+	memberRepo      repositories.MemberRepository
+	settingsService *SettingsService
+}
+
+// NewLibraryService creates a new instance of LibraryService.
+// It takes the DocumentService, a MemberRepository and the SettingsService (for the names of the
+// associations) as dependencies, adhering to the dependency inversion principle.
+func NewLibraryService(documentService *DocumentService, memberRepo repositories.MemberRepository, settingsService *SettingsService) *LibraryService {
+	return &LibraryService{documentService: documentService, memberRepo: memberRepo, settingsService: settingsService}
+}
+
+// GetMemberships returns the associations whose library a user may browse: its own association
+// and those it is an active member of.
+func (s *LibraryService) GetMemberships(user models.User) ([]models.LibraryMembership, error) {
+	memberships := []models.LibraryMembership{{AssociationID: user.ID, Owner: true}}
+	members, err := s.activeMembers(user)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[uint]bool{user.ID: true}
+	for _, member := range members {
+		if seen[member.UserID] {
+			continue
+		}
+		seen[member.UserID] = true
+		memberships = append(memberships, models.LibraryMembership{AssociationID: member.UserID, BoardRole: member.BoardRole})
+	}
+	for i := range memberships {
+		if memberships[i].Name, err = s.associationName(memberships[i].AssociationID); err != nil {
+			return nil, err
+		}
+	}
+	return memberships, nil
+}
+
+// GetMembership returns the standing of a user in an association, or nil if the user is neither
+// its owner nor one of its active members. A nil user is an anonymous visitor.
+func (s *LibraryService) GetMembership(user *models.User, associationID uint) (*models.LibraryMembership, error) {
+	if user == nil {
+		return nil, nil
+	}
+	if user.ID == associationID {
+		return &models.LibraryMembership{AssociationID: associationID, Owner: true}, nil
+	}
+	members, err := s.activeMembers(*user)
+	if err != nil {
+		return nil, err
+	}
+	var membership *models.LibraryMembership
+	for _, member := range members {
+		if member.UserID != associationID {
+			continue
+		}
+		// A user with several member records is on the board if any of them has a role.
+		if membership == nil || membership.BoardRole == "" {
+			membership = &models.LibraryMembership{AssociationID: associationID, BoardRole: member.BoardRole}
+		}
+	}
+	return membership, nil
+}
+
+// GetLibrary retrieves the documents of an association a user may see, by name, with the name of
+// the association and the standing of the user in it (nil for visitors, who only see the public
+// documents). Only the documents matching the search, if any, are listed.
+func (s *LibraryService) GetLibrary(user *models.User, associationID uint, search string) (string, *models.LibraryMembership, []models.Document, error) {
+	membership, err := s.GetMembership(user, associationID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	name, err := s.associationName(associationID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	audience := []models.DocumentVisibility{models.VisibilityPublic}
+	if membership != nil {
+		audience = membership.Audience()
+	}
+	// The owner sees its private documents in its own library, not in the members' one.
+	if membership != nil && membership.Owner {
+		audience = audience[1:]
+	}
+	documents, err := s.documentService.SearchDocuments(associationID, models.DocumentQuery{
+		AllFolders:   true,
+		Search:       search,
+		Sort:         models.DocumentSortName,
+		Visibilities: audience,
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return name, membership, documents, nil
+}
+
+// CanView reports whether a user (nil for an anonymous visitor) may see and download a document.
+func (s *LibraryService) CanView(user *models.User, document *models.Document) (bool, error) {
+	if document.Visibility == models.VisibilityPublic {
+		return true, nil
+	}
+	membership, err := s.GetMembership(user, document.UserID)
+	if err != nil || membership == nil {
+		return false, err
+	}
+	for _, visibility := range membership.Audience() {
+		if document.Visibility == visibility {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetDocument retrieves a document a user (nil for an anonymous visitor) may see. An error is
+// returned alike when the document does not exist and when the user may not see it.
+func (s *LibraryService) GetDocument(user *models.User, documentID uint) (*models.Document, error) {
+	document, err := s.documentService.GetDocumentByID(documentID)
+	if err != nil {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	allowed, err := s.CanView(user, document)
+	if err != nil {
+		return nil, fmt.Errorf("impossible de vérifier l'accès au document: %w", err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return document, nil
+}
+
+// activeMembers returns the member records of a user, in all the associations, that are active:
+// with the active status and a membership not ended yet.
+func (s *LibraryService) activeMembers(user models.User) ([]models.Member, error) {
+	email := strings.TrimSpace(user.Email)
+	if email == "" {
+		return nil, nil
+	}
+	members, err := s.memberRepo.FindMembersByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().Truncate(24 * time.Hour)
+	active := members[:0]
+	for _, member := range members {
+		if member.MembershipStatus != models.StatusActive || (member.EndDate != nil && member.EndDate.Before(today)) {
+			continue
+		}
+		active = append(active, member)
+	}
+	return active, nil
+}
+
+// associationName returns the name of an association, as set in its settings.
+func (s *LibraryService) associationName(associationID uint) (string, error) {
+	settings, err := s.settingsService.GetSettings(associationID)
+	if err != nil {
+		return "", err
+	}
+	if settings.LegalName != "" {
+		return settings.LegalName, nil
+	}
+	return fmt.Sprintf("Association n°%d", associationID), nil
+}
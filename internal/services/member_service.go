@@ -78,6 +78,7 @@ func (s *MemberService) validateMember(member *models.Member) error {
 	member.FirstName = strings.TrimSpace(member.FirstName)
 	member.LastName = strings.TrimSpace(member.LastName)
 	member.Email = strings.TrimSpace(member.Email)
+	member.BoardRole = strings.TrimSpace(member.BoardRole)
 
 	if member.FirstName == "" {
 		return fmt.Errorf("le prénom est requis")
@@ -89,6 +90,9 @@ func (s *MemberService) validateMember(member *models.Member) error {
 		return fmt.Errorf("l'email est requis")
 	}
 	// Ideally, add more robust email validation here.
+	if len([]rune(member.BoardRole)) > 100 {
+		return fmt.Errorf("la fonction au bureau ne doit pas dépasser 100 caractères")
+	}
 
 	return nil
 }
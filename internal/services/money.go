@@ -0,0 +1,128 @@
+package services
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// FormatCents formats an amount in cents with two decimals and a comma separator (e.g., "-12,50").
+// Journal amounts are kept in minor units of the base currency, which always has two decimals.
+func FormatCents(cents int64) string {
+	return models.FormatMinorUnits(cents, 2, ",")
+}
+
+// ParseCents parses a decimal amount typed by a user ("12.5", "12,50") into cents.
+// An empty value is treated as zero.
+func ParseCents(value string) (int64, error) {
+	return ParseMinorUnits(value, 2)
+}
+
+// ParseMinorUnits parses a decimal amount ("1234.5", "1234,50", "-3") into an integer number
+// of minor units with the given number of decimals, without going through floating point.
+// An empty value is treated as zero; more decimals than the currency allows are rejected.
+func ParseMinorUnits(value string, decimals int) (int64, error) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, ",", "."))
+	if value == "" {
+		return 0, nil
+	}
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("montant invalide")
+	}
+	if len(fracPart) > decimals {
+		return 0, fmt.Errorf("au plus %d décimale(s) sont autorisées", decimals)
+	}
+	var amount int64
+	for _, r := range intPart + fracPart + strings.Repeat("0", decimals-len(fracPart)) {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("montant invalide: %q", value)
+		}
+		if amount > (1<<62)/10 {
+			return 0, fmt.Errorf("montant trop élevé: %q", value)
+		}
+		amount = amount*10 + int64(r-'0')
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ParseMoney parses a decimal amount in the given ISO 4217 currency.
+func ParseMoney(value, currency string) (models.Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	decimals, ok := models.CurrencyDecimals(currency)
+	if !ok {
+		return models.Money{}, fmt.Errorf("devise non supportée: %q", currency)
+	}
+	amount, err := ParseMinorUnits(value, decimals)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return models.Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseExchangeRate parses a strictly positive exchange rate ("1.0856", "0,92") and returns it
+// in a normalized form (dot separator, no superfluous zeros) along with its exact value.
+func ParseExchangeRate(value string) (string, *big.Rat, error) {
+	value = strings.TrimSpace(strings.ReplaceAll(value, ",", "."))
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || strings.ContainsAny(value, "/eE") {
+		return "", nil, fmt.Errorf("taux de change invalide: %q", value)
+	}
+	if rate.Sign() <= 0 {
+		return "", nil, fmt.Errorf("le taux de change doit être supérieur à zéro")
+	}
+	if strings.Contains(value, ".") {
+		value = strings.TrimRight(strings.TrimRight(value, "0"), ".")
+	}
+	return strings.TrimLeft(value, "+"), rate, nil
+}
+
+// ConvertMoney converts an amount to the target currency with an exchange rate expressed as the
+// number of target units for one unit of the source currency. The result is rounded half away
+// from zero to the minor unit of the target currency.
+func ConvertMoney(amount models.Money, rate *big.Rat, targetCurrency string) (int64, error) {
+	sourceDecimals, ok := models.CurrencyDecimals(amount.Currency)
+	if !ok {
+		return 0, fmt.Errorf("devise non supportée: %q", amount.Currency)
+	}
+	targetDecimals, ok := models.CurrencyDecimals(targetCurrency)
+	if !ok {
+		return 0, fmt.Errorf("devise non supportée: %q", targetCurrency)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Amount), rate)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(targetDecimals-sourceDecimals))), nil)
+	if targetDecimals >= sourceDecimals {
+		converted.Mul(converted, new(big.Rat).SetInt(scale))
+	} else {
+		converted.Quo(converted, new(big.Rat).SetInt(scale))
+	}
+
+	// Round half away from zero: add or subtract one half, then truncate toward zero.
+	half := big.NewRat(1, 2)
+	if converted.Sign() < 0 {
+		converted.Sub(converted, half)
+	} else {
+		converted.Add(converted, half)
+	}
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("montant converti trop élevé")
+	}
+	return result.Int64(), nil
+}
+
+// abs returns the absolute value of an integer.
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
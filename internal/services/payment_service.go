@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/payments"
+	"gorm.io/gorm"
+)
+
+// paymentReferencePrefix prefixes the ID of an online payment in the reference given to the provider.
+const paymentReferencePrefix = "paiement-"
+
+// PaymentService encapsulates the business logic for online payments of dues and event fees.
+// Members pay through a public link; the provider then reports the outcome by webhook, which
+// records the income transaction and, for dues, the member's payment.
+type PaymentService struct {
+	paymentRepo    repositories.OnlinePaymentRepository
+	provider       payments.PaymentProvider
+	financeService *FinanceService
+	memberService  *MemberService
+	eventService   *EventService
+	cfg            *config.Config
+}
+
+// NewPaymentService creates a new instance of PaymentService.
+// It takes an OnlinePaymentRepository, the PaymentProvider (nil when online payment is disabled),
+// the FinanceService, the MemberService, the EventService and the Config (for the public address
+// of the application) as dependencies, adhering to the dependency inversion principle.
+func NewPaymentService(paymentRepo repositories.OnlinePaymentRepository, provider payments.PaymentProvider, financeService *FinanceService, memberService *MemberService, eventService *EventService, cfg *config.Config) *PaymentService {
+	return &PaymentService{
+		paymentRepo:    paymentRepo,
+		provider:       provider,
+		financeService: financeService,
+		memberService:  memberService,
+		eventService:   eventService,
+		cfg:            cfg,
+	}
+}
+
+// Enabled reports whether a payment provider is configured.
+func (s *PaymentService) Enabled() bool {
+	return s.provider != nil
+}
+
+// BaseCurrency returns the currency online payments of an association are collected in.
+func (s *PaymentService) BaseCurrency(userID uint) (string, error) {
+	return s.financeService.GetBaseCurrency(userID)
+}
+
+// CreatePaymentRequest validates and records a request for a member to pay dues or an event fee
+// online, and generates the secret token of its public payment page.
+func (s *PaymentService) CreatePaymentRequest(payment *models.OnlinePayment) error {
+	if !s.Enabled() {
+		return fmt.Errorf("aucun prestataire de paiement en ligne n'est configuré")
+	}
+	member, err := s.memberService.GetMemberByID(payment.MemberID)
+	if err != nil || member.UserID != payment.UserID {
+		return fmt.Errorf("membre invalide")
+	}
+
+	payment.Description = strings.TrimSpace(payment.Description)
+	switch payment.Purpose {
+	case models.PaymentDues:
+		payment.EventID = nil
+		if payment.Description == "" {
+			payment.Description = fmt.Sprintf("Cotisation %d", time.Now().Year())
+		}
+	case models.PaymentEventFee:
+		if payment.EventID == nil {
+			return fmt.Errorf("l'événement est requis pour une participation")
+		}
+		event, err := s.eventService.GetEventByID(*payment.EventID)
+		if err != nil || event.UserID != payment.UserID {
+			return fmt.Errorf("événement invalide")
+		}
+		if payment.Description == "" {
+			payment.Description = "Participation : " + event.Title
+		}
+	default:
+		return fmt.Errorf("objet du paiement invalide")
+	}
+
+	if payment.Amount.Amount <= 0 {
+		return fmt.Errorf("le montant doit être supérieur à zéro")
+	}
+	// The income is recorded without exchange rate, so it must be collected in the base currency.
+	baseCurrency, err := s.financeService.GetBaseCurrency(payment.UserID)
+	if err != nil {
+		return fmt.Errorf("erreur lors de la récupération de la devise de référence: %w", err)
+	}
+	if payment.Amount.Currency == "" {
+		payment.Amount.Currency = baseCurrency
+	}
+	if payment.Amount.Currency != baseCurrency {
+		return fmt.Errorf("les paiements en ligne sont encaissés dans la devise de référence (%s)", baseCurrency)
+	}
+
+	token, err := newPaymentToken()
+	if err != nil {
+		return err
+	}
+	payment.Token = token
+	payment.Status = models.OnlinePaymentPending
+	return s.paymentRepo.CreatePayment(payment)
+}
+
+// GetPaymentsByUserID retrieves the online payments of an association, most recent first.
+func (s *PaymentService) GetPaymentsByUserID(userID uint) ([]models.OnlinePayment, error) {
+	return s.paymentRepo.FindPaymentsByUserID(userID)
+}
+
+// GetPaymentByToken retrieves the online payment behind a public payment page.
+func (s *PaymentService) GetPaymentByToken(token string) (*models.OnlinePayment, error) {
+	if token == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return s.paymentRepo.FindPaymentByToken(token)
+}
+
+// PaymentURL returns the address of the public page where the member pays.
+func (s *PaymentService) PaymentURL(payment models.OnlinePayment) string {
+	return strings.TrimRight(s.cfg.AppURL, "/") + "/pay/" + payment.Token
+}
+
+// StartCheckout opens a checkout session with the provider for the payment behind a public page
+// and returns the address the member must be redirected to. A failed attempt can be retried.
+func (s *PaymentService) StartCheckout(ctx context.Context, token string) (string, error) {
+	if !s.Enabled() {
+		return "", fmt.Errorf("le paiement en ligne n'est pas disponible")
+	}
+	payment, err := s.GetPaymentByToken(token)
+	if err != nil {
+		return "", fmt.Errorf("paiement introuvable")
+	}
+	if payment.Status != models.OnlinePaymentPending && payment.Status != models.OnlinePaymentFailed {
+		return "", fmt.Errorf("ce paiement n'est plus payable (%s)", payment.Status)
+	}
+	member, err := s.memberService.GetMemberByID(payment.MemberID)
+	if err != nil {
+		return "", fmt.Errorf("membre introuvable")
+	}
+
+	// A failed attempt is over: the next one opens a new session. Retrying an attempt whose
+	// session could not be recorded reuses it.
+	attempt := payment.Attempts
+	if attempt == 0 || payment.Status == models.OnlinePaymentFailed {
+		attempt++
+	}
+	pageURL := s.PaymentURL(*payment)
+	session, err := s.provider.CreateCheckoutSession(ctx, payments.CheckoutRequest{
+		Reference:   paymentReferencePrefix + strconv.FormatUint(uint64(payment.ID), 10),
+		Attempt:     attempt,
+		Description: payment.Description,
+		Amount:      payment.Amount.Amount,
+		Currency:    payment.Amount.Currency,
+		Email:       member.Email,
+		SuccessURL:  pageURL + "?retour=succes",
+		CancelURL:   pageURL + "?retour=annule",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payment.Status = models.OnlinePaymentPending
+	payment.Provider = s.provider.Name()
+	payment.SessionID = session.ID
+	payment.Attempts = attempt
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return "", err
+	}
+	return session.URL, nil
+}
+
+// HandleWebhook authenticates and processes a webhook delivery from the provider. Deliveries of
+// an event already processed, as providers retry them until acknowledged, are ignored, and a
+// payment is recorded only once even if several of its events arrive at the same time.
+func (s *PaymentService) HandleWebhook(payload []byte, header http.Header) error {
+	if !s.Enabled() {
+		return fmt.Errorf("le paiement en ligne n'est pas disponible")
+	}
+	event, err := s.provider.VerifyWebhook(payload, header)
+	if err != nil {
+		return err
+	}
+	processed, err := s.paymentRepo.IsWebhookEventProcessed(s.provider.Name(), event.ID)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		err = s.recordPayment(event)
+	case payments.EventPaymentFailed:
+		err = s.recordFailure(event)
+	}
+	if err != nil {
+		return err
+	}
+	return s.paymentRepo.RecordWebhookEvent(s.provider.Name(), event.ID, string(event.Type))
+}
+
+// Refund refunds a paid online payment through the provider and records the refund as an expense.
+func (s *PaymentService) Refund(ctx context.Context, userID, paymentID uint) error {
+	payment, err := s.getPayment(userID, paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != models.OnlinePaymentPaid || payment.PaymentRef == "" {
+		return fmt.Errorf("seul un paiement reçu peut être remboursé")
+	}
+	if !s.Enabled() || payment.Provider != s.provider.Name() {
+		return fmt.Errorf("le prestataire de ce paiement n'est plus configuré")
+	}
+	// Claim the payment first, so that the refund cannot be requested twice.
+	if ok, err := s.paymentRepo.TransitionPayment(payment.ID, models.OnlinePaymentPaid, models.OnlinePaymentRefunded); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("ce paiement est déjà en cours de remboursement")
+	}
+	if _, err := s.provider.Refund(ctx, payment.PaymentRef, payment.Amount.Amount, payment.Amount.Currency); err != nil {
+		if _, revertErr := s.paymentRepo.TransitionPayment(payment.ID, models.OnlinePaymentRefunded, models.OnlinePaymentPaid); revertErr != nil {
+			log.Printf("ERREUR: Impossible de rétablir le paiement en ligne %d après l'échec du remboursement: %v", payment.ID, revertErr)
+		}
+		return err
+	}
+
+	now := time.Now()
+	payment.Status = models.OnlinePaymentRefunded
+	payment.RefundedAt = &now
+	// The money has left the account: the refund is recorded as a posted expense without approval.
+	memberID := payment.MemberID
+	refund := &models.Transaction{
+		UserID:      payment.UserID,
+		Type:        models.TypeExpense,
+		Status:      models.TransactionPosted,
+		Date:        now,
+		Amount:      payment.Amount,
+		Description: "Remboursement du paiement en ligne : " + payment.Description,
+		MemberID:    &memberID,
+	}
+	if err := s.financeService.CreateTransaction(refund); err != nil {
+		if saveErr := s.paymentRepo.UpdatePayment(payment); saveErr != nil {
+			log.Printf("ERREUR: Impossible d'enregistrer le remboursement du paiement en ligne %d: %v", payment.ID, saveErr)
+		}
+		return fmt.Errorf("paiement remboursé, mais l'enregistrement de la dépense a échoué: %w", err)
+	}
+	payment.RefundTransactionID = &refund.ID
+	return s.paymentRepo.UpdatePayment(payment)
+}
+
+// CancelPayment withdraws a payment request that has not been paid.
+func (s *PaymentService) CancelPayment(userID, paymentID uint) error {
+	payment, err := s.getPayment(userID, paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.Status != models.OnlinePaymentPending && payment.Status != models.OnlinePaymentFailed {
+		return fmt.Errorf("seule une demande non payée peut être annulée")
+	}
+	if ok, err := s.paymentRepo.TransitionPayment(payment.ID, payment.Status, models.OnlinePaymentCancelled); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("le statut de ce paiement vient de changer, veuillez réessayer")
+	}
+	return nil
+}
+
+// recordPayment records the income of a successful checkout, marks the payment as paid and, for
+// dues, records the member's payment.
+func (s *PaymentService) recordPayment(event *payments.WebhookEvent) error {
+	payment, err := s.findEventPayment(event)
+	if err != nil {
+		return err
+	}
+	if payment == nil {
+		log.Printf("ERREUR: Paiement en ligne inconnu pour la session %q (événement %s)", event.SessionID, event.ID)
+		return nil
+	}
+	if payment.Status != models.OnlinePaymentPending && payment.Status != models.OnlinePaymentFailed {
+		return nil // Already recorded by an earlier event.
+	}
+	if event.Amount != payment.Amount.Amount || !strings.EqualFold(event.Currency, payment.Amount.Currency) {
+		return fmt.Errorf("le montant payé (%d %s) ne correspond pas au paiement en ligne %d", event.Amount, event.Currency, payment.ID)
+	}
+	previous := payment.Status
+	if ok, err := s.paymentRepo.TransitionPayment(payment.ID, previous, models.OnlinePaymentPaid); err != nil || !ok {
+		return err // Another delivery is recording this payment.
+	}
+
+	now := time.Now()
+	memberID := payment.MemberID
+	income := &models.Transaction{
+		UserID:      payment.UserID,
+		Type:        models.TypeIncome,
+		Status:      models.TransactionPosted,
+		Date:        now,
+		Amount:      payment.Amount,
+		Description: "Paiement en ligne : " + payment.Description,
+		AccountCode: payment.AccountCode(),
+		MemberID:    &memberID,
+	}
+	if err := s.financeService.CreateTransaction(income); err != nil {
+		// Let the provider deliver the event again once the problem is solved.
+		if _, revertErr := s.paymentRepo.TransitionPayment(payment.ID, models.OnlinePaymentPaid, previous); revertErr != nil {
+			log.Printf("ERREUR: Impossible de rétablir le paiement en ligne %d: %v", payment.ID, revertErr)
+		}
+		return fmt.Errorf("erreur lors de l'enregistrement du paiement en ligne %d: %w", payment.ID, err)
+	}
+
+	payment.Status = models.OnlinePaymentPaid
+	payment.PaidAt = &now
+	payment.PaymentRef = event.PaymentRef
+	payment.TransactionID = &income.ID
+	if err := s.paymentRepo.UpdatePayment(payment); err != nil {
+		return err
+	}
+	if payment.Purpose == models.PaymentDues {
+		if err := s.memberService.MarkPaymentReceived(payment.MemberID, now); err != nil {
+			log.Printf("ERREUR: Impossible d'enregistrer la cotisation du membre %d: %v", payment.MemberID, err)
+		}
+	}
+	return nil
+}
+
+// recordFailure marks a pending payment as failed, so that the association sees the attempt
+// while the member can still pay again.
+func (s *PaymentService) recordFailure(event *payments.WebhookEvent) error {
+	payment, err := s.findEventPayment(event)
+	if err != nil || payment == nil {
+		return err
+	}
+	// Only the current session counts: an older one may expire after a successful retry.
+	if payment.SessionID != event.SessionID {
+		return nil
+	}
+	_, err = s.paymentRepo.TransitionPayment(payment.ID, models.OnlinePaymentPending, models.OnlinePaymentFailed)
+	return err
+}
+
+// findEventPayment retrieves the payment a webhook event relates to, by checkout session or by the
+// reference given when the session was opened. It returns nil if no payment matches.
+func (s *PaymentService) findEventPayment(event *payments.WebhookEvent) (*models.OnlinePayment, error) {
+	if event.SessionID != "" {
+		payment, err := s.paymentRepo.FindPaymentBySessionID(event.SessionID)
+		if err == nil {
+			return payment, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	id, parseErr := strconv.ParseUint(strings.TrimPrefix(event.Reference, paymentReferencePrefix), 10, 64)
+	if !strings.HasPrefix(event.Reference, paymentReferencePrefix) || parseErr != nil {
+		return nil, nil
+	}
+	payment, err := s.paymentRepo.FindPaymentByID(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return payment, err
+}
+
+// getPayment retrieves an online payment and ensures it belongs to the given association.
+func (s *PaymentService) getPayment(userID, paymentID uint) (*models.OnlinePayment, error) {
+	payment, err := s.paymentRepo.FindPaymentByID(paymentID)
+	if err != nil || payment.UserID != userID {
+		return nil, fmt.Errorf("paiement introuvable")
+	}
+	return payment, nil
+}
+
+// newPaymentToken returns a random token for a public payment page.
+func newPaymentToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erreur lors de la génération du lien de paiement: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/payments"
+	"gorm.io/gorm"
+)
+
+// newTestPaymentService returns a PaymentService backed by an in-memory database and the
+// FakeProvider, and a member of association 1 owing dues of 25 EUR.
+func newTestPaymentService(t *testing.T) (*PaymentService, *payments.FakeProvider, *gorm.DB, *models.OnlinePayment) {
+	t.Helper()
+	db := newTestDB(t, &repositories.MemberDB{}, &repositories.EventDB{}, &repositories.TransactionDB{}, &repositories.AccountDB{}, &repositories.JournalEntryDB{}, &repositories.JournalLineDB{}, &repositories.AssociationSettingsDB{}, &repositories.FiscalPeriodClosureDB{}, &repositories.FiscalPeriodEventDB{}, &repositories.OnlinePaymentDB{}, &repositories.PaymentWebhookEventDB{})
+
+	transactionRepo := repositories.NewGormTransactionRepository(db)
+	settingsService := NewSettingsService(repositories.NewGormSettingsRepository(db), transactionRepo)
+	periodService := NewFiscalPeriodService(repositories.NewGormFiscalPeriodRepository(db), transactionRepo, settingsService)
+	ledgerService := NewLedgerService(repositories.NewGormLedgerRepository(db), periodService)
+	financeService := NewFinanceService(transactionRepo, ledgerService, settingsService, periodService)
+	memberService := NewMemberService(repositories.NewGormMemberRepository(db))
+	provider := payments.NewFakeProvider("http://localhost/payments/fake/")
+	service := NewPaymentService(repositories.NewGormOnlinePaymentRepository(db), provider, financeService, memberService, NewEventService(repositories.NewGormEventRepository(db)), &config.Config{AppURL: "http://localhost"})
+
+	member := &models.Member{UserID: 1, FirstName: "Alice", LastName: "Martin", Email: "alice@example.org", MembershipStatus: models.StatusActive, JoinDate: time.Now()}
+	if err := memberService.CreateMember(member); err != nil {
+		t.Fatal(err)
+	}
+	payment := &models.OnlinePayment{UserID: 1, MemberID: member.ID, Purpose: models.PaymentDues, Amount: models.Money{Amount: 2500, Currency: "EUR"}}
+	if err := service.CreatePaymentRequest(payment); err != nil {
+		t.Fatal(err)
+	}
+	return service, provider, db, payment
+}
+
+// startCheckout opens a checkout session for the payment and returns its identifier.
+func startCheckout(t *testing.T, service *PaymentService, payment *models.OnlinePayment) string {
+	t.Helper()
+	checkoutURL, err := service.StartCheckout(context.Background(), payment.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return checkoutURL[strings.LastIndex(checkoutURL, "/")+1:]
+}
+
+func TestPaymentServiceHandleWebhook(t *testing.T) {
+	t.Run("replayed delivery", func(t *testing.T) {
+		service, provider, db, payment := newTestPaymentService(t)
+		payload, header, err := provider.Complete(startCheckout(t, service, payment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := service.HandleWebhook(payload, header); err != nil {
+				t.Fatalf("delivery %d: %v", i+1, err)
+			}
+		}
+
+		paid, err := service.GetPaymentByToken(payment.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if paid.Status != models.OnlinePaymentPaid || paid.TransactionID == nil || paid.PaymentRef == "" {
+			t.Errorf("payment = %s, transaction %v, ref %q, want paid with its income", paid.Status, paid.TransactionID, paid.PaymentRef)
+		}
+		var incomes int64
+		db.Model(&repositories.TransactionDB{}).Where("user_id = ? AND type = ?", 1, models.TypeIncome).Count(&incomes)
+		if incomes != 1 {
+			t.Errorf("%d income transactions, want 1", incomes)
+		}
+		var member repositories.MemberDB
+		db.First(&member, payment.MemberID)
+		if member.LastPaymentDate == nil {
+			t.Error("dues payment not recorded on the member")
+		}
+	})
+
+	t.Run("tampered delivery", func(t *testing.T) {
+		service, provider, _, payment := newTestPaymentService(t)
+		payload, header, err := provider.Complete(startCheckout(t, service, payment))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := []byte(strings.Replace(string(payload), "2500", "25", 1))
+		if err := service.HandleWebhook(tampered, header); !errors.Is(err, payments.ErrInvalidSignature) {
+			t.Fatalf("HandleWebhook = %v, want ErrInvalidSignature", err)
+		}
+		if current, _ := service.GetPaymentByToken(payment.Token); current.Status != models.OnlinePaymentPending {
+			t.Errorf("payment = %s, want %s", current.Status, models.OnlinePaymentPending)
+		}
+	})
+
+	t.Run("retry after a failure", func(t *testing.T) {
+		service, provider, _, payment := newTestPaymentService(t)
+		first := startCheckout(t, service, payment)
+		payload, header, err := provider.Cancel(first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.HandleWebhook(payload, header); err != nil {
+			t.Fatal(err)
+		}
+		if current, _ := service.GetPaymentByToken(payment.Token); current.Status != models.OnlinePaymentFailed {
+			t.Fatalf("payment = %s, want %s", current.Status, models.OnlinePaymentFailed)
+		}
+
+		second := startCheckout(t, service, payment)
+		session, _ := provider.Session(second)
+		if second == first || session.Attempt != 2 {
+			t.Fatalf("retry opened session %s (attempt %d), want a new session for attempt 2", second, session.Attempt)
+		}
+		payload, header, err = provider.Complete(second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.HandleWebhook(payload, header); err != nil {
+			t.Fatal(err)
+		}
+		// The expiry of the first session, delivered again late, must not affect the paid payment.
+		payload, header, _ = provider.Cancel(first)
+		if err := service.HandleWebhook(payload, header); err != nil {
+			t.Fatal(err)
+		}
+		if current, _ := service.GetPaymentByToken(payment.Token); current.Status != models.OnlinePaymentPaid || current.Attempts != 2 {
+			t.Errorf("payment = %s after %d attempts, want %s after 2", current.Status, current.Attempts, models.OnlinePaymentPaid)
+		}
+	})
+}
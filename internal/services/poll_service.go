@@ -1,8 +1,13 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/JneiraS/BaseSasS/internal/domain/models"
 	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
@@ -10,23 +15,49 @@ import (
 
 // PollService encapsulates the business logic for managing polls.
 // It interacts with PollRepository and VoteRepository to perform poll and vote-related operations.
+// The resolutions of a general assembly are polls too, opened to votes with the AssemblyService.
 type PollService struct {
-	pollRepo repositories.PollRepository
-	voteRepo repositories.VoteRepository
+	pollRepo        repositories.PollRepository
+	voteRepo        repositories.VoteRepository
+	assemblyService *AssemblyService
 }
 
 // NewPollService creates a new instance of PollService.
-// It takes PollRepository and VoteRepository as dependencies, adhering to the dependency inversion principle.
-func NewPollService(pollRepo repositories.PollRepository, voteRepo repositories.VoteRepository) *PollService {
-	return &PollService{pollRepo: pollRepo, voteRepo: voteRepo}
+// It takes PollRepository, VoteRepository and the AssemblyService (for the resolutions of general
+// assemblies) as dependencies, adhering to the dependency inversion principle.
+func NewPollService(pollRepo repositories.PollRepository, voteRepo repositories.VoteRepository, assemblyService *AssemblyService) *PollService {
+	return &PollService{pollRepo: pollRepo, voteRepo: voteRepo, assemblyService: assemblyService}
 }
 
 // CreatePoll handles the creation of a new poll with its options.
-// It performs validation on the poll data before persisting it via the repository.
-func (s *PollService) CreatePoll(poll *models.Poll) error {
+// It performs validation on the poll data before persisting it via the repository. The poll is
+// opened at once if asked to, and otherwise saved as a draft, which opens by itself at its
+// opening time if one is set. The resolution of a general assembly is only opened during the
+// session, once the quorum is reached, and thus has no opening time.
+func (s *PollService) CreatePoll(poll *models.Poll, openNow bool) error {
 	if err := s.validatePoll(poll); err != nil {
 		return err
 	}
+	if poll.AssemblyID != nil {
+		if err := s.assemblyService.checkResolution(poll.UserID, *poll.AssemblyID); err != nil {
+			return err
+		}
+		poll.OpensAt = nil
+		if openNow {
+			if err := s.assemblyService.checkResolutionOpening(*poll.AssemblyID); err != nil {
+				return err
+			}
+		}
+	}
+	now := time.Now()
+	if err := validatePollSchedule(poll, now); err != nil {
+		return err
+	}
+	poll.Status = models.PollDraft
+	if openNow {
+		poll.Status = models.PollOpen
+		poll.OpensAt = &now
+	}
 	return s.pollRepo.CreatePoll(poll)
 }
 
@@ -40,17 +71,110 @@ func (s *PollService) GetAllPolls() ([]models.Poll, error) {
 	return s.pollRepo.FindAllPolls()
 }
 
+// GetVisiblePolls retrieves the polls a user may see: all the polls but the drafts of the other
+// users.
+func (s *PollService) GetVisiblePolls(userID uint) ([]models.Poll, error) {
+	polls, err := s.pollRepo.FindAllPolls()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	visible := polls[:0]
+	for _, poll := range polls {
+		if poll.UserID == userID || poll.State(now) != models.PollDraft {
+			visible = append(visible, poll)
+		}
+	}
+	return visible, nil
+}
+
+// GetVisiblePoll retrieves a poll a user may see. A draft is only seen by its creator: an error is
+// returned alike when the poll does not exist and when it is the draft of another user.
+func (s *PollService) GetVisiblePoll(userID, pollID uint) (*models.Poll, error) {
+	poll, err := s.pollRepo.FindPollByID(pollID)
+	if err != nil || (poll.UserID != userID && poll.State(time.Now()) == models.PollDraft) {
+		return nil, fmt.Errorf("sondage non trouvé")
+	}
+	return poll, nil
+}
+
 // GetPollsByUserID retrieves all polls created by a specific user.
 func (s *PollService) GetPollsByUserID(userID uint) ([]models.Poll, error) {
 	return s.pollRepo.FindPollsByUserID(userID)
 }
 
-// UpdatePoll handles the update of an existing poll.
-// It performs validation on the updated poll data before persisting the changes.
-func (s *PollService) UpdatePoll(poll *models.Poll) error {
+// UpdatePoll handles the update of an existing poll of the user.
+// It performs validation on the updated poll data before persisting the changes. A draft can be
+// changed entirely; once the poll is open, only its closing time and the visibility of its
+// results can be, so that all the ballots are cast alike, and a closed poll cannot be changed
+// anymore.
+func (s *PollService) UpdatePoll(userID uint, poll *models.Poll) error {
+	existing, err := s.pollRepo.FindPollByID(poll.ID)
+	if err != nil || existing.UserID != userID {
+		return fmt.Errorf("sondage non trouvé")
+	}
+	now := time.Now()
+	switch existing.State(now) {
+	case models.PollClosed:
+		return fmt.Errorf("un sondage clôturé ne peut plus être modifié")
+	case models.PollOpen:
+		poll.Question = existing.Question
+		poll.Options = existing.Options
+		poll.OpensAt = existing.OpensAt
+		poll.Type = existing.Type
+		poll.MinChoices = existing.MinChoices
+		poll.MaxChoices = existing.MaxChoices
+		poll.Anonymous = existing.Anonymous
+	}
+	poll.Model = existing.Model
+	poll.UserID = existing.UserID
+	poll.Status = existing.Status
+	poll.AssemblyID = existing.AssemblyID
+	if poll.AssemblyID != nil {
+		poll.OpensAt = existing.OpensAt
+	}
 	if err := s.validatePoll(poll); err != nil {
 		return err
 	}
+	if err := validatePollSchedule(poll, now); err != nil {
+		return err
+	}
+	return s.pollRepo.UpdatePoll(poll)
+}
+
+// OpenPoll opens a draft of the user to votes now, whatever its scheduled opening time. A
+// resolution is opened during the session of its general assembly, once the quorum is reached.
+func (s *PollService) OpenPoll(userID, pollID uint) error {
+	poll, err := s.pollRepo.FindPollByID(pollID)
+	if err != nil || poll.UserID != userID {
+		return fmt.Errorf("sondage non trouvé")
+	}
+	now := time.Now()
+	if poll.State(now) != models.PollDraft {
+		return fmt.Errorf("seul un brouillon peut être ouvert")
+	}
+	if poll.AssemblyID != nil {
+		if err := s.assemblyService.checkResolutionOpening(*poll.AssemblyID); err != nil {
+			return err
+		}
+	}
+	poll.Status = models.PollOpen
+	poll.OpensAt = &now
+	return s.pollRepo.UpdatePoll(poll)
+}
+
+// ClosePoll closes an open poll of the user now, whatever its scheduled closing time.
+func (s *PollService) ClosePoll(userID, pollID uint) error {
+	poll, err := s.pollRepo.FindPollByID(pollID)
+	if err != nil || poll.UserID != userID {
+		return fmt.Errorf("sondage non trouvé")
+	}
+	now := time.Now()
+	if poll.State(now) != models.PollOpen {
+		return fmt.Errorf("seul un sondage ouvert peut être clôturé")
+	}
+	poll.Status = models.PollClosed
+	poll.ClosesAt = &now
 	return s.pollRepo.UpdatePoll(poll)
 }
 
@@ -60,42 +184,133 @@ func (s *PollService) DeletePoll(id uint) error {
 	return s.pollRepo.DeletePoll(id)
 }
 
-// Vote records a user's vote for a given poll option.
-// It first checks if the user has already voted in the poll and validates the option.
-func (s *PollService) Vote(optionID, userID, pollID uint) error {
+// Vote records the ballot of a user in a poll: the options chosen, in order of preference for a
+// ranked poll. It first checks if the user has already voted in the poll, then validates the
+// ballot against the type of the poll. Votes are only accepted while the poll is open. The ballot
+// of a secret poll is recorded apart from the participation of the user, and the receipt code
+// with which the user can check it is counted is returned; it is empty for the other polls. The
+// ballot cast on the resolution of a general assembly carries the votes of the member the user is
+// and of the members it represents.
+func (s *PollService) Vote(user models.User, pollID uint, choices []uint) (string, error) {
+	userID := user.ID
 	// Check if the user has already voted for this poll.
 	hasVoted, err := s.voteRepo.HasUserVoted(userID, pollID)
 	if err != nil {
-		return fmt.Errorf("erreur lors de la vérification du vote: %w", err)
+		return "", fmt.Errorf("erreur lors de la vérification du vote: %w", err)
 	}
 	if hasVoted {
-		return fmt.Errorf("vous avez déjà voté pour ce sondage")
+		return "", fmt.Errorf("vous avez déjà voté pour ce sondage")
 	}
 
-	// Verify that the option belongs to the specified poll.
 	poll, err := s.pollRepo.FindPollByID(pollID)
 	if err != nil {
-		return fmt.Errorf("sondage non trouvé: %w", err)
+		return "", fmt.Errorf("sondage non trouvé: %w", err)
+	}
+	switch poll.State(time.Now()) {
+	case models.PollDraft:
+		if poll.UserID != userID {
+			return "", fmt.Errorf("sondage non trouvé")
+		}
+		return "", fmt.Errorf("ce sondage n'est pas encore ouvert au vote")
+	case models.PollClosed:
+		return "", fmt.Errorf("ce sondage est clôturé")
 	}
 
-	optionExists := false
-	for _, opt := range poll.Options {
-		if opt.ID == optionID {
-			optionExists = true
-			break
+	if err := validateBallot(poll, choices); err != nil {
+		return "", err
+	}
+	weight, err := s.VotingWeight(user, poll)
+	if err != nil {
+		return "", err
+	}
+
+	// Persist the ballot with the participation of the user; the repository settles concurrent
+	// ballots of the same user.
+	var receipt string
+	var cast bool
+	if poll.Anonymous {
+		if receipt, err = newReceiptCode(); err != nil {
+			return "", err
+		}
+		var ballots []models.SecretBallot
+		if ballots, err = secretBallots(poll.ID, receipt, choices, weight); err != nil {
+			return "", err
+		}
+		cast, err = s.voteRepo.CastSecretBallot(poll.ID, userID, ballots)
+	} else {
+		votes := make([]models.Vote, len(choices))
+		for i, optionID := range choices {
+			votes[i] = models.Vote{OptionID: optionID, UserID: userID, Weight: weight}
+			if poll.Type == models.PollRanked {
+				votes[i].Rank = i + 1
+			}
 		}
+		cast, err = s.voteRepo.CastBallot(poll.ID, userID, votes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("erreur lors de l'enregistrement du vote: %w", err)
+	}
+	if !cast {
+		return "", fmt.Errorf("vous avez déjà voté pour ce sondage")
+	}
+	return receipt, nil
+}
+
+// VotingWeight returns the votes the ballot of a user carries in a poll: one, or for the
+// resolution of a general assembly, the votes of the member the user is and of the members it
+// represents. An error tells why a user cannot vote on a resolution.
+func (s *PollService) VotingWeight(user models.User, poll *models.Poll) (int, error) {
+	if poll.AssemblyID == nil {
+		return 1, nil
 	}
+	return s.assemblyService.VoterWeight(user, *poll.AssemblyID)
+}
 
-	if !optionExists {
-		return fmt.Errorf("l'option de vote spécifiée n'appartient pas à ce sondage")
+// VerifyReceipt checks that the ballot of a secret poll given a receipt code is counted.
+func (s *PollService) VerifyReceipt(poll *models.Poll, receipt string) (bool, error) {
+	if !poll.Anonymous {
+		return false, fmt.Errorf("ce sondage n'est pas à bulletin secret")
+	}
+	receipt = normalizeReceipt(receipt)
+	if receipt == "" {
+		return false, fmt.Errorf("veuillez saisir votre code de reçu")
 	}
+	return s.voteRepo.SecretBallotExists(poll.ID, receiptHash(receipt))
+}
 
-	// Create and persist the new vote.
-	vote := &models.Vote{
-		OptionID: optionID,
-		UserID:   userID,
+// validateBallot checks the options chosen by a ballot: options of the poll, each chosen once,
+// in a number allowed by the type of the poll.
+func validateBallot(poll *models.Poll, choices []uint) error {
+	inPoll := make(map[uint]bool, len(poll.Options))
+	for _, opt := range poll.Options {
+		inPoll[opt.ID] = true
+	}
+	chosen := make(map[uint]bool, len(choices))
+	for _, optionID := range choices {
+		// Verify that the option belongs to the specified poll.
+		if !inPoll[optionID] {
+			return fmt.Errorf("l'option de vote spécifiée n'appartient pas à ce sondage")
+		}
+		if chosen[optionID] {
+			return fmt.Errorf("une option ne peut être choisie qu'une fois")
+		}
+		chosen[optionID] = true
 	}
-	return s.voteRepo.CreateVote(vote)
+
+	minChoices, maxChoices := poll.ChoiceLimits()
+	switch {
+	case len(choices) == 0 && poll.Type == models.PollRanked:
+		return fmt.Errorf("veuillez classer au moins une option")
+	case len(choices) == 0:
+		return fmt.Errorf("veuillez choisir une option")
+	case len(choices) > 1 && (poll.Type == "" || poll.Type == models.PollSingle):
+		return fmt.Errorf("une seule option peut être choisie")
+	case len(choices) < minChoices:
+		return fmt.Errorf("veuillez choisir au moins %d options", minChoices)
+	case len(choices) > maxChoices:
+		return fmt.Errorf("vous pouvez choisir au plus %d options", maxChoices)
+	}
+	return nil
 }
 
 // GetPollResults retrieves the results of a poll (vote counts per option).
@@ -103,6 +318,39 @@ func (s *PollService) GetPollResults(pollID uint) (map[uint]int64, error) {
 	return s.pollRepo.GetPollResults(pollID)
 }
 
+// GetPollTally counts the ballots cast in a poll, by the methods suited to its type.
+func (s *PollService) GetPollTally(poll *models.Poll) (*models.PollTally, error) {
+	return tallyPollBallots(s.voteRepo, poll)
+}
+
+// tallyPollBallots counts the ballots cast in a poll, read from the secret ballots of a secret
+// poll and from the votes of the others. A secret ballot carries a single vote, so that the
+// ballots of a secret poll are counted from the participations.
+func tallyPollBallots(voteRepo repositories.VoteRepository, poll *models.Poll) (*models.PollTally, error) {
+	if poll.Anonymous {
+		secretBallots, err := voteRepo.FindSecretBallotsByPollID(poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		ballots := make([]models.Ballot, len(secretBallots))
+		for i, ballot := range secretBallots {
+			ballots[i] = models.Ballot{Choices: ballot.Choices, Weight: 1}
+		}
+		participations, err := voteRepo.CountParticipations(poll.ID)
+		if err != nil {
+			return nil, err
+		}
+		tally := TallyPoll(poll, ballots)
+		tally.Ballots = int(participations)
+		return tally, nil
+	}
+	votes, err := voteRepo.FindVotesByPollID(poll.ID)
+	if err != nil {
+		return nil, err
+	}
+	return TallyPoll(poll, pollBallots(votes)), nil
+}
+
 // HasUserVoted checks if a user has already voted in a given poll.
 // It delegates the check to the underlying vote repository.
 func (s *PollService) HasUserVoted(userID, pollID uint) (bool, error) {
@@ -110,7 +358,9 @@ func (s *PollService) HasUserVoted(userID, pollID uint) (bool, error) {
 }
 
 // validatePoll performs business logic validation on a Poll model.
-// It checks for a non-empty question and at least two options, and validates each option's text.
+// It checks for a non-empty question and at least two options, validates each option's text, the
+// type of the poll, single choice by default, with the number of options a multiple choice
+// ballot chooses, and the visibility of the results, live by default.
 func (s *PollService) validatePoll(poll *models.Poll) error {
 	poll.Question = strings.TrimSpace(poll.Question)
 
@@ -128,5 +378,98 @@ func (s *PollService) validatePoll(poll *models.Poll) error {
 		}
 	}
 
+	if poll.Type == "" {
+		poll.Type = models.PollSingle
+	}
+	if !poll.Type.Valid() {
+		return fmt.Errorf("type de sondage invalide")
+	}
+	if poll.Type == models.PollMultiple {
+		if poll.MinChoices == 0 {
+			poll.MinChoices = 1
+		}
+		if poll.MaxChoices == 0 {
+			poll.MaxChoices = len(poll.Options)
+		}
+		if poll.MinChoices < 1 || poll.MinChoices > poll.MaxChoices {
+			return fmt.Errorf("le nombre minimum de choix doit être compris entre 1 et le nombre maximum")
+		}
+		if poll.MaxChoices > len(poll.Options) {
+			return fmt.Errorf("le nombre maximum de choix ne peut dépasser le nombre d'options")
+		}
+	} else {
+		poll.MinChoices, poll.MaxChoices = 0, 0
+	}
+
+	if poll.ResultsVisibility == "" {
+		poll.ResultsVisibility = models.ResultsLive
+	}
+	if !poll.ResultsVisibility.Valid() {
+		return fmt.Errorf("visibilité des résultats invalide")
+	}
+
 	return nil
 }
+
+// validatePollSchedule checks the opening and closing times of a poll: a poll closes after it
+// opens, and is not given a closing time already passed.
+func validatePollSchedule(poll *models.Poll, now time.Time) error {
+	if poll.ClosesAt == nil {
+		return nil
+	}
+	if !poll.ClosesAt.After(now) {
+		return fmt.Errorf("la date de clôture doit être dans le futur")
+	}
+	if poll.OpensAt != nil && !poll.ClosesAt.After(*poll.OpensAt) {
+		return fmt.Errorf("la date de clôture doit être postérieure à la date d'ouverture")
+	}
+	return nil
+}
+
+// receiptEncoding writes the receipt codes of secret ballots with letters and digits easy to
+// read out, without padding.
+var receiptEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newReceiptCode returns a random receipt code for a secret ballot, in groups of four characters
+// (e.g. "K7QW-3MZD-PX2A-YH6B").
+func newReceiptCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erreur lors de la génération du code de reçu: %w", err)
+	}
+	code := receiptEncoding.EncodeToString(b)
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// secretBallots returns the secret ballots recording a ballot of a secret poll, one per vote it
+// carries: the first is identified by the hash of the receipt code, the others by random hashes,
+// which nothing links to the first.
+func secretBallots(pollID uint, receipt string, choices []uint, weight int) ([]models.SecretBallot, error) {
+	ballots := []models.SecretBallot{{PollID: pollID, ReceiptHash: receiptHash(receipt), Choices: choices}}
+	for i := 1; i < weight; i++ {
+		key := make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("erreur lors de l'enregistrement du vote: %w", err)
+		}
+		ballots = append(ballots, models.SecretBallot{PollID: pollID, ReceiptHash: hex.EncodeToString(key), Choices: choices})
+	}
+	return ballots, nil
+}
+
+// normalizeReceipt returns a receipt code as entered by a voter, without its separators and in
+// upper case.
+func normalizeReceipt(receipt string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(receipt)))
+}
+
+// receiptHash returns the hash under which the ballot given a receipt code is stored. The codes
+// are random, so that a fast hash is enough.
+func receiptHash(receipt string) string {
+	sum := sha256.Sum256([]byte(normalizeReceipt(receipt)))
+	return hex.EncodeToString(sum[:])
+}
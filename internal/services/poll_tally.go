@@ -0,0 +1,311 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// TallyPoll counts the ballots of a poll, each ballot counting for as many votes as it carries.
+// The ballots of a ranked poll are also counted by instant-runoff and by the Schulze method.
+func TallyPoll(poll *models.Poll, ballots []models.Ballot) *models.PollTally {
+	tally := &models.PollTally{Type: poll.Type, Ballots: len(ballots)}
+	if tally.Type == "" {
+		tally.Type = models.PollSingle
+	}
+
+	options := make([]uint, len(poll.Options))
+	counts := make(map[uint]int, len(poll.Options))
+	for i, option := range poll.Options {
+		options[i] = option.ID
+	}
+	for _, ballot := range ballots {
+		weight := ballotWeight(ballot)
+		tally.Votes += weight
+		if tally.Type == models.PollRanked {
+			if len(ballot.Choices) > 0 {
+				counts[ballot.Choices[0]] += weight // First choices.
+			}
+			continue
+		}
+		for _, optionID := range ballot.Choices {
+			counts[optionID] += weight
+		}
+	}
+
+	leading := leadingOptions(options, counts, leadingCount(poll))
+	for _, option := range poll.Options {
+		optionTally := models.OptionTally{OptionID: option.ID, Text: option.Text, Votes: counts[option.ID]}
+		if tally.Votes > 0 {
+			optionTally.Percent = float64(optionTally.Votes) / float64(tally.Votes) * 100
+		}
+		optionTally.Leading = tally.Type != models.PollRanked && leading[option.ID]
+		tally.Options = append(tally.Options, optionTally)
+	}
+
+	if tally.Type == models.PollRanked {
+		tally.InstantRunoff = InstantRunoff(options, ballots)
+		tally.Schulze = Schulze(options, ballots)
+	}
+	return tally
+}
+
+// ballotWeight returns the votes a ballot carries, one for the ballots cast before votes were
+// weighted.
+func ballotWeight(ballot models.Ballot) int {
+	if ballot.Weight < 1 {
+		return 1
+	}
+	return ballot.Weight
+}
+
+// leadingCount returns the number of options leading a poll: the seats to fill of a multiple
+// choice poll, one otherwise.
+func leadingCount(poll *models.Poll) int {
+	if poll.Type == models.PollMultiple && poll.MaxChoices > 0 {
+		return poll.MaxChoices
+	}
+	return 1
+}
+
+// leadingOptions returns the options among the n having the most votes, the options tied with the
+// n-th one included. An option without votes never leads.
+func leadingOptions(options []uint, counts map[uint]int, n int) map[uint]bool {
+	sorted := append([]uint(nil), options...)
+	sort.SliceStable(sorted, func(i, j int) bool { return counts[sorted[i]] > counts[sorted[j]] })
+	leading := make(map[uint]bool)
+	if n > len(sorted) {
+		n = len(sorted)
+	}
+	if n == 0 {
+		return leading
+	}
+	threshold := counts[sorted[n-1]]
+	for _, optionID := range sorted {
+		if counts[optionID] > 0 && counts[optionID] >= threshold {
+			leading[optionID] = true
+		}
+	}
+	return leading
+}
+
+// InstantRunoff counts ranked ballots by instant-runoff. At each round, every ballot counts for
+// the first of its options still in the running; an option counted by more than half of the
+// votes of the ballots not exhausted wins, otherwise the option with the fewest votes is
+// eliminated. Options tied for the fewest votes are told apart by their votes in the previous
+// rounds, from the latest, then by the order of the options, the last one being eliminated. The
+// count ends in a tie when all the options still in the running have the same number of votes.
+func InstantRunoff(options []uint, ballots []models.Ballot) *models.InstantRunoffResult {
+	result := &models.InstantRunoffResult{}
+	if len(ballots) == 0 || len(options) == 0 {
+		return result
+	}
+
+	total := 0
+	for _, ballot := range ballots {
+		total += ballotWeight(ballot)
+	}
+	running := append([]uint(nil), options...)
+	for {
+		inRunning := make(map[uint]bool, len(running))
+		for _, optionID := range running {
+			inRunning[optionID] = true
+		}
+		round := models.RunoffRound{Number: len(result.Rounds) + 1, Votes: make(map[uint]int, len(running))}
+		for _, optionID := range running {
+			round.Votes[optionID] = 0
+		}
+		for _, ballot := range ballots {
+			counted := false
+			for _, optionID := range ballot.Choices {
+				if inRunning[optionID] {
+					round.Votes[optionID] += ballotWeight(ballot)
+					counted = true
+					break
+				}
+			}
+			if !counted {
+				round.Exhausted += ballotWeight(ballot)
+			}
+		}
+
+		active := total - round.Exhausted
+		for _, optionID := range running {
+			if round.Votes[optionID]*2 > active {
+				result.Rounds = append(result.Rounds, round)
+				result.Winners = []uint{optionID}
+				return result
+			}
+		}
+
+		fewest := round.Votes[running[0]]
+		for _, optionID := range running {
+			if round.Votes[optionID] < fewest {
+				fewest = round.Votes[optionID]
+			}
+		}
+		var last []uint
+		for _, optionID := range running {
+			if round.Votes[optionID] == fewest {
+				last = append(last, optionID)
+			}
+		}
+		if len(last) == len(running) {
+			result.Rounds = append(result.Rounds, round)
+			result.Winners = running
+			return result
+		}
+
+		eliminated := runoffLoser(last, result.Rounds)
+		round.Eliminated = []uint{eliminated}
+		result.Rounds = append(result.Rounds, round)
+
+		remaining := running[:0:0]
+		for _, optionID := range running {
+			if optionID != eliminated {
+				remaining = append(remaining, optionID)
+			}
+		}
+		running = remaining
+	}
+}
+
+// runoffLoser picks the option to eliminate among options tied for the fewest votes: the one with
+// the fewest votes in the latest previous round telling them apart, or else the last one.
+func runoffLoser(tied []uint, previous []models.RunoffRound) uint {
+	for i := len(previous) - 1; i >= 0 && len(tied) > 1; i-- {
+		votes := previous[i].Votes
+		fewest := votes[tied[0]]
+		for _, optionID := range tied {
+			if votes[optionID] < fewest {
+				fewest = votes[optionID]
+			}
+		}
+		var still []uint
+		for _, optionID := range tied {
+			if votes[optionID] == fewest {
+				still = append(still, optionID)
+			}
+		}
+		tied = still
+	}
+	return tied[len(tied)-1]
+}
+
+// Schulze counts ranked ballots by the Schulze method, a Condorcet method. Each pair of options is
+// compared: a ballot prefers an option it ranks higher, or ranks at all, to another one. The
+// options are then ranked by the strongest paths of pairwise victories between them, an option
+// beating another when its strongest path to the other is stronger than the reverse one. An
+// option beating all the others in head-to-head comparisons always wins.
+func Schulze(options []uint, ballots []models.Ballot) *models.SchulzeResult {
+	n := len(options)
+	index := make(map[uint]int, n)
+	for i, optionID := range options {
+		index[optionID] = i
+	}
+
+	result := &models.SchulzeResult{Preferences: squareMatrix(n), Strengths: squareMatrix(n)}
+	d, p := result.Preferences, result.Strengths
+	for _, ballot := range ballots {
+		rank := make([]int, n) // 0 for the options the ballot does not rank.
+		for position, optionID := range ballot.Choices {
+			if i, ok := index[optionID]; ok {
+				rank[i] = position + 1
+			}
+		}
+		for i := 0; i < n; i++ {
+			if rank[i] == 0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if i != j && (rank[j] == 0 || rank[i] < rank[j]) {
+					d[i][j] += ballotWeight(ballot)
+				}
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && d[i][j] > d[j][i] {
+				p[i][j] = d[i][j]
+			}
+		}
+	}
+	for k := 0; k < n; k++ {
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j == i || j == k {
+					continue
+				}
+				if path := min(p[i][k], p[k][j]); path > p[i][j] {
+					p[i][j] = path
+				}
+			}
+		}
+	}
+
+	// The relation "beats" is transitive: an option beating another one beats all the options the
+	// other one beats, so the options are ranked by the number of options they beat.
+	wins := make([]int, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i != j && p[i][j] > p[j][i] {
+				wins[i]++
+			}
+		}
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return wins[order[a]] > wins[order[b]] })
+	for position, i := range order {
+		if position == 0 || wins[i] != wins[order[position-1]] {
+			result.Ranking = append(result.Ranking, nil)
+		}
+		place := len(result.Ranking) - 1
+		result.Ranking[place] = append(result.Ranking[place], options[i])
+	}
+	if len(ballots) > 0 && len(result.Ranking) > 0 {
+		result.Winners = result.Ranking[0]
+	}
+	return result
+}
+
+// squareMatrix returns an n by n matrix of zeros.
+func squareMatrix(n int) [][]int {
+	matrix := make([][]int, n)
+	for i := range matrix {
+		matrix[i] = make([]int, n)
+	}
+	return matrix
+}
+
+// pollBallots groups the votes of a poll into ballots, the votes of a voter forming a ballot in
+// the order of their ranks.
+func pollBallots(votes []models.Vote) []models.Ballot {
+	byUser := make(map[uint]int)
+	var grouped [][]models.Vote
+	for _, vote := range votes {
+		i, ok := byUser[vote.UserID]
+		if !ok {
+			i = len(grouped)
+			byUser[vote.UserID] = i
+			grouped = append(grouped, nil)
+		}
+		grouped[i] = append(grouped[i], vote)
+	}
+	ballots := make([]models.Ballot, len(grouped))
+	for i, ballotVotes := range grouped {
+		sort.SliceStable(ballotVotes, func(a, b int) bool { return ballotVotes[a].Rank < ballotVotes[b].Rank })
+		ballots[i].Weight = ballotVotes[0].Weight
+		for _, vote := range ballotVotes {
+			ballots[i].Choices = append(ballots[i].Choices, vote.OptionID)
+		}
+	}
+	return ballots
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+// Options of the test polls.
+const (
+	optionA uint = iota + 1
+	optionB
+	optionC
+	optionD
+	optionE
+)
+
+// option returns an option of a test poll.
+func option(id uint, text string) models.Option {
+	return models.Option{Model: gorm.Model{ID: id}, Text: text}
+}
+
+// ballots returns weight ballots with the same choices.
+func ballots(weight int, choices ...uint) []models.Ballot {
+	result := make([]models.Ballot, weight)
+	for i := range result {
+		result[i] = models.Ballot{Choices: choices, Weight: 1}
+	}
+	return result
+}
+
+// schulzeExample is the example of the Wikipedia article on the Schulze method: 45 voters
+// ranking five options, E winning.
+var schulzeExample = [][]models.Ballot{
+	ballots(5, optionA, optionC, optionB, optionE, optionD),
+	ballots(5, optionA, optionD, optionE, optionC, optionB),
+	ballots(8, optionB, optionE, optionD, optionA, optionC),
+	ballots(3, optionC, optionA, optionB, optionE, optionD),
+	ballots(7, optionC, optionA, optionE, optionB, optionD),
+	ballots(2, optionC, optionB, optionA, optionD, optionE),
+	ballots(7, optionD, optionC, optionE, optionB, optionA),
+	ballots(8, optionE, optionB, optionA, optionD, optionC),
+}
+
+// concat returns the ballots of the groups, one after another.
+func concat(groups ...[]models.Ballot) []models.Ballot {
+	var result []models.Ballot
+	for _, group := range groups {
+		result = append(result, group...)
+	}
+	return result
+}
+
+// weighted returns a ballot for each group, carrying the votes of the group.
+func weighted(groups ...[]models.Ballot) []models.Ballot {
+	result := make([]models.Ballot, len(groups))
+	for i, group := range groups {
+		result[i] = models.Ballot{Choices: group[0].Choices, Weight: len(group)}
+	}
+	return result
+}
+
+func TestSchulze(t *testing.T) {
+	options := []uint{optionA, optionB, optionC, optionD, optionE}
+	tests := []struct {
+		name        string
+		options     []uint
+		ballots     []models.Ballot
+		preferences [][]int
+		strengths   [][]int
+		ranking     [][]uint
+		winners     []uint
+	}{
+		{
+			name:    "wikipedia example",
+			options: options,
+			ballots: concat(schulzeExample...),
+			preferences: [][]int{
+				{0, 20, 26, 30, 22},
+				{25, 0, 16, 33, 18},
+				{19, 29, 0, 17, 24},
+				{15, 12, 28, 0, 14},
+				{23, 27, 21, 31, 0},
+			},
+			strengths: [][]int{
+				{0, 28, 28, 30, 24},
+				{25, 0, 28, 33, 24},
+				{25, 29, 0, 29, 24},
+				{25, 28, 28, 0, 24},
+				{25, 28, 28, 31, 0},
+			},
+			ranking: [][]uint{{optionE}, {optionA}, {optionC}, {optionB}, {optionD}},
+			winners: []uint{optionE},
+		},
+		{
+			name:    "wikipedia example with weighted ballots",
+			options: options,
+			ballots: weighted(schulzeExample...),
+			ranking: [][]uint{{optionE}, {optionA}, {optionC}, {optionB}, {optionD}},
+			winners: []uint{optionE},
+		},
+		{
+			name:    "condorcet winner",
+			options: []uint{optionA, optionB, optionC},
+			ballots: concat(
+				ballots(4, optionB, optionA, optionC),
+				ballots(3, optionC, optionA, optionB),
+				ballots(2, optionA, optionB, optionC),
+			),
+			preferences: [][]int{
+				{0, 5, 6},
+				{4, 0, 6},
+				{3, 3, 0},
+			},
+			ranking: [][]uint{{optionA}, {optionB}, {optionC}},
+			winners: []uint{optionA},
+		},
+		{
+			name:    "unranked options come last",
+			options: []uint{optionA, optionB, optionC},
+			ballots: concat(ballots(2, optionB), ballots(1, optionA, optionC)),
+			ranking: [][]uint{{optionB}, {optionA}, {optionC}},
+			winners: []uint{optionB},
+		},
+		{
+			name:    "tie",
+			options: []uint{optionA, optionB},
+			ballots: concat(ballots(1, optionA, optionB), ballots(1, optionB, optionA)),
+			ranking: [][]uint{{optionA, optionB}},
+			winners: []uint{optionA, optionB},
+		},
+		{
+			name:    "no ballots",
+			options: []uint{optionA, optionB},
+			ranking: [][]uint{{optionA, optionB}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Schulze(tt.options, tt.ballots)
+			if tt.preferences != nil && !reflect.DeepEqual(result.Preferences, tt.preferences) {
+				t.Errorf("preferences = %v, want %v", result.Preferences, tt.preferences)
+			}
+			if tt.strengths != nil && !reflect.DeepEqual(result.Strengths, tt.strengths) {
+				t.Errorf("strengths = %v, want %v", result.Strengths, tt.strengths)
+			}
+			if !reflect.DeepEqual(result.Ranking, tt.ranking) {
+				t.Errorf("ranking = %v, want %v", result.Ranking, tt.ranking)
+			}
+			if !reflect.DeepEqual(result.Winners, tt.winners) {
+				t.Errorf("winners = %v, want %v", result.Winners, tt.winners)
+			}
+		})
+	}
+}
+
+func TestInstantRunoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []uint
+		ballots    []models.Ballot
+		votes      []map[uint]int
+		exhausted  []int
+		eliminated [][]uint
+		winners    []uint
+	}{
+		{
+			name:    "majority in the first round",
+			options: []uint{optionA, optionB, optionC},
+			ballots: concat(ballots(4, optionA), ballots(2, optionB), ballots(1, optionC)),
+			votes: []map[uint]int{
+				{optionA: 4, optionB: 2, optionC: 1},
+			},
+			exhausted:  []int{0},
+			eliminated: [][]uint{nil},
+			winners:    []uint{optionA},
+		},
+		{
+			// D and C are tied in the first round, the last one, D, is eliminated; B and C are tied
+			// in the second round, C having fewer votes in the first round is eliminated. The
+			// ballots ranking D only are exhausted from the second round, the ballot ranking D then
+			// C from the third one.
+			name:    "several rounds with exhausted ballots",
+			options: []uint{optionA, optionB, optionC, optionD},
+			ballots: concat(
+				ballots(5, optionA),
+				ballots(4, optionB, optionC),
+				ballots(3, optionC, optionB),
+				ballots(2, optionD),
+				ballots(1, optionD, optionC),
+			),
+			votes: []map[uint]int{
+				{optionA: 5, optionB: 4, optionC: 3, optionD: 3},
+				{optionA: 5, optionB: 4, optionC: 4},
+				{optionA: 5, optionB: 7},
+			},
+			exhausted:  []int{0, 2, 3},
+			eliminated: [][]uint{{optionD}, {optionC}, nil},
+			winners:    []uint{optionB},
+		},
+		{
+			// The Condorcet winner A has the fewest first choices and is eliminated first.
+			name:    "condorcet winner eliminated",
+			options: []uint{optionA, optionB, optionC},
+			ballots: concat(
+				ballots(4, optionB, optionA, optionC),
+				ballots(3, optionC, optionA, optionB),
+				ballots(2, optionA, optionB, optionC),
+			),
+			votes: []map[uint]int{
+				{optionA: 2, optionB: 4, optionC: 3},
+				{optionB: 6, optionC: 3},
+			},
+			exhausted:  []int{0, 0},
+			eliminated: [][]uint{{optionA}, nil},
+			winners:    []uint{optionB},
+		},
+		{
+			name:    "weighted ballots",
+			options: []uint{optionA, optionB, optionC},
+			ballots: []models.Ballot{
+				{Choices: []uint{optionA}, Weight: 3},
+				{Choices: []uint{optionB, optionA}, Weight: 2},
+				{Choices: []uint{optionC, optionB}, Weight: 2},
+				{Choices: []uint{optionC, optionB}}, // Cast before votes were weighted.
+			},
+			votes: []map[uint]int{
+				{optionA: 3, optionB: 2, optionC: 3},
+				{optionA: 5, optionC: 3},
+			},
+			exhausted:  []int{0, 0},
+			eliminated: [][]uint{{optionB}, nil},
+			winners:    []uint{optionA},
+		},
+		{
+			name:    "tie at the end",
+			options: []uint{optionA, optionB, optionC},
+			ballots: concat(
+				ballots(2, optionA),
+				ballots(2, optionB),
+				ballots(1, optionC),
+			),
+			votes: []map[uint]int{
+				{optionA: 2, optionB: 2, optionC: 1},
+				{optionA: 2, optionB: 2},
+			},
+			exhausted:  []int{0, 1},
+			eliminated: [][]uint{{optionC}, nil},
+			winners:    []uint{optionA, optionB},
+		},
+		{
+			name:    "no ballots",
+			options: []uint{optionA, optionB},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InstantRunoff(tt.options, tt.ballots)
+			if len(result.Rounds) != len(tt.votes) {
+				t.Fatalf("rounds = %d, want %d", len(result.Rounds), len(tt.votes))
+			}
+			for i, round := range result.Rounds {
+				if round.Number != i+1 {
+					t.Errorf("round %d: number = %d", i+1, round.Number)
+				}
+				if !reflect.DeepEqual(round.Votes, tt.votes[i]) {
+					t.Errorf("round %d: votes = %v, want %v", i+1, round.Votes, tt.votes[i])
+				}
+				if round.Exhausted != tt.exhausted[i] {
+					t.Errorf("round %d: exhausted = %d, want %d", i+1, round.Exhausted, tt.exhausted[i])
+				}
+				if !reflect.DeepEqual(round.Eliminated, tt.eliminated[i]) {
+					t.Errorf("round %d: eliminated = %v, want %v", i+1, round.Eliminated, tt.eliminated[i])
+				}
+			}
+			if !reflect.DeepEqual(result.Winners, tt.winners) {
+				t.Errorf("winners = %v, want %v", result.Winners, tt.winners)
+			}
+		})
+	}
+}
+
+func TestRunoffLoser(t *testing.T) {
+	tests := []struct {
+		name     string
+		tied     []uint
+		previous []models.RunoffRound
+		want     uint
+	}{
+		{
+			name: "no previous round",
+			tied: []uint{optionA, optionB, optionC},
+			want: optionC,
+		},
+		{
+			name:     "latest round",
+			tied:     []uint{optionA, optionB},
+			previous: []models.RunoffRound{{Votes: map[uint]int{optionA: 1, optionB: 4}}, {Votes: map[uint]int{optionA: 3, optionB: 2}}},
+			want:     optionB,
+		},
+		{
+			name:     "earlier round when the latest one ties",
+			tied:     []uint{optionA, optionB},
+			previous: []models.RunoffRound{{Votes: map[uint]int{optionA: 2, optionB: 5}}, {Votes: map[uint]int{optionA: 3, optionB: 3}}},
+			want:     optionA,
+		},
+		{
+			name: "rounds narrowing the tie",
+			tied: []uint{optionA, optionB, optionC},
+			previous: []models.RunoffRound{
+				{Votes: map[uint]int{optionA: 4, optionB: 1, optionC: 2}},
+				{Votes: map[uint]int{optionA: 5, optionB: 3, optionC: 3}},
+			},
+			want: optionB,
+		},
+		{
+			name: "tied in every round",
+			tied: []uint{optionA, optionB, optionC},
+			previous: []models.RunoffRound{
+				{Votes: map[uint]int{optionA: 1, optionB: 1, optionC: 1}},
+				{Votes: map[uint]int{optionA: 2, optionB: 2, optionC: 2}},
+			},
+			want: optionC,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runoffLoser(tt.tied, tt.previous); got != tt.want {
+				t.Errorf("runoffLoser() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTallyPollWeightedBallots(t *testing.T) {
+	poll := &models.Poll{
+		Type:    models.PollSingle,
+		Options: []models.Option{option(optionA, "Pour"), option(optionB, "Contre")},
+	}
+	tally := TallyPoll(poll, []models.Ballot{
+		{Choices: []uint{optionA}, Weight: 3},
+		{Choices: []uint{optionB}, Weight: 1},
+		{Choices: []uint{optionB}}, // Cast before votes were weighted.
+	})
+
+	if tally.Ballots != 3 || tally.Votes != 5 {
+		t.Fatalf("ballots = %d, votes = %d, want 3 and 5", tally.Ballots, tally.Votes)
+	}
+	want := []models.OptionTally{
+		{OptionID: optionA, Text: "Pour", Votes: 3, Percent: 60, Leading: true},
+		{OptionID: optionB, Text: "Contre", Votes: 2, Percent: 40},
+	}
+	if !reflect.DeepEqual(tally.Options, want) {
+		t.Errorf("options = %+v, want %+v", tally.Options, want)
+	}
+}
+
+func TestValidateBallot(t *testing.T) {
+	options := []models.Option{option(optionA, "A"), option(optionB, "B"), option(optionC, "C")}
+	single := &models.Poll{Type: models.PollSingle, Options: options}
+	multiple := &models.Poll{Type: models.PollMultiple, MinChoices: 2, MaxChoices: 2, Options: options}
+	approval := &models.Poll{Type: models.PollApproval, Options: options}
+	ranked := &models.Poll{Type: models.PollRanked, Options: options}
+
+	tests := []struct {
+		name    string
+		poll    *models.Poll
+		choices []uint
+		wantErr string
+	}{
+		{name: "single", poll: single, choices: []uint{optionB}},
+		{name: "single without type", poll: &models.Poll{Options: options}, choices: []uint{optionA}},
+		{name: "single without choice", poll: single, wantErr: "veuillez choisir une option"},
+		{name: "single with two choices", poll: single, choices: []uint{optionA, optionB}, wantErr: "une seule option peut être choisie"},
+		{name: "multiple", poll: multiple, choices: []uint{optionA, optionC}},
+		{name: "multiple under the minimum", poll: multiple, choices: []uint{optionA}, wantErr: "veuillez choisir au moins 2 options"},
+		{name: "multiple over the maximum", poll: multiple, choices: []uint{optionA, optionB, optionC}, wantErr: "vous pouvez choisir au plus 2 options"},
+		{name: "approval of every option", poll: approval, choices: []uint{optionA, optionB, optionC}},
+		{name: "approval of an option twice", poll: approval, choices: []uint{optionA, optionA}, wantErr: "une option ne peut être choisie qu'une fois"},
+		{name: "ranked", poll: ranked, choices: []uint{optionC, optionA}},
+		{name: "ranked without choice", poll: ranked, wantErr: "veuillez classer au moins une option"},
+		{name: "ranked duplicate", poll: ranked, choices: []uint{optionC, optionA, optionC}, wantErr: "une option ne peut être choisie qu'une fois"},
+		{name: "option of another poll", poll: approval, choices: []uint{optionA, optionE}, wantErr: "l'option de vote spécifiée n'appartient pas à ce sondage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBallot(tt.poll, tt.choices)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateBallot() = %v, want no error", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Errorf("validateBallot() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
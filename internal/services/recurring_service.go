@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+)
+
+// RecurringService encapsulates the business logic for recurring transactions: templates,
+// the scheduler recording their occurrences, and the forecasts computed from them.
+// Occurrences are recorded through the FinanceService, so that they are validated and
+// journalized like any other transaction.
+type RecurringService struct {
+	recurringRepo   repositories.RecurringRepository
+	transactionRepo repositories.TransactionRepository
+	financeService  *FinanceService
+	periodService   *FiscalPeriodService
+}
+
+// NewRecurringService creates a new instance of RecurringService.
+// It takes a RecurringRepository, a TransactionRepository, a FinanceService and a
+// FiscalPeriodService as dependencies, adhering to the dependency inversion principle.
+func NewRecurringService(recurringRepo repositories.RecurringRepository, transactionRepo repositories.TransactionRepository, financeService *FinanceService, periodService *FiscalPeriodService) *RecurringService {
+	return &RecurringService{recurringRepo: recurringRepo, transactionRepo: transactionRepo, financeService: financeService, periodService: periodService}
+}
+
+// CreateRecurring validates and saves a new recurring transaction template.
+// Its first occurrence falls due on the start date.
+func (s *RecurringService) CreateRecurring(recurring *models.RecurringTransaction) error {
+	if err := s.validateRecurring(recurring, nil); err != nil {
+		return err
+	}
+	recurring.Occurrences = 0
+	recurring.NextDueDate = recurring.StartDate
+	return s.recurringRepo.CreateRecurring(recurring)
+}
+
+// GetRecurringByID retrieves a recurring transaction template by its ID.
+func (s *RecurringService) GetRecurringByID(id uint) (*models.RecurringTransaction, error) {
+	return s.recurringRepo.FindRecurringByID(id)
+}
+
+// GetRecurringByUserID retrieves the recurring transaction templates of a user.
+func (s *RecurringService) GetRecurringByUserID(userID uint) ([]models.RecurringTransaction, error) {
+	return s.recurringRepo.FindRecurringByUserID(userID)
+}
+
+// UpdateRecurring validates and saves the changes made to a template. When the schedule changes,
+// the next occurrence becomes the first one of the new schedule after the last recorded occurrence,
+// so that no due date is recorded twice.
+func (s *RecurringService) UpdateRecurring(recurring *models.RecurringTransaction) error {
+	current, err := s.recurringRepo.FindRecurringByID(recurring.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.validateRecurring(recurring, current); err != nil {
+		return err
+	}
+	recurring.Occurrences, recurring.NextDueDate = current.Occurrences, current.NextDueDate
+	if current.Frequency != recurring.Frequency || !current.StartDate.Equal(recurring.StartDate) {
+		recurring.Occurrences = 0
+		if current.Occurrences > 0 {
+			lastRecorded := current.Frequency.Occurrence(current.StartDate, current.Occurrences-1)
+			for !recurring.Frequency.Occurrence(recurring.StartDate, recurring.Occurrences).After(lastRecorded) {
+				recurring.Occurrences++
+			}
+		}
+		recurring.NextDueDate = recurring.Frequency.Occurrence(recurring.StartDate, recurring.Occurrences)
+	}
+	return s.recurringRepo.UpdateRecurring(recurring)
+}
+
+// DeleteRecurring deletes a template. The occurrences already recorded are kept.
+func (s *RecurringService) DeleteRecurring(id uint) error {
+	return s.recurringRepo.DeleteRecurring(id)
+}
+
+// MaterializeDue records, for all users, the occurrences of active templates due on or before
+// the given date, catching up on missed due dates. It returns the number of transactions recorded.
+// An occurrence that was already recorded is skipped, so that running the job twice is harmless,
+// as is an occurrence falling due within a closed fiscal year, which can no longer be recorded.
+func (s *RecurringService) MaterializeDue(asOf time.Time) (int, error) {
+	due, err := s.recurringRepo.FindDueRecurring(asOf)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := range due {
+		recurring := &due[i]
+		var closed []string // Due dates skipped for falling within a closed fiscal year.
+		for !recurring.NextDueDate.After(asOf) && !recurring.Finished() {
+			recorded, err := s.recordOccurrence(recurring)
+			if errors.Is(err, errClosedPeriod) {
+				closed = append(closed, recurring.NextDueDate.Format("02/01/2006"))
+			} else if err != nil {
+				log.Printf("ERREUR: Échec de l'enregistrement de l'échéance du %s de la transaction récurrente %d: %v", recurring.NextDueDate.Format("02/01/2006"), recurring.ID, err)
+				break
+			}
+			if recorded {
+				count++
+			}
+			recurring.Occurrences++
+			recurring.NextDueDate = recurring.Frequency.Occurrence(recurring.StartDate, recurring.Occurrences)
+			if err := s.recurringRepo.UpdateRecurring(recurring); err != nil {
+				return count, err
+			}
+		}
+		if len(closed) > 0 {
+			log.Printf("AVERTISSEMENT: Échéances de la transaction récurrente %d ignorées, dans un exercice clôturé: %s", recurring.ID, strings.Join(closed, ", "))
+		}
+	}
+	return count, nil
+}
+
+// RunScheduler records the due occurrences immediately, then at every interval until the context
+// is cancelled. It is meant to be run in its own goroutine.
+func (s *RecurringService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if count, err := s.MaterializeDue(time.Now()); err != nil {
+			log.Printf("ERREUR: Échec de l'enregistrement des transactions récurrentes: %v", err)
+		} else if count > 0 {
+			log.Printf("Transactions récurrentes enregistrées: %d", count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetUpcoming returns the occurrences of a user's active templates not yet recorded and falling due
+// before the given date, in chronological order. Overdue occurrences the scheduler has not recorded
+// yet are included.
+func (s *RecurringService) GetUpcoming(userID uint, until time.Time) ([]models.UpcomingOccurrence, error) {
+	templates, err := s.recurringRepo.FindRecurringByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	var upcoming []models.UpcomingOccurrence
+	for i := range templates {
+		recurring := &templates[i]
+		if !recurring.Active {
+			continue
+		}
+		// Convert the amount to the base currency once per template.
+		probe := s.occurrence(recurring, recurring.NextDueDate)
+		if err := s.financeService.ValidateTransaction(probe); err != nil {
+			log.Printf("ERREUR: Transaction récurrente %d invalide: %v", recurring.ID, err)
+			continue
+		}
+		for n := recurring.Occurrences; ; n++ {
+			date := recurring.Frequency.Occurrence(recurring.StartDate, n)
+			if !date.Before(until) || (recurring.EndDate != nil && date.After(*recurring.EndDate)) {
+				break
+			}
+			upcoming = append(upcoming, models.UpcomingOccurrence{
+				RecurringID: recurring.ID,
+				Date:        date,
+				Description: recurring.Description,
+				Type:        recurring.Type,
+				Amount:      recurring.Amount,
+				BaseAmount:  probe.BaseAmount,
+			})
+		}
+	}
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].Date.Before(upcoming[j].Date) })
+	return upcoming, nil
+}
+
+// GetForecast projects the balance of a user's association over the coming months, starting from
+// the current balance of the posted transactions and adding the upcoming recurring occurrences.
+// The first month is the current one.
+func (s *RecurringService) GetForecast(userID uint, months int) ([]models.ForecastMonth, error) {
+	income, err := s.financeService.GetTotalIncome(userID)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.financeService.GetTotalExpenses(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	firstMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	upcoming, err := s.GetUpcoming(userID, firstMonth.AddDate(0, months, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := make([]models.ForecastMonth, months)
+	balance := income.Amount - expenses.Amount
+	next := 0
+	for i := range forecast {
+		month := firstMonth.AddDate(0, i, 0)
+		forecast[i].Month = month
+		// Overdue occurrences are counted in the first month.
+		for ; next < len(upcoming) && upcoming[next].Date.Before(month.AddDate(0, 1, 0)); next++ {
+			if upcoming[next].Type == models.TypeIncome {
+				forecast[i].Income += upcoming[next].BaseAmount
+			} else {
+				forecast[i].Expenses += upcoming[next].BaseAmount
+			}
+		}
+		balance += forecast[i].Income - forecast[i].Expenses
+		forecast[i].Balance = balance
+	}
+	return forecast, nil
+}
+
+// errClosedPeriod is returned by recordOccurrence for an occurrence falling due within a closed
+// fiscal year.
+var errClosedPeriod = errors.New("échéance dans un exercice clôturé")
+
+// recordOccurrence records the occurrence of a template due on its next due date, unless it was
+// already recorded. It reports whether a transaction was created, and returns errClosedPeriod if
+// the due date falls within a closed fiscal year.
+func (s *RecurringService) recordOccurrence(recurring *models.RecurringTransaction) (bool, error) {
+	exists, err := s.transactionRepo.RecurringOccurrenceExists(recurring.ID, recurring.NextDueDate)
+	if err != nil || exists {
+		return false, err
+	}
+	closed, err := s.periodService.IsClosed(recurring.UserID, recurring.NextDueDate)
+	if err != nil {
+		return false, err
+	}
+	if closed {
+		return false, errClosedPeriod
+	}
+	if err := s.financeService.CreateTransaction(s.occurrence(recurring, recurring.NextDueDate)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// occurrence builds the transaction of a template falling due on the given date.
+func (s *RecurringService) occurrence(recurring *models.RecurringTransaction, date time.Time) *models.Transaction {
+	recurringID := recurring.ID
+	return &models.Transaction{
+		Amount:       recurring.Amount,
+		ExchangeRate: recurring.ExchangeRate,
+		Type:         recurring.Type,
+		Description:  recurring.Description,
+		Date:         date,
+		AccountCode:  recurring.AccountCode,
+		UserID:       recurring.UserID,
+		RecurringID:  &recurringID,
+	}
+}
+
+// validateRecurring performs business logic validation on a template, given its current version
+// (nil for a new template). The amount, type, category and exchange rate are checked by validating
+// its first occurrence as a transaction. A new start date cannot fall within a closed fiscal year.
+func (s *RecurringService) validateRecurring(recurring, current *models.RecurringTransaction) error {
+	recurring.Description = strings.TrimSpace(recurring.Description)
+	valid := false
+	for _, frequency := range models.Frequencies {
+		if recurring.Frequency == frequency {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("fréquence invalide")
+	}
+	if recurring.StartDate.IsZero() {
+		return fmt.Errorf("la date de début est requise")
+	}
+	if recurring.EndDate != nil && recurring.EndDate.Before(recurring.StartDate) {
+		return fmt.Errorf("la date de fin doit être postérieure à la date de début")
+	}
+	if current == nil || !current.StartDate.Equal(recurring.StartDate) {
+		if err := s.periodService.EnsureOpen(recurring.UserID, recurring.StartDate); err != nil {
+			return err
+		}
+	}
+
+	probe := s.occurrence(recurring, recurring.StartDate)
+	if err := s.financeService.ValidateTransaction(probe); err != nil {
+		return err
+	}
+	recurring.Amount = probe.Amount
+	recurring.ExchangeRate = probe.ExchangeRate
+	return nil
+}
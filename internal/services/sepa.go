@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+)
+
+// ibanLengths gives the length of the IBANs of the countries of the SEPA scheme.
+var ibanLengths = map[string]int{
+	"AD": 24, "AT": 20, "BE": 16, "BG": 22, "CH": 21, "CY": 28, "CZ": 24, "DE": 22, "DK": 18,
+	"EE": 20, "ES": 24, "FI": 18, "FR": 27, "GB": 22, "GI": 23, "GR": 27, "HR": 21, "HU": 28,
+	"IE": 22, "IS": 26, "IT": 27, "LI": 21, "LT": 20, "LU": 20, "LV": 21, "MC": 27, "MT": 31,
+	"NL": 18, "NO": 15, "PL": 28, "PT": 25, "RO": 24, "SE": 24, "SI": 19, "SK": 24, "SM": 27,
+	"VA": 22,
+}
+
+var (
+	bicPattern       = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	creditorIDFormat = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{3}[A-Z0-9]{1,28}$`)
+	sepaIDPattern    = regexp.MustCompile(`^[A-Za-z0-9/?:().,'+ -]{1,35}$`)
+)
+
+// sepaAccents transliterates the accented letters of Western European languages, which are outside
+// the character set allowed in SEPA files.
+var sepaAccents = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a", "å", "a", "æ", "ae", "ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e", "í", "i", "ì", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o", "œ", "oe", "ß", "ss",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y",
+	"À", "A", "Â", "A", "Ä", "A", "Á", "A", "Ã", "A", "Å", "A", "Æ", "AE", "Ç", "C",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E", "Í", "I", "Ì", "I", "Î", "I", "Ï", "I", "Ñ", "N",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Ö", "O", "Õ", "O", "Ø", "O", "Œ", "OE",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U", "Ý", "Y",
+	"’", "'", "–", "-", "—", "-",
+)
+
+// NormalizeIBAN removes the spaces of an IBAN and puts it in upper case.
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(iban), ""))
+}
+
+// ValidateIBAN checks the country, the length and the ISO 7064 MOD 97-10 check digits of a
+// normalized IBAN.
+func ValidateIBAN(iban string) error {
+	if len(iban) < 5 {
+		return fmt.Errorf("IBAN invalide")
+	}
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return fmt.Errorf("IBAN invalide : le pays %s ne fait pas partie de la zone SEPA", iban[:2])
+	}
+	if len(iban) != length {
+		return fmt.Errorf("IBAN invalide : un IBAN %s compte %d caractères", iban[:2], length)
+	}
+	if mod97(iban[4:]+iban[:4]) != 1 {
+		return fmt.Errorf("IBAN invalide : la clé de contrôle ne correspond pas")
+	}
+	return nil
+}
+
+// ValidateBIC checks the format of a BIC (ISO 9362), 8 or 11 characters in upper case.
+func ValidateBIC(bic string) error {
+	if !bicPattern.MatchString(bic) {
+		return fmt.Errorf("BIC invalide")
+	}
+	return nil
+}
+
+// ValidateCreditorID checks a SEPA creditor identifier (ICS in France): its check digits are
+// computed like those of an IBAN on the national identifier, ignoring the creditor business code.
+func ValidateCreditorID(id string) error {
+	if !creditorIDFormat.MatchString(id) || len(id) > 35 {
+		return fmt.Errorf("identifiant créancier SEPA invalide")
+	}
+	if mod97(id[7:]+id[:4]) != 1 {
+		return fmt.Errorf("identifiant créancier SEPA invalide : la clé de contrôle ne correspond pas")
+	}
+	return nil
+}
+
+// ValidateMandateReference checks a unique mandate reference: at most 35 characters of the SEPA
+// character set.
+func ValidateMandateReference(reference string) error {
+	if !sepaIDPattern.MatchString(reference) {
+		return fmt.Errorf("référence de mandat invalide : 35 caractères au plus, lettres non accentuées, chiffres et / - ? : ( ) . , ' +")
+	}
+	return nil
+}
+
+// mod97 returns the remainder of the division by 97 of an alphanumeric string in which each
+// letter stands for a two-digit number (A = 10, ..., Z = 35). It returns -1 on any other character.
+func mod97(value string) int {
+	remainder := 0
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			remainder = (remainder*10 + int(r-'0')) % 97
+		case r >= 'A' && r <= 'Z':
+			remainder = (remainder*100 + int(r-'A') + 10) % 97
+		default:
+			return -1
+		}
+	}
+	return remainder
+}
+
+// sepaText converts free text to the restricted character set of SEPA files and truncates it.
+func sepaText(value string, maxLength int) string {
+	value = sepaAccents.Replace(value)
+	var b strings.Builder
+	for _, r := range value {
+		if r < 128 && sepaIDPattern.MatchString(string(r)) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+	text := strings.Join(strings.Fields(b.String()), " ")
+	if len(text) > maxLength {
+		text = strings.TrimSpace(text[:maxLength])
+	}
+	return text
+}
+
+// pain008Document is the root of an ISO 20022 pain.008.001.02 customer direct debit initiation.
+type pain008Document struct {
+	XMLName    xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.008.001.02 Document"`
+	Initiation struct {
+		GroupHeader struct {
+			MessageID     string `xml:"MsgId"`
+			CreationTime  string `xml:"CreDtTm"`
+			Count         int    `xml:"NbOfTxs"`
+			ControlSum    string `xml:"CtrlSum"`
+			InitiatorName string `xml:"InitgPty>Nm"`
+		} `xml:"GrpHdr"`
+		PaymentInfos []pain008PaymentInfo `xml:"PmtInf"`
+	} `xml:"CstmrDrctDbtInitn"`
+}
+
+// pain008PaymentInfo groups the debits of a batch sharing a sequence type.
+type pain008PaymentInfo struct {
+	ID              string               `xml:"PmtInfId"`
+	Method          string               `xml:"PmtMtd"`
+	BatchBooking    bool                 `xml:"BtchBookg"`
+	Count           int                  `xml:"NbOfTxs"`
+	ControlSum      string               `xml:"CtrlSum"`
+	ServiceLevel    string               `xml:"PmtTpInf>SvcLvl>Cd"`
+	LocalInstrument string               `xml:"PmtTpInf>LclInstrm>Cd"`
+	SequenceType    string               `xml:"PmtTpInf>SeqTp"`
+	CollectionDate  string               `xml:"ReqdColltnDt"`
+	CreditorName    string               `xml:"Cdtr>Nm"`
+	CreditorIBAN    string               `xml:"CdtrAcct>Id>IBAN"`
+	CreditorAgent   pain008Agent         `xml:"CdtrAgt>FinInstnId"`
+	ChargeBearer    string               `xml:"ChrgBr"`
+	CreditorID      string               `xml:"CdtrSchmeId>Id>PrvtId>Othr>Id"`
+	CreditorScheme  string               `xml:"CdtrSchmeId>Id>PrvtId>Othr>SchmeNm>Prtry"`
+	Transactions    []pain008Transaction `xml:"DrctDbtTxInf"`
+}
+
+// pain008Agent identifies a bank by its BIC, or states that the BIC is not provided.
+type pain008Agent struct {
+	BIC   string `xml:"BIC,omitempty"`
+	Other string `xml:"Othr>Id,omitempty"`
+}
+
+// pain008Transaction is a single debit of a member's account.
+type pain008Transaction struct {
+	EndToEndID string `xml:"PmtId>EndToEndId"`
+	Amount     struct {
+		Currency string `xml:"Ccy,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"InstdAmt"`
+	MandateID     string       `xml:"DrctDbtTx>MndtRltdInf>MndtId"`
+	SignatureDate string       `xml:"DrctDbtTx>MndtRltdInf>DtOfSgntr"`
+	DebtorAgent   pain008Agent `xml:"DbtrAgt>FinInstnId"`
+	DebtorName    string       `xml:"Dbtr>Nm"`
+	DebtorIBAN    string       `xml:"DbtrAcct>Id>IBAN"`
+	Remittance    string       `xml:"RmtInf>Ustrd"`
+}
+
+// BuildPain008 generates the pain.008.001.02 file of a direct debit batch, with one payment
+// information block per sequence type. Rejected items are left out.
+func BuildPain008(batch *models.DirectDebitBatch, settings *models.AssociationSettings, mandates map[uint]models.SEPAMandate, debtorNames map[uint]string, now time.Time) ([]byte, error) {
+	var doc pain008Document
+	header := &doc.Initiation.GroupHeader
+	header.MessageID = batch.MessageID
+	header.CreationTime = now.Format("2006-01-02T15:04:05")
+	header.InitiatorName = sepaText(settings.LegalName, 70)
+
+	var total int64
+	blocks := map[models.SEPASequenceType]*pain008PaymentInfo{}
+	blockTotals := map[models.SEPASequenceType]int64{}
+	var order []models.SEPASequenceType
+	for _, item := range batch.Items {
+		if item.Status == models.DebitItemRejected {
+			continue
+		}
+		mandate, ok := mandates[item.MandateID]
+		if !ok {
+			return nil, fmt.Errorf("mandat %d introuvable", item.MandateID)
+		}
+		block, ok := blocks[item.SequenceType]
+		if !ok {
+			block = &pain008PaymentInfo{
+				ID:              sepaText(batch.MessageID+"-"+string(item.SequenceType), 35),
+				Method:          "DD",
+				BatchBooking:    true,
+				ServiceLevel:    "SEPA",
+				LocalInstrument: "CORE",
+				SequenceType:    string(item.SequenceType),
+				CollectionDate:  batch.CollectionDate.Format("2006-01-02"),
+				CreditorName:    sepaText(settings.LegalName, 70),
+				CreditorIBAN:    settings.SEPAIBAN,
+				CreditorAgent:   agent(settings.SEPABIC),
+				ChargeBearer:    "SLEV",
+				CreditorID:      settings.SEPACreditorID,
+				CreditorScheme:  "SEPA",
+			}
+			blocks[item.SequenceType] = block
+			order = append(order, item.SequenceType)
+		}
+
+		transaction := pain008Transaction{
+			EndToEndID:    item.EndToEndID,
+			MandateID:     mandate.Reference,
+			SignatureDate: mandate.SignatureDate.Format("2006-01-02"),
+			DebtorAgent:   agent(mandate.BIC),
+			DebtorName:    sepaText(debtorNames[item.MemberID], 70),
+			DebtorIBAN:    mandate.IBAN,
+			Remittance:    sepaText(batch.Description, 140),
+		}
+		transaction.Amount.Currency = item.Amount.Currency
+		transaction.Amount.Value = item.Amount.Decimal()
+		block.Transactions = append(block.Transactions, transaction)
+		block.Count++
+		blockTotals[item.SequenceType] += item.Amount.Amount
+		total += item.Amount.Amount
+		header.Count++
+	}
+	if header.Count == 0 {
+		return nil, fmt.Errorf("aucun prélèvement à transmettre")
+	}
+	header.ControlSum = models.Money{Amount: total, Currency: models.SEPACurrency}.Decimal()
+	for _, sequenceType := range order {
+		block := blocks[sequenceType]
+		block.ControlSum = models.Money{Amount: blockTotals[sequenceType], Currency: models.SEPACurrency}.Decimal()
+		doc.Initiation.PaymentInfos = append(doc.Initiation.PaymentInfos, *block)
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// agent returns the bank identification of a BIC, "NOTPROVIDED" being allowed for SEPA debtors
+// whose BIC is unknown.
+func agent(bic string) pain008Agent {
+	if bic == "" {
+		return pain008Agent{Other: "NOTPROVIDED"}
+	}
+	return pain008Agent{BIC: bic}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"gorm.io/gorm"
+)
+
+// SettingsService encapsulates the business logic for the settings of an association.
+type SettingsService struct {
+	settingsRepo    repositories.SettingsRepository
+	transactionRepo repositories.TransactionRepository
+}
+
+// NewSettingsService creates a new instance of SettingsService.
+// It takes a SettingsRepository and a TransactionRepository (used to protect settings that
+// existing data depends on) as dependencies, adhering to the dependency inversion principle.
+func NewSettingsService(settingsRepo repositories.SettingsRepository, transactionRepo repositories.TransactionRepository) *SettingsService {
+	return &SettingsService{settingsRepo: settingsRepo, transactionRepo: transactionRepo}
+}
+
+// GetSettings returns the settings of a user's association, falling back to the defaults
+// when the association has not saved any settings yet.
+func (s *SettingsService) GetSettings(userID uint) (*models.AssociationSettings, error) {
+	settings, err := s.settingsRepo.FindSettingsByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.AssociationSettings{UserID: userID, BaseCurrency: models.DefaultCurrency, FiscalYearStartMonth: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if settings.BaseCurrency == "" {
+		settings.BaseCurrency = models.DefaultCurrency
+	}
+	if settings.FiscalYearStartMonth == 0 {
+		settings.FiscalYearStartMonth = 1
+	}
+	return settings, nil
+}
+
+// GetBaseCurrency returns the currency the accounts of a user's association are kept in.
+func (s *SettingsService) GetBaseCurrency(userID uint) (string, error) {
+	settings, err := s.GetSettings(userID)
+	if err != nil {
+		return "", err
+	}
+	return settings.BaseCurrency, nil
+}
+
+// GetTreasurerAssociations returns the owners of the associations whose expenses must be approved
+// by the user with the given email.
+func (s *SettingsService) GetTreasurerAssociations(email string) ([]uint, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, nil
+	}
+	return s.settingsRepo.FindUserIDsByTreasurerEmail(email)
+}
+
+// UpdateSettings validates and saves the settings of an association.
+// The base currency can only be changed while no transaction has been recorded,
+// since every stored base amount and journal entry is expressed in it.
+func (s *SettingsService) UpdateSettings(settings *models.AssociationSettings) error {
+	current, err := s.GetSettings(settings.UserID)
+	if err != nil {
+		return err
+	}
+
+	settings.BaseCurrency = strings.ToUpper(strings.TrimSpace(settings.BaseCurrency))
+	decimals, ok := models.CurrencyDecimals(settings.BaseCurrency)
+	if !ok {
+		return fmt.Errorf("devise non supportée: %q", settings.BaseCurrency)
+	}
+	// Journal amounts are kept in cents; see FormatCents.
+	if decimals != 2 {
+		return fmt.Errorf("la devise de référence doit avoir deux décimales")
+	}
+	if settings.ReceiptThreshold < 0 {
+		return fmt.Errorf("le seuil de justificatif ne peut pas être négatif")
+	}
+	if settings.ApprovalThreshold < 0 {
+		return fmt.Errorf("le seuil d'approbation ne peut pas être négatif")
+	}
+	settings.TreasurerEmail = strings.ToLower(strings.TrimSpace(settings.TreasurerEmail))
+	if settings.TreasurerEmail != "" && !strings.Contains(settings.TreasurerEmail, "@") {
+		return fmt.Errorf("adresse e-mail du trésorier invalide")
+	}
+	if settings.FiscalYearStartMonth < 1 || settings.FiscalYearStartMonth > 12 {
+		return fmt.Errorf("le mois de début d'exercice doit être compris entre 1 et 12")
+	}
+	for _, field := range []*string{&settings.LegalName, &settings.Address, &settings.Purpose, &settings.RegistrationNumber, &settings.DonationEligibility, &settings.SignatoryName} {
+		*field = strings.TrimSpace(*field)
+	}
+	if err := s.validateSEPACreditor(settings); err != nil {
+		return err
+	}
+	if settings.BaseCurrency != current.BaseCurrency {
+		count, err := s.transactionRepo.CountTransactions(settings.UserID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("la devise de référence ne peut plus être modifiée une fois des transactions enregistrées")
+		}
+	}
+
+	settings.ID = current.ID
+	settings.CreatedAt = current.CreatedAt
+	return s.settingsRepo.SaveSettings(settings)
+}
+
+// validateSEPACreditor normalizes and checks the SEPA creditor details, which are either all empty
+// or complete enough to collect direct debits (the BIC being optional).
+func (s *SettingsService) validateSEPACreditor(settings *models.AssociationSettings) error {
+	settings.SEPACreditorID = NormalizeIBAN(settings.SEPACreditorID)
+	settings.SEPAIBAN = NormalizeIBAN(settings.SEPAIBAN)
+	settings.SEPABIC = NormalizeIBAN(settings.SEPABIC)
+	if settings.SEPACreditorID == "" && settings.SEPAIBAN == "" && settings.SEPABIC == "" {
+		return nil
+	}
+	if settings.SEPACreditorID == "" || settings.SEPAIBAN == "" {
+		return fmt.Errorf("l'identifiant créancier SEPA et l'IBAN de l'association sont requis pour les prélèvements")
+	}
+	if err := ValidateCreditorID(settings.SEPACreditorID); err != nil {
+		return err
+	}
+	if err := ValidateIBAN(settings.SEPAIBAN); err != nil {
+		return err
+	}
+	if settings.SEPABIC != "" {
+		return ValidateBIC(settings.SEPABIC)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/pdf"
+)
+
+// Size of the box the signatures are reproduced in on the certificate, in points.
+const (
+	signatureBoxWidth  = 200.0
+	signatureBoxHeight = 60.0
+)
+
+// signatureTimeFormat is the format of the times of the certificate, with their time zone.
+const signatureTimeFormat = "02/01/2006 à 15:04:05 MST"
+
+// renderSignatureCertificate renders the audit certificate of a completed signature request: the
+// signed file with its hash, then each signer with the time, address and browser it signed from
+// and its signature.
+func renderSignatureCertificate(request *models.DocumentSignatureRequest, association string) *pdf.Document {
+	w := &pdfWriter{doc: pdf.New("Certificat de signature - " + request.DocumentName)}
+	w.newPage()
+
+	w.paragraph(0, 16, true, "Certificat de signature électronique")
+	w.paragraph(0, 10, false, association)
+	w.space(6)
+	w.rule()
+
+	w.paragraph(0, 12, true, "Document signé")
+	w.field("Nom :", request.DocumentName)
+	w.field("Version :", fmt.Sprintf("%d", request.Version))
+	w.field("Taille :", FormatFileSize(request.FileSize))
+	w.field("Type :", request.MimeType)
+	w.field("Empreinte SHA-256 :", request.DocumentHash)
+	w.space(6)
+
+	w.paragraph(0, 12, true, "Demande de signature")
+	w.field("Référence :", fmt.Sprintf("%d", request.ID))
+	w.field("Envoyée le :", request.CreatedAt.Format(signatureTimeFormat))
+	w.field("Par :", request.CreatedBy)
+	if request.Message != "" {
+		w.field("Message :", request.Message)
+	}
+	if request.CompletedAt != nil {
+		w.field("Signée par tous le :", request.CompletedAt.Format(signatureTimeFormat))
+	}
+	w.space(6)
+
+	for i, signer := range request.Signers {
+		w.ensure(220)
+		w.rule()
+		w.paragraph(0, 12, true, fmt.Sprintf("Signataire %d : %s", i+1, signer.Name))
+		w.field("Email :", signer.Email)
+		if signer.EmailSentAt != nil {
+			w.field("Lien envoyé le :", signer.EmailSentAt.Format(signatureTimeFormat))
+		}
+		if signer.ViewedAt != nil {
+			w.field("Consulté le :", signer.ViewedAt.Format(signatureTimeFormat))
+		}
+		if signer.SignedAt != nil {
+			w.field("Signé le :", signer.SignedAt.Format(signatureTimeFormat))
+		}
+		w.field("Adresse IP :", signer.IPAddress)
+		w.field("Navigateur :", signer.UserAgent)
+		verified := "identique à l'empreinte du document"
+		if signer.DocumentHash != request.DocumentHash {
+			verified = "DIFFÉRENTE de l'empreinte du document"
+		}
+		w.field("Empreinte vérifiée :", signer.DocumentHash+" ("+verified+")")
+		w.field("Nom saisi :", signer.SignatureText)
+		w.field("Signature :", string(signer.Method))
+		drawSignature(w, signer)
+	}
+
+	w.rule()
+	w.paragraph(0, 8, false, "Chaque signataire a reçu un lien personnel à son adresse email, a consulté le document, "+
+		"saisi son nom et accepté de le signer électroniquement. L'empreinte SHA-256 du fichier a été calculée à l'envoi de "+
+		"la demande puis vérifiée à chaque signature : elle permet de s'assurer que le document signé est identique au "+
+		"document présenté aux signataires.")
+	return w.doc
+}
+
+// drawSignature reproduces a signature in a box under the cursor: the strokes drawn by the signer,
+// scaled to the box, or its typed name.
+func drawSignature(w *pdfWriter, signer models.DocumentSigner) {
+	w.ensure(signatureBoxHeight + 10)
+	w.space(signatureBoxHeight + 6)
+	x, y := pdfMarginLeft, w.y
+	w.page.Rect(x, y, signatureBoxWidth, signatureBoxHeight)
+
+	var strokes [][]float64
+	if signer.SignatureStrokes != "" && json.Unmarshal([]byte(signer.SignatureStrokes), &strokes) == nil {
+		scale := min(signatureBoxWidth/models.SignaturePadWidth, signatureBoxHeight/models.SignaturePadHeight)
+		for _, stroke := range strokes {
+			points := make([]float64, 0, len(stroke)+2)
+			for i := 0; i+1 < len(stroke); i += 2 {
+				points = append(points, x+stroke[i]*scale, y+(models.SignaturePadHeight-stroke[i+1])*scale)
+			}
+			if len(points) == 2 {
+				// A dot is drawn as a tiny line.
+				points = append(points, points[0]+0.5, points[1])
+			}
+			w.page.Polyline(1, points...)
+		}
+		return
+	}
+	w.page.Text(x+10, y+signatureBoxHeight/2-6, 16, true, signer.SignatureText)
+}
@@ -0,0 +1,557 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+	"github.com/JneiraS/BaseSasS/internal/domain/models"
+	"github.com/JneiraS/BaseSasS/internal/domain/repositories"
+	"github.com/JneiraS/BaseSasS/internal/pdf"
+	"gorm.io/gorm"
+)
+
+// ErrSignatureUnavailable is returned for unknown signing links, and for the links of a deleted
+// document, which are not told apart so as not to inform on the links that exist.
+var ErrSignatureUnavailable = errors.New("ce lien de signature est invalide ou n'est plus valable")
+
+// Limits of the signature requests.
+const (
+	maxSigners             = 20
+	maxSignatureMessage    = 1000
+	maxSignatureNameLength = 100
+	maxDeclineReasonLength = 500
+	maxSignatureStrokes    = 100
+	maxSignaturePoints     = 5000
+)
+
+// SignatureService encapsulates the business logic of the electronic signature of documents: the
+// association asks some of its members to sign a version of a document, each signer receives a
+// personal signing link by email, and a signed PDF with an audit certificate is produced once
+// everyone signed.
+type SignatureService struct {
+	documentRepo    repositories.DocumentRepository
+	documentService *DocumentService
+	memberRepo      repositories.MemberRepository
+	emailService    *EmailService
+	settingsService *SettingsService
+	appURL          string // Base URL of the application, for the signing links.
+}
+
+// NewSignatureService creates a new instance of SignatureService.
+// It takes a DocumentRepository, the DocumentService holding the files, a MemberRepository (the
+// signers are members), the EmailService sending the signing links and the SettingsService (for the
+// name of the association) as dependencies, adhering to the dependency inversion principle.
+func NewSignatureService(documentRepo repositories.DocumentRepository, documentService *DocumentService, memberRepo repositories.MemberRepository, emailService *EmailService, settingsService *SettingsService, cfg *config.Config) *SignatureService {
+	return &SignatureService{
+		documentRepo:    documentRepo,
+		documentService: documentService,
+		memberRepo:      memberRepo,
+		emailService:    emailService,
+		settingsService: settingsService,
+		appURL:          strings.TrimSuffix(cfg.AppURL, "/"),
+	}
+}
+
+// CreateSignatureRequest asks members of the user's association to sign the current version of a
+// document, identified by the SHA-256 hash of its file, and emails each of them its signing link.
+// It returns the request with the names of the signers the email could not be sent to, who can be
+// reminded later.
+func (s *SignatureService) CreateSignatureRequest(ctx context.Context, user models.User, documentID uint, memberIDs []uint, message string) (*models.DocumentSignatureRequest, []string, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != user.ID {
+		return nil, nil, fmt.Errorf("document non trouvé")
+	}
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > maxSignatureMessage {
+		return nil, nil, fmt.Errorf("le message ne peut pas dépasser %d caractères", maxSignatureMessage)
+	}
+	if len(memberIDs) == 0 {
+		return nil, nil, fmt.Errorf("choisissez au moins un signataire")
+	}
+
+	request := &models.DocumentSignatureRequest{
+		UserID:       user.ID,
+		DocumentID:   document.ID,
+		DocumentName: document.Name,
+		Version:      document.Version,
+		FilePath:     document.FilePath,
+		FileSize:     document.FileSize,
+		MimeType:     document.MimeType,
+		Message:      message,
+		Status:       models.SignaturePending,
+		CreatedByID:  user.ID,
+		CreatedBy:    user.Name,
+	}
+	tokens := map[uint]string{}
+	seen := map[string]bool{}
+	for _, memberID := range memberIDs {
+		member, err := s.memberRepo.FindMemberByID(memberID)
+		if err != nil || member.UserID != user.ID {
+			return nil, nil, fmt.Errorf("signataire introuvable parmi les membres")
+		}
+		email := strings.ToLower(strings.TrimSpace(member.Email))
+		if email == "" {
+			return nil, nil, fmt.Errorf("%s %s n'a pas d'adresse email", member.FirstName, member.LastName)
+		}
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		token, err := newShareToken()
+		if err != nil {
+			return nil, nil, err
+		}
+		tokens[member.ID] = token
+		request.Signers = append(request.Signers, models.DocumentSigner{
+			MemberID:  member.ID,
+			Name:      strings.TrimSpace(member.FirstName + " " + member.LastName),
+			Email:     member.Email,
+			TokenHash: shareTokenHash(token),
+			Status:    models.SignerPending,
+		})
+	}
+	if len(request.Signers) > maxSigners {
+		return nil, nil, fmt.Errorf("une demande ne peut pas compter plus de %d signataires", maxSigners)
+	}
+
+	if request.DocumentHash, err = s.fileHash(ctx, document.FilePath); err != nil {
+		return nil, nil, err
+	}
+	if err := s.documentRepo.CreateSignatureRequest(request); err != nil {
+		return nil, nil, fmt.Errorf("impossible d'enregistrer la demande de signature: %w", err)
+	}
+
+	var failed []string
+	for i := range request.Signers {
+		signer := &request.Signers[i]
+		if err := s.sendSigningLink(request, signer, tokens[signer.MemberID], false); err != nil {
+			log.Printf("ERREUR: Impossible d'envoyer le lien de signature à %s: %v", signer.Email, err)
+			failed = append(failed, signer.Name)
+		}
+	}
+	return request, failed, nil
+}
+
+// RemindSigners sends a new signing link to the signers of a request who have not answered yet;
+// their previous links stop working. It returns the number of links sent and the names of the
+// signers the email could not be sent to.
+func (s *SignatureService) RemindSigners(userID, documentID, requestID uint) (int, []string, error) {
+	request, err := s.GetSignatureRequest(userID, documentID, requestID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if request.Status != models.SignaturePending {
+		return 0, nil, fmt.Errorf("cette demande de signature n'est plus en cours")
+	}
+	sent := 0
+	var failed []string
+	for i := range request.Signers {
+		signer := &request.Signers[i]
+		if signer.Status != models.SignerPending {
+			continue
+		}
+		token, err := newShareToken()
+		if err != nil {
+			return sent, failed, err
+		}
+		if err := s.sendSigningLink(request, signer, token, true); err != nil {
+			log.Printf("ERREUR: Impossible d'envoyer le lien de signature à %s: %v", signer.Email, err)
+			failed = append(failed, signer.Name)
+			continue
+		}
+		sent++
+	}
+	return sent, failed, nil
+}
+
+// sendSigningLink emails a signer its signing link with the given token, and records it as sent.
+// A reminder replaces the previous token of the signer only once the email was sent, so that a
+// failed reminder leaves the previous link working.
+func (s *SignatureService) sendSigningLink(request *models.DocumentSignatureRequest, signer *models.DocumentSigner, token string, reminder bool) error {
+	association := s.associationName(request.UserID)
+	subject := fmt.Sprintf("Signature demandée : %s", request.DocumentName)
+	if reminder {
+		subject = "Rappel - " + subject
+	}
+	body := fmt.Sprintf("%s, %s vous demande de signer le document « %s ».", signer.Name, association, request.DocumentName)
+	if request.Message != "" {
+		body += " Message : " + request.Message
+	}
+	body += fmt.Sprintf(" Pour consulter le document et le signer, ouvrez ce lien personnel, à ne pas transmettre : %s", s.SigningURL(token))
+	if err := s.emailService.SendEmail([]string{signer.Email}, subject, body); err != nil {
+		return err
+	}
+	now := time.Now()
+	signer.EmailSentAt = &now
+	signer.TokenHash = shareTokenHash(token)
+	return s.documentRepo.UpdateSignerToken(signer.ID, signer.TokenHash, signer.EmailSentAt)
+}
+
+// SigningURL returns the URL of the signing link with the given token.
+func (s *SignatureService) SigningURL(token string) string {
+	return s.appURL + "/sign/" + token
+}
+
+// GetSignerCandidates returns the members of the user's association who can be asked to sign: those
+// with an email address, by name.
+func (s *SignatureService) GetSignerCandidates(userID uint) ([]models.Member, error) {
+	members, err := s.memberRepo.FindMembersByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	candidates := members[:0]
+	for _, member := range members {
+		if strings.TrimSpace(member.Email) != "" {
+			candidates = append(candidates, member)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return strings.ToLower(candidates[i].LastName+" "+candidates[i].FirstName) < strings.ToLower(candidates[j].LastName+" "+candidates[j].FirstName)
+	})
+	return candidates, nil
+}
+
+// GetSignatureRequests retrieves the signature requests of a document of the user, most recent
+// first.
+func (s *SignatureService) GetSignatureRequests(userID, documentID uint) ([]models.DocumentSignatureRequest, error) {
+	document, err := s.documentRepo.FindDocumentByID(documentID)
+	if err != nil || document.UserID != userID {
+		return nil, fmt.Errorf("document non trouvé")
+	}
+	return s.documentRepo.FindSignatureRequestsByDocumentID(documentID)
+}
+
+// GetSignatureRequest retrieves a signature request of a document of the user, with its signers.
+func (s *SignatureService) GetSignatureRequest(userID, documentID, requestID uint) (*models.DocumentSignatureRequest, error) {
+	request, err := s.documentRepo.FindSignatureRequestByID(requestID)
+	if err != nil || request.UserID != userID || request.DocumentID != documentID {
+		return nil, fmt.Errorf("demande de signature introuvable")
+	}
+	return request, nil
+}
+
+// CancelSignatureRequest withdraws a pending signature request of the user: the signing links
+// stop working, and the signatures already given are kept in the history.
+func (s *SignatureService) CancelSignatureRequest(userID, documentID, requestID uint) error {
+	request, err := s.GetSignatureRequest(userID, documentID, requestID)
+	if err != nil {
+		return err
+	}
+	cancelled, err := s.documentRepo.CancelSignatureRequest(request.ID, time.Now())
+	if err != nil {
+		return fmt.Errorf("impossible d'annuler la demande de signature: %w", err)
+	}
+	if !cancelled {
+		return fmt.Errorf("cette demande de signature n'est plus en cours")
+	}
+	return nil
+}
+
+// OpenSignedDocument opens the signed PDF of a completed signature request of the user, producing
+// it first if that failed when the last signer signed. The caller must close the file.
+func (s *SignatureService) OpenSignedDocument(ctx context.Context, userID, documentID, requestID uint) (*models.DocumentSignatureRequest, io.ReadCloser, error) {
+	request, err := s.GetSignatureRequest(userID, documentID, requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.openSignedFile(ctx, request)
+}
+
+// openSignedFile opens the signed PDF of a completed request, producing it if needed.
+func (s *SignatureService) openSignedFile(ctx context.Context, request *models.DocumentSignatureRequest) (*models.DocumentSignatureRequest, io.ReadCloser, error) {
+	if request.Status != models.SignatureCompleted {
+		return nil, nil, fmt.Errorf("le document n'a pas encore été signé par tous les signataires")
+	}
+	if !request.HasSignedFile() {
+		if err := s.produceSignedFile(ctx, request); err != nil {
+			return nil, nil, err
+		}
+	}
+	file, err := s.documentService.OpenFile(ctx, request.SignedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return request, file, nil
+}
+
+// SignedFileName returns the name under which the signed PDF of a request is downloaded.
+func SignedFileName(request *models.DocumentSignatureRequest) string {
+	name := request.DocumentName
+	if i := strings.LastIndex(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return name + " - signé.pdf"
+}
+
+// GetSigning retrieves the signature request and the signer of a signing link, and records the
+// first time the link was opened. The request is returned whatever its state, so that the signer
+// can be told it was already signed or cancelled.
+func (s *SignatureService) GetSigning(token string) (*models.DocumentSignatureRequest, *models.DocumentSigner, error) {
+	if token == "" {
+		return nil, nil, ErrSignatureUnavailable
+	}
+	signer, err := s.documentRepo.FindSignerByTokenHash(shareTokenHash(token))
+	if err != nil {
+		return nil, nil, ErrSignatureUnavailable
+	}
+	request, err := s.documentRepo.FindSignatureRequestByID(signer.RequestID)
+	if err != nil {
+		return nil, nil, ErrSignatureUnavailable
+	}
+	// The links of a deleted document stop working with it.
+	if _, err := s.documentRepo.FindDocumentByID(request.DocumentID); err != nil {
+		return nil, nil, ErrSignatureUnavailable
+	}
+	if signer.ViewedAt == nil {
+		now := time.Now()
+		if err := s.documentRepo.RecordSignerView(signer.ID, now); err != nil {
+			log.Printf("ERREUR: Impossible d'enregistrer la consultation du signataire %d: %v", signer.ID, err)
+		}
+		signer.ViewedAt = &now
+	}
+	return request, signer, nil
+}
+
+// OpenSigningDocument opens, for the signer of a signing link, the file to sign while the request
+// is pending, and the signed PDF once everyone signed. The caller must close the file.
+func (s *SignatureService) OpenSigningDocument(ctx context.Context, token string) (string, string, int64, io.ReadCloser, error) {
+	request, _, err := s.GetSigning(token)
+	if err != nil {
+		return "", "", 0, nil, err
+	}
+	switch request.Status {
+	case models.SignaturePending:
+		file, err := s.documentService.OpenFile(ctx, request.FilePath)
+		if err != nil {
+			return "", "", 0, nil, err
+		}
+		return request.DocumentName, request.MimeType, request.FileSize, file, nil
+	case models.SignatureCompleted:
+		request, file, err := s.openSignedFile(ctx, request)
+		if err != nil {
+			return "", "", 0, nil, err
+		}
+		return SignedFileName(request), "application/pdf", 0, file, nil
+	}
+	return "", "", 0, nil, fmt.Errorf("cette demande de signature n'est plus en cours")
+}
+
+// Sign records the signature of the signer of a signing link: its name as typed, and optionally its
+// handwritten signature, with the time, its address and browser and the hash of the file, checked
+// against the hash of the file the request was made for. It returns the request, completed when
+// the signer was the last one.
+func (s *SignatureService) Sign(ctx context.Context, token, name, strokes string, consent bool, ipAddress, userAgent string) (*models.DocumentSignatureRequest, error) {
+	request, signer, err := s.GetSigning(token)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != models.SignaturePending || signer.Status != models.SignerPending {
+		return nil, fmt.Errorf("cette demande de signature n'attend plus votre signature")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("saisissez votre nom pour signer")
+	}
+	if utf8.RuneCountInString(name) > maxSignatureNameLength {
+		return nil, fmt.Errorf("le nom ne peut pas dépasser %d caractères", maxSignatureNameLength)
+	}
+	if !consent {
+		return nil, fmt.Errorf("vous devez accepter de signer électroniquement le document")
+	}
+	signer.SignatureText = name
+	if signer.SignatureStrokes, err = normalizeStrokes(strokes); err != nil {
+		return nil, err
+	}
+	signer.Method = models.SignatureTyped
+	if signer.SignatureStrokes != "" {
+		signer.Method = models.SignatureDrawn
+	}
+
+	// The file is hashed again, so that a signature is only recorded for the file it was asked for.
+	hash, err := s.fileHash(ctx, request.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if hash != request.DocumentHash {
+		log.Printf("ERREUR: L'empreinte du fichier de la demande de signature %d a changé", request.ID)
+		return nil, fmt.Errorf("le fichier a été modifié depuis la demande de signature: il ne peut pas être signé")
+	}
+	now := time.Now()
+	signer.SignedAt = &now
+	signer.DocumentHash = hash
+	signer.IPAddress = ipAddress
+	signer.UserAgent = truncateUserAgent(userAgent)
+	completed, err := s.documentRepo.RecordSignature(signer)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("cette demande de signature n'attend plus votre signature")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("impossible d'enregistrer la signature: %w", err)
+	}
+	if !completed {
+		return request, nil
+	}
+
+	request, err = s.documentRepo.FindSignatureRequestByID(request.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.produceSignedFile(ctx, request); err != nil {
+		// The signed PDF is produced again when it is downloaded.
+		log.Printf("ERREUR: Impossible de produire le PDF signé de la demande %d: %v", request.ID, err)
+	}
+	return request, nil
+}
+
+// Decline records that the signer of a signing link refuses to sign, with its reason, which ends
+// the request.
+func (s *SignatureService) Decline(token, reason, ipAddress, userAgent string) error {
+	request, signer, err := s.GetSigning(token)
+	if err != nil {
+		return err
+	}
+	if request.Status != models.SignaturePending || signer.Status != models.SignerPending {
+		return fmt.Errorf("cette demande de signature n'attend plus votre réponse")
+	}
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > maxDeclineReasonLength {
+		return fmt.Errorf("le motif ne peut pas dépasser %d caractères", maxDeclineReasonLength)
+	}
+	now := time.Now()
+	signer.SignedAt = &now
+	signer.DeclineReason = reason
+	signer.IPAddress = ipAddress
+	signer.UserAgent = truncateUserAgent(userAgent)
+	recorded, err := s.documentRepo.RecordSignatureRefusal(signer)
+	if err != nil {
+		return fmt.Errorf("impossible d'enregistrer le refus: %w", err)
+	}
+	if !recorded {
+		return fmt.Errorf("cette demande de signature n'attend plus votre réponse")
+	}
+	return nil
+}
+
+// produceSignedFile produces and stores the signed PDF of a completed request: the signed file
+// followed by the audit certificate when it is a PDF, the certificate alone otherwise, which
+// identifies the file by its hash.
+func (s *SignatureService) produceSignedFile(ctx context.Context, request *models.DocumentSignatureRequest) error {
+	certificate := renderSignatureCertificate(request, s.associationName(request.UserID))
+	content := certificate.Bytes()
+	if request.MimeType == "application/pdf" {
+		file, err := s.documentService.OpenFile(ctx, request.FilePath)
+		if err != nil {
+			return err
+		}
+		original, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("impossible de lire le document signé: %w", err)
+		}
+		if signed, err := pdf.Append(original, certificate); err == nil {
+			content = signed
+		} else {
+			log.Printf("AVERTISSEMENT: Certificat de la demande de signature %d produit seul: %v", request.ID, err)
+		}
+	}
+
+	sum := sha256.Sum256(content)
+	key := fmt.Sprintf("%d_%s_%s", request.UserID, time.Now().Format("20060102150405.000000000"), storageName(SignedFileName(request)))
+	if err := s.documentService.store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		return fmt.Errorf("impossible d'enregistrer le PDF signé: %w", err)
+	}
+	if err := s.documentRepo.SetSignedFile(request.ID, key, hex.EncodeToString(sum[:])); err != nil {
+		s.documentService.removeFile(key)
+		return err
+	}
+	request.SignedKey = key
+	request.SignedHash = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// fileHash returns the SHA-256 hash of a file of the document store, in hexadecimal.
+func (s *SignatureService) fileHash(ctx context.Context, key string) (string, error) {
+	file, err := s.documentService.OpenFile(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("impossible de lire le fichier: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// associationName returns the name of an association, as set in its settings.
+func (s *SignatureService) associationName(userID uint) string {
+	if settings, err := s.settingsService.GetSettings(userID); err == nil && settings.LegalName != "" {
+		return settings.LegalName
+	}
+	return "Votre association"
+}
+
+// normalizeStrokes checks a signature drawn on the signing page, as a JSON array of strokes made
+// of x, y coordinates, and returns it with the coordinates rounded and kept within the pad. It
+// returns an empty string when nothing was drawn.
+func normalizeStrokes(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	var strokes [][]float64
+	if err := json.Unmarshal([]byte(value), &strokes); err != nil {
+		return "", fmt.Errorf("signature manuscrite invalide")
+	}
+	if len(strokes) > maxSignatureStrokes {
+		return "", fmt.Errorf("signature manuscrite trop complexe")
+	}
+	points := 0
+	normalized := make([][]int, 0, len(strokes))
+	for _, stroke := range strokes {
+		if len(stroke) < 2 || len(stroke)%2 != 0 {
+			continue
+		}
+		points += len(stroke) / 2
+		if points > maxSignaturePoints {
+			return "", fmt.Errorf("signature manuscrite trop complexe")
+		}
+		coordinates := make([]int, len(stroke))
+		for i, value := range stroke {
+			limit := float64(models.SignaturePadWidth)
+			if i%2 == 1 {
+				limit = models.SignaturePadHeight
+			}
+			if math.IsNaN(value) {
+				return "", fmt.Errorf("signature manuscrite invalide")
+			}
+			coordinates[i] = int(math.Round(math.Max(0, math.Min(limit, value))))
+		}
+		normalized = append(normalized, coordinates)
+	}
+	if len(normalized) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(normalized)
+	return string(b), err
+}
+
+// truncateUserAgent shortens the browser identification recorded with a signature.
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return userAgent
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FSStore stores files in a directory of the local file system.
+type FSStore struct {
+	root string
+}
+
+// NewFSStore creates a store writing to the given directory, creating it if needed.
+func NewFSStore(root string) (*FSStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("impossible de créer le répertoire de stockage des documents: %w", err)
+	}
+	return &FSStore{root: root}, nil
+}
+
+// Name returns the name of the backend.
+func (s *FSStore) Name() string {
+	return BackendLocal + " (" + s.root + ")"
+}
+
+// Put writes the file to a temporary file of the directory, then renames it, so that a failed
+// upload never leaves a partial file under the key.
+func (s *FSStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.root, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("impossible de créer le fichier sur le serveur: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed.
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("impossible de copier le fichier: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("fichier incomplet: %d octets reçus sur %d", written, size)
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.root, key))
+}
+
+// Get opens the file stored under key.
+func (s *FSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(s.root, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Stat returns the size of the file stored under key.
+func (s *FSStore) Stat(ctx context.Context, key string) (int64, error) {
+	if err := validKey(key); err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(filepath.Join(s.root, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Delete removes the file stored under key.
+func (s *FSStore) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.root, key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// List calls fn for each file of the directory. Subdirectories are ignored.
+func (s *FSStore) List(ctx context.Context, fn func(FileInfo) error) error {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return fmt.Errorf("impossible de lister le répertoire de stockage: %w", err)
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // Deleted meanwhile.
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(FileInfo{Key: entry.Name(), Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
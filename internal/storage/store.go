@@ -0,0 +1,102 @@
+// Package storage stores the files of the document library. Each backend implements BlobStore:
+// the local file system, for a single instance, or an S3-compatible object store shared by
+// several instances. Files are streamed in and out without being buffered in memory.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/JneiraS/BaseSasS/internal/config"
+)
+
+// ErrNotFound is returned when no file is stored under a key.
+var ErrNotFound = errors.New("fichier introuvable dans le stockage")
+
+// Names of the storage backends, as set in DOCUMENT_STORAGE_BACKEND.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// BlobStore is implemented by the storage backends. Keys are flat file names chosen by the
+// application, without path separators.
+type BlobStore interface {
+	// Name returns the name of the backend, for logs.
+	Name() string
+	// Put stores the content read from r under key, replacing any previous file. size is the
+	// number of bytes r yields.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat returns the size of the file stored under key, or ErrNotFound.
+	Stat(ctx context.Context, key string) (int64, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// List calls fn for each file of the store, in no particular order, and stops at the first
+	// error returned by fn.
+	List(ctx context.Context, fn func(FileInfo) error) error
+}
+
+// FileInfo describes a file of a store.
+type FileInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time // When the file was stored.
+}
+
+// Open creates the store of the given backend from the configuration.
+func Open(backend string, cfg *config.Config) (BlobStore, error) {
+	switch backend {
+	case BackendLocal, "":
+		return NewFSStore(cfg.DocumentStoragePath)
+	case BackendS3:
+		return NewS3Store(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKeyID,
+			SecretKey: cfg.S3SecretAccessKey,
+		})
+	default:
+		return nil, fmt.Errorf("stockage de documents inconnu: %q", backend)
+	}
+}
+
+// Copy streams the file stored under key from one store to another, then checks that the copy
+// has the size of the original.
+func Copy(ctx context.Context, from, to BlobStore, key string) error {
+	size, err := from.Stat(ctx, key)
+	if err != nil {
+		return err
+	}
+	src, err := from.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := to.Put(ctx, key, src, size, ""); err != nil {
+		return err
+	}
+	copied, err := to.Stat(ctx, key)
+	if err != nil {
+		return err
+	}
+	if copied != size {
+		return fmt.Errorf("copie de %s incomplète: %d octets sur %d", key, copied, size)
+	}
+	return nil
+}
+
+// validKey ensures that a key is a plain file name, so that it cannot designate a file outside of
+// the store.
+func validKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) || strings.ContainsRune(key, 0) {
+		return fmt.Errorf("clé de stockage invalide: %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Preview window of the document library: shows the thumbnail or the beginning of the text of a
+// document without downloading it. Previews are generated in the background after the upload, so
+// a preview still pending is asked again a few times.
+document.addEventListener('DOMContentLoaded', function() {
+    const dialog = document.getElementById('preview-dialog');
+    if (!dialog) {
+        return;
+    }
+    const title = document.getElementById('preview-title');
+    const message = document.getElementById('preview-message');
+    const image = document.getElementById('preview-image');
+    const text = document.getElementById('preview-text');
+    const download = document.getElementById('preview-download');
+    let request = 0;
+
+    function show(data) {
+        title.textContent = data.name;
+        download.href = data.download;
+        image.hidden = !data.thumbnail;
+        if (data.thumbnail) {
+            image.src = data.thumbnail;
+        } else {
+            image.removeAttribute('src');
+        }
+        text.hidden = !data.excerpt;
+        text.textContent = data.excerpt || '';
+        if (data.pending) {
+            message.textContent = 'Aperçu en cours de génération…';
+        } else if (!data.thumbnail && !data.excerpt) {
+            message.textContent = 'Aucun aperçu disponible pour ce type de fichier (' + data.mime_type + ').';
+        } else {
+            message.textContent = '';
+        }
+    }
+
+    async function load(url, attempt, current) {
+        try {
+            const response = await fetch(url, { credentials: 'include' });
+            const data = await response.json();
+            if (current !== request) {
+                return;
+            }
+            if (!response.ok) {
+                message.textContent = data.error || 'Aperçu indisponible.';
+                return;
+            }
+            show(data);
+            if (data.pending && attempt < 10 && dialog.open) {
+                setTimeout(function() { load(url, attempt + 1, current); }, 1500);
+            }
+        } catch (error) {
+            if (current === request) {
+                message.textContent = 'Aperçu indisponible.';
+            }
+        }
+    }
+
+    document.querySelectorAll('.preview-btn').forEach(function(button) {
+        button.addEventListener('click', function() {
+            request++;
+            title.textContent = '';
+            message.textContent = 'Chargement…';
+            image.hidden = true;
+            text.hidden = true;
+            dialog.showModal();
+            load(button.dataset.preview, 0, request);
+        });
+    });
+
+    document.getElementById('preview-close').addEventListener('click', function() {
+        dialog.close();
+    });
+    // A click on the backdrop, outside of the window, closes it.
+    dialog.addEventListener('click', function(event) {
+        if (event.target === dialog) {
+            dialog.close();
+        }
+    });
+});
@@ -0,0 +1,70 @@
+// Signature pad of the signing page: the signer draws its signature with the mouse, a finger or a
+// stylus. The strokes are kept as lists of x, y coordinates in the units of the pad, whatever its
+// displayed size, and written as JSON to the hidden "strokes" field of the signing form.
+document.addEventListener('DOMContentLoaded', function() {
+    const canvas = document.getElementById('signature-pad');
+    const input = document.getElementById('signature-strokes');
+    if (!canvas || !input) {
+        return;
+    }
+    const clear = document.getElementById('signature-clear');
+    const context = canvas.getContext('2d');
+    const strokes = [];
+    let current = null;
+
+    function point(event) {
+        const rect = canvas.getBoundingClientRect();
+        return [
+            Math.round((event.clientX - rect.left) * canvas.width / rect.width),
+            Math.round((event.clientY - rect.top) * canvas.height / rect.height)
+        ];
+    }
+
+    function draw() {
+        context.clearRect(0, 0, canvas.width, canvas.height);
+        context.lineWidth = 2;
+        context.lineCap = 'round';
+        context.lineJoin = 'round';
+        context.strokeStyle = '#1a1a1a';
+        strokes.forEach(function(stroke) {
+            context.beginPath();
+            context.moveTo(stroke[0], stroke[1]);
+            for (let i = 2; i < stroke.length; i += 2) {
+                context.lineTo(stroke[i], stroke[i + 1]);
+            }
+            if (stroke.length === 2) {
+                context.lineTo(stroke[0] + 0.5, stroke[1]);
+            }
+            context.stroke();
+        });
+        input.value = strokes.length ? JSON.stringify(strokes) : '';
+    }
+
+    canvas.addEventListener('pointerdown', function(event) {
+        event.preventDefault();
+        canvas.setPointerCapture(event.pointerId);
+        current = point(event);
+        strokes.push(current);
+        draw();
+    });
+    canvas.addEventListener('pointermove', function(event) {
+        if (!current) {
+            return;
+        }
+        current.push.apply(current, point(event));
+        draw();
+    });
+    ['pointerup', 'pointercancel', 'pointerleave'].forEach(function(type) {
+        canvas.addEventListener(type, function() {
+            current = null;
+        });
+    });
+
+    if (clear) {
+        clear.addEventListener('click', function() {
+            strokes.length = 0;
+            current = null;
+            draw();
+        });
+    }
+});